@@ -2,31 +2,220 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/rathore/langchain-agent/guard"
 	"github.com/rathore/langchain-agent/llm"
+	"github.com/rathore/langchain-agent/redact"
 	"github.com/rathore/langchain-agent/tools"
 )
 
 // Agent runs the autonomous agent loop
 type Agent struct {
 	client       llm.ChatClient
-	tools        map[string]tools.Tool
+	registry     *tools.Registry
 	toolDefs     []llm.ToolDef
+	toolOrder    []tools.Tool    // registration order, for listing
+	disabled     map[string]bool // tools hidden from the LLM (see SetToolEnabled)
 	maxIter      int
 	history      []llm.Message
+	memory       Memory
+	summary      string // of history[:summarized], see Memory
+	summarized   int
 	systemPrompt string
-	mu           sync.Mutex // serialises Run() and ClearHistory() across REPL + webhook callers
+	nativePrompt string           // systemPrompt for native tool calling
+	offered      []llm.ToolDef    // enabled tools as offered to the LLM
+	budget       PromptBudget     // bounds systemPrompt by pruning offered
+	promptTokens int              // size of systemPrompt
+	pruned       []string         // tools offered without parameters
+	textTools    bool             // never use native tool calling
+	native       bool             // try native tool calling (see chat)
+	retriever    ContextRetriever // nil unless auto-RAG is on
+	redactor     *redact.Redactor // nil masks nothing
+	guard        *guard.Guard     // nil leaves tool output unwrapped
+	pipe         *pipe            // nil sends tool results whole, without handles
+	evidence     bool             // add Evidence to each RunResult
+	policy       Policy           // nil allows every call
+	price        *llm.Price       // nil: costs not tracked
+	maxCost      float64          // 0: no limit
+	maxRunCost   float64          // 0: no limit
+	nudges       Nudges
+	hooks        Hooks
+	defaults     map[string]string // see SetDefault
+	prefs        Preferences
+	toolStats    *tools.Stats // nil: calls not counted
+	totals       Totals       // every run since New, for Totals
+	lastRun      *RunResult   // most recent run, for LastRun
+	runs         []*RunResult // runs of the current conversation, for Runs
+	mu           sync.Mutex   // serialises Run() and ClearHistory() across REPL + webhook callers
 }
 
-// Config holds agent configuration
+// Config holds agent configuration. Only Client or Model is required.
 type Config struct {
-	Model   string
-	MaxIter int
+	Model   string // Ollama model, used when Client is nil
+	MaxIter int    // LLM calls per run (default 10)
 	Tools   []tools.Tool
-	Client  llm.ChatClient // Optional: inject custom client (for testing)
+	Client  llm.ChatClient // LLM backend (default: Ollama on localhost)
+
+	// Registry, instead of Tools, supplies the tools with their metadata and
+	// aliases
+	Registry *tools.Registry
+
+	// Retriever, when set, is searched with every user query and its results
+	// are added to the message sent to the LLM (auto-RAG), for models that
+	// tend to answer without calling the wiki tool
+	Retriever ContextRetriever
+
+	// Redactor, when set, masks secrets in tool results and retrieved
+	// context before the LLM, the hooks and the run record see them
+	Redactor *redact.Redactor
+
+	// Guard, when set, wraps tool results and retrieved context in
+	// untrusted-content blocks the system prompt tells the LLM not to obey,
+	// and screens them for injected instructions
+	Guard *guard.Guard
+
+	// PipeThreshold, when above 0, stores every tool result under a handle
+	// (@result1, ...) that a later tool call can pass as a parameter value
+	// to get the whole result, and shows the LLM only the start and end of
+	// results longer than this many bytes
+	PipeThreshold int
+
+	// Evidence adds to each RunResult the claims of its answer with the
+	// tool results and wiki text that support them (see Evidence)
+	Evidence bool
+
+	// Policy, when set, is checked before every tool call; a denied call is
+	// reported to the LLM as the tool's error
+	Policy Policy
+
+	// Price, when set, is what the model charges (see llm.PriceOf): each
+	// RunResult gets its Cost, and MaxCost and MaxRunCost bound the spend
+	Price *llm.Price
+
+	// MaxCost fails runs with ErrSpendLimit once the agent's runs have cost
+	// this many dollars in all; MaxRunCost stops a run that has cost this
+	// much. Checked before each LLM call (0: no limit).
+	MaxCost    float64
+	MaxRunCost float64
+
+	// Nudges correct a model that neither calls a tool nor answers, and
+	// stop runs that stall
+	Nudges Nudges
+
+	// Defaults are the initial session defaults (see SetDefault)
+	Defaults map[string]string
+
+	// Preferences ask for answers in a language, length, units and date
+	// format (see SetPreferences)
+	Preferences Preferences
+
+	// ToolStats counts each tool's calls, errors and latency, e.g. shared
+	// by several agents and kept in a file across sessions
+	ToolStats *tools.Stats
+
+	// TextToolCalls makes the LLM write tool calls as JSON in its replies
+	// even when the client has native tool calling (llm.NativeToolClient)
+	TextToolCalls bool
+
+	// Memory bounds the history sent with each query, summarizing the
+	// oldest exchanges of long conversations
+	Memory Memory
+
+	// Hooks are called as each run happens (see Hooks)
+	Hooks Hooks
+
+	// PromptBudget bounds the system prompt, pruning the tool definitions
+	// of agents with many tools
+	PromptBudget PromptBudget
+}
+
+// ErrSpendLimit is returned by runs stopped by Config.MaxCost or MaxRunCost
+var ErrSpendLimit = errors.New("spend limit reached")
+
+// Policy decides which tool calls may run (see policy.Role)
+type Policy interface {
+	Check(tool string, meta tools.Meta, params map[string]any) error
+}
+
+// ContextRetriever finds background context for a user query
+type ContextRetriever interface {
+	Retrieve(ctx context.Context, query string) (string, error)
+}
+
+// RunResult is the structured record of one Run: the final answer, every
+// LLM step with the tool it called, and the tokens and time used
+type RunResult struct {
+	Query   string        `json:"query"`
+	Answer  string        `json:"answer"`
+	Steps   []Step        `json:"steps"`
+	Usage   llm.Usage     `json:"usage"`
+	Cost    float64       `json:"cost_usd,omitempty"` // with Config.Price
+	Elapsed time.Duration `json:"elapsed_ns"`
+
+	// Evidence is set when Config.Evidence is
+	Evidence *Evidence `json:"evidence,omitempty"`
+}
+
+// Totals is what an agent's runs have used since it was created, across
+// conversations
+type Totals struct {
+	Runs  int       `json:"runs"`
+	Usage llm.Usage `json:"usage"`
+	Cost  float64   `json:"cost_usd"` // with Config.Price
+}
+
+// Step is one LLM response within a run
+type Step struct {
+	Output   string        `json:"output"`
+	ToolCall *ToolCall     `json:"tool_call,omitempty"`
+	Usage    llm.Usage     `json:"usage"`
+	Elapsed  time.Duration `json:"elapsed_ns"` // LLM response time
+
+	// Nudge is the corrective message the step's reply was answered with
+	Nudge string `json:"nudge,omitempty"`
+}
+
+// ToolCall is a tool invocation and its result
+type ToolCall struct {
+	Name    string         `json:"name"`
+	Params  map[string]any `json:"params"`
+	Result  string         `json:"result"`
+	Error   string         `json:"error,omitempty"`
+	Elapsed time.Duration  `json:"elapsed_ns"`
+
+	// Handle names the result for later tool calls (see
+	// Config.PipeThreshold)
+	Handle string `json:"handle,omitempty"`
+
+	// Injection is the instruction-like text the Guard found in the result
+	Injection []string `json:"injection,omitempty"`
+}
+
+// Event types reported to RunEvents callbacks
+const (
+	EventToken      = "token"       // streamed LLM text
+	EventToolCall   = "tool_call"   // a tool is about to run
+	EventToolResult = "tool_result" // a tool returned
+	EventAnswer     = "answer"      // the final answer
+	EventError      = "error"       // the run failed (sent by RunStream; Run returns the error)
+)
+
+// Event is a step of a run as it happens, for real-time frontends
+type Event struct {
+	Type   string         `json:"type"`
+	Text   string         `json:"text,omitempty"` // token text or final answer
+	Tool   string         `json:"tool,omitempty"`
+	Params map[string]any `json:"params,omitempty"`
+	Result string         `json:"result,omitempty"`
+	Error  string         `json:"error,omitempty"`
+
+	Evidence *Evidence  `json:"evidence,omitempty"` // with the answer, see Config.Evidence
+	Run      *RunResult `json:"-"`                  // the whole run, with RunStream's last event
 }
 
 // New creates a new agent
@@ -34,6 +223,19 @@ func New(cfg Config) (*Agent, error) {
 	var client llm.ChatClient
 	var err error
 
+	registry := cfg.Registry
+	if registry != nil && len(cfg.Tools) > 0 {
+		return nil, fmt.Errorf("set Tools or Registry, not both")
+	}
+	if registry == nil {
+		registry = tools.NewRegistry()
+		for _, t := range cfg.Tools {
+			if _, err := registry.Register(t, tools.Meta{}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	if cfg.Client != nil {
 		client = cfg.Client
 	} else {
@@ -44,115 +246,512 @@ func New(cfg Config) (*Agent, error) {
 	}
 
 	a := &Agent{
-		client:  client,
-		tools:   make(map[string]tools.Tool),
-		maxIter: cfg.MaxIter,
+		client:     client,
+		registry:   registry,
+		disabled:   make(map[string]bool),
+		maxIter:    cfg.MaxIter,
+		retriever:  cfg.Retriever,
+		redactor:   cfg.Redactor,
+		guard:      cfg.Guard,
+		pipe:       newPipe(cfg.PipeThreshold),
+		evidence:   cfg.Evidence,
+		policy:     cfg.Policy,
+		price:      cfg.Price,
+		maxCost:    cfg.MaxCost,
+		maxRunCost: cfg.MaxRunCost,
+		nudges:     cfg.Nudges,
+		memory:     cfg.Memory,
+		hooks:      cfg.Hooks,
+		budget:     cfg.PromptBudget,
+		prefs:      cfg.Preferences,
+		toolStats:  cfg.ToolStats,
+		textTools:  cfg.TextToolCalls,
+		native:     !cfg.TextToolCalls,
 	}
 
 	if a.maxIter == 0 {
 		a.maxIter = 10
 	}
+	for k, v := range cfg.Defaults {
+		if err := a.SetDefault(k, v); err != nil {
+			return nil, err
+		}
+	}
 
 	// Register tools
-	for _, t := range cfg.Tools {
-		a.tools[t.Name()] = t
+	for _, t := range registry.Tools() {
+		a.toolOrder = append(a.toolOrder, t)
+		meta, _ := registry.Meta(t.Name())
 		a.toolDefs = append(a.toolDefs, llm.ToolDef{
 			Name:        t.Name(),
-			Description: t.Description(),
+			Description: t.Description() + metaNote(meta),
 			Parameters:  t.Parameters(),
 		})
 	}
 
-	a.systemPrompt = llm.BuildSystemPrompt(a.toolDefs)
+	a.buildSystemPrompts()
 	return a, nil
 }
 
 // Run executes the agent with the given user input
 func (a *Agent) Run(ctx context.Context, userInput string) (string, error) {
+	result, err := a.RunDetailed(ctx, userInput)
+	if err != nil {
+		return "", err
+	}
+	return result.Answer, nil
+}
+
+// RunDetailed is Run returning the whole run. On error the result still
+// holds the steps taken so far.
+func (a *Agent) RunDetailed(ctx context.Context, userInput string) (*RunResult, error) {
+	return a.RunEvents(ctx, userInput, nil)
+}
+
+// RunEvents is RunDetailed that also reports each step to emit as it
+// happens. Tool-call JSON from the LLM isn't sent as tokens.
+func (a *Agent) RunEvents(ctx context.Context, userInput string, emit func(Event)) (result *RunResult, err error) {
+	if emit == nil {
+		emit = func(Event) {}
+	}
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	// Build messages: system + history + new user input
+	// Build messages: system (with the summary of earlier turns, if
+	// any) + history + new user input
+	memoryUsage := a.compact(ctx, userInput)
 	messages := []llm.Message{
-		{Role: "system", Content: a.systemPrompt},
+		{Role: "system", Content: a.systemMessage()},
 	}
-	messages = append(messages, a.history...)
-	messages = append(messages, llm.Message{Role: "user", Content: userInput})
+	messages = append(messages, a.history[a.summarized:]...)
+	content, found := a.withContext(ctx, userInput)
+	messages = append(messages, llm.Message{Role: "user", Content: content})
 
-	// Add user message to history
+	// Add user message to history (without retrieved context, which is
+	// fetched afresh for each query)
 	a.history = append(a.history, llm.Message{Role: "user", Content: userInput})
 
-	// Agent loop
+	start := time.Now()
+	result = &RunResult{Query: userInput}
+	a.lastRun = result
+	a.runs = append(a.runs, result)
+	a.totals.Runs++
+	a.addUsage(result, memoryUsage)
+	defer func() {
+		result.Elapsed = time.Since(start)
+		a.hooks.final(ctx, result, err)
+	}()
+
+	// Agent loop. Steps that make no progress (an invalid reply, a repeated
+	// call with the same result) are counted to catch a stalled run early.
+	unproductive := 0
+	outputs := map[string]string{} // tool results by callKey
 	for i := 0; i < a.maxIter; i++ {
 		var resp *llm.Response
 		var err error
 
-		if sc, ok := a.client.(llm.StreamingChatClient); ok {
-			fmt.Print("\n[Agent] ")
-			resp, err = sc.ChatStream(ctx, messages, func(chunk string) {
-				fmt.Print(chunk)
-			})
-			fmt.Println()
-		} else {
-			resp, err = a.client.Chat(ctx, messages)
-			if err == nil {
-				fmt.Printf("\n[Agent] %s\n", resp.Content)
-			}
+		if err := a.checkSpend(result); err != nil {
+			return result, err
+		}
+		if err := a.nudges.wait(ctx, unproductive); err != nil {
+			return result, fmt.Errorf("agent iteration %d: %w", i, err)
+		}
+		a.hooks.iteration(ctx, i+1)
+
+		stepStart := time.Now()
+		resp, err = a.chat(ctx, messages, emit)
+		if errors.Is(err, llm.ErrToolsUnsupported) {
+			// Fall back to tool calls as JSON in the reply for good
+			a.hooks.note(ctx, NoteNative, fmt.Sprintf("%v; using text tool calls", err))
+			a.native = false
+			messages[0].Content = a.systemMessage()
+			resp, err = a.chat(ctx, messages, emit)
 		}
 		if err != nil {
-			return "", fmt.Errorf("agent iteration %d: %w", i, err)
+			return result, fmt.Errorf("agent iteration %d: %w", i, err)
 		}
+		a.addUsage(result, resp.Usage)
+		step := Step{Output: resp.Content, Usage: resp.Usage, Elapsed: time.Since(stepStart)}
 
 		// Check for tool calls
 		if len(resp.ToolCalls) > 0 {
 			tc := resp.ToolCalls[0] // Handle one tool call at a time
-			fmt.Printf("[Tool Call] %s: %v\n", tc.Name, tc.Params)
+			if t, ok := a.registry.Lookup(tc.Name); ok {
+				tc.Name = t.Name() // an alias
+				tc.Params = a.applyDefaults(t, tc.Params)
+			}
+			emit(Event{Type: EventToolCall, Tool: tc.Name, Params: tc.Params})
+			a.hooks.toolStart(ctx, tc.Name, tc.Params)
 
-			result, err := a.executeTool(ctx, tc)
+			call := &ToolCall{Name: tc.Name, Params: tc.Params}
+			toolStart := time.Now()
+			output, err := a.executeTool(ctx, tc)
+			call.Elapsed = time.Since(toolStart)
 			if err != nil {
-				result = fmt.Sprintf("Error: %v", err)
+				call.Error = err.Error()
+				output = fmt.Sprintf("Error: %v", err)
+			}
+			output = a.redactor.Redact(output)
+			call.Error = a.redactor.Redact(call.Error)
+			call.Result = output
+			returned, content := "returned", output
+			if a.pipe != nil {
+				call.Handle = a.pipe.store(output)
+				returned = "returned " + call.Handle
+				content = a.pipe.preview(call.Handle, output)
+			}
+			wrapped, findings := a.guard.Wrap("tool "+tc.Name, content)
+			call.Injection = findings
+			step.ToolCall = call
+			result.Steps = append(result.Steps, step)
+			emit(Event{Type: EventToolResult, Tool: tc.Name, Result: output, Error: call.Error})
+			a.hooks.toolEnd(ctx, call)
+			if len(findings) > 0 {
+				a.hooks.note(ctx, NoteGuard, fmt.Sprintf("%s output looks like instructions: %s", tc.Name, strings.Join(findings, "; ")))
+			}
+			if err := ctx.Err(); err != nil {
+				return result, fmt.Errorf("agent iteration %d: %w", i, err)
 			}
-			fmt.Printf("[Tool Result] %s\n", truncate(result, 500))
 
 			// Add assistant's tool call and tool result to messages
 			messages = append(messages, llm.Message{
 				Role:    "assistant",
 				Content: resp.Content,
 			})
+			key := callKey(tc.Name, tc.Params)
+			if prev, ok := outputs[key]; ok && prev == output {
+				unproductive++
+				if nudge := a.nudges.repeat(); nudge != "" {
+					step.Nudge = nudge
+					wrapped += "\n\n" + nudge
+					a.hooks.note(ctx, NoteNudge, nudge)
+				}
+			} else {
+				unproductive = 0
+			}
+			outputs[key] = output
+			result.Steps[len(result.Steps)-1] = step
 			messages = append(messages, llm.Message{
 				Role:    "tool",
-				Content: fmt.Sprintf("Tool '%s' returned:\n%s", tc.Name, result),
+				Content: fmt.Sprintf("Tool '%s' %s:\n%s", tc.Name, returned, wrapped),
 			})
+			if a.nudges.stalled(unproductive) {
+				return result, fmt.Errorf("%w: %d steps in a row without progress", ErrStalled, unproductive)
+			}
 			continue
 		}
 
 		// No tool call - this is the final answer
 		if resp.IsFinish || !strings.Contains(resp.Content, "{") {
+			result.Steps = append(result.Steps, step)
 			// Add final response to history
 			a.history = append(a.history, llm.Message{
 				Role:    "assistant",
 				Content: resp.Content,
 			})
-			return resp.Content, nil
+			result.Answer = resp.Content
+			if a.evidence {
+				result.Evidence = buildEvidence(result, found)
+			}
+			emit(Event{Type: EventAnswer, Text: resp.Content, Evidence: result.Evidence})
+			return result, nil
 		}
 
-		// Add response to messages and continue
+		// Neither: nudge the model toward a tool call or an answer
+		unproductive++
 		messages = append(messages, llm.Message{
 			Role:    "assistant",
 			Content: resp.Content,
 		})
+		if nudge := a.nudges.invalid(); nudge != "" {
+			step.Nudge = nudge
+			messages = append(messages, llm.Message{Role: "user", Content: nudge})
+			a.hooks.note(ctx, NoteNudge, nudge)
+		}
+		result.Steps = append(result.Steps, step)
+		if a.nudges.stalled(unproductive) {
+			return result, fmt.Errorf("%w: %d steps in a row without progress", ErrStalled, unproductive)
+		}
 	}
 
-	return "", fmt.Errorf("max iterations (%d) reached", a.maxIter)
+	return result, fmt.Errorf("max iterations (%d) reached", a.maxIter)
+}
+
+// withContext prepends retrieved context to the user input when auto-RAG is
+// on, and returns the context too. Retrieval errors are reported and the
+// question is sent alone.
+func (a *Agent) withContext(ctx context.Context, userInput string) (string, string) {
+	if a.retriever == nil {
+		return userInput, ""
+	}
+	found, err := a.retriever.Retrieve(ctx, userInput)
+	if err != nil {
+		a.hooks.note(ctx, NoteContext, fmt.Sprintf("retrieval failed: %v", err))
+		return userInput, ""
+	}
+	if found == "" {
+		return userInput, ""
+	}
+	found = a.redactor.Redact(found)
+	a.hooks.note(ctx, NoteContext, fmt.Sprintf("added %d characters of wiki results", len(found)))
+	wrapped, findings := a.guard.Wrap("wiki search", found)
+	if len(findings) > 0 {
+		a.hooks.note(ctx, NoteGuard, "wiki results look like instructions: "+strings.Join(findings, "; "))
+	}
+	return "Wiki results retrieved automatically for this question. They may be unrelated: use them only if they help, cite their Source lines when you do, and call a tool if you need more.\n\n" +
+		wrapped + "\nQuestion: " + userInput, found
 }
 
 // executeTool runs the specified tool
 func (a *Agent) executeTool(ctx context.Context, tc llm.ToolCallParse) (string, error) {
-	tool, ok := a.tools[tc.Name]
+	tool, ok := a.registry.Lookup(tc.Name)
+	if !ok && tc.Name == describeToolName && len(a.pruned) > 0 {
+		return a.describeTool(tc.Params)
+	}
 	if !ok {
 		return "", fmt.Errorf("unknown tool: %s", tc.Name)
 	}
-	return tool.Call(ctx, tc.Params)
+	if a.disabled[tc.Name] {
+		return "", fmt.Errorf("tool %s is disabled", tc.Name)
+	}
+	params, err := a.pipe.resolve(tc.Params)
+	if err != nil {
+		return "", err
+	}
+	if a.policy != nil {
+		meta, _ := a.registry.Meta(tc.Name)
+		if err := a.policy.Check(tc.Name, meta, params); err != nil {
+			return "", fmt.Errorf("denied by policy: %w", err)
+		}
+	}
+	start := time.Now()
+	output, err := tool.Call(ctx, params)
+	if ctx.Err() == nil { // a cancelled run says nothing about the tool
+		a.toolStats.Record(tc.Name, time.Since(start), err != nil)
+	}
+	return output, err
+}
+
+// Tools returns the registered tools in registration order, enabled or not
+func (a *Agent) Tools() []tools.Tool {
+	return a.toolOrder
+}
+
+// Registry returns the agent's tools with their metadata
+func (a *Agent) Registry() *tools.Registry {
+	return a.registry
+}
+
+// ToolEnabled reports whether a registered tool is offered to the LLM
+func (a *Agent) ToolEnabled(name string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	t, ok := a.registry.Lookup(name)
+	return ok && !a.disabled[t.Name()]
+}
+
+// SetToolEnabled enables or disables a registered tool. Disabled tools are
+// left out of the system prompt and refuse calls until enabled again.
+func (a *Agent) SetToolEnabled(name string, enabled bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	t, ok := a.registry.Lookup(name)
+	if !ok {
+		return fmt.Errorf("unknown tool: %s", name)
+	}
+	name = t.Name()
+	if enabled {
+		delete(a.disabled, name)
+	} else {
+		a.disabled[name] = true
+	}
+
+	a.buildSystemPrompts()
+	return nil
+}
+
+// enabledDefs returns the definitions of the tools offered to the LLM
+func (a *Agent) enabledDefs() []llm.ToolDef {
+	var defs []llm.ToolDef
+	for _, def := range a.toolDefs {
+		if !a.disabled[def.Name] {
+			defs = append(defs, def)
+		}
+	}
+	return defs
+}
+
+// metaNote tells the LLM whether a tool's calls change state, how risky
+// they are and how slow, so it can plan: look before changing anything,
+// and leave slow tools for when a faster one won't do
+func metaNote(m tools.Meta) string {
+	var notes []string
+	switch w := m.ReadOnlyWhen; {
+	case m.ReadOnly:
+		notes = append(notes, "read-only")
+	case w != nil && len(w.Values) > 0 && len(w.Values) <= 4:
+		notes = append(notes, fmt.Sprintf("read-only when %s is %s", w.Param, strings.Join(w.Values, " or ")))
+	}
+	if !m.ReadOnly && m.Risk == tools.RiskHigh {
+		notes = append(notes, "may change state, high risk")
+	}
+	if m.Latency >= 2*time.Second {
+		notes = append(notes, fmt.Sprintf("slow, about %s per call", m.Latency))
+	}
+	if len(notes) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(notes, "; ") + "]"
+}
+
+// buildSystemPrompts builds the system prompts offering the enabled tools,
+// pruned to the PromptBudget, with the sections of the enabled defenses and
+// features
+func (a *Agent) buildSystemPrompts() {
+	extra := a.pipe.instructions() + a.guard.Instructions()
+	defs := a.fitBudget(a.enabledDefs(), extra)
+	a.offered = defs
+	a.systemPrompt = llm.BuildSystemPrompt(defs) + extra
+	a.nativePrompt = llm.BuildNativeSystemPrompt(defs) + extra
+}
+
+// nativeClient returns the client when runs use its native tool calling
+func (a *Agent) nativeClient() (llm.NativeToolClient, bool) {
+	if !a.native {
+		return nil, false
+	}
+	nc, ok := a.client.(llm.NativeToolClient)
+	return nc, ok
+}
+
+// systemMessage is the content of the system message starting each run
+func (a *Agent) systemMessage() string {
+	prompt := a.systemPrompt
+	if _, ok := a.nativeClient(); ok {
+		prompt = a.nativePrompt
+	}
+	return prompt + a.defaultsNote() + a.prefs.prompt() + a.summaryNote()
+}
+
+// chat sends messages to the LLM, offering the enabled tools natively when
+// the client can take them, and reports the reply, streaming it when the
+// client can
+func (a *Agent) chat(ctx context.Context, messages []llm.Message, emit func(Event)) (resp *llm.Response, err error) {
+	a.hooks.llmStart(ctx, messages)
+	defer func() { a.hooks.llmEnd(ctx, resp, err) }()
+	stream := func(chunk string) {
+		emit(Event{Type: EventToken, Text: chunk})
+		a.hooks.llmChunk(ctx, chunk)
+	}
+	if nc, ok := a.nativeClient(); ok {
+		return nc.ChatWithTools(ctx, messages, a.offered, stream)
+	}
+	if sc, ok := a.client.(llm.StreamingChatClient); ok {
+		return sc.ChatStream(ctx, messages, stream)
+	}
+	resp, err = a.client.Chat(ctx, messages)
+	if err == nil && len(resp.ToolCalls) == 0 {
+		emit(Event{Type: EventToken, Text: resp.Content})
+		a.hooks.llmChunk(ctx, resp.Content)
+	}
+	return resp, err
+}
+
+// History returns a copy of the conversation history
+func (a *Agent) History() []llm.Message {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]llm.Message(nil), a.history...)
+}
+
+// LastRun returns the record of the most recent run (nil before the first).
+// It is only complete once that run has returned.
+func (a *Agent) LastRun() *RunResult {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.lastRun
+}
+
+// Runs returns the runs of the current conversation (since the last
+// ClearHistory), oldest first
+func (a *Agent) Runs() []*RunResult {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]*RunResult(nil), a.runs...)
+}
+
+// UndoLastRun removes the last exchange (query and answer, if any) from the
+// conversation and returns its query, so it can be run again; "" if the
+// conversation is empty
+func (a *Agent) UndoLastRun() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.runs) == 0 {
+		return ""
+	}
+	last := a.runs[len(a.runs)-1]
+	a.runs = a.runs[:len(a.runs)-1]
+	for i := len(a.history) - 1; i >= 0; i-- {
+		if a.history[i].Role == "user" {
+			a.history = a.history[:i]
+			break
+		}
+	}
+	if a.summarized > len(a.history) {
+		a.resetSummary()
+	}
+	a.lastRun = nil
+	if len(a.runs) > 0 {
+		a.lastRun = a.runs[len(a.runs)-1]
+	}
+	return last.Query
+}
+
+// SetClient switches the LLM client for later runs and returns the previous
+// one
+func (a *Agent) SetClient(client llm.ChatClient) llm.ChatClient {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	prev := a.client
+	a.client = client
+	a.native = !a.textTools // the new model may have tool support
+	return prev
+}
+
+// addUsage counts tokens used by the run in its result and the totals
+func (a *Agent) addUsage(result *RunResult, usage llm.Usage) {
+	result.Usage.Add(usage)
+	a.totals.Usage.Add(usage)
+	if a.price != nil {
+		cost := a.price.Cost(usage)
+		result.Cost += cost
+		a.totals.Cost += cost
+	}
+}
+
+// checkSpend fails a run before its next LLM call once a spend limit is
+// reached
+func (a *Agent) checkSpend(result *RunResult) error {
+	switch {
+	case a.maxCost > 0 && a.totals.Cost >= a.maxCost:
+		return fmt.Errorf("%w: $%.4f spent of $%.2f", ErrSpendLimit, a.totals.Cost, a.maxCost)
+	case a.maxRunCost > 0 && result.Cost >= a.maxRunCost:
+		return fmt.Errorf("%w: this run cost $%.4f of $%.2f", ErrSpendLimit, result.Cost, a.maxRunCost)
+	}
+	return nil
+}
+
+// Totals returns what the agent's runs have used since it was created;
+// ClearHistory doesn't reset it
+func (a *Agent) Totals() Totals {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.totals
+}
+
+// ToolStats returns the tool call stats the agent records into, nil if none
+func (a *Agent) ToolStats() *tools.Stats {
+	return a.toolStats
 }
 
 // ClearHistory clears the conversation history
@@ -160,6 +759,9 @@ func (a *Agent) ClearHistory() {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 	a.history = nil
+	a.runs = nil
+	a.pipe.clear()
+	a.resetSummary()
 }
 
 func truncate(s string, maxLen int) string {
@@ -0,0 +1,145 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rathore/langchain-agent/llm"
+)
+
+// describeToolName is the built-in tool that gives the LLM the full
+// definition of a tool pruned from the system prompt
+const describeToolName = "describe_tool"
+
+// PromptBudget bounds the system prompt for models with a small context
+// window and agents with many tools. When the prompt with every tool
+// definition is over Tokens, the largest definitions are cut to a line
+// without parameters, largest first, until it fits, and the LLM is given
+// describe_tool to get a pruned tool's full definition before calling it.
+type PromptBudget struct {
+	// Tokens is the most the system prompt may take, tool definitions
+	// included (0: no limit). Session defaults, preferences and the
+	// conversation summary come on top.
+	Tokens int
+
+	// Tokenizer counts the tokens (nil: about 4 bytes a token)
+	Tokenizer Tokenizer
+}
+
+func (b PromptBudget) tokenizer() Tokenizer {
+	if b.Tokenizer == nil {
+		return byteTokenizer{}
+	}
+	return b.Tokenizer
+}
+
+// PromptSize is the size of the system prompt, see SystemPromptSize
+type PromptSize struct {
+	Tokens int      // the system prompt with the offered tool definitions
+	Budget int      // PromptBudget.Tokens
+	Pruned []string // tools offered without parameters, to fit the budget
+}
+
+// OverBudget reports whether the prompt is over the budget even with the
+// tools pruned
+func (s PromptSize) OverBudget() bool {
+	return s.Budget > 0 && s.Tokens > s.Budget
+}
+
+// SystemPromptSize returns the size of the system prompt and the tools
+// pruned from it
+func (a *Agent) SystemPromptSize() PromptSize {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return PromptSize{Tokens: a.promptTokens, Budget: a.budget.Tokens, Pruned: append([]string(nil), a.pruned...)}
+}
+
+// fitBudget returns defs with as many of them pruned, largest first, as
+// it takes for the system prompt (with extra) to fit the budget, plus
+// describe_tool if any were. It records the prompt's size and the pruned
+// tools.
+func (a *Agent) fitBudget(defs []llm.ToolDef, extra string) []llm.ToolDef {
+	tok := a.budget.tokenizer()
+	size := func(defs []llm.ToolDef) int {
+		return tok.CountTokens(llm.BuildSystemPrompt(defs) + extra)
+	}
+	a.pruned = nil
+	a.promptTokens = size(defs)
+	if a.budget.Tokens <= 0 || a.promptTokens <= a.budget.Tokens {
+		return defs
+	}
+
+	order := make([]int, len(defs))
+	lengths := make([]int, len(defs))
+	for i, def := range defs {
+		order[i] = i
+		data, _ := json.Marshal(def)
+		lengths[i] = len(data)
+	}
+	sort.SliceStable(order, func(i, j int) bool { return lengths[order[i]] > lengths[order[j]] })
+
+	offered := append([]llm.ToolDef(nil), defs...)
+	offered = append(offered, llm.ToolDef{}) // describe_tool, filled in below
+	for _, i := range order {
+		offered[i] = briefDef(defs[i])
+		a.pruned = append(a.pruned, defs[i].Name)
+		offered[len(offered)-1] = describeToolDef(a.pruned)
+		if a.promptTokens = size(offered); a.promptTokens <= a.budget.Tokens {
+			break
+		}
+	}
+	sort.Strings(a.pruned)
+	return offered
+}
+
+// briefDef is def without parameters and with the first sentence of its
+// description
+func briefDef(def llm.ToolDef) llm.ToolDef {
+	desc := def.Description
+	if i := strings.Index(desc, ". "); i > 0 {
+		desc = desc[:i+1]
+	}
+	if i := strings.IndexByte(desc, '\n'); i > 0 {
+		desc = desc[:i]
+	}
+	if len(desc) > 120 {
+		desc = desc[:117] + "..."
+	}
+	return llm.ToolDef{
+		Name:        def.Name,
+		Description: desc + " Call describe_tool for its parameters first.",
+		Parameters:  map[string]any{"type": "object"},
+	}
+}
+
+func describeToolDef(pruned []string) llm.ToolDef {
+	return llm.ToolDef{
+		Name:        describeToolName,
+		Description: "Get the description and parameters of a tool listed without them",
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"name": map[string]any{"type": "string", "enum": append([]string(nil), pruned...)},
+			},
+			"required": []string{"name"},
+		},
+	}
+}
+
+// describeTool returns the full definition of the enabled tool named in
+// params, for describe_tool
+func (a *Agent) describeTool(params map[string]any) (string, error) {
+	name, _ := params["name"].(string)
+	if t, ok := a.registry.Lookup(name); ok {
+		name = t.Name()
+	}
+	for _, def := range a.enabledDefs() {
+		if def.Name == name {
+			data, err := json.MarshalIndent(def, "", "  ")
+			return string(data), err
+		}
+	}
+	return "", fmt.Errorf("unknown tool: %q", name)
+}
@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rathore/langchain-agent/llm"
+	"github.com/rathore/langchain-agent/tools"
+)
+
+func TestPromptBudget(t *testing.T) {
+	long := "Query the inventory. " + strings.Repeat("Supports filters by rack, row and owner. ", 30)
+	toolset := func() []tools.Tool {
+		return []tools.Tool{
+			&MockTool{name: "small", description: "Do a small thing", result: "ok"},
+			&MockTool{name: "big", description: long, result: "3 servers"},
+		}
+	}
+	full, _ := New(Config{Client: &MockLLMClient{}, Tools: toolset(), PromptBudget: PromptBudget{Tokenizer: wordTokenizer{}}})
+	size := full.SystemPromptSize()
+	if size.Tokens == 0 || len(size.Pruned) != 0 || size.OverBudget() {
+		t.Fatalf("unbounded SystemPromptSize() = %+v", size)
+	}
+
+	client := &MockLLMClient{responses: []*llm.Response{
+		{ToolCalls: []llm.ToolCallParse{{Name: "describe_tool", Params: map[string]any{"name": "big"}}}},
+		{ToolCalls: []llm.ToolCallParse{{Name: "big", Params: map[string]any{"input": "rack 4"}}}},
+		{Content: "3 servers.", IsFinish: true},
+	}}
+	ag, _ := New(Config{Client: client, Tools: toolset(), PromptBudget: PromptBudget{Tokens: size.Tokens - 1, Tokenizer: wordTokenizer{}}})
+	size = ag.SystemPromptSize()
+	if len(size.Pruned) != 1 || size.Pruned[0] != "big" || size.OverBudget() {
+		t.Fatalf("SystemPromptSize() = %+v, want big pruned", size)
+	}
+	result, err := ag.RunDetailed(t.Context(), "servers in rack 4?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	system := client.messages[0][0].Content
+	if strings.Contains(system, "Supports filters") || !strings.Contains(system, `"name": "describe_tool"`) || !strings.Contains(system, "Do a small thing") {
+		t.Errorf("system prompt:\n%s", system)
+	}
+	described := result.Steps[0].ToolCall
+	if described.Error != "" || !strings.Contains(described.Result, "Supports filters by rack") || !strings.Contains(described.Result, `"input"`) {
+		t.Errorf("describe_tool = %+v", described)
+	}
+	if result.Steps[1].ToolCall.Result != "3 servers" {
+		t.Errorf("big = %+v", result.Steps[1].ToolCall)
+	}
+
+	// Without room for any definition, every tool is pruned
+	ag, _ = New(Config{Client: client, Tools: toolset(), PromptBudget: PromptBudget{Tokens: 10}})
+	if size := ag.SystemPromptSize(); len(size.Pruned) != 2 || !size.OverBudget() {
+		t.Errorf("SystemPromptSize() = %+v, want both pruned and over budget", size)
+	}
+
+	// describe_tool only exists while tools are pruned
+	if _, err := full.executeTool(t.Context(), llm.ToolCallParse{Name: "describe_tool", Params: map[string]any{"name": "big"}}); err == nil {
+		t.Error("describe_tool ran without pruned tools")
+	}
+}
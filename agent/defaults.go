@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/rathore/langchain-agent/tools"
+)
+
+// defaultParams are the tool parameters a session default fills besides the
+// one named like it, e.g. the kube context of Kubernetes MCP servers
+var defaultParams = map[string][]string{
+	"cluster": {"context", "kube_context"},
+}
+
+var defaultKeyRe = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// SetDefault sets a session default, such as the namespace, cluster or host
+// the user is working on; "" removes it. A tool call that leaves out a
+// parameter named like a default (for cluster also context and
+// kube_context) gets the default's value, and the system prompt lists the
+// defaults so the model can use them in commands, e.g. kubectl -n.
+func (a *Agent) SetDefault(key, value string) error {
+	if !defaultKeyRe.MatchString(key) {
+		return fmt.Errorf("invalid default name %q (use lowercase letters, digits and _, e.g. namespace)", key)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if value == "" {
+		delete(a.defaults, key)
+		return nil
+	}
+	if a.defaults == nil {
+		a.defaults = map[string]string{}
+	}
+	a.defaults[key] = value
+	return nil
+}
+
+// Defaults returns a copy of the session defaults
+func (a *Agent) Defaults() map[string]string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make(map[string]string, len(a.defaults))
+	for k, v := range a.defaults {
+		out[k] = v
+	}
+	return out
+}
+
+// ParseDefaults parses "key=value" pairs, separated by commas or spaces, as
+// taken by /context set and --context
+func ParseDefaults(s string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, pair := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !ok || !defaultKeyRe.MatchString(key) {
+			return nil, fmt.Errorf("invalid default %q (use key=value, e.g. namespace=prod)", pair)
+		}
+		out[key] = strings.TrimSpace(value)
+	}
+	return out, nil
+}
+
+// defaultsNote is the system prompt line listing the session defaults
+func (a *Agent) defaultsNote() string {
+	if len(a.defaults) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(a.defaults))
+	for k, v := range a.defaults {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return fmt.Sprintf("\n\nSESSION DEFAULTS set by the user: %s. Use them whenever the user doesn't name another one, including in commands (e.g. kubectl -n <namespace>, --context <cluster>).", strings.Join(pairs, ", "))
+}
+
+// applyDefaults fills the parameters of a call to t that the model left out
+// and that a session default covers, returning params (allocated if nil).
+// For a Dispatcher such as an MCP tool, the arguments it passes on are
+// filled instead.
+func (a *Agent) applyDefaults(t tools.Tool, params map[string]any) map[string]any {
+	if len(a.defaults) == 0 {
+		return params
+	}
+	if params == nil {
+		params = map[string]any{}
+	}
+	for {
+		u, ok := t.(interface{ Unwrap() tools.Tool })
+		if !ok {
+			break
+		}
+		t = u.Unwrap() // registered under a namespaced name, or with limits
+	}
+	props, _ := t.Parameters()["properties"].(map[string]any)
+	args := params
+	if d, ok := t.(tools.Dispatcher); ok {
+		if props, args = d.CallArguments(params); props == nil {
+			return params
+		}
+	}
+	for key, value := range a.defaults {
+		for _, name := range append([]string{key}, defaultParams[key]...) {
+			if _, declared := props[name]; !declared {
+				continue
+			}
+			if v, ok := args[name]; ok && v != nil && v != "" {
+				continue
+			}
+			args[name] = value
+		}
+	}
+	return params
+}
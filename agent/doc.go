@@ -0,0 +1,27 @@
+// Package agent runs the tool-calling loop: it sends the conversation and
+// tool definitions to an LLM, runs the tool the LLM asks for, feeds the
+// result back and repeats until the LLM answers.
+//
+// Embedding the agent in another program takes a client, some tools and a
+// Config:
+//
+//	client, err := llm.NewClient("qwen2.5:7b", "http://localhost:11434")
+//	...
+//	ag, err := agent.New(agent.Config{
+//		Client: client,
+//		Tools:  []tools.Tool{&tools.ShellTool{}, myTool},
+//	})
+//	...
+//	result, err := ag.RunEvents(ctx, "how much disk is left?", func(e agent.Event) {
+//		// e.Type is EventToken, EventToolCall, EventToolResult or EventAnswer
+//	})
+//
+// RunStream delivers the same events on a channel, ending with the answer
+// or the error, for web frontends that pass them on as they come.
+//
+// The agent prints nothing: Config.Hooks follow every run instead, for
+// showing its progress, logging, metrics or a UI.
+//
+// An Agent keeps the conversation history between runs (ClearHistory starts
+// over) and is safe for concurrent use; runs are serialized.
+package agent
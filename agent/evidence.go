@@ -0,0 +1,307 @@
+package agent
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Evidence ties the claims of a final answer to the tool results and wiki
+// text of the run that support them (see Config.Evidence). It is computed
+// from the step trace by word overlap, not asked of the model, so a claim
+// the model made up shows as unsupported.
+type Evidence struct {
+	Claims    []Claim `json:"claims"`
+	Supported int     `json:"supported"` // claims with support
+	// Confidence is "high" (most claims supported and no tool failed),
+	// "medium", "low", or "none" when the run saw no tool output or wiki
+	// text at all
+	Confidence string `json:"confidence"`
+}
+
+// Claim is a sentence, list item or command of the answer
+type Claim struct {
+	Text    string     `json:"text"`
+	Support []Citation `json:"support,omitempty"` // best first
+}
+
+// Citation is where a claim's words were found
+type Citation struct {
+	Source  string  `json:"source"`         // e.g. "ssh web-01: df -h /", "wiki: Deploy Guide (Source: ...)"
+	Step    int     `json:"step,omitempty"` // 1-based step of the tool call; 0 for auto-RAG context
+	Excerpt string  `json:"excerpt"`        // the matching lines
+	Score   float64 `json:"score"`          // share of the claim's words found, specific ones weighing more
+}
+
+const (
+	// minSupport is the score a citation needs
+	minSupport = 0.5
+	// maxCitations is the most citations kept per claim
+	maxCitations = 2
+)
+
+// evidenceSource is a tool result or wiki chunk the run saw
+type evidenceSource struct {
+	label string
+	step  int
+	lines []string
+}
+
+// wikiResultStart matches the first line of each result in wiki output
+var wikiResultStart = regexp.MustCompile(`(?m)^\d+\. \[(?:TEXT|DIAGRAM)\] `)
+
+// buildEvidence matches the claims of result's answer against its tool
+// results and context, the auto-RAG text sent with the query
+func buildEvidence(result *RunResult, context string) *Evidence {
+	var sources []evidenceSource
+	failed := 0
+	for i, step := range result.Steps {
+		call := step.ToolCall
+		if call == nil {
+			continue
+		}
+		if call.Error != "" {
+			failed++
+			continue
+		}
+		sources = append(sources, splitSources(toolLabel(call), i+1, call.Result)...)
+	}
+	if context != "" {
+		sources = append(sources, splitSources("wiki context", 0, context)...)
+	}
+
+	ev := &Evidence{Claims: []Claim{}}
+	for _, text := range splitClaims(result.Answer) {
+		claim := Claim{Text: text}
+		words := evidenceWords(text)
+		if weight(words) < 2 {
+			continue // too short to check, e.g. "Done."
+		}
+		for _, src := range sources {
+			if c, ok := bestCitation(words, src); ok {
+				claim.Support = append(claim.Support, c)
+			}
+		}
+		sort.SliceStable(claim.Support, func(i, j int) bool { return claim.Support[i].Score > claim.Support[j].Score })
+		if len(claim.Support) > maxCitations {
+			claim.Support = claim.Support[:maxCitations]
+		}
+		if len(claim.Support) > 0 {
+			ev.Supported++
+		}
+		ev.Claims = append(ev.Claims, claim)
+	}
+
+	share := 0.0
+	if len(ev.Claims) > 0 {
+		share = float64(ev.Supported) / float64(len(ev.Claims))
+	}
+	switch {
+	case len(sources) == 0:
+		ev.Confidence = "none"
+	case share >= 0.75 && failed == 0:
+		ev.Confidence = "high"
+	case share >= 0.4:
+		ev.Confidence = "medium"
+	default:
+		ev.Confidence = "low"
+	}
+	return ev
+}
+
+// toolLabel names a tool call by its tool and main parameters
+func toolLabel(call *ToolCall) string {
+	var args []string
+	for _, key := range []string{"host", "command", "query", "page", "tool_name", "name"} {
+		if v, ok := call.Params[key].(string); ok && v != "" {
+			args = append(args, v)
+		}
+	}
+	if len(args) == 0 {
+		return call.Name
+	}
+	return truncate(call.Name+" "+strings.Join(args, ": "), 80)
+}
+
+// splitSources splits wiki output into one source per result, labelled
+// with its page and Source line; other output is one source
+func splitSources(label string, step int, text string) []evidenceSource {
+	starts := wikiResultStart.FindAllStringIndex(text, -1)
+	if len(starts) == 0 {
+		return []evidenceSource{{label: label, step: step, lines: nonEmptyLines(text)}}
+	}
+	var out []evidenceSource
+	for i, s := range starts {
+		end := len(text)
+		if i+1 < len(starts) {
+			end = starts[i+1][0]
+		}
+		lines := nonEmptyLines(text[s[0]:end])
+		title := wikiResultStart.ReplaceAllString(lines[0], "")
+		if j := strings.LastIndex(title, " (score:"); j > 0 {
+			title = title[:j]
+		}
+		src := evidenceSource{label: "wiki: " + title, step: step}
+		for _, line := range lines[1:] {
+			if cite, ok := strings.CutPrefix(line, "Source: "); ok {
+				src.label += " (" + cite + ")"
+				continue
+			}
+			src.lines = append(src.lines, line)
+		}
+		out = append(out, src)
+	}
+	return out
+}
+
+func nonEmptyLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// bestCitation scores the claim against each line of src and each pair of
+// adjacent lines, for claims that draw on both
+func bestCitation(claim map[string]int, src evidenceSource) (Citation, bool) {
+	best := Citation{Source: src.label, Step: src.step}
+	for i := range src.lines {
+		for n := 1; n <= 2 && i+n <= len(src.lines); n++ {
+			excerpt := strings.Join(src.lines[i:i+n], " ")
+			if score := overlap(claim, evidenceWords(excerpt)); score > best.Score+0.05 {
+				best.Score, best.Excerpt = score, excerpt
+			}
+		}
+	}
+	best.Score = float64(int(best.Score*100+0.5)) / 100
+	best.Excerpt = truncate(best.Excerpt, 160)
+	return best, best.Score >= minSupport
+}
+
+// overlap is the weighted share of the claim's words in the excerpt
+func overlap(claim, excerpt map[string]int) float64 {
+	total, found := 0, 0
+	for word, w := range claim {
+		total += w
+		if excerpt[word] > 0 {
+			found += w
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(found) / float64(total)
+}
+
+// weight is the total weight of a claim's words
+func weight(words map[string]int) int {
+	total := 0
+	for _, w := range words {
+		total += w
+	}
+	return total
+}
+
+// evidenceWords returns the words of text that can support a claim, with
+// their weight: 3 for specific tokens (numbers, hosts, paths, versions), 1
+// for other words. Stop words are left out.
+func evidenceWords(text string) map[string]int {
+	words := map[string]int{}
+	for _, token := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("._/:%-@", r)
+	}) {
+		token = strings.Trim(token, ".:-/_@")
+		if token == "" || stopWords[token] {
+			continue
+		}
+		w := 1
+		if strings.ContainsAny(token, "0123456789./:_%@") || strings.Contains(token, "-") {
+			w = 3
+		}
+		words[token] = w
+	}
+	return words
+}
+
+// splitClaims splits an answer into sentences, list items and code lines,
+// dropping Markdown syntax
+func splitClaims(answer string) []string {
+	var claims []string
+	inCode := false
+	for _, line := range strings.Split(answer, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") {
+			inCode = !inCode
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		if inCode {
+			claims = append(claims, line)
+			continue
+		}
+		line = strings.TrimLeft(line, "#>*-+ ")
+		if i := strings.Index(line, ". "); i > 0 && i <= 3 && strings.Trim(line[:i], "0123456789") == "" {
+			line = line[i+2:] // numbered list item
+		}
+		line = strings.NewReplacer("**", "", "__", "", "`", "").Replace(line)
+		claims = append(claims, splitSentences(line)...)
+	}
+	return claims
+}
+
+// splitSentences splits at ". ", "! " and "? " followed by a capital letter,
+// so "e.g. this" and "v1.2 is" stay whole
+func splitSentences(line string) []string {
+	var out []string
+	start := 0
+	runes := []rune(line)
+	for i := 0; i+2 < len(runes); i++ {
+		if strings.ContainsRune(".!?", runes[i]) && runes[i+1] == ' ' && unicode.IsUpper(runes[i+2]) {
+			out = append(out, strings.TrimSpace(string(runes[start:i+1])))
+			start = i + 2
+		}
+	}
+	if rest := strings.TrimSpace(string(runes[start:])); rest != "" {
+		out = append(out, rest)
+	}
+	return out
+}
+
+var stopWords = func() map[string]bool {
+	m := map[string]bool{}
+	for _, w := range strings.Fields(`a an the and or but if then so of to in on at by for with from as
+		is are was were be been being it its this that these those there here
+		i you we they he she them your our my me us do does did done has have had
+		not no can could would should will may might must shall just also only
+		very more most some any all each both than too about into over under
+		what which who whom when where why how use used using run ran`) {
+		m[w] = true
+	}
+	return m
+}()
+
+// WriteText writes the evidence as a list of claims with their sources
+func (e *Evidence) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "[Evidence] confidence %s: %d of %d claims supported by tool output or wiki text\n", e.Confidence, e.Supported, len(e.Claims))
+	for i, c := range e.Claims {
+		fmt.Fprintf(w, "  %d. %s\n", i+1, truncate(c.Text, 200))
+		if len(c.Support) == 0 {
+			fmt.Fprintf(w, "     ✗ no supporting tool output or wiki text\n")
+		}
+		for _, s := range c.Support {
+			where := "auto-RAG"
+			if s.Step > 0 {
+				where = fmt.Sprintf("step %d", s.Step)
+			}
+			fmt.Fprintf(w, "     ← %s, %s: %q\n", where, s.Source, s.Excerpt)
+		}
+	}
+}
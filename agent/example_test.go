@@ -0,0 +1,69 @@
+package agent_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rathore/langchain-agent/agent"
+	"github.com/rathore/langchain-agent/llm"
+	"github.com/rathore/langchain-agent/tools"
+)
+
+// upperTool is a tool defined by the embedding program
+type upperTool struct{}
+
+func (upperTool) Name() string        { return "upper" }
+func (upperTool) Description() string { return "Upper-case a text" }
+func (upperTool) Parameters() map[string]any {
+	return map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"text": map[string]any{"type": "string"}},
+		"required":   []string{"text"},
+	}
+}
+
+func (upperTool) Call(ctx context.Context, params map[string]any) (string, error) {
+	text, _ := params["text"].(string)
+	return strings.ToUpper(text), nil
+}
+
+// cannedClient stands in for a real LLM (llm.NewClient, llm.NewGeminiClient)
+type cannedClient struct{ replies []*llm.Response }
+
+func (c *cannedClient) Chat(ctx context.Context, messages []llm.Message) (*llm.Response, error) {
+	reply := c.replies[0]
+	c.replies = c.replies[1:]
+	return reply, nil
+}
+
+func Example() {
+	client := &cannedClient{replies: []*llm.Response{
+		{ToolCalls: []llm.ToolCallParse{{Name: "upper", Params: map[string]any{"text": "hello"}}}},
+		{Content: "It is HELLO.", IsFinish: true},
+	}}
+	ag, err := agent.New(agent.Config{
+		Client: client,
+		Tools:  []tools.Tool{upperTool{}},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := ag.RunEvents(context.Background(), "shout hello", func(e agent.Event) {
+		switch e.Type {
+		case agent.EventToolCall:
+			fmt.Println("calling", e.Tool, e.Params)
+		case agent.EventToolResult:
+			fmt.Println("got", e.Result)
+		}
+	})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(result.Answer, len(result.Steps), "steps")
+	// Output:
+	// calling upper map[text:hello]
+	// got HELLO
+	// It is HELLO. 2 steps
+}
@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/rathore/langchain-agent/llm"
+)
+
+// Kinds of Hooks.OnNote notes
+const (
+	NoteContext = "context" // auto-RAG added wiki results, or couldn't
+	NoteGuard   = "guard"   // a tool result or wiki results look like instructions
+	NoteNudge   = "nudge"   // the model was nudged (see Nudges)
+	NoteMemory  = "memory"  // earlier turns were summarized, or couldn't be
+	NoteNative  = "native"  // native tool calling failed; text tool calls from now on
+)
+
+// Hooks are called as every run of an agent happens, for programs that
+// show its progress or build a UI, logs or metrics on it; the agent prints
+// nothing itself. Any of them may be nil. They run on the run's goroutine
+// while the agent is locked, so they must not call the agent's methods,
+// and should return quickly.
+type Hooks struct {
+	// OnIteration is called at the start of each step of the loop, before
+	// its LLM call, with the step number from 1
+	OnIteration func(ctx context.Context, iteration int)
+
+	// OnLLMStart is called with the messages about to be sent to the LLM
+	OnLLMStart func(ctx context.Context, messages []llm.Message)
+
+	// OnLLMChunk is called with each piece of reply text as the LLM
+	// streams it, or once with the whole reply from a client that doesn't
+	// stream (tool-call JSON from such a client isn't sent)
+	OnLLMChunk func(ctx context.Context, chunk string)
+
+	// OnLLMEnd is called when the LLM has replied, with the whole reply,
+	// or with the error the call failed with
+	OnLLMEnd func(ctx context.Context, resp *llm.Response, err error)
+
+	// OnToolStart is called before a tool runs, with the parameters it gets
+	OnToolStart func(ctx context.Context, tool string, params map[string]any)
+
+	// OnToolEnd is called when a tool returns, with the call's record: its
+	// (redacted) result or error and how long it took
+	OnToolEnd func(ctx context.Context, call ToolCall)
+
+	// OnNote is called with what else happened that a user may want to
+	// know, such as a nudge sent to the model; kind is one of the Note
+	// constants
+	OnNote func(ctx context.Context, kind, text string)
+
+	// OnFinal is called when a run ends: with its answer, or with the
+	// error it failed with and the steps taken so far
+	OnFinal func(ctx context.Context, result *RunResult, err error)
+}
+
+func (h Hooks) iteration(ctx context.Context, iteration int) {
+	if h.OnIteration != nil {
+		h.OnIteration(ctx, iteration)
+	}
+}
+
+func (h Hooks) llmStart(ctx context.Context, messages []llm.Message) {
+	if h.OnLLMStart != nil {
+		h.OnLLMStart(ctx, messages)
+	}
+}
+
+func (h Hooks) llmChunk(ctx context.Context, chunk string) {
+	if h.OnLLMChunk != nil {
+		h.OnLLMChunk(ctx, chunk)
+	}
+}
+
+func (h Hooks) llmEnd(ctx context.Context, resp *llm.Response, err error) {
+	if h.OnLLMEnd != nil {
+		h.OnLLMEnd(ctx, resp, err)
+	}
+}
+
+func (h Hooks) toolStart(ctx context.Context, tool string, params map[string]any) {
+	if h.OnToolStart != nil {
+		h.OnToolStart(ctx, tool, params)
+	}
+}
+
+func (h Hooks) toolEnd(ctx context.Context, call *ToolCall) {
+	if h.OnToolEnd != nil {
+		h.OnToolEnd(ctx, *call)
+	}
+}
+
+func (h Hooks) note(ctx context.Context, kind, text string) {
+	if h.OnNote != nil {
+		h.OnNote(ctx, kind, text)
+	}
+}
+
+func (h Hooks) final(ctx context.Context, result *RunResult, err error) {
+	if h.OnFinal != nil {
+		h.OnFinal(ctx, result, err)
+	}
+}
@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rathore/langchain-agent/llm"
+	"github.com/rathore/langchain-agent/tools"
+)
+
+func TestHooks(t *testing.T) {
+	var calls []string
+	var final *RunResult
+	hooks := Hooks{
+		OnIteration: func(ctx context.Context, i int) { calls = append(calls, fmt.Sprintf("iteration %d", i)) },
+		OnLLMStart: func(ctx context.Context, messages []llm.Message) {
+			calls = append(calls, fmt.Sprintf("llm %d messages", len(messages)))
+		},
+		OnLLMChunk: func(ctx context.Context, chunk string) { calls = append(calls, "chunk "+chunk) },
+		OnLLMEnd: func(ctx context.Context, resp *llm.Response, err error) {
+			if err != nil {
+				calls = append(calls, "llm end: "+err.Error())
+				return
+			}
+			calls = append(calls, fmt.Sprintf("llm end %d tool calls", len(resp.ToolCalls)))
+		},
+		OnToolStart: func(ctx context.Context, tool string, params map[string]any) { calls = append(calls, "tool "+tool) },
+		OnToolEnd: func(ctx context.Context, call ToolCall) {
+			calls = append(calls, fmt.Sprintf("tool end %s: %s", call.Name, call.Result))
+		},
+		OnFinal: func(ctx context.Context, result *RunResult, err error) {
+			final = result
+			calls = append(calls, fmt.Sprintf("final %q %v", result.Answer, err))
+		},
+	}
+	client := &MockLLMClient{responses: []*llm.Response{
+		{Content: `{"name": "test"}`, ToolCalls: []llm.ToolCallParse{{Name: "test", Params: map[string]any{"input": "x"}}}},
+		{Content: "Done.", IsFinish: true},
+	}}
+	ag, _ := New(Config{
+		Client: client,
+		Tools:  []tools.Tool{&MockTool{name: "test", result: "tool output"}},
+		Hooks:  hooks,
+	})
+	if _, err := ag.Run(t.Context(), "go"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"iteration 1", "llm 2 messages", "llm end 1 tool calls", "tool test", "tool end test: tool output",
+		"iteration 2", "llm 4 messages", "chunk Done.", "llm end 0 tool calls", `final "Done." <nil>`,
+	}
+	if got := strings.Join(calls, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("hooks called:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+	if final == nil || final.Elapsed == 0 || len(final.Steps) != 2 {
+		t.Errorf("final result = %+v", final)
+	}
+
+	// A failed run reports its error
+	calls = nil
+	ag, _ = New(Config{Client: &MockLLMClient{}, Hooks: hooks})
+	if _, err := ag.Run(t.Context(), "go"); err == nil {
+		t.Fatal("Run succeeded without responses")
+	}
+	if last := calls[len(calls)-1]; !strings.HasPrefix(last, `final "" agent iteration 0`) {
+		t.Errorf("last hook call = %q", last)
+	}
+
+	// Streamed chunks are reported as they come
+	calls = nil
+	streaming := &MockStreamingClient{MockLLMClient{responses: []*llm.Response{{Content: "Streamed.", IsFinish: true}}}}
+	ag, _ = New(Config{Client: streaming, Hooks: Hooks{OnLLMChunk: hooks.OnLLMChunk}})
+	if _, err := ag.Run(t.Context(), "go"); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 1 || calls[0] != "chunk Streamed." {
+		t.Errorf("streamed chunks = %v", calls)
+	}
+}
@@ -0,0 +1,163 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rathore/langchain-agent/llm"
+)
+
+// Tokenizer counts tokens the way the model would (rag.ApproxTokenizer is
+// one)
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+// byteTokenizer estimates a token per 4 bytes, the usual rule of thumb for
+// BPE models on English text
+type byteTokenizer struct{}
+
+func (byteTokenizer) CountTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// messageOverhead is what a message costs beyond its content (role and
+// delimiters), in tokens
+const messageOverhead = 4
+
+// Memory keeps long conversations within the model's context. When the
+// history sent with a query would pass Budget tokens, its oldest exchanges
+// are summarized into a conversation summary in the system message, and
+// only the recent ones are sent whole; History still returns all of them.
+// The zero value sends the whole history.
+type Memory struct {
+	// Budget is the most tokens of history, with the new query, sent to
+	// the LLM (0: no limit). Summarizing leaves about half of it, so it
+	// doesn't happen again with every query.
+	Budget int
+
+	// Tokenizer counts the tokens (nil: about 4 bytes a token)
+	Tokenizer Tokenizer
+
+	// Summarizer updates the summary prev with earlier turns of the
+	// conversation (nil: the agent's LLM writes it)
+	Summarizer func(ctx context.Context, prev string, turns []llm.Message) (string, error)
+}
+
+func (m Memory) tokenizer() Tokenizer {
+	if m.Tokenizer == nil {
+		return byteTokenizer{}
+	}
+	return m.Tokenizer
+}
+
+// countTokens is the size of messages as sent
+func countTokens(tok Tokenizer, messages []llm.Message) int {
+	n := 0
+	for _, msg := range messages {
+		n += tok.CountTokens(msg.Content) + messageOverhead
+	}
+	return n
+}
+
+const memoryPrompt = `You condense the earlier part of a conversation between an operator and an infrastructure assistant, so the assistant can carry on without it.
+Update the current summary, if any, with the turns given. Keep every host, namespace, service, command, error message, number and decision exactly as written, what was found and what is still open; leave out greetings and repetition. Write at most 200 words of plain sentences and reply with only the summary.`
+
+// summarizeTurns is the default Memory.Summarizer, returning the tokens
+// the LLM used as well
+func summarizeTurns(ctx context.Context, client llm.ChatClient, prev string, turns []llm.Message) (string, llm.Usage, error) {
+	var sb strings.Builder
+	if prev != "" {
+		fmt.Fprintf(&sb, "Current summary: %s\n\n", prev)
+	}
+	sb.WriteString("Turns to add:\n")
+	for _, msg := range turns {
+		role := "Operator"
+		switch msg.Role {
+		case "assistant":
+			role = "Assistant"
+		case "tool":
+			role = "Tool"
+		}
+		fmt.Fprintf(&sb, "%s: %s\n", role, truncate(msg.Content, 4000))
+	}
+	resp, err := client.Chat(ctx, []llm.Message{
+		{Role: "system", Content: memoryPrompt},
+		{Role: "user", Content: sb.String()},
+	})
+	if err != nil {
+		return prev, llm.Usage{}, fmt.Errorf("failed to summarize earlier turns: %w", err)
+	}
+	summary := strings.TrimSpace(resp.Content)
+	if summary == "" {
+		return prev, resp.Usage, fmt.Errorf("failed to summarize earlier turns: empty reply")
+	}
+	return summary, resp.Usage, nil
+}
+
+// compact summarizes the oldest exchanges not yet in the summary when the
+// rest of the history and query pass the Memory budget, folding whole
+// exchanges until about half the budget is left. It returns the tokens
+// used. A failed summary is reported and the history sent whole.
+func (a *Agent) compact(ctx context.Context, query string) llm.Usage {
+	if a.memory.Budget <= 0 || a.summarized > len(a.history) {
+		return llm.Usage{}
+	}
+	tok := a.memory.tokenizer()
+	total := tok.CountTokens(query) + messageOverhead + countTokens(tok, a.history[a.summarized:])
+	if total <= a.memory.Budget {
+		return llm.Usage{}
+	}
+	cut := a.summarized
+	for cut < len(a.history) && total > a.memory.Budget/2 {
+		next := cut + 1
+		for next < len(a.history) && a.history[next].Role != "user" {
+			next++
+		}
+		total -= countTokens(tok, a.history[cut:next])
+		cut = next
+	}
+	turns := a.history[a.summarized:cut]
+
+	var summary string
+	var usage llm.Usage
+	var err error
+	if a.memory.Summarizer != nil {
+		summary, err = a.memory.Summarizer(ctx, a.summary, turns)
+	} else {
+		summary, usage, err = summarizeTurns(ctx, a.client, a.summary, turns)
+	}
+	if err != nil {
+		a.hooks.note(ctx, NoteMemory, fmt.Sprintf("%v; sending the whole history", err))
+		return usage
+	}
+	a.hooks.note(ctx, NoteMemory, fmt.Sprintf("Summarized %d earlier messages to stay within %d tokens", len(turns), a.memory.Budget))
+	a.summary, a.summarized = summary, cut
+	return usage
+}
+
+// summaryNote is the conversation summary part of the system message. It
+// goes there rather than in a message of its own because some backends
+// (Gemini) keep only one system message.
+func (a *Agent) summaryNote() string {
+	if a.summary == "" {
+		return ""
+	}
+	return "\n\nCONVERSATION SUMMARY of the earlier turns, which are no longer shown: " + a.summary
+}
+
+// resetSummary forgets the conversation summary, with the history it
+// covers
+func (a *Agent) resetSummary() {
+	a.summary, a.summarized = "", 0
+}
+
+// ConversationSummary returns the summary standing in for the oldest
+// messages of History, and how many of them it covers ("", 0 until the
+// Memory budget is first passed)
+func (a *Agent) ConversationSummary() (string, int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.summary, a.summarized
+}
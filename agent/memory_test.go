@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rathore/langchain-agent/llm"
+)
+
+// wordTokenizer counts words, to make budgets easy to reason about
+type wordTokenizer struct{}
+
+func (wordTokenizer) CountTokens(text string) int { return len(strings.Fields(text)) }
+
+func TestMemory_Summarizes(t *testing.T) {
+	answer := strings.Repeat("word ", 20) // 20 tokens + 4 overhead
+	client := &MockLLMClient{}
+	for range 4 {
+		client.responses = append(client.responses, &llm.Response{Content: answer})
+	}
+	var folded [][]llm.Message
+	var prevs []string
+	ag, _ := New(Config{Client: client, Memory: Memory{
+		Budget:    80,
+		Tokenizer: wordTokenizer{},
+		Summarizer: func(ctx context.Context, prev string, turns []llm.Message) (string, error) {
+			folded = append(folded, turns)
+			prevs = append(prevs, prev)
+			return "summary " + turns[0].Content, nil
+		},
+	}})
+
+	for _, q := range []string{"q1", "q2", "q3", "q4"} {
+		if _, err := ag.Run(t.Context(), q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Each exchange is 5 + 24 tokens: q3 finds 58 + 5, q4 finds 87 + 5
+	if len(folded) != 1 || len(folded[0]) != 4 || folded[0][0].Content != "q1" || prevs[0] != "" {
+		t.Fatalf("folded %v (prev %q), want q1..q2 once", folded, prevs)
+	}
+	last := client.messages[3]
+	if !strings.Contains(last[0].Content, "CONVERSATION SUMMARY of the earlier turns, which are no longer shown: summary q1") {
+		t.Errorf("system message lacks the summary:\n%s", last[0].Content)
+	}
+	if len(last) != 4 || last[1].Content != "q3" || last[3].Content != "q4" {
+		t.Errorf("sent %d messages after the summary: %v", len(last)-1, last[1:])
+	}
+	if h := ag.History(); len(h) != 8 {
+		t.Errorf("History() has %d messages, want all 8", len(h))
+	}
+	if summary, n := ag.ConversationSummary(); summary != "summary q1" || n != 4 {
+		t.Errorf("ConversationSummary() = %q, %d", summary, n)
+	}
+
+	ag.UndoLastRun()
+	ag.UndoLastRun()
+	ag.UndoLastRun()
+	if summary, n := ag.ConversationSummary(); summary != "" || n != 0 {
+		t.Errorf("after undoing summarized turns: %q, %d", summary, n)
+	}
+	ag.ClearHistory()
+	if summary, _ := ag.ConversationSummary(); summary != "" {
+		t.Errorf("summary %q survived ClearHistory", summary)
+	}
+}
+
+func TestMemory_DefaultSummarizer(t *testing.T) {
+	client := &MockLLMClient{responses: []*llm.Response{
+		{Content: strings.Repeat("disk ", 100)},
+		{Content: "  The disk on web-1 is 95% full.  ", Usage: llm.Usage{PromptTokens: 50, CompletionTokens: 10, TotalTokens: 60}},
+		{Content: "Cleaned."},
+	}}
+	ag, _ := New(Config{Client: client, Memory: Memory{Budget: 100}})
+	ag.Run(t.Context(), "check the disk on web-1")
+	result, err := ag.RunDetailed(t.Context(), "clean it")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prompt := client.messages[1]
+	if prompt[0].Content != memoryPrompt || !strings.Contains(prompt[1].Content, "Operator: check the disk on web-1") {
+		t.Errorf("summary request = %v", prompt)
+	}
+	if summary, _ := ag.ConversationSummary(); summary != "The disk on web-1 is 95% full." {
+		t.Errorf("summary = %q", summary)
+	}
+	if result.Usage.PromptTokens != 50 {
+		t.Errorf("run usage %+v doesn't count the summary", result.Usage)
+	}
+}
+
+func TestMemory_SummaryFails(t *testing.T) {
+	client := &MockLLMClient{responses: []*llm.Response{{Content: strings.Repeat("x ", 50)}, {Content: "ok"}}}
+	ag, _ := New(Config{Client: client, Memory: Memory{
+		Budget:     10,
+		Tokenizer:  wordTokenizer{},
+		Summarizer: func(context.Context, string, []llm.Message) (string, error) { return "", errors.New("offline") },
+	}})
+	ag.Run(t.Context(), "first")
+	if _, err := ag.Run(t.Context(), "second"); err != nil {
+		t.Fatal(err)
+	}
+	if sent := client.messages[1]; len(sent) != 4 {
+		t.Errorf("sent %d messages, want the whole history after a failed summary", len(sent))
+	}
+}
@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// Mux serializes the progress of agents that run at the same time, such
+// as the REPL's and each webhook user's, onto one writer, so their lines
+// don't interleave mid-line. Each Stream's lines are
+// labeled with its name. A stream that has begun a line, such as a reply
+// being streamed, keeps the writer until it ends the line; what other
+// streams write meanwhile is held and written after it, in order.
+type Mux struct {
+	mu      sync.Mutex
+	out     io.Writer
+	owner   *muxStream   // has a line open on out
+	waiting []*muxStream // hold output until owner ends its line
+}
+
+// NewMux returns a Mux writing to out
+func NewMux(out io.Writer) *Mux {
+	return &Mux{out: out}
+}
+
+// Stream returns a writer whose lines go to the Mux's writer prefixed with
+// "label | "; an empty label adds nothing, for the main session
+func (m *Mux) Stream(label string) io.Writer {
+	s := &muxStream{m: m}
+	if label != "" {
+		s.prefix = []byte(label + " | ")
+	}
+	return s
+}
+
+type muxStream struct {
+	m       *Mux
+	prefix  []byte
+	pending []byte // held while another stream has a line open
+}
+
+func (s *muxStream) Write(p []byte) (int, error) {
+	m := s.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.owner != nil && m.owner != s {
+		if len(s.pending) == 0 {
+			m.waiting = append(m.waiting, s)
+		}
+		s.pending = append(s.pending, p...)
+		return len(p), nil
+	}
+	if err := m.write(s, p); err != nil {
+		return 0, err
+	}
+	return len(p), m.flush()
+}
+
+// write copies p from s to out, labeling each line s begins; s owns out
+// while its last line is unfinished. Empty lines aren't labeled.
+func (m *Mux) write(s *muxStream, p []byte) error {
+	for len(p) > 0 {
+		if m.owner == nil && p[0] != '\n' {
+			if _, err := m.out.Write(s.prefix); err != nil {
+				return err
+			}
+			m.owner = s
+		}
+		line := p
+		if i := bytes.IndexByte(p, '\n'); i >= 0 {
+			line = p[:i+1]
+		}
+		if _, err := m.out.Write(line); err != nil {
+			return err
+		}
+		if line[len(line)-1] == '\n' {
+			m.owner = nil
+		}
+		p = p[len(line):]
+	}
+	return nil
+}
+
+// flush writes the held output of waiting streams, oldest first, until one
+// of them leaves a line open
+func (m *Mux) flush() error {
+	for m.owner == nil && len(m.waiting) > 0 {
+		s := m.waiting[0]
+		m.waiting = m.waiting[1:]
+		p := s.pending
+		s.pending = nil
+		if err := m.write(s, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
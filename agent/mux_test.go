@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMux(t *testing.T) {
+	var out strings.Builder
+	m := NewMux(&out)
+	repl, alice := m.Stream(""), m.Stream("alice")
+
+	io.WriteString(repl, "\n[Agent] Disk is ")
+	io.WriteString(alice, "\n[Tool Call] shell: df\n[Tool Result] 40%\n")
+	io.WriteString(alice, "[Agent] partial")
+	io.WriteString(repl, "40% full.\n")
+	io.WriteString(alice, " answer\n")
+
+	want := "\n[Agent] Disk is 40% full.\n" +
+		"\nalice | [Tool Call] shell: df\nalice | [Tool Result] 40%\nalice | [Agent] partial answer\n"
+	if out.String() != want {
+		t.Errorf("output:\n%q\nwant:\n%q", out.String(), want)
+	}
+}
+
+func TestMux_Concurrent(t *testing.T) {
+	var out strings.Builder
+	m := NewMux(&out)
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := m.Stream(fmt.Sprintf("user%d", i))
+			for j := range 50 {
+				// A line written in pieces, as streamed tokens are
+				for _, piece := range strings.SplitAfter(fmt.Sprintf("line %d of user%d\n", j, i), " ") {
+					io.WriteString(w, piece)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 8*50 {
+		t.Fatalf("%d lines, want %d", len(lines), 8*50)
+	}
+	for _, line := range lines {
+		label, text, ok := strings.Cut(line, " | ")
+		if !ok || !strings.HasSuffix(text, "of "+label) {
+			t.Fatalf("garbled line %q", line)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultNudge is sent after a reply that is neither a tool call nor a
+// final answer, such as malformed tool-call JSON
+const DefaultNudge = `Your last reply was neither a valid tool call nor a final answer. Either call one tool with ONLY a JSON object {"name": "tool_name", "parameters": {...}}, or give your final answer as plain text without JSON.`
+
+// DefaultRepeatNudge is added to the result of a tool call that repeats an
+// earlier call of the run and returned the same output
+const DefaultRepeatNudge = "You already made this exact call in this run and it returned the same result. Use that result, try a different call, or give your final answer."
+
+// defaultStallAfter is Nudges.StallAfter when it is 0
+const defaultStallAfter = 3
+
+// maxBackoff caps the wait between unproductive steps
+const maxBackoff = 30 * time.Second
+
+// ErrStalled is returned by runs stopped by Nudges.StallAfter
+var ErrStalled = errors.New("run stalled")
+
+// Nudges correct a model that stops making progress: a reply that is
+// neither a tool call nor a final answer, or a tool call repeated with the
+// same result. The zero value sends the default messages and stops a run
+// after 3 such steps in a row, before MaxIter would.
+type Nudges struct {
+	// Invalid is sent after a reply that is neither a tool call nor a final
+	// answer ("": DefaultNudge; "none": the reply is kept and the LLM asked
+	// again without a message)
+	Invalid string
+
+	// Repeat is added to the result of a repeated tool call ("":
+	// DefaultRepeatNudge; "none": nothing)
+	Repeat string
+
+	// StallAfter ends a run with ErrStalled after this many unproductive
+	// steps in a row (0: 3; below 0: never)
+	StallAfter int
+
+	// Backoff waits this long before the LLM call after an unproductive
+	// step, doubling for each further one up to 30s, for hosted models that
+	// garble replies under load (0: no wait)
+	Backoff time.Duration
+}
+
+// invalid returns the message sent after an invalid reply, "" for none
+func (n Nudges) invalid() string {
+	return nudgeText(n.Invalid, DefaultNudge)
+}
+
+// repeat returns the note added to a repeated call's result, "" for none
+func (n Nudges) repeat() string {
+	return nudgeText(n.Repeat, DefaultRepeatNudge)
+}
+
+func nudgeText(msg, def string) string {
+	switch msg {
+	case "":
+		return def
+	case "none":
+		return ""
+	}
+	return msg
+}
+
+// stalled reports whether a run with this many unproductive steps in a row
+// should stop
+func (n Nudges) stalled(unproductive int) bool {
+	limit := n.StallAfter
+	if limit == 0 {
+		limit = defaultStallAfter
+	}
+	return limit > 0 && unproductive >= limit
+}
+
+// wait sleeps the backoff for the unproductive-th step in a row
+func (n Nudges) wait(ctx context.Context, unproductive int) error {
+	if n.Backoff <= 0 || unproductive == 0 {
+		return nil
+	}
+	d := n.Backoff
+	for i := 1; i < unproductive && d < maxBackoff; i++ {
+		d *= 2
+	}
+	t := time.NewTimer(min(d, maxBackoff))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// callKey identifies a tool call by its name and parameters
+func callKey(name string, params map[string]any) string {
+	p, _ := json.Marshal(params) // map keys are sorted
+	return fmt.Sprintf("%s %s", name, p)
+}
@@ -0,0 +1,169 @@
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxPipedResults is how many results a conversation keeps by handle; older
+// ones are dropped
+const maxPipedResults = 20
+
+// handlePattern matches a parameter value that is a result handle
+var handlePattern = regexp.MustCompile(`^@result\d+$`)
+
+// pipe keeps the tool results of a conversation by handle (@result1,
+// @result2, ...), so a later tool call can take a whole result as a
+// parameter value while the LLM only sees a preview of it
+type pipe struct {
+	threshold int // results longer than this are previewed
+	next      int
+	results   map[string]string
+	order     []string // handles, oldest first
+}
+
+func newPipe(threshold int) *pipe {
+	if threshold <= 0 {
+		return nil
+	}
+	return &pipe{threshold: threshold, results: map[string]string{}}
+}
+
+// store keeps output and returns its handle
+func (p *pipe) store(output string) string {
+	p.next++
+	handle := fmt.Sprintf("@result%d", p.next)
+	p.results[handle] = output
+	p.order = append(p.order, handle)
+	if len(p.order) > maxPipedResults {
+		delete(p.results, p.order[0])
+		p.order = p.order[1:]
+	}
+	return handle
+}
+
+// clear drops every result, for a new conversation
+func (p *pipe) clear() {
+	if p == nil {
+		return
+	}
+	p.results = map[string]string{}
+	p.order = nil
+}
+
+// resolve returns params with every string value that is a handle, at any
+// depth, replaced by its result. Handles inside longer strings are left
+// alone, so a result never becomes part of a command line.
+func (p *pipe) resolve(params map[string]any) (map[string]any, error) {
+	if p == nil {
+		return params, nil
+	}
+	resolved, err := p.resolveValue(params)
+	if err != nil {
+		return nil, err
+	}
+	m, _ := resolved.(map[string]any)
+	return m, nil
+}
+
+func (p *pipe) resolveValue(v any) (any, error) {
+	switch v := v.(type) {
+	case string:
+		handle := strings.TrimSpace(v)
+		if !handlePattern.MatchString(handle) {
+			return v, nil
+		}
+		result, ok := p.results[handle]
+		if !ok {
+			return nil, fmt.Errorf("unknown result handle %s (this conversation has %s)", handle, p.available())
+		}
+		return result, nil
+	case map[string]any:
+		if v == nil {
+			return v, nil
+		}
+		out := make(map[string]any, len(v))
+		for k, x := range v {
+			r, err := p.resolveValue(x)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = r
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, x := range v {
+			r, err := p.resolveValue(x)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = r
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+func (p *pipe) available() string {
+	switch len(p.order) {
+	case 0:
+		return "no stored results"
+	case 1:
+		return p.order[0]
+	default:
+		return p.order[0] + " to " + p.order[len(p.order)-1]
+	}
+}
+
+// preview returns output as the LLM sees it: whole up to the threshold,
+// otherwise its start and end around a note of what was left out
+func (p *pipe) preview(handle, output string) string {
+	if len(output) <= p.threshold {
+		return output
+	}
+	head := cut(output[:p.threshold*3/4], true)
+	tail := cut(output[len(output)-p.threshold/4:], false)
+	omitted := output[len(head) : len(output)-len(tail)]
+	return fmt.Sprintf("%s\n[... %d of %d characters (%d lines) omitted; %s holds the whole output ...]\n%s",
+		head, len(omitted), len(output), strings.Count(output, "\n")+1, handle, tail)
+}
+
+// cut trims a slice of the output to whole lines, unless that would lose
+// more than half of it, else to whole characters: head keeps the start of
+// s, otherwise the end
+func cut(s string, head bool) string {
+	if head {
+		if i := strings.LastIndexByte(s, '\n'); i > len(s)/2 {
+			return s[:i]
+		}
+		for len(s) > 0 && !utf8.ValidString(s) {
+			s = s[:len(s)-1]
+		}
+		return s
+	}
+	if i := strings.IndexByte(s, '\n'); i >= 0 && i < len(s)/2 {
+		return s[i+1:]
+	}
+	for len(s) > 0 && !utf8.RuneStart(s[0]) {
+		s = s[1:]
+	}
+	return s
+}
+
+// instructions is the system prompt section on result handles, or "" when
+// results aren't piped
+func (p *pipe) instructions() string {
+	if p == nil {
+		return ""
+	}
+	return `
+RESULT HANDLES:
+Every tool result is stored under a handle such as @result3, named in the "Tool ... returned" line. Long results are shown shortened.
+- To give a whole result to another tool, pass the handle alone as a parameter value, e.g. "content": "@result3". Never copy a long result into a tool call yourself.
+- A handle inside a longer value (e.g. part of a command) is not replaced.
+`
+}
@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Preferences are how the user wants answers written. They are added to the
+// system prompt of every run; empty fields leave the model's defaults.
+type Preferences struct {
+	Language   string `json:"language,omitempty"`    // e.g. German, pt-BR
+	Verbosity  string `json:"verbosity,omitempty"`   // brief, normal or detailed
+	Units      string `json:"units,omitempty"`       // metric or imperial
+	DateFormat string `json:"date_format,omitempty"` // e.g. DD.MM.YYYY, ISO 8601
+}
+
+// PreferenceKeys are the names Preferences.Set takes
+var PreferenceKeys = []string{"language", "verbosity", "units", "date_format"}
+
+// Set sets the preference named key ("" clears it), checking verbosity and
+// units against their allowed values
+func (p *Preferences) Set(key, value string) error {
+	value = strings.TrimSpace(value)
+	switch strings.ReplaceAll(strings.ToLower(key), "-", "_") {
+	case "language", "lang":
+		p.Language = value
+	case "verbosity":
+		value = strings.ToLower(value)
+		if value != "" && value != "brief" && value != "normal" && value != "detailed" {
+			return fmt.Errorf("unknown verbosity %q (use brief, normal or detailed)", value)
+		}
+		p.Verbosity = value
+	case "units":
+		value = strings.ToLower(value)
+		if value != "" && value != "metric" && value != "imperial" {
+			return fmt.Errorf("unknown units %q (use metric or imperial)", value)
+		}
+		p.Units = value
+	case "date_format", "dates":
+		p.DateFormat = value
+	default:
+		return fmt.Errorf("unknown preference %q (use %s)", key, strings.Join(PreferenceKeys, ", "))
+	}
+	return nil
+}
+
+// IsZero reports whether no preference is set
+func (p Preferences) IsZero() bool {
+	return p == Preferences{}
+}
+
+// String lists the set preferences as key=value pairs
+func (p Preferences) String() string {
+	var pairs []string
+	for _, kv := range [][2]string{{"language", p.Language}, {"verbosity", p.Verbosity}, {"units", p.Units}, {"date_format", p.DateFormat}} {
+		if kv[1] != "" {
+			pairs = append(pairs, kv[0]+"="+kv[1])
+		}
+	}
+	return strings.Join(pairs, " ")
+}
+
+// prompt is the system prompt section asking for the preferences
+func (p Preferences) prompt() string {
+	var asks []string
+	if p.Language != "" {
+		asks = append(asks, fmt.Sprintf("Write your final answers in %s, whatever language the tool output or wiki is in.", p.Language))
+	}
+	switch p.Verbosity {
+	case "brief":
+		asks = append(asks, "Keep answers brief: the key facts in a few sentences or a short list.")
+	case "detailed":
+		asks = append(asks, "Give detailed answers: explain your reasoning and include the relevant output.")
+	}
+	switch p.Units {
+	case "metric":
+		asks = append(asks, "Use metric units (°C, km, kg, GB).")
+	case "imperial":
+		asks = append(asks, "Use imperial units (°F, miles, pounds), converting where needed.")
+	}
+	if p.DateFormat != "" {
+		asks = append(asks, fmt.Sprintf("Write dates as %s.", p.DateFormat))
+	}
+	if len(asks) == 0 {
+		return ""
+	}
+	return "\n\nANSWER PREFERENCES of the user:\n- " + strings.Join(asks, "\n- ") + "\nTool calls, commands and quoted output stay as they are."
+}
+
+// SetPreferences replaces the user's answer preferences
+func (a *Agent) SetPreferences(p Preferences) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.prefs = p
+}
+
+// Preferences returns the user's answer preferences
+func (a *Agent) Preferences() Preferences {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.prefs
+}
@@ -0,0 +1,172 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rathore/langchain-agent/llm"
+)
+
+// Session is a saved conversation
+type Session struct {
+	ID      string        `json:"id"`
+	Started time.Time     `json:"started"`
+	Updated time.Time     `json:"updated"`
+	Title   string        `json:"title"`
+	Summary string        `json:"summary,omitempty"`
+	History []llm.Message `json:"history"`
+
+	Preferences *Preferences `json:"preferences,omitempty"`
+	// Named is set when the user chose the title, which summaries then keep
+	Named bool `json:"named,omitempty"`
+}
+
+// Exchanges is the number of queries in the conversation
+func (s Session) Exchanges() int {
+	n := 0
+	for _, msg := range s.History {
+		if msg.Role == "user" {
+			n++
+		}
+	}
+	return n
+}
+
+// ErrSessionNotFound is returned by SessionStore.Load for an unknown ID
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionStore keeps conversations across restarts. FileStore and
+// SQLiteStore implement it.
+type SessionStore interface {
+	// Save adds s, or replaces the session with its ID
+	Save(s Session) error
+	// Load returns the session with id, or an error wrapping
+	// ErrSessionNotFound
+	Load(id string) (Session, error)
+	// List returns the saved sessions, most recently updated first
+	List() ([]Session, error)
+}
+
+// ExportHistory returns the conversation and the answer preferences as a
+// Session, to save in a SessionStore; the caller gives it an ID and title
+func (a *Agent) ExportHistory() Session {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	s := Session{History: append([]llm.Message(nil), a.history...), Updated: time.Now()}
+	if !a.prefs.IsZero() {
+		p := a.prefs
+		s.Preferences = &p
+	}
+	return s
+}
+
+// LoadHistory continues a saved conversation: its history replaces the
+// agent's, as after ClearHistory, and its preferences, if any, are set
+func (a *Agent) LoadHistory(s Session) error {
+	for i, msg := range s.History {
+		switch msg.Role {
+		case "user", "assistant", "tool":
+		default:
+			return fmt.Errorf("invalid history: message %d has role %q", i+1, msg.Role)
+		}
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.history = append([]llm.Message(nil), s.History...)
+	a.runs = nil
+	a.lastRun = nil
+	a.pipe.clear()
+	a.resetSummary()
+	if s.Preferences != nil {
+		a.prefs = *s.Preferences
+	}
+	return nil
+}
+
+// FileStore is a SessionStore keeping each session in a JSON file named
+// after its ID
+type FileStore struct {
+	Dir string
+}
+
+// NewFileStore returns a store in dir, creating it if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create sessions dir: %w", err)
+	}
+	return &FileStore{Dir: dir}, nil
+}
+
+// path is the file of session id
+func (f *FileStore) path(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return "", fmt.Errorf("invalid session id %q", id)
+	}
+	return filepath.Join(f.Dir, id+".json"), nil
+}
+
+// Save writes s to its file, replacing it atomically
+func (f *FileStore) Save(s Session) error {
+	path, err := f.path(s.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+func (f *FileStore) Load(id string) (Session, error) {
+	path, err := f.path(id)
+	if err != nil {
+		return Session{}, err
+	}
+	s, err := loadSessionFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	return s, err
+}
+
+// List skips unreadable files
+func (f *FileStore) List() ([]Session, error) {
+	paths, err := filepath.Glob(filepath.Join(f.Dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	var sessions []Session
+	for _, path := range paths {
+		if s, err := loadSessionFile(path); err == nil {
+			sessions = append(sessions, s)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Updated.After(sessions[j].Updated) })
+	return sessions, nil
+}
+
+func loadSessionFile(path string) (Session, error) {
+	var s Session
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("invalid session file %s: %w", filepath.Base(path), err)
+	}
+	return s, nil
+}
@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rathore/langchain-agent/llm"
+)
+
+// testSessionStore checks the SessionStore contract
+func testSessionStore(t *testing.T, store SessionStore) {
+	t.Helper()
+	now := time.Now()
+	older := Session{ID: "a", Updated: now.Add(-time.Hour), Title: "older", History: []llm.Message{{Role: "user", Content: "q"}}}
+	newer := Session{ID: "b", Updated: now, Title: "newer", Preferences: &Preferences{Language: "German"}}
+	for _, s := range []Session{older, newer} {
+		if err := store.Save(s); err != nil {
+			t.Fatalf("Save(%s): %v", s.ID, err)
+		}
+	}
+	older.Title = "older, renamed"
+	if err := store.Save(older); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.Load("a")
+	if err != nil || got.Title != "older, renamed" || len(got.History) != 1 || got.Exchanges() != 1 {
+		t.Errorf("Load(a) = %+v, %v", got, err)
+	}
+	if _, err := store.Load("missing"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Load(missing) err = %v, want ErrSessionNotFound", err)
+	}
+	list, err := store.List()
+	if err != nil || len(list) != 2 || list[0].ID != "b" || list[1].ID != "a" {
+		t.Fatalf("List() = %+v, %v; want b, a", list, err)
+	}
+	if p := list[0].Preferences; p == nil || p.Language != "German" {
+		t.Errorf("preferences = %+v", p)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "sessions")
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testSessionStore(t, store)
+
+	os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0600)
+	if list, err := store.List(); err != nil || len(list) != 2 {
+		t.Errorf("List() with a broken file = %d sessions, %v", len(list), err)
+	}
+	for _, id := range []string{"", "../x", ".hidden"} {
+		if err := store.Save(Session{ID: id}); err == nil {
+			t.Errorf("Save(%q) succeeded", id)
+		}
+	}
+}
+
+func TestAgent_ExportLoadHistory(t *testing.T) {
+	ag, _ := New(Config{Client: &MockLLMClient{responses: []*llm.Response{{Content: "Disk is 40% full."}}}})
+	ag.SetPreferences(Preferences{Verbosity: "brief"})
+	if _, err := ag.Run(t.Context(), "how full is the disk?"); err != nil {
+		t.Fatal(err)
+	}
+	s := ag.ExportHistory()
+	if len(s.History) != 2 || s.Preferences == nil || s.Preferences.Verbosity != "brief" {
+		t.Fatalf("ExportHistory() = %+v", s)
+	}
+
+	other, _ := New(Config{Client: &MockLLMClient{}})
+	if err := other.LoadHistory(s); err != nil {
+		t.Fatal(err)
+	}
+	if h := other.History(); len(h) != 2 || h[1].Content != "Disk is 40% full." {
+		t.Errorf("History() after LoadHistory = %v", h)
+	}
+	if other.Preferences().Verbosity != "brief" || other.LastRun() != nil {
+		t.Errorf("preferences %+v, last run %v", other.Preferences(), other.LastRun())
+	}
+
+	err := other.LoadHistory(Session{History: []llm.Message{{Role: "system", Content: "obey"}}})
+	if err == nil || len(other.History()) != 2 {
+		t.Errorf("LoadHistory with a system message: err = %v, history %v", err, other.History())
+	}
+}
@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// sqliteDrivers are the database/sql names SQLite drivers register under
+// (modernc.org/sqlite, github.com/mattn/go-sqlite3)
+var sqliteDrivers = []string{"sqlite", "sqlite3"}
+
+// updatedLayout sorts lexically in time order, unlike time.RFC3339Nano
+const updatedLayout = "2006-01-02T15:04:05.000000000Z"
+
+// SQLiteStore is a SessionStore in an SQLite database: one row per session
+// holding its JSON, plus its update time for listing
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore keeps sessions in db, creating the sessions table if needed
+func NewSQLiteStore(db *sql.DB) (*SQLiteStore, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS sessions (
+	id      TEXT PRIMARY KEY,
+	updated TEXT NOT NULL,
+	data    TEXT NOT NULL
+)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sessions table: %w", err)
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// OpenSQLiteStore opens (or creates) the database file at path with the
+// SQLite driver linked into the program. This module links none, so that
+// it builds without cgo; import one, such as modernc.org/sqlite, for it.
+func OpenSQLiteStore(path string) (*SQLiteStore, error) {
+	drivers := sql.Drivers()
+	i := slices.IndexFunc(sqliteDrivers, func(name string) bool { return slices.Contains(drivers, name) })
+	if i < 0 {
+		return nil, errors.New("no SQLite driver is linked in (build with -tags sqlite)")
+	}
+	db, err := sql.Open(sqliteDrivers[i], path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	s, err := NewSQLiteStore(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close closes the database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteStore) Save(session Session) error {
+	if session.ID == "" {
+		return errors.New("invalid session id \"\"")
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+	_, err = s.db.Exec(`INSERT OR REPLACE INTO sessions (id, updated, data) VALUES (?, ?, ?)`,
+		session.ID, session.Updated.UTC().Format(updatedLayout), string(data))
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Load(id string) (Session, error) {
+	var data string
+	err := s.db.QueryRow(`SELECT data FROM sessions WHERE id = ?`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Session{}, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	} else if err != nil {
+		return Session{}, fmt.Errorf("failed to load session: %w", err)
+	}
+	var session Session
+	if err := json.Unmarshal([]byte(data), &session); err != nil {
+		return Session{}, fmt.Errorf("invalid session %s: %w", id, err)
+	}
+	return session, nil
+}
+
+// List skips rows that don't decode
+func (s *SQLiteStore) List() ([]Session, error) {
+	rows, err := s.db.Query(`SELECT data FROM sessions ORDER BY updated DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+	var sessions []Session
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to list sessions: %w", err)
+		}
+		var session Session
+		if json.Unmarshal([]byte(data), &session) == nil {
+			sessions = append(sessions, session)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	return sessions, nil
+}
@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.db")
+	store, err := OpenSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testSessionStore(t, store)
+
+	if err := store.Save(Session{}); err == nil {
+		t.Error("Save without an ID succeeded")
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The sessions outlive the store, and a row that doesn't decode is
+	// skipped by List
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO sessions (id, updated, data) VALUES ('bad', ?, '{')`, time.Now().UTC().Format(updatedLayout)); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	store, err = OpenSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	sessions, err := store.List()
+	if err != nil || len(sessions) != 2 {
+		t.Fatalf("List() after reopening = %d sessions, %v; want 2", len(sessions), err)
+	}
+	if _, err := store.Load("bad"); err == nil || !strings.Contains(err.Error(), "invalid session bad") {
+		t.Errorf("Load(bad) error = %v", err)
+	}
+}
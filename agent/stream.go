@@ -0,0 +1,38 @@
+package agent
+
+import "context"
+
+// RunStream is RunEvents delivering the events on a channel, for frontends
+// that stream a run to a client. The last event is EventAnswer or, if the
+// run failed, EventError; both carry the whole run in Run. The channel is
+// closed when the run is over.
+//
+// The run waits for the channel to be read; once ctx is done, events that
+// can't be delivered are dropped and the run stops at its next step.
+func (a *Agent) RunStream(ctx context.Context, userInput string) <-chan Event {
+	events := make(chan Event, 16)
+	send := func(e Event) {
+		select {
+		case events <- e:
+		case <-ctx.Done():
+		}
+	}
+	go func() {
+		defer close(events)
+		var answer *Event
+		result, err := a.RunEvents(ctx, userInput, func(e Event) {
+			if e.Type == EventAnswer {
+				answer = &e // sent once the run is complete
+				return
+			}
+			send(e)
+		})
+		if err != nil {
+			send(Event{Type: EventError, Error: err.Error(), Run: result})
+			return
+		}
+		answer.Run = result
+		send(*answer)
+	}()
+	return events
+}
@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/rathore/langchain-agent/llm"
+)
+
+// Summary is a short title and running summary of a conversation, for
+// listing saved sessions
+type Summary struct {
+	Title   string `json:"title"`
+	Summary string `json:"summary,omitempty"`
+}
+
+// maxTitleLen caps a title, in characters
+const maxTitleLen = 80
+
+const summaryPrompt = `You keep the title and summary of a conversation between an operator and an infrastructure assistant, for a list of past sessions.
+Update them with the new exchange. Reply with only a JSON object: {"title": "...", "summary": "..."}
+- title: at most 8 words naming the system and the problem or task, e.g. "api-server CrashLoopBackOff investigation". No quotes or trailing period. Keep the current title unless the conversation has moved on to something else.
+- summary: at most 3 sentences on what was asked, what was found or done, and what is still open.`
+
+// Summarize returns prev updated with an exchange of the conversation, the
+// user query and the answer, written by client. An empty prev starts a new
+// summary.
+func Summarize(ctx context.Context, client llm.ChatClient, prev Summary, exchange []llm.Message) (Summary, error) {
+	var sb strings.Builder
+	if prev.Title != "" {
+		fmt.Fprintf(&sb, "Current title: %s\nCurrent summary: %s\n\n", prev.Title, prev.Summary)
+	}
+	sb.WriteString("New exchange:\n")
+	for _, msg := range exchange {
+		role := "Operator"
+		if msg.Role == "assistant" {
+			role = "Assistant"
+		}
+		fmt.Fprintf(&sb, "%s: %s\n", role, truncate(msg.Content, 2000))
+	}
+
+	resp, err := client.Chat(ctx, []llm.Message{
+		{Role: "system", Content: summaryPrompt},
+		{Role: "user", Content: sb.String()},
+	})
+	if err != nil {
+		return prev, fmt.Errorf("failed to summarize conversation: %w", err)
+	}
+	content := resp.Content
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return prev, fmt.Errorf("summary is not JSON: %s", truncate(content, 200))
+	}
+	var s Summary
+	if err := json.Unmarshal([]byte(content[start:end+1]), &s); err != nil {
+		return prev, fmt.Errorf("failed to parse summary: %w", err)
+	}
+	s.Title = strings.TrimRight(strings.Trim(strings.TrimSpace(s.Title), `"'`), ".")
+	s.Summary = strings.TrimSpace(s.Summary)
+	if s.Title == "" {
+		return prev, fmt.Errorf("summary has no title")
+	}
+	s.Title = truncate(s.Title, maxTitleLen)
+	return s, nil
+}
+
+// FallbackTitle names a conversation by its first query, for when no
+// summary could be written
+func FallbackTitle(query string) string {
+	return truncate(strings.Join(strings.Fields(query), " "), maxTitleLen)
+}
@@ -0,0 +1,232 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/rathore/langchain-agent/agent"
+	"github.com/rathore/langchain-agent/rag"
+	"github.com/rathore/langchain-agent/tools"
+)
+
+// Config sizes a benchmark run; zero fields get the defaults
+type Config struct {
+	Pages     int // synthetic wiki pages to index (default 200)
+	Queries   int // wiki searches to time (default 500)
+	Runs      int // agent runs with the null LLM (default 1000)
+	ToolCalls int // tool calls per agent run (default 2)
+	Dims      int // embedding dimensions (default 768, as nomic-embed-text)
+}
+
+func (c *Config) defaults() {
+	if c.Pages <= 0 {
+		c.Pages = 200
+	}
+	if c.Queries <= 0 {
+		c.Queries = 500
+	}
+	if c.Runs <= 0 {
+		c.Runs = 1000
+	}
+	if c.ToolCalls <= 0 {
+		c.ToolCalls = 2
+	}
+	if c.Dims <= 0 {
+		c.Dims = 768
+	}
+}
+
+// Report holds the results of Run
+type Report struct {
+	Config Config        `json:"config"`
+	Index  IndexResult   `json:"index"`
+	Search LatencyResult `json:"search"`
+	Agent  AgentResult   `json:"agent"`
+}
+
+// IndexResult is the throughput of indexing the synthetic wiki
+type IndexResult struct {
+	Pages       int           `json:"pages"`
+	Docs        int           `json:"docs"` // chunks stored
+	Elapsed     time.Duration `json:"elapsed_ns"`
+	PagesPerSec float64       `json:"pages_per_sec"`
+}
+
+// LatencyResult is the distribution of wiki search times
+type LatencyResult struct {
+	Queries int           `json:"queries"`
+	Docs    int           `json:"docs"` // chunks searched
+	Mean    time.Duration `json:"mean_ns"`
+	P50     time.Duration `json:"p50_ns"`
+	P95     time.Duration `json:"p95_ns"`
+	P99     time.Duration `json:"p99_ns"`
+}
+
+// AgentResult is the agent loop's own time per run and per LLM step, with
+// an LLM and a tool that return at once
+type AgentResult struct {
+	Runs      int           `json:"runs"`
+	ToolCalls int           `json:"tool_calls"` // per run
+	PerRun    time.Duration `json:"per_run_ns"`
+	PerStep   time.Duration `json:"per_step_ns"`
+}
+
+// Run indexes a synthetic wiki of cfg.Pages pages in a temporary directory,
+// times cfg.Queries searches of it and cfg.Runs agent runs
+func Run(ctx context.Context, cfg Config) (*Report, error) {
+	cfg.defaults()
+	dir, err := os.MkdirTemp("", "langchain-agent-bench")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bench dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := WriteWiki(dir, cfg.Pages); err != nil {
+		return nil, err
+	}
+
+	report := &Report{Config: cfg}
+	embedder := HashEmbedder{Dims: cfg.Dims}
+	store := rag.NewMemoryStore()
+	report.Index, err = Index(ctx, dir, embedder, store)
+	if err != nil {
+		return nil, err
+	}
+	report.Search, err = Search(ctx, tools.NewWikiTool(embedder, store), cfg.Queries)
+	if err != nil {
+		return nil, err
+	}
+	report.Search.Docs = report.Index.Docs
+	report.Agent, err = AgentLoop(ctx, cfg.Runs, cfg.ToolCalls)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// Index indexes the Confluence export in dir into store and reports the
+// pages indexed per second. The indexer's progress output is discarded.
+func Index(ctx context.Context, dir string, embedder rag.Embedder, store rag.Store) (IndexResult, error) {
+	config := rag.DefaultConfig()
+	config.WikiPath = dir
+	config.Embedder = embedder
+	config.Store = store
+	idx, err := rag.NewIndexer(config)
+	if err != nil {
+		return IndexResult{}, err
+	}
+	var r IndexResult
+	err = quietly(func() error {
+		start := time.Now()
+		if err := idx.Index(ctx); err != nil {
+			return err
+		}
+		r.Elapsed = time.Since(start)
+		return nil
+	})
+	if err != nil {
+		return IndexResult{}, err
+	}
+	stats, err := idx.Stats(ctx)
+	if err != nil {
+		return IndexResult{}, err
+	}
+	r.Pages, r.Docs = stats.Pages, stats.Vectors
+	r.PagesPerSec = float64(r.Pages) / r.Elapsed.Seconds()
+	return r, nil
+}
+
+// Search times n searches through the wiki tool, as the agent makes them
+// (query embedding, vector search and section expansion)
+func Search(ctx context.Context, wiki *tools.WikiTool, n int) (LatencyResult, error) {
+	times := make([]time.Duration, n)
+	var total time.Duration
+	for i := range n {
+		query := fmt.Sprintf("%s %s %s", words[i%len(words)], words[(i*5+1)%len(words)], words[(i*11+2)%len(words)])
+		start := time.Now()
+		if _, err := wiki.Call(ctx, map[string]any{"action": "search", "query": query}); err != nil {
+			return LatencyResult{}, fmt.Errorf("failed to search %q: %w", query, err)
+		}
+		times[i] = time.Since(start)
+		total += times[i]
+	}
+	slices.Sort(times)
+	return LatencyResult{
+		Queries: n,
+		Mean:    total / time.Duration(n),
+		P50:     percentile(times, 50),
+		P95:     percentile(times, 95),
+		P99:     percentile(times, 99),
+	}, nil
+}
+
+// AgentLoop times runs agent runs of toolCalls tool calls each, with
+// NullLLM and a tool that returns at once, each in a fresh conversation
+func AgentLoop(ctx context.Context, runs, toolCalls int) (AgentResult, error) {
+	ag, err := agent.New(agent.Config{
+		Client:  NullLLM{Tool: "noop", ToolCalls: toolCalls},
+		Tools:   []tools.Tool{noopTool{}},
+		MaxIter: toolCalls + 1,
+	})
+	if err != nil {
+		return AgentResult{}, err
+	}
+	start := time.Now()
+	for range runs {
+		ag.ClearHistory()
+		if _, err := ag.RunDetailed(ctx, "check the pods and report"); err != nil {
+			return AgentResult{}, err
+		}
+	}
+	elapsed := time.Since(start)
+	return AgentResult{
+		Runs:      runs,
+		ToolCalls: toolCalls,
+		PerRun:    elapsed / time.Duration(runs),
+		PerStep:   elapsed / time.Duration(runs*(toolCalls+1)),
+	}, nil
+}
+
+// WriteText writes the report as a short table
+func (r *Report) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "Indexing:   %d pages, %d chunks in %s (%.0f pages/s)\n",
+		r.Index.Pages, r.Index.Docs, round(r.Index.Elapsed), r.Index.PagesPerSec)
+	fmt.Fprintf(w, "Search:     %d queries over %d chunks: mean %s, p50 %s, p95 %s, p99 %s\n",
+		r.Search.Queries, r.Search.Docs, round(r.Search.Mean), round(r.Search.P50), round(r.Search.P95), round(r.Search.P99))
+	fmt.Fprintf(w, "Agent loop: %d runs of %d tool calls: %s per run, %s per LLM step\n",
+		r.Agent.Runs, r.Agent.ToolCalls, round(r.Agent.PerRun), round(r.Agent.PerStep))
+}
+
+// percentile returns the p-th percentile of sorted times
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[min(len(sorted)*p/100, len(sorted)-1)]
+}
+
+// round keeps three significant digits of a duration
+func round(d time.Duration) time.Duration {
+	unit := time.Duration(1)
+	for d/unit >= 1000 {
+		unit *= 10
+	}
+	return d.Round(unit)
+}
+
+// quietly runs f with os.Stdout discarded: the indexer prints its progress
+// there
+func quietly(f func() error) error {
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		return f()
+	}
+	defer devNull.Close()
+	stdout := os.Stdout
+	os.Stdout = devNull
+	defer func() { os.Stdout = stdout }()
+	return f()
+}
@@ -0,0 +1,111 @@
+package bench
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rathore/langchain-agent/rag"
+	"github.com/rathore/langchain-agent/tools"
+)
+
+func TestRun(t *testing.T) {
+	report, err := Run(context.Background(), Config{Pages: 5, Queries: 20, Runs: 10, ToolCalls: 3, Dims: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Index.Pages != 5 || report.Index.Docs < 5 || report.Index.PagesPerSec <= 0 {
+		t.Errorf("index = %+v", report.Index)
+	}
+	if s := report.Search; s.Queries != 20 || s.Docs != report.Index.Docs || s.P50 <= 0 || s.P50 > s.P99 {
+		t.Errorf("search = %+v", s)
+	}
+	if a := report.Agent; a.Runs != 10 || a.ToolCalls != 3 || a.PerStep <= 0 || a.PerRun < a.PerStep {
+		t.Errorf("agent = %+v", a)
+	}
+
+	var b strings.Builder
+	report.WriteText(&b)
+	for _, s := range []string{"Indexing:   5 pages", "Search:     20 queries", "Agent loop: 10 runs of 3 tool calls"} {
+		if !strings.Contains(b.String(), s) {
+			t.Errorf("report missing %q:\n%s", s, b.String())
+		}
+	}
+}
+
+func TestNullLLM(t *testing.T) {
+	ag, err := AgentLoop(context.Background(), 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ag.Runs != 1 {
+		t.Errorf("AgentLoop = %+v", ag)
+	}
+	// MaxIter is exactly the tool calls plus the answer, so a NullLLM that
+	// miscounted its calls would fail the run
+	if _, err := AgentLoop(context.Background(), 3, 5); err != nil {
+		t.Errorf("AgentLoop(3, 5) = %v", err)
+	}
+}
+
+func TestHashEmbedder(t *testing.T) {
+	e := HashEmbedder{Dims: 128}
+	ctx := context.Background()
+	a, _ := e.Embed(ctx, "Deploy the canary release")
+	b, _ := e.Embed(ctx, "the canary release: deploy")
+	c, _ := e.Embed(ctx, "postgres backup restore")
+	dot := func(x, y []float32) (s float32) {
+		for i := range x {
+			s += x[i] * y[i]
+		}
+		return s
+	}
+	if d := dot(a, b); d < 0.99 {
+		t.Errorf("same words: similarity %v, want 1", d)
+	}
+	if dot(a, c) >= dot(a, b) {
+		t.Errorf("different words should be less similar")
+	}
+}
+
+func BenchmarkIndex(b *testing.B) {
+	const pages = 50
+	dir := b.TempDir()
+	if err := WriteWiki(dir, pages); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for range b.N {
+		if _, err := Index(context.Background(), dir, HashEmbedder{Dims: 768}, rag.NewMemoryStore()); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(pages*b.N)/b.Elapsed().Seconds(), "pages/s")
+}
+
+func BenchmarkSearch(b *testing.B) {
+	dir := b.TempDir()
+	if err := WriteWiki(dir, 200); err != nil {
+		b.Fatal(err)
+	}
+	embedder := HashEmbedder{Dims: 768}
+	store := rag.NewMemoryStore()
+	if _, err := Index(context.Background(), dir, embedder, store); err != nil {
+		b.Fatal(err)
+	}
+	wiki := tools.NewWikiTool(embedder, store)
+	b.ResetTimer()
+	for i := range b.N {
+		query := words[i%len(words)] + " " + words[(i*7+3)%len(words)]
+		if _, err := wiki.Call(context.Background(), map[string]any{"action": "search", "query": query}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkAgentLoop times runs of two tool calls and an answer
+func BenchmarkAgentLoop(b *testing.B) {
+	if _, err := AgentLoop(context.Background(), b.N, 2); err != nil {
+		b.Fatal(err)
+	}
+}
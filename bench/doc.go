@@ -0,0 +1,9 @@
+// Package bench measures the agent's own overhead, without a model or a
+// vector database: wiki indexing throughput over a synthetic Confluence
+// export, wiki search latency on the embedded store, and the agent loop
+// driven by a null LLM. Embeddings come from HashEmbedder, so the numbers
+// move only when the pipeline code does.
+//
+// `langchain-agent bench` prints a Report; the Benchmark functions in this
+// package measure the same paths for `go test -bench`.
+package bench
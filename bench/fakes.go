@@ -0,0 +1,146 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rathore/langchain-agent/llm"
+	"github.com/rathore/langchain-agent/rag"
+)
+
+// HashEmbedder is a rag.Embedder that hashes words into a vector of Dims
+// dimensions: texts sharing words are similar, and embedding costs next to
+// nothing
+type HashEmbedder struct {
+	Dims int
+}
+
+var _ rag.Embedder = HashEmbedder{}
+
+func (e HashEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	v := make([]float32, e.Dims)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), isSeparator) {
+		h := fnv.New32a()
+		h.Write([]byte(word))
+		sum := h.Sum32()
+		if sum&1 == 0 {
+			v[int(sum>>1)%e.Dims]++
+		} else {
+			v[int(sum>>1)%e.Dims]--
+		}
+	}
+	var norm float64
+	for _, x := range v {
+		norm += float64(x * x)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range v {
+			v[i] *= scale
+		}
+	}
+	return v, nil
+}
+
+func (e HashEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i], _ = e.Embed(ctx, text)
+	}
+	return vectors, nil
+}
+
+func isSeparator(r rune) bool {
+	return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+}
+
+// NullLLM is an llm.ChatClient that answers every query with ToolCalls
+// calls to Tool and then a short answer. Its replies go through
+// llm.ParseResponse like a backend's.
+type NullLLM struct {
+	Tool      string
+	ToolCalls int
+}
+
+func (c NullLLM) Chat(ctx context.Context, messages []llm.Message) (*llm.Response, error) {
+	calls := 0
+	for i := len(messages) - 1; i >= 0 && messages[i].Role != "user"; i-- {
+		if messages[i].Role == "tool" {
+			calls++
+		}
+	}
+	if calls < c.ToolCalls {
+		return llm.ParseResponse(fmt.Sprintf(`{"name": %q, "parameters": {"step": %d}}`, c.Tool, calls+1)), nil
+	}
+	return llm.ParseResponse("Done: every step succeeded."), nil
+}
+
+// noopTool is the tool NullLLM calls
+type noopTool struct{}
+
+func (noopTool) Name() string        { return "noop" }
+func (noopTool) Description() string { return "Does nothing and returns a short status line" }
+func (noopTool) Parameters() map[string]any {
+	return map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"step": map[string]any{"type": "integer"}},
+	}
+}
+func (noopTool) Call(ctx context.Context, params map[string]any) (string, error) {
+	return "ok: 3 pods running, 0 restarts", nil
+}
+
+// words make up the synthetic wiki's text
+var words = strings.Fields(`deploy rollout cluster node pod service ingress
+	database replica backup restore network firewall vlan route dns certificate
+	token vault secret monitor alert dashboard latency throughput queue worker
+	cache redis postgres kafka topic partition consumer producer schedule cron
+	runbook incident escalation oncall pager release canary rollback config
+	helm chart namespace quota limit memory cpu disk volume snapshot storage
+	region zone failover health probe restart log trace metric label owner team`)
+
+// WriteWiki writes a synthetic Confluence HTML export of n pages to dir:
+// each page has a few sections of paragraphs and a table. The text is
+// random but the same for the same n.
+func WriteWiki(dir string, n int) error {
+	rng := rand.New(rand.NewPCG(1, 2))
+	sentence := func() string {
+		s := make([]string, 8+rng.IntN(10))
+		for i := range s {
+			s[i] = words[rng.IntN(len(words))]
+		}
+		return strings.ToUpper(s[0][:1]) + strings.Join(s, " ")[1:] + "."
+	}
+	for p := range n {
+		var b strings.Builder
+		title := fmt.Sprintf("Page %d: %s %s", p+1, words[p%len(words)], words[(p*7)%len(words)])
+		fmt.Fprintf(&b, "<html><head><title>%s</title></head><body>\n", html.EscapeString(title))
+		for s := range 3 + rng.IntN(3) {
+			fmt.Fprintf(&b, "<h2 id=\"Page%d-Section%d\">Section %d: %s</h2>\n", p+1, s+1, s+1, words[rng.IntN(len(words))])
+			for range 2 + rng.IntN(3) {
+				var para []string
+				for range 3 + rng.IntN(4) {
+					para = append(para, sentence())
+				}
+				fmt.Fprintf(&b, "<p>%s</p>\n", strings.Join(para, " "))
+			}
+		}
+		b.WriteString("<table><tr><th>Host</th><th>Role</th><th>Owner</th></tr>\n")
+		for r := range 4 {
+			fmt.Fprintf(&b, "<tr><td>host-%d-%d</td><td>%s</td><td>%s</td></tr>\n", p+1, r+1, words[rng.IntN(len(words))], words[rng.IntN(len(words))])
+		}
+		b.WriteString("</table>\n</body></html>\n")
+		name := filepath.Join(dir, fmt.Sprintf("page-%04d.html", p+1))
+		if err := os.WriteFile(name, []byte(b.String()), 0644); err != nil {
+			return fmt.Errorf("failed to write synthetic wiki: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/rathore/langchain-agent/tools"
+)
+
+// approver asks whether a risky tool call may run (--approve). It asks only
+// during the REPL's own runs, on the REPL's input; calls made anywhere else
+// (--batch, or triggers and webhook users through turnDown) are turned down.
+type approver struct {
+	mu    sync.Mutex
+	out   io.Writer
+	lines lineReader // nil between REPL runs
+}
+
+// asking lets the approver ask on lines until done is called
+func (a *approver) asking(lines lineReader) (done func()) {
+	a.mu.Lock()
+	a.lines = lines
+	a.mu.Unlock()
+	return func() {
+		a.mu.Lock()
+		a.lines = nil
+		a.mu.Unlock()
+	}
+}
+
+// turnDown is the policy.ApproveFunc of runs nobody watches
+func turnDown(string, tools.Risk, map[string]any) bool { return false }
+
+// approve is a policy.ApproveFunc
+func (a *approver) approve(tool string, risk tools.Risk, params map[string]any) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.lines == nil {
+		return false
+	}
+	args, _ := json.Marshal(params)
+	fmt.Fprintf(a.out, "\n[Approve] %s %s is a %s risk call. Run it? [y/N]", tool, args, risk)
+	answer, err := a.lines.ReadLine()
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/rathore/langchain-agent/tools"
+)
+
+func TestApprover(t *testing.T) {
+	var out strings.Builder
+	a := &approver{out: &out}
+	params := map[string]any{"command": "reboot"}
+
+	if a.approve("shell", tools.RiskHigh, params) {
+		t.Error("approved outside a REPL run")
+	}
+	if out.Len() != 0 {
+		t.Errorf("asked outside a REPL run: %q", out.String())
+	}
+
+	lines := &scannerReader{scanner: bufio.NewScanner(strings.NewReader("Yes\nn\n"))}
+	done := a.asking(lines)
+	if !a.approve("shell", tools.RiskHigh, params) {
+		t.Error("\"Yes\" not taken as approval")
+	}
+	if a.approve("shell", tools.RiskHigh, params) {
+		t.Error("\"n\" taken as approval")
+	}
+	if a.approve("shell", tools.RiskHigh, params) {
+		t.Error("approved at end of input")
+	}
+	done()
+	if want := `[Approve] shell {"command":"reboot"} is a high risk call. Run it? [y/N]`; !strings.Contains(out.String(), want) {
+		t.Errorf("prompt = %q, want %q", out.String(), want)
+	}
+	if a.lines != nil {
+		t.Error("still asking after done")
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/rathore/langchain-agent/agent"
+	"github.com/rathore/langchain-agent/format"
+)
+
+// batchQuery is one query of a --batch file
+type batchQuery struct {
+	ID    string `json:"id"`
+	Query string `json:"query"`
+}
+
+// readBatch reads a --batch file: one query per line, or JSON lines of
+// {"id": "...", "query": "..."} (id optional). Blank lines and lines
+// starting with # are skipped; queries without an id are numbered by line.
+func readBatch(path string) ([]batchQuery, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open batch file: %w", err)
+	}
+	defer f.Close()
+
+	var queries []batchQuery
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		q := batchQuery{Query: line}
+		if strings.HasPrefix(line, "{") {
+			q = batchQuery{}
+			if err := json.Unmarshal([]byte(line), &q); err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid JSON: %w", path, n, err)
+			}
+			if q.Query == "" {
+				return nil, fmt.Errorf("%s:%d: query is required", path, n)
+			}
+		}
+		if q.ID == "" {
+			q.ID = strconv.Itoa(n)
+		}
+		queries = append(queries, q)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read batch file: %w", err)
+	}
+	return queries, nil
+}
+
+// runBatch runs each query and writes its run result to out, titled by the
+// query, passing it to record as well. With shared, queries continue one
+// conversation; otherwise history is cleared before each. It returns the
+// number of runs that failed.
+func runBatch(ctx context.Context, ag *agent.Agent, queries []batchQuery, shared bool, out *format.Encoder, record func(*agent.RunResult, error)) (int, error) {
+	failed := 0
+	for i, q := range queries {
+		if ctx.Err() != nil {
+			return failed, ctx.Err()
+		}
+		if !shared {
+			ag.ClearHistory()
+		}
+		fmt.Printf("\n[Batch %d/%d] %s\n", i+1, len(queries), q.Query)
+		result, err := ag.RunDetailed(ctx, q.Query)
+		record(result, err)
+		record := runOutput{ID: q.ID, RunResult: result, Title: q.Query}
+		if err != nil {
+			failed++
+			record.Error = err.Error()
+		}
+		if err := out.Encode(record); err != nil {
+			return failed, fmt.Errorf("failed to write batch result: %w", err)
+		}
+	}
+	return failed, nil
+}
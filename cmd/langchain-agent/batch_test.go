@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rathore/langchain-agent/agent"
+	"github.com/rathore/langchain-agent/format"
+	"github.com/rathore/langchain-agent/llm"
+	"github.com/rathore/langchain-agent/replay"
+)
+
+func TestReadBatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queries.txt")
+	os.WriteFile(path, []byte(`# smoke tests
+what is the uptime of web1?
+
+{"id": "disk", "query": "check disk usage on db1"}
+{"query": "who is on call?"}
+`), 0644)
+
+	queries, err := readBatch(path)
+	if err != nil {
+		t.Fatalf("readBatch() error = %v", err)
+	}
+	want := []batchQuery{
+		{ID: "2", Query: "what is the uptime of web1?"},
+		{ID: "disk", Query: "check disk usage on db1"},
+		{ID: "5", Query: "who is on call?"},
+	}
+	if len(queries) != len(want) {
+		t.Fatalf("readBatch() = %+v, want %+v", queries, want)
+	}
+	for i := range want {
+		if queries[i] != want[i] {
+			t.Errorf("query %d = %+v, want %+v", i, queries[i], want[i])
+		}
+	}
+
+	os.WriteFile(path, []byte(`{"id": "x"}`+"\n"), 0644)
+	if _, err := readBatch(path); err == nil || !strings.Contains(err.Error(), ":1: query is required") {
+		t.Errorf("readBatch() error = %v, want a missing-query error with the line", err)
+	}
+}
+
+func TestRunBatch(t *testing.T) {
+	for _, shared := range []bool{false, true} {
+		client := &scriptedClient{responses: []*llm.Response{
+			{Content: "first", IsFinish: true},
+			{Content: "second", IsFinish: true},
+		}}
+		var prompts [][]llm.Message
+		cassette := replay.NewRecorder(recordingClient{client, &prompts})
+		ag, _ := agent.New(agent.Config{Client: cassette})
+
+		var out strings.Builder
+		queries := []batchQuery{{ID: "a", Query: "q1"}, {ID: "b", Query: "q2"}, {ID: "c", Query: "q3"}}
+		failed, err := runBatch(context.Background(), ag, queries, shared, format.NewEncoder(&out, format.JSON{}), cassette.AddRun)
+		if err != nil {
+			t.Fatalf("runBatch() error = %v", err)
+		}
+		if failed != 1 {
+			t.Errorf("failed = %d, want 1 (the third query has no response)", failed)
+		}
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("got %d result lines, want 3:\n%s", len(lines), out.String())
+		}
+		var second, third map[string]any
+		json.Unmarshal([]byte(lines[1]), &second)
+		json.Unmarshal([]byte(lines[2]), &third)
+		if second["id"] != "b" || second["query"] != "q2" || second["answer"] != "second" {
+			t.Errorf("second result = %v", second)
+		}
+		if third["error"] == nil {
+			t.Errorf("third result = %v, want an error", third)
+		}
+
+		// The second query sees the first only in a shared session
+		sawFirst := false
+		for _, msg := range prompts[1] {
+			if msg.Content == "q1" {
+				sawFirst = true
+			}
+		}
+		if sawFirst != shared {
+			t.Errorf("shared=%v: second prompt includes the first query = %v", shared, sawFirst)
+		}
+
+		// --record files every run, noting which started a new conversation
+		runs := cassette.Cassette(ag.Registry()).Runs
+		if len(runs) != 3 || runs[1].Query != "q2" || runs[1].Fresh == shared || runs[2].Error == "" {
+			t.Errorf("shared=%v: recorded runs = %+v", shared, runs)
+		}
+	}
+
+	// Other --output formats title each result by its query
+	client := &scriptedClient{responses: []*llm.Response{{Content: "**up** 3 days", IsFinish: true}}}
+	ag, _ := agent.New(agent.Config{Client: client})
+	var out strings.Builder
+	runBatch(context.Background(), ag, []batchQuery{{ID: "1", Query: "uptime?"}}, false, format.NewEncoder(&out, format.Plain{}), func(*agent.RunResult, error) {})
+	if want := "[uptime?]\nup 3 days\n"; out.String() != want {
+		t.Errorf("plain batch output = %q, want %q", out.String(), want)
+	}
+}
+
+// recordingClient records the messages sent to a client
+type recordingClient struct {
+	llm.ChatClient
+	prompts *[][]llm.Message
+}
+
+func (c recordingClient) Chat(ctx context.Context, messages []llm.Message) (*llm.Response, error) {
+	*c.prompts = append(*c.prompts, messages)
+	return c.ChatClient.Chat(ctx, messages)
+}
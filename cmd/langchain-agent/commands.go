@@ -0,0 +1,534 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rathore/langchain-agent/agent"
+	"github.com/rathore/langchain-agent/llm"
+	"github.com/rathore/langchain-agent/tools"
+)
+
+// toolsCommand handles "/tools", "/tools <name>", "/tools enable <name>"
+// and "/tools disable <name>"
+func toolsCommand(w io.Writer, ag *agent.Agent, args []string) {
+	switch {
+	case len(args) == 2 && (args[0] == "enable" || args[0] == "disable"):
+		if err := ag.SetToolEnabled(args[1], args[0] == "enable"); err != nil {
+			fmt.Fprintln(w, err)
+			return
+		}
+		fmt.Fprintf(w, "Tool %q %sd.\n", args[1], args[0])
+	case len(args) <= 1:
+		found := false
+		for _, t := range ag.Tools() {
+			if len(args) == 1 && t.Name() != args[0] {
+				continue
+			}
+			found = true
+			printTool(w, t, ag.ToolEnabled(t.Name()))
+			printToolMeta(w, ag.Registry(), t.Name())
+		}
+		if !found {
+			fmt.Fprintf(w, "unknown tool: %s\n", args[0])
+		}
+	default:
+		fmt.Fprintln(w, "Usage: /tools [name] | /tools enable <name> | /tools disable <name>")
+	}
+}
+
+// printTool writes a tool's name, state, description and parameters. For an
+// MCP tool the tools discovered on its server are listed instead of the
+// wrapper's own parameters.
+func printTool(w io.Writer, t tools.Tool, enabled bool) {
+	state := "enabled"
+	if !enabled {
+		state = "disabled"
+	}
+	fmt.Fprintf(w, "%s [%s]\n  %s\n", t.Name(), state, t.Description())
+	for {
+		u, ok := t.(interface{ Unwrap() tools.Tool })
+		if !ok {
+			break
+		}
+		t = u.Unwrap() // registered under a namespaced name, or with limits
+	}
+	if m, ok := t.(*tools.MCPTool); ok {
+		for _, st := range m.ServerTools() {
+			fmt.Fprintf(w, "  - %s: %s\n", st.Name, firstLine(st.Description))
+			if params := schemaSummary(st.Parameters); params != "" {
+				fmt.Fprintf(w, "      params: %s\n", params)
+			}
+		}
+		return
+	}
+	if params := schemaSummary(t.Parameters()); params != "" {
+		fmt.Fprintf(w, "  params: %s\n", params)
+	}
+}
+
+// printToolMeta writes what the registry knows about a tool: its category,
+// whether it (or which of its calls) is read-only, the risk of its other
+// calls, its typical latency and its aliases
+func printToolMeta(w io.Writer, r *tools.Registry, name string) {
+	meta, _ := r.Meta(name)
+	var parts []string
+	if meta.Category != "" {
+		parts = append(parts, "category: "+meta.Category)
+	}
+	if meta.ReadOnly {
+		parts = append(parts, "read-only")
+	} else {
+		if w := meta.ReadOnlyWhen; w != nil && len(w.Values) > 0 {
+			parts = append(parts, fmt.Sprintf("read-only %s: %s", w.Param, strings.Join(w.Values, ", ")))
+		}
+		parts = append(parts, "risk: "+string(meta.CallRisk(nil)))
+	}
+	if meta.Latency > 0 {
+		parts = append(parts, "latency: ~"+meta.Latency.String())
+	}
+	if aliases := r.Aliases(name); len(aliases) > 0 {
+		parts = append(parts, "aliases: "+strings.Join(aliases, ", "))
+	}
+	if len(parts) > 0 {
+		fmt.Fprintf(w, "  (%s)\n", strings.Join(parts, "; "))
+	}
+}
+
+// schemaSummary renders a JSON schema's properties as
+// "name (type, required), ..." in name order
+func schemaSummary(schema map[string]any) string {
+	props, _ := schema["properties"].(map[string]any)
+	required := map[string]bool{}
+	switch req := schema["required"].(type) {
+	case []string:
+		for _, name := range req {
+			required[name] = true
+		}
+	case []any:
+		for _, name := range req {
+			required[fmt.Sprint(name)] = true
+		}
+	}
+
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		typ := "any"
+		if p, ok := props[name].(map[string]any); ok {
+			if s, ok := p["type"].(string); ok {
+				typ = s
+			}
+		}
+		if required[name] {
+			typ += ", required"
+		}
+		parts = append(parts, fmt.Sprintf("%s (%s)", name, typ))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// firstLine returns the first line of s
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
+
+// historyCommand prints the conversation history sent to the LLM
+func historyCommand(w io.Writer, ag *agent.Agent) {
+	history := ag.History()
+	if len(history) == 0 {
+		fmt.Fprintln(w, "History is empty.")
+		return
+	}
+	for _, msg := range history {
+		fmt.Fprintf(w, "[%s] %s\n", msg.Role, msg.Content)
+	}
+	if summary, n := ag.ConversationSummary(); n > 0 {
+		fmt.Fprintf(w, "[summary] Sent instead of the first %d messages: %s\n", n, summary)
+	}
+}
+
+// showCommand prints the full trace of the last run: every LLM step and
+// tool call with its output and timing
+func showCommand(w io.Writer, ag *agent.Agent) {
+	run := ag.LastRun()
+	if run == nil {
+		fmt.Fprintln(w, "No run yet.")
+		return
+	}
+	fmt.Fprintf(w, "Query: %s\n", run.Query)
+	for i, step := range run.Steps {
+		fmt.Fprintf(w, "\n--- Step %d (LLM %s) ---\n%s\n", i+1, round(step.Elapsed), step.Output)
+		if tc := step.ToolCall; tc != nil {
+			fmt.Fprintf(w, "[Tool Call] %s: %v (%s)\n", tc.Name, tc.Params, round(tc.Elapsed))
+			if tc.Error != "" {
+				fmt.Fprintf(w, "[Tool Error] %s\n", tc.Error)
+			} else {
+				fmt.Fprintf(w, "[Tool Result]\n%s\n", tc.Result)
+			}
+		}
+		if step.Nudge != "" {
+			fmt.Fprintf(w, "[Nudge] %s\n", step.Nudge)
+		}
+	}
+	fmt.Fprintf(w, "\n%d steps in %s", len(run.Steps), round(run.Elapsed))
+	if run.Usage.TotalTokens > 0 {
+		fmt.Fprintf(w, ", %d tokens (%d prompt, %d completion)", run.Usage.TotalTokens, run.Usage.PromptTokens, run.Usage.CompletionTokens)
+	}
+	if run.Cost > 0 {
+		fmt.Fprintf(w, ", $%.4f", run.Cost)
+	}
+	fmt.Fprintln(w)
+	if run.Answer == "" {
+		fmt.Fprintln(w, "The run ended without an answer.")
+	}
+}
+
+// statsCommand prints what the session's runs have used: runs, tokens and,
+// with a price, their cost against maxCost
+func statsCommand(w io.Writer, totals agent.Totals, price *llm.Price, maxCost float64) {
+	fmt.Fprintf(w, "Queries: %d\n", totals.Runs)
+	fmt.Fprintf(w, "Tokens:  %d (%d prompt, %d completion)\n", totals.Usage.TotalTokens, totals.Usage.PromptTokens, totals.Usage.CompletionTokens)
+	switch {
+	case price == nil:
+		fmt.Fprintln(w, "Cost:    not tracked (local model or unknown price; see --price)")
+	case maxCost > 0:
+		fmt.Fprintf(w, "Cost:    $%.4f of $%.2f limit (%s)\n", totals.Cost, maxCost, price)
+	default:
+		fmt.Fprintf(w, "Cost:    $%.4f (%s)\n", totals.Cost, price)
+	}
+}
+
+// flakyErrorRate is the error rate from which /stats tools flags a tool
+const flakyErrorRate = 0.2
+
+// toolStatsCommand handles "/stats tools": every tool's calls, error rate
+// and latency across sessions, most used first, then the registered tools
+// never called
+func toolStatsCommand(w io.Writer, stats *tools.Stats, registered []tools.Tool) {
+	if stats == nil {
+		fmt.Fprintln(w, "Tool stats are off (--no-tool-stats).")
+		return
+	}
+	snapshot, since := stats.Snapshot()
+	names := make([]string, 0, len(snapshot))
+	for name := range snapshot {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if a, b := snapshot[names[i]].Calls, snapshot[names[j]].Calls; a != b {
+			return a > b
+		}
+		return names[i] < names[j]
+	})
+
+	if len(names) == 0 {
+		fmt.Fprintln(w, "No tool calls recorded yet.")
+	} else {
+		fmt.Fprintf(w, "Tool calls since %s:\n", since.Format("2006-01-02"))
+		fmt.Fprintf(w, "  %-20s %7s %7s %10s %10s %10s  %s\n", "TOOL", "CALLS", "ERRORS", "AVG", "P95", "MAX", "LAST USED")
+		for _, name := range names {
+			t := snapshot[name]
+			errors := fmt.Sprintf("%.0f%%", 100*t.ErrorRate())
+			if t.Calls >= 5 && t.ErrorRate() >= flakyErrorRate {
+				errors += "!"
+			}
+			fmt.Fprintf(w, "  %-20s %7d %7s %10s %10s %10s  %s\n", name, t.Calls, errors,
+				round(t.Mean()), round(t.Percentile(0.95)), round(t.Max), t.Last.Format("2006-01-02 15:04"))
+		}
+		fmt.Fprintf(w, "  (! = at least %.0f%% of 5 or more calls failed)\n", 100*flakyErrorRate)
+	}
+
+	var unused []string
+	for _, t := range registered {
+		if snapshot[t.Name()].Calls == 0 {
+			unused = append(unused, t.Name())
+		}
+	}
+	if len(unused) > 0 {
+		fmt.Fprintf(w, "Never called: %s\n", strings.Join(unused, ", "))
+	}
+}
+
+// round shortens a duration for display
+func round(d time.Duration) time.Duration {
+	return d.Round(time.Millisecond)
+}
+
+// exportCommand writes the conversation since the last /clear to path as a
+// Markdown transcript
+func exportCommand(w io.Writer, ag *agent.Agent, path string) {
+	if path == "" {
+		fmt.Fprintln(w, "Usage: /export <file.md>")
+		return
+	}
+	runs := ag.Runs()
+	if len(runs) == 0 {
+		fmt.Fprintln(w, "Nothing to export yet.")
+		return
+	}
+	if err := os.WriteFile(path, []byte(transcriptMarkdown(runs, time.Now())), 0644); err != nil {
+		fmt.Fprintf(w, "failed to write transcript: %v\n", err)
+		return
+	}
+	fmt.Fprintf(w, "Exported %d exchanges to %s\n", len(runs), path)
+}
+
+// transcriptMarkdown renders runs as Markdown: each question and answer,
+// with every tool call and its output in a collapsed <details> section
+func transcriptMarkdown(runs []*agent.RunResult, exported time.Time) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Agent transcript\n\n_Exported %s_\n", exported.Format("2006-01-02 15:04 MST"))
+	for _, run := range runs {
+		fmt.Fprintf(&sb, "\n---\n\n**User:** %s\n\n", run.Query)
+		for _, step := range run.Steps {
+			tc := step.ToolCall
+			if tc == nil {
+				continue
+			}
+			params, _ := json.Marshal(tc.Params)
+			status := ""
+			if tc.Error != "" {
+				status = " — failed"
+			}
+			fmt.Fprintf(&sb, "<details>\n<summary>Tool <code>%s</code> %s (%s)%s</summary>\n\n",
+				tc.Name, html.EscapeString(string(params)), round(tc.Elapsed), status)
+			output := tc.Result
+			if tc.Error != "" {
+				output = "Error: " + tc.Error
+			}
+			fence := codeFence(output)
+			fmt.Fprintf(&sb, "%s\n%s\n%s\n\n</details>\n\n", fence, strings.TrimRight(output, "\n"), fence)
+		}
+		if run.Answer != "" {
+			fmt.Fprintf(&sb, "**Agent:**\n\n%s\n", run.Answer)
+		} else {
+			sb.WriteString("_No answer (the run failed or was cancelled)._\n")
+		}
+	}
+	return sb.String()
+}
+
+// codeFence returns a backtick fence longer than any backtick run in s
+func codeFence(s string) string {
+	longest, run := 0, 0
+	for _, c := range s {
+		if c == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// retryCommand removes the last exchange and returns its query to run
+// again. With a model, a client for it is swapped in; restore puts the
+// previous client back once the run is done.
+func retryCommand(w io.Writer, ag *agent.Agent, model string, newClient func(model string) (llm.ChatClient, error)) (query string, restore func(), ok bool) {
+	if len(ag.Runs()) == 0 {
+		fmt.Fprintln(w, "Nothing to retry.")
+		return "", nil, false
+	}
+	restore = func() {}
+	if model != "" {
+		client, err := newClient(model)
+		if err != nil {
+			fmt.Fprintln(w, err)
+			return "", nil, false
+		}
+		prev := ag.SetClient(client)
+		restore = func() {
+			ag.SetClient(prev)
+			if c, ok := client.(io.Closer); ok {
+				c.Close()
+			}
+		}
+		fmt.Fprintf(w, "Retrying with model %s.\n", model)
+	}
+	return ag.UndoLastRun(), restore, true
+}
+
+// editCommand lets the user amend the last query with edit, then removes the
+// last exchange and returns the amended query to run in its place
+func editCommand(w io.Writer, ag *agent.Agent, edit func(text string) (string, error)) (string, bool) {
+	runs := ag.Runs()
+	if len(runs) == 0 {
+		fmt.Fprintln(w, "Nothing to edit.")
+		return "", false
+	}
+	query, err := edit(runs[len(runs)-1].Query)
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return "", false
+	}
+	query = strings.TrimSpace(query)
+	if query == "" {
+		fmt.Fprintln(w, "Empty query; nothing to run.")
+		return "", false
+	}
+	ag.UndoLastRun()
+	return query, true
+}
+
+// editText opens text in $VISUAL or $EDITOR (default vi) and returns the
+// saved result
+func editText(text string) (string, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	f, err := os.CreateTemp("", "langchain-agent-*.md")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(text + "\n")
+	f.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], f.Name())...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %s failed: %w", editor, err)
+	}
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", fmt.Errorf("failed to read edited query: %w", err)
+	}
+	return string(data), nil
+}
+
+// contextCommand handles "/context", "/context set key=value ...",
+// "/context unset key ..." and "/context clear", applying changes to every
+// agent (one per model with --compare)
+func contextCommand(w io.Writer, agents []*agent.Agent, args string) {
+	verb, rest, _ := strings.Cut(args, " ")
+	switch verb {
+	case "":
+	case "set":
+		defaults, err := agent.ParseDefaults(rest)
+		if err != nil || len(defaults) == 0 {
+			fmt.Fprintln(w, "Usage: /context set key=value ... (e.g. namespace=prod cluster=staging host=web-1)")
+			return
+		}
+		for _, ag := range agents {
+			for k, v := range defaults {
+				ag.SetDefault(k, v)
+			}
+		}
+	case "unset":
+		for _, ag := range agents {
+			for _, k := range strings.Fields(rest) {
+				ag.SetDefault(k, "")
+			}
+		}
+	case "clear":
+		for _, ag := range agents {
+			for k := range ag.Defaults() {
+				ag.SetDefault(k, "")
+			}
+		}
+	default:
+		fmt.Fprintln(w, "Usage: /context [set key=value ... | unset key ... | clear]")
+		return
+	}
+
+	defaults := agents[0].Defaults()
+	if len(defaults) == 0 {
+		fmt.Fprintln(w, "No session defaults. Set some with /context set namespace=prod cluster=staging host=web-1.")
+		return
+	}
+	keys := make([]string, 0, len(defaults))
+	for k := range defaults {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s = %s\n", k, defaults[k])
+	}
+}
+
+// prefsCommand handles "/prefs", "/prefs set key=value ...", "/prefs unset
+// key ..." and "/prefs clear" for every agent, reporting whether the
+// preferences changed
+func prefsCommand(w io.Writer, agents []*agent.Agent, args string) bool {
+	prefs := agents[0].Preferences()
+	verb, rest, _ := strings.Cut(args, " ")
+	switch verb {
+	case "":
+	case "set":
+		// Values may have spaces ("date_format=DD MMM YYYY"), so split on
+		// the keys rather than on whitespace
+		pairs := splitPrefs(rest)
+		if len(pairs) == 0 {
+			fmt.Fprintf(w, "Usage: /prefs set key=value ... (keys: %s)\n", strings.Join(agent.PreferenceKeys, ", "))
+			return false
+		}
+		for _, kv := range pairs {
+			if err := prefs.Set(kv[0], kv[1]); err != nil {
+				fmt.Fprintln(w, err)
+				return false
+			}
+		}
+	case "unset":
+		for _, key := range strings.Fields(rest) {
+			if err := prefs.Set(key, ""); err != nil {
+				fmt.Fprintln(w, err)
+				return false
+			}
+		}
+	case "clear":
+		prefs = agent.Preferences{}
+	default:
+		fmt.Fprintln(w, "Usage: /prefs [set key=value ... | unset key ... | clear]")
+		return false
+	}
+	if verb != "" {
+		for _, ag := range agents {
+			ag.SetPreferences(prefs)
+		}
+	}
+	if prefs.IsZero() {
+		fmt.Fprintf(w, "No answer preferences. Set some with /prefs set language=German verbosity=brief (keys: %s).\n", strings.Join(agent.PreferenceKeys, ", "))
+	} else {
+		fmt.Fprintf(w, "Answer preferences: %s\n", prefs)
+	}
+	return verb != ""
+}
+
+// splitPrefs splits "a=1 b=two words" into key/value pairs; nil if s doesn't
+// start with a key
+func splitPrefs(s string) [][2]string {
+	var pairs [][2]string
+	for _, field := range strings.Fields(s) {
+		if key, value, ok := strings.Cut(field, "="); ok && key != "" {
+			pairs = append(pairs, [2]string{key, value})
+			continue
+		}
+		if len(pairs) == 0 {
+			return nil
+		}
+		pairs[len(pairs)-1][1] += " " + field
+	}
+	return pairs
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rathore/langchain-agent/agent"
+	"github.com/rathore/langchain-agent/llm"
+	"github.com/rathore/langchain-agent/tools"
+)
+
+// stubClient is an llm.ChatClient that always gives the same answer
+type stubClient struct{}
+
+func (stubClient) Chat(ctx context.Context, messages []llm.Message) (*llm.Response, error) {
+	return &llm.Response{Content: "ok", IsFinish: true}, nil
+}
+
+func TestToolsCommand(t *testing.T) {
+	registry := tools.NewRegistry()
+	registry.Register(&tools.ShellTool{}, tools.Meta{Category: tools.CategoryLocal})
+	registry.Register(&tools.SSHTool{}, tools.Meta{Category: tools.CategoryRemote})
+	registry.Alias("bash", "shell")
+	ag, err := agent.New(agent.Config{Client: stubClient{}, Registry: registry})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	toolsCommand(&out, ag, nil)
+	for _, want := range []string{"shell [enabled]", "ssh [enabled]", "command (string, required)", "(category: local; risk: high; aliases: bash)", "(category: remote; risk: high; latency: ~2s)"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("/tools output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	toolsCommand(&out, ag, []string{"disable", "shell"})
+	toolsCommand(&out, ag, []string{"shell"})
+	if !strings.Contains(out.String(), "shell [disabled]") || strings.Contains(out.String(), "ssh [") {
+		t.Errorf("/tools shell after disable:\n%s", out.String())
+	}
+	if ag.ToolEnabled("shell") {
+		t.Error("shell should be disabled")
+	}
+
+	out.Reset()
+	toolsCommand(&out, ag, []string{"enable", "nope"})
+	if !strings.Contains(out.String(), "unknown tool") {
+		t.Errorf("enabling an unknown tool: %q", out.String())
+	}
+}
+
+func TestSchemaSummary(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"host":    map[string]any{"type": "string"},
+			"command": map[string]any{"type": "string"},
+			"extra":   map[string]any{},
+		},
+		"required": []any{"command"},
+	}
+	want := "command (string, required), extra (any), host (string)"
+	if got := schemaSummary(schema); got != want {
+		t.Errorf("schemaSummary() = %q, want %q", got, want)
+	}
+}
+
+// scriptedClient is an llm.ChatClient returning its responses in turn
+type scriptedClient struct {
+	responses []*llm.Response
+}
+
+func (c *scriptedClient) Chat(ctx context.Context, messages []llm.Message) (*llm.Response, error) {
+	if len(c.responses) == 0 {
+		return nil, errors.New("no more responses")
+	}
+	resp := c.responses[0]
+	c.responses = c.responses[1:]
+	return resp, nil
+}
+
+func TestHistoryAndShowCommands(t *testing.T) {
+	client := &scriptedClient{responses: []*llm.Response{
+		{
+			Content:   `{"name": "shell", "parameters": {"command": "echo hi"}}`,
+			ToolCalls: []llm.ToolCallParse{{Name: "shell", Params: map[string]any{"command": "echo hi"}}},
+		},
+		{Content: "It printed hi.", IsFinish: true, Usage: llm.Usage{PromptTokens: 40, CompletionTokens: 5, TotalTokens: 45}},
+	}}
+	ag, _ := agent.New(agent.Config{Client: client, Tools: []tools.Tool{&tools.ShellTool{}}})
+
+	var out strings.Builder
+	historyCommand(&out, ag)
+	showCommand(&out, ag)
+	if !strings.Contains(out.String(), "History is empty.") || !strings.Contains(out.String(), "No run yet.") {
+		t.Errorf("before any run:\n%s", out.String())
+	}
+
+	if _, err := ag.Run(context.Background(), "run echo hi"); err != nil {
+		t.Fatal(err)
+	}
+
+	out.Reset()
+	historyCommand(&out, ag)
+	if got := out.String(); got != "[user] run echo hi\n[assistant] It printed hi.\n" {
+		t.Errorf("/history = %q", got)
+	}
+
+	out.Reset()
+	showCommand(&out, ag)
+	for _, want := range []string{"Query: run echo hi", "--- Step 1", "[Tool Call] shell: map[command:echo hi]", "[Tool Result]\nhi\n", "--- Step 2", "It printed hi.", "2 steps in", "45 tokens"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("/show output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestStatsCommand(t *testing.T) {
+	totals := agent.Totals{Runs: 2, Usage: llm.Usage{PromptTokens: 1200, CompletionTokens: 300, TotalTokens: 1500}, Cost: 0.0123}
+	var sb strings.Builder
+	statsCommand(&sb, totals, &llm.Price{Input: 1.25, Output: 10}, 5)
+	for _, want := range []string{"Queries: 2", "Tokens:  1500 (1200 prompt, 300 completion)", "Cost:    $0.0123 of $5.00 limit ($1.25/$10 per 1M tokens)"} {
+		if !strings.Contains(sb.String(), want) {
+			t.Errorf("/stats output missing %q:\n%s", want, sb.String())
+		}
+	}
+
+	sb.Reset()
+	statsCommand(&sb, totals, nil, 0)
+	if !strings.Contains(sb.String(), "not tracked") {
+		t.Errorf("/stats without a price = %q", sb.String())
+	}
+}
+
+func TestToolStatsCommand(t *testing.T) {
+	stats := tools.NewStats()
+	for i := 0; i < 5; i++ {
+		stats.Record("ssh", 2*time.Second, i < 2)
+	}
+	stats.Record("shell", 100*time.Millisecond, false)
+	var sb strings.Builder
+	toolStatsCommand(&sb, stats, []tools.Tool{&tools.ShellTool{}, &tools.SSHTool{}, &tools.EdgeTempTool{}})
+	out := sb.String()
+	if !regexp.MustCompile(`(?s)ssh +5 +40%! .*shell +1 +0% `).MatchString(out) {
+		t.Errorf("/stats tools doesn't rank ssh first and flag it:\n%s", out)
+	}
+	if !strings.Contains(out, "Never called: edge_temp") {
+		t.Errorf("/stats tools doesn't list the unused tool:\n%s", out)
+	}
+
+	sb.Reset()
+	toolStatsCommand(&sb, nil, nil)
+	if !strings.Contains(sb.String(), "--no-tool-stats") {
+		t.Errorf("/stats tools without stats = %q", sb.String())
+	}
+}
+
+func TestExportCommand(t *testing.T) {
+	client := &scriptedClient{responses: []*llm.Response{
+		{
+			Content:   `{"name": "shell", "parameters": {"command": "echo '` + "```" + `'"}}`,
+			ToolCalls: []llm.ToolCallParse{{Name: "shell", Params: map[string]any{"command": "echo '```'"}}},
+		},
+		{Content: "It printed a **fence**.", IsFinish: true},
+		{Content: "Nothing else.", IsFinish: true},
+	}}
+	ag, _ := agent.New(agent.Config{Client: client, Tools: []tools.Tool{&tools.ShellTool{}}})
+	ag.Run(context.Background(), "print a fence")
+	ag.Run(context.Background(), "anything else?")
+
+	path := filepath.Join(t.TempDir(), "incident.md")
+	var out strings.Builder
+	exportCommand(&out, ag, path)
+	if !strings.Contains(out.String(), "Exported 2 exchanges") {
+		t.Fatalf("/export output = %q", out.String())
+	}
+	data, _ := os.ReadFile(path)
+	md := string(data)
+	for _, want := range []string{
+		"**User:** print a fence",
+		"<summary>Tool <code>shell</code> {&#34;command&#34;:&#34;echo &#39;```&#39;&#34;}",
+		"````\n```\n````", // the output's own fence can't close the block
+		"</details>",
+		"**Agent:**\n\nIt printed a **fence**.",
+		"**User:** anything else?",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("transcript missing %q:\n%s", want, md)
+		}
+	}
+
+	ag.ClearHistory()
+	out.Reset()
+	exportCommand(&out, ag, path)
+	if !strings.Contains(out.String(), "Nothing to export") {
+		t.Errorf("/export after /clear = %q", out.String())
+	}
+}
+
+func TestRetryCommand(t *testing.T) {
+	first := &scriptedClient{responses: []*llm.Response{{Content: "first answer", IsFinish: true}}}
+	ag, _ := agent.New(agent.Config{Client: first})
+	second := &scriptedClient{responses: []*llm.Response{{Content: "second answer", IsFinish: true}}}
+	newClient := func(model string) (llm.ChatClient, error) {
+		if model != "big" {
+			return nil, errors.New("unknown model " + model)
+		}
+		return second, nil
+	}
+
+	var out strings.Builder
+	if _, _, ok := retryCommand(&out, ag, "", newClient); ok || !strings.Contains(out.String(), "Nothing to retry.") {
+		t.Errorf("/retry before any run: ok=%v, output %q", ok, out.String())
+	}
+
+	ag.Run(context.Background(), "what is up?")
+	out.Reset()
+	if _, _, ok := retryCommand(&out, ag, "tiny", newClient); ok || !strings.Contains(out.String(), "unknown model tiny") {
+		t.Errorf("/retry tiny: ok=%v, output %q", ok, out.String())
+	}
+	if len(ag.History()) != 2 {
+		t.Errorf("a failed /retry should keep the exchange, history = %+v", ag.History())
+	}
+
+	query, restore, ok := retryCommand(&out, ag, "big", newClient)
+	if !ok || query != "what is up?" {
+		t.Fatalf("/retry big = %q, %v", query, ok)
+	}
+	answer, err := ag.Run(context.Background(), query)
+	restore()
+	if err != nil || answer != "second answer" {
+		t.Errorf("retried run = %q, %v, want the other model's answer", answer, err)
+	}
+	if history := ag.History(); len(history) != 2 || history[1].Content != "second answer" {
+		t.Errorf("history after /retry = %+v, want only the retried exchange", history)
+	}
+	if prev := ag.SetClient(first); prev != first {
+		t.Error("restore should put the original client back")
+	}
+}
+
+func TestEditCommand(t *testing.T) {
+	ag, _ := agent.New(agent.Config{Client: stubClient{}})
+	upper := func(text string) (string, error) { return strings.ToUpper(text) + "\n", nil }
+
+	var out strings.Builder
+	if _, ok := editCommand(&out, ag, upper); ok || !strings.Contains(out.String(), "Nothing to edit.") {
+		t.Errorf("/edit before any run: ok=%v, output %q", ok, out.String())
+	}
+
+	ag.Run(context.Background(), "check disk on db1")
+	out.Reset()
+	if _, ok := editCommand(&out, ag, func(string) (string, error) { return " \n", nil }); ok || len(ag.Runs()) != 1 {
+		t.Errorf("/edit to an empty query: ok=%v, runs %d, output %q", ok, len(ag.Runs()), out.String())
+	}
+	if _, ok := editCommand(&out, ag, func(string) (string, error) { return "", errors.New("editor vi failed") }); ok || !strings.Contains(out.String(), "editor vi failed") {
+		t.Errorf("/edit with a failing editor: ok=%v, output %q", ok, out.String())
+	}
+
+	query, ok := editCommand(&out, ag, upper)
+	if !ok || query != "CHECK DISK ON DB1" {
+		t.Errorf("/edit = %q, %v", query, ok)
+	}
+	if len(ag.Runs()) != 0 || len(ag.History()) != 0 {
+		t.Errorf("/edit should remove the exchange it replaces, history = %+v", ag.History())
+	}
+}
+
+func TestEditText(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "editor.sh")
+	os.WriteFile(script, []byte("#!/bin/sh\nsed -i 's/db1/db2/' \"$1\"\n"), 0755)
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", script)
+
+	got, err := editText("check disk on db1")
+	if err != nil || got != "check disk on db2\n" {
+		t.Errorf("editText() = %q, %v", got, err)
+	}
+}
+
+func TestContextCommand(t *testing.T) {
+	a, _ := agent.New(agent.Config{Client: &scriptedClient{}})
+	b, _ := agent.New(agent.Config{Client: &scriptedClient{}})
+	agents := []*agent.Agent{a, b}
+	var sb strings.Builder
+	contextCommand(&sb, agents, "set namespace=prod cluster=staging")
+	if sb.String() != "cluster = staging\nnamespace = prod\n" {
+		t.Errorf("/context set output = %q", sb.String())
+	}
+	if b.Defaults()["namespace"] != "prod" {
+		t.Errorf("second agent defaults = %v, want them set too", b.Defaults())
+	}
+
+	sb.Reset()
+	contextCommand(&sb, agents, "unset cluster")
+	if sb.String() != "namespace = prod\n" {
+		t.Errorf("/context unset output = %q", sb.String())
+	}
+
+	sb.Reset()
+	contextCommand(&sb, agents, "set namespace")
+	if !strings.Contains(sb.String(), "Usage") {
+		t.Errorf("/context set without a value = %q, want usage", sb.String())
+	}
+
+	sb.Reset()
+	contextCommand(&sb, agents, "clear")
+	if !strings.Contains(sb.String(), "No session defaults") || len(b.Defaults()) != 0 {
+		t.Errorf("/context clear output = %q, defaults %v", sb.String(), b.Defaults())
+	}
+}
+
+func TestPrefsCommand(t *testing.T) {
+	a, _ := agent.New(agent.Config{Client: &scriptedClient{}})
+	var sb strings.Builder
+	if !prefsCommand(&sb, []*agent.Agent{a}, "set language=Brazilian Portuguese verbosity=brief date_format=DD/MM/YYYY") {
+		t.Fatalf("/prefs set reported no change: %s", sb.String())
+	}
+	want := agent.Preferences{Language: "Brazilian Portuguese", Verbosity: "brief", DateFormat: "DD/MM/YYYY"}
+	if got := a.Preferences(); got != want {
+		t.Errorf("preferences = %+v, want %+v", got, want)
+	}
+
+	sb.Reset()
+	if prefsCommand(&sb, []*agent.Agent{a}, "set units=furlongs") || !strings.Contains(sb.String(), "metric or imperial") {
+		t.Errorf("/prefs set with bad units = %q", sb.String())
+	}
+	sb.Reset()
+	prefsCommand(&sb, []*agent.Agent{a}, "unset verbosity")
+	if sb.String() != "Answer preferences: language=Brazilian Portuguese date_format=DD/MM/YYYY\n" {
+		t.Errorf("/prefs unset output = %q", sb.String())
+	}
+	sb.Reset()
+	prefsCommand(&sb, []*agent.Agent{a}, "clear")
+	if !a.Preferences().IsZero() || !strings.Contains(sb.String(), "No answer preferences") {
+		t.Errorf("/prefs clear: %+v, %q", a.Preferences(), sb.String())
+	}
+}
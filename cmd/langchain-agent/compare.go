@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rathore/langchain-agent/agent"
+)
+
+// contender is one model of a --compare session, with its own agent and so
+// its own history
+type contender struct {
+	model string
+	agent *agent.Agent
+}
+
+// compareOutput is the --output json record of a --compare query
+type compareOutput struct {
+	Query string          `json:"query"`
+	Runs  []compareResult `json:"runs"`
+}
+
+// compareResult is one model's run of a --compare query
+type compareResult struct {
+	Model string `json:"model"`
+	runOutput
+}
+
+// parseCompare splits the --compare list into at least two distinct models
+func parseCompare(list string) ([]string, error) {
+	var models []string
+	seen := map[string]bool{}
+	for _, m := range strings.Split(list, ",") {
+		m = strings.TrimSpace(m)
+		if m == "" || seen[m] {
+			continue
+		}
+		seen[m] = true
+		models = append(models, m)
+	}
+	if len(models) < 2 {
+		return nil, fmt.Errorf("--compare needs two or more models, e.g. --compare qwen2.5:32b,llama3.1")
+	}
+	return models, nil
+}
+
+// compareQuery runs query through each contender in turn and prints each
+// answer, then a table of what each run took. With out set, one JSON
+// record of all the runs is written to it instead.
+func compareQuery(ctx context.Context, w io.Writer, contenders []contender, query string, color bool, out *json.Encoder, record func(*agent.RunResult, error)) {
+	results := make([]compareResult, len(contenders))
+	for i, c := range contenders {
+		fmt.Fprintf(w, "\n=== %s ===\n", c.model)
+		result, err := c.agent.RunDetailed(ctx, query)
+		record(result, err)
+		results[i] = compareResult{Model: c.model, runOutput: runOutput{RunResult: result}}
+		if err != nil {
+			results[i].Error = err.Error()
+		}
+		if errors.Is(err, context.Canceled) {
+			fmt.Fprintln(w, "\n[Cancelled]")
+			return
+		}
+	}
+
+	if out != nil {
+		if err := out.Encode(compareOutput{Query: query, Runs: results}); err != nil {
+			fmt.Fprintf(w, "Write error: %v\n", err)
+		}
+		return
+	}
+	for _, r := range results {
+		if r.Error != "" {
+			fmt.Fprintf(w, "\n[Error %s] %s\n", r.Model, r.Error)
+			continue
+		}
+		fmt.Fprintf(w, "\n[Answer %s]\n%s\n", r.Model, renderMarkdown(r.Answer, color))
+	}
+	fmt.Fprintln(w)
+	writeCompareTable(w, results)
+}
+
+// undoContenders removes the last exchange from every contender but the
+// first, whose exchange /retry and /edit have removed already
+func undoContenders(contenders []contender) {
+	for _, c := range contenders[min(1, len(contenders)):] {
+		c.agent.UndoLastRun()
+	}
+}
+
+// writeCompareTable writes a row per model: steps, tools called, tokens,
+// time and, for hosted models, cost
+func writeCompareTable(w io.Writer, results []compareResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Model\tSteps\tTool calls\tTokens\tTime\tCost\tResult")
+	for _, r := range results {
+		var calls []string
+		steps := 0
+		usage, elapsed, cost := "-", "-", "-"
+		if r.RunResult != nil {
+			steps = len(r.Steps)
+			for _, s := range r.Steps {
+				if s.ToolCall != nil {
+					calls = append(calls, s.ToolCall.Name)
+				}
+			}
+			if r.Usage.TotalTokens > 0 {
+				usage = fmt.Sprint(r.Usage.TotalTokens)
+			}
+			elapsed = r.Elapsed.Round(100 * time.Millisecond).String()
+			if r.Cost > 0 {
+				cost = fmt.Sprintf("$%.4f", r.Cost)
+			}
+		}
+		toolCalls := "-"
+		if len(calls) > 0 {
+			toolCalls = fmt.Sprintf("%d (%s)", len(calls), truncateText(strings.Join(calls, ", "), 40))
+		}
+		status := "answered"
+		if r.Error != "" {
+			status = "failed: " + truncateText(r.Error, 50)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\n", r.Model, steps, toolCalls, usage, elapsed, cost, status)
+	}
+	tw.Flush()
+}
+
+// truncateText shortens s to n bytes with an ellipsis
+func truncateText(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
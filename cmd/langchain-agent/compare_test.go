@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/rathore/langchain-agent/agent"
+	"github.com/rathore/langchain-agent/llm"
+)
+
+// answerClient is an llm.ChatClient that always answers with itself
+type answerClient string
+
+func (c answerClient) Chat(ctx context.Context, messages []llm.Message) (*llm.Response, error) {
+	return &llm.Response{Content: string(c), IsFinish: true, Usage: llm.Usage{PromptTokens: 90, CompletionTokens: 10, TotalTokens: 100}}, nil
+}
+
+func TestParseCompare(t *testing.T) {
+	models, err := parseCompare(" qwen2.5:32b, llama3.1,qwen2.5:32b,")
+	if err != nil || strings.Join(models, "|") != "qwen2.5:32b|llama3.1" {
+		t.Errorf("parseCompare = %q, %v", models, err)
+	}
+	if _, err := parseCompare("llama3.1"); err == nil {
+		t.Error("parseCompare of one model succeeded")
+	}
+}
+
+func TestCompareQuery(t *testing.T) {
+	var contenders []contender
+	for _, model := range []string{"big", "small"} {
+		a, err := agent.New(agent.Config{Client: answerClient("Answer from " + model)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		contenders = append(contenders, contender{model: model, agent: a})
+	}
+	recorded := 0
+	record := func(*agent.RunResult, error) { recorded++ }
+
+	var out bytes.Buffer
+	compareQuery(context.Background(), &out, contenders, "uptime?", false, nil, record)
+	for _, want := range []string{"=== big ===", "[Answer big]\nAnswer from big", "[Answer small]\nAnswer from small", "Model", "Tokens", "small  1      -           100"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+	if recorded != 2 {
+		t.Errorf("recorded %d runs, want 2", recorded)
+	}
+
+	// Histories are separate, and /retry undoes the exchange of every model
+	if h := contenders[1].agent.History(); len(h) != 2 || h[1].Content != "Answer from small" {
+		t.Errorf("small history = %v", h)
+	}
+	contenders[0].agent.UndoLastRun()
+	undoContenders(contenders)
+	if n := len(contenders[1].agent.History()); n != 0 {
+		t.Errorf("small history has %d messages after undo, want 0", n)
+	}
+
+	out.Reset()
+	var records bytes.Buffer
+	compareQuery(context.Background(), &out, contenders, "uptime?", false, json.NewEncoder(&records), record)
+	var got compareOutput
+	if err := json.Unmarshal(records.Bytes(), &got); err != nil {
+		t.Fatalf("JSON record: %v\n%s", err, records.String())
+	}
+	if got.Query != "uptime?" || len(got.Runs) != 2 || got.Runs[1].Model != "small" || got.Runs[1].Answer != "Answer from small" {
+		t.Errorf("JSON record = %s", records.String())
+	}
+}
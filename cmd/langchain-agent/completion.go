@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// subcommands lists the first arguments that select a subcommand
+var subcommands = []string{"ask", "bench", "completion", "credentials", "doctor", "eval", "reembed"}
+
+// completionShells are the shells `langchain-agent completion` writes
+// scripts for
+var completionShells = []string{"bash", "zsh", "fish"}
+
+// flagChoices are the values offered for flags that take one of a fixed set
+var flagChoices = map[string][]string{
+	"provider":             {"ollama", "gemini", "openai", "azure", "anthropic"},
+	"backend":              {"ollama", "gemini", "openai", "azure", "anthropic"},
+	"output":               {"text", "json"},
+	"batch-session":        {"fresh", "shared"},
+	"embed-provider":       {"ollama", "openai"},
+	"vision-provider":      {"ollama", "openai"},
+	"wiki-format":          {"confluence", "mediawiki", "notion"},
+	"table-format":         {"rows", "markdown"},
+	"wiki-query-expansion": {"none", "multi-query", "hyde"},
+	"ocr":                  {"tesseract", "vision"},
+	"vision-profiles":      {"heuristic", "classify"},
+	"qdrant-quantization":  {"scalar", "product"},
+	"injection-screen":     {"off", "warn", "redact", "block"},
+	"approve":              {"low", "medium", "high"},
+	"session-store":        {"file", "sqlite"},
+	"credential-store":     {"auto", "keyring", "file"},
+}
+
+// flagArgKinds says how to complete other flag values: "file", "dir" or
+// "model" (Ollama models, listed by `langchain-agent completion models`)
+var flagArgKinds = map[string]string{
+	"auth-config":   "file",
+	"config":        "file",
+	"batch":         "file",
+	"eval-baseline": "file",
+	"eval-report":   "file",
+	"batch-output":  "file",
+	"history-file":  "file",
+	"index-report":  "file",
+	"load-report":   "file",
+	"policy":        "file",
+	"qdrant-ca":     "file",
+	"record":        "file",
+	"replay":        "file",
+	"socket":        "file",
+	"wiki":          "file",
+	"plugins":       "dir",
+	"prompts":       "dir",
+	"sessions-dir":  "dir",
+	"workspace":     "dir",
+	"model":         "model",
+	"embed-model":   "model",
+	"vision-model":  "model",
+	"summary-model": "model",
+}
+
+// completionFlag is a flag as completion scripts see it
+type completionFlag struct {
+	Name   string
+	Usage  string // first sentence of the flag's usage
+	Bool   bool
+	Values []string
+	Kind   string
+}
+
+// completionFlags lists fs's flags in name order
+func completionFlags(fs *flag.FlagSet) []completionFlag {
+	var flags []completionFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		bf, ok := f.Value.(interface{ IsBoolFlag() bool })
+		flags = append(flags, completionFlag{
+			Name:   f.Name,
+			Usage:  shortUsage(f.Usage),
+			Bool:   ok && bf.IsBoolFlag(),
+			Values: flagChoices[f.Name],
+			Kind:   flagArgKinds[f.Name],
+		})
+	})
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+	return flags
+}
+
+// shortUsage cuts a flag's usage down to a one-line description
+func shortUsage(usage string) string {
+	for _, sep := range []string{" (", ": ", "; ", ", e.g.", ". ", " — "} {
+		if i := strings.Index(usage, sep); i > 0 {
+			usage = usage[:i]
+		}
+	}
+	return strings.TrimSuffix(usage, ".")
+}
+
+// completionScript returns the completion script for shell
+func completionScript(shell string, flags []completionFlag) (string, error) {
+	switch shell {
+	case "bash":
+		return bashCompletion(flags), nil
+	case "zsh":
+		return zshCompletion(flags), nil
+	case "fish":
+		return fishCompletion(flags), nil
+	default:
+		return "", fmt.Errorf("unknown shell %q (use %s)", shell, strings.Join(completionShells, ", "))
+	}
+}
+
+func bashCompletion(flags []completionFlag) string {
+	var sb strings.Builder
+	sb.WriteString(`# bash completion for langchain-agent
+# Install: source <(langchain-agent completion bash)
+_langchain_agent() {
+    local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"
+    case "$prev" in
+`)
+	var names, files, dirs, models, free []string
+	for _, f := range flags {
+		names = append(names, "--"+f.Name)
+		opt := "-" + f.Name + "|--" + f.Name
+		switch {
+		case f.Bool:
+		case len(f.Values) > 0:
+			fmt.Fprintf(&sb, "        %s) COMPREPLY=($(compgen -W %q -- \"$cur\")); return ;;\n", opt, strings.Join(f.Values, " "))
+		case f.Kind == "file":
+			files = append(files, opt)
+		case f.Kind == "dir":
+			dirs = append(dirs, opt)
+		case f.Kind == "model":
+			models = append(models, opt)
+		default:
+			free = append(free, opt)
+		}
+	}
+	if len(models) > 0 {
+		fmt.Fprintf(&sb, "        %s) COMPREPLY=($(compgen -W \"$(langchain-agent completion models 2>/dev/null)\" -- \"$cur\")); return ;;\n", strings.Join(models, "|"))
+	}
+	if len(files) > 0 {
+		fmt.Fprintf(&sb, "        %s) COMPREPLY=($(compgen -f -- \"$cur\")); return ;;\n", strings.Join(files, "|"))
+	}
+	if len(dirs) > 0 {
+		fmt.Fprintf(&sb, "        %s) COMPREPLY=($(compgen -d -- \"$cur\")); return ;;\n", strings.Join(dirs, "|"))
+	}
+	if len(free) > 0 {
+		fmt.Fprintf(&sb, "        %s) return ;;\n", strings.Join(free, "|"))
+	}
+	fmt.Fprintf(&sb, `    esac
+    if [[ "${COMP_WORDS[1]}" == completion && $COMP_CWORD -eq 2 ]]; then
+        COMPREPLY=($(compgen -W %q -- "$cur"))
+    elif [[ $COMP_CWORD -eq 1 && "$cur" != -* ]]; then
+        COMPREPLY=($(compgen -W %q -- "$cur"))
+    else
+        COMPREPLY=($(compgen -W %q -- "$cur"))
+    fi
+}
+complete -o default -F _langchain_agent langchain-agent
+`, strings.Join(completionShells, " "), strings.Join(subcommands, " "), strings.Join(names, " "))
+	return sb.String()
+}
+
+func zshCompletion(flags []completionFlag) string {
+	var sb strings.Builder
+	sb.WriteString(`#compdef langchain-agent
+# zsh completion for langchain-agent
+# Install: langchain-agent completion zsh > "${fpath[1]}/_langchain-agent"
+_langchain_agent_models() {
+    local -a models
+    models=(${(f)"$(langchain-agent completion models 2>/dev/null)"})
+    compadd -a models
+}
+
+_langchain_agent() {
+    _arguments \
+`)
+	escape := strings.NewReplacer(`'`, `'\''`, "[", `\[`, "]", `\]`, ":", `\:`)
+	for _, f := range flags {
+		spec := fmt.Sprintf("--%s[%s]", f.Name, escape.Replace(f.Usage))
+		switch {
+		case f.Bool:
+		case len(f.Values) > 0:
+			spec += fmt.Sprintf(":%s:(%s)", f.Name, strings.Join(f.Values, " "))
+		case f.Kind == "file":
+			spec += ":file:_files"
+		case f.Kind == "dir":
+			spec += ":directory:_files -/"
+		case f.Kind == "model":
+			spec += ":model:_langchain_agent_models"
+		default:
+			spec += ":" + f.Name + ": "
+		}
+		fmt.Fprintf(&sb, "        '%s' \\\n", spec)
+	}
+	fmt.Fprintf(&sb, `        '1:command:(%s)' \
+        '*::arg:->args'
+    if [[ $state == args && $words[1] == completion ]]; then
+        _values shell %s
+    fi
+}
+
+_langchain_agent "$@"
+`, strings.Join(subcommands, " "), strings.Join(completionShells, " "))
+	return sb.String()
+}
+
+func fishCompletion(flags []completionFlag) string {
+	var sb strings.Builder
+	sb.WriteString(`# fish completion for langchain-agent
+# Install: langchain-agent completion fish > ~/.config/fish/completions/langchain-agent.fish
+complete -c langchain-agent -f
+`)
+	fmt.Fprintf(&sb, "complete -c langchain-agent -n __fish_use_subcommand -a '%s'\n", strings.Join(subcommands, " "))
+	fmt.Fprintf(&sb, "complete -c langchain-agent -n '__fish_seen_subcommand_from completion' -a '%s'\n", strings.Join(completionShells, " "))
+	escape := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	for _, f := range flags {
+		line := fmt.Sprintf("complete -c langchain-agent -l %s -d '%s'", f.Name, escape.Replace(f.Usage))
+		switch {
+		case f.Bool:
+		case len(f.Values) > 0:
+			line += fmt.Sprintf(" -x -a '%s'", strings.Join(f.Values, " "))
+		case f.Kind == "file":
+			line += " -r -F"
+		case f.Kind == "dir":
+			line += " -x -a '(__fish_complete_directories)'"
+		case f.Kind == "model":
+			line += " -x -a '(langchain-agent completion models 2>/dev/null)'"
+		default:
+			line += " -x"
+		}
+		sb.WriteString(line + "\n")
+	}
+	return sb.String()
+}
+
+// completionModels lists the models on the Ollama server, for completing
+// --model and the other model flags
+func completionModels(ctx context.Context, ollamaURL string) ([]string, error) {
+	client := &http.Client{Timeout: doctorTimeout}
+	models, err := ollamaModels(ctx, client, ollamaServer(ollamaURL))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(models)
+	return models, nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testCompletionFlags() []completionFlag {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("backend", "ollama", "LLM backend: ollama or gemini")
+	fs.String("model", "", "Model name (default: qwen2.5:32b)")
+	fs.String("config", "", "YAML file of flag settings")
+	fs.String("prompts", "", "Directory of prompt templates")
+	fs.Int("max-iter", 10, "Maximum agent iterations per query")
+	fs.Bool("no-color", false, "Print answers as raw Markdown; don't use colors")
+	return completionFlags(fs)
+}
+
+func TestCompletionFlags(t *testing.T) {
+	flags := testCompletionFlags()
+	var names []string
+	for _, f := range flags {
+		names = append(names, f.Name)
+	}
+	if want := []string{"backend", "config", "max-iter", "model", "no-color", "prompts"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("flags = %v, want %v", names, want)
+	}
+	if f := flags[0]; f.Usage != "LLM backend" || !reflect.DeepEqual(f.Values, []string{"ollama", "gemini", "openai", "azure", "anthropic"}) {
+		t.Errorf("backend = %+v", f)
+	}
+	if f := flags[3]; f.Kind != "model" || f.Usage != "Model name" {
+		t.Errorf("model = %+v", f)
+	}
+	if f := flags[4]; !f.Bool || f.Usage != "Print answers as raw Markdown" {
+		t.Errorf("no-color = %+v", f)
+	}
+}
+
+func TestShortUsage(t *testing.T) {
+	for usage, want := range map[string]string{
+		"Extra CQL filter for --confluence-url, e.g. 'label = \"runbook\"'": "Extra CQL filter for --confluence-url",
+		"Only index the wiki, then exit":                                    "Only index the wiki, then exit",
+		"Edge target user@host (Pi, NUC) — enables edge_temp":               "Edge target user@host",
+		"Keep going. Then stop.":                                            "Keep going",
+	} {
+		if got := shortUsage(usage); got != want {
+			t.Errorf("shortUsage(%q) = %q, want %q", usage, got, want)
+		}
+	}
+}
+
+func TestCompletionScript(t *testing.T) {
+	flags := testCompletionFlags()
+	tests := map[string][]string{
+		"bash": {
+			`-backend|--backend) COMPREPLY=($(compgen -W "ollama gemini openai azure anthropic"`,
+			`-model|--model) COMPREPLY=($(compgen -W "$(langchain-agent completion models 2>/dev/null)"`,
+			`-config|--config) COMPREPLY=($(compgen -f`,
+			`-prompts|--prompts) COMPREPLY=($(compgen -d`,
+			`-max-iter|--max-iter) return ;;`,
+			`compgen -W "ask bench completion credentials doctor eval reembed"`,
+			`complete -o default -F _langchain_agent langchain-agent`,
+		},
+		"zsh": {
+			"#compdef langchain-agent",
+			"'--backend[LLM backend]:backend:(ollama gemini openai azure anthropic)'",
+			"'--model[Model name]:model:_langchain_agent_models'",
+			"'--no-color[Print answers as raw Markdown]'",
+			"'1:command:(ask bench completion credentials doctor eval reembed)'",
+		},
+		"fish": {
+			"complete -c langchain-agent -l backend -d 'LLM backend' -x -a 'ollama gemini openai azure anthropic'",
+			"complete -c langchain-agent -l model -d 'Model name' -x -a '(langchain-agent completion models 2>/dev/null)'",
+			"complete -c langchain-agent -l config -d 'YAML file of flag settings' -r -F",
+			"complete -c langchain-agent -l no-color -d 'Print answers as raw Markdown'\n",
+		},
+	}
+	for shell, wants := range tests {
+		script, err := completionScript(shell, flags)
+		if err != nil {
+			t.Fatalf("completionScript(%s) error = %v", shell, err)
+		}
+		for _, want := range wants {
+			if !strings.Contains(script, want) {
+				t.Errorf("%s script missing %q:\n%s", shell, want, script)
+			}
+		}
+		// Check the syntax when the shell is installed
+		if path, err := exec.LookPath(shell); err == nil {
+			cmd := exec.Command(path, "-n")
+			cmd.Stdin = strings.NewReader(script)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("%s -n: %v\n%s", shell, err, out)
+			}
+		}
+	}
+	if _, err := completionScript("powershell", flags); err == nil {
+		t.Error("completionScript(powershell) error = nil")
+	}
+}
+
+func TestCompletionModels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"models": [{"name": "qwen2.5:32b"}, {"name": "llama3.1:latest"}]}`))
+	}))
+	defer srv.Close()
+
+	models, err := completionModels(context.Background(), srv.URL)
+	if want := []string{"llama3.1:latest", "qwen2.5:32b"}; err != nil || !reflect.DeepEqual(models, want) {
+		t.Errorf("completionModels() = %v, %v, want %v", models, err, want)
+	}
+}
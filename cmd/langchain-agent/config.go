@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// applyConfigFile sets flags from a YAML config file. Keys are flag names;
+// nested maps join their keys with "-", so
+//
+//	vision:
+//	  provider: openai
+//	  model: gpt-4o-mini
+//
+// sets --vision-provider and --vision-model. A list sets a repeatable flag
+// (--wiki, --mcp, ...) once per item. Flags given on the command line
+// override the file.
+func applyConfigFile(fs *flag.FlagSet, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	settings := map[string]any{}
+	flattenConfig("", raw, settings)
+
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names) // deterministic error reporting
+	for _, name := range names {
+		f := fs.Lookup(name)
+		if f == nil || name == "config" {
+			return fmt.Errorf("unknown setting %q in %s", name, path)
+		}
+		if explicit[name] {
+			continue
+		}
+		values, isList := settings[name].([]any)
+		if !isList {
+			values = []any{settings[name]}
+		} else if _, repeatable := f.Value.(*stringSlice); !repeatable {
+			return fmt.Errorf("setting %q in %s takes a single value, not a list", name, path)
+		}
+		for _, v := range values {
+			if _, nested := v.(map[string]any); nested || v == nil {
+				return fmt.Errorf("setting %q in %s: want a value, got %v", name, path, v)
+			}
+			if err := f.Value.Set(fmt.Sprint(v)); err != nil {
+				return fmt.Errorf("setting %q in %s: %w", name, path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// flattenConfig joins nested map keys with "-" into out
+func flattenConfig(prefix string, in map[string]any, out map[string]any) {
+	for k, v := range in {
+		name := k
+		if prefix != "" {
+			name = prefix + "-" + k
+		}
+		if m, ok := v.(map[string]any); ok {
+			flattenConfig(name, m, out)
+			continue
+		}
+		out[name] = v
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplyConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.yaml")
+	os.WriteFile(path, []byte(`
+backend: gemini
+model: gemini-2.5-pro
+max-iter: 20
+reindex-interval: 1h
+wiki:
+  - ops:/wiki/ops
+  - dev:/wiki/dev
+vision:
+  provider: openai
+  workers: 4
+`), 0644)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	backend := fs.String("backend", "ollama", "")
+	model := fs.String("model", "", "")
+	maxIter := fs.Int("max-iter", 10, "")
+	interval := fs.Duration("reindex-interval", 0, "")
+	var wiki stringSlice
+	fs.Var(&wiki, "wiki", "")
+	provider := fs.String("vision-provider", "ollama", "")
+	workers := fs.Int("vision-workers", 2, "")
+	if err := fs.Parse([]string{"--model", "gemini-2.5-flash"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := applyConfigFile(fs, path); err != nil {
+		t.Fatalf("applyConfigFile() error = %v", err)
+	}
+	if *backend != "gemini" || *maxIter != 20 || *interval != time.Hour {
+		t.Errorf("backend=%q max-iter=%d reindex-interval=%v, want values from the file", *backend, *maxIter, *interval)
+	}
+	if *model != "gemini-2.5-flash" {
+		t.Errorf("model = %q, want the command-line value to win", *model)
+	}
+	if strings.Join(wiki, ",") != "ops:/wiki/ops,dev:/wiki/dev" {
+		t.Errorf("wiki = %q, want both list items", wiki)
+	}
+	if *provider != "openai" || *workers != 4 {
+		t.Errorf("vision-provider=%q vision-workers=%d, want nested keys joined with -", *provider, *workers)
+	}
+
+	for content, want := range map[string]string{
+		"modle: llama3\n":    `unknown setting "modle"`,
+		"model: [a, b]\n":    "takes a single value",
+		"max-iter: lots\n":   `setting "max-iter"`,
+		"backend: [ollama\n": "failed to parse",
+	} {
+		os.WriteFile(path, []byte(content), 0644)
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.String("model", "", "")
+		fs.String("backend", "", "")
+		fs.Int("max-iter", 10, "")
+		if err := applyConfigFile(fs, path); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("applyConfigFile(%q) error = %v, want %q", content, err, want)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rathore/langchain-agent/credentials"
+	"golang.org/x/term"
+)
+
+// passphraseEnv holds the file store's master passphrase for unattended
+// runs; without it the passphrase is asked on the terminal
+const passphraseEnv = "LANGCHAIN_AGENT_CREDENTIALS_PASSPHRASE"
+
+// openCredentials opens the --credential-store of kind in the user config
+// dir
+func openCredentials(kind string) (credentials.Store, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the config dir: %w", err)
+	}
+	return credentials.Open(kind, filepath.Join(dir, "langchain-agent"), filePassphrase)
+}
+
+// filePassphrase returns the file store's master passphrase from
+// $LANGCHAIN_AGENT_CREDENTIALS_PASSPHRASE or the terminal, asking twice for
+// a new file
+func filePassphrase(create bool) ([]byte, error) {
+	if passphrase := os.Getenv(passphraseEnv); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil, fmt.Errorf("set $%s or run on a terminal", passphraseEnv)
+	}
+	prompt := "Credentials passphrase: "
+	if create {
+		prompt = "New credentials passphrase: "
+	}
+	passphrase, err := readHidden(prompt)
+	if err != nil || !create {
+		return passphrase, err
+	}
+	again, err := readHidden("Repeat passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(passphrase, again) {
+		return nil, errors.New("the passphrases don't match")
+	}
+	return passphrase, nil
+}
+
+// readHidden asks on the terminal without echoing the answer
+func readHidden(prompt string) ([]byte, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	answer, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	return answer, err
+}
+
+// storedSSHPassword looks up the password of user@host for the ssh tool
+func storedSSHPassword(store credentials.Store) func(user, host string) (string, error) {
+	return func(user, host string) (string, error) {
+		return credentials.Lookup(store, credentials.SSHPassword(user, host))
+	}
+}
+
+// storedSSHPassphrase looks up the passphrase of a key file for the ssh
+// tool
+func storedSSHPassphrase(store credentials.Store) func(keyFile string) ([]byte, error) {
+	return func(keyFile string) ([]byte, error) {
+		passphrase, err := credentials.Lookup(store, credentials.SSHKey(keyFile))
+		return []byte(passphrase), err
+	}
+}
+
+// credentialsUsage describes `langchain-agent credentials`
+const credentialsUsage = `Usage: langchain-agent credentials [--credential-store auto|keyring|file] <command>
+  add <name>     store a secret, read from the terminal or stdin
+  list           list the stored names
+  remove <name>  delete a secret
+Names: an environment variable such as OPENAI_API_KEY (exported when unset),
+ssh/<user>@<host> (an SSH password), ssh-key/<key file name> (a key passphrase)`
+
+// runCredentials runs `langchain-agent credentials args`, reading a new
+// secret from in, and returns the exit code
+func runCredentials(w io.Writer, in io.Reader, store credentials.Store, args []string) int {
+	command, name := "", ""
+	if len(args) > 0 {
+		command = args[0]
+	}
+	if len(args) == 2 {
+		name = args[1]
+	}
+	switch {
+	case command == "list" && len(args) == 1:
+		names, err := store.List()
+		if err != nil {
+			fmt.Fprintln(w, err)
+			return 1
+		}
+		if len(names) == 0 {
+			fmt.Fprintln(w, "No credentials stored.")
+		}
+		for _, name := range names {
+			fmt.Fprintln(w, name)
+		}
+	case command == "add" && name != "":
+		if err := credentials.CheckName(name); err != nil {
+			fmt.Fprintln(w, err)
+			return 2
+		}
+		secret, err := readSecret(in, name)
+		if err != nil {
+			fmt.Fprintf(w, "Failed to read the secret: %v\n", err)
+			return 1
+		}
+		if secret == "" {
+			fmt.Fprintln(w, "Empty secret; nothing stored.")
+			return 1
+		}
+		if err := store.Set(name, secret); err != nil {
+			fmt.Fprintln(w, err)
+			return 1
+		}
+		fmt.Fprintf(w, "Stored %s.\n", name)
+	case command == "remove" && name != "":
+		if err := store.Delete(name); err != nil {
+			fmt.Fprintln(w, err)
+			return 1
+		}
+		fmt.Fprintf(w, "Removed %s.\n", name)
+	default:
+		fmt.Fprintln(w, credentialsUsage)
+		return 2
+	}
+	return 0
+}
+
+// readSecret asks for the secret of name without echo when in is the
+// terminal, else reads all of in, e.g. a pipe from a password manager
+func readSecret(in io.Reader, name string) (string, error) {
+	if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		secret, err := readHidden("Secret for " + name + ": ")
+		return string(secret), err
+	}
+	data, err := io.ReadAll(in)
+	return strings.TrimRight(string(data), "\r\n"), err
+}
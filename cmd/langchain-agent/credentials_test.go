@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rathore/langchain-agent/credentials"
+)
+
+func TestRunCredentials(t *testing.T) {
+	store := credentials.NewFile(filepath.Join(t.TempDir(), "credentials.json"), func(bool) ([]byte, error) {
+		return []byte("master"), nil
+	})
+	run := func(stdin string, args ...string) (int, string) {
+		var out bytes.Buffer
+		code := runCredentials(&out, strings.NewReader(stdin), store, args)
+		return code, out.String()
+	}
+
+	if code, out := run("", "list"); code != 0 || out != "No credentials stored.\n" {
+		t.Errorf("list = %d, %q", code, out)
+	}
+	if code, out := run("sk-test\n", "add", "OPENAI_API_KEY"); code != 0 || out != "Stored OPENAI_API_KEY.\n" {
+		t.Errorf("add = %d, %q", code, out)
+	}
+	if secret, _ := store.Get("OPENAI_API_KEY"); secret != "sk-test" {
+		t.Errorf("stored %q, want the trailing newline trimmed", secret)
+	}
+	run("hunter2", "add", "ssh/deploy@web-1")
+	if code, out := run("", "list"); code != 0 || out != "OPENAI_API_KEY\nssh/deploy@web-1\n" {
+		t.Errorf("list = %d, %q", code, out)
+	}
+
+	password, err := storedSSHPassword(store)("deploy", "web-1")
+	if err != nil || password != "hunter2" {
+		t.Errorf("stored SSH password = %q, %v", password, err)
+	}
+	if passphrase, err := storedSSHPassphrase(store)("/home/me/.ssh/id_ed25519"); err != nil || len(passphrase) != 0 {
+		t.Errorf("unstored passphrase = %q, %v", passphrase, err)
+	}
+
+	if code, _ := run("", "remove", "OPENAI_API_KEY"); code != 0 {
+		t.Errorf("remove exit code %d", code)
+	}
+	if code, out := run("", "remove", "OPENAI_API_KEY"); code != 1 || !strings.Contains(out, "not found") {
+		t.Errorf("second remove = %d, %q", code, out)
+	}
+	for _, args := range [][]string{nil, {"add"}, {"get", "x"}, {"add", "bad name"}} {
+		if code, _ := run("s", args...); code != 2 {
+			t.Errorf("%v: exit code %d, want usage", args, code)
+		}
+	}
+	if code, _ := run("", "add", "EMPTY"); code != 1 {
+		t.Errorf("add with an empty secret: exit code %d", code)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/rathore/langchain-agent/agent"
+	"github.com/rathore/langchain-agent/webhook"
+	"golang.org/x/net/websocket"
+)
+
+// defaultSocketPath is the daemon's unix socket: in $XDG_RUNTIME_DIR when
+// set, else in a dir of the user's in the user cache dir, or the temp dir
+// without one, named with the uid as the temp dir is shared
+func defaultSocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "langchain-agent.sock")
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	name := "langchain-agent"
+	if uid := os.Getuid(); uid >= 0 {
+		name = fmt.Sprintf("langchain-agent-%d", uid)
+	}
+	return filepath.Join(dir, name, "agent.sock")
+}
+
+// serveDaemon serves the agent's HTTP/WebSocket API on a unix socket until
+// ctx is cancelled. The socket is only accessible to the current user, as
+// whoever can connect can run commands through the agent's tools.
+func serveDaemon(ctx context.Context, path string, ag *agent.Agent) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create socket dir: %w", err)
+	}
+	if err := checkSocketDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("a daemon is already listening on %s", path)
+	}
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return fmt.Errorf("%s exists and isn't a socket", path)
+		}
+		os.Remove(path) // stale socket of a daemon that didn't exit cleanly
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	defer os.Remove(path)
+	if err := os.Chmod(path, 0600); err != nil {
+		ln.Close()
+		return fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+	return webhook.Serve(ctx, ln, ag)
+}
+
+// checkSocketDir makes sure no other user can reach the socket's dir: in
+// one they own or can write to, they could put their own socket in the
+// daemon's place and get the queries sent to it
+func checkSocketDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return fmt.Errorf("failed to check socket dir: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("socket dir %s isn't a directory (or is a symlink)", dir)
+	}
+	if uid, ok := fileOwner(info); ok && uid != os.Getuid() {
+		return fmt.Errorf("socket dir %s belongs to uid %d, not to you", dir, uid)
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		return fmt.Errorf("socket dir %s is open to other users (mode %04o): chmod 700 it or pick another --socket", dir, perm)
+	}
+	return nil
+}
+
+// askDaemon sends query to the daemon on path as a new conversation, writes
+// tool progress to progress as it happens and returns the answer. Cancelling
+// ctx cancels the run.
+func askDaemon(ctx context.Context, path, query string, progress io.Writer) (string, error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "unix", path)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to the daemon (start it with `langchain-agent --daemon`): %w", err)
+	}
+	config, err := websocket.NewConfig("ws://langchain-agent/ws", "http://langchain-agent")
+	if err != nil {
+		conn.Close()
+		return "", err
+	}
+	ws, err := websocket.NewClient(config, conn)
+	if err != nil {
+		conn.Close()
+		return "", fmt.Errorf("failed to open daemon session: %w", err)
+	}
+	defer ws.Close()
+	stop := context.AfterFunc(ctx, func() { ws.Close() })
+	defer stop()
+
+	if err := websocket.JSON.Send(ws, map[string]any{"prompt": query, "fresh": true}); err != nil {
+		return "", fmt.Errorf("failed to send query: %w", err)
+	}
+	for {
+		var e agent.Event
+		if err := websocket.JSON.Receive(ws, &e); err != nil {
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			return "", fmt.Errorf("daemon connection lost: %w", err)
+		}
+		switch e.Type {
+		case agent.EventToolCall:
+			fmt.Fprintf(progress, "[Tool Call] %s: %v\n", e.Tool, e.Params)
+		case agent.EventToolResult:
+			if e.Error != "" {
+				fmt.Fprintf(progress, "[Tool Error] %s\n", e.Error)
+			}
+		case agent.EventAnswer:
+			return e.Text, nil
+		case agent.EventError:
+			return "", errors.New(e.Error)
+		}
+	}
+}
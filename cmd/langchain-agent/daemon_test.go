@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rathore/langchain-agent/agent"
+	"github.com/rathore/langchain-agent/llm"
+	"github.com/rathore/langchain-agent/tools"
+)
+
+func TestDaemon(t *testing.T) {
+	client := &scriptedClient{responses: []*llm.Response{
+		{
+			Content:   `{"name": "shell", "parameters": {"command": "echo hi"}}`,
+			ToolCalls: []llm.ToolCallParse{{Name: "shell", Params: map[string]any{"command": "echo hi"}}},
+		},
+		{Content: "It printed hi.", IsFinish: true},
+		{Content: "Second answer.", IsFinish: true},
+	}}
+	ag, _ := agent.New(agent.Config{Client: client, Tools: []tools.Tool{&tools.ShellTool{}}})
+	sock := filepath.Join(t.TempDir(), "run", "agent.sock")
+
+	if _, err := askDaemon(context.Background(), sock, "hi", os.Stderr); err == nil || !strings.Contains(err.Error(), "--daemon") {
+		t.Errorf("askDaemon() without a daemon error = %v, want a hint to start it", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- serveDaemon(ctx, sock, ag) }()
+	for i := 0; i < 100; i++ {
+		if _, err := os.Stat(sock); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if info, err := os.Stat(sock); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("socket = %v, %v, want mode 0600", info, err)
+	}
+	if err := serveDaemon(ctx, sock, ag); err == nil || !strings.Contains(err.Error(), "already listening") {
+		t.Errorf("second serveDaemon() error = %v, want already listening", err)
+	}
+
+	var progress strings.Builder
+	answer, err := askDaemon(context.Background(), sock, "run echo hi", &progress)
+	if err != nil || answer != "It printed hi." {
+		t.Fatalf("askDaemon() = %q, %v", answer, err)
+	}
+	if !strings.Contains(progress.String(), "[Tool Call] shell: map[command:echo hi]") {
+		t.Errorf("progress = %q, want the tool call", progress.String())
+	}
+
+	// Each ask starts a new conversation
+	if answer, err := askDaemon(context.Background(), sock, "again", &progress); err != nil || answer != "Second answer." {
+		t.Errorf("second askDaemon() = %q, %v", answer, err)
+	}
+	if history := ag.History(); len(history) != 2 || history[0].Content != "again" {
+		t.Errorf("history = %+v, want only the last query", history)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("serveDaemon() = %v after cancel", err)
+	}
+	if _, err := os.Stat(sock); !os.IsNotExist(err) {
+		t.Errorf("socket should be removed on exit, stat error = %v", err)
+	}
+}
+
+func TestServeDaemon_RefusesUnsafePaths(t *testing.T) {
+	ag, _ := agent.New(agent.Config{Client: &scriptedClient{}})
+	dir := filepath.Join(t.TempDir(), "run")
+	os.Mkdir(dir, 0700)
+
+	// A file in the socket's place is never removed
+	file := filepath.Join(dir, "notes.txt")
+	os.WriteFile(file, []byte("keep me"), 0600)
+	if err := serveDaemon(context.Background(), file, ag); err == nil || !strings.Contains(err.Error(), "isn't a socket") {
+		t.Errorf("serveDaemon() on a file error = %v", err)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "keep me" {
+		t.Error("the file was removed")
+	}
+
+	shared := filepath.Join(dir, "shared")
+	os.Mkdir(shared, 0700)
+	os.Chmod(shared, 0777)
+	if err := serveDaemon(context.Background(), filepath.Join(shared, "agent.sock"), ag); err == nil || !strings.Contains(err.Error(), "open to other users (mode 0777)") {
+		t.Errorf("serveDaemon() in a world-writable dir error = %v", err)
+	}
+
+	link := filepath.Join(dir, "link")
+	os.Symlink(shared, link)
+	if err := serveDaemon(context.Background(), filepath.Join(link, "agent.sock"), ag); err == nil || !strings.Contains(err.Error(), "isn't a directory") {
+		t.Errorf("serveDaemon() in a symlinked dir error = %v", err)
+	}
+}
+
+func TestDefaultSocketPath(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	if path := defaultSocketPath(); !strings.Contains(path, fmt.Sprintf("langchain-agent-%d", os.Getuid())) {
+		t.Errorf("defaultSocketPath() = %s, want a dir named with the uid", path)
+	}
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rathore/langchain-agent/rag"
+	"github.com/rathore/langchain-agent/tools"
+)
+
+// doctorTimeout bounds each network check
+const doctorTimeout = 5 * time.Second
+
+// doctorConfig is what `langchain-agent doctor` checks, taken from the same
+// flags as a normal run
+type doctorConfig struct {
+	Backend   string // LLM provider
+	Model     string
+	OllamaURL string
+	APIURL    string // --api-url, for the openai and azure providers
+
+	Wiki           bool // a wiki source is configured, so indexing needs embeddings and vision
+	EmbedProvider  string
+	EmbedModel     string
+	VisionProvider string
+	VisionModel    string
+	VisionURL      string
+	NoImages       bool // --no-images: the vision model isn't used
+	SummaryModel   string
+
+	QdrantURL   string
+	Qdrant      rag.QdrantOptions
+	Collections []string // wiki collections the run would use
+
+	MCPSpecs   []string
+	PluginsDir string
+	SSH        bool   // the ssh tool is registered
+	Prometheus string // --prometheus URL
+}
+
+// checkStatus is the outcome of one doctor check
+type checkStatus int
+
+const (
+	checkOK checkStatus = iota
+	checkWarn
+	checkFail
+)
+
+// checkResult is one line of the doctor report, with a hint on how to fix
+// anything that isn't ok
+type checkResult struct {
+	Status checkStatus
+	Name   string
+	Detail string
+	Fix    string
+}
+
+// runDoctor checks every service the configuration depends on, writes a
+// report to w and returns the number of failed checks
+func runDoctor(ctx context.Context, w io.Writer, config doctorConfig) int {
+	client := &http.Client{Timeout: doctorTimeout}
+	var results []checkResult
+
+	// Group the Ollama models needed by server, so each is asked once
+	var servers []string
+	needed := map[string][]string{}
+	need := func(server, model string) {
+		if _, ok := needed[server]; !ok {
+			servers = append(servers, server)
+		}
+		needed[server] = append(needed[server], model)
+	}
+	switch config.Backend {
+	case "gemini":
+		results = append(results, checkEnv("Gemini API key", "GOOGLE_API_KEY", checkFail))
+	case "openai":
+		// OpenAI-compatible local servers usually don't check keys
+		missing := checkFail
+		if config.APIURL != "" || os.Getenv("OPENAI_BASE_URL") != "" {
+			missing = checkWarn
+		}
+		results = append(results, checkEnv("OpenAI API key", "OPENAI_API_KEY", missing))
+	case "anthropic":
+		results = append(results, checkEnv("Anthropic API key", "ANTHROPIC_API_KEY", checkFail))
+	case "azure":
+		if os.Getenv("AZURE_OPENAI_API_KEY") != "" || os.Getenv("OPENAI_API_KEY") == "" {
+			results = append(results, checkEnv("Azure OpenAI API key", "AZURE_OPENAI_API_KEY", checkFail))
+		}
+		if config.APIURL == "" {
+			results = append(results, checkEnv("Azure OpenAI endpoint", "AZURE_OPENAI_ENDPOINT", checkFail))
+		}
+	default:
+		need(ollamaServer(config.OllamaURL), config.Model)
+	}
+	if config.Wiki {
+		switch config.EmbedProvider {
+		case "", "ollama":
+			need(ollamaServer(""), config.EmbedModel)
+		case "openai":
+			results = append(results, checkEnv("Embedding API key", "OPENAI_API_KEY", checkFail))
+		}
+		switch {
+		case config.NoImages: // the vision model isn't used
+		case config.VisionProvider == "" || config.VisionProvider == "ollama":
+			need(ollamaServer(config.VisionURL), config.VisionModel)
+		case config.VisionProvider == "openai":
+			results = append(results, checkEnv("Vision API key", "OPENAI_API_KEY", checkWarn))
+		}
+		if config.SummaryModel != "" {
+			need(ollamaServer(config.OllamaURL), config.SummaryModel)
+		}
+	}
+	for _, server := range servers {
+		results = append(results, checkOllama(ctx, client, server, needed[server])...)
+	}
+
+	if config.QdrantURL != "" {
+		results = append(results, checkQdrant(ctx, config.QdrantURL, config.Qdrant, config.Collections)...)
+	}
+	for i, spec := range config.MCPSpecs {
+		results = append(results, checkMCP(ctx, spec, i))
+	}
+	results = append(results, checkPlugins(ctx, config.PluginsDir)...)
+	if config.SSH {
+		results = append(results, checkSSH(tools.CheckSSHAuth())...)
+	}
+	if config.Prometheus != "" {
+		results = append(results, checkPrometheus(ctx, &tools.PrometheusTool{URL: config.Prometheus, Token: os.Getenv("PROMETHEUS_TOKEN"), Timeout: doctorTimeout}))
+	}
+
+	failed := 0
+	for _, r := range results {
+		mark := "✓"
+		switch r.Status {
+		case checkWarn:
+			mark = "!"
+		case checkFail:
+			mark = "✗"
+			failed++
+		}
+		fmt.Fprintf(w, "%s %s: %s\n", mark, r.Name, r.Detail)
+		if r.Fix != "" && r.Status != checkOK {
+			fmt.Fprintf(w, "    → %s\n", r.Fix)
+		}
+	}
+	if failed > 0 {
+		fmt.Fprintf(w, "\n%d check(s) failed.\n", failed)
+	} else {
+		fmt.Fprintln(w, "\nAll checks passed.")
+	}
+	return failed
+}
+
+// ollamaServer resolves the Ollama server the way the Ollama client does:
+// the given URL, else $OLLAMA_HOST, else the local default
+func ollamaServer(url string) string {
+	if url == "" {
+		url = os.Getenv("OLLAMA_HOST")
+	}
+	if url == "" {
+		return "http://127.0.0.1:11434"
+	}
+	if !strings.Contains(url, "://") {
+		if _, _, err := net.SplitHostPort(url); err != nil {
+			url = net.JoinHostPort(strings.Trim(url, "[]"), "11434")
+		}
+		url = "http://" + url
+	}
+	return strings.TrimRight(url, "/")
+}
+
+// checkOllama checks that server answers and has each model pulled
+func checkOllama(ctx context.Context, client *http.Client, server string, models []string) []checkResult {
+	name := "Ollama " + server
+	available, err := ollamaModels(ctx, client, server)
+	if err != nil {
+		return []checkResult{{Status: checkFail, Name: name, Detail: err.Error(),
+			Fix: "start it with `ollama serve`, or point --ollama-url/$OLLAMA_HOST at a running server"}}
+	}
+	results := []checkResult{{Status: checkOK, Name: name, Detail: fmt.Sprintf("reachable (%d models)", len(available))}}
+	seen := map[string]bool{}
+	for _, model := range models {
+		if seen[model] {
+			continue
+		}
+		seen[model] = true
+		if hasModel(available, model) {
+			results = append(results, checkResult{Status: checkOK, Name: "Model " + model, Detail: "available"})
+		} else {
+			results = append(results, checkResult{Status: checkFail, Name: "Model " + model, Detail: "not pulled on " + server,
+				Fix: "ollama pull " + model})
+		}
+	}
+	return results
+}
+
+// ollamaModels lists the models pulled on an Ollama server
+func ollamaModels(ctx context.Context, client *http.Client, server string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", server+"/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("failed to decode model list: %w", err)
+	}
+	models := make([]string, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		models = append(models, m.Name)
+	}
+	return models, nil
+}
+
+// hasModel reports whether model is in available; a model without a tag
+// matches its ":latest" version
+func hasModel(available []string, model string) bool {
+	for _, name := range available {
+		if name == model || (!strings.Contains(model, ":") && name == model+":latest") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkEnv checks that an environment variable holding a credential is set
+func checkEnv(name, env string, missing checkStatus) checkResult {
+	if os.Getenv(env) == "" {
+		return checkResult{Status: missing, Name: name, Detail: "$" + env + " is not set", Fix: "export " + env + "=..., or store it: langchain-agent credentials add " + env}
+	}
+	return checkResult{Status: checkOK, Name: name, Detail: "$" + env + " is set"}
+}
+
+// checkQdrant checks that Qdrant answers and reports each wiki collection
+func checkQdrant(ctx context.Context, url string, opts rag.QdrantOptions, collections []string) []checkResult {
+	var results []checkResult
+	for _, collection := range collections {
+		name := "Qdrant collection " + collection
+		store, err := rag.NewVectorStoreWithOptions(url, collection, opts)
+		if err != nil {
+			return append(results, checkResult{Status: checkFail, Name: "Qdrant " + url, Detail: err.Error(),
+				Fix: "check --qdrant-ca, --qdrant-grpc and the collection flags"})
+		}
+		checkCtx, cancel := context.WithTimeout(ctx, doctorTimeout)
+		info, err := store.Info(checkCtx)
+		cancel()
+		store.Close()
+		switch {
+		case err != nil:
+			return append(results, checkResult{Status: checkFail, Name: "Qdrant " + url, Detail: err.Error(),
+				Fix: "check that Qdrant is running and $QDRANT_API_KEY is correct"})
+		case !info.Exists:
+			results = append(results, checkResult{Status: checkWarn, Name: name, Detail: "not created yet",
+				Fix: "index the wiki first, e.g. with --index-only"})
+		case info.Status != "green":
+			results = append(results, checkResult{Status: checkWarn, Name: name, Detail: fmt.Sprintf("status %s, %d points", info.Status, info.Points),
+				Fix: "Qdrant is still optimizing the collection (yellow) or it needs attention (red); see the Qdrant logs"})
+		default:
+			results = append(results, checkResult{Status: checkOK, Name: name, Detail: fmt.Sprintf("%d points", info.Points)})
+		}
+	}
+	return results
+}
+
+// checkMCP connects to an --mcp server to check its handshake
+func checkMCP(ctx context.Context, spec string, index int) checkResult {
+	name, target := parseMCPSpec(spec, index)
+	ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+
+	var mcpTool *tools.MCPTool
+	var err error
+	fix := "run `" + target + "` by hand to see why it fails"
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		fix = "check the URL and that the server is running"
+		mcpTool, err = tools.NewMCPToolFromURL(ctx, name, target)
+	} else {
+		parts := strings.Fields(target)
+		if len(parts) == 0 {
+			return checkResult{Status: checkFail, Name: "MCP " + name, Detail: "empty command", Fix: "use --mcp [label:]command-or-url"}
+		}
+		mcpTool, err = tools.NewMCPTool(ctx, name, parts[0], parts[1:])
+	}
+	if err != nil {
+		return checkResult{Status: checkFail, Name: "MCP " + name, Detail: err.Error(), Fix: fix}
+	}
+	defer mcpTool.Close()
+	return checkResult{Status: checkOK, Name: "MCP " + name, Detail: fmt.Sprintf("connected (%d tools)", mcpTool.ToolCount())}
+}
+
+// checkPlugins runs describe on each tool plugin, as startup would
+func checkPlugins(ctx context.Context, dir string) []checkResult {
+	paths, err := tools.FindPlugins(dir)
+	if err != nil {
+		return []checkResult{{Status: checkFail, Name: "Plugins", Detail: err.Error(), Fix: "check --plugins"}}
+	}
+	var results []checkResult
+	for _, path := range paths {
+		name := "Plugin " + filepath.Base(path)
+		p, err := tools.NewPluginTool(ctx, path)
+		if err != nil {
+			results = append(results, checkResult{Status: checkFail, Name: name, Detail: err.Error(),
+				Fix: "run `" + path + " describe` by hand, or remove its execute permission"})
+			continue
+		}
+		results = append(results, checkResult{Status: checkOK, Name: name, Detail: "tool " + p.Name()})
+	}
+	return results
+}
+
+// checkPrometheus asks the prometheus tool's server for its version
+func checkPrometheus(ctx context.Context, t *tools.PrometheusTool) checkResult {
+	version, err := t.Version(ctx)
+	if err != nil {
+		return checkResult{Status: checkFail, Name: "Prometheus", Detail: err.Error(),
+			Fix: "check --prometheus and that the server is running; set $PROMETHEUS_TOKEN if it needs a token"}
+	}
+	return checkResult{Status: checkOK, Name: "Prometheus", Detail: fmt.Sprintf("%s (version %s)", t.URL, version)}
+}
+
+// checkSSH reports the credentials ssh tool connections can use
+func checkSSH(info tools.SSHAuthInfo) []checkResult {
+	var results []checkResult
+	switch {
+	case info.AgentSocket == "":
+		results = append(results, checkResult{Status: checkWarn, Name: "SSH agent", Detail: "$SSH_AUTH_SOCK is not set",
+			Fix: "eval $(ssh-agent) && ssh-add"})
+	case info.AgentErr != nil:
+		results = append(results, checkResult{Status: checkWarn, Name: "SSH agent", Detail: info.AgentErr.Error(),
+			Fix: "restart it with eval $(ssh-agent) && ssh-add"})
+	case info.AgentKeys == 0:
+		results = append(results, checkResult{Status: checkWarn, Name: "SSH agent", Detail: "running, but no keys loaded",
+			Fix: "ssh-add"})
+	default:
+		results = append(results, checkResult{Status: checkOK, Name: "SSH agent", Detail: fmt.Sprintf("%d keys loaded", info.AgentKeys)})
+	}
+	if len(info.KeyFiles) > 0 {
+		results = append(results, checkResult{Status: checkOK, Name: "SSH keys", Detail: strings.Join(info.KeyFiles, ", ")})
+	}
+	if len(info.EncryptedKeys) > 0 {
+		results = append(results, checkResult{Status: checkWarn, Name: "SSH keys", Detail: "passphrase-protected: " + strings.Join(info.EncryptedKeys, ", ") + "; the ssh tool will ask for the passphrase",
+			Fix: "load them into the agent with ssh-add"})
+	}
+	if info.AgentKeys == 0 && len(info.KeyFiles) == 0 && len(info.EncryptedKeys) == 0 {
+		results = append(results, checkResult{Status: checkWarn, Name: "SSH auth", Detail: "no usable key; the ssh tool will prompt for passwords"})
+	}
+	return results
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rathore/langchain-agent/tools"
+)
+
+func TestOllamaServer(t *testing.T) {
+	tests := []struct {
+		url, env, want string
+	}{
+		{"http://gpu-box:11434/", "", "http://gpu-box:11434"},
+		{"", "", "http://127.0.0.1:11434"},
+		{"", "gpu-box", "http://gpu-box:11434"},
+		{"", "10.0.0.5:8080", "http://10.0.0.5:8080"},
+		{"", "https://ollama.example.com", "https://ollama.example.com"},
+	}
+	for _, tt := range tests {
+		t.Setenv("OLLAMA_HOST", tt.env)
+		if got := ollamaServer(tt.url); got != tt.want {
+			t.Errorf("ollamaServer(%q) with OLLAMA_HOST=%q = %q, want %q", tt.url, tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestHasModel(t *testing.T) {
+	available := []string{"qwen2.5:32b", "nomic-embed-text:latest"}
+	for model, want := range map[string]bool{
+		"qwen2.5:32b":             true,
+		"qwen2.5":                 false,
+		"nomic-embed-text":        true,
+		"nomic-embed-text:latest": true,
+		"llava":                   false,
+	} {
+		if got := hasModel(available, model); got != want {
+			t.Errorf("hasModel(%q) = %v, want %v", model, got, want)
+		}
+	}
+}
+
+func TestCheckOllama(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/tags" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"models": [{"name": "qwen2.5:32b"}, {"name": "llava:latest"}]}`))
+	}))
+	defer srv.Close()
+
+	results := checkOllama(context.Background(), srv.Client(), srv.URL, []string{"qwen2.5:32b", "llava", "nomic-embed-text", "llava"})
+	if len(results) != 4 {
+		t.Fatalf("checkOllama() = %+v, want the server and 3 models", results)
+	}
+	for i, want := range []checkStatus{checkOK, checkOK, checkOK, checkFail} {
+		if results[i].Status != want {
+			t.Errorf("result %d = %+v, want status %d", i, results[i], want)
+		}
+	}
+	if fix := results[3].Fix; fix != "ollama pull nomic-embed-text" {
+		t.Errorf("missing model fix = %q", fix)
+	}
+
+	srv.Close()
+	results = checkOllama(context.Background(), srv.Client(), srv.URL, []string{"qwen2.5:32b"})
+	if len(results) != 1 || results[0].Status != checkFail || results[0].Fix == "" {
+		t.Errorf("checkOllama(down) = %+v, want one failure with a fix", results)
+	}
+}
+
+func TestCheckPrometheus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status": "success", "data": {"version": "2.53.0"}}`))
+	}))
+	defer srv.Close()
+
+	if r := checkPrometheus(context.Background(), &tools.PrometheusTool{URL: srv.URL}); r.Status != checkOK || !strings.Contains(r.Detail, "version 2.53.0") {
+		t.Errorf("checkPrometheus() = %+v", r)
+	}
+	srv.Close()
+	if r := checkPrometheus(context.Background(), &tools.PrometheusTool{URL: srv.URL}); r.Status != checkFail || r.Fix == "" {
+		t.Errorf("checkPrometheus(down) = %+v, want a failure with a fix", r)
+	}
+}
+
+func TestCheckSSH(t *testing.T) {
+	results := checkSSH(tools.SSHAuthInfo{AgentSocket: "/tmp/agent.sock", AgentKeys: 2})
+	if len(results) != 1 || results[0].Status != checkOK {
+		t.Errorf("checkSSH(agent with keys) = %+v, want one ok", results)
+	}
+
+	results = checkSSH(tools.SSHAuthInfo{AgentSocket: "/tmp/agent.sock", AgentErr: errors.New("connection refused"), EncryptedKeys: []string{"/home/u/.ssh/id_ed25519"}})
+	var details []string
+	for _, r := range results {
+		if r.Status == checkOK {
+			t.Errorf("checkSSH(no usable key) has ok result %+v", r)
+		}
+		details = append(details, r.Detail)
+	}
+	got := strings.Join(details, "\n")
+	for _, want := range []string{"connection refused", "passphrase-protected", "ask for the passphrase"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("checkSSH() details = %q, want %q", got, want)
+		}
+	}
+
+	results = checkSSH(tools.SSHAuthInfo{})
+	if last := results[len(results)-1]; last.Detail != "no usable key; the ssh tool will prompt for passwords" {
+		t.Errorf("checkSSH(nothing) last = %+v", last)
+	}
+}
+
+func TestCheckPlugins(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "good"), []byte("#!/bin/sh\necho '{\"name\": \"jira\", \"description\": \"Search Jira\"}'\n"), 0755)
+	os.WriteFile(filepath.Join(dir, "bad"), []byte("#!/bin/sh\nexit 1\n"), 0755)
+	results := checkPlugins(context.Background(), dir)
+	if len(results) != 2 || results[0].Status != checkFail || results[1].Status != checkOK || results[1].Detail != "tool jira" {
+		t.Errorf("checkPlugins() = %+v, want bad failed and good ok", results)
+	}
+	if results := checkPlugins(context.Background(), filepath.Join(dir, "missing")); len(results) != 0 {
+		t.Errorf("checkPlugins(missing dir) = %+v, want nothing", results)
+	}
+}
+
+func TestRunDoctor(t *testing.T) {
+	t.Setenv("GOOGLE_API_KEY", "")
+	var sb strings.Builder
+	failed := runDoctor(context.Background(), &sb, doctorConfig{Backend: "gemini", Model: "gemini-2.5-flash"})
+	if failed != 1 {
+		t.Errorf("runDoctor() = %d failures, want 1", failed)
+	}
+	out := sb.String()
+	if !strings.Contains(out, "✗ Gemini API key: $GOOGLE_API_KEY is not set") || !strings.Contains(out, "→ export GOOGLE_API_KEY=...") {
+		t.Errorf("runDoctor() output = %q, want the missing key and its fix", out)
+	}
+
+	t.Setenv("GOOGLE_API_KEY", "key")
+	sb.Reset()
+	if failed := runDoctor(context.Background(), &sb, doctorConfig{Backend: "gemini"}); failed != 0 || !strings.Contains(sb.String(), "All checks passed.") {
+		t.Errorf("runDoctor() = %d, %q, want all checks passed", failed, sb.String())
+	}
+}
+
+func TestRunDoctor_OpenAI(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("OPENAI_BASE_URL", "")
+	var sb strings.Builder
+	if failed := runDoctor(context.Background(), &sb, doctorConfig{Backend: "openai", Model: "gpt-4o-mini"}); failed != 1 {
+		t.Errorf("runDoctor(openai) = %d failures, want 1:\n%s", failed, sb.String())
+	}
+
+	// A local OpenAI-compatible server needs no key
+	sb.Reset()
+	if failed := runDoctor(context.Background(), &sb, doctorConfig{Backend: "openai", APIURL: "http://localhost:8000/v1"}); failed != 0 || !strings.Contains(sb.String(), "! OpenAI API key") {
+		t.Errorf("runDoctor(openai, --api-url) = %d, %q, want a warning only", failed, sb.String())
+	}
+}
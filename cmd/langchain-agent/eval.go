@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/rathore/langchain-agent/eval"
+)
+
+// writeEval prints an eval report, compared with baseline if given, and
+// saves it to reportFile if set
+func writeEval(w io.Writer, report, baseline *eval.Report, reportFile string) error {
+	report.WriteText(w)
+	if baseline != nil {
+		fmt.Fprintln(w)
+		eval.Compare(w, baseline, report)
+	}
+	if reportFile != "" {
+		if err := report.Save(reportFile); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "\nReport written to %s\n", reportFile)
+	}
+	return nil
+}
+
+// writeEvalOutput writes the report to stdout for --output json or junit;
+// with text, writeEval has printed it already
+func writeEvalOutput(w io.Writer, report *eval.Report, output string) error {
+	switch output {
+	case "json":
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			return fmt.Errorf("failed to write eval report: %w", err)
+		}
+	case "junit":
+		return report.WriteJUnit(w)
+	}
+	return nil
+}
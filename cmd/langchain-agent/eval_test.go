@@ -0,0 +1,49 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rathore/langchain-agent/eval"
+)
+
+func TestWriteEval(t *testing.T) {
+	baseline := &eval.Report{Suite: "ops", Model: "llama3.1"}
+	baseline.Add(eval.TaskResult{Name: "disk", Score: 0.5, Weight: 1})
+	report := &eval.Report{Suite: "ops", Model: "qwen2.5:32b"}
+	report.Add(eval.TaskResult{Name: "disk", Pass: true, Score: 1, Weight: 1})
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	var b strings.Builder
+	if err := writeEval(&b, report, baseline, path); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"Passed 1/1 tasks", "llama3.1 (baseline) vs qwen2.5:32b", "fixed", "Report written to " + path} {
+		if !strings.Contains(b.String(), s) {
+			t.Errorf("output missing %q:\n%s", s, b.String())
+		}
+	}
+	if saved, err := eval.LoadReport(path); err != nil || saved.Model != "qwen2.5:32b" {
+		t.Errorf("saved report = %+v, %v", saved, err)
+	}
+}
+
+func TestWriteEvalOutput(t *testing.T) {
+	report := &eval.Report{Suite: "ops", Model: "qwen2.5:32b"}
+	report.Add(eval.TaskResult{Name: "disk", Pass: true, Score: 1, Weight: 1})
+
+	for output, want := range map[string]string{
+		"text":  "",
+		"json":  `"suite":"ops"`,
+		"junit": `<testsuite name="ops" tests="1" failures="0" errors="0"`,
+	} {
+		var b strings.Builder
+		if err := writeEvalOutput(&b, report, output); err != nil {
+			t.Fatal(err)
+		}
+		if want == "" && b.Len() > 0 || !strings.Contains(b.String(), want) {
+			t.Errorf("--output %s wrote %q, want %q", output, b.String(), want)
+		}
+	}
+}
@@ -0,0 +1,1728 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rathore/langchain-agent/agent"
+	"github.com/rathore/langchain-agent/bench"
+	"github.com/rathore/langchain-agent/credentials"
+	"github.com/rathore/langchain-agent/eval"
+	"github.com/rathore/langchain-agent/format"
+	"github.com/rathore/langchain-agent/guard"
+	"github.com/rathore/langchain-agent/llm"
+	"github.com/rathore/langchain-agent/policy"
+	"github.com/rathore/langchain-agent/rag"
+	"github.com/rathore/langchain-agent/redact"
+	"github.com/rathore/langchain-agent/replay"
+	"github.com/rathore/langchain-agent/tools"
+	"github.com/rathore/langchain-agent/trigger"
+	"github.com/rathore/langchain-agent/webhook"
+)
+
+// stringSlice implements flag.Value for repeatable string flags.
+type stringSlice []string
+
+func (s *stringSlice) String() string { return strings.Join(*s, ", ") }
+func (s *stringSlice) Set(val string) error {
+	*s = append(*s, val)
+	return nil
+}
+
+// parseMCPSpec parses an MCP spec into a tool name and target command/URL.
+// Format: [label:]command-or-url
+// If label is provided: tool name is "mcp_<label>"
+// If no label: "mcp" for index 0, "mcp2" for index 1, etc.
+func parseMCPSpec(spec string, index int) (name, target string) {
+	// Check for label:target format.
+	// Only split if the part before ':' doesn't look like a URL scheme.
+	if i := strings.Index(spec, ":"); i > 0 {
+		prefix := spec[:i]
+		if prefix != "http" && prefix != "https" {
+			label := prefix
+			target = strings.TrimSpace(spec[i+1:])
+			return "mcp_" + label, target
+		}
+	}
+
+	// No label — auto-generate name
+	if index == 0 {
+		return "mcp", spec
+	}
+	return fmt.Sprintf("mcp%d", index+1), spec
+}
+
+// parseWikiSpec parses a wiki spec into a knowledge base label and export path.
+// Format: [label:]path
+// A labeled source is indexed into its own collection and searched by the
+// "wiki_<label>" tool; an unlabeled one uses the default collection and "wiki".
+func parseWikiSpec(spec string) (label, path string) {
+	if i := strings.Index(spec, ":"); i > 0 && !strings.ContainsAny(spec[:i], `/\.`) {
+		return spec[:i], strings.TrimSpace(spec[i+1:])
+	}
+	return "", spec
+}
+
+// parseSourceSpec parses a --wiki-source spec. Format: name[@format]:path
+func parseSourceSpec(spec string) (rag.IndexSource, error) {
+	name, path := parseWikiSpec(spec)
+	if name == "" || path == "" {
+		return rag.IndexSource{}, fmt.Errorf("invalid --wiki-source %q (use name[@format]:path)", spec)
+	}
+	src := rag.IndexSource{Name: name, Path: path}
+	if i := strings.Index(name, "@"); i >= 0 {
+		src.Name, src.Format = name[:i], name[i+1:]
+	}
+	return src, nil
+}
+
+// toolFilter applies --enable-tools and --disable-tools to tool names
+type toolFilter struct {
+	enable  map[string]bool // nil = every tool not disabled
+	disable map[string]bool
+	seen    map[string]bool // names checked, to report unknown ones
+}
+
+// newToolFilter builds a filter from flag values, each a comma-separated
+// list of tool names
+func newToolFilter(enable, disable []string) *toolFilter {
+	names := func(values []string) map[string]bool {
+		set := map[string]bool{}
+		for _, v := range values {
+			for _, name := range strings.Split(v, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					set[name] = true
+				}
+			}
+		}
+		return set
+	}
+	f := &toolFilter{disable: names(disable), seen: map[string]bool{}}
+	if len(enable) > 0 {
+		f.enable = names(enable)
+	}
+	return f
+}
+
+// allows reports whether the named tool should be registered
+func (f *toolFilter) allows(name string) bool {
+	f.seen[name] = true
+	if f.disable[name] {
+		return false
+	}
+	return f.enable == nil || f.enable[name]
+}
+
+// unknown returns the names given in the flags that matched no tool
+func (f *toolFilter) unknown() []string {
+	var names []string
+	for _, set := range []map[string]bool{f.enable, f.disable} {
+		for name := range set {
+			if !f.seen[name] {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// providerURL picks the base URL flag that applies to a provider
+func providerURL(provider, ollamaURL, apiURL string) string {
+	if provider == "ollama" {
+		return ollamaURL
+	}
+	return apiURL
+}
+
+// defaultModel is the model used for a provider without --model. Azure
+// OpenAI has none: the model is the deployment name.
+func defaultModel(provider string) string {
+	switch provider {
+	case "gemini":
+		return "gemini-2.5-flash"
+	case "openai":
+		return "gpt-4o-mini"
+	case "anthropic":
+		return "claude-sonnet-4-5"
+	case "azure":
+		return ""
+	default:
+		return "qwen2.5:32b"
+	}
+}
+
+// defaultPluginsDir is where tool plugins are looked for without --plugins
+func defaultPluginsDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "langchain-agent", "plugins")
+}
+
+// shortenMCPDescriptions has client shorten the MCP tool descriptions
+// longer than limit (--mcp-description-limit), with the summaries cached
+// in the user cache dir so only new and changed descriptions cost a call
+func shortenMCPDescriptions(mcpTools []*tools.MCPTool, client llm.ChatClient, limit int) {
+	var cache *tools.DescriptionCache
+	if cacheDir, err := os.UserCacheDir(); err == nil {
+		path := filepath.Join(cacheDir, "langchain-agent", "mcp-descriptions.json")
+		if cache, err = tools.LoadDescriptionCache(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	ctx := context.Background()
+	for _, m := range mcpTools {
+		n, err := m.ShortenDescriptions(ctx, client, limit, cache)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %v; cut to %d characters instead\n", m.Name(), err, limit)
+		}
+		if n > 0 {
+			fmt.Printf("Summarized %d %s tool descriptions to %d characters.\n", n, m.Name(), limit)
+		}
+	}
+	if cache != nil {
+		if err := cache.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+}
+
+// reportPromptSize tells which tools were pruned from the system prompt to
+// fit --prompt-tokens, and warns when it doesn't fit even so
+func reportPromptSize(size agent.PromptSize) {
+	if len(size.Pruned) > 0 {
+		fmt.Printf("System prompt: %d tokens; listed without parameters to fit --prompt-tokens: %s\n", size.Tokens, strings.Join(size.Pruned, ", "))
+	}
+	if size.OverBudget() {
+		fmt.Fprintf(os.Stderr, "Warning: the system prompt is %d tokens, over --prompt-tokens %d even with every tool pruned\n", size.Tokens, size.Budget)
+	}
+}
+
+// readOnlyRegistry keeps only the tools registered as read-only as a
+// whole. Triggered runs get it: their prompts hold what the sender posted,
+// so shell and ssh, whose commands only the read-only checker guards, and
+// mixed tools are left out.
+func readOnlyRegistry(r *tools.Registry) *tools.Registry {
+	return r.Filter(func(name string) bool {
+		meta, _ := r.Meta(name)
+		return meta.ReadOnly
+	})
+}
+
+// cutCommand reports whether input is the slash command name, and returns
+// its arguments
+func cutCommand(input, name string) (string, bool) {
+	args, ok := strings.CutPrefix(input, name)
+	if !ok || (args != "" && args[0] != ' ') {
+		return "", false
+	}
+	return strings.TrimSpace(args), true
+}
+
+// runOutput is the --output (and --batch) record of one query
+type runOutput = format.Result
+
+func main() {
+	// Subcommands: "langchain-agent doctor [flags]" checks the configured
+	// services instead of starting the agent; "langchain-agent ask [flags]
+	// query" sends a query to a running --daemon; "langchain-agent
+	// completion <shell>" prints a shell completion script; "langchain-agent
+	// eval [flags] tasks.yaml" scores the agent on a task suite;
+	// "langchain-agent bench" measures indexing, search and agent loop speed;
+	// "langchain-agent reembed --embed-model m [flags]" moves the wiki
+	// collections to a new embedding model; "langchain-agent credentials
+	// add|list|remove" manages the stored secrets
+	var subcommand string
+	if len(os.Args) > 1 && slices.Contains(subcommands, os.Args[1]) {
+		subcommand = os.Args[1]
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+
+	provider := flag.String("provider", "ollama", "LLM provider: "+strings.Join(llm.Providers(), ", ")+" (openai: OpenAI or any OpenAI-compatible server such as vLLM or LM Studio; azure: Azure OpenAI; anthropic: Claude, key from $ANTHROPIC_API_KEY)")
+	flag.StringVar(provider, "backend", "ollama", "Alias of --provider")
+	model := flag.String("model", "", "Model name (default: qwen2.5:32b for ollama, gemini-2.5-flash for gemini, gpt-4o-mini for openai, claude-sonnet-4-5 for anthropic; for azure, the deployment name)")
+	ollamaURL := flag.String("ollama-url", "", "Ollama server URL (default: http://localhost:11434; also honors $OLLAMA_HOST). Ignored for other providers")
+	apiURL := flag.String("api-url", "", "Base URL for --provider openai (default: https://api.openai.com/v1 or $OPENAI_BASE_URL; e.g. http://localhost:8000/v1 for vLLM), the resource endpoint for azure (default: $AZURE_OPENAI_ENDPOINT), or a gateway for anthropic")
+	azureAPIVersion := flag.String("azure-api-version", llm.AzureAPIVersion, "Azure OpenAI API version")
+	maxIter := flag.Int("max-iter", 10, "Maximum agent iterations per query")
+	textTools := flag.Bool("text-tools", false, "Have the model write tool calls as JSON in its replies instead of using the provider's native tool calling (automatic for models without tool support)")
+	nudge := flag.String("nudge", "", "Message sent to the model after a reply that is neither a tool call nor a final answer (default: a built-in reminder of the tool-call format; none: retry without one)")
+	stallAfter := flag.Int("stall-after", 3, "Stop a query after this many steps in a row without progress (invalid replies, repeated calls with the same result), before --max-iter (0: never)")
+	language := flag.String("language", "", "Language to write answers in, e.g. German or pt-BR (change with /prefs)")
+	verbosity := flag.String("verbosity", "", "Answer length: brief, normal or detailed (default: the model's)")
+	units := flag.String("units", "", "Units in answers: metric or imperial (default: the model's)")
+	dateFormat := flag.String("date-format", "", "How answers write dates, e.g. DD.MM.YYYY or ISO 8601")
+	var contextDefaults stringSlice
+	flag.Var(&contextDefaults, "context", "Session default tools use when the model leaves it out, as key=value (repeatable or comma-separated, e.g. namespace=prod,cluster=staging,host=web-1; change with /context)")
+	historyTokens := flag.Int("history-tokens", 0, "Most tokens of conversation history sent with a query; beyond it the oldest exchanges are summarized by the model into the system message (0: send it all; e.g. 6000 for an 8k-context model)")
+	promptTokens := flag.Int("prompt-tokens", 0, "Most tokens of the system prompt; beyond it the largest tool definitions are listed without parameters, which the model gets with describe_tool (0: no limit; e.g. 3000 for an 8k-context model with many MCP tools)")
+	nudgeBackoff := flag.Duration("nudge-backoff", 0, "Wait this long before retrying after a step without progress, doubling each time up to 30s (e.g. 2s for rate-limited hosted models)")
+	var wikiSpecs stringSlice
+	flag.Var(&wikiSpecs, "wiki", "Wiki export to index and search (repeatable). Format: [label:]path — labeled exports get their own collection and a wiki_<label> tool")
+	var sourceSpecs stringSlice
+	flag.Var(&sourceSpecs, "wiki-source", "Export to combine into the default wiki collection (repeatable). Format: name[@format]:path — documents are tagged with name, which wiki searches can filter on")
+	wikiFormat := flag.String("wiki-format", "confluence", "Format of the --wiki export: confluence (HTML), mediawiki (XML dump file), notion (Markdown/HTML export)")
+	var skipSelectors, skipText stringSlice
+	flag.Var(&skipSelectors, "skip-html", "Also leave this HTML element out of wiki pages: tag, #id, .class or tag.class, e.g. .page-toc (repeatable)")
+	flag.Var(&skipText, "skip-text", "Also drop wiki text chunks matching this regular expression, e.g. '^Owner: ' (repeatable)")
+	keepBoilerplate := flag.Bool("keep-boilerplate", false, "Index the navigation sidebars, footers, comment sections and author lines that are skipped by default")
+	maxPageMB := flag.Int("max-page-mb", rag.DefaultMaxFileSize>>20, "Skip wiki page files larger than this many MB, so one runaway export page can't exhaust memory (0 for no limit)")
+	tableFormat := flag.String("table-format", "rows", "How wiki HTML tables are chunked: rows (\"Header: value; ...\" per row) or markdown")
+	chunkTokens := flag.Int("chunk-tokens", 256, "Max wiki chunk size in embedding tokens (0 = legacy 500-byte chunks)")
+	embedProvider := flag.String("embed-provider", "ollama", "Wiki embedding backend: ollama or openai (any OpenAI-compatible /embeddings API; key from $OPENAI_API_KEY)")
+	embedModel := flag.String("embed-model", "", "Wiki embedding model (default: nomic-embed-text for ollama, text-embedding-3-small for openai)")
+	multilingual := flag.Bool("multilingual", false, "Wiki mixes languages (e.g. English and German pages): default --embed-model to "+rag.MultilingualEmbedModel+" for ollama, so questions find pages in any language")
+	embedURL := flag.String("embed-url", "", "Base URL for --embed-provider openai (default: https://api.openai.com/v1)")
+	qdrantURL := flag.String("qdrant", "", "Qdrant server URL, e.g. http://localhost:6333 (default: embedded on-disk store in the user cache dir)")
+	qdrantCA := flag.String("qdrant-ca", "", "PEM CA bundle for an https:// Qdrant with a private CA (API key from $QDRANT_API_KEY)")
+	qdrantInsecure := flag.Bool("qdrant-insecure", false, "Skip TLS certificate verification for Qdrant (testing only)")
+	qdrantGRPC := flag.String("qdrant-grpc", "", "Qdrant gRPC host:port (e.g. localhost:6334) for faster upserts and searches; REST is still used for collection management")
+	qdrantHNSWM := flag.Int("qdrant-hnsw-m", 0, "HNSW graph degree for new Qdrant collections (0 = Qdrant default 16)")
+	qdrantEfConstruct := flag.Int("qdrant-ef-construct", 0, "HNSW ef_construct for new Qdrant collections (0 = Qdrant default 100)")
+	qdrantOnDisk := flag.Bool("qdrant-on-disk", false, "Store vectors and payloads of new Qdrant collections on disk to bound memory")
+	qdrantQuantization := flag.String("qdrant-quantization", "", "Quantize new Qdrant collections: scalar (int8) or product")
+	milvusURL := flag.String("milvus", "", "Milvus server URL, e.g. http://localhost:19530 (token from $MILVUS_TOKEN)")
+	weaviateURL := flag.String("weaviate", "", "Weaviate server URL, e.g. http://localhost:8080 (API key from $WEAVIATE_API_KEY)")
+	autoRAG := flag.Int("auto-rag", 0, "Search the wiki with every query and add the top N results to the prompt, for models that skip the wiki tool (0 = off)")
+	wikiExpansion := flag.String("wiki-query-expansion", "none", "Rewrite wiki search queries with the LLM before searching: none, multi-query (search rephrasings and fuse results) or hyde (embed a hypothetical answer)")
+	visionProvider := flag.String("vision-provider", "ollama", "Wiki diagram description backend: ollama or openai (any OpenAI-compatible chat API with image input; key from $OPENAI_API_KEY)")
+	visionModel := flag.String("vision-model", "", "Vision model for wiki diagrams (default: llava for ollama, gpt-4o-mini for openai; any Ollama multimodal model works)")
+	visionURL := flag.String("vision-url", "", "Ollama server or OpenAI base URL for the vision model (default: the provider's)")
+	visionWorkers := flag.Int("vision-workers", 2, "Wiki images described concurrently")
+	visionProfiles := flag.String("vision-profiles", "", "Describe diagrams, screenshots and charts with prompts made for each: heuristic (pick by image file name and alt text) or classify (also ask the vision model about the rest); default: one generic prompt")
+	dedupThreshold := flag.Float64("dedup-threshold", 0.9, "Drop wiki chunks at least this similar (0-1) to one already indexed, e.g. repeated navigation and footers (0 disables)")
+	summaryModel := flag.String("summary-model", "", "Ollama model that writes a summary of each wiki page while indexing, for broad questions (e.g. llama3.2; default: no summaries)")
+	noImages := flag.Bool("no-images", false, "Leave wiki images out of the index: no descriptions, no OCR and no vision model needed")
+	maxPageImages := flag.Int("max-page-images", 0, "Describe at most this many images per wiki page, in page order (0 for all)")
+	minImagePx := flag.Int("min-image-px", rag.DefaultMinImageSize, "Skip wiki images narrower or shorter than this many pixels, such as icons and emoticons (0 for any size)")
+	minImageKB := flag.Int("min-image-kb", 0, "Skip wiki image files smaller than this many KB (0 for any size)")
+	ocr := flag.String("ocr", "", "Also index text inside wiki images (config screenshots, dashboards): tesseract or vision (transcribe with the vision model)")
+	loadReport := flag.String("load-report", "", "Write the export problems found while loading the wiki (missing images, broken links, unreadable files, empty and orphan pages) to this JSON file")
+	indexReport := flag.String("index-report", "", "Write wiki documents skipped during indexing (embedding/vision failures) to this JSON file")
+	freshIndex := flag.Bool("fresh-index", false, "Ignore the checkpoint of an interrupted wiki index run and re-index from scratch")
+	wikiFallback := flag.Bool("wiki-fallback", false, "While the wiki's vector store (Qdrant) is unreachable, search the wiki export by keywords instead of reporting the wiki unavailable")
+	watch := flag.Bool("watch", false, "Keep the wiki index in sync with changes to the export files while the agent runs")
+	reindexInterval := flag.Duration("reindex-interval", 0, "Also re-sync the wiki index on this schedule (e.g. 1h; Confluence API sources are re-fetched)")
+	indexOnly := flag.Bool("index-only", false, "Only index the wiki, then exit")
+	confluenceURL := flag.String("confluence-url", "", "Confluence base URL to index via REST API instead of an HTML export (token from $CONFLUENCE_TOKEN, user from $CONFLUENCE_USER)")
+	var confluenceSpaces stringSlice
+	flag.Var(&confluenceSpaces, "confluence-space", "Confluence space key to index (repeatable; default: all visible spaces)")
+	confluenceCQL := flag.String("confluence-cql", "", "Extra CQL filter for --confluence-url, e.g. 'label = \"runbook\"'")
+	confluenceDelta := flag.Bool("confluence-delta", false, "Only re-index Confluence pages modified since the last sync")
+	var mcpSpecs stringSlice
+	flag.Var(&mcpSpecs, "mcp", "MCP server (repeatable). Format: [label:]command-or-url")
+	prometheusURL := flag.String("prometheus", "", "Prometheus server URL (e.g. http://prometheus:9090) — enables the prometheus tool for metric queries and alerts (token from $PROMETHEUS_TOKEN)")
+	edgeHost := flag.String("edge", "", "Edge target user@host (Pi, mini-PC, NUC, ...) — enables edge_temp, edge_gpio, edge_camera tools")
+	var enableTools, disableTools stringSlice
+	flag.Var(&enableTools, "enable-tools", "Register only these tools (comma-separated names, e.g. wiki,mcp; repeatable; default: all)")
+	flag.Var(&disableTools, "disable-tools", "Never register these tools (comma-separated names, e.g. shell,ssh; repeatable)")
+	policyFile := flag.String("policy", "", "YAML file of roles: the tools, hosts, Kubernetes namespaces and commands each may use, checked before every tool call")
+	role := flag.String("role", "", "--policy role for this session (default: the policy's default role)")
+	approve := flag.String("approve", "", "Ask in the REPL before running tool calls of this risk or higher: low, medium or high (see /tools); with nobody to ask, e.g. in --batch or the webhook, such calls are denied")
+	readOnly := flag.Bool("read-only", false, "Block every tool call that may change state: shell and ssh commands not known to be read-only (kubectl get, ls, ...), writes, and MCP tools not annotated read-only; on top of any --policy role, for demos against production")
+	var redactPatterns, redactAllow stringSlice
+	flag.Var(&redactPatterns, "redact-pattern", "Also mask text matching this regular expression in tool output (repeatable; a group named \"secret\" masks only that group)")
+	flag.Var(&redactAllow, "redact-allow", "Never mask a secret matching this whole regular expression, e.g. a known test token (repeatable)")
+	injectionScreen := flag.String("injection-screen", "off", "Screen tool output and wiki results for injected instructions: off, warn (flag them to the LLM), redact (remove them) or block (withhold the content)")
+	var injectionPatterns stringSlice
+	flag.Var(&injectionPatterns, "injection-pattern", "Also treat text matching this regular expression as an injected instruction (repeatable; see --injection-screen)")
+	noUntrustedBlocks := flag.Bool("no-untrusted-blocks", false, "Pass tool output and wiki results to the LLM as is, without untrusted-content blocks or --injection-screen")
+	toolConcurrency := flag.Int("tool-concurrency", 0, "Calls each remote tool (ssh, MCP servers, edge tools, plugins) runs at once; more wait their turn (0: no limit)")
+	breakerFailures := flag.Int("breaker-failures", 3, "Failures in a row (unreachable host, dead MCP server, timeout) after which a remote tool fails fast until --breaker-cooldown has passed (0: never)")
+	breakerCooldown := flag.Duration("breaker-cooldown", 30*time.Second, "How long a circuit-broken tool fails fast before it is tried again")
+	mcpTimeout := flag.Duration("mcp-timeout", 60*time.Second, "Timeout of each MCP tool call")
+	mcpDescLimit := flag.Int("mcp-description-limit", 0, "Have the LLM shorten MCP tool descriptions longer than this many characters before they go into the system prompt, for small models; summaries are cached in the user cache dir (0: whole descriptions)")
+	price := flag.String("price", "", "Model price as INPUT/OUTPUT dollars per million tokens, e.g. 1.25/10, for cost tracking (default: the list price of known hosted models; none for ollama)")
+	maxCost := flag.Float64("max-cost", 0, "Refuse queries once the session's queries have cost this many dollars (0: no limit; needs a known price)")
+	maxRunCost := flag.Float64("max-run-cost", 0, "Stop a query once it has cost this many dollars (0: no limit; needs a known price)")
+	evidence := flag.Bool("evidence", false, "After each answer, list the tool output and wiki text that support each of its claims, with a confidence level (in --output json: an \"evidence\" field)")
+	rawOutput := flag.Bool("raw-output", false, "Pass shell and SSH output to the LLM as the command wrote it, without stripping ANSI codes, fixing encodings or collapsing progress bars")
+	pipeThreshold := flag.Int("pipe-threshold", 8000, "Show the LLM only the start and end of tool results longer than this many bytes; every result gets a handle (@result1, ...) that passes it whole to a later tool call (0: send results whole, without handles)")
+	workspaceDir := flag.String("workspace", "", "Directory for files tools keep during the session (saved logs, scripts, MCP images), kept across sessions (default: a temporary directory removed on exit)")
+	workspaceMaxAge := flag.Duration("workspace-max-age", 0, "Remove workspace files older than this, e.g. 168h (default: keep)")
+	workspaceMaxMB := flag.Int("workspace-max-mb", 100, "Remove the oldest workspace files when it grows beyond this many megabytes (0: no limit)")
+	compare := flag.String("compare", "", "Run each REPL query through these models in turn (comma-separated, e.g. qwen2.5:32b,llama3.1), with shared tools and separate histories, and compare their answers, steps, tokens and time")
+	recordFile := flag.String("record", "", "Record every run (LLM requests and responses, tool calls) to this cassette file, for replay tests")
+	replayFile := flag.String("replay", "", "eval: replay the LLM responses recorded in this --record cassette instead of asking the model")
+	evalReport := flag.String("eval-report", "", "eval: write the scores as a JSON report to this file")
+	evalBaseline := flag.String("eval-baseline", "", "eval: compare the scores with this earlier --eval-report (another model or prompt)")
+	benchPages := flag.Int("bench-pages", 200, "bench: synthetic wiki pages to index")
+	benchQueries := flag.Int("bench-queries", 500, "bench: wiki searches to time")
+	benchRuns := flag.Int("bench-runs", 1000, "bench: agent runs to time with the null LLM")
+	noRedact := flag.Bool("no-redact", false, "Don't mask API keys, passwords, private keys and tokens in tool output (--redact-pattern still applies)")
+	webhookPort := flag.Int("webhook-port", 0, "If >0, start an HTTP webhook listener on this port (POST /webhook, GET /ws, GET /health, GET /metrics)")
+	triggersFile := flag.String("triggers", "", "YAML file of triggers that start a run for Kubernetes warning events or Alertmanager alerts and save a triage report (with the REPL or --daemon)")
+	authConfig := flag.String("auth-config", "", "YAML file of webhook users (API keys, OIDC, per-user tools and rate limits); makes the --webhook-port server multi-user")
+	batchFile := flag.String("batch", "", "Run the queries in this file (one per line, or JSON lines of {\"id\", \"query\"}) and exit, writing one JSON result per query")
+	batchOutput := flag.String("batch-output", "", "File for --batch results (default: stdout, with progress on stderr)")
+	batchSession := flag.String("batch-session", "fresh", "--batch conversation: fresh (clear history before each query) or shared")
+	noColor := flag.Bool("no-color", false, "Print answers as raw Markdown without ANSI colors (default: colors when stdout is a terminal and $NO_COLOR is unset)")
+	pluginsDir := flag.String("plugins", "", "Directory of tool plugin executables (default: langchain-agent/plugins in the user config dir)")
+	promptsDir := flag.String("prompts", "", "Directory of prompt templates (*.yaml) for /run (default: langchain-agent/prompts in the user config dir)")
+	historyFile := flag.String("history-file", "", "REPL history file (default: langchain-agent/history in the user cache dir)")
+	sessionsDir := flag.String("sessions-dir", "", "Directory where each REPL conversation is saved with a title and summary, listed by /sessions (default: langchain-agent/sessions in the user cache dir)")
+	sessionStore := flag.String("session-store", "file", "Where sessions are saved: file (a JSON file per session in --sessions-dir) or sqlite (sessions.db in --sessions-dir, or the DSN in $"+sessionDSNEnv+"; needs a build with -tags sqlite)")
+	credentialStore := flag.String("credential-store", "auto", "Where `langchain-agent credentials` keeps secrets and they are looked up: auto (the OS keyring if there is one, else file), keyring (secret-tool or the macOS keychain) or file (credentials.json in langchain-agent in the user config dir, encrypted with a passphrase from $"+passphraseEnv+" or the terminal)")
+	noSessions := flag.Bool("no-sessions", false, "Don't save REPL conversations")
+	toolStatsFile := flag.String("tool-stats", "", "File where each tool's calls, errors and latency are counted across sessions, shown by /stats tools and on the webhook's /metrics (default: langchain-agent/tool-stats.json in the user cache dir)")
+	noToolStats := flag.Bool("no-tool-stats", false, "Don't count tool calls")
+	noSessionSummary := flag.Bool("no-session-summary", false, "Title saved sessions by their first query instead of asking the LLM for a title and summary after each exchange")
+	output := flag.String("output", "text", "Answer format: text (rendered for the terminal); json, markdown, plain or slack (Block Kit JSON), with one result per query on stdout and progress on stderr; or junit, for eval reports")
+	daemon := flag.Bool("daemon", false, "Run without a REPL, serving \"langchain-agent ask\" queries on --socket with MCP connections, SSH connections and the wiki index kept warm")
+	socketPath := flag.String("socket", defaultSocketPath(), "Unix socket of the --daemon")
+	configFile := flag.String("config", "", "YAML file of flag settings (keys are flag names; command-line flags override it)")
+	flag.Parse()
+	if *configFile != "" {
+		if err := applyConfigFile(flag.CommandLine, *configFile); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	// An explicit --embed-model wins; OpenAI's embedding models are already multilingual
+	if *multilingual && *embedModel == "" && *embedProvider == "ollama" {
+		*embedModel = rag.MultilingualEmbedModel
+	}
+
+	if subcommand == "completion" {
+		args := flag.Args()
+		if len(args) != 1 {
+			fmt.Fprintf(os.Stderr, "Usage: langchain-agent completion <%s>\n", strings.Join(completionShells, "|"))
+			os.Exit(2)
+		}
+		if args[0] == "models" {
+			// Called by the completion scripts; stays quiet when Ollama is down
+			models, err := completionModels(context.Background(), *ollamaURL)
+			if err != nil {
+				os.Exit(1)
+			}
+			fmt.Println(strings.Join(models, "\n"))
+			return
+		}
+		script, err := completionScript(args[0], completionFlags(flag.CommandLine))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		fmt.Print(script)
+		return
+	}
+
+	// The ask client only talks to the daemon, which has everything set up
+	if subcommand == "ask" {
+		query := strings.Join(flag.Args(), " ")
+		if query == "" {
+			data, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to read query: %v\n", err)
+				os.Exit(1)
+			}
+			query = strings.TrimSpace(string(data))
+		}
+		if query == "" {
+			fmt.Fprintln(os.Stderr, "Usage: langchain-agent ask [--socket path] <query>")
+			os.Exit(2)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		answer, err := askDaemon(ctx, *socketPath, query, os.Stderr)
+		stop()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if *output == "text" {
+			fmt.Println(renderMarkdown(answer, useColor(*noColor)))
+			return
+		}
+		f, err := format.Get(*output)
+		if err == nil {
+			err = f.Format(os.Stdout, runOutput{RunResult: &agent.RunResult{Query: query, Answer: answer}})
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if subcommand == "credentials" {
+		store, err := openCredentials(*credentialStore)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(runCredentials(os.Stderr, os.Stdin, store, flag.Args()))
+	}
+
+	// Stored API keys and tokens fill in the environment variables that
+	// aren't set, before anything reads them
+	creds, err := openCredentials(*credentialStore)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: no credential store: %v\n", err)
+	} else if _, err := credentials.ExportEnv(creds); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to read stored credentials: %v\n", err)
+	}
+
+	// bench needs no model or services: it uses a synthetic wiki, hashed
+	// embeddings and a null LLM
+	if subcommand == "bench" {
+		fmt.Fprintf(os.Stderr, "Benchmarking %d pages, %d searches, %d agent runs...\n", *benchPages, *benchQueries, *benchRuns)
+		report, err := bench.Run(context.Background(), bench.Config{Pages: *benchPages, Queries: *benchQueries, Runs: *benchRuns})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Benchmark failed: %v\n", err)
+			os.Exit(1)
+		}
+		if *output == "json" {
+			json.NewEncoder(os.Stdout).Encode(report)
+		} else {
+			report.WriteText(os.Stdout)
+		}
+		return
+	}
+
+	if *compare != "" && (subcommand != "" || *batchFile != "" || *daemon || *webhookPort > 0 || *recordFile != "") {
+		fmt.Fprintln(os.Stderr, "--compare is for REPL queries; it can't be used with subcommands, --batch, --daemon, --webhook-port or --record")
+		os.Exit(1)
+	}
+	if *compare != "" && *output != "text" && *output != "json" {
+		fmt.Fprintln(os.Stderr, "--compare writes text or json (--output)")
+		os.Exit(1)
+	}
+	if *mcpDescLimit < 0 || *mcpDescLimit > 0 && *mcpDescLimit < 20 {
+		fmt.Fprintln(os.Stderr, "--mcp-description-limit must be 0 or at least 20")
+		os.Exit(1)
+	}
+
+	// eval runs its task suite on the agent set up as for the REPL
+	var suite *eval.Suite
+	var baseline *eval.Report
+	if subcommand == "eval" {
+		args := flag.Args()
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, "Usage: langchain-agent eval [--replay cassette.json] [--eval-report report.json] [--eval-baseline report.json] [flags] tasks.yaml")
+			os.Exit(2)
+		}
+		var err error
+		suite, err = eval.Load(args[0])
+		if err == nil && *evalBaseline != "" {
+			baseline, err = eval.LoadReport(*evalBaseline)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *replayFile != "" || *evalReport != "" || *evalBaseline != "" {
+		fmt.Fprintln(os.Stderr, "--replay, --eval-report and --eval-baseline are for the eval subcommand")
+		os.Exit(1)
+	}
+
+	// With an --output other than text stdout carries only run results (or
+	// the eval report); everything else printed (progress, streaming,
+	// prompts) goes to stderr
+	stdout := os.Stdout
+	var resultFormat format.Formatter
+	switch *output {
+	case "text":
+	case "junit":
+		if suite == nil {
+			fmt.Fprintln(os.Stderr, "--output junit is for the eval subcommand")
+			os.Exit(1)
+		}
+		os.Stdout = os.Stderr
+	default:
+		f, err := format.Get(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unknown --output %q (use text, %s, or junit for eval)\n", *output, strings.Join(format.Names(), ", "))
+			os.Exit(1)
+		}
+		if suite != nil && *output != "json" {
+			fmt.Fprintln(os.Stderr, "eval reports are written as text, json or junit (--output)")
+			os.Exit(1)
+		}
+		resultFormat = f
+		os.Stdout = os.Stderr
+	}
+	var resultOut *format.Encoder
+	if resultFormat != nil {
+		resultOut = format.NewEncoder(stdout, resultFormat)
+	}
+	if *batchFile != "" && *batchOutput == "" && resultOut == nil {
+		resultFormat = format.JSON{}
+		resultOut = format.NewEncoder(stdout, resultFormat)
+		os.Stdout = os.Stderr
+	}
+	// --compare writes one JSON record of all the models' runs
+	var compareOut *json.Encoder
+	if *output == "json" {
+		compareOut = json.NewEncoder(stdout)
+	}
+	var approveRisk tools.Risk
+	if *approve != "" {
+		risk, err := tools.ParseRisk(*approve)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --approve: %v\n", err)
+			os.Exit(1)
+		}
+		approveRisk = risk
+	}
+	if *batchSession != "fresh" && *batchSession != "shared" {
+		fmt.Fprintf(os.Stderr, "Unknown --batch-session %q (use fresh or shared)\n", *batchSession)
+		os.Exit(1)
+	}
+	redactor, err := redact.New(redact.Config{Patterns: redactPatterns, Allow: redactAllow, NoDefaults: *noRedact})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	var contentGuard *guard.Guard
+	if !*noUntrustedBlocks {
+		contentGuard, err = guard.New(guard.Config{Screen: *injectionScreen, Patterns: injectionPatterns})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	var pol *policy.File
+	var sessionRole *policy.Role
+	if *policyFile != "" {
+		pol, err = policy.Load(*policyFile)
+		if err == nil {
+			sessionRole, err = pol.Role(*role)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *role != "" {
+		fmt.Fprintln(os.Stderr, "--role requires --policy")
+		os.Exit(1)
+	}
+	var auth webhook.Authenticator
+	if *authConfig != "" {
+		if *webhookPort <= 0 {
+			fmt.Fprintln(os.Stderr, "--auth-config requires --webhook-port")
+			os.Exit(1)
+		}
+		config, err := webhook.LoadAuthConfig(*authConfig)
+		if err == nil {
+			err = checkUserRoles(config, pol)
+		}
+		if err == nil {
+			auth, err = webhook.NewAuthenticator(context.Background(), config)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	var triggers *trigger.Config
+	if *triggersFile != "" {
+		triggers, err = trigger.Load(*triggersFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if triggers.Reports == "" {
+			if cacheDir, err := os.UserCacheDir(); err == nil {
+				triggers.Reports = filepath.Join(cacheDir, "langchain-agent", "triage")
+			}
+		}
+		for _, t := range triggers.Triggers {
+			if t.Source == "webhook" && *webhookPort <= 0 {
+				fmt.Fprintf(os.Stderr, "Trigger %s receives webhooks: it requires --webhook-port\n", t.Name)
+				os.Exit(1)
+			}
+		}
+	}
+
+	// Set default model based on provider
+	if *model == "" {
+		*model = defaultModel(*provider)
+	}
+
+	// Costs are tracked for hosted models with a known or given price
+	var modelPrice *llm.Price
+	if *price != "" {
+		p, err := llm.ParsePrice(*price)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		modelPrice = &p
+	} else if p, ok := llm.PriceOf(*model); ok && *provider != "ollama" {
+		modelPrice = &p
+	}
+	if (*maxCost > 0 || *maxRunCost > 0) && modelPrice == nil {
+		fmt.Fprintf(os.Stderr, "--max-cost and --max-run-cost need a price, and none is known for model %s: set --price\n", *model)
+		os.Exit(1)
+	}
+
+	filter := newToolFilter(enableTools, disableTools)
+	if subcommand == "doctor" {
+		config := doctorConfig{
+			Backend:        *provider,
+			APIURL:         *apiURL,
+			Model:          *model,
+			OllamaURL:      *ollamaURL,
+			Wiki:           len(wikiSpecs) > 0 || len(sourceSpecs) > 0 || *confluenceURL != "",
+			EmbedProvider:  *embedProvider,
+			EmbedModel:     *embedModel,
+			VisionProvider: *visionProvider,
+			VisionModel:    *visionModel,
+			VisionURL:      *visionURL,
+			NoImages:       *noImages,
+			SummaryModel:   *summaryModel,
+			QdrantURL:      *qdrantURL,
+			Qdrant: rag.QdrantOptions{
+				APIKey:             os.Getenv("QDRANT_API_KEY"),
+				CAFile:             *qdrantCA,
+				InsecureSkipVerify: *qdrantInsecure,
+				GRPCAddr:           *qdrantGRPC,
+			},
+			SSH: filter.allows("ssh"),
+		}
+		if filter.allows("prometheus") {
+			config.Prometheus = *prometheusURL
+		}
+		if config.EmbedModel == "" && (config.EmbedProvider == "" || config.EmbedProvider == "ollama") {
+			config.EmbedModel = rag.DefaultConfig().EmbedModel
+		}
+		if config.VisionModel == "" && (config.VisionProvider == "" || config.VisionProvider == "ollama") {
+			config.VisionModel = rag.DefaultConfig().VisionModel
+		}
+		if len(sourceSpecs) > 0 || *confluenceURL != "" {
+			config.Collections = append(config.Collections, rag.DefaultConfig().CollectionName)
+		}
+		for _, spec := range wikiSpecs {
+			collection := rag.DefaultConfig().CollectionName
+			if label, _ := parseWikiSpec(spec); label != "" {
+				collection += "_" + label
+			}
+			config.Collections = append(config.Collections, collection)
+		}
+		if len(config.Collections) == 0 {
+			config.Collections = []string{rag.DefaultConfig().CollectionName}
+		}
+		for i, spec := range mcpSpecs {
+			if name, _ := parseMCPSpec(spec, i); filter.allows(name) {
+				config.MCPSpecs = append(config.MCPSpecs, spec)
+			}
+		}
+		config.PluginsDir = *pluginsDir
+		if config.PluginsDir == "" {
+			config.PluginsDir = defaultPluginsDir()
+		}
+		if runDoctor(context.Background(), os.Stdout, config) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Wiki sources. Each gets its own collection and tool;
+	// --confluence-url and the --wiki-source exports share the default
+	// (unlabeled) one.
+	var wikiSources []rag.IndexerConfig
+	var wikiLabels []string
+	seen := map[string]bool{}
+	addWikiSource := func(label string, config rag.IndexerConfig) {
+		if seen[label] {
+			if label == "" {
+				fmt.Fprintln(os.Stderr, "Only one unlabeled wiki source is allowed (use --wiki label:path for the others)")
+			} else {
+				fmt.Fprintf(os.Stderr, "Duplicate wiki label %q\n", label)
+			}
+			os.Exit(1)
+		}
+		seen[label] = true
+		if label != "" {
+			config.CollectionName += "_" + label
+		}
+		wikiSources = append(wikiSources, config)
+		wikiLabels = append(wikiLabels, label)
+	}
+
+	baseConfig := rag.DefaultConfig()
+	baseConfig.Format = *wikiFormat
+	baseConfig.ChunkTokens = *chunkTokens
+	baseConfig.TableFormat = *tableFormat
+	baseConfig.MaxFileSize = int64(*maxPageMB) << 20
+	if *keepBoilerplate {
+		baseConfig.SkipSelectors, baseConfig.SkipPatterns = nil, nil
+	}
+	baseConfig.SkipSelectors = append(slices.Clone(baseConfig.SkipSelectors), skipSelectors...)
+	baseConfig.SkipPatterns = append(slices.Clone(baseConfig.SkipPatterns), skipText...)
+	baseConfig.ReportFile = *indexReport
+	baseConfig.LoadReportFile = *loadReport
+	baseConfig.FreshIndex = *freshIndex
+	baseConfig.OCR = *ocr
+	baseConfig.SkipImages = *noImages
+	baseConfig.MaxPageImages = *maxPageImages
+	baseConfig.MinImageWidth = *minImagePx
+	baseConfig.MinImageHeight = *minImagePx
+	baseConfig.MinImageBytes = int64(*minImageKB) << 10
+	baseConfig.SummaryModel = *summaryModel
+	baseConfig.SummaryURL = *ollamaURL
+	baseConfig.VisionProvider = *visionProvider
+	if *visionModel != "" || *visionProvider != "ollama" {
+		baseConfig.VisionModel = *visionModel
+	}
+	baseConfig.VisionURL = *visionURL
+	baseConfig.VisionAPIKey = os.Getenv("OPENAI_API_KEY")
+	baseConfig.VisionWorkers = *visionWorkers
+	baseConfig.VisionProfiles = *visionProfiles
+	baseConfig.DedupThreshold = *dedupThreshold
+	baseConfig.EmbedProvider = *embedProvider
+	if *embedModel != "" || *embedProvider != "ollama" {
+		baseConfig.EmbedModel = *embedModel
+	}
+	baseConfig.EmbedURL = *embedURL
+	baseConfig.EmbedAPIKey = os.Getenv("OPENAI_API_KEY")
+	baseConfig.QdrantURL = *qdrantURL
+	baseConfig.Qdrant = rag.QdrantOptions{
+		APIKey:             os.Getenv("QDRANT_API_KEY"),
+		CAFile:             *qdrantCA,
+		InsecureSkipVerify: *qdrantInsecure,
+		GRPCAddr:           *qdrantGRPC,
+		Collection: rag.QdrantCollectionConfig{
+			HNSWM:           *qdrantHNSWM,
+			HNSWEfConstruct: *qdrantEfConstruct,
+			OnDiskVectors:   *qdrantOnDisk,
+			OnDiskPayload:   *qdrantOnDisk,
+			Quantization:    *qdrantQuantization,
+		},
+	}
+	baseConfig.MilvusURL = *milvusURL
+	baseConfig.MilvusToken = os.Getenv("MILVUS_TOKEN")
+	baseConfig.WeaviateURL = *weaviateURL
+	baseConfig.WeaviateAPIKey = os.Getenv("WEAVIATE_API_KEY")
+	var confluence *rag.ConfluenceAPIConfig
+	if *confluenceURL != "" {
+		confluence = &rag.ConfluenceAPIConfig{
+			BaseURL:     *confluenceURL,
+			User:        os.Getenv("CONFLUENCE_USER"),
+			Token:       os.Getenv("CONFLUENCE_TOKEN"),
+			Spaces:      confluenceSpaces,
+			CQL:         *confluenceCQL,
+			TableFormat: *tableFormat,
+		}
+		if *confluenceDelta {
+			cacheDir, err := os.UserCacheDir()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to locate cache dir for --confluence-delta: %v\n", err)
+				os.Exit(1)
+			}
+			confluence.StateFile = filepath.Join(cacheDir, "langchain-agent", "confluence-sync.json")
+		}
+	}
+	switch {
+	case len(sourceSpecs) > 0:
+		// One collection for every --wiki-source, plus Confluence if given
+		config := baseConfig
+		for _, spec := range sourceSpecs {
+			src, err := parseSourceSpec(spec)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			config.Sources = append(config.Sources, src)
+		}
+		if confluence != nil {
+			if *confluenceDelta {
+				fmt.Fprintln(os.Stderr, "--confluence-delta can't be combined with --wiki-source")
+				os.Exit(1)
+			}
+			config.Sources = append(config.Sources, rag.IndexSource{Name: "confluence", Confluence: confluence})
+		}
+		addWikiSource("", config)
+	case confluence != nil:
+		config := baseConfig
+		config.Confluence = confluence
+		addWikiSource("", config)
+	}
+	for _, spec := range wikiSpecs {
+		label, path := parseWikiSpec(spec)
+		config := baseConfig
+		config.WikiPath = path
+		addWikiSource(label, config)
+	}
+
+	switch *wikiExpansion {
+	case tools.ExpandNone, tools.ExpandMultiQuery, tools.ExpandHyDE:
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown --wiki-query-expansion %q (use none, multi-query or hyde)\n", *wikiExpansion)
+		os.Exit(1)
+	}
+
+	// reembed copies each wiki collection into one embedded with
+	// --embed-model and switches the wiki over to it, without loading the
+	// wiki or describing its diagrams again
+	if subcommand == "reembed" {
+		if len(wikiSources) == 0 || *embedModel == "" && *embedProvider == "ollama" {
+			fmt.Fprintln(os.Stderr, "Usage: langchain-agent reembed --embed-model <model> [--embed-provider p] <the agent's --wiki, --wiki-source or --confluence-url flags>")
+			os.Exit(2)
+		}
+		if runReembed(context.Background(), os.Stdout, wikiSources) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Printf("LangChain Agent (provider: %s, model: %s)\n", *provider, *model)
+
+	// Initialize tools
+	registry := tools.NewRegistry()
+	// Built-in tools declare their category, read-only calls and risk
+	register := func(t tools.Tool) string {
+		name, err := registry.Register(t, tools.Meta{})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to register tool: %v\n", err)
+			os.Exit(1)
+		}
+		return name
+	}
+	workspace, err := tools.NewWorkspace(*workspaceDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer workspace.Close()
+	workspace.MaxAge, workspace.MaxBytes = *workspaceMaxAge, int64(*workspaceMaxMB)<<20
+	if removed, err := workspace.Prune(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	} else if len(removed) > 0 {
+		fmt.Printf("Workspace: removed %d old files\n", len(removed))
+	}
+	// Remote tools fail fast while what they talk to is down
+	limited := func(t tools.Tool, keyParam string) tools.Tool {
+		return tools.WithLimits(t, tools.Limits{
+			MaxConcurrent: *toolConcurrency,
+			Failures:      *breakerFailures,
+			Cooldown:      *breakerCooldown,
+			KeyParam:      keyParam,
+		})
+	}
+	sshTool := &tools.SSHTool{KeepConnections: *daemon, RawOutput: *rawOutput}
+	if creds != nil {
+		sshTool.Password, sshTool.Passphrase = storedSSHPassword(creds), storedSSHPassphrase(creds)
+	}
+	defer sshTool.Close()
+	if filter.allows(sshTool.Name()) {
+		register(limited(sshTool, "host"))
+	}
+	if shell := (&tools.ShellTool{Workspace: workspace, RawOutput: *rawOutput}); filter.allows(shell.Name()) {
+		register(shell)
+		// Names small models reach for when they mean the shell tool
+		for _, alias := range []string{"bash", "sh", "run_command"} {
+			registry.Alias(alias, shell.Name())
+		}
+	}
+	if t := (&tools.WorkspaceTool{Workspace: workspace}); filter.allows(t.Name()) {
+		register(t)
+	}
+
+	// MCP tools (only when --mcp is provided)
+	var mcpTools []*tools.MCPTool
+	for i, spec := range mcpSpecs {
+		name, target := parseMCPSpec(spec, i)
+		if !filter.allows(name) {
+			continue
+		}
+		ctx := context.Background()
+		var mcpTool *tools.MCPTool
+		var err error
+
+		if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+			mcpTool, err = tools.NewMCPToolFromURL(ctx, name, target)
+		} else {
+			parts := strings.Fields(target)
+			if len(parts) == 0 {
+				fmt.Fprintf(os.Stderr, "Invalid --mcp command: %s\n", spec)
+				os.Exit(1)
+			}
+			mcpTool, err = tools.NewMCPTool(ctx, name, parts[0], parts[1:])
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to connect to MCP server %q: %v\n", name, err)
+			os.Exit(1)
+		}
+		defer mcpTool.Close()
+		mcpTool.Workspace = workspace
+		mcpTool.Timeout = *mcpTimeout
+		register(limited(mcpTool, ""))
+		mcpTools = append(mcpTools, mcpTool)
+		fmt.Printf("MCP server %q connected (%d tools discovered)\n", name, mcpTool.ToolCount())
+	}
+
+	// Prometheus tool (only when --prometheus is provided)
+	if t := (&tools.PrometheusTool{URL: *prometheusURL, Token: os.Getenv("PROMETHEUS_TOKEN")}); *prometheusURL != "" && filter.allows(t.Name()) {
+		register(limited(t, ""))
+		fmt.Printf("Prometheus tool enabled (%s)\n", *prometheusURL)
+	}
+
+	// Edge sensor tools (only when --edge is provided)
+	if *edgeHost != "" {
+		if t := tools.NewEdgeTempTool(*edgeHost); filter.allows(t.Name()) {
+			register(limited(t, ""))
+		}
+		if t := tools.NewEdgeGPIOTool(*edgeHost); filter.allows(t.Name()) {
+			register(limited(t, ""))
+		}
+		fmt.Printf("Edge sensor tools enabled (target: %s)\n", *edgeHost)
+	}
+
+	// Index each wiki source and set up its tool
+	var wikiTools []*tools.WikiTool
+	var wikiIndexers []*rag.Indexer
+	for i, config := range wikiSources {
+		label := wikiLabels[i]
+		indexer, err := rag.NewIndexer(config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create indexer: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Index the wiki content
+		ctx := context.Background()
+		source := config.WikiPath
+		switch {
+		case len(config.Sources) > 0:
+			names := make([]string, len(config.Sources))
+			for i, src := range config.Sources {
+				names[i] = src.Name
+			}
+			source = strings.Join(names, ", ")
+		case config.Confluence != nil:
+			source = config.Confluence.BaseURL
+		}
+		fmt.Printf("Indexing wiki from: %s (collection: %s)\n", source, config.CollectionName)
+		if err := indexer.Index(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to index wiki: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Add wiki tool (auto-RAG can still search it when the tool is disabled)
+		wikiTool := tools.NewNamedWikiTool(label, indexer.GetEmbeddings(), indexer.GetStore())
+		wikiTool.OnStatusChange(func(msg string) { fmt.Printf("\n[Wiki] %s\n", msg) })
+		if *wikiFallback {
+			wikiTool.SetFallback(func(ctx context.Context) (rag.Store, error) {
+				store, err := indexer.KeywordIndex(ctx)
+				if err != nil {
+					return nil, err
+				}
+				return store, nil
+			})
+		}
+		allowed := filter.allows(wikiTool.Name())
+		if allowed {
+			register(wikiTool)
+		}
+		wikiTools = append(wikiTools, wikiTool)
+		wikiIndexers = append(wikiIndexers, indexer)
+		if !*indexOnly && allowed {
+			fmt.Printf("Wiki tool %q enabled.\n", wikiTool.Name())
+		}
+	}
+
+	// Plugin tools: executables speaking JSON over stdio (see tools.PluginTool),
+	// registered after the built-in tools so those keep their names.
+	// A broken plugin is reported and skipped rather than stopping the agent.
+	if *pluginsDir == "" {
+		*pluginsDir = defaultPluginsDir()
+	}
+	plugins, err := tools.LoadPlugins(context.Background(), *pluginsDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	for _, p := range plugins {
+		if !filter.allows(p.Name()) {
+			continue
+		}
+		// A plugin named like a built-in tool becomes plugin_<name>
+		name, err := registry.Register(limited(p, ""), tools.Meta{Namespace: "plugin"})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: plugin %s: %v\n", filepath.Base(p.Path), err)
+			continue
+		}
+		fmt.Printf("Plugin tool %q loaded (%s)\n", name, p.Path)
+	}
+
+	if unknown := filter.unknown(); len(unknown) > 0 {
+		fmt.Fprintf(os.Stderr, "Unknown tool in --enable-tools/--disable-tools: %s\n", strings.Join(unknown, ", "))
+		os.Exit(1)
+	}
+
+	if *indexOnly && len(wikiSources) > 0 {
+		fmt.Println("Indexing complete. Exiting.")
+		return
+	}
+
+	if *watch || *reindexInterval > 0 {
+		opts := rag.WatchOptions{Interval: *reindexInterval}
+		if *watch {
+			opts.Poll = 2 * time.Second
+		}
+		for _, indexer := range wikiIndexers {
+			go indexer.Watch(context.Background(), opts)
+		}
+		if len(wikiIndexers) > 0 {
+			fmt.Println("Wiki index sync enabled.")
+		}
+	}
+
+	defaults, err := agent.ParseDefaults(strings.Join(contextDefaults, ","))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --context: %v\n", err)
+		os.Exit(1)
+	}
+	var prefs agent.Preferences
+	for _, p := range []struct{ key, value string }{{"language", *language}, {"verbosity", *verbosity}, {"units", *units}, {"date_format", *dateFormat}} {
+		if err := prefs.Set(p.key, p.value); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --%s: %v\n", strings.ReplaceAll(p.key, "_", "-"), err)
+			os.Exit(1)
+		}
+	}
+	stall := *stallAfter
+	if stall == 0 {
+		stall = -1 // never; 0 is the default in agent.Nudges
+	}
+	agentConfig := agent.Config{
+		Model:         *model,
+		MaxIter:       *maxIter,
+		Registry:      registry,
+		Redactor:      redactor,
+		Guard:         contentGuard,
+		PipeThreshold: *pipeThreshold,
+		Evidence:      *evidence,
+		Price:         modelPrice,
+		MaxCost:       *maxCost,
+		MaxRunCost:    *maxRunCost,
+		Defaults:      defaults,
+		Preferences:   prefs,
+		Nudges:        agent.Nudges{Invalid: *nudge, StallAfter: stall, Backoff: *nudgeBackoff},
+		TextToolCalls: *textTools,
+		Memory:        agent.Memory{Budget: *historyTokens, Tokenizer: rag.ApproxTokenizer{}},
+		PromptBudget:  agent.PromptBudget{Tokens: *promptTokens, Tokenizer: rag.ApproxTokenizer{}},
+	}
+	if sessionRole != nil {
+		agentConfig.Policy = sessionRole
+		fmt.Printf("Policy role %q applies to tool calls.\n", sessionRole.Name)
+	}
+	if *readOnly {
+		var next policy.Checker
+		if sessionRole != nil {
+			next = sessionRole
+		}
+		agentConfig.Policy = policy.ReadOnly(next)
+		fmt.Println("Read-only mode: tool calls that may change state are blocked.")
+	}
+	// Runs nobody watches (triggers, webhook users) can't approve anything
+	approvals := &approver{out: os.Stdout}
+	unattended := agentConfig.Policy
+	if approveRisk != "" {
+		var next policy.Checker
+		if agentConfig.Policy != nil {
+			next = agentConfig.Policy
+		}
+		agentConfig.Policy = policy.Approve(next, approveRisk, approvals.approve)
+		unattended = policy.Approve(next, approveRisk, turnDown)
+		fmt.Printf("Tool calls of %s risk or higher need approval.\n", approveRisk)
+	}
+
+	// finishEval prints an eval report and exits with 1 if a task failed
+	finishEval := func(report *eval.Report) {
+		err := writeEval(os.Stdout, report, baseline, *evalReport)
+		if err == nil {
+			err = writeEvalOutput(stdout, report, *output)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if report.Passed < len(report.Tasks) {
+			os.Exit(1)
+		}
+	}
+
+	// eval --replay gets the LLM responses from the cassette, so no client
+	// is needed
+	if suite != nil && *replayFile != "" {
+		cassette, err := replay.Load(*replayFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		report, err := eval.Replay(context.Background(), suite, cassette, agentConfig)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to replay %s: %v\n", *replayFile, err)
+			os.Exit(1)
+		}
+		report.Model = "replay of " + *replayFile
+		finishEval(report)
+		return
+	}
+
+	fmt.Println("Type /help for commands")
+	fmt.Println("---")
+
+	// Create LLM client based on provider
+	newClient := func(model string) (llm.ChatClient, error) {
+		return llm.NewClientFor(*provider, llm.Options{
+			Model:      model,
+			BaseURL:    providerURL(*provider, *ollamaURL, *apiURL),
+			APIVersion: *azureAPIVersion,
+		})
+	}
+	client, err := newClient(*model)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if c, ok := client.(io.Closer); ok {
+		defer c.Close()
+	}
+	summaryClient := client // session titles stay out of --record cassettes
+
+	// --record wraps the client; recordRun files each finished run in the
+	// cassette, which is rewritten after every run
+	var recorder *replay.Recorder
+	recordRun := func(*agent.RunResult, error) {}
+	if *recordFile != "" {
+		recorder = replay.NewRecorder(client)
+		recorder.Redactor = redactor
+		client = recorder
+		recordRun = func(result *agent.RunResult, err error) {
+			recorder.AddRun(result, err)
+			if err := recorder.Cassette(registry).Save(*recordFile); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+		fmt.Printf("Recording runs to %s\n", *recordFile)
+	}
+
+	if *mcpDescLimit > 0 && len(mcpTools) > 0 {
+		shortenMCPDescriptions(mcpTools, summaryClient, *mcpDescLimit)
+	}
+
+	for _, wt := range wikiTools {
+		if err := wt.SetQueryExpansion(*wikiExpansion, client); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to configure %s: %v\n", wt.Name(), err)
+			os.Exit(1)
+		}
+	}
+
+	// Tool stats are flushed to their file every 30s and on exit, adding
+	// to the calls of other langchain-agent processes
+	if !*noToolStats {
+		if *toolStatsFile == "" {
+			if cacheDir, err := os.UserCacheDir(); err == nil {
+				*toolStatsFile = filepath.Join(cacheDir, "langchain-agent", "tool-stats.json")
+			}
+		}
+		stats, err := tools.LoadStats(*toolStatsFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: tool stats not kept: %v\n", err)
+			stats = tools.NewStats()
+		}
+		agentConfig.ToolStats = stats
+		flushStats := func() {
+			if err := stats.Flush(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+		defer flushStats()
+		go func() {
+			for range time.Tick(30 * time.Second) {
+				flushStats()
+			}
+		}()
+	}
+
+	// Create agent
+	agentConfig.Client = client
+	if *autoRAG > 0 && len(wikiTools) > 0 {
+		agentConfig.Retriever = tools.WikiRetriever{Tools: wikiTools, Limit: *autoRAG}
+		if recorder != nil {
+			agentConfig.Retriever = recorder.Retriever(agentConfig.Retriever)
+		}
+		fmt.Printf("Auto-RAG enabled: top %d wiki results are added to each query.\n", *autoRAG)
+	}
+	// The agent's progress is printed on stdout. Webhook users run at the
+	// same time as the REPL: their progress goes through one Mux, labeled
+	// with the user, so lines stay whole.
+	var outMux *agent.Mux
+	agentConfig.Hooks = progressHooks(os.Stdout)
+	if *webhookPort > 0 {
+		outMux = agent.NewMux(os.Stdout)
+		agentConfig.Hooks = progressHooks(outMux.Stream(""))
+	}
+	ag, err := agent.New(agentConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create agent: %v\n", err)
+		os.Exit(1)
+	}
+	reportPromptSize(ag.SystemPromptSize())
+
+	// --compare gives each model an agent of its own; REPL commands such as
+	// /show act on the first
+	var contenders []contender
+	if *compare != "" {
+		models, err := parseCompare(*compare)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		for _, m := range models {
+			c, err := newClient(m)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			if closer, ok := c.(io.Closer); ok {
+				defer closer.Close()
+			}
+			cfg := agentConfig
+			cfg.Model, cfg.Client, cfg.Price = m, c, nil
+			cfg.Hooks = progressHooks(os.Stdout)
+			if m == *model {
+				cfg.Price = modelPrice
+			} else if p, ok := llm.PriceOf(m); ok && *provider != "ollama" {
+				cfg.Price = &p
+			}
+			a, err := agent.New(cfg)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to create agent: %v\n", err)
+				os.Exit(1)
+			}
+			contenders = append(contenders, contender{model: m, agent: a})
+		}
+		ag = contenders[0].agent
+		fmt.Printf("Comparing %s: each query runs through every model in turn.\n", strings.Join(models, ", "))
+	}
+
+	// --triggers runs on an agent of its own, so triggered runs don't mix
+	// with the REPL's conversation; their progress only goes to the reports.
+	// Their prompts hold the events' payloads, so they get only read-only
+	// tools, and read-only mode on top.
+	var triggerRunner *trigger.Runner
+	var webhookOpts []webhook.Option
+	if outMux != nil {
+		webhookOpts = append(webhookOpts, webhook.WithLog(outMux.Stream("")))
+	}
+	if triggers != nil {
+		cfg := agentConfig
+		cfg.Hooks = agent.Hooks{}
+		cfg.Registry = readOnlyRegistry(registry)
+		cfg.Policy = unattended
+		if !*readOnly {
+			var next policy.Checker
+			if unattended != nil {
+				next = unattended
+			}
+			cfg.Policy = policy.ReadOnly(next)
+		}
+		triggerAgent, err := agent.New(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create trigger agent: %v\n", err)
+			os.Exit(1)
+		}
+		triggerRunner = trigger.NewRunner(triggers, func(ctx context.Context, prompt string) (*agent.RunResult, error) {
+			triggerAgent.ClearHistory()
+			return triggerAgent.RunDetailed(ctx, prompt)
+		})
+		webhookOpts = append(webhookOpts, webhook.WithRoute("/webhook/", triggerRunner.WebhookHandler()))
+	}
+	startTriggers := func(ctx context.Context) {
+		go func() {
+			if err := triggerRunner.Run(ctx); err != nil {
+				fmt.Fprintf(os.Stderr, "Triggers stopped: %v\n", err)
+			}
+		}()
+		var names []string
+		for _, t := range triggers.Triggers {
+			names = append(names, fmt.Sprintf("%s (%s)", t.Name, t.Source))
+		}
+		fmt.Printf("Triggers: %s; reports in %s\n", strings.Join(names, ", "), triggers.Reports)
+	}
+
+	// With --auth-config every webhook user gets their own agent; without it
+	// the webhook shares the local agent and its unrestricted tools
+	startWebhook := func(ctx context.Context) {
+		go func() {
+			var err error
+			if auth != nil {
+				cfg := agentConfig
+				cfg.Policy = unattended
+				users := webhook.NewMultiUser(auth, userAgents(cfg, pol, wikiTools, *autoRAG, *readOnly, approveRisk, outMux))
+				users.SetToolStats(agentConfig.ToolStats)
+				err = webhook.StartMultiUser(ctx, *webhookPort, users, webhookOpts...)
+			} else {
+				err = webhook.Start(ctx, *webhookPort, ag, webhookOpts...)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Webhook server error: %v\n", err)
+			}
+		}()
+		if auth != nil {
+			fmt.Printf("Webhook listener on :%d (POST /webhook, GET /ws, GET /health, GET /metrics; authenticated per --auth-config)\n", *webhookPort)
+		} else {
+			fmt.Printf("Webhook listener on :%d (POST /webhook, GET /ws, GET /health, GET /metrics)\n", *webhookPort)
+			fmt.Fprintln(os.Stderr, "Warning: the webhook has no --auth-config; anyone who can reach the port can use every tool.")
+		}
+	}
+
+	if suite != nil {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		report, err := eval.Run(ctx, ag, suite, os.Stdout, recordRun)
+		stop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Eval stopped: %v\n", err)
+			os.Exit(1)
+		}
+		report.Model = *model
+		fmt.Println()
+		finishEval(report)
+		return
+	}
+
+	if *batchFile != "" {
+		queries, err := readBatch(*batchFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		out := resultOut
+		if *batchOutput != "" {
+			f, err := os.Create(*batchOutput)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to create batch output: %v\n", err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			if resultFormat == nil {
+				resultFormat = format.JSON{}
+			}
+			out = format.NewEncoder(f, resultFormat)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		failed, err := runBatch(ctx, ag, queries, *batchSession == "shared", out, recordRun)
+		stop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Batch stopped: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\nBatch complete: %d queries, %d failed.\n", len(queries), failed)
+		if failed > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *daemon {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if *webhookPort > 0 {
+			startWebhook(ctx)
+		}
+		if triggers != nil {
+			startTriggers(ctx)
+		}
+		fmt.Printf("Daemon listening on %s (query it with: langchain-agent ask \"...\")\n", *socketPath)
+		if err := serveDaemon(ctx, *socketPath, ag); err != nil {
+			fmt.Fprintf(os.Stderr, "Daemon error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Daemon stopped.")
+		return
+	}
+
+	// REPL loop
+	if *historyFile == "" {
+		if cacheDir, err := os.UserCacheDir(); err == nil {
+			*historyFile = filepath.Join(cacheDir, "langchain-agent", "history")
+			os.MkdirAll(filepath.Dir(*historyFile), 0755)
+		}
+	}
+	if *promptsDir == "" {
+		if configDir, err := os.UserConfigDir(); err == nil {
+			*promptsDir = filepath.Join(configDir, "langchain-agent", "prompts")
+		}
+	}
+	if *sessionsDir == "" {
+		if cacheDir, err := os.UserCacheDir(); err == nil {
+			*sessionsDir = filepath.Join(cacheDir, "langchain-agent", "sessions")
+		}
+	}
+	var sessions *sessionLog
+	if !*noSessions && *sessionsDir != "" {
+		var summarize func(context.Context, agent.Summary, []llm.Message) (agent.Summary, error)
+		if !*noSessionSummary {
+			summarize = func(ctx context.Context, prev agent.Summary, exchange []llm.Message) (agent.Summary, error) {
+				return agent.Summarize(ctx, summaryClient, prev, exchange)
+			}
+		}
+		store, where, err := openSessionStore(*sessionStore, *sessionsDir)
+		if err != nil {
+			fmt.Printf("Warning: sessions not saved: %v\n", err)
+		} else {
+			if closer, ok := store.(io.Closer); ok {
+				defer closer.Close()
+			}
+			sessions = newSessionLog(store, where, summarize)
+			defer sessions.Close()
+			sessions.setPreferences(prefs)
+		}
+	}
+	recordSession := func() {
+		if sessions != nil {
+			if err := sessions.record(ag.History()); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+	}
+	// /context and /prefs apply to every model of a --compare session
+	replAgents := []*agent.Agent{ag}
+	if contenders != nil {
+		replAgents = nil
+		for _, c := range contenders {
+			replAgents = append(replAgents, c.agent)
+		}
+	}
+	lines := newLineReader(*historyFile)
+	color := useColor(*noColor)
+	ctx := context.Background()
+
+	// Ctrl+C cancels the run in flight; a second one exits
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	interrupts := newInterrupter(sigs, os.Exit)
+
+	// Webhook listener (only when --webhook-port is provided)
+	if *webhookPort > 0 {
+		startWebhook(ctx)
+	}
+	if triggers != nil {
+		startTriggers(ctx)
+	}
+
+	var readErr error
+	for {
+		line, err := lines.ReadLine()
+		if err != nil {
+			readErr = err
+			break
+		}
+
+		input := strings.TrimSpace(line)
+		if input == "" {
+			continue
+		}
+
+		if args, ok := cutCommand(input, "/tools"); ok {
+			toolsCommand(os.Stdout, ag, strings.Fields(args))
+			continue
+		}
+		if args, ok := cutCommand(input, "/context"); ok {
+			contextCommand(os.Stdout, replAgents, args)
+			continue
+		}
+		if args, ok := cutCommand(input, "/prefs"); ok {
+			if prefsCommand(os.Stdout, replAgents, args) && sessions != nil {
+				if err := sessions.setPreferences(ag.Preferences()); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				}
+			}
+			continue
+		}
+		if title, ok := cutCommand(input, "/save"); ok {
+			saveCommand(os.Stdout, sessions, ag, title)
+			continue
+		}
+		if ref, ok := cutCommand(input, "/load"); ok {
+			loadCommand(os.Stdout, sessions, replAgents, ref)
+			continue
+		}
+		if path, ok := cutCommand(input, "/export"); ok {
+			exportCommand(os.Stdout, ag, path)
+			continue
+		}
+
+		if args, ok := cutCommand(input, "/run"); ok {
+			prompt, ok := runCommand(os.Stdout, *promptsDir, args)
+			if !ok {
+				continue
+			}
+			fmt.Printf("[Prompt] %s\n", prompt)
+			input = prompt
+		}
+
+		// /retry and /edit replace the last exchange with a new run; a
+		// /retry model is only used for that run
+		restoreClient := func() {}
+		if model, ok := cutCommand(input, "/retry"); ok {
+			if model != "" && contenders != nil {
+				fmt.Println("/retry <model> can't be used with --compare.")
+				continue
+			}
+			query, restore, ok := retryCommand(os.Stdout, ag, model, newClient)
+			if !ok {
+				continue
+			}
+			fmt.Printf("[Retry] %s\n", query)
+			input, restoreClient = query, restore
+			undoContenders(contenders)
+		} else if input == "/edit" {
+			query, ok := editCommand(os.Stdout, ag, editText)
+			if !ok {
+				continue
+			}
+			fmt.Printf("[Edited] %s\n", query)
+			input = query
+			undoContenders(contenders)
+		}
+
+		switch strings.ToLower(input) {
+		case "quit", "exit", "/exit":
+			fmt.Println("Goodbye!")
+			return
+		case "clear", "/clear":
+			ag.ClearHistory()
+			for _, c := range contenders {
+				c.agent.ClearHistory()
+			}
+			if sessions != nil {
+				sessions.reset()
+			}
+			fmt.Println("History cleared.")
+			continue
+		case "/sessions":
+			sessionsCommand(os.Stdout, sessions)
+			continue
+		case "/history":
+			historyCommand(os.Stdout, ag)
+			continue
+		case "/show":
+			showCommand(os.Stdout, ag)
+			continue
+		case "/stats":
+			statsCommand(os.Stdout, ag.Totals(), modelPrice, *maxCost)
+			continue
+		case "/stats tools":
+			toolStatsCommand(os.Stdout, ag.ToolStats(), ag.Tools())
+			continue
+		case "/wiki stats":
+			if len(wikiIndexers) == 0 {
+				fmt.Println("No wiki sources configured.")
+			}
+			for i, indexer := range wikiIndexers {
+				if err := wikiTools[i].Degraded(); err != nil {
+					fmt.Printf("[%s] degraded: vector index unreachable (%v)\n", wikiTools[i].Name(), err)
+					continue
+				}
+				stats, err := indexer.Stats(ctx)
+				if err != nil {
+					fmt.Printf("[%s] %v\n", wikiTools[i].Name(), err)
+					continue
+				}
+				fmt.Printf("[%s]\n%s\n", wikiTools[i].Name(), stats)
+			}
+			continue
+		case "/help":
+			fmt.Println("Commands:")
+			fmt.Println("  /help        - Show this help message")
+			fmt.Println("  /clear       - Clear conversation history")
+			fmt.Println("  /tools       - List tools with their parameters (/tools <name> for one)")
+			fmt.Println("  /tools enable|disable <name> - Offer a tool to the LLM or hide it")
+			fmt.Println("  /history     - Show the conversation history")
+			fmt.Println("  /sessions    - List saved conversations with their titles and summaries")
+			fmt.Println("  /save [title] - Save the conversation now, optionally under your own title")
+			fmt.Println("  /load <n|id> - Continue a saved conversation (number or ID from /sessions)")
+			fmt.Println("  /show        - Show every step of the last run (tool calls, outputs, timings)")
+			fmt.Println("  /stats       - Show the queries, tokens and cost of this session")
+			fmt.Println("  /stats tools - Show each tool's calls, error rate and latency across sessions")
+			fmt.Println("  /prefs       - Show answer preferences (/prefs set language=German verbosity=brief units=metric, unset <key>, clear)")
+			fmt.Println("  /context     - Show session defaults (/context set namespace=prod host=web-1, unset <key>, clear)")
+			fmt.Println("  /export <file.md> - Save the conversation as a Markdown transcript")
+			fmt.Println("  /retry [model] - Re-run the last query (optionally with another model)")
+			fmt.Println("  /edit        - Amend the last query in $EDITOR and re-run it")
+			fmt.Println("  /run         - List prompt templates (/run <name> key=value ... runs one)")
+			fmt.Println("  /wiki stats  - Show index statistics for each wiki source")
+			fmt.Println("  /exit        - Exit the agent")
+			fmt.Println("")
+			fmt.Println("Anything else is sent to the LLM as a prompt. Up/Down recall earlier")
+			fmt.Println("lines and Ctrl-R searches them.")
+			continue
+		}
+
+		runCtx, cancel := interrupts.run(ctx)
+		stopAsking := approvals.asking(lines)
+		done := func() {
+			stopAsking()
+			cancel()
+		}
+		if contenders != nil {
+			compareQuery(runCtx, os.Stdout, contenders, input, color, compareOut, recordRun)
+			done()
+			recordSession()
+			continue
+		}
+		if resultOut != nil {
+			result, err := ag.RunDetailed(runCtx, input)
+			done()
+			restoreClient()
+			recordRun(result, err)
+			recordSession()
+			out := runOutput{RunResult: result}
+			if err != nil {
+				out.Error = err.Error()
+			}
+			if err := resultOut.Encode(out); err != nil {
+				fmt.Fprintf(os.Stderr, "Write error: %v\n", err)
+			}
+			continue
+		}
+
+		result, err := ag.Run(runCtx, input)
+		done()
+		restoreClient()
+		recordRun(ag.LastRun(), err)
+		recordSession()
+		if errors.Is(err, context.Canceled) {
+			fmt.Println("\n[Cancelled]")
+			continue
+		}
+		if err != nil {
+			fmt.Printf("\n[Error] %v\n", err)
+			continue
+		}
+
+		fmt.Printf("\n[Answer]\n%s\n", renderMarkdown(result, color))
+		if ev := ag.LastRun().Evidence; ev != nil {
+			fmt.Println()
+			ev.WriteText(os.Stdout)
+		}
+	}
+
+	if readErr != io.EOF {
+		fmt.Fprintf(os.Stderr, "Read error: %v\n", readErr)
+	}
+
+	// If a webhook listener or triggers are running, keep the process alive
+	// after REPL EOF (e.g. when launched as a daemon with stdin closed).
+	if *webhookPort > 0 || triggers != nil {
+		fmt.Println("REPL closed; webhook listener or triggers still running. Ctrl+C to exit.")
+		select {}
+	}
+}
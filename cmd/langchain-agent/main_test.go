@@ -0,0 +1,55 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rathore/langchain-agent/tools"
+)
+
+func TestToolFilter(t *testing.T) {
+	f := newToolFilter(nil, []string{"shell, ssh"})
+	for name, want := range map[string]bool{"shell": false, "ssh": false, "wiki": true, "mcp": true} {
+		if got := f.allows(name); got != want {
+			t.Errorf("disable shell,ssh: allows(%q) = %v, want %v", name, got, want)
+		}
+	}
+	if unknown := f.unknown(); len(unknown) != 0 {
+		t.Errorf("unknown() = %v, want none", unknown)
+	}
+
+	f = newToolFilter([]string{"wiki,mcp_fs", "shell"}, []string{"shell", "edge_gpio"})
+	for name, want := range map[string]bool{"wiki": true, "mcp_fs": true, "shell": false, "ssh": false, "wiki_ops": false} {
+		if got := f.allows(name); got != want {
+			t.Errorf("enable wiki,mcp_fs,shell / disable shell: allows(%q) = %v, want %v", name, got, want)
+		}
+	}
+	if got := strings.Join(f.unknown(), ","); got != "edge_gpio" {
+		t.Errorf("unknown() = %q, want edge_gpio (never registered)", got)
+	}
+}
+
+func TestReadOnlyRegistry(t *testing.T) {
+	registry := tools.NewRegistry()
+	for _, tt := range []struct {
+		tool tools.Tool
+		meta tools.Meta
+	}{
+		{&tools.ShellTool{}, tools.Meta{Category: tools.CategoryLocal}},
+		{&tools.SSHTool{}, tools.Meta{Category: tools.CategoryRemote}},
+		{&tools.PrometheusTool{}, tools.Meta{}},
+		{&tools.WorkspaceTool{}, tools.Meta{}},
+	} {
+		if _, err := registry.Register(tt.tool, tt.meta); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var names []string
+	for _, tool := range readOnlyRegistry(registry).Tools() {
+		names = append(names, tool.Name())
+	}
+	if !slices.Equal(names, []string{"prometheus"}) {
+		t.Errorf("readOnlyRegistry tools = %v, want only prometheus", names)
+	}
+}
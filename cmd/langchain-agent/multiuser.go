@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/rathore/langchain-agent/agent"
+	"github.com/rathore/langchain-agent/policy"
+	"github.com/rathore/langchain-agent/tools"
+	"github.com/rathore/langchain-agent/webhook"
+)
+
+// userAgents returns the agent factory for an --auth-config server. Each
+// user's agent shares config's model and client but only gets the tools of
+// config.Registry the user is allowed, checks its tool calls against the
+// user's role in pol (config.Policy for users without one), within
+// --read-only if set, with --approve calls denied, and auto-RAG only searches the wikis they may search.
+// With out set, the agent's progress goes to a stream of it labeled with the user's name.
+func userAgents(config agent.Config, pol *policy.File, wikiTools []*tools.WikiTool, autoRAG int, readOnly bool, approveRisk tools.Risk, out *agent.Mux) func(u *webhook.User) (*agent.Agent, error) {
+	return func(u *webhook.User) (*agent.Agent, error) {
+		c := config
+		if u.Role != "" {
+			role, err := pol.Role(u.Role)
+			if err != nil {
+				return nil, fmt.Errorf("user %s: %w", u.Name, err)
+			}
+			c.Policy = role
+			if readOnly {
+				c.Policy = policy.ReadOnly(role)
+			}
+			if approveRisk != "" {
+				c.Policy = policy.Approve(c.Policy, approveRisk, turnDown)
+			}
+		}
+		if u.Language != "" {
+			c.Preferences.Language = u.Language
+		}
+		if out != nil {
+			c.Hooks = progressHooks(out.Stream(u.Name))
+		}
+		c.Registry = config.Registry.Filter(u.AllowsTool)
+		c.Retriever = nil
+		var wikis []*tools.WikiTool
+		for _, wt := range wikiTools {
+			if u.AllowsTool(wt.Name()) {
+				wikis = append(wikis, wt)
+			}
+		}
+		if autoRAG > 0 && len(wikis) > 0 {
+			c.Retriever = tools.WikiRetriever{Tools: wikis, Limit: autoRAG}
+		}
+		return agent.New(c)
+	}
+}
+
+// checkUserRoles makes sure every role the auth config names is in pol, so
+// a typo fails at startup rather than on the user's first request
+func checkUserRoles(config *webhook.AuthConfig, pol *policy.File) error {
+	users := config.Users
+	if config.Default != nil {
+		users = append(users[:len(users):len(users)], *config.Default)
+	}
+	for _, u := range users {
+		if u.Role == "" {
+			continue
+		}
+		if pol == nil {
+			return fmt.Errorf("auth config: role %q of user %q requires --policy", u.Role, u.Name)
+		}
+		if _, err := pol.Role(u.Role); err != nil {
+			return fmt.Errorf("auth config: user %q: %w", u.Name, err)
+		}
+	}
+	return nil
+}
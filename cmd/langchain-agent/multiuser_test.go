@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rathore/langchain-agent/agent"
+	"github.com/rathore/langchain-agent/llm"
+	"github.com/rathore/langchain-agent/policy"
+	"github.com/rathore/langchain-agent/tools"
+	"github.com/rathore/langchain-agent/webhook"
+)
+
+// countingEmbedder counts searches and fails them
+type countingEmbedder struct{ calls int }
+
+func (e *countingEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	e.calls++
+	return nil, errors.New("offline")
+}
+
+func (e *countingEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	return nil, errors.New("offline")
+}
+
+func TestUserAgents(t *testing.T) {
+	embedder := &countingEmbedder{}
+	wiki := tools.NewNamedWikiTool("ops", embedder, nil)
+	registry := tools.NewRegistry()
+	registry.Register(&tools.ShellTool{}, tools.Meta{Category: tools.CategoryLocal})
+	registry.Register(wiki, tools.Meta{Category: tools.CategoryKnowledge, ReadOnly: true})
+	registry.Alias("bash", "shell")
+	config := agent.Config{
+		Client: &scriptedClient{responses: []*llm.Response{
+			{Content: "a", IsFinish: true}, {Content: "b", IsFinish: true},
+		}},
+		Registry: registry,
+	}
+	newAgent := userAgents(config, nil, []*tools.WikiTool{wiki}, 3, false, "", nil)
+
+	tests := []struct {
+		user      webhook.User
+		wantTools []string
+		wantRAG   bool
+	}{
+		{webhook.User{Name: "chat-only"}, nil, false},
+		{webhook.User{Name: "wiki", Tools: []string{"wiki_ops"}}, []string{"wiki_ops"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.user.Name, func(t *testing.T) {
+			ag, err := newAgent(&tt.user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, tool := range ag.Tools() {
+				names = append(names, tool.Name())
+			}
+			if !slices.Equal(names, tt.wantTools) {
+				t.Errorf("tools = %v, want %v", names, tt.wantTools)
+			}
+			before := embedder.calls
+			if _, err := ag.Run(context.Background(), "how do I deploy?"); err != nil {
+				t.Fatal(err)
+			}
+			if searched := embedder.calls > before; searched != tt.wantRAG {
+				t.Errorf("auto-RAG searched = %v, want %v", searched, tt.wantRAG)
+			}
+		})
+	}
+	if len(registry.Tools()) != 2 {
+		t.Errorf("registry tools = %v, want them unchanged", registry.Tools())
+	}
+
+	ag, err := newAgent(&webhook.User{Name: "hans", Language: "German"})
+	if err != nil || ag.Preferences().Language != "German" {
+		t.Errorf("preferences = %+v, %v; want the user's language", ag.Preferences(), err)
+	}
+
+	// With a Mux, each user's progress is labeled with their name
+	var out strings.Builder
+	config.Client = &scriptedClient{responses: []*llm.Response{{Content: "Hi.", IsFinish: true}}}
+	ag, _ = userAgents(config, nil, nil, 0, false, "", agent.NewMux(&out))(&webhook.User{Name: "dana"})
+	if _, err := ag.Run(context.Background(), "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "dana | [Agent] Hi.\n") {
+		t.Errorf("output = %q, want it labeled dana", out.String())
+	}
+}
+
+func TestUserAgents_Roles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	os.WriteFile(path, []byte("default: operator\nroles:\n  operator: {}\n  viewer:\n    read_only: true\n"), 0644)
+	pol, err := policy.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	operator, _ := pol.Role("")
+
+	registry := tools.NewRegistry()
+	registry.Register(&tools.ShellTool{}, tools.Meta{Category: tools.CategoryLocal})
+	call := &llm.Response{ToolCalls: []llm.ToolCallParse{{Name: "shell", Params: map[string]any{"command": "true"}}}}
+	config := agent.Config{
+		Client:   &scriptedClient{responses: []*llm.Response{call, {Content: "a", IsFinish: true}, call, {Content: "b", IsFinish: true}}},
+		Registry: registry,
+		Policy:   operator,
+	}
+	newAgent := userAgents(config, pol, nil, 0, false, "", nil)
+
+	for _, tt := range []struct {
+		user       webhook.User
+		wantDenied bool
+	}{
+		{webhook.User{Name: "alice", Tools: []string{"*"}}, false},
+		{webhook.User{Name: "bob", Tools: []string{"*"}, Role: "viewer"}, true},
+	} {
+		ag, err := newAgent(&tt.user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := ag.RunDetailed(context.Background(), "run true")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if denied := strings.Contains(result.Steps[0].ToolCall.Error, "denied by policy"); denied != tt.wantDenied {
+			t.Errorf("%s: shell error = %q, want denied = %v", tt.user.Name, result.Steps[0].ToolCall.Error, tt.wantDenied)
+		}
+	}
+
+	// --read-only applies on top of each user's role
+	touch := &llm.Response{ToolCalls: []llm.ToolCallParse{{Name: "shell", Params: map[string]any{"command": "touch /tmp/x"}}}}
+	config.Client = &scriptedClient{responses: []*llm.Response{touch, {Content: "a", IsFinish: true}}}
+	ag, err := userAgents(config, pol, nil, 0, true, "", nil)(&webhook.User{Name: "carol", Tools: []string{"*"}, Role: "operator"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ag.RunDetailed(context.Background(), "touch it")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := result.Steps[0].ToolCall.Error; !strings.Contains(got, "read-only mode: touch isn't known to be read-only") {
+		t.Errorf("read-only shell error = %q", got)
+	}
+
+	auth := &webhook.AuthConfig{Users: []webhook.UserConfig{{Name: "bob", Role: "viewer"}}}
+	if err := checkUserRoles(auth, pol); err != nil {
+		t.Errorf("checkUserRoles() = %v", err)
+	}
+	if err := checkUserRoles(auth, nil); err == nil {
+		t.Error("a role without --policy should fail")
+	}
+	auth.Default = &webhook.UserConfig{Role: "admin"}
+	if err := checkUserRoles(auth, pol); err == nil || !strings.Contains(err.Error(), `unknown role "admin"`) {
+		t.Errorf("unknown default role: %v", err)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// fileOwner can't tell who owns a file here; the mode check remains
+func fileOwner(info os.FileInfo) (int, bool) {
+	return 0, false
+}
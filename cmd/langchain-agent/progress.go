@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rathore/langchain-agent/agent"
+	"github.com/rathore/langchain-agent/llm"
+)
+
+// progress prints what an agent does as it happens: the LLM's replies as
+// they stream, tool calls and their results, and the agent's notes
+type progress struct {
+	w        io.Writer
+	replying bool // an [Agent] line is open
+}
+
+// progressHooks returns hooks printing an agent's progress to w. Each
+// agent needs hooks of its own, as a reply is printed across calls.
+func progressHooks(w io.Writer) agent.Hooks {
+	p := &progress{w: w}
+	return agent.Hooks{
+		OnLLMChunk:  p.chunk,
+		OnLLMEnd:    p.llmEnd,
+		OnToolStart: p.toolStart,
+		OnToolEnd:   p.toolEnd,
+		OnNote:      p.note,
+	}
+}
+
+func (p *progress) chunk(ctx context.Context, chunk string) {
+	if !p.replying {
+		fmt.Fprint(p.w, "\n[Agent] ")
+		p.replying = true
+	}
+	fmt.Fprint(p.w, chunk)
+}
+
+// llmEnd ends the reply line; a reply that wasn't streamed, such as a
+// tool call from a client without streaming, is printed whole
+func (p *progress) llmEnd(ctx context.Context, resp *llm.Response, err error) {
+	if p.replying {
+		fmt.Fprintln(p.w)
+		p.replying = false
+	} else if err == nil && resp.Content != "" {
+		fmt.Fprintf(p.w, "\n[Agent] %s\n", resp.Content)
+	}
+}
+
+func (p *progress) toolStart(ctx context.Context, tool string, params map[string]any) {
+	fmt.Fprintf(p.w, "[Tool Call] %s: %v\n", tool, params)
+}
+
+func (p *progress) toolEnd(ctx context.Context, call agent.ToolCall) {
+	fmt.Fprintf(p.w, "[Tool Result] %s\n", truncateText(call.Result, 500))
+}
+
+func (p *progress) note(ctx context.Context, kind, text string) {
+	label := "Agent"
+	if kind != agent.NoteNative {
+		label = strings.ToUpper(kind[:1]) + kind[1:]
+	}
+	fmt.Fprintf(p.w, "[%s] %s\n", label, text)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rathore/langchain-agent/agent"
+	"github.com/rathore/langchain-agent/llm"
+	"github.com/rathore/langchain-agent/tools"
+)
+
+// streamingClient streams each scripted reply in two chunks
+type streamingClient struct{ scriptedClient }
+
+func (c *streamingClient) ChatStream(ctx context.Context, messages []llm.Message, onChunk func(string)) (*llm.Response, error) {
+	resp, err := c.Chat(ctx, messages)
+	if err == nil {
+		half := len(resp.Content) / 2
+		onChunk(resp.Content[:half])
+		onChunk(resp.Content[half:])
+	}
+	return resp, err
+}
+
+func TestProgress(t *testing.T) {
+	var out strings.Builder
+	ag, _ := agent.New(agent.Config{
+		Client: &scriptedClient{responses: []*llm.Response{
+			{Content: `{"name": "shell"}`, ToolCalls: []llm.ToolCallParse{{Name: "shell", Params: map[string]any{"command": "echo hi"}}}},
+			{Content: "Done.", IsFinish: true},
+		}},
+		Tools: []tools.Tool{&tools.ShellTool{}},
+		Hooks: progressHooks(&out),
+	})
+	if _, err := ag.Run(context.Background(), "go"); err != nil {
+		t.Fatal(err)
+	}
+	want := "\n[Agent] {\"name\": \"shell\"}\n[Tool Call] shell: map[command:echo hi]\n[Tool Result] hi\n\n\n[Agent] Done.\n"
+	if out.String() != want {
+		t.Errorf("output:\n%q\nwant:\n%q", out.String(), want)
+	}
+
+	// Streamed replies are printed as they come, on one line
+	out.Reset()
+	ag, _ = agent.New(agent.Config{
+		Client: &streamingClient{scriptedClient{responses: []*llm.Response{{Content: "not json {"}, {Content: "Done.", IsFinish: true}}}},
+		Nudges: agent.Nudges{Invalid: "Call a tool or answer."},
+		Hooks:  progressHooks(&out),
+	})
+	if _, err := ag.Run(context.Background(), "go"); err != nil {
+		t.Fatal(err)
+	}
+	want = "\n[Agent] not json {\n[Nudge] Call a tool or answer.\n\n[Agent] Done.\n"
+	if out.String() != want {
+		t.Errorf("output:\n%q\nwant:\n%q", out.String(), want)
+	}
+}
+
+func TestProgress_Mux(t *testing.T) {
+	var out strings.Builder
+	m := agent.NewMux(&out)
+	ag, _ := agent.New(agent.Config{
+		Client: &streamingClient{scriptedClient{responses: []*llm.Response{{Content: "Done.", IsFinish: true}}}},
+		Hooks:  progressHooks(m.Stream("bob")),
+	})
+	if _, err := ag.Run(context.Background(), "go"); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "\nbob | [Agent] Done.\n" {
+		t.Errorf("output = %q", out.String())
+	}
+}
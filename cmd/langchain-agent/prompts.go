@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// promptTemplate is a named, parameterized prompt from the --prompts
+// directory, e.g. triage-pod.yaml:
+//
+//	description: Find out why a pod is unhealthy
+//	params: [namespace, pod]
+//	defaults:
+//	  namespace: default
+//	prompt: |
+//	  Describe pod {{.pod}} in namespace {{.namespace}} and check its logs ...
+type promptTemplate struct {
+	Name        string            `yaml:"-"`
+	Description string            `yaml:"description"`
+	Params      []string          `yaml:"params"`
+	Defaults    map[string]string `yaml:"defaults"`
+	Prompt      string            `yaml:"prompt"`
+
+	tmpl *template.Template
+}
+
+// loadPrompts reads every *.yaml / *.yml template in dir, keyed by file name
+// without the extension. A missing directory has no templates.
+func loadPrompts(dir string) (map[string]*promptTemplate, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompts dir: %w", err)
+	}
+	prompts := map[string]*promptTemplate{}
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if e.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prompt: %w", err)
+		}
+		p := &promptTemplate{Name: strings.TrimSuffix(e.Name(), ext)}
+		if err := yaml.Unmarshal(data, p); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if strings.TrimSpace(p.Prompt) == "" {
+			return nil, fmt.Errorf("%s: prompt is required", path)
+		}
+		for name := range p.Defaults {
+			if !p.hasParam(name) {
+				return nil, fmt.Errorf("%s: default for undeclared param %q", path, name)
+			}
+		}
+		p.tmpl, err = template.New(p.Name).Option("missingkey=error").Parse(p.Prompt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		prompts[p.Name] = p
+	}
+	return prompts, nil
+}
+
+func (p *promptTemplate) hasParam(name string) bool {
+	for _, param := range p.Params {
+		if param == name {
+			return true
+		}
+	}
+	return false
+}
+
+// usage shows how to invoke the template, e.g.
+// "/run triage-pod [namespace=default] pod=..."
+func (p *promptTemplate) usage() string {
+	parts := []string{"/run", p.Name}
+	for _, param := range p.Params {
+		if def, ok := p.Defaults[param]; ok {
+			parts = append(parts, fmt.Sprintf("[%s=%s]", param, def))
+		} else {
+			parts = append(parts, param+"=...")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// render fills in the template; every param without a default is required
+func (p *promptTemplate) render(args map[string]string) (string, error) {
+	data := map[string]string{}
+	for name, value := range p.Defaults {
+		data[name] = value
+	}
+	for name, value := range args {
+		if !p.hasParam(name) {
+			return "", fmt.Errorf("unknown parameter %q (usage: %s)", name, p.usage())
+		}
+		data[name] = value
+	}
+	var missing []string
+	for _, param := range p.Params {
+		if _, ok := data[param]; !ok {
+			missing = append(missing, param)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing parameter %s (usage: %s)", strings.Join(missing, ", "), p.usage())
+	}
+	var sb strings.Builder
+	if err := p.tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt: %w", err)
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// runCommand handles "/run" (list templates) and "/run <name> key=value ...".
+// Templates are re-read on every call so edits apply without a restart. It
+// returns the rendered prompt, or false if there is nothing to run.
+func runCommand(w io.Writer, dir, args string) (string, bool) {
+	prompts, err := loadPrompts(dir)
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return "", false
+	}
+	fields, err := splitArgs(args)
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return "", false
+	}
+	if len(fields) == 0 {
+		if len(prompts) == 0 {
+			fmt.Fprintf(w, "No prompt templates in %s.\n", dir)
+			return "", false
+		}
+		names := make([]string, 0, len(prompts))
+		for name := range prompts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			p := prompts[name]
+			fmt.Fprintf(w, "%s\n  %s\n", p.usage(), firstLine(p.Description))
+		}
+		return "", false
+	}
+
+	p, ok := prompts[fields[0]]
+	if !ok {
+		fmt.Fprintf(w, "unknown prompt template: %s (/run lists them)\n", fields[0])
+		return "", false
+	}
+	values := map[string]string{}
+	for _, field := range fields[1:] {
+		name, value, ok := strings.Cut(field, "=")
+		if !ok || name == "" {
+			fmt.Fprintf(w, "expected key=value, got %q (usage: %s)\n", field, p.usage())
+			return "", false
+		}
+		values[name] = value
+	}
+	prompt, err := p.render(values)
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return "", false
+	}
+	return prompt, true
+}
+
+// splitArgs splits s on whitespace, keeping single- or double-quoted
+// sections together (quotes are removed): msg="disk full" → msg=disk full
+func splitArgs(s string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	var quote rune
+	inArg := false
+	for _, c := range s {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(c)
+			}
+		case c == '"' || c == '\'':
+			quote, inArg = c, true
+		case c == ' ' || c == '\t':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(c)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const triagePod = `description: Find out why a pod is unhealthy
+params: [namespace, pod]
+defaults:
+  namespace: default
+prompt: |
+  Describe pod {{.pod}} in namespace {{.namespace}} and summarize its recent logs.
+`
+
+func writePrompts(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadPrompts(t *testing.T) {
+	dir := writePrompts(t, map[string]string{
+		"triage-pod.yaml": triagePod,
+		"disk.yml":        "prompt: Check disk usage on {{.host}}\nparams: [host]\n",
+		"notes.txt":       "ignored",
+	})
+	prompts, err := loadPrompts(dir)
+	if err != nil {
+		t.Fatalf("loadPrompts() error = %v", err)
+	}
+	if len(prompts) != 2 || prompts["triage-pod"] == nil || prompts["disk"] == nil {
+		t.Fatalf("loadPrompts() = %v, want triage-pod and disk", prompts)
+	}
+	if got := prompts["triage-pod"].usage(); got != "/run triage-pod [namespace=default] pod=..." {
+		t.Errorf("usage() = %q", got)
+	}
+
+	if prompts, err := loadPrompts(filepath.Join(dir, "missing")); err != nil || prompts != nil {
+		t.Errorf("loadPrompts(missing dir) = %v, %v, want no templates", prompts, err)
+	}
+
+	for name, content := range map[string]string{
+		"no prompt":          "params: [a]\n",
+		"bad template":       "prompt: '{{.a'\n",
+		"undeclared default": "prompt: hi\ndefaults: {a: b}\n",
+	} {
+		dir := writePrompts(t, map[string]string{"bad.yaml": content})
+		if _, err := loadPrompts(dir); err == nil {
+			t.Errorf("loadPrompts(%s) error = nil", name)
+		}
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	dir := writePrompts(t, map[string]string{"triage-pod.yaml": triagePod})
+
+	var sb strings.Builder
+	prompt, ok := runCommand(&sb, dir, " triage-pod ns=x")
+	if ok || !strings.Contains(sb.String(), `unknown parameter "ns"`) {
+		t.Errorf("runCommand(unknown param) = %q, %v, output %q", prompt, ok, sb.String())
+	}
+
+	sb.Reset()
+	if _, ok := runCommand(&sb, dir, " triage-pod"); ok || !strings.Contains(sb.String(), "missing parameter pod") {
+		t.Errorf("runCommand(missing param) output = %q", sb.String())
+	}
+
+	sb.Reset()
+	prompt, ok = runCommand(&sb, dir, ` triage-pod namespace=prod pod="api 1"`)
+	if want := "Describe pod api 1 in namespace prod and summarize its recent logs."; !ok || prompt != want {
+		t.Errorf("runCommand() = %q, %v, want %q (output %q)", prompt, ok, want, sb.String())
+	}
+
+	sb.Reset()
+	prompt, ok = runCommand(&sb, dir, " triage-pod pod=api-1")
+	if !ok || !strings.Contains(prompt, "namespace default") {
+		t.Errorf("runCommand(default namespace) = %q, %v", prompt, ok)
+	}
+
+	sb.Reset()
+	if _, ok := runCommand(&sb, dir, ""); ok || !strings.Contains(sb.String(), "Find out why a pod is unhealthy") {
+		t.Errorf("runCommand(list) output = %q", sb.String())
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	got, err := splitArgs(` triage  pod=api-1 msg="disk full" note='it''s' empty=""`)
+	want := []string{"triage", "pod=api-1", "msg=disk full", "note=its", "empty="}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("splitArgs() = %q, %v, want %q", got, err, want)
+	}
+	if _, err := splitArgs(`msg="open`); err == nil {
+		t.Error("splitArgs(unterminated quote) error = nil")
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/rathore/langchain-agent/rag"
+)
+
+// runReembed re-embeds the collection of each wiki source with the model
+// of its config (`langchain-agent reembed`) and reports to w. Returns the
+// number of collections that failed; those keep their old collection.
+func runReembed(ctx context.Context, w io.Writer, configs []rag.IndexerConfig) int {
+	failed := 0
+	for _, config := range configs {
+		fmt.Fprintf(w, "Re-embedding %s with %s...\n", config.CollectionName, embedModelName(config))
+		indexer, err := rag.NewIndexer(config)
+		if err != nil {
+			fmt.Fprintf(w, "Failed to create indexer: %v\n", err)
+			failed++
+			continue
+		}
+		result, err := indexer.Reembed(ctx)
+		if err != nil {
+			fmt.Fprintf(w, "Failed to re-embed %s: %v\n", config.CollectionName, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(w, "%s now uses %s: %d documents, %d dims (%s is kept; delete it once nothing searches it)\n",
+			config.CollectionName, result.To, result.Docs, result.VectorSize, result.From)
+	}
+	return failed
+}
+
+// embedModelName describes the embedding model of config for messages
+func embedModelName(config rag.IndexerConfig) string {
+	switch {
+	case config.EmbedModel == "":
+		return config.EmbedProvider + " default model"
+	case config.EmbedProvider == "":
+		return config.EmbedModel
+	}
+	return config.EmbedProvider + "/" + config.EmbedModel
+}
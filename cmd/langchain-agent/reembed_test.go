@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rathore/langchain-agent/bench"
+	"github.com/rathore/langchain-agent/rag"
+)
+
+func TestRunReembed(t *testing.T) {
+	dir := t.TempDir()
+	wiki := filepath.Join(dir, "wiki")
+	os.Mkdir(wiki, 0755)
+	page := `<html><head><title>Backups</title></head><body><p>Backups run nightly at 02:00 to the NAS.</p></body></html>`
+	if err := os.WriteFile(filepath.Join(wiki, "backups.html"), []byte(page), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := rag.DefaultConfig()
+	config.WikiPath = wiki
+	config.StorePath = filepath.Join(dir, "vectors")
+	config.StatsFile = filepath.Join(dir, "stats.json")
+	config.CheckpointFile = filepath.Join(dir, "checkpoint.json")
+	config.ActiveFile = filepath.Join(dir, "active.json")
+	config.Embedder = bench.HashEmbedder{Dims: 8}
+	indexer, err := rag.NewIndexer(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := indexer.Index(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	config.EmbedProvider = "ollama"
+	config.EmbedModel = "bge-m3"
+	config.Embedder = bench.HashEmbedder{Dims: 16}
+	var sb strings.Builder
+	if failed := runReembed(context.Background(), &sb, []rag.IndexerConfig{config}); failed != 0 {
+		t.Fatalf("runReembed() failed %d:\n%s", failed, sb.String())
+	}
+	want := "confluence_wiki now uses confluence_wiki_bge_m3: 1 documents, 16 dims (confluence_wiki is kept"
+	if out := sb.String(); !strings.Contains(out, "Re-embedding confluence_wiki with ollama/bge-m3...") || !strings.Contains(out, want) {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+
+	// Running it again has nothing to do
+	sb.Reset()
+	if failed := runReembed(context.Background(), &sb, []rag.IndexerConfig{config}); failed != 1 || !strings.Contains(sb.String(), "already holds ollama/bge-m3 vectors") {
+		t.Errorf("second runReembed() = %d: %q", failed, sb.String())
+	}
+}
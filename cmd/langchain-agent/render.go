@@ -0,0 +1,286 @@
+package main
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// ANSI styles used when rendering answers
+const (
+	ansiReset     = "\x1b[0m"
+	ansiBold      = "\x1b[1m"
+	ansiItalic    = "\x1b[3m"
+	ansiUnderline = "\x1b[4m"
+	ansiGreen     = "\x1b[32m"
+	ansiYellow    = "\x1b[33m"
+	ansiBlue      = "\x1b[34m"
+	ansiMagenta   = "\x1b[35m"
+	ansiCyan      = "\x1b[36m"
+	ansiGray      = "\x1b[90m"
+)
+
+// useColor reports whether stdout should get ANSI colors: it must be a
+// terminal, and neither --no-color nor $NO_COLOR (https://no-color.org) may
+// be set
+func useColor(noColor bool) bool {
+	if noColor || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+var (
+	headingRe   = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	listRe      = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(.*)$`)
+	ruleRe      = regexp.MustCompile(`^\s*(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	tableSepRe  = regexp.MustCompile(`^\|?(\s*:?-+:?\s*\|)*\s*:?-+:?\s*\|?$`)
+	boldRe      = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	italicRe    = regexp.MustCompile(`(^|[^*\w])\*([^*\s][^*]*)\*|(^|[^_\w])_([^_\s][^_]*)_`)
+	linkRe      = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	ansiCodeRe  = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+	codeTokenRe = regexp.MustCompile(`"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'|` + "`[^`]*`" + `|\b\d+(?:\.\d+)?\b|\b[A-Za-z_]\w*\b`)
+)
+
+// keywords highlighted in fenced code blocks, by language
+var codeKeywords = map[string][]string{
+	"go":         {"break", "case", "chan", "const", "continue", "default", "defer", "else", "fallthrough", "for", "func", "go", "goto", "if", "import", "interface", "map", "package", "range", "return", "select", "struct", "switch", "type", "var", "nil", "true", "false"},
+	"python":     {"and", "as", "assert", "async", "await", "break", "class", "continue", "def", "del", "elif", "else", "except", "finally", "for", "from", "global", "if", "import", "in", "is", "lambda", "not", "or", "pass", "raise", "return", "try", "while", "with", "yield", "None", "True", "False"},
+	"sh":         {"if", "then", "else", "elif", "fi", "for", "while", "until", "do", "done", "case", "esac", "in", "function", "return", "export", "local", "sudo", "echo"},
+	"javascript": {"async", "await", "break", "case", "catch", "class", "const", "continue", "default", "else", "export", "for", "function", "if", "import", "let", "new", "return", "switch", "this", "throw", "try", "var", "while", "null", "undefined", "true", "false"},
+	"yaml":       {"true", "false", "null", "yes", "no"},
+	"json":       {"true", "false", "null"},
+	"sql":        {"SELECT", "FROM", "WHERE", "JOIN", "LEFT", "RIGHT", "INNER", "ON", "GROUP", "BY", "ORDER", "LIMIT", "INSERT", "INTO", "VALUES", "UPDATE", "SET", "DELETE", "CREATE", "TABLE", "AND", "OR", "NOT", "NULL", "AS"},
+}
+
+// codeLangAliases maps fence labels to codeKeywords keys
+var codeLangAliases = map[string]string{
+	"golang": "go", "py": "python", "bash": "sh", "shell": "sh", "zsh": "sh", "console": "sh",
+	"js": "javascript", "ts": "javascript", "typescript": "javascript", "yml": "yaml",
+}
+
+// renderMarkdown renders Markdown for the terminal: headings, lists,
+// quotes, tables, inline styles and fenced code blocks with syntax
+// highlighting. Without color the text is returned unchanged.
+func renderMarkdown(md string, color bool) string {
+	if !color {
+		return md
+	}
+	lines := strings.Split(md, "\n")
+	var out []string
+	inCode, lang := false, ""
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			if inCode {
+				inCode = false
+				out = append(out, ansiGray+"└─"+ansiReset)
+				continue
+			}
+			inCode, lang = true, strings.ToLower(strings.TrimSpace(trimmed[3:]))
+			out = append(out, ansiGray+"┌─ "+lang+ansiReset)
+			continue
+		}
+		if inCode {
+			out = append(out, ansiGray+"│ "+ansiReset+highlightCode(line, lang))
+			continue
+		}
+
+		// A table is a run of |-rows whose second row is a separator
+		if strings.HasPrefix(trimmed, "|") && i+1 < len(lines) && tableSepRe.MatchString(strings.TrimSpace(lines[i+1])) {
+			end := i + 2
+			for end < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[end]), "|") {
+				end++
+			}
+			out = append(out, renderTable(lines[i], lines[i+2:end])...)
+			i = end - 1
+			continue
+		}
+
+		switch {
+		case headingRe.MatchString(line):
+			m := headingRe.FindStringSubmatch(line)
+			style := ansiBold
+			switch len(m[1]) {
+			case 1:
+				style = ansiBold + ansiUnderline + ansiMagenta
+			case 2:
+				style = ansiBold + ansiCyan
+			}
+			out = append(out, style+m[2]+ansiReset)
+		case ruleRe.MatchString(line):
+			out = append(out, ansiGray+strings.Repeat("─", 40)+ansiReset)
+		case strings.HasPrefix(trimmed, ">"):
+			quote := strings.TrimSpace(strings.TrimPrefix(trimmed, ">"))
+			out = append(out, ansiGray+"│ "+ansiReset+ansiItalic+renderInline(quote)+ansiReset)
+		case listRe.MatchString(line):
+			m := listRe.FindStringSubmatch(line)
+			marker := m[2]
+			if strings.ContainsAny(marker, "-*+") {
+				marker = "•"
+			}
+			out = append(out, m[1]+ansiCyan+marker+ansiReset+" "+renderInline(m[3]))
+		default:
+			out = append(out, renderInline(line))
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// renderInline styles `code`, **bold**, *italic* and [links](url) in a
+// line of text, leaving code spans untouched
+func renderInline(s string) string {
+	parts := strings.Split(s, "`")
+	if len(parts)%2 == 0 { // unbalanced backtick: don't treat it as code
+		return styleText(s)
+	}
+	for i := range parts {
+		if i%2 == 1 {
+			parts[i] = ansiYellow + parts[i] + ansiReset
+		} else {
+			parts[i] = styleText(parts[i])
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// styleText applies the non-code inline styles
+func styleText(s string) string {
+	s = linkRe.ReplaceAllString(s, ansiUnderline+ansiBlue+"$1"+ansiReset+ansiGray+" ($2)"+ansiReset)
+	s = boldRe.ReplaceAllString(s, ansiBold+"$1$2"+ansiReset)
+	return italicRe.ReplaceAllString(s, "$1$3"+ansiItalic+"$2$4"+ansiReset)
+}
+
+// renderTable lays out a Markdown table with aligned columns
+func renderTable(header string, rows []string) []string {
+	cells := func(row string) []string {
+		row = strings.TrimSpace(row)
+		row = strings.TrimPrefix(row, "|")
+		row = strings.TrimSuffix(row, "|")
+		parts := strings.Split(row, "|")
+		for i, p := range parts {
+			parts[i] = renderInline(strings.TrimSpace(p))
+		}
+		return parts
+	}
+	table := [][]string{cells(header)}
+	for _, row := range rows {
+		table = append(table, cells(row))
+	}
+
+	var widths []int
+	for _, row := range table {
+		for i, cell := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], visibleLen(cell))
+		}
+	}
+
+	format := func(row []string, style string) string {
+		var sb strings.Builder
+		for i, w := range widths {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			if i > 0 {
+				sb.WriteString(ansiGray + " │ " + ansiReset)
+			}
+			sb.WriteString(style + cell + ansiReset + strings.Repeat(" ", w-visibleLen(cell)))
+		}
+		return strings.TrimRight(sb.String(), " ")
+	}
+
+	out := []string{format(table[0], ansiBold)}
+	var sep []string
+	for _, w := range widths {
+		sep = append(sep, strings.Repeat("─", w))
+	}
+	out = append(out, ansiGray+strings.Join(sep, "─┼─")+ansiReset)
+	for _, row := range table[1:] {
+		out = append(out, format(row, ""))
+	}
+	return out
+}
+
+// highlightCode colors one line of a fenced code block: comments, strings,
+// numbers and the language's keywords
+func highlightCode(line, lang string) string {
+	if alias, ok := codeLangAliases[lang]; ok {
+		lang = alias
+	}
+	keywords := map[string]bool{}
+	for _, k := range codeKeywords[lang] {
+		keywords[k] = true
+	}
+
+	// Split off a trailing comment
+	code, comment := line, ""
+	switch lang {
+	case "sh", "python", "yaml":
+		if i := commentStart(line, "#"); i >= 0 {
+			code, comment = line[:i], line[i:]
+		}
+	case "go", "javascript":
+		if i := commentStart(line, "//"); i >= 0 {
+			code, comment = line[:i], line[i:]
+		}
+	case "sql":
+		if i := commentStart(line, "--"); i >= 0 {
+			code, comment = line[:i], line[i:]
+		}
+	}
+
+	code = codeTokenRe.ReplaceAllStringFunc(code, func(tok string) string {
+		switch c := tok[0]; {
+		case c == '"' || c == '\'' || c == '`':
+			return ansiGreen + tok + ansiReset
+		case c >= '0' && c <= '9':
+			return ansiCyan + tok + ansiReset
+		case keywords[tok] || (lang == "sql" && keywords[strings.ToUpper(tok)]):
+			return ansiMagenta + tok + ansiReset
+		}
+		return tok
+	})
+	if comment != "" {
+		code += ansiGray + comment + ansiReset
+	}
+	return code
+}
+
+// commentStart finds a comment marker outside of quotes, or returns -1
+func commentStart(line, marker string) int {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case strings.HasPrefix(line[i:], marker):
+			// "#" only starts a shell comment at a word boundary (not $#, a#b)
+			if marker == "#" && i > 0 && line[i-1] != ' ' && line[i-1] != '\t' {
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// visibleLen is the display width of s without ANSI codes
+func visibleLen(s string) int {
+	return utf8.RuneCountInString(ansiCodeRe.ReplaceAllString(s, ""))
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown_NoColor(t *testing.T) {
+	md := "# Disk usage\n\n**/var** is `92%` full."
+	if got := renderMarkdown(md, false); got != md {
+		t.Errorf("renderMarkdown(color=false) = %q, want the Markdown unchanged", got)
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	md := strings.Join([]string{
+		"## Disk usage",
+		"- **/var** is `92%` full, see [runbook](https://wiki/disk)",
+		"> rotate logs first",
+		"| Mount | Used |",
+		"|---|---:|",
+		"| /var | 92% |",
+		"| /home | 8% |",
+		"```bash",
+		`sudo du -sh /var/log # biggest dirs`,
+		"```",
+	}, "\n")
+	got := renderMarkdown(md, true)
+	lines := strings.Split(got, "\n")
+	plain := strings.Split(ansiCodeRe.ReplaceAllString(got, ""), "\n")
+
+	if lines[0] != ansiBold+ansiCyan+"Disk usage"+ansiReset {
+		t.Errorf("heading = %q", lines[0])
+	}
+	for _, want := range []string{ansiBold + "/var" + ansiReset, ansiYellow + "92%" + ansiReset, ansiUnderline + ansiBlue + "runbook"} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("list item %q missing %q", lines[1], want)
+		}
+	}
+	if plain[1] != "• /var is 92% full, see runbook (https://wiki/disk)" {
+		t.Errorf("list item text = %q", plain[1])
+	}
+	if plain[2] != "│ rotate logs first" {
+		t.Errorf("quote = %q", plain[2])
+	}
+
+	// Table columns are aligned
+	wantTable := []string{"Mount │ Used", "──────┼─────", "/var  │ 92%", "/home │ 8%"}
+	for i, want := range wantTable {
+		if plain[3+i] != want {
+			t.Errorf("table line %d = %q, want %q", i, plain[3+i], want)
+		}
+	}
+
+	// Code: keyword and comment highlighted, markers replaced by a frame
+	if plain[7] != "┌─ bash" || plain[9] != "└─" {
+		t.Errorf("code frame = %q / %q", plain[7], plain[9])
+	}
+	if !strings.Contains(lines[8], ansiMagenta+"sudo"+ansiReset) || !strings.Contains(lines[8], ansiGray+"# biggest dirs") {
+		t.Errorf("code line = %q, want keyword and comment colored", lines[8])
+	}
+}
+
+func TestHighlightCode_Strings(t *testing.T) {
+	got := highlightCode(`fmt.Println("# not a comment") // done`, "go")
+	if !strings.Contains(got, ansiGreen+`"# not a comment"`+ansiReset) || !strings.Contains(got, ansiGray+"// done") {
+		t.Errorf("highlightCode() = %q", got)
+	}
+	if got := highlightCode("echo $#", "bash"); strings.Contains(got, ansiGray) {
+		t.Errorf("$# should not start a comment: %q", got)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// maxHistory caps the REPL history kept in memory and on disk
+const maxHistory = 1000
+
+// keyCtrlR starts (or continues) a reverse history search
+const keyCtrlR = 'R' - '@'
+
+// lineReader reads REPL input lines; io.EOF ends the session
+type lineReader interface {
+	ReadLine() (string, error)
+}
+
+// newLineReader returns a line editor (arrow-key history, Ctrl-R search,
+// history saved to historyFile) when stdin is a terminal, and a plain line
+// scanner otherwise, e.g. when queries are piped in
+func newLineReader(historyFile string) lineReader {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return &scannerReader{scanner: bufio.NewScanner(os.Stdin)}
+	}
+	history := &fileHistory{}
+	if historyFile != "" {
+		if err := history.load(historyFile); err != nil {
+			fmt.Printf("Warning: REPL history not saved: %v\n", err)
+		}
+	}
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, "> ")
+	t.History = history
+	search := &historySearch{history: history}
+	t.AutoCompleteCallback = search.key
+	return &terminalReader{fd: fd, term: t}
+}
+
+// scannerReader reads lines from non-interactive input
+type scannerReader struct {
+	scanner *bufio.Scanner
+}
+
+func (r *scannerReader) ReadLine() (string, error) {
+	fmt.Print("\n> ")
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.scanner.Text(), nil
+}
+
+// terminalReader reads lines with the x/term line editor. The terminal is
+// only in raw mode while a line is being read, so agent output printed
+// between prompts is unaffected.
+type terminalReader struct {
+	fd   int
+	term *term.Terminal
+}
+
+func (r *terminalReader) ReadLine() (string, error) {
+	fmt.Println()
+	state, err := term.MakeRaw(r.fd)
+	if err != nil {
+		return "", fmt.Errorf("failed to set terminal raw mode: %w", err)
+	}
+	defer term.Restore(r.fd, state)
+	return r.term.ReadLine()
+}
+
+// fileHistory is a term.History that appends each entry to a file, so
+// history survives restarts
+type fileHistory struct {
+	entries []string // oldest first
+	file    *os.File // nil when history isn't persisted
+}
+
+// load reads previous entries from path and opens it for appending
+func (h *fileHistory) load(path string) error {
+	if data, err := os.ReadFile(path); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if line != "" {
+				h.entries = append(h.entries, line)
+			}
+		}
+		h.trim()
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+	h.file = f
+	return nil
+}
+
+// Add records a line; blank lines and repeats of the last entry are skipped
+func (h *fileHistory) Add(entry string) {
+	entry = strings.TrimSpace(entry)
+	if entry == "" || strings.Contains(entry, "\n") {
+		return
+	}
+	if n := len(h.entries); n > 0 && h.entries[n-1] == entry {
+		return
+	}
+	h.entries = append(h.entries, entry)
+	h.trim()
+	if h.file != nil {
+		fmt.Fprintln(h.file, entry)
+	}
+}
+
+func (h *fileHistory) Len() int { return len(h.entries) }
+
+// At returns an entry; 0 is the most recent
+func (h *fileHistory) At(idx int) string {
+	return h.entries[len(h.entries)-1-idx]
+}
+
+func (h *fileHistory) trim() {
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+	}
+}
+
+// historySearch implements Ctrl-R: the first press replaces the line with
+// the most recent history entry containing it, and each further press moves
+// to the next older match
+type historySearch struct {
+	history term.History
+	query   string // text being searched for
+	match   string // line shown by the last search (to spot a new search)
+	next    int    // history index to resume from
+}
+
+// key is a term.Terminal AutoCompleteCallback
+func (s *historySearch) key(line string, pos int, key rune) (string, int, bool) {
+	if key != keyCtrlR {
+		return "", 0, false
+	}
+	if line != s.match || s.match == "" {
+		s.query, s.next = line, 0
+	}
+	for i := s.next; i < s.history.Len(); i++ {
+		entry := s.history.At(i)
+		if strings.Contains(entry, s.query) && entry != line {
+			s.match, s.next = entry, i+1
+			return entry, len(entry), true
+		}
+	}
+	return line, pos, true // no (further) match; keep the line
+}
+
+// interrupter turns Ctrl+C into cancellation of the current run. A second
+// Ctrl+C before the run has stopped, or one while no run is in flight,
+// exits. (At a terminal prompt the line editor reads Ctrl+C itself and ends
+// the session.)
+type interrupter struct {
+	exit func(code int)
+
+	mu     sync.Mutex
+	cancel context.CancelFunc // cancels the run in flight; nil if none
+}
+
+// newInterrupter handles the signals arriving on sigs until it is closed
+func newInterrupter(sigs <-chan os.Signal, exit func(code int)) *interrupter {
+	i := &interrupter{exit: exit}
+	go func() {
+		for range sigs {
+			i.mu.Lock()
+			cancel := i.cancel
+			i.cancel = nil
+			i.mu.Unlock()
+			if cancel == nil {
+				fmt.Fprintln(os.Stderr, "\nInterrupted.")
+				i.exit(130)
+				continue
+			}
+			fmt.Println("\n[Interrupted] Cancelling the run (Ctrl+C again to exit)...")
+			cancel()
+		}
+	}()
+	return i
+}
+
+// run returns a context for one agent run that Ctrl+C cancels, and a
+// function to call once the run has returned
+func (i *interrupter) run(ctx context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	i.mu.Lock()
+	i.cancel = cancel
+	i.mu.Unlock()
+	return ctx, func() {
+		i.mu.Lock()
+		i.cancel = nil
+		i.mu.Unlock()
+		cancel()
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	os.WriteFile(path, []byte("uptime on web1\ndf -h on web1\n"), 0600)
+
+	h := &fileHistory{}
+	if err := h.load(path); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	h.Add("check nginx logs")
+	h.Add("check nginx logs") // repeat: skipped
+	h.Add("   ")              // blank: skipped
+
+	if h.Len() != 3 || h.At(0) != "check nginx logs" || h.At(2) != "uptime on web1" {
+		t.Errorf("history = %q, want the file's entries then the new one", h.entries)
+	}
+
+	// A new session sees everything added before
+	h2 := &fileHistory{}
+	if err := h2.load(path); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if h2.Len() != 3 || h2.At(0) != "check nginx logs" {
+		t.Errorf("reloaded history = %q", h2.entries)
+	}
+}
+
+func TestHistorySearch(t *testing.T) {
+	h := &fileHistory{}
+	for _, line := range []string{"uptime on web1", "df -h on db1", "df -h on web1", "/wiki stats"} {
+		h.Add(line)
+	}
+	s := &historySearch{history: h}
+
+	line, _, ok := s.key("web1", 4, keyCtrlR)
+	if !ok || line != "df -h on web1" {
+		t.Fatalf("first Ctrl-R = %q, want the most recent match", line)
+	}
+	line, pos, _ := s.key(line, len(line), keyCtrlR)
+	if line != "uptime on web1" || pos != len(line) {
+		t.Errorf("second Ctrl-R = %q (pos %d), want the next older match", line, pos)
+	}
+	line, _, _ = s.key(line, len(line), keyCtrlR)
+	if line != "uptime on web1" {
+		t.Errorf("Ctrl-R past the oldest match = %q, want the line unchanged", line)
+	}
+
+	// Editing the line starts a new search
+	if line, _, _ = s.key("stats", 5, keyCtrlR); line != "/wiki stats" {
+		t.Errorf("new search = %q, want /wiki stats", line)
+	}
+	if _, _, ok := s.key("x", 1, 'x'); ok {
+		t.Error("other keys should be left to the terminal")
+	}
+}
+
+func TestInterrupter(t *testing.T) {
+	sigs := make(chan os.Signal)
+	exited := make(chan int, 1)
+	i := newInterrupter(sigs, func(code int) { exited <- code })
+
+	// First Ctrl+C cancels the run; the second exits
+	ctx, done := i.run(context.Background())
+	sigs <- os.Interrupt
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("first Ctrl+C should cancel the run")
+	}
+	sigs <- os.Interrupt
+	if code := <-exited; code != 130 {
+		t.Errorf("exit code = %d, want 130", code)
+	}
+	done()
+
+	// Once a run has finished, Ctrl+C exits rather than cancelling a stale run
+	ctx, done = i.run(context.Background())
+	done()
+	sigs <- os.Interrupt
+	<-exited
+	if ctx.Err() == nil {
+		t.Error("done() should release the run context")
+	}
+}
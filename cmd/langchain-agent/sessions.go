@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rathore/langchain-agent/agent"
+	"github.com/rathore/langchain-agent/llm"
+)
+
+// maxListedSessions is how many sessions /sessions lists, newest first
+const maxListedSessions = 20
+
+// summaryTimeout bounds each title and summary update
+const summaryTimeout = 2 * time.Minute
+
+// sessionDSNEnv overrides the sqlite store's file with a DSN, which may
+// hold a secret such as an SQLCipher key, so it comes from the environment
+// or the credential store rather than a flag
+const sessionDSNEnv = "LANGCHAIN_AGENT_SESSION_DSN"
+
+// openSessionStore opens the --session-store of kind in dir, returning it
+// with the path /sessions shows
+func openSessionStore(kind, dir string) (agent.SessionStore, string, error) {
+	switch kind {
+	case "file":
+		store, err := agent.NewFileStore(dir)
+		return store, dir, err
+	case "sqlite":
+		if dsn := os.Getenv(sessionDSNEnv); dsn != "" {
+			store, err := agent.OpenSQLiteStore(dsn)
+			return store, "$" + sessionDSNEnv, err
+		}
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, "", fmt.Errorf("failed to create sessions dir: %w", err)
+		}
+		path := filepath.Join(dir, "sessions.db")
+		store, err := agent.OpenSQLiteStore(path)
+		return store, path, err
+	}
+	return nil, "", fmt.Errorf("unknown --session-store %q (use file or sqlite)", kind)
+}
+
+// sessionLog saves the REPL conversation to store after every exchange, and
+// keeps its title and summary up to date in the background so the REPL
+// doesn't wait for them. /clear starts a new session.
+type sessionLog struct {
+	store agent.SessionStore
+	where string // the store's file or dir, for /sessions
+	// summarize updates a summary with an exchange; nil titles sessions
+	// by their first query
+	summarize func(ctx context.Context, prev agent.Summary, exchange []llm.Message) (agent.Summary, error)
+
+	mu      sync.Mutex
+	current agent.Session
+	queue   chan summaryJob
+	done    chan struct{}
+}
+
+// summaryJob is an exchange to summarize into session id
+type summaryJob struct {
+	id       string
+	exchange []llm.Message
+}
+
+func newSessionLog(store agent.SessionStore, where string, summarize func(context.Context, agent.Summary, []llm.Message) (agent.Summary, error)) *sessionLog {
+	l := &sessionLog{store: store, where: where, summarize: summarize, queue: make(chan summaryJob, 16), done: make(chan struct{})}
+	l.reset()
+	go l.summarizeLoop()
+	return l
+}
+
+// reset starts a new session, with the preferences of the last; it is
+// saved with its first exchange
+func (l *sessionLog) reset() {
+	now := time.Now()
+	l.mu.Lock()
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	l.current = agent.Session{ID: now.Format("20060102-150405-") + hex.EncodeToString(suffix), Started: now, Preferences: l.current.Preferences}
+	l.mu.Unlock()
+}
+
+// setPreferences records the answer preferences of the current session
+func (l *sessionLog) setPreferences(p agent.Preferences) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.current.Preferences = nil
+	if !p.IsZero() {
+		l.current.Preferences = &p
+	}
+	if len(l.current.History) == 0 {
+		return nil // saved with the first exchange
+	}
+	return l.store.Save(l.current)
+}
+
+// record saves history as the current session's conversation and queues
+// its last exchange, if answered, for the summary
+func (l *sessionLog) record(history []llm.Message) error {
+	if len(history) == 0 {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.current.History = history
+	l.current.Updated = time.Now()
+	if l.current.Title == "" {
+		l.current.Title = agent.FallbackTitle(history[0].Content)
+	}
+	if err := l.store.Save(l.current); err != nil {
+		return err
+	}
+	if l.summarize != nil && len(history) >= 2 && history[len(history)-1].Role == "assistant" {
+		exchange := history[len(history)-2:]
+		select {
+		case l.queue <- summaryJob{id: l.current.ID, exchange: exchange}:
+		default: // the summary is far behind; the next exchange updates it
+		}
+	}
+	return nil
+}
+
+// name titles the current session (/save), keeping the title from
+// summaries, and saves it
+func (l *sessionLog) name(title string, history []llm.Message) (agent.Session, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(history) == 0 {
+		return l.current, errors.New("nothing to save yet")
+	}
+	l.current.History = history
+	l.current.Updated = time.Now()
+	if title != "" {
+		l.current.Title, l.current.Named = agent.FallbackTitle(title), true
+	} else if l.current.Title == "" {
+		l.current.Title = agent.FallbackTitle(history[0].Content)
+	}
+	return l.current, l.store.Save(l.current)
+}
+
+// resume makes the session that ref names the current one (/load): its
+// number in /sessions, its ID or the start of its ID
+func (l *sessionLog) resume(ref string) (agent.Session, error) {
+	sessions, err := l.store.List()
+	if err != nil {
+		return agent.Session{}, err
+	}
+	var found []agent.Session
+	if n, err := strconv.Atoi(ref); err == nil && n >= 1 && n <= len(sessions) {
+		found = sessions[n-1 : n]
+	} else {
+		for _, s := range sessions {
+			if s.ID == ref {
+				found = []agent.Session{s}
+				break
+			}
+			if strings.HasPrefix(s.ID, ref) {
+				found = append(found, s)
+			}
+		}
+	}
+	switch len(found) {
+	case 0:
+		return agent.Session{}, fmt.Errorf("%w: %s (see /sessions)", agent.ErrSessionNotFound, ref)
+	case 1:
+	default:
+		return agent.Session{}, fmt.Errorf("%d sessions start with %s; give more of the ID", len(found), ref)
+	}
+	l.mu.Lock()
+	l.current = found[0]
+	l.mu.Unlock()
+	return found[0], nil
+}
+
+// summarizeLoop applies queued exchanges to the summaries one at a time,
+// so each builds on the last
+func (l *sessionLog) summarizeLoop() {
+	defer close(l.done)
+	summaries := map[string]agent.Summary{}
+	for job := range l.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), summaryTimeout)
+		s, err := l.summarize(ctx, summaries[job.id], job.exchange)
+		cancel()
+		if err != nil {
+			continue // keep the title we have
+		}
+		summaries[job.id] = s
+
+		l.mu.Lock()
+		if l.current.ID == job.id {
+			setSummary(&l.current, s)
+			l.store.Save(l.current)
+		} else if old, err := l.store.Load(job.id); err == nil {
+			setSummary(&old, s) // cleared while summarizing
+			l.store.Save(old)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// setSummary updates a session's summary, and its title unless the user
+// named it
+func setSummary(session *agent.Session, s agent.Summary) {
+	if !session.Named {
+		session.Title = s.Title
+	}
+	session.Summary = s.Summary
+}
+
+// Close waits for pending summaries
+func (l *sessionLog) Close() {
+	close(l.queue)
+	<-l.done
+}
+
+// list returns the saved sessions, most recently updated first
+func (l *sessionLog) list() ([]agent.Session, error) {
+	return l.store.List()
+}
+
+// sessionsCommand lists the saved sessions with their titles and summaries,
+// marking the current one
+func sessionsCommand(w io.Writer, l *sessionLog) {
+	if l == nil {
+		fmt.Fprintln(w, "Sessions are not saved (--no-sessions).")
+		return
+	}
+	sessions, err := l.list()
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return
+	}
+	if len(sessions) == 0 {
+		fmt.Fprintln(w, "No saved sessions yet.")
+		return
+	}
+	l.mu.Lock()
+	currentID := l.current.ID
+	l.mu.Unlock()
+	for i, s := range sessions {
+		if i == maxListedSessions {
+			fmt.Fprintf(w, "... and %d older sessions in %s\n", len(sessions)-i, l.where)
+			break
+		}
+		mark := " "
+		if s.ID == currentID {
+			mark = "*"
+		}
+		fmt.Fprintf(w, "%2d %s %s  %s (%s)\n", i+1, mark, s.Updated.Local().Format("2006-01-02 15:04"), s.Title, exchangeCount(s.Exchanges()))
+		if s.Summary != "" {
+			fmt.Fprintf(w, "       %s\n", strings.ReplaceAll(s.Summary, "\n", " "))
+		}
+	}
+}
+
+// exchangeCount is "1 exchange" or "n exchanges"
+func exchangeCount(n int) string {
+	if n == 1 {
+		return "1 exchange"
+	}
+	return fmt.Sprintf("%d exchanges", n)
+}
+
+// saveCommand saves the conversation now, titled title if given (/save)
+func saveCommand(w io.Writer, l *sessionLog, ag *agent.Agent, title string) {
+	if l == nil {
+		fmt.Fprintln(w, "Sessions are not saved (--no-sessions).")
+		return
+	}
+	s, err := l.name(title, ag.History())
+	if err != nil {
+		fmt.Fprintf(w, "Not saved: %v\n", err)
+		return
+	}
+	fmt.Fprintf(w, "Saved %s: %s (%s)\n", s.ID, s.Title, exchangeCount(s.Exchanges()))
+}
+
+// loadCommand continues a saved session in agents, in place of the current
+// conversation (/load); false if nothing was loaded
+func loadCommand(w io.Writer, l *sessionLog, agents []*agent.Agent, ref string) bool {
+	if l == nil {
+		fmt.Fprintln(w, "Sessions are not saved (--no-sessions).")
+		return false
+	}
+	if ref == "" {
+		fmt.Fprintln(w, "Usage: /load <number or ID from /sessions>")
+		return false
+	}
+	s, err := l.resume(ref)
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return false
+	}
+	for _, ag := range agents {
+		if err := ag.LoadHistory(s); err != nil {
+			fmt.Fprintf(w, "Failed to load %s: %v\n", s.ID, err)
+			return false
+		}
+	}
+	fmt.Fprintf(w, "Loaded %s (%s); the conversation continues from there.\n", s.Title, exchangeCount(s.Exchanges()))
+	return true
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rathore/langchain-agent/agent"
+	"github.com/rathore/langchain-agent/llm"
+)
+
+func TestSessionLog(t *testing.T) {
+	dir := t.TempDir()
+	var calls []agent.Summary
+	summarize := func(ctx context.Context, prev agent.Summary, exchange []llm.Message) (agent.Summary, error) {
+		calls = append(calls, prev)
+		if strings.Contains(exchange[0].Content, "fail") {
+			return prev, errors.New("model unavailable")
+		}
+		return agent.Summary{Title: "api-server CrashLoopBackOff investigation", Summary: fmt.Sprintf("%d exchanges.", len(calls))}, nil
+	}
+	store, where, err := openSessionStore("file", dir)
+	if err != nil || where != dir {
+		t.Fatal(err)
+	}
+	l := newSessionLog(store, where, summarize)
+
+	history := []llm.Message{{Role: "user", Content: "why is api-server restarting?"}, {Role: "assistant", Content: "A secret is missing."}}
+	if err := l.record(history); err != nil {
+		t.Fatal(err)
+	}
+	history = append(history, llm.Message{Role: "user", Content: "and now?"}, llm.Message{Role: "assistant", Content: "It runs."})
+	l.record(history)
+	l.record(append(history, llm.Message{Role: "user", Content: "cancelled run"})) // unanswered: not summarized
+	l.reset()
+	l.record([]llm.Message{{Role: "user", Content: "this will fail"}, {Role: "assistant", Content: "ok"}})
+	l.Close()
+
+	if len(calls) != 3 || calls[1].Title == "" {
+		t.Errorf("summarize calls = %+v, want 3 each building on the last", calls)
+	}
+	sessions, err := l.list()
+	if err != nil || len(sessions) != 2 {
+		t.Fatalf("list = %d sessions, %v; want 2", len(sessions), err)
+	}
+	if s := sessions[0]; s.Title != "this will fail" || s.Summary != "" {
+		t.Errorf("newest session = %+v, want titled by its query after a failed summary", s)
+	}
+	if s := sessions[1]; s.Title != "api-server CrashLoopBackOff investigation" || s.Summary != "2 exchanges." || len(s.History) != 5 {
+		t.Errorf("first session = %+v", s)
+	}
+
+	var out bytes.Buffer
+	sessionsCommand(&out, l)
+	for _, want := range []string{"* ", "this will fail (1 exchange)", "api-server CrashLoopBackOff investigation (3 exchanges)", "    2 exchanges."} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("/sessions output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	// Preferences are saved with the session and kept by /clear
+	l = newSessionLog(store, dir, nil)
+	l.setPreferences(agent.Preferences{Language: "German"})
+	l.record(history[:2])
+	l.reset()
+	l.record(history[:2])
+	l.Close()
+	sessions, _ = l.list()
+	if p := sessions[0].Preferences; p == nil || p.Language != "German" {
+		t.Errorf("preferences after /clear = %+v, want German", p)
+	}
+
+	out.Reset()
+	sessionsCommand(&out, nil)
+	if !strings.Contains(out.String(), "not saved") {
+		t.Errorf("/sessions without a log = %q", out.String())
+	}
+}
+
+func TestSaveAndLoadCommands(t *testing.T) {
+	store, _ := agent.NewFileStore(t.TempDir())
+	summarize := func(ctx context.Context, prev agent.Summary, exchange []llm.Message) (agent.Summary, error) {
+		return agent.Summary{Title: "LLM title", Summary: "What happened."}, nil
+	}
+	l := newSessionLog(store, "dir", summarize)
+	ag, _ := agent.New(agent.Config{Client: stubClient{}})
+
+	var out bytes.Buffer
+	saveCommand(&out, l, ag, "disk cleanup")
+	if !strings.Contains(out.String(), "Not saved: nothing to save yet") {
+		t.Errorf("/save without history: %q", out.String())
+	}
+
+	history := []llm.Message{{Role: "user", Content: "clean /var/log"}, {Role: "assistant", Content: "Freed 2 GB."}}
+	l.record(history)
+	l.mu.Lock()
+	id := l.current.ID
+	l.mu.Unlock()
+	out.Reset()
+	ag.LoadHistory(agent.Session{History: history})
+	saveCommand(&out, l, ag, "disk cleanup on web-1")
+	if !strings.Contains(out.String(), "Saved "+id+": disk cleanup on web-1 (1 exchange)") {
+		t.Errorf("/save = %q", out.String())
+	}
+	l.reset()
+	l.record([]llm.Message{{Role: "user", Content: "other"}, {Role: "assistant", Content: "ok"}})
+	l.Close() // summaries done: the named session keeps its title
+
+	out.Reset()
+	fresh, _ := agent.New(agent.Config{Client: stubClient{}})
+	if !loadCommand(&out, l, []*agent.Agent{fresh}, "2") {
+		t.Fatalf("/load 2 failed: %q", out.String())
+	}
+	if !strings.Contains(out.String(), "Loaded disk cleanup on web-1 (1 exchange)") {
+		t.Errorf("/load = %q", out.String())
+	}
+	if got := fresh.History(); len(got) != 2 || got[1].Content != "Freed 2 GB." {
+		t.Errorf("history after /load = %v", got)
+	}
+	if s, _ := store.Load(id); s.Title != "disk cleanup on web-1" || s.Summary != "What happened." {
+		t.Errorf("named session = %+v", s)
+	}
+	l.mu.Lock()
+	if l.current.ID != id {
+		t.Errorf("current session = %s, want the loaded %s", l.current.ID, id)
+	}
+	l.mu.Unlock()
+
+	for ref, want := range map[string]string{"": "Usage: /load", "nope": "session not found: nope", "2": ""} {
+		out.Reset()
+		loadCommand(&out, l, []*agent.Agent{fresh}, ref)
+		if want != "" && !strings.Contains(out.String(), want) {
+			t.Errorf("/load %q = %q, want %q", ref, out.String(), want)
+		}
+	}
+	out.Reset()
+	loadCommand(&out, nil, nil, "1")
+	if !strings.Contains(out.String(), "not saved") {
+		t.Errorf("/load without a log = %q", out.String())
+	}
+}
@@ -0,0 +1,7 @@
+//go:build sqlite
+
+package main
+
+// The pure-Go SQLite driver for --session-store sqlite; it isn't linked by
+// default, as it adds several MB to the binary
+import _ "modernc.org/sqlite"
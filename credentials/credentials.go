@@ -0,0 +1,115 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// ErrNotFound is returned by Store.Get and Store.Delete for an unknown name
+var ErrNotFound = errors.New("credential not found")
+
+// Store keeps secrets by name. Keyring and File implement it.
+type Store interface {
+	// Get returns the secret called name, or an error wrapping ErrNotFound
+	Get(name string) (string, error)
+	// Set adds the secret, or replaces the one called name
+	Set(name, secret string) error
+	// Delete removes the secret called name
+	Delete(name string) error
+	// List returns the names of the stored secrets, sorted; it never needs
+	// the secrets themselves, so it doesn't unlock anything
+	List() ([]string, error)
+}
+
+// validName keeps names safe to pass to the keyring tools unquoted
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._@:/+-]{0,199}$`)
+
+// CheckName returns an error if name can't name a credential
+func CheckName(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid credential name %q (use letters, digits and . _ @ : / + -)", name)
+	}
+	return nil
+}
+
+// envName matches the names ExportEnv exports, such as OPENAI_API_KEY
+var envName = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// SSHPassword names the password of user@host, e.g. "ssh/deploy@web-1"
+func SSHPassword(user, host string) string {
+	return "ssh/" + user + "@" + host
+}
+
+// SSHKey names the passphrase of an SSH key file, e.g. "ssh-key/id_ed25519"
+func SSHKey(keyFile string) string {
+	return "ssh-key/" + filepath.Base(keyFile)
+}
+
+// Lookup returns the secret called name, or "" if store has none
+func Lookup(store Store, name string) (string, error) {
+	secret, err := store.Get(name)
+	if errors.Is(err, ErrNotFound) {
+		return "", nil
+	}
+	return secret, err
+}
+
+// ExportEnv sets each unset environment variable named by a stored
+// credential, such as OPENAI_API_KEY or CONFLUENCE_TOKEN, to its secret, so
+// code that reads keys from the environment finds them. The environment
+// wins: a variable that is already set is left alone. Returns the names set.
+func ExportEnv(store Store) ([]string, error) {
+	names, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	var set []string
+	for _, name := range names {
+		if !envName.MatchString(name) || os.Getenv(name) != "" {
+			continue
+		}
+		secret, err := store.Get(name)
+		if err != nil {
+			return set, err
+		}
+		if err := os.Setenv(name, secret); err != nil {
+			return set, fmt.Errorf("failed to set $%s: %w", name, err)
+		}
+		set = append(set, name)
+	}
+	return set, nil
+}
+
+// Open returns the store of kind: "keyring" (the OS keyring), "file" (an
+// encrypted file) or "auto" (the keyring if this system has a keyring tool,
+// else the file). Both keep their files in dir: the keyring its list of
+// names, the file store the secrets. passphrase gives the file's master
+// passphrase; create is true when the file is new.
+func Open(kind, dir string, passphrase func(create bool) ([]byte, error)) (Store, error) {
+	switch kind {
+	case "auto":
+		if keyring, err := NewKeyring(dir); err == nil {
+			return keyring, nil
+		}
+		return NewFile(filepath.Join(dir, fileName), passphrase), nil
+	case "keyring":
+		return NewKeyring(dir)
+	case "file":
+		return NewFile(filepath.Join(dir, fileName), passphrase), nil
+	}
+	return nil, fmt.Errorf("unknown credential store %q (use auto, keyring or file)", kind)
+}
+
+// sortedKeys returns the keys of m, sorted
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,45 @@
+package credentials
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestExportEnv(t *testing.T) {
+	t.Setenv("TEST_CRED_TOKEN", "")
+	t.Setenv("TEST_CRED_SET", "from env")
+	fake := &fakeKeyring{items: map[string]string{}}
+	store := &Keyring{Index: filepath.Join(t.TempDir(), indexName), tool: secretTool{}, run: fake.run}
+	for name, secret := range map[string]string{"TEST_CRED_TOKEN": "t0k", "TEST_CRED_SET": "stored", "ssh/web-1": "pw"} {
+		store.Set(name, secret)
+	}
+
+	set, err := ExportEnv(store)
+	if err != nil || !slices.Equal(set, []string{"TEST_CRED_TOKEN"}) {
+		t.Fatalf("ExportEnv() = %v, %v", set, err)
+	}
+	if v := os.Getenv("TEST_CRED_TOKEN"); v != "t0k" {
+		t.Errorf("$TEST_CRED_TOKEN = %q", v)
+	}
+	if v := os.Getenv("TEST_CRED_SET"); v != "from env" {
+		t.Errorf("$TEST_CRED_SET = %q, want the environment to win", v)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	if _, err := Open("vault", t.TempDir(), nil); err == nil {
+		t.Error("Open(vault) succeeded")
+	}
+	store, err := Open("file", t.TempDir(), nil)
+	if _, ok := store.(*File); err != nil || !ok {
+		t.Errorf("Open(file) = %T, %v", store, err)
+	}
+	if name := SSHKey("/home/me/.ssh/id_ed25519"); name != "ssh-key/id_ed25519" || CheckName(name) != nil {
+		t.Errorf("SSHKey() = %q", name)
+	}
+	if name := SSHPassword("deploy", "10.0.0.5:2222"); CheckName(name) != nil {
+		t.Errorf("SSHPassword() = %q is invalid", name)
+	}
+}
@@ -0,0 +1,5 @@
+// Package credentials keeps SSH passwords, API tokens and database DSNs out
+// of flags and config files: in the OS keyring (the Secret Service via
+// secret-tool on Linux, the login keychain on macOS) or, where there is
+// none, in a file encrypted with a master passphrase.
+package credentials
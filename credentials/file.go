@@ -0,0 +1,232 @@
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// fileName is the encrypted file in the store dir
+const fileName = "credentials.json"
+
+// fileVersion is the format of the file: argon2id key, AES-256-GCM
+const fileVersion = 1
+
+// Argon2id cost, the RFC 9106 second recommended option
+const (
+	argonTime    = 3
+	argonMemory  = 64 * 1024 // KiB
+	argonThreads = 4
+)
+
+// fileData is the file's JSON. The names are in the clear, so List needs
+// no passphrase, but authenticated: they are the additional data of Data.
+type fileData struct {
+	Version int      `json:"version"`
+	Names   []string `json:"names"`
+	Salt    []byte   `json:"salt"`
+	Nonce   []byte   `json:"nonce"`
+	Data    []byte   `json:"data"` // the JSON name → secret map, sealed
+}
+
+// File is a Store keeping the secrets in one file, encrypted with a key
+// derived from a master passphrase. The passphrase is asked for when a
+// secret is first read or written, and the key kept for the process.
+type File struct {
+	Path       string
+	Passphrase func(create bool) ([]byte, error)
+
+	mu   sync.Mutex
+	key  []byte
+	salt []byte // of key
+}
+
+// NewFile returns the store in the file at path, which is created on the
+// first Set
+func NewFile(path string, passphrase func(create bool) ([]byte, error)) *File {
+	return &File{Path: path, Passphrase: passphrase}
+}
+
+func (f *File) Get(name string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, err := f.read()
+	if err != nil {
+		return "", err
+	}
+	if data == nil || !slices.Contains(data.Names, name) {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	secrets, err := f.open(data)
+	if err != nil {
+		return "", err
+	}
+	secret, ok := secrets[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return secret, nil
+}
+
+func (f *File) Set(name, secret string) error {
+	if err := CheckName(name); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, err := f.read()
+	if err != nil {
+		return err
+	}
+	secrets := map[string]string{}
+	if data != nil {
+		if secrets, err = f.open(data); err != nil {
+			return err
+		}
+	}
+	secrets[name] = secret
+	return f.write(data, secrets)
+}
+
+func (f *File) Delete(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, err := f.read()
+	if err != nil {
+		return err
+	}
+	if data == nil || !slices.Contains(data.Names, name) {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	secrets, err := f.open(data)
+	if err != nil {
+		return err
+	}
+	delete(secrets, name)
+	return f.write(data, secrets)
+}
+
+func (f *File) List() ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, err := f.read()
+	if err != nil || data == nil {
+		return nil, err
+	}
+	return data.Names, nil
+}
+
+// read returns the file's contents, or nil if there is no file yet
+func (f *File) read() (*fileData, error) {
+	raw, err := os.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+	var data fileData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("invalid credentials file %s: %w", f.Path, err)
+	}
+	if data.Version != fileVersion {
+		return nil, fmt.Errorf("credentials file %s has unknown version %d", f.Path, data.Version)
+	}
+	return &data, nil
+}
+
+// open decrypts the secrets in data
+func (f *File) open(data *fileData) (map[string]string, error) {
+	aead, err := f.cipher(data.Salt, false)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(nil, data.Nonce, data.Data, additionalData(data.Names))
+	if err != nil {
+		// Ask again next time rather than failing for the whole process
+		f.key = nil
+		return nil, errors.New("failed to decrypt credentials: wrong passphrase or a damaged file")
+	}
+	var secrets map[string]string
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return nil, fmt.Errorf("invalid credentials: %w", err)
+	}
+	return secrets, nil
+}
+
+// write seals secrets, with the salt of prev if any, and replaces the file
+// atomically
+func (f *File) write(prev *fileData, secrets map[string]string) error {
+	data := fileData{Version: fileVersion, Names: sortedKeys(secrets)}
+	if prev != nil {
+		data.Salt = prev.Salt
+	} else {
+		data.Salt = make([]byte, 16)
+		rand.Read(data.Salt)
+	}
+	aead, err := f.cipher(data.Salt, prev == nil)
+	if err != nil {
+		return err
+	}
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return fmt.Errorf("failed to encode credentials: %w", err)
+	}
+	data.Nonce = make([]byte, aead.NonceSize())
+	rand.Read(data.Nonce)
+	data.Data = aead.Seal(nil, data.Nonce, plain, additionalData(data.Names))
+
+	raw, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode credentials: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0700); err != nil {
+		return fmt.Errorf("failed to create credentials dir: %w", err)
+	}
+	tmp := f.Path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0600); err != nil {
+		return fmt.Errorf("failed to save credentials: %w", err)
+	}
+	if err := os.Rename(tmp, f.Path); err != nil {
+		return fmt.Errorf("failed to save credentials: %w", err)
+	}
+	return nil
+}
+
+// cipher returns the AEAD for salt, asking for the passphrase unless the
+// key for salt is known
+func (f *File) cipher(salt []byte, create bool) (cipher.AEAD, error) {
+	if f.key == nil || string(f.salt) != string(salt) {
+		if f.Passphrase == nil {
+			return nil, errors.New("no passphrase for the credentials file")
+		}
+		passphrase, err := f.Passphrase(create)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get passphrase: %w", err)
+		}
+		if len(passphrase) == 0 {
+			return nil, errors.New("empty passphrase")
+		}
+		f.key = argon2.IDKey(passphrase, salt, argonTime, argonMemory, argonThreads, 32)
+		f.salt = salt
+	}
+	block, err := aes.NewCipher(f.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// additionalData binds the clear names to the sealed secrets
+func additionalData(names []string) []byte {
+	return []byte(strings.Join(names, "\n"))
+}
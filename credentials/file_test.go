@@ -0,0 +1,93 @@
+package credentials
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testStore checks the Store contract
+func testStore(t *testing.T, store Store) {
+	t.Helper()
+	if names, err := store.List(); err != nil || len(names) != 0 {
+		t.Fatalf("List() on a new store = %v, %v", names, err)
+	}
+	for name, secret := range map[string]string{"OPENAI_API_KEY": "sk-1", "ssh/deploy@web-1": "hunter2"} {
+		if err := store.Set(name, secret); err != nil {
+			t.Fatalf("Set(%s): %v", name, err)
+		}
+	}
+	if err := store.Set("OPENAI_API_KEY", "sk-2"); err != nil {
+		t.Fatal(err)
+	}
+	if secret, err := store.Get("OPENAI_API_KEY"); err != nil || secret != "sk-2" {
+		t.Errorf("Get(OPENAI_API_KEY) = %q, %v; want sk-2", secret, err)
+	}
+	if _, err := store.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(missing) err = %v, want ErrNotFound", err)
+	}
+	if names, err := store.List(); err != nil || strings.Join(names, ",") != "OPENAI_API_KEY,ssh/deploy@web-1" {
+		t.Errorf("List() = %v, %v", names, err)
+	}
+	if err := store.Delete("OPENAI_API_KEY"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete("OPENAI_API_KEY"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete err = %v, want ErrNotFound", err)
+	}
+	if names, _ := store.List(); len(names) != 1 || names[0] != "ssh/deploy@web-1" {
+		t.Errorf("List() after Delete = %v", names)
+	}
+	if err := store.Set("../x y", "s"); err == nil {
+		t.Error("Set with an invalid name succeeded")
+	}
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "creds", fileName)
+	var asked []bool
+	passphrase := func(create bool) ([]byte, error) {
+		asked = append(asked, create)
+		return []byte("correct horse"), nil
+	}
+	testStore(t, NewFile(path, passphrase))
+	if len(asked) != 1 || !asked[0] {
+		t.Errorf("asked %v, want once, creating", asked)
+	}
+
+	raw, _ := os.ReadFile(path)
+	if strings.Contains(string(raw), "hunter2") {
+		t.Error("secret stored in the clear")
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// A new process asks again; List doesn't
+	asked = nil
+	store := NewFile(path, passphrase)
+	if names, _ := store.List(); len(names) != 1 || len(asked) != 0 {
+		t.Errorf("List() = %v, asked %v", names, asked)
+	}
+	if secret, err := store.Get("ssh/deploy@web-1"); err != nil || secret != "hunter2" || len(asked) != 1 || asked[0] {
+		t.Errorf("Get() = %q, %v; asked %v", secret, err, asked)
+	}
+
+	wrong := NewFile(path, func(bool) ([]byte, error) { return []byte("wrong"), nil })
+	if _, err := wrong.Get("ssh/deploy@web-1"); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("Get with a wrong passphrase err = %v", err)
+	}
+
+	// The clear names are authenticated
+	var data fileData
+	json.Unmarshal(raw, &data)
+	data.Names = append(data.Names, "injected")
+	raw, _ = json.Marshal(data)
+	os.WriteFile(path, raw, 0600)
+	if _, err := NewFile(path, passphrase).Get("ssh/deploy@web-1"); err == nil {
+		t.Error("Get succeeded with tampered names")
+	}
+}
@@ -0,0 +1,215 @@
+package credentials
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// service is the keyring service the secrets are stored under
+const service = "langchain-agent"
+
+// indexName is the file in the store dir listing the keyring's names
+const indexName = "keyring-credentials.json"
+
+// runFunc runs a command with stdin and returns its output
+type runFunc func(stdin string, name string, args ...string) (string, error)
+
+// keyringTool drives one OS keyring's command-line tool
+type keyringTool interface {
+	get(run runFunc, name string) (string, error)
+	set(run runFunc, name, secret string) error
+	delete(run runFunc, name string) error
+}
+
+// Keyring is a Store in the OS keyring, driven through its command-line
+// tool so no cgo is needed: secret-tool (libsecret) for the Secret Service
+// of GNOME Keyring or KWallet, or security for the macOS keychain. Keyrings
+// can't portably list one program's items, so the names are also kept in
+// a file, which holds no secrets.
+type Keyring struct {
+	Index string // the names file
+
+	mu   sync.Mutex
+	tool keyringTool
+	run  runFunc
+}
+
+// NewKeyring returns the keyring store with its names file in dir, or an
+// error if this system has no keyring tool
+func NewKeyring(dir string) (*Keyring, error) {
+	var tool keyringTool
+	switch runtime.GOOS {
+	case "darwin":
+		if _, err := exec.LookPath("security"); err == nil {
+			tool = keychain{}
+		}
+	default:
+		if _, err := exec.LookPath("secret-tool"); err == nil {
+			tool = secretTool{}
+		}
+	}
+	if tool == nil {
+		return nil, errors.New("no OS keyring tool found (install secret-tool from libsecret, or use the file store)")
+	}
+	return &Keyring{Index: filepath.Join(dir, indexName), tool: tool, run: runCommand}, nil
+}
+
+func (k *Keyring) Get(name string) (string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	names, err := k.names()
+	if err != nil {
+		return "", err
+	}
+	if !slices.Contains(names, name) {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	secret, err := k.tool.get(k.run, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s from the keyring: %w", name, err)
+	}
+	return secret, nil
+}
+
+func (k *Keyring) Set(name, secret string) error {
+	if err := CheckName(name); err != nil {
+		return err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	names, err := k.names()
+	if err != nil {
+		return err
+	}
+	if err := k.tool.set(k.run, name, secret); err != nil {
+		return fmt.Errorf("failed to store %s in the keyring: %w", name, err)
+	}
+	if slices.Contains(names, name) {
+		return nil
+	}
+	names = append(names, name)
+	slices.Sort(names)
+	return k.saveNames(names)
+}
+
+func (k *Keyring) Delete(name string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	names, err := k.names()
+	if err != nil {
+		return err
+	}
+	i := slices.Index(names, name)
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err := k.tool.delete(k.run, name); err != nil {
+		return fmt.Errorf("failed to remove %s from the keyring: %w", name, err)
+	}
+	return k.saveNames(slices.Delete(names, i, i+1))
+}
+
+func (k *Keyring) List() ([]string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.names()
+}
+
+// names reads the names file; none yet is an empty list
+func (k *Keyring) names() ([]string, error) {
+	data, err := os.ReadFile(k.Index)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read credential names: %w", err)
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return nil, fmt.Errorf("invalid credential names file %s: %w", k.Index, err)
+	}
+	return names, nil
+}
+
+// saveNames replaces the names file atomically
+func (k *Keyring) saveNames(names []string) error {
+	data, err := json.Marshal(names)
+	if err != nil {
+		return fmt.Errorf("failed to encode credential names: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(k.Index), 0700); err != nil {
+		return fmt.Errorf("failed to create credentials dir: %w", err)
+	}
+	tmp := k.Index + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to save credential names: %w", err)
+	}
+	if err := os.Rename(tmp, k.Index); err != nil {
+		return fmt.Errorf("failed to save credential names: %w", err)
+	}
+	return nil
+}
+
+// secretTool stores items with the attributes service and account; the
+// secret goes through stdin, never the command line
+type secretTool struct{}
+
+func (secretTool) get(run runFunc, name string) (string, error) {
+	return run("", "secret-tool", "lookup", "service", service, "account", name)
+}
+
+func (secretTool) set(run runFunc, name, secret string) error {
+	_, err := run(secret, "secret-tool", "store", "--label", service+" "+name, "service", service, "account", name)
+	return err
+}
+
+func (secretTool) delete(run runFunc, name string) error {
+	_, err := run("", "secret-tool", "clear", "service", service, "account", name)
+	return err
+}
+
+// keychain stores generic passwords with the service and account. Adding
+// one goes through security's interactive mode, reading the command from
+// stdin, so the secret (hex-encoded) isn't on its command line.
+type keychain struct{}
+
+func (keychain) get(run runFunc, name string) (string, error) {
+	out, err := run("", "security", "find-generic-password", "-s", service, "-a", name, "-w")
+	return strings.TrimSuffix(out, "\n"), err
+}
+
+func (keychain) set(run runFunc, name, secret string) error {
+	command := fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n", service, name, hex.EncodeToString([]byte(secret)))
+	_, err := run(command, "security", "-i")
+	return err
+}
+
+func (keychain) delete(run runFunc, name string) error {
+	_, err := run("", "security", "delete-generic-password", "-s", service, "-a", name)
+	return err
+}
+
+// runCommand runs a keyring tool, with its error output in the error
+func runCommand(stdin string, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %w: %s", name, err, msg)
+		}
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return string(out), nil
+}
@@ -0,0 +1,59 @@
+package credentials
+
+import (
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeKeyring serves the secret-tool and security commands from a map
+type fakeKeyring struct {
+	items map[string]string
+	calls []string
+}
+
+func (f *fakeKeyring) run(stdin string, name string, args ...string) (string, error) {
+	f.calls = append(f.calls, name+" "+strings.Join(args, " "))
+	if name == "security" && slices.Equal(args, []string{"-i"}) {
+		// add-generic-password -U -s service -a account -X hex
+		fields := strings.Fields(stdin)
+		secret, err := hex.DecodeString(fields[len(fields)-1])
+		if err != nil {
+			return "", err
+		}
+		f.items[fields[len(fields)-3]] = string(secret)
+		return "", nil
+	}
+	account := args[slices.Index(args, map[string]string{"secret-tool": "account", "security": "-a"}[name])+1]
+	switch args[0] {
+	case "store":
+		f.items[account] = stdin
+	case "lookup":
+		return f.items[account], nil
+	case "find-generic-password":
+		return f.items[account] + "\n", nil
+	case "clear", "delete-generic-password":
+		delete(f.items, account)
+	default:
+		return "", errors.New("unexpected command")
+	}
+	return "", nil
+}
+
+func TestKeyring(t *testing.T) {
+	for _, tool := range []keyringTool{secretTool{}, keychain{}} {
+		fake := &fakeKeyring{items: map[string]string{}}
+		testStore(t, &Keyring{Index: filepath.Join(t.TempDir(), indexName), tool: tool, run: fake.run})
+		if len(fake.items) != 1 || fake.items["ssh/deploy@web-1"] != "hunter2" {
+			t.Errorf("%T: keyring holds %v", tool, fake.items)
+		}
+		for _, call := range fake.calls {
+			if strings.Contains(call, "hunter2") || strings.Contains(call, "sk-") {
+				t.Errorf("%T: secret on the command line: %s", tool, call)
+			}
+		}
+	}
+}
@@ -0,0 +1,11 @@
+// Package eval scores the agent on a suite of tasks: queries with the tools
+// they should (and shouldn't) call and assertions on the answer. A suite
+// runs against a live model with Run, or against a --record cassette with
+// Replay, and gives a Report with each task's pass/fail and score; Compare
+// sets two reports side by side to compare models or prompts.
+//
+//	suite, err := eval.Load("tasks.yaml")
+//	report, err := eval.Run(ctx, ag, suite, os.Stdout, nil)
+//	report.Model = "qwen2.5:32b"
+//	report.WriteText(os.Stdout)
+package eval
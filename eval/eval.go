@@ -0,0 +1,259 @@
+package eval
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rathore/langchain-agent/agent"
+	"github.com/rathore/langchain-agent/llm"
+	"github.com/rathore/langchain-agent/replay"
+	"gopkg.in/yaml.v3"
+)
+
+// Suite is a task file:
+//
+//	name: ops-basics
+//	tasks:
+//	  - name: disk
+//	    query: how much disk space is left on /?
+//	    tools: [shell]           # must be called (* and ? wildcards)
+//	    not_tools: [ssh]         # must not be called
+//	    max_tool_calls: 2
+//	    answer:
+//	      contains: ["%"]        # case-insensitive
+//	      not_contains: ["I can't"]
+//	      matches: ['\d+%']      # regular expressions
+//	    weight: 2                # share of the suite score (default 1)
+//
+// Every task runs in a fresh conversation.
+type Suite struct {
+	Name  string `yaml:"name"`
+	Tasks []Task `yaml:"tasks"`
+}
+
+// Task is one query and what its run should do
+type Task struct {
+	Name         string   `yaml:"name"`
+	Query        string   `yaml:"query"`
+	Tools        []string `yaml:"tools"`
+	NotTools     []string `yaml:"not_tools"`
+	MaxToolCalls *int     `yaml:"max_tool_calls"`
+	Answer       Answer   `yaml:"answer"`
+	Weight       float64  `yaml:"weight"`
+}
+
+// Answer are assertions on a task's final answer
+type Answer struct {
+	Contains    []string `yaml:"contains"`
+	NotContains []string `yaml:"not_contains"`
+	Matches     []string `yaml:"matches"`
+}
+
+// Load reads and validates a task file. Tasks without a name are named by
+// their position.
+func Load(filename string) (*Suite, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read eval suite: %w", err)
+	}
+	var s Suite
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse eval suite %s: %w", filename, err)
+	}
+	if len(s.Tasks) == 0 {
+		return nil, fmt.Errorf("eval suite %s has no tasks", filename)
+	}
+	if s.Name == "" {
+		s.Name = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	}
+	names := map[string]bool{}
+	for i := range s.Tasks {
+		t := &s.Tasks[i]
+		if t.Name == "" {
+			t.Name = fmt.Sprintf("task %d", i+1)
+		}
+		if names[t.Name] {
+			return nil, fmt.Errorf("eval suite %s: duplicate task %q", filename, t.Name)
+		}
+		names[t.Name] = true
+		if err := t.validate(); err != nil {
+			return nil, fmt.Errorf("eval suite %s: task %q: %w", filename, t.Name, err)
+		}
+	}
+	return &s, nil
+}
+
+func (t *Task) validate() error {
+	if strings.TrimSpace(t.Query) == "" {
+		return fmt.Errorf("query is required")
+	}
+	if t.Weight < 0 {
+		return fmt.Errorf("weight must not be negative")
+	}
+	if t.Weight == 0 {
+		t.Weight = 1
+	}
+	for _, pattern := range append(append([]string(nil), t.Tools...), t.NotTools...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid tool pattern %q: %w", pattern, err)
+		}
+	}
+	for _, expr := range t.Answer.Matches {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf("invalid answer pattern %q: %w", expr, err)
+		}
+	}
+	return nil
+}
+
+// Outcome is what a run did, as far as tasks check it
+type Outcome struct {
+	Answer  string
+	Error   string
+	Tools   []string // tools called, in order
+	Usage   llm.Usage
+	Elapsed time.Duration
+}
+
+// FromResult is the outcome of a live run
+func FromResult(result *agent.RunResult, err error) Outcome {
+	var o Outcome
+	if result != nil {
+		o.Answer, o.Usage, o.Elapsed = result.Answer, result.Usage, result.Elapsed
+		for _, step := range result.Steps {
+			if step.ToolCall != nil {
+				o.Tools = append(o.Tools, step.ToolCall.Name)
+			}
+		}
+	}
+	if err != nil {
+		o.Error = err.Error()
+	}
+	return o
+}
+
+// FromReplay is the outcome of a replayed run
+func FromReplay(run replay.Run) Outcome {
+	o := Outcome{Answer: run.Answer, Error: run.Error}
+	for _, step := range run.Steps {
+		if step.ToolCall != nil {
+			o.Tools = append(o.Tools, step.ToolCall.Name)
+		}
+	}
+	return o
+}
+
+// CheckResult is one assertion of a task
+type CheckResult struct {
+	Name   string `json:"name"`
+	Pass   bool   `json:"pass"`
+	Detail string `json:"detail,omitempty"` // why it failed
+}
+
+// TaskResult is how a run did on its task. Score is the share of the
+// task's checks that passed; the task passes if all of them did.
+type TaskResult struct {
+	Name    string        `json:"name"`
+	Query   string        `json:"query"`
+	Pass    bool          `json:"pass"`
+	Score   float64       `json:"score"`
+	Weight  float64       `json:"weight"`
+	Checks  []CheckResult `json:"checks"`
+	Answer  string        `json:"answer"`
+	Tools   []string      `json:"tools"`
+	Error   string        `json:"error,omitempty"`
+	Usage   llm.Usage     `json:"usage"`
+	Elapsed time.Duration `json:"elapsed_ns"`
+}
+
+// Check scores an outcome against the task. The run must have finished
+// without an error; then each expected tool, unwanted tool, tool call limit
+// and answer assertion is a check.
+func (t *Task) Check(o Outcome) TaskResult {
+	r := TaskResult{
+		Name:    t.Name,
+		Query:   t.Query,
+		Weight:  t.Weight,
+		Answer:  o.Answer,
+		Tools:   o.Tools,
+		Error:   o.Error,
+		Usage:   o.Usage,
+		Elapsed: o.Elapsed,
+	}
+	if r.Weight == 0 {
+		r.Weight = 1
+	}
+	add := func(name string, pass bool, detail string) {
+		c := CheckResult{Name: name, Pass: pass}
+		if !pass {
+			c.Detail = detail
+		}
+		r.Checks = append(r.Checks, c)
+	}
+
+	add("completed", o.Error == "", o.Error)
+	called := "no tools called"
+	if len(o.Tools) > 0 {
+		called = "called " + strings.Join(o.Tools, ", ")
+	}
+	for _, pattern := range t.Tools {
+		add("calls "+pattern, calledTool(o.Tools, pattern) != "", called)
+	}
+	for _, pattern := range t.NotTools {
+		name := calledTool(o.Tools, pattern)
+		add("doesn't call "+pattern, name == "", "called "+name)
+	}
+	if t.MaxToolCalls != nil {
+		add(fmt.Sprintf("at most %d tool calls", *t.MaxToolCalls), len(o.Tools) <= *t.MaxToolCalls,
+			fmt.Sprintf("%d tool calls", len(o.Tools)))
+	}
+	answer := strings.ToLower(o.Answer)
+	for _, s := range t.Answer.Contains {
+		add(fmt.Sprintf("answer contains %q", s), strings.Contains(answer, strings.ToLower(s)), quote(o.Answer))
+	}
+	for _, s := range t.Answer.NotContains {
+		add(fmt.Sprintf("answer doesn't contain %q", s), !strings.Contains(answer, strings.ToLower(s)), quote(o.Answer))
+	}
+	for _, expr := range t.Answer.Matches {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			add("answer matches "+expr, false, err.Error())
+			continue
+		}
+		add("answer matches "+expr, re.MatchString(o.Answer), quote(o.Answer))
+	}
+
+	passed := 0
+	for _, c := range r.Checks {
+		if c.Pass {
+			passed++
+		}
+	}
+	r.Score = float64(passed) / float64(len(r.Checks))
+	r.Pass = passed == len(r.Checks)
+	return r
+}
+
+// calledTool returns the first called tool matching pattern, or ""
+func calledTool(called []string, pattern string) string {
+	for _, name := range called {
+		if ok, _ := path.Match(pattern, name); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// quote shortens an answer for a failed check's detail
+func quote(answer string) string {
+	answer = strings.Join(strings.Fields(answer), " ")
+	if r := []rune(answer); len(r) > 120 {
+		answer = string(r[:120]) + "..."
+	}
+	return fmt.Sprintf("answer was %q", answer)
+}
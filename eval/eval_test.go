@@ -0,0 +1,237 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rathore/langchain-agent/agent"
+	"github.com/rathore/langchain-agent/llm"
+	"github.com/rathore/langchain-agent/replay"
+	"github.com/rathore/langchain-agent/tools"
+)
+
+const testSuite = `
+name: ops
+tasks:
+  - name: disk
+    query: how much disk space is left?
+    tools: [shell]
+    not_tools: [ssh]
+    max_tool_calls: 1
+    answer:
+      contains: ["42%"]
+      matches: ['\d+% used']
+    weight: 2
+  - query: what is a container?
+    max_tool_calls: 0
+    answer:
+      not_contains: ["error"]
+`
+
+func loadTestSuite(t *testing.T, data string) (*Suite, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tasks.yaml")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path)
+}
+
+func TestLoad(t *testing.T) {
+	s, err := loadTestSuite(t, testSuite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "ops" || len(s.Tasks) != 2 || s.Tasks[1].Name != "task 2" || s.Tasks[1].Weight != 1 {
+		t.Errorf("suite = %+v", s)
+	}
+	if s.Tasks[1].MaxToolCalls == nil || *s.Tasks[1].MaxToolCalls != 0 {
+		t.Errorf("max_tool_calls: 0 should be kept")
+	}
+
+	for _, tt := range []struct{ data, wantErr string }{
+		{"tasks: []", "has no tasks"},
+		{"tasks:\n  - name: a", "query is required"},
+		{"tasks:\n  - {name: a, query: x}\n  - {name: a, query: y}", `duplicate task "a"`},
+		{"tasks:\n  - query: x\n    answer:\n      matches: ['(']", "invalid answer pattern"},
+		{"tasks:\n  - query: x\n    tools: ['[a-']", "invalid tool pattern"},
+	} {
+		if _, err := loadTestSuite(t, tt.data); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Load(%q) error = %v, want %q", tt.data, err, tt.wantErr)
+		}
+	}
+}
+
+func TestTask_Check(t *testing.T) {
+	s, err := loadTestSuite(t, testSuite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	disk := &s.Tasks[0]
+
+	r := disk.Check(Outcome{Answer: "The disk is 42% used.", Tools: []string{"shell"}})
+	if !r.Pass || r.Score != 1 || len(r.Checks) != 6 {
+		t.Errorf("passing run = %+v", r)
+	}
+
+	r = disk.Check(Outcome{Answer: "It is 42 percent full.", Tools: []string{"ssh", "shell"}})
+	if r.Pass || r.Score != 2.0/6 {
+		t.Errorf("failing run score = %v, pass = %v", r.Score, r.Pass)
+	}
+	var failed []string
+	for _, c := range r.Checks {
+		if !c.Pass {
+			failed = append(failed, c.Name+": "+c.Detail)
+		}
+	}
+	want := []string{
+		"doesn't call ssh: called ssh",
+		"at most 1 tool calls: 2 tool calls",
+		`answer contains "42%": answer was "It is 42 percent full."`,
+		`answer matches \d+% used: answer was "It is 42 percent full."`,
+	}
+	if strings.Join(failed, "\n") != strings.Join(want, "\n") {
+		t.Errorf("failed checks:\n%s\nwant:\n%s", strings.Join(failed, "\n"), strings.Join(want, "\n"))
+	}
+
+	r = disk.Check(Outcome{Error: "agent iteration 0: connection refused"})
+	if r.Pass || r.Checks[0].Name != "completed" || r.Checks[0].Pass {
+		t.Errorf("errored run = %+v", r)
+	}
+}
+
+// scriptedClient answers with canned responses
+type scriptedClient struct{ responses []string }
+
+func (c *scriptedClient) Chat(ctx context.Context, messages []llm.Message) (*llm.Response, error) {
+	resp := llm.ParseResponse(c.responses[0])
+	c.responses = c.responses[1:]
+	return resp, nil
+}
+
+// dfTool stands in for the shell
+type dfTool struct{}
+
+func (dfTool) Name() string               { return "shell" }
+func (dfTool) Description() string        { return "Run a command" }
+func (dfTool) Parameters() map[string]any { return map[string]any{"type": "object"} }
+func (dfTool) Call(ctx context.Context, params map[string]any) (string, error) {
+	return "/dev/sda1 100G 58G 42G 58% /", nil
+}
+
+func TestRunAndReplay(t *testing.T) {
+	s, err := loadTestSuite(t, testSuite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := replay.NewRecorder(&scriptedClient{responses: []string{
+		`{"name": "shell", "parameters": {"command": "df -h /"}}`,
+		"The disk is 58% used.",
+		"A container is an isolated process.",
+	}})
+	registry := tools.NewRegistry()
+	registry.Register(dfTool{}, tools.Meta{Category: tools.CategoryLocal})
+	ag, err := agent.New(agent.Config{Client: rec, Registry: registry})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var progress bytes.Buffer
+	report, err := Run(context.Background(), ag, s, &progress, rec.AddRun)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(progress.String(), "[Eval 2/2] task 2: what is a container?") {
+		t.Errorf("progress = %q", progress.String())
+	}
+	// disk: 5 of 6 checks (the answer says 58%, not 42%), weight 2; task 2 passes
+	if report.Passed != 1 || len(report.Tasks) != 2 || math.Abs(report.Score-(2*5.0/6+1)/3) > 1e-9 {
+		t.Errorf("report = %+v", report)
+	}
+
+	// The recorded runs replay to the same scores
+	replayed, err := Replay(context.Background(), s, rec.Cassette(registry), agent.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed.Passed != report.Passed || replayed.Score != report.Score {
+		t.Errorf("replayed report = %+v, want the live scores", replayed)
+	}
+
+	// A task that wasn't recorded fails
+	s.Tasks = append(s.Tasks, Task{Name: "uptime", Query: "how long has it been up?", Weight: 1})
+	replayed, err = Replay(context.Background(), s, rec.Cassette(registry), agent.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := replayed.Tasks[2]; last.Pass || last.Error != "no recorded run of this query" {
+		t.Errorf("unrecorded task = %+v", last)
+	}
+}
+
+func TestReport(t *testing.T) {
+	base := &Report{Suite: "ops", Model: "llama3.1"}
+	base.Add(TaskResult{Name: "disk", Pass: true, Score: 1, Weight: 1})
+	base.Add(TaskResult{Name: "pods", Score: 0.5, Weight: 1})
+	base.Add(TaskResult{Name: "temp", Score: 0.5, Weight: 1})
+	now := &Report{Suite: "ops", Model: "qwen2.5:32b"}
+	now.Add(TaskResult{Name: "disk", Score: 0.5, Weight: 1, Checks: []CheckResult{{Name: "calls shell", Detail: "no tools called"}}})
+	now.Add(TaskResult{Name: "pods", Pass: true, Score: 1, Weight: 1})
+	now.Add(TaskResult{Name: "gpio", Score: 0.75, Weight: 1})
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := base.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadReport(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Passed != 1 || loaded.Score != 2.0/3 {
+		t.Errorf("loaded report = %+v", loaded)
+	}
+
+	var b strings.Builder
+	now.WriteText(&b)
+	for _, s := range []string{"✗ disk (score 0.50", "    ✗ calls shell: no tools called", "✓ pods", "Passed 1/3 tasks, score 0.75"} {
+		if !strings.Contains(b.String(), s) {
+			t.Errorf("report missing %q:\n%s", s, b.String())
+		}
+	}
+
+	b.Reset()
+	Compare(&b, loaded, now)
+	for _, s := range []string{"llama3.1 (baseline) vs qwen2.5:32b", "regressed", "fixed", "new", "removed", "score 0.67 → 0.75 (+0.08)"} {
+		if !strings.Contains(b.String(), s) {
+			t.Errorf("comparison missing %q:\n%s", s, b.String())
+		}
+	}
+}
+
+func TestReport_WriteJUnit(t *testing.T) {
+	r := &Report{Suite: "ops", Model: "llama3.1"}
+	r.Add(TaskResult{Name: "disk", Pass: true, Score: 1, Weight: 1, Answer: "42% used"})
+	r.Add(TaskResult{Name: "pods", Score: 0.5, Weight: 1, Checks: []CheckResult{{Name: "calls kubectl", Detail: "no tools called"}}})
+	r.Add(TaskResult{Name: "gpio", Query: "read pin 4", Weight: 1, Error: "timeout"})
+
+	var b strings.Builder
+	if err := r.WriteJUnit(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`<testsuite name="ops" tests="3" failures="1" errors="1"`,
+		`<property name="model" value="llama3.1"></property>`,
+		`<system-out>42% used</system-out>`,
+		`<failure message="score 0.50">calls kubectl: no tools called</failure>`,
+		`<error message="timeout">read pin 4</error>`,
+	} {
+		if !strings.Contains(b.String(), s) {
+			t.Errorf("JUnit report missing %q:\n%s", s, b.String())
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rathore/langchain-agent/agent"
+	"github.com/rathore/langchain-agent/llm"
+	"github.com/rathore/langchain-agent/replay"
+)
+
+// Report is how a model did on a suite. Score is the mean of the task
+// scores, weighted by task weight.
+type Report struct {
+	Suite   string        `json:"suite"`
+	Model   string        `json:"model"`
+	Tasks   []TaskResult  `json:"tasks"`
+	Passed  int           `json:"passed"`
+	Score   float64       `json:"score"`
+	Usage   llm.Usage     `json:"usage"`
+	Elapsed time.Duration `json:"elapsed_ns"`
+}
+
+// Add adds a task's result to the report and its totals
+func (r *Report) Add(t TaskResult) {
+	r.Tasks = append(r.Tasks, t)
+	if t.Pass {
+		r.Passed++
+	}
+	r.Usage.Add(t.Usage)
+	r.Elapsed += t.Elapsed
+	var sum, weights float64
+	for _, t := range r.Tasks {
+		sum += t.Score * t.Weight
+		weights += t.Weight
+	}
+	r.Score = 0
+	if weights > 0 {
+		r.Score = sum / weights
+	}
+}
+
+// Runner runs queries; *agent.Agent is one
+type Runner interface {
+	ClearHistory()
+	RunDetailed(ctx context.Context, query string) (*agent.RunResult, error)
+}
+
+// Run runs each task of the suite in a fresh conversation and scores it,
+// writing progress to out. Each run is passed to record, if not nil. If ctx
+// is cancelled, Run returns the tasks finished so far with ctx's error.
+func Run(ctx context.Context, ag Runner, s *Suite, out io.Writer, record func(*agent.RunResult, error)) (*Report, error) {
+	report := &Report{Suite: s.Name}
+	for i := range s.Tasks {
+		task := &s.Tasks[i]
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+		fmt.Fprintf(out, "\n[Eval %d/%d] %s: %s\n", i+1, len(s.Tasks), task.Name, task.Query)
+		ag.ClearHistory()
+		result, err := ag.RunDetailed(ctx, task.Query)
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+		if record != nil {
+			record(result, err)
+		}
+		report.Add(task.Check(FromResult(result, err)))
+	}
+	return report, nil
+}
+
+// Replay scores the suite on a cassette recorded from a run of it (see
+// replay.Replay for cfg): each task is checked against the replayed run of
+// its query, so a prompt or parser change can be scored without the model.
+// A task whose query wasn't recorded fails.
+func Replay(ctx context.Context, s *Suite, c *replay.Cassette, cfg agent.Config) (*Report, error) {
+	got, err := replay.Replay(ctx, c, cfg)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{Suite: s.Name}
+	used := make([]bool, len(got.Runs))
+	for i := range s.Tasks {
+		task := &s.Tasks[i]
+		o := Outcome{Error: "no recorded run of this query"}
+		for j, run := range got.Runs {
+			if !used[j] && run.Query == task.Query {
+				used[j] = true
+				o = FromReplay(run)
+				break
+			}
+		}
+		report.Add(task.Check(o))
+	}
+	return report, nil
+}
+
+// LoadReport reads a report saved with Save
+func LoadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read eval report: %w", err)
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse eval report %s: %w", path, err)
+	}
+	return &r, nil
+}
+
+// Save writes the report as indented JSON
+func (r *Report) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode eval report: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write eval report: %w", err)
+	}
+	return nil
+}
+
+// WriteText writes each task's result, with the checks that failed, and
+// the totals
+func (r *Report) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "Suite %s, model %s\n", r.Suite, r.Model)
+	for _, t := range r.Tasks {
+		fmt.Fprintf(w, "%s %s (score %.2f, %s, %d tokens)\n", mark(t.Pass), t.Name, t.Score, seconds(t.Elapsed), t.Usage.TotalTokens)
+		for _, c := range t.Checks {
+			if !c.Pass {
+				fmt.Fprintf(w, "    ✗ %s: %s\n", c.Name, c.Detail)
+			}
+		}
+	}
+	fmt.Fprintf(w, "\nPassed %d/%d tasks, score %.2f (%s, %d tokens)\n", r.Passed, len(r.Tasks), r.Score, seconds(r.Elapsed), r.Usage.TotalTokens)
+}
+
+// junitSuite is the JUnit XML of a report, which CI systems show as a
+// test suite with a test case per task
+type junitSuite struct {
+	XMLName  xml.Name    `xml:"testsuite"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Time     string      `xml:"time,attr"`
+	Props    []junitProp `xml:"properties>property"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitProp struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure"`
+	Error     *junitMessage `xml:"error"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as JUnit XML: a test case per task, failed
+// by its failed checks or errored by a run error, with the answer as its
+// output
+func (r *Report) WriteJUnit(w io.Writer) error {
+	suite := junitSuite{
+		Name:  r.Suite,
+		Tests: len(r.Tasks),
+		Time:  fmt.Sprintf("%.3f", r.Elapsed.Seconds()),
+		Props: []junitProp{
+			{Name: "model", Value: r.Model},
+			{Name: "score", Value: fmt.Sprintf("%.2f", r.Score)},
+			{Name: "tokens", Value: fmt.Sprint(r.Usage.TotalTokens)},
+		},
+	}
+	for _, t := range r.Tasks {
+		c := junitCase{Name: t.Name, Classname: r.Suite, Time: fmt.Sprintf("%.3f", t.Elapsed.Seconds()), SystemOut: t.Answer}
+		switch {
+		case t.Error != "":
+			suite.Errors++
+			c.Error = &junitMessage{Message: t.Error, Text: t.Query}
+		case !t.Pass:
+			suite.Failures++
+			var failed []string
+			for _, check := range t.Checks {
+				if !check.Pass {
+					failed = append(failed, fmt.Sprintf("%s: %s", check.Name, check.Detail))
+				}
+			}
+			c.Failure = &junitMessage{Message: fmt.Sprintf("score %.2f", t.Score), Text: strings.Join(failed, "\n")}
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suite); err != nil {
+		return fmt.Errorf("failed to write JUnit report: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// Compare writes a table of each task's result in base and in r, for
+// comparing two models or prompts on the same suite. Tasks that passed in
+// base but fail in r are marked as regressions.
+func Compare(w io.Writer, base, r *Report) {
+	fmt.Fprintf(w, "Suite %s: %s (baseline) vs %s\n", r.Suite, base.Model, r.Model)
+	baseTasks := map[string]TaskResult{}
+	for _, t := range base.Tasks {
+		baseTasks[t.Name] = t
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "task\tbaseline\tnow\t")
+	seen := map[string]bool{}
+	for _, t := range r.Tasks {
+		seen[t.Name] = true
+		b, ok := baseTasks[t.Name]
+		if !ok {
+			fmt.Fprintf(tw, "%s\t-\t%s\tnew\n", t.Name, result(t))
+			continue
+		}
+		note := ""
+		switch {
+		case b.Pass && !t.Pass:
+			note = "regressed"
+		case !b.Pass && t.Pass:
+			note = "fixed"
+		case t.Score != b.Score:
+			note = fmt.Sprintf("%+.2f", t.Score-b.Score)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", t.Name, result(b), result(t), note)
+	}
+	for _, b := range base.Tasks {
+		if !seen[b.Name] {
+			fmt.Fprintf(tw, "%s\t%s\t-\tremoved\n", b.Name, result(b))
+		}
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\nPassed %d/%d → %d/%d, score %.2f → %.2f (%+.2f), %s → %s, %d → %d tokens\n",
+		base.Passed, len(base.Tasks), r.Passed, len(r.Tasks), base.Score, r.Score, r.Score-base.Score,
+		seconds(base.Elapsed), seconds(r.Elapsed), base.Usage.TotalTokens, r.Usage.TotalTokens)
+}
+
+func result(t TaskResult) string {
+	return fmt.Sprintf("%s %.2f", mark(t.Pass), t.Score)
+}
+
+func mark(pass bool) string {
+	if pass {
+		return "✓"
+	}
+	return "✗"
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.1fs", d.Seconds())
+}
@@ -0,0 +1,6 @@
+// Package format renders the result of an agent run for where it is going:
+// JSON for programs, Markdown or plain text for people and files, and Slack
+// Block Kit messages for chat. The CLI's --output, the webhook's "format"
+// field and the triggers' post_to all pick a formatter by name, so
+// integrations don't each re-implement the conversion.
+package format
@@ -0,0 +1,224 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rathore/langchain-agent/agent"
+)
+
+// Result is a finished run to format. Its JSON is the --output json record
+// of a query; RunResult is nil if the run failed before it started.
+type Result struct {
+	ID string `json:"id,omitempty"` // e.g. the --batch query id
+	*agent.RunResult
+	Error string `json:"error,omitempty"`
+	// Title heads the formatted answer where the format has headings
+	// (Markdown, Slack); ID is used when it's empty
+	Title string `json:"-"`
+}
+
+// title is the heading of r, if any
+func (r Result) title() string {
+	if r.Title != "" {
+		return r.Title
+	}
+	return r.ID
+}
+
+// answer is the answer of r, if any
+func (r Result) answer() string {
+	if r.RunResult == nil {
+		return ""
+	}
+	return r.Answer
+}
+
+// Formatter writes a run result in one format
+type Formatter interface {
+	Format(w io.Writer, r Result) error
+	// ContentType is the MIME type of the output, for HTTP
+	ContentType() string
+}
+
+var (
+	mu         sync.RWMutex
+	formatters = map[string]Formatter{
+		"json":     JSON{},
+		"markdown": Markdown{},
+		"plain":    Plain{},
+		"slack":    Slack{},
+	}
+)
+
+// Register makes f available by name, replacing any formatter of that name
+func Register(name string, f Formatter) {
+	mu.Lock()
+	defer mu.Unlock()
+	formatters[name] = f
+}
+
+// Get returns the formatter registered as name
+func Get(name string) (Formatter, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	f, ok := formatters[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q (use %s)", name, strings.Join(names(), ", "))
+	}
+	return f, nil
+}
+
+// Names lists the registered formats, sorted
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return names()
+}
+
+func names() []string {
+	list := make([]string, 0, len(formatters))
+	for name := range formatters {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// Encoder writes a stream of results in one format. JSON formats get one
+// line per result; text formats are separated by a blank line.
+type Encoder struct {
+	w       io.Writer
+	f       Formatter
+	written bool
+}
+
+// NewEncoder returns an encoder writing to w with f
+func NewEncoder(w io.Writer, f Formatter) *Encoder {
+	return &Encoder{w: w, f: f}
+}
+
+// Encode writes r
+func (e *Encoder) Encode(r Result) error {
+	if e.written && !strings.Contains(e.f.ContentType(), "json") {
+		if _, err := io.WriteString(e.w, "\n"); err != nil {
+			return err
+		}
+	}
+	e.written = true
+	return e.f.Format(e.w, r)
+}
+
+// JSON writes the result as one line of JSON: the run's query, answer,
+// steps, usage and cost
+type JSON struct{}
+
+func (JSON) Format(w io.Writer, r Result) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+func (JSON) ContentType() string { return "application/json" }
+
+// Markdown writes the answer as the model wrote it, under a heading if the
+// result has a title
+type Markdown struct{}
+
+func (Markdown) Format(w io.Writer, r Result) error {
+	var sb strings.Builder
+	if t := r.title(); t != "" {
+		fmt.Fprintf(&sb, "## %s\n\n", t)
+	}
+	if r.Error != "" {
+		fmt.Fprintf(&sb, "**Error:** %s\n", r.Error)
+	} else {
+		sb.WriteString(strings.TrimRight(r.answer(), "\n") + "\n")
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func (Markdown) ContentType() string { return "text/markdown; charset=utf-8" }
+
+// Plain writes the answer with its Markdown removed, for logs, emails and
+// terminals that don't render it
+type Plain struct{}
+
+func (Plain) Format(w io.Writer, r Result) error {
+	var sb strings.Builder
+	if t := r.title(); t != "" {
+		fmt.Fprintf(&sb, "[%s]\n", t)
+	}
+	if r.Error != "" {
+		fmt.Fprintf(&sb, "Error: %s\n", r.Error)
+	} else {
+		sb.WriteString(strings.TrimRight(StripMarkdown(r.answer()), "\n") + "\n")
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func (Plain) ContentType() string { return "text/plain; charset=utf-8" }
+
+var (
+	headingRe  = regexp.MustCompile(`^\s*#{1,6}\s+(.*?)\s*#*\s*$`)
+	bulletRe   = regexp.MustCompile(`^(\s*)[-*+]\s+`)
+	quoteRe    = regexp.MustCompile(`^\s*>\s?`)
+	ruleRe     = regexp.MustCompile(`^\s*(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	tableSepRe = regexp.MustCompile(`^\|?(\s*:?-+:?\s*\|)*\s*:?-+:?\s*\|?$`)
+	boldRe     = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	italicRe   = regexp.MustCompile(`(^|[^*\w])\*([^*\s][^*]*)\*|(^|[^_\w])_([^_\s][^_]*)_`)
+	linkRe     = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	codeSpanRe = regexp.MustCompile("`([^`]*)`")
+)
+
+// StripMarkdown turns Markdown into plain text: headings, emphasis, code
+// fences and table separators are dropped, bullets become •, and links
+// become "text (url)". Code blocks are kept as they are.
+func StripMarkdown(md string) string {
+	lines := strings.Split(md, "\n")
+	out := make([]string, 0, len(lines))
+	inCode := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			out = append(out, line)
+			continue
+		}
+		switch {
+		case tableSepRe.MatchString(trimmed) && strings.Contains(trimmed, "|"):
+			continue
+		case ruleRe.MatchString(line):
+			out = append(out, "")
+			continue
+		case headingRe.MatchString(line):
+			line = headingRe.ReplaceAllString(line, "$1")
+		case strings.HasPrefix(trimmed, "|"):
+			cells := strings.Split(strings.Trim(trimmed, "|"), "|")
+			for i, c := range cells {
+				cells[i] = strings.TrimSpace(c)
+			}
+			line = strings.Join(cells, "  ")
+		}
+		line = quoteRe.ReplaceAllString(line, "")
+		line = bulletRe.ReplaceAllString(line, "$1• ")
+		out = append(out, stripInline(line))
+	}
+	return strings.Join(out, "\n")
+}
+
+// stripInline drops the inline Markdown of a line
+func stripInline(s string) string {
+	s = linkRe.ReplaceAllString(s, "$1 ($2)")
+	s = boldRe.ReplaceAllString(s, "$1$2")
+	s = italicRe.ReplaceAllString(s, "$1$3$2$4")
+	return codeSpanRe.ReplaceAllString(s, "$1")
+}
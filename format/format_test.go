@@ -0,0 +1,159 @@
+package format
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rathore/langchain-agent/agent"
+)
+
+const answer = "## Disk usage\n\n**/var** is *91%* full, see [the runbook](https://wiki/disk).\n\n- clean `/var/log`\n- resize\n\n| fs | used |\n|----|------|\n| /var | 91% |\n\n```sh\ndu -sh /var/* | sort -h\n```"
+
+func result() Result {
+	return Result{ID: "q1", RunResult: &agent.RunResult{
+		Query:  "why is the disk full?",
+		Answer: answer,
+		Steps: []agent.Step{
+			{ToolCall: &agent.ToolCall{Name: "shell"}},
+			{ToolCall: &agent.ToolCall{Name: "shell"}},
+			{ToolCall: &agent.ToolCall{Name: "wiki_search"}},
+		},
+		Elapsed: 3210 * time.Millisecond,
+	}}
+}
+
+func TestGet(t *testing.T) {
+	for _, name := range []string{"json", "markdown", "plain", "slack"} {
+		if _, err := Get(name); err != nil {
+			t.Errorf("Get(%q) error = %v", name, err)
+		}
+	}
+	if _, err := Get("html"); err == nil || !strings.Contains(err.Error(), "json, markdown, plain, slack") {
+		t.Errorf("Get(html) error = %v, want the known formats", err)
+	}
+
+	Register("upper", upper{})
+	f, err := Get("upper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	f.Format(&sb, Result{RunResult: &agent.RunResult{Answer: "ok"}})
+	if sb.String() != "OK" {
+		t.Errorf("registered formatter wrote %q", sb.String())
+	}
+}
+
+type upper struct{}
+
+func (upper) Format(w io.Writer, r Result) error {
+	_, err := io.WriteString(w, strings.ToUpper(r.Answer))
+	return err
+}
+
+func (upper) ContentType() string { return "text/plain" }
+
+func TestJSON(t *testing.T) {
+	var sb strings.Builder
+	enc := NewEncoder(&sb, JSON{})
+	enc.Encode(result())
+	enc.Encode(Result{ID: "q2", Error: "timeout", Title: "ignored"})
+	lines := strings.Split(strings.TrimSpace(sb.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("JSON wrote %d lines:\n%s", len(lines), sb.String())
+	}
+	var got struct {
+		ID     string `json:"id"`
+		Query  string `json:"query"`
+		Answer string `json:"answer"`
+		Error  string `json:"error"`
+	}
+	json.Unmarshal([]byte(lines[0]), &got)
+	if got.ID != "q1" || got.Query != "why is the disk full?" || got.Answer != answer {
+		t.Errorf("first record = %+v", got)
+	}
+	if lines[1] != `{"id":"q2","error":"timeout"}` {
+		t.Errorf("failed record = %s", lines[1])
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	var sb strings.Builder
+	enc := NewEncoder(&sb, Markdown{})
+	enc.Encode(result())
+	enc.Encode(Result{Error: "timeout"})
+	want := "## q1\n\n" + answer + "\n\n**Error:** timeout\n"
+	if sb.String() != want {
+		t.Errorf("Markdown wrote %q, want %q", sb.String(), want)
+	}
+}
+
+func TestPlain(t *testing.T) {
+	var sb strings.Builder
+	Plain{}.Format(&sb, result())
+	want := "[q1]\nDisk usage\n\n/var is 91% full, see the runbook (https://wiki/disk).\n\n• clean /var/log\n• resize\n\nfs  used\n/var  91%\n\ndu -sh /var/* | sort -h\n"
+	if sb.String() != want {
+		t.Errorf("Plain wrote %q, want %q", sb.String(), want)
+	}
+}
+
+func TestSlack(t *testing.T) {
+	r := result()
+	r.Title = "Triage: DiskFull on db-1"
+	var sb strings.Builder
+	if err := (Slack{}).Format(&sb, r); err != nil {
+		t.Fatal(err)
+	}
+	var msg slackMessage
+	if err := json.Unmarshal([]byte(sb.String()), &msg); err != nil {
+		t.Fatalf("invalid JSON %s: %v", sb.String(), err)
+	}
+	if len(msg.Blocks) != 3 || msg.Blocks[0].Type != "header" || msg.Blocks[0].Text.Text != r.Title {
+		t.Fatalf("blocks = %s", sb.String())
+	}
+	wantSection := "*Disk usage*\n\n*/var* is _91%_ full, see <https://wiki/disk|the runbook>.\n\n• clean `/var/log`\n• resize\n\n```\n| fs | used |\n| /var | 91% |\n```\n\n```\ndu -sh /var/* | sort -h\n```"
+	if got := msg.Blocks[1].Text.Text; got != wantSection {
+		t.Errorf("section = %q, want %q", got, wantSection)
+	}
+	if got := msg.Blocks[2].Elements[0].Text; got != "Tools: `shell`, `wiki_search` · 3.2s" {
+		t.Errorf("context = %q", got)
+	}
+	if !strings.HasPrefix(msg.Text, "Disk usage\n") {
+		t.Errorf("fallback text = %q", msg.Text)
+	}
+
+	sb.Reset()
+	(Slack{}).Format(&sb, Result{Error: "a <b> & c"})
+	if want := `{"text":"Error: a <b> & c","blocks":[{"type":"section","text":{"type":"mrkdwn","text":":warning: *Error:* a &lt;b&gt; &amp; c"}}]}` + "\n"; sb.String() != want {
+		t.Errorf("error message = %s", sb.String())
+	}
+}
+
+func TestSplit(t *testing.T) {
+	text := "intro\n```\n" + strings.Repeat("line of code\n", 40) + "```\nend"
+	chunks := split(text, 100)
+	if len(chunks) < 5 {
+		t.Fatalf("split into %d chunks", len(chunks))
+	}
+	for i, c := range chunks {
+		if len(c) > 100 {
+			t.Errorf("chunk %d is %d bytes", i, len(c))
+		}
+		if strings.Count(c, "```")%2 != 0 {
+			t.Errorf("chunk %d has unbalanced fences: %q", i, c)
+		}
+	}
+	if joined := strings.Join(chunks, "\n"); !strings.Contains(joined, "intro") || !strings.HasSuffix(joined, "end") {
+		t.Errorf("split lost text: %q", joined)
+	}
+
+	long := split(strings.Repeat("é", 200), 100)
+	for i, c := range long {
+		if len(c) > 100 || !strings.HasPrefix(c, "é") {
+			t.Errorf("chunk %d = %q", i, c)
+		}
+	}
+}
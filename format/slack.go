@@ -0,0 +1,236 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Slack limits (https://api.slack.com/reference/block-kit/blocks)
+const (
+	slackHeaderMax  = 150
+	slackSectionMax = 3000
+	slackBlocksMax  = 50
+)
+
+// Slack writes the result as one line of Slack Block Kit JSON, ready to
+// post to an incoming webhook or chat.postMessage: a header with the
+// title, the answer in mrkdwn sections and a context line with the tools
+// called and the run time. "text" holds the plain answer for
+// notifications.
+type Slack struct{}
+
+type slackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type     string       `json:"type"`
+	Text     *slackText   `json:"text,omitempty"`
+	Elements []*slackText `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func (Slack) Format(w io.Writer, r Result) error {
+	var msg slackMessage
+	if t := r.title(); t != "" {
+		msg.Blocks = append(msg.Blocks, slackBlock{Type: "header", Text: &slackText{Type: "plain_text", Text: truncate(t, slackHeaderMax)}})
+	}
+	if r.Error != "" {
+		msg.Text = "Error: " + r.Error
+		msg.Blocks = append(msg.Blocks, section(":warning: *Error:* "+escape(r.Error)))
+	} else {
+		msg.Text = truncate(StripMarkdown(r.answer()), slackSectionMax)
+		for _, chunk := range split(Mrkdwn(r.answer()), slackSectionMax) {
+			msg.Blocks = append(msg.Blocks, section(chunk))
+		}
+	}
+	if r.RunResult != nil {
+		var notes []string
+		var tools []string
+		seen := map[string]bool{}
+		for _, s := range r.Steps {
+			if s.ToolCall != nil && !seen[s.ToolCall.Name] {
+				seen[s.ToolCall.Name] = true
+				tools = append(tools, "`"+s.ToolCall.Name+"`")
+			}
+		}
+		if len(tools) > 0 {
+			notes = append(notes, "Tools: "+strings.Join(tools, ", "))
+		}
+		if r.Elapsed > 0 {
+			notes = append(notes, r.Elapsed.Round(100*time.Millisecond).String())
+		}
+		if len(notes) > 0 {
+			msg.Blocks = append(msg.Blocks, slackBlock{Type: "context", Elements: []*slackText{{Type: "mrkdwn", Text: strings.Join(notes, " · ")}}})
+		}
+	}
+	if len(msg.Blocks) > slackBlocksMax {
+		msg.Blocks = append(msg.Blocks[:slackBlocksMax-1], section("…"))
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(msg); err != nil {
+		return fmt.Errorf("failed to write Slack message: %w", err)
+	}
+	return nil
+}
+
+func (Slack) ContentType() string { return "application/json" }
+
+func section(text string) slackBlock {
+	return slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: text}}
+}
+
+var (
+	slackBoldRe   = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	slackItalicRe = regexp.MustCompile(`(^|[^*\w])\*([^*\s][^*]*)\*`)
+	slackStrikeRe = regexp.MustCompile(`~~([^~]+)~~`)
+)
+
+// Mrkdwn converts Markdown to Slack's mrkdwn: **bold** becomes *bold*,
+// *italic* _italic_, headings bold lines, bullets •, links <url|text> and
+// tables code blocks, since Slack has no tables. Code is left alone but
+// for escaping &, < and >.
+func Mrkdwn(md string) string {
+	lines := strings.Split(md, "\n")
+	out := make([]string, 0, len(lines))
+	inCode, inTable := false, false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		isTable := !inCode && strings.HasPrefix(trimmed, "|")
+		if isTable != inTable {
+			out = append(out, "```")
+			inTable = isTable
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inCode = !inCode
+			out = append(out, "```") // Slack ignores the language
+			continue
+		}
+		if inCode || inTable {
+			if !(inTable && tableSepRe.MatchString(trimmed)) {
+				out = append(out, escape(line))
+			}
+			continue
+		}
+		if m := headingRe.FindStringSubmatch(line); m != nil {
+			out = append(out, "*"+slackInline(stripBold(m[1]))+"*")
+			continue
+		}
+		if ruleRe.MatchString(line) {
+			out = append(out, "───")
+			continue
+		}
+		line = bulletRe.ReplaceAllString(line, "$1• ")
+		out = append(out, slackInline(line))
+	}
+	if inTable {
+		out = append(out, "```")
+	}
+	return strings.Join(out, "\n")
+}
+
+// slackInline converts the inline Markdown of a line outside code spans
+func slackInline(s string) string {
+	parts := strings.Split(s, "`")
+	if len(parts)%2 == 0 { // unbalanced backtick: not code
+		parts = []string{s}
+	}
+	for i := range parts {
+		if i%2 == 1 {
+			parts[i] = escape(parts[i])
+			continue
+		}
+		p := escape(parts[i])
+		p = linkRe.ReplaceAllString(p, "<$2|$1>")
+		// Italic first, so the bold it produces isn't read as italic
+		p = slackItalicRe.ReplaceAllString(p, "${1}_${2}_")
+		p = slackBoldRe.ReplaceAllString(p, "*$1$2*")
+		parts[i] = slackStrikeRe.ReplaceAllString(p, "~$1~")
+	}
+	return strings.Join(parts, "`")
+}
+
+// stripBold drops ** and __ from a heading, which is bold already
+func stripBold(s string) string {
+	return slackBoldRe.ReplaceAllString(s, "$1$2")
+}
+
+// escape escapes the characters Slack treats as markup
+func escape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// split breaks text into chunks of at most n bytes, at line ends where it
+// can, keeping code blocks balanced across chunks
+func split(text string, n int) []string {
+	var chunks []string
+	var cur strings.Builder
+	inCode := false
+	add := func(line string) {
+		if cur.Len() > 0 {
+			cur.WriteByte('\n')
+		}
+		cur.WriteString(line)
+	}
+	flush := func() {
+		if cur.Len() == 0 || cur.String() == "```" {
+			return
+		}
+		chunk := cur.String()
+		if inCode {
+			chunk += "\n```"
+		}
+		chunks = append(chunks, chunk)
+		cur.Reset()
+		if inCode {
+			cur.WriteString("```")
+		}
+	}
+	for _, line := range strings.Split(text, "\n") {
+		for len(line) > n-8 { // room for the code fences
+			cut := n - 8
+			for cut > 0 && !utf8.RuneStart(line[cut]) {
+				cut--
+			}
+			flush()
+			add(line[:cut])
+			flush()
+			line = line[cut:]
+		}
+		if cur.Len()+len(line)+1 > n-4 {
+			flush()
+		}
+		add(line)
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+		}
+	}
+	flush()
+	if len(chunks) == 0 {
+		chunks = []string{" "} // a section's text can't be empty
+	}
+	return chunks
+}
+
+// truncate shortens s to at most n bytes, ending with … if it was cut
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	cut := n - len("…")
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…"
+}
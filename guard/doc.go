@@ -0,0 +1,7 @@
+// Package guard defends the agent against prompt injection: instructions
+// planted in a wiki page, a file or a command's output that the model might
+// follow as if the user had written them. A Guard wraps tool results and
+// retrieved wiki text in delimited untrusted-content blocks, which the
+// system prompt tells the model never to obey, and can screen them for
+// instruction-like text to flag, remove or withhold.
+package guard
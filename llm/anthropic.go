@@ -0,0 +1,179 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/anthropic"
+)
+
+// AnthropicMaxTokens bounds each reply: the Messages API requires a limit
+const AnthropicMaxTokens = 4096
+
+// AnthropicClient wraps the Anthropic Messages API (Claude models) with the
+// ChatClient interface.
+type AnthropicClient struct {
+	llm   *anthropic.LLM
+	model string
+}
+
+// Ensure AnthropicClient implements all three interfaces.
+var _ ChatClient = (*AnthropicClient)(nil)
+var _ StreamingChatClient = (*AnthropicClient)(nil)
+var _ NativeToolClient = (*AnthropicClient)(nil)
+
+// NewAnthropicClient creates an Anthropic client. The API key defaults to
+// $ANTHROPIC_API_KEY; opts.BaseURL points it at a proxy or gateway.
+func NewAnthropicClient(opts Options) (*AnthropicClient, error) {
+	key := firstNonEmpty(opts.APIKey, os.Getenv("ANTHROPIC_API_KEY"))
+	if key == "" {
+		return nil, fmt.Errorf("failed to create anthropic client: $ANTHROPIC_API_KEY is not set")
+	}
+	anthropicOpts := []anthropic.Option{anthropic.WithModel(opts.Model), anthropic.WithToken(key)}
+	if opts.BaseURL != "" {
+		anthropicOpts = append(anthropicOpts, anthropic.WithBaseURL(opts.BaseURL))
+	}
+	llm, err := anthropic.New(anthropicOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create anthropic client: %w", err)
+	}
+	return &AnthropicClient{llm: llm, model: opts.Model}, nil
+}
+
+// anthropicMessages maps the conversation to the Messages API with
+// toolMessages: system messages become the system prompt (langchaingo moves
+// them), tool calls and results become tool_use and tool_result blocks. The
+// tools called are returned too, since the API rejects tool_use blocks for
+// undeclared tools; their full definitions are in the system prompt.
+func anthropicMessages(messages []Message) ([]llms.MessageContent, []llms.Tool) {
+	out, called := toolMessages(messages)
+	var tools []llms.Tool
+	for _, name := range called {
+		tools = append(tools, llms.Tool{
+			Type: "function",
+			Function: &llms.FunctionDefinition{
+				Name:        name,
+				Description: "See the tool list in the system prompt",
+				Parameters:  map[string]any{"type": "object"},
+			},
+		})
+	}
+	return out, tools
+}
+
+// anthropicResponse turns Claude's content blocks into a Response. A native
+// tool_use block is written back as the tool call JSON the agent keeps in
+// its history, so the next request maps it to tool_use again.
+func anthropicResponse(resp *llms.ContentResponse) (*Response, error) {
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no response from anthropic")
+	}
+	var text strings.Builder
+	var calls []ToolCallParse
+	for _, choice := range resp.Choices {
+		text.WriteString(choice.Content)
+		parsed, err := llmsToolCalls(choice.ToolCalls)
+		if err != nil {
+			return nil, fmt.Errorf("anthropic returned an invalid tool call: %w", err)
+		}
+		calls = append(calls, parsed...)
+	}
+	return nativeResponse(text.String(), calls, anthropicUsage(resp.Choices[0].GenerationInfo)), nil
+}
+
+// anthropicUsage reads the token counts langchaingo reports for Anthropic,
+// which uses its own InputTokens/OutputTokens keys
+func anthropicUsage(info map[string]any) Usage {
+	u := usageFrom(map[string]any{
+		"PromptTokens":     info["InputTokens"],
+		"CompletionTokens": info["OutputTokens"],
+	})
+	u.TotalTokens = u.PromptTokens + u.CompletionTokens
+	return u
+}
+
+// Chat sends messages to Claude and returns the response.
+func (c *AnthropicClient) Chat(ctx context.Context, messages []Message) (*Response, error) {
+	llmMessages, tools := anthropicMessages(messages)
+
+	opts := []llms.CallOption{llms.WithMaxTokens(AnthropicMaxTokens)}
+	if len(tools) > 0 {
+		opts = append(opts, llms.WithTools(tools))
+	}
+	resp, err := c.llm.GenerateContent(ctx, llmMessages, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("anthropic generate failed: %w", err)
+	}
+	return anthropicResponse(resp)
+}
+
+// ChatStream sends messages to Claude and streams text responses in real-time.
+func (c *AnthropicClient) ChatStream(ctx context.Context, messages []Message, streamFunc func(chunk string)) (*Response, error) {
+	llmMessages, tools := anthropicMessages(messages)
+
+	var buf strings.Builder
+	streaming := false
+	jsonMode := false
+
+	opts := []llms.CallOption{
+		llms.WithMaxTokens(AnthropicMaxTokens),
+		llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+			buf.Write(chunk)
+
+			if !streaming && !jsonMode {
+				trimmed := strings.TrimSpace(buf.String())
+				if len(trimmed) > 0 {
+					if trimmed[0] == '{' {
+						jsonMode = true
+					} else {
+						streaming = true
+						streamFunc(buf.String())
+					}
+				}
+			} else if streaming {
+				streamFunc(string(chunk))
+			}
+
+			return nil
+		}),
+	}
+	if len(tools) > 0 {
+		opts = append(opts, llms.WithTools(tools))
+	}
+	resp, err := c.llm.GenerateContent(ctx, llmMessages, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("anthropic generate failed: %w", err)
+	}
+	return anthropicResponse(resp)
+}
+
+// ChatWithTools sends messages to Claude with the tool definitions, so it
+// calls tools with tool_use blocks.
+func (c *AnthropicClient) ChatWithTools(ctx context.Context, messages []Message, tools []ToolDef, streamFunc func(chunk string)) (*Response, error) {
+	llmMessages, used := anthropicMessages(messages)
+
+	// Tools called earlier but no longer offered must still be declared
+	offered := llmsTools(tools)
+	for _, t := range used {
+		if !slices.ContainsFunc(tools, func(d ToolDef) bool { return d.Name == t.Function.Name }) {
+			offered = append(offered, t)
+		}
+	}
+	opts := []llms.CallOption{llms.WithMaxTokens(AnthropicMaxTokens), llms.WithTools(offered)}
+	if streamFunc != nil {
+		stream := replyStreamer(streamFunc)
+		opts = append(opts, llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+			stream(string(chunk))
+			return nil
+		}))
+	}
+	resp, err := c.llm.GenerateContent(ctx, llmMessages, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("anthropic generate failed: %w", err)
+	}
+	return anthropicResponse(resp)
+}
@@ -0,0 +1,113 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestAnthropicMessages(t *testing.T) {
+	msgs, tools := anthropicMessages([]Message{
+		{Role: "system", Content: "You are an agent."},
+		{Role: "user", Content: "uptime on web-1?"},
+		{Role: "assistant", Content: `{"name": "ssh", "parameters": {"host": "web-1", "command": "uptime"}}`},
+		{Role: "tool", Content: "Tool 'ssh' returned:\n up 3 days"},
+		{Role: "assistant", Content: "web-1 has been up 3 days."},
+		{Role: "tool", Content: "stray tool message"},
+	})
+	if len(msgs) != 6 {
+		t.Fatalf("got %d messages, want 6", len(msgs))
+	}
+	if msgs[0].Role != llms.ChatMessageTypeSystem || msgs[1].Role != llms.ChatMessageTypeHuman {
+		t.Errorf("roles = %s, %s", msgs[0].Role, msgs[1].Role)
+	}
+	call, ok := msgs[2].Parts[0].(llms.ToolCall)
+	if !ok || call.FunctionCall.Name != "ssh" || !strings.Contains(call.FunctionCall.Arguments, `"host":"web-1"`) {
+		t.Fatalf("assistant tool call = %#v", msgs[2].Parts[0])
+	}
+	result, ok := msgs[3].Parts[0].(llms.ToolCallResponse)
+	if !ok || result.ToolCallID != call.ID || !strings.Contains(result.Content, "up 3 days") {
+		t.Errorf("tool result = %#v, want the result of %s", msgs[3].Parts[0], call.ID)
+	}
+	if _, ok := msgs[4].Parts[0].(llms.TextContent); !ok || msgs[4].Role != llms.ChatMessageTypeAI {
+		t.Errorf("final answer = %#v", msgs[4])
+	}
+	// A tool message without a preceding call can't be a tool_result
+	if msgs[5].Role != llms.ChatMessageTypeHuman {
+		t.Errorf("stray tool message role = %s", msgs[5].Role)
+	}
+	if len(tools) != 1 || tools[0].Function.Name != "ssh" {
+		t.Errorf("tools = %+v, want ssh declared", tools)
+	}
+}
+
+func TestAnthropicClient_Chat(t *testing.T) {
+	var req map[string]any
+	var key string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			http.NotFound(w, r)
+			return
+		}
+		key = r.Header.Get("x-api-key")
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5","stop_reason":"tool_use",
+			"content":[{"type":"text","text":"Checking."},{"type":"tool_use","id":"toolu_9","name":"shell","input":{"command":"df -h"}}],
+			"usage":{"input_tokens":120,"output_tokens":30}}`))
+	}))
+	defer srv.Close()
+
+	c, err := NewClientFor("anthropic", Options{Model: "claude-sonnet-4-5", BaseURL: srv.URL + "/v1", APIKey: "sk-test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Chat(context.Background(), []Message{
+		{Role: "system", Content: "tools here"},
+		{Role: "user", Content: "uptime?"},
+		{Role: "assistant", Content: `{"name": "shell", "parameters": {"command": "uptime"}}`},
+		{Role: "tool", Content: "Tool 'shell' returned:\n up 1 day"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if key != "sk-test" || req["system"] != "tools here" || req["max_tokens"] != float64(AnthropicMaxTokens) {
+		t.Errorf("request key=%q system=%v max_tokens=%v", key, req["system"], req["max_tokens"])
+	}
+	messages, _ := req["messages"].([]any)
+	if len(messages) != 3 {
+		t.Fatalf("request messages = %v", req["messages"])
+	}
+	raw, _ := json.Marshal(messages[1:])
+	for _, want := range []string{`"type":"tool_use"`, `"type":"tool_result"`, `"tool_use_id":"call_2"`, `"id":"call_2"`} {
+		if !strings.Contains(string(raw), want) {
+			t.Errorf("request messages missing %s: %s", want, raw)
+		}
+	}
+	if tools, _ := req["tools"].([]any); len(tools) != 1 {
+		t.Errorf("request tools = %v, want shell declared", req["tools"])
+	}
+
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "shell" || resp.ToolCalls[0].Params["command"] != "df -h" {
+		t.Errorf("ToolCalls = %+v", resp.ToolCalls)
+	}
+	if again := ParseResponse(resp.Content); len(again.ToolCalls) != 1 || again.ToolCalls[0].Name != "shell" {
+		t.Errorf("Content = %q, want tool call JSON for the history", resp.Content)
+	}
+	if resp.Usage != (Usage{PromptTokens: 120, CompletionTokens: 30, TotalTokens: 150}) {
+		t.Errorf("Usage = %+v", resp.Usage)
+	}
+}
+
+func TestNewAnthropicClient_NeedsKey(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	if _, err := NewAnthropicClient(Options{Model: "claude-sonnet-4-5"}); err == nil || !strings.Contains(err.Error(), "ANTHROPIC_API_KEY") {
+		t.Errorf("err = %v", err)
+	}
+}
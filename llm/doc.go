@@ -0,0 +1,9 @@
+// Package llm holds the chat clients the agent talks to: Ollama (NewClient),
+// Gemini (NewGeminiClient), OpenAI-compatible APIs (NewOpenAIClient,
+// NewAzureOpenAIClient) and Anthropic (NewAnthropicClient). NewClientFor creates one by provider name; Register
+// adds providers. Any type implementing ChatClient can be used instead;
+// implementing StreamingChatClient as well streams tokens.
+//
+// The clients parse tool calls from the JSON the model writes in its reply,
+// following the instructions BuildSystemPrompt gives it.
+package llm
@@ -49,7 +49,9 @@ func (c *GeminiClient) Chat(ctx context.Context, messages []Message) (*Response,
 	}
 
 	content := resp.Choices[0].Content
-	return parseResponse(content), nil
+	r := ParseResponse(content)
+	r.Usage = usageFrom(resp.Choices[0].GenerationInfo)
+	return r, nil
 }
 
 // ChatStream sends messages to Gemini and streams text responses in real-time.
@@ -89,5 +91,7 @@ func (c *GeminiClient) ChatStream(ctx context.Context, messages []Message, strea
 	}
 
 	content := resp.Choices[0].Content
-	return parseResponse(content), nil
+	r := ParseResponse(content)
+	r.Usage = usageFrom(resp.Choices[0].GenerationInfo)
+	return r, nil
 }
@@ -0,0 +1,183 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// NativeToolClient is implemented by clients whose backend takes tool
+// definitions in the request and returns tool calls as structured data
+// (Ollama's /api/chat tools, OpenAI function calling), instead of the model
+// writing them as JSON in its reply.
+type NativeToolClient interface {
+	ChatClient
+
+	// ChatWithTools sends messages offering tools. A nil streamFunc doesn't
+	// stream. A tool call comes back in ToolCalls, with Content set to its
+	// JSON text form so the conversation history reads the same either
+	// way. Returns an error wrapping ErrToolsUnsupported when the model
+	// can't call tools, so the caller can fall back to Chat.
+	ChatWithTools(ctx context.Context, messages []Message, tools []ToolDef, streamFunc func(chunk string)) (*Response, error)
+}
+
+// toolNameRe matches the tool names the Anthropic and OpenAI APIs accept
+var toolNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// ErrToolsUnsupported means the model has no native tool calling
+var ErrToolsUnsupported = errors.New("model does not support native tool calling")
+
+// toolsUnsupported reports whether a backend error says the model can't
+// take tools (Ollama: "... does not support tools"; vLLM without
+// --enable-auto-tool-choice)
+func toolsUnsupported(msg string) bool {
+	msg = strings.ToLower(msg)
+	return strings.Contains(msg, "does not support tools") ||
+		strings.Contains(msg, "enable-auto-tool-choice") ||
+		strings.Contains(msg, "tools are not supported") ||
+		strings.Contains(msg, "tool calling is not supported")
+}
+
+// toolCallJSON is the text form of a tool call the system prompt asks for,
+// which the agent keeps in its history
+func toolCallJSON(call ToolCallParse) string {
+	params := call.Params
+	if params == nil {
+		params = map[string]any{}
+	}
+	data, _ := json.Marshal(map[string]any{"name": call.Name, "parameters": params})
+	return string(data)
+}
+
+// nativeResponse builds the Response to a native tool-calling request:
+// the first tool call if there is one, otherwise the text, which is still
+// parsed in case the model wrote its call as JSON anyway
+func nativeResponse(content string, calls []ToolCallParse, usage Usage) *Response {
+	var r *Response
+	if len(calls) > 0 {
+		r = &Response{Content: toolCallJSON(calls[0]), ToolCalls: calls[:1]}
+	} else {
+		r = ParseResponse(content)
+	}
+	r.Usage = usage
+	return r
+}
+
+// toolMessages converts messages for an API with native tool calling: an
+// assistant message holding a tool call becomes a ToolCall part and the
+// tool message after it the ToolCallResponse for that call, so the model
+// sees its calls and their results as such rather than as text. A tool
+// message without a call before it goes as user text. Returns the names of
+// the tools called, in order of first use.
+func toolMessages(messages []Message) ([]llms.MessageContent, []string) {
+	var out []llms.MessageContent
+	var called []string
+	seen := map[string]bool{}
+	pendingID, pendingName := "", ""
+	for _, msg := range messages {
+		switch msg.Role {
+		case "system":
+			out = append(out, llms.TextParts(llms.ChatMessageTypeSystem, msg.Content))
+		case "assistant":
+			pendingID, pendingName = "", ""
+			parsed := ParseResponse(msg.Content)
+			if len(parsed.ToolCalls) == 0 || !toolNameRe.MatchString(parsed.ToolCalls[0].Name) {
+				out = append(out, llms.TextParts(llms.ChatMessageTypeAI, msg.Content))
+				continue
+			}
+			tc := parsed.ToolCalls[0]
+			args, _ := json.Marshal(tc.Params)
+			if tc.Params == nil {
+				args = []byte("{}")
+			}
+			pendingID, pendingName = fmt.Sprintf("call_%d", len(out)), tc.Name
+			out = append(out, llms.MessageContent{
+				Role: llms.ChatMessageTypeAI,
+				Parts: []llms.ContentPart{llms.ToolCall{
+					ID:           pendingID,
+					Type:         "function",
+					FunctionCall: &llms.FunctionCall{Name: tc.Name, Arguments: string(args)},
+				}},
+			})
+			if !seen[tc.Name] {
+				seen[tc.Name] = true
+				called = append(called, tc.Name)
+			}
+		case "tool":
+			if pendingID == "" {
+				out = append(out, llms.TextParts(llms.ChatMessageTypeHuman, msg.Content))
+				continue
+			}
+			out = append(out, llms.MessageContent{
+				Role:  llms.ChatMessageTypeTool,
+				Parts: []llms.ContentPart{llms.ToolCallResponse{ToolCallID: pendingID, Name: pendingName, Content: msg.Content}},
+			})
+			pendingID, pendingName = "", ""
+		default:
+			out = append(out, llms.TextParts(llms.ChatMessageTypeHuman, msg.Content))
+		}
+	}
+	return out, called
+}
+
+// llmsTools converts tool definitions for langchaingo
+func llmsTools(defs []ToolDef) []llms.Tool {
+	tools := make([]llms.Tool, 0, len(defs))
+	for _, def := range defs {
+		params := def.Parameters
+		if params == nil {
+			params = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+		tools = append(tools, llms.Tool{
+			Type:     "function",
+			Function: &llms.FunctionDefinition{Name: def.Name, Description: def.Description, Parameters: params},
+		})
+	}
+	return tools
+}
+
+// llmsToolCalls converts the tool calls of a langchaingo response
+func llmsToolCalls(calls []llms.ToolCall) ([]ToolCallParse, error) {
+	var out []ToolCallParse
+	for _, tc := range calls {
+		if tc.FunctionCall == nil {
+			continue
+		}
+		var params map[string]any
+		if args := strings.TrimSpace(tc.FunctionCall.Arguments); args != "" {
+			if err := json.Unmarshal([]byte(args), &params); err != nil {
+				return nil, fmt.Errorf("invalid arguments for tool %s: %w", tc.FunctionCall.Name, err)
+			}
+		}
+		out = append(out, ToolCallParse{Name: tc.FunctionCall.Name, Params: params})
+	}
+	return out, nil
+}
+
+// replyStreamer passes streamed reply text on to streamFunc, holding back
+// replies that start with '{' (tool call JSON), like ChatStream
+func replyStreamer(streamFunc func(chunk string)) func(chunk string) {
+	var buf strings.Builder
+	streaming, jsonMode := false, false
+	return func(chunk string) {
+		buf.WriteString(chunk)
+		if !streaming && !jsonMode {
+			trimmed := strings.TrimSpace(buf.String())
+			if len(trimmed) > 0 {
+				if trimmed[0] == '{' {
+					jsonMode = true
+				} else {
+					streaming = true
+					streamFunc(buf.String())
+				}
+			}
+		} else if streaming {
+			streamFunc(chunk)
+		}
+	}
+}
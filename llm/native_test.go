@@ -0,0 +1,101 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenAIClient_ChatWithTools(t *testing.T) {
+	var req map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["model"] == "plain" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"message":"\"auto\" tool choice requires --enable-auto-tool-choice and --tool-call-parser to be set","type":"BadRequestError"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"c1","object":"chat.completion","model":"gpt-4o-mini","choices":[{"index":0,"finish_reason":"tool_calls",
+			"message":{"role":"assistant","content":"","tool_calls":[{"id":"call_x","type":"function","function":{"name":"ssh","arguments":"{\"host\":\"web-1\",\"command\":\"uptime\"}"}}]}}],
+			"usage":{"prompt_tokens":80,"completion_tokens":12,"total_tokens":92}}`))
+	}))
+	defer srv.Close()
+
+	c, err := NewOpenAIClient(Options{Model: "gpt-4o-mini", BaseURL: srv.URL, APIKey: "sk-test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ssh := ToolDef{Name: "ssh", Description: "Run a command on a host"}
+	resp, err := c.ChatWithTools(context.Background(), []Message{{Role: "user", Content: "uptime on web-1?"}}, []ToolDef{ssh}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := json.Marshal(req["tools"])
+	if !strings.Contains(string(raw), `"name":"ssh"`) || !strings.Contains(string(raw), `"type":"object"`) {
+		t.Errorf("request tools = %s", raw)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "ssh" || resp.ToolCalls[0].Params["host"] != "web-1" {
+		t.Errorf("ToolCalls = %+v", resp.ToolCalls)
+	}
+	if resp.Usage.TotalTokens != 92 {
+		t.Errorf("Usage = %+v", resp.Usage)
+	}
+
+	c, _ = NewOpenAIClient(Options{Model: "plain", BaseURL: srv.URL})
+	if _, err := c.ChatWithTools(context.Background(), []Message{{Role: "user", Content: "hi"}}, []ToolDef{ssh}, nil); !errors.Is(err, ErrToolsUnsupported) {
+		t.Errorf("err = %v, want ErrToolsUnsupported", err)
+	}
+}
+
+func TestBuildNativeSystemPrompt(t *testing.T) {
+	defs := []ToolDef{{Name: "ssh", Description: "Run a command on a remote host"}}
+	prompt := BuildNativeSystemPrompt(defs)
+	if strings.Contains(prompt, "Available tools:") || strings.Contains(prompt, `{"name": "tool_name"`) {
+		t.Errorf("native prompt lists tools or asks for JSON:\n%s", prompt)
+	}
+	if !strings.Contains(prompt, `use "ssh" tool`) {
+		t.Errorf("native prompt lost the routing lines:\n%s", prompt)
+	}
+	if text := BuildSystemPrompt(defs); !strings.Contains(text, "Available tools:") || !strings.Contains(text, "Run a command on a remote host") {
+		t.Errorf("text prompt doesn't list the tools:\n%s", text)
+	}
+}
+
+func TestReplyStreamer(t *testing.T) {
+	var got strings.Builder
+	stream := replyStreamer(func(chunk string) { got.WriteString(chunk) })
+	for _, chunk := range []string{" ", "Disk ", "is fine."} {
+		stream(chunk)
+	}
+	if got.String() != " Disk is fine." {
+		t.Errorf("streamed %q", got.String())
+	}
+
+	got.Reset()
+	stream = replyStreamer(func(chunk string) { got.WriteString(chunk) })
+	stream(`{"name": `)
+	stream(`"shell"}`)
+	if got.Len() != 0 {
+		t.Errorf("tool call JSON streamed: %q", got.String())
+	}
+}
+
+func TestToolCallDelta(t *testing.T) {
+	tests := map[string]bool{
+		`[{"id":"call_1","type":"function","function":{"name":"ssh","arguments":""}}]`: true,
+		`[{"function":{"arguments":"{\"host\""}}]`:                                     true,
+		`[1] see the docs`: false,
+		`The answer`:       false,
+		``:                 false,
+	}
+	for chunk, want := range tests {
+		if got := toolCallDelta([]byte(chunk)); got != want {
+			t.Errorf("toolCallDelta(%q) = %v, want %v", chunk, got, want)
+		}
+	}
+}
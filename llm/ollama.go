@@ -17,8 +17,9 @@ type ChatClient interface {
 
 // Client wraps the Ollama LLM with tool calling support
 type Client struct {
-	llm   *ollama.LLM
-	model string
+	llm       *ollama.LLM
+	model     string
+	serverURL string // for the native tool calling requests
 }
 
 // StreamingChatClient extends ChatClient with streaming support
@@ -27,9 +28,10 @@ type StreamingChatClient interface {
 	ChatStream(ctx context.Context, messages []Message, streamFunc func(chunk string)) (*Response, error)
 }
 
-// Ensure Client implements both interfaces
+// Ensure Client implements all three interfaces
 var _ ChatClient = (*Client)(nil)
 var _ StreamingChatClient = (*Client)(nil)
+var _ NativeToolClient = (*Client)(nil)
 
 // Message represents a chat message
 type Message struct {
@@ -42,6 +44,44 @@ type Response struct {
 	Content   string          // Text response
 	ToolCalls []ToolCallParse // Parsed tool calls, if any
 	IsFinish  bool            // True if this is a final answer
+	Usage     Usage           // Token counts reported by the backend (zero if unknown)
+}
+
+// Usage counts the tokens of one or more LLM calls
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Add accumulates the token counts of another call
+func (u *Usage) Add(o Usage) {
+	u.PromptTokens += o.PromptTokens
+	u.CompletionTokens += o.CompletionTokens
+	u.TotalTokens += o.TotalTokens
+}
+
+// usageFrom reads token counts from langchaingo generation info, whose
+// PromptTokens/CompletionTokens/TotalTokens keys are shared across backends
+func usageFrom(info map[string]any) Usage {
+	count := func(key string) int {
+		switch v := info[key].(type) {
+		case int:
+			return v
+		case int32:
+			return int(v)
+		case int64:
+			return int(v)
+		case float64:
+			return int(v)
+		}
+		return 0
+	}
+	return Usage{
+		PromptTokens:     count("PromptTokens"),
+		CompletionTokens: count("CompletionTokens"),
+		TotalTokens:      count("TotalTokens"),
+	}
 }
 
 // ToolCallParse represents a parsed tool call
@@ -51,18 +91,24 @@ type ToolCallParse struct {
 }
 
 // NewClient creates a new Ollama client. If serverURL is non-empty it points
-// the client at that Ollama server (e.g. "http://big-tower.local:11434");
-// otherwise langchaingo's default of http://localhost:11434 is used.
+// the client at that Ollama server (e.g. "http://big-tower.local:11434" or,
+// like $OLLAMA_HOST, "big-tower.local:11434"); otherwise DefaultOllamaURL is
+// used.
 func NewClient(model, serverURL string) (*Client, error) {
 	opts := []ollama.Option{ollama.WithModel(model)}
-	if serverURL != "" {
+	if serverURL == "" {
+		serverURL = DefaultOllamaURL
+	} else {
+		if !strings.Contains(serverURL, "://") {
+			serverURL = "http://" + serverURL // $OLLAMA_HOST is often host:port
+		}
 		opts = append(opts, ollama.WithServerURL(serverURL))
 	}
 	llm, err := ollama.New(opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create ollama client: %w", err)
 	}
-	return &Client{llm: llm, model: model}, nil
+	return &Client{llm: llm, model: model, serverURL: strings.TrimSuffix(serverURL, "/")}, nil
 }
 
 // convertMessages converts internal Message types to langchaingo format.
@@ -104,7 +150,9 @@ func (c *Client) Chat(ctx context.Context, messages []Message) (*Response, error
 	}
 
 	content := resp.Choices[0].Content
-	return parseResponse(content), nil
+	r := ParseResponse(content)
+	r.Usage = usageFrom(resp.Choices[0].GenerationInfo)
+	return r, nil
 }
 
 // ChatStream sends messages to the LLM and streams text responses in real-time.
@@ -145,11 +193,15 @@ func (c *Client) ChatStream(ctx context.Context, messages []Message, streamFunc
 	}
 
 	content := resp.Choices[0].Content
-	return parseResponse(content), nil
+	r := ParseResponse(content)
+	r.Usage = usageFrom(resp.Choices[0].GenerationInfo)
+	return r, nil
 }
 
-// parseResponse extracts tool calls or final answer from LLM response.
-func parseResponse(content string) *Response {
+// ParseResponse extracts tool calls or final answer from an LLM response
+// text. Backends use it on what the model wrote; replay tests use it to
+// re-parse recorded responses.
+func ParseResponse(content string) *Response {
 	resp := &Response{Content: content}
 
 	// Try to find JSON tool call in the response
@@ -240,6 +292,21 @@ func findMatchingBrace(s string) int {
 	return -1
 }
 
+// hostRoutingLine builds the ssh/shell routing lines, only for the tools
+// that are registered
+func hostRoutingLine(tools []ToolDef) string {
+	var sb strings.Builder
+	for _, t := range tools {
+		switch t.Name {
+		case "ssh":
+			sb.WriteString("- \"ssh to\", \"connect to\", user@host, remote server, IP address → use \"ssh\" tool\n")
+		case "shell":
+			sb.WriteString("- Local machine operations, run commands, check files → use \"shell\" tool\n")
+		}
+	}
+	return sb.String()
+}
+
 // edgeRoutingLine builds routing hints for edge_* sensor tools, only including
 // lines for tools that are actually registered.
 func edgeRoutingLine(tools []ToolDef) string {
@@ -262,6 +329,29 @@ func edgeRoutingLine(tools []ToolDef) string {
 	return sb.String()
 }
 
+// wikiRoutingLine builds the wiki routing line for the system prompt, naming
+// every registered wiki knowledge-base tool ("wiki", "wiki_ops", ...).
+// Returns empty string if no wiki tools are present.
+func wikiRoutingLine(tools []ToolDef) string {
+	var wikiNames []string
+	for _, t := range tools {
+		if t.Name == "wiki" || strings.HasPrefix(t.Name, "wiki_") {
+			wikiNames = append(wikiNames, fmt.Sprintf("%q", t.Name))
+		}
+	}
+	if len(wikiNames) == 0 {
+		return ""
+	}
+	line := "- \"wiki\", \"confluence\", \"documentation\", \"diagram\", \"architecture\" → use "
+	if len(wikiNames) == 1 {
+		line += wikiNames[0] + " tool\n"
+	} else {
+		line += strings.Join(wikiNames, " or ") + " tool (pick the knowledge base whose description matches)\n"
+	}
+	return line + "- When your answer uses wiki results, cite the pages it relies on using their \"Source:\" lines, e.g. (Source: Deploy Guide, https://wiki/deploy#Rollback); never cite a page you did not retrieve\n" +
+		"- If a wiki result is relevant but incomplete, read the whole page with action \"get_page\" and the result's Source path\n"
+}
+
 // mcpRoutingLine builds the MCP routing line for the system prompt.
 // Returns empty string if no MCP tools are present.
 func mcpRoutingLine(tools []ToolDef) string {
@@ -280,23 +370,53 @@ func mcpRoutingLine(tools []ToolDef) string {
 	return fmt.Sprintf("- \"mcp\", MCP tool calls → use %s tool (check descriptions for available tools)\n", strings.Join(mcpNames, " or "))
 }
 
+// metricsRoutingLine builds the routing line of the prometheus tool, if it
+// is registered
+func metricsRoutingLine(tools []ToolDef) string {
+	for _, t := range tools {
+		if t.Name == "prometheus" {
+			return "- \"metrics\", \"cpu usage\", \"memory usage\", \"alerts\", what a pod did before it crashed or restarted → use \"prometheus\" tool (find series with 'labels' / 'label_values', then 'query' or 'query_range')\n"
+		}
+	}
+	return ""
+}
+
 // BuildSystemPrompt creates the system prompt with tool definitions
 func BuildSystemPrompt(tools []ToolDef) string {
+	return buildSystemPrompt(tools, false)
+}
+
+// BuildNativeSystemPrompt creates the system prompt for a backend with
+// native tool calling (NativeToolClient): the tool definitions go in the
+// request, so the prompt doesn't list them or ask for JSON.
+func BuildNativeSystemPrompt(tools []ToolDef) string {
+	return buildSystemPrompt(tools, true)
+}
+
+func buildSystemPrompt(tools []ToolDef, native bool) string {
 	var sb strings.Builder
 	sb.WriteString(`You are an autonomous agent that uses tools to complete tasks.
 
 RESPONSE FORMAT:
-- To call a tool: respond with ONLY a JSON object: {"name": "tool_name", "parameters": {...}}
+`)
+	if native {
+		sb.WriteString(`- To call a tool: use the tool calling interface, one tool at a time
+- To give final answer: respond with plain text
+`)
+	} else {
+		sb.WriteString(`- To call a tool: respond with ONLY a JSON object: {"name": "tool_name", "parameters": {...}}
 - To give final answer: respond with plain text (no JSON)
-
+`)
+	}
+	sb.WriteString(`
 WHEN TO USE TOOLS:
-- "ssh to", "connect to", user@host, remote server, IP address → use "ssh" tool
-- Local machine operations, run commands, check files → use "shell" tool
 `)
+	sb.WriteString(hostRoutingLine(tools))
 	sb.WriteString(mcpRoutingLine(tools))
+	sb.WriteString(metricsRoutingLine(tools))
 	sb.WriteString(edgeRoutingLine(tools))
-	sb.WriteString(`- "wiki", "confluence", "documentation", "diagram", "architecture" → use "wiki" tool
-
+	sb.WriteString(wikiRoutingLine(tools))
+	sb.WriteString(`
 WHEN NOT TO USE TOOLS (answer directly from your knowledge):
 - General knowledge questions (math, science, history, concepts)
 - Explanations, definitions, "what is", "how does X work"
@@ -314,15 +434,16 @@ CRITICAL RULES:
 - If a command fails or returns empty, report exactly what happened
 - For knowledge questions, use your own knowledge - no tools needed
 - If unsure about facts, say so
-
-Available tools:
 `)
 
-	for _, tool := range tools {
-		toolJSON, _ := json.MarshalIndent(tool, "", "  ")
-		sb.WriteString("\n")
-		sb.Write(toolJSON)
-		sb.WriteString("\n")
+	if !native {
+		sb.WriteString("\nAvailable tools:\n")
+		for _, tool := range tools {
+			toolJSON, _ := json.MarshalIndent(tool, "", "  ")
+			sb.WriteString("\n")
+			sb.Write(toolJSON)
+			sb.WriteString("\n")
+		}
 	}
 
 	sb.WriteString(`
@@ -58,7 +58,7 @@ func TestParseResponse_ValidToolCall(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			resp := parseResponse(tt.content)
+			resp := ParseResponse(tt.content)
 
 			if len(resp.ToolCalls) == 0 {
 				t.Fatal("expected tool call, got none")
@@ -108,7 +108,7 @@ func TestParseResponse_ToolCallTruncatesHallucination(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			resp := parseResponse(tt.content)
+			resp := ParseResponse(tt.content)
 			if len(resp.ToolCalls) == 0 {
 				t.Fatal("expected tool call, got none")
 			}
@@ -140,7 +140,7 @@ func TestParseResponse_FinalAnswer(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			resp := parseResponse(tt.content)
+			resp := ParseResponse(tt.content)
 
 			if len(resp.ToolCalls) > 0 {
 				t.Errorf("expected no tool calls, got %d", len(resp.ToolCalls))
@@ -180,7 +180,7 @@ func TestParseResponse_MalformedJSON(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			resp := parseResponse(tt.content)
+			resp := ParseResponse(tt.content)
 
 			// Should not crash, should treat as final answer or no tool call
 			if len(resp.ToolCalls) > 0 && resp.ToolCalls[0].Name != "" {
@@ -307,6 +307,26 @@ func TestBuildSystemPrompt_EmptyTools(t *testing.T) {
 	}
 }
 
+func TestWikiRoutingLine(t *testing.T) {
+	if got := wikiRoutingLine([]ToolDef{{Name: "shell"}}); got != "" {
+		t.Errorf("wikiRoutingLine() without wiki tools = %q, want empty", got)
+	}
+
+	single := wikiRoutingLine([]ToolDef{{Name: "wiki"}})
+	if !strings.Contains(single, `use "wiki" tool`) {
+		t.Errorf("single wiki line = %q, want it to route to \"wiki\"", single)
+	}
+
+	if !strings.Contains(single, `"Source:"`) {
+		t.Errorf("single wiki line = %q, want a citation instruction", single)
+	}
+
+	multi := wikiRoutingLine([]ToolDef{{Name: "wiki_ops"}, {Name: "shell"}, {Name: "wiki_dev"}})
+	if !strings.Contains(multi, `"wiki_ops" or "wiki_dev"`) {
+		t.Errorf("multi wiki line = %q, want both knowledge bases", multi)
+	}
+}
+
 func TestToolDef_JSONMarshal(t *testing.T) {
 	tool := ToolDef{
 		Name:        "test",
@@ -333,3 +353,32 @@ func TestToolDef_JSONMarshal(t *testing.T) {
 		t.Error("prompt should contain tool description")
 	}
 }
+
+func TestUsageFrom(t *testing.T) {
+	got := usageFrom(map[string]any{"PromptTokens": 12, "CompletionTokens": int32(3), "TotalTokens": 15, "ThinkingTokens": 0})
+	if want := (Usage{PromptTokens: 12, CompletionTokens: 3, TotalTokens: 15}); got != want {
+		t.Errorf("usageFrom() = %+v, want %+v", got, want)
+	}
+	if got := usageFrom(nil); got != (Usage{}) {
+		t.Errorf("usageFrom(nil) = %+v, want zero", got)
+	}
+}
+
+func TestHostRoutingLine(t *testing.T) {
+	both := hostRoutingLine([]ToolDef{{Name: "ssh"}, {Name: "shell"}})
+	if !strings.Contains(both, `use "ssh" tool`) || !strings.Contains(both, `use "shell" tool`) {
+		t.Errorf("hostRoutingLine(ssh, shell) = %q, want both lines", both)
+	}
+	if got := hostRoutingLine([]ToolDef{{Name: "wiki"}}); got != "" {
+		t.Errorf("hostRoutingLine(wiki) = %q, want empty when ssh and shell are disabled", got)
+	}
+}
+
+func TestMetricsRoutingLine(t *testing.T) {
+	if got := metricsRoutingLine([]ToolDef{{Name: "shell"}, {Name: "prometheus"}}); !strings.Contains(got, `use "prometheus" tool`) {
+		t.Errorf("metricsRoutingLine(prometheus) = %q", got)
+	}
+	if got := metricsRoutingLine([]ToolDef{{Name: "shell"}}); got != "" {
+		t.Errorf("metricsRoutingLine(shell) = %q, want empty", got)
+	}
+}
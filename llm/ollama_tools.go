@@ -0,0 +1,157 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultOllamaURL is the Ollama server used when none is given
+const DefaultOllamaURL = "http://localhost:11434"
+
+// langchaingo's ollama package has no tool support, so ChatWithTools talks
+// to /api/chat itself.
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string         `json:"name"`
+		Arguments map[string]any `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+}
+
+type ollamaTool struct {
+	Type     string  `json:"type"`
+	Function ToolDef `json:"function"`
+}
+
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Tools    []ollamaTool    `json:"tools"`
+	Stream   bool            `json:"stream"`
+}
+
+type ollamaChatResponse struct {
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+// ollamaMessages maps the conversation to /api/chat messages: assistant
+// tool call JSON becomes tool_calls and tool results use the "tool" role
+func ollamaMessages(messages []Message) []ollamaMessage {
+	out := make([]ollamaMessage, 0, len(messages))
+	for _, msg := range messages {
+		m := ollamaMessage{Role: msg.Role, Content: msg.Content}
+		switch msg.Role {
+		case "system", "user", "tool":
+		case "assistant":
+			if parsed := ParseResponse(msg.Content); len(parsed.ToolCalls) > 0 {
+				var tc ollamaToolCall
+				tc.Function.Name = parsed.ToolCalls[0].Name
+				tc.Function.Arguments = parsed.ToolCalls[0].Params
+				if tc.Function.Arguments == nil {
+					tc.Function.Arguments = map[string]any{}
+				}
+				m = ollamaMessage{Role: "assistant", ToolCalls: []ollamaToolCall{tc}}
+			}
+		default:
+			m.Role = "user"
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// ChatWithTools sends messages to Ollama's /api/chat with the tool
+// definitions. Models without tool support (no tools in their template)
+// make it return an error wrapping ErrToolsUnsupported.
+func (c *Client) ChatWithTools(ctx context.Context, messages []Message, tools []ToolDef, streamFunc func(chunk string)) (*Response, error) {
+	req := ollamaChatRequest{
+		Model:    c.model,
+		Messages: ollamaMessages(messages),
+		Tools:    make([]ollamaTool, 0, len(tools)),
+		Stream:   streamFunc != nil,
+	}
+	for _, def := range tools {
+		if def.Parameters == nil {
+			def.Parameters = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+		req.Tools = append(req.Tools, ollamaTool{Type: "function", Function: def})
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode ollama request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.serverURL+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("llm generate failed: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpResp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("llm generate failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(httpResp.Body, 64<<10))
+		var e ollamaChatResponse
+		msg := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &e) == nil && e.Error != "" {
+			msg = e.Error
+		}
+		if toolsUnsupported(msg) {
+			return nil, fmt.Errorf("%w: %s", ErrToolsUnsupported, msg)
+		}
+		return nil, fmt.Errorf("llm generate failed: %s: %s", httpResp.Status, msg)
+	}
+
+	// Streamed or not, the body is a sequence of JSON objects
+	stream := func(string) {}
+	if streamFunc != nil {
+		stream = replyStreamer(streamFunc)
+	}
+	var content strings.Builder
+	var calls []ToolCallParse
+	var usage Usage
+	dec := json.NewDecoder(httpResp.Body)
+	for {
+		var chunk ollamaChatResponse
+		if err := dec.Decode(&chunk); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to decode ollama response: %w", err)
+		}
+		if chunk.Error != "" {
+			return nil, fmt.Errorf("llm generate failed: %s", chunk.Error)
+		}
+		if chunk.Message.Content != "" {
+			content.WriteString(chunk.Message.Content)
+			stream(chunk.Message.Content)
+		}
+		for _, tc := range chunk.Message.ToolCalls {
+			calls = append(calls, ToolCallParse{Name: tc.Function.Name, Params: tc.Function.Arguments})
+		}
+		if chunk.Done {
+			usage = Usage{
+				PromptTokens:     chunk.PromptEvalCount,
+				CompletionTokens: chunk.EvalCount,
+				TotalTokens:      chunk.PromptEvalCount + chunk.EvalCount,
+			}
+		}
+	}
+	return nativeResponse(content.String(), calls, usage), nil
+}
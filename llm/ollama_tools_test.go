@@ -0,0 +1,109 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClient_ChatWithTools(t *testing.T) {
+	var req ollamaChatRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model == "gemma2" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"registry.ollama.ai/library/gemma2:latest does not support tools"}`))
+			return
+		}
+		if req.Stream {
+			w.Write([]byte(`{"message":{"role":"assistant","content":"Disk "},"done":false}` + "\n" +
+				`{"message":{"role":"assistant","content":"is fine."},"done":false}` + "\n" +
+				`{"message":{"role":"assistant","content":""},"done":true,"prompt_eval_count":50,"eval_count":4}` + "\n"))
+			return
+		}
+		w.Write([]byte(`{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"shell","arguments":{"command":"df -h"}}}]},
+			"done":true,"prompt_eval_count":120,"eval_count":20}`))
+	}))
+	defer srv.Close()
+
+	c, err := NewClient("qwen2.5:7b", srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shell := ToolDef{Name: "shell", Description: "Run a command", Parameters: map[string]any{"type": "object"}}
+	history := []Message{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: "uptime?"},
+		{Role: "assistant", Content: `{"name": "shell", "parameters": {"command": "uptime"}}`},
+		{Role: "tool", Content: "Tool 'shell' returned:\n up 1 day"},
+		{Role: "user", Content: "and disk?"},
+	}
+
+	resp, err := c.ChatWithTools(context.Background(), history, []ToolDef{shell}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(req.Tools) != 1 || req.Tools[0].Type != "function" || req.Tools[0].Function.Name != "shell" || req.Stream {
+		t.Errorf("request tools = %+v, stream = %v", req.Tools, req.Stream)
+	}
+	if m := req.Messages[2]; m.Role != "assistant" || m.Content != "" || len(m.ToolCalls) != 1 || m.ToolCalls[0].Function.Arguments["command"] != "uptime" {
+		t.Errorf("assistant tool call message = %+v", m)
+	}
+	if req.Messages[3].Role != "tool" {
+		t.Errorf("tool result role = %s", req.Messages[3].Role)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "shell" || resp.ToolCalls[0].Params["command"] != "df -h" {
+		t.Errorf("ToolCalls = %+v", resp.ToolCalls)
+	}
+	if again := ParseResponse(resp.Content); len(again.ToolCalls) != 1 || again.ToolCalls[0].Name != "shell" {
+		t.Errorf("Content = %q, want the tool call JSON", resp.Content)
+	}
+	if resp.Usage != (Usage{PromptTokens: 120, CompletionTokens: 20, TotalTokens: 140}) {
+		t.Errorf("Usage = %+v", resp.Usage)
+	}
+
+	// Streamed answer
+	var streamed strings.Builder
+	resp, err = c.ChatWithTools(context.Background(), history, []ToolDef{shell}, func(chunk string) { streamed.WriteString(chunk) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !req.Stream || resp.Content != "Disk is fine." || !resp.IsFinish || streamed.String() != "Disk is fine." {
+		t.Errorf("streamed response = %+v, streamed %q", resp, streamed.String())
+	}
+	if resp.Usage.TotalTokens != 54 {
+		t.Errorf("Usage = %+v", resp.Usage)
+	}
+
+	// A model without tool support
+	c, _ = NewClient("gemma2", srv.URL)
+	if _, err := c.ChatWithTools(context.Background(), history, []ToolDef{shell}, nil); !errors.Is(err, ErrToolsUnsupported) {
+		t.Errorf("err = %v, want ErrToolsUnsupported", err)
+	}
+}
+
+func TestNewClient_ServerURL(t *testing.T) {
+	tests := map[string]string{
+		"":                         DefaultOllamaURL,
+		"http://big-tower:11434/":  "http://big-tower:11434",
+		"0.0.0.0:11434":            "http://0.0.0.0:11434",
+		"https://ollama.corp/base": "https://ollama.corp/base",
+	}
+	for in, want := range tests {
+		c, err := NewClient("qwen2.5:7b", in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.serverURL != want {
+			t.Errorf("NewClient(%q) serverURL = %q, want %q", in, c.serverURL, want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/openai"
+)
+
+// AzureAPIVersion is the Azure OpenAI API version used when none is given
+const AzureAPIVersion = "2024-06-01"
+
+// OpenAIClient wraps an OpenAI chat completions API with the ChatClient
+// interface: OpenAI itself, Azure OpenAI, or an OpenAI-compatible server
+// (vLLM, LM Studio, llama.cpp server, ...).
+type OpenAIClient struct {
+	llm   *openai.LLM
+	model string
+}
+
+// Ensure OpenAIClient implements all three interfaces.
+var _ ChatClient = (*OpenAIClient)(nil)
+var _ StreamingChatClient = (*OpenAIClient)(nil)
+var _ NativeToolClient = (*OpenAIClient)(nil)
+
+// NewOpenAIClient creates a client for OpenAI or, with opts.BaseURL, an
+// OpenAI-compatible server. The API key defaults to $OPENAI_API_KEY and the
+// base URL to $OPENAI_BASE_URL; local servers that don't check keys need
+// none.
+func NewOpenAIClient(opts Options) (*OpenAIClient, error) {
+	if opts.BaseURL == "" {
+		opts.BaseURL = os.Getenv("OPENAI_BASE_URL")
+	}
+	key := firstNonEmpty(opts.APIKey, os.Getenv("OPENAI_API_KEY"))
+	if key == "" {
+		if opts.BaseURL == "" {
+			return nil, fmt.Errorf("failed to create openai client: $OPENAI_API_KEY is not set")
+		}
+		key = "none" // local servers ignore it, but the client requires one
+	}
+	openaiOpts := []openai.Option{openai.WithModel(opts.Model), openai.WithToken(key)}
+	if opts.BaseURL != "" {
+		openaiOpts = append(openaiOpts, openai.WithBaseURL(opts.BaseURL))
+	}
+	llm, err := openai.New(openaiOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create openai client: %w", err)
+	}
+	return &OpenAIClient{llm: llm, model: opts.Model}, nil
+}
+
+// NewAzureOpenAIClient creates a client for an Azure OpenAI deployment:
+// opts.BaseURL is the resource endpoint (https://<resource>.openai.azure.com)
+// and opts.Model the deployment name. The API key defaults to
+// $AZURE_OPENAI_API_KEY, then $OPENAI_API_KEY.
+func NewAzureOpenAIClient(opts Options) (*OpenAIClient, error) {
+	if opts.BaseURL == "" {
+		opts.BaseURL = os.Getenv("AZURE_OPENAI_ENDPOINT")
+	}
+	if opts.BaseURL == "" {
+		return nil, fmt.Errorf("failed to create azure openai client: the resource endpoint is required")
+	}
+	if opts.Model == "" {
+		return nil, fmt.Errorf("failed to create azure openai client: the deployment name is required as the model")
+	}
+	key := firstNonEmpty(opts.APIKey, os.Getenv("AZURE_OPENAI_API_KEY"), os.Getenv("OPENAI_API_KEY"))
+	if key == "" {
+		return nil, fmt.Errorf("failed to create azure openai client: $AZURE_OPENAI_API_KEY is not set")
+	}
+	version := firstNonEmpty(opts.APIVersion, AzureAPIVersion)
+	llm, err := openai.New(
+		openai.WithAPIType(openai.APITypeAzure),
+		openai.WithBaseURL(opts.BaseURL),
+		openai.WithAPIVersion(version),
+		openai.WithModel(opts.Model),
+		openai.WithToken(key),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create azure openai client: %w", err)
+	}
+	return &OpenAIClient{llm: llm, model: opts.Model}, nil
+}
+
+// firstNonEmpty returns the first of values that isn't empty
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Chat sends messages to the OpenAI API and returns the response.
+func (c *OpenAIClient) Chat(ctx context.Context, messages []Message) (*Response, error) {
+	llmMessages := convertMessages(messages)
+
+	resp, err := c.llm.GenerateContent(ctx, llmMessages)
+	if err != nil {
+		return nil, fmt.Errorf("openai generate failed: %w", err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no response from openai")
+	}
+
+	content := resp.Choices[0].Content
+	r := ParseResponse(content)
+	r.Usage = usageFrom(resp.Choices[0].GenerationInfo)
+	return r, nil
+}
+
+// ChatStream sends messages to the OpenAI API and streams text responses in real-time.
+func (c *OpenAIClient) ChatStream(ctx context.Context, messages []Message, streamFunc func(chunk string)) (*Response, error) {
+	llmMessages := convertMessages(messages)
+
+	var buf strings.Builder
+	streaming := false
+	jsonMode := false
+
+	resp, err := c.llm.GenerateContent(ctx, llmMessages,
+		llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+			buf.Write(chunk)
+
+			if !streaming && !jsonMode {
+				trimmed := strings.TrimSpace(buf.String())
+				if len(trimmed) > 0 {
+					if trimmed[0] == '{' {
+						jsonMode = true
+					} else {
+						streaming = true
+						streamFunc(buf.String())
+					}
+				}
+			} else if streaming {
+				streamFunc(string(chunk))
+			}
+
+			return nil
+		}))
+	if err != nil {
+		return nil, fmt.Errorf("openai generate failed: %w", err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no response from openai")
+	}
+
+	content := resp.Choices[0].Content
+	r := ParseResponse(content)
+	r.Usage = usageFrom(resp.Choices[0].GenerationInfo)
+	return r, nil
+}
+
+// ChatWithTools sends messages to the OpenAI API with the tool definitions
+// as functions. OpenAI-compatible servers that can't take tools (vLLM
+// without --enable-auto-tool-choice) make it return an error wrapping
+// ErrToolsUnsupported.
+func (c *OpenAIClient) ChatWithTools(ctx context.Context, messages []Message, tools []ToolDef, streamFunc func(chunk string)) (*Response, error) {
+	llmMessages, _ := toolMessages(messages)
+
+	opts := []llms.CallOption{llms.WithTools(llmsTools(tools))}
+	if streamFunc != nil {
+		stream := replyStreamer(streamFunc)
+		opts = append(opts, llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+			if !toolCallDelta(chunk) {
+				stream(string(chunk))
+			}
+			return nil
+		}))
+	}
+	resp, err := c.llm.GenerateContent(ctx, llmMessages, opts...)
+	if err != nil {
+		if toolsUnsupported(err.Error()) {
+			return nil, fmt.Errorf("%w: %v", ErrToolsUnsupported, err)
+		}
+		return nil, fmt.Errorf("openai generate failed: %w", err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no response from openai")
+	}
+
+	calls, err := llmsToolCalls(resp.Choices[0].ToolCalls)
+	if err != nil {
+		return nil, fmt.Errorf("openai returned an invalid tool call: %w", err)
+	}
+	return nativeResponse(resp.Choices[0].Content, calls, usageFrom(resp.Choices[0].GenerationInfo)), nil
+}
+
+// toolCallDelta reports whether a streamed chunk is a tool call delta,
+// which langchaingo passes to the streaming func as JSON alongside the text
+func toolCallDelta(chunk []byte) bool {
+	if len(chunk) == 0 || chunk[0] != '[' {
+		return false
+	}
+	var deltas []struct {
+		Function *json.RawMessage `json:"function"`
+	}
+	return json.Unmarshal(chunk, &deltas) == nil && len(deltas) > 0 && deltas[0].Function != nil
+}
@@ -0,0 +1,75 @@
+package llm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Price is what a hosted model charges, in US dollars per million tokens
+type Price struct {
+	Input  float64 `json:"input"`  // prompt tokens
+	Output float64 `json:"output"` // completion tokens
+}
+
+// Cost returns the dollar cost of u
+func (p Price) Cost(u Usage) float64 {
+	return (float64(u.PromptTokens)*p.Input + float64(u.CompletionTokens)*p.Output) / 1e6
+}
+
+func (p Price) String() string {
+	return fmt.Sprintf("$%g/$%g per 1M tokens", p.Input, p.Output)
+}
+
+// Prices are the list prices of hosted models, by model name prefix (the
+// longest matching prefix wins, so dated and -latest variants are found).
+// Local Ollama models cost nothing and are not listed.
+var Prices = map[string]Price{
+	"gemini-2.5-pro":        {Input: 1.25, Output: 10},
+	"gemini-2.5-flash":      {Input: 0.30, Output: 2.50},
+	"gemini-2.5-flash-lite": {Input: 0.10, Output: 0.40},
+	"gemini-2.0-flash":      {Input: 0.10, Output: 0.40},
+	"gemini-2.0-flash-lite": {Input: 0.075, Output: 0.30},
+	"gemini-1.5-pro":        {Input: 1.25, Output: 5},
+	"gemini-1.5-flash":      {Input: 0.075, Output: 0.30},
+	"gpt-4o":                {Input: 2.50, Output: 10},
+	"gpt-4o-mini":           {Input: 0.15, Output: 0.60},
+	"gpt-4.1":               {Input: 2, Output: 8},
+	"gpt-4.1-mini":          {Input: 0.40, Output: 1.60},
+	"gpt-4.1-nano":          {Input: 0.10, Output: 0.40},
+	"claude-opus-4":         {Input: 15, Output: 75},
+	"claude-opus-4-5":       {Input: 5, Output: 25},
+	"claude-sonnet-4":       {Input: 3, Output: 15},
+	"claude-haiku-4-5":      {Input: 1, Output: 5},
+	"claude-3-5-haiku":      {Input: 0.80, Output: 4},
+}
+
+// PriceOf looks up model in Prices
+func PriceOf(model string) (Price, bool) {
+	model = strings.ToLower(strings.TrimPrefix(model, "models/"))
+	best, found := "", false
+	for prefix := range Prices {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best, found = prefix, true
+		}
+	}
+	return Prices[best], found
+}
+
+// ParsePrice parses "INPUT/OUTPUT" dollars per million tokens, e.g.
+// "1.25/10"
+func ParsePrice(s string) (Price, error) {
+	in, out, ok := strings.Cut(s, "/")
+	if !ok {
+		return Price{}, fmt.Errorf("invalid price %q (use INPUT/OUTPUT dollars per million tokens, e.g. 1.25/10)", s)
+	}
+	var p Price
+	var err error
+	if p.Input, err = strconv.ParseFloat(strings.TrimSpace(strings.TrimPrefix(in, "$")), 64); err != nil || p.Input < 0 {
+		return Price{}, fmt.Errorf("invalid input price in %q", s)
+	}
+	if p.Output, err = strconv.ParseFloat(strings.TrimSpace(strings.TrimPrefix(out, "$")), 64); err != nil || p.Output < 0 {
+		return Price{}, fmt.Errorf("invalid output price in %q", s)
+	}
+	return p, nil
+}
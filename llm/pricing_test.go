@@ -0,0 +1,45 @@
+package llm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPriceOf(t *testing.T) {
+	tests := []struct {
+		model string
+		want  Price
+		found bool
+	}{
+		{"gemini-2.5-flash", Price{Input: 0.30, Output: 2.50}, true},
+		{"models/gemini-2.5-flash-lite-preview-06-17", Price{Input: 0.10, Output: 0.40}, true}, // longest prefix
+		{"Gemini-2.5-Pro", Price{Input: 1.25, Output: 10}, true},
+		{"qwen2.5:32b", Price{}, false},
+	}
+	for _, tt := range tests {
+		got, found := PriceOf(tt.model)
+		if got != tt.want || found != tt.found {
+			t.Errorf("PriceOf(%q) = %v, %v; want %v, %v", tt.model, got, found, tt.want, tt.found)
+		}
+	}
+}
+
+func TestPrice_Cost(t *testing.T) {
+	p := Price{Input: 1.25, Output: 10}
+	got := p.Cost(Usage{PromptTokens: 200_000, CompletionTokens: 10_000, TotalTokens: 210_000})
+	if math.Abs(got-0.35) > 1e-9 {
+		t.Errorf("Cost = %v, want 0.35", got)
+	}
+}
+
+func TestParsePrice(t *testing.T) {
+	p, err := ParsePrice("$3/15")
+	if err != nil || p != (Price{Input: 3, Output: 15}) {
+		t.Errorf("ParsePrice = %v, %v", p, err)
+	}
+	for _, bad := range []string{"3", "x/15", "3/-1"} {
+		if _, err := ParsePrice(bad); err == nil {
+			t.Errorf("ParsePrice(%q) succeeded", bad)
+		}
+	}
+}
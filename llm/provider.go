@@ -0,0 +1,89 @@
+package llm
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Options configure a chat client created by NewClientFor. Providers ignore
+// the options that don't apply to them.
+type Options struct {
+	Model      string // model name (Azure OpenAI: the deployment name)
+	BaseURL    string // server or API base URL ("" = the provider's default)
+	APIKey     string // API key ("" = the provider's environment variable)
+	APIVersion string // Azure OpenAI API version ("" = a recent default)
+}
+
+// Provider creates chat clients for one kind of backend
+type Provider func(opts Options) (ChatClient, error)
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]Provider{}
+)
+
+// Register makes a provider available to NewClientFor under name. It
+// panics if the name is taken, like database/sql.Register, since that is a
+// programming error.
+func Register(name string, p Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	if p == nil {
+		panic("llm: Register provider is nil")
+	}
+	if _, dup := providers[name]; dup {
+		panic("llm: Register called twice for provider " + name)
+	}
+	providers[name] = p
+}
+
+// Providers returns the names of the registered providers, sorted
+func Providers() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewClientFor creates a chat client with the named provider: "ollama",
+// "gemini", "openai" (OpenAI and OpenAI-compatible servers such as vLLM
+// and LM Studio), "azure" (Azure OpenAI), "anthropic" (Claude) or one added
+// with Register
+func NewClientFor(name string, opts Options) (ChatClient, error) {
+	providersMu.RLock()
+	p, ok := providers[name]
+	providersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown provider: %s (use %s)", name, strings.Join(Providers(), ", "))
+	}
+	return p(opts)
+}
+
+func init() {
+	Register("ollama", func(opts Options) (ChatClient, error) {
+		url := opts.BaseURL
+		if url == "" {
+			url = os.Getenv("OLLAMA_HOST")
+		}
+		return NewClient(opts.Model, url)
+	})
+	Register("gemini", func(opts Options) (ChatClient, error) {
+		return NewGeminiClient(opts.Model)
+	})
+	Register("openai", func(opts Options) (ChatClient, error) {
+		return NewOpenAIClient(opts)
+	})
+	Register("azure", func(opts Options) (ChatClient, error) {
+		return NewAzureOpenAIClient(opts)
+	})
+	Register("anthropic", func(opts Options) (ChatClient, error) {
+		return NewAnthropicClient(opts)
+	})
+}
@@ -0,0 +1,134 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type fakeClient struct{}
+
+func (fakeClient) Chat(ctx context.Context, messages []Message) (*Response, error) {
+	return &Response{Content: "fake", IsFinish: true}, nil
+}
+
+func TestRegister(t *testing.T) {
+	var got Options
+	Register("test-fake", func(opts Options) (ChatClient, error) {
+		got = opts
+		return fakeClient{}, nil
+	})
+	defer func() {
+		providersMu.Lock()
+		delete(providers, "test-fake")
+		providersMu.Unlock()
+	}()
+
+	if !slices.Contains(Providers(), "test-fake") {
+		t.Errorf("Providers() = %v, want test-fake included", Providers())
+	}
+	c, err := NewClientFor("test-fake", Options{Model: "m1", BaseURL: "http://x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.(fakeClient); !ok || got.Model != "m1" || got.BaseURL != "http://x" {
+		t.Errorf("NewClientFor() = %T with %+v", c, got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a provider twice did not panic")
+		}
+	}()
+	Register("test-fake", func(Options) (ChatClient, error) { return fakeClient{}, nil })
+}
+
+func TestNewClientFor_Unknown(t *testing.T) {
+	_, err := NewClientFor("nope", Options{})
+	if err == nil || !strings.Contains(err.Error(), "unknown provider: nope") || !strings.Contains(err.Error(), "ollama, openai") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestBuiltinProviders(t *testing.T) {
+	for _, name := range []string{"azure", "gemini", "ollama", "openai"} {
+		if !slices.Contains(Providers(), name) {
+			t.Errorf("provider %s is not registered", name)
+		}
+	}
+}
+
+func TestOpenAIClient_CompatibleServer(t *testing.T) {
+	var req struct {
+		Model    string `json:"model"`
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"1","object":"chat.completion","model":"qwen","choices":[{"index":0,"finish_reason":"stop",
+			"message":{"role":"assistant","content":"{\"name\": \"shell\", \"parameters\": {\"command\": \"uptime\"}}"}}],
+			"usage":{"prompt_tokens":12,"completion_tokens":8,"total_tokens":20}}`))
+	}))
+	defer srv.Close()
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("OPENAI_BASE_URL", "")
+
+	c, err := NewClientFor("openai", Options{Model: "qwen", BaseURL: srv.URL + "/v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Chat(context.Background(), []Message{{Role: "system", Content: "tools"}, {Role: "user", Content: "uptime?"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "shell" || resp.ToolCalls[0].Params["command"] != "uptime" {
+		t.Errorf("ToolCalls = %+v", resp.ToolCalls)
+	}
+	if resp.Usage.TotalTokens != 20 {
+		t.Errorf("Usage = %+v", resp.Usage)
+	}
+	if req.Model != "qwen" || len(req.Messages) != 2 || req.Messages[1].Content != "uptime?" {
+		t.Errorf("request = %+v", req)
+	}
+	if auth != "Bearer none" {
+		t.Errorf("Authorization = %q, want the placeholder key", auth)
+	}
+}
+
+func TestNewOpenAIClient_NeedsKeyForOpenAI(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("OPENAI_BASE_URL", "")
+	if _, err := NewOpenAIClient(Options{Model: "gpt-4o-mini"}); err == nil || !strings.Contains(err.Error(), "OPENAI_API_KEY") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestNewAzureOpenAIClient(t *testing.T) {
+	t.Setenv("AZURE_OPENAI_ENDPOINT", "")
+	t.Setenv("AZURE_OPENAI_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "")
+	if _, err := NewAzureOpenAIClient(Options{Model: "gpt4o-prod"}); err == nil || !strings.Contains(err.Error(), "endpoint") {
+		t.Errorf("no endpoint: err = %v", err)
+	}
+	if _, err := NewAzureOpenAIClient(Options{BaseURL: "https://r.openai.azure.com"}); err == nil || !strings.Contains(err.Error(), "deployment") {
+		t.Errorf("no deployment: err = %v", err)
+	}
+	t.Setenv("AZURE_OPENAI_API_KEY", "k")
+	if _, err := NewAzureOpenAIClient(Options{BaseURL: "https://r.openai.azure.com", Model: "gpt4o-prod"}); err != nil {
+		t.Errorf("err = %v", err)
+	}
+}
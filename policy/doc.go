@@ -0,0 +1,5 @@
+// Package policy reads --policy files, which give each role the tools,
+// hosts, Kubernetes namespaces and commands it may use. A Role is an
+// agent.Policy: the agent checks every tool call against it before running
+// the tool.
+package policy
@@ -0,0 +1,329 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/rathore/langchain-agent/tools"
+	"gopkg.in/yaml.v3"
+)
+
+// File is a policy file:
+//
+//	default: operator              # role used when none is chosen
+//	roles:
+//	  viewer:
+//	    read_only: true            # only tools registered as read-only
+//	  operator:
+//	    tools: ["*"]               # tool name patterns (default: all)
+//	    deny_tools: [edge_gpio]
+//	    categories: [local, remote, knowledge]
+//	    max_risk: medium           # no high risk calls (see CallRisk)
+//	    hosts: ["*.lab.example.com", "10.0.0.*"]
+//	    namespaces: [default, "team-*"]
+//	    commands:
+//	      allow: ['^(ls|cat|df|uptime|kubectl get)\b']
+//	      deny: ['\bsudo\b', 'rm -rf']
+//
+// Lists left out allow everything; an empty list allows nothing.
+type File struct {
+	Default string           `yaml:"default"`
+	Roles   map[string]*Role `yaml:"roles"`
+}
+
+// Role is what the agent may do with one role's permissions
+type Role struct {
+	Name string `yaml:"-"`
+
+	Tools      []string `yaml:"tools"`      // tool names, with * and ? wildcards
+	DenyTools  []string `yaml:"deny_tools"` // tool names never allowed, even if in Tools
+	Categories []string `yaml:"categories"` // tool categories (tools.Category*)
+	ReadOnly   bool     `yaml:"read_only"`  // only read-only calls (tools.Meta.ReadOnlyCall)
+
+	// MaxRisk is the highest risk of the calls the role may make (see
+	// CallRisk; "" = any)
+	MaxRisk tools.Risk `yaml:"max_risk"`
+
+	// Hosts are the hosts a "host" parameter (ssh) may name, with wildcards;
+	// user@ and :port are ignored
+	Hosts []string `yaml:"hosts"`
+	// Namespaces are the Kubernetes namespaces kubectl and helm commands,
+	// and tools with a "namespace" parameter (MCP Kubernetes servers), may
+	// use; a command without -n uses "default", and --all-namespaces is only
+	// allowed by "*"
+	Namespaces []string     `yaml:"namespaces"`
+	Commands   CommandRules `yaml:"commands"`
+
+	allow, deny []*regexp.Regexp
+}
+
+// CommandRules are regular expressions checked against a "command"
+// parameter (shell, ssh). Every part of a command line (split at ;, &&, ||,
+// | and newlines) must match an Allow rule, if there are any, and none may
+// match a Deny rule.
+type CommandRules struct {
+	Allow []string `yaml:"allow"`
+	Deny  []string `yaml:"deny"`
+}
+
+// Load reads and validates a policy file
+func Load(filename string) (*File, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+	var f File
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse policy %s: %w", filename, err)
+	}
+	if len(f.Roles) == 0 {
+		return nil, fmt.Errorf("policy %s has no roles", filename)
+	}
+	if f.Default != "" && f.Roles[f.Default] == nil {
+		return nil, fmt.Errorf("policy %s: default role %q is not defined", filename, f.Default)
+	}
+	for name, r := range f.Roles {
+		if r == nil {
+			r = &Role{}
+			f.Roles[name] = r
+		}
+		r.Name = name
+		if err := r.compile(); err != nil {
+			return nil, fmt.Errorf("policy %s: role %s: %w", filename, name, err)
+		}
+	}
+	return &f, nil
+}
+
+// Role returns the named role, or the default role for ""
+func (f *File) Role(name string) (*Role, error) {
+	if name == "" {
+		name = f.Default
+		if name == "" {
+			return nil, errors.New("policy has no default role; choose one")
+		}
+	}
+	r, ok := f.Roles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown role %q", name)
+	}
+	return r, nil
+}
+
+// compile checks the role's patterns and risk and compiles its command
+// rules
+func (r *Role) compile() error {
+	if r.MaxRisk != "" {
+		risk, err := tools.ParseRisk(string(r.MaxRisk))
+		if err != nil {
+			return fmt.Errorf("max_risk: %w", err)
+		}
+		r.MaxRisk = risk
+	}
+	for _, list := range [][]string{r.Tools, r.DenyTools, r.Hosts, r.Namespaces} {
+		for _, p := range list {
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q", p)
+			}
+		}
+	}
+	for _, rule := range r.Commands.Allow {
+		re, err := regexp.Compile(rule)
+		if err != nil {
+			return fmt.Errorf("invalid command rule %q: %w", rule, err)
+		}
+		r.allow = append(r.allow, re)
+	}
+	for _, rule := range r.Commands.Deny {
+		re, err := regexp.Compile(rule)
+		if err != nil {
+			return fmt.Errorf("invalid command rule %q: %w", rule, err)
+		}
+		r.deny = append(r.deny, re)
+	}
+	return nil
+}
+
+// AllowsTool reports whether the role may use a tool at all, whatever its
+// parameters
+func (r *Role) AllowsTool(name string, meta tools.Meta) bool {
+	return r.checkTool(name, meta) == nil
+}
+
+// Check returns an error if the role may not call the tool with params
+func (r *Role) Check(name string, meta tools.Meta, params map[string]any) error {
+	if err := r.checkTool(name, meta); err != nil {
+		return err
+	}
+	if r.ReadOnly && !meta.ReadOnlyCall(params) {
+		return fmt.Errorf("role %s may only make read-only calls of %s", r.Name, name)
+	}
+	if r.Namespaces != nil {
+		if err := r.checkNamespaces(paramNamespaces(params)); err != nil {
+			return err
+		}
+	}
+	if host, ok := params["host"].(string); ok && r.Hosts != nil {
+		if !matchAny(r.Hosts, hostname(host)) {
+			return fmt.Errorf("role %s may not connect to %s", r.Name, host)
+		}
+	}
+	if command, ok := params["command"].(string); ok {
+		if err := r.checkCommand(command); err != nil {
+			return err
+		}
+	}
+	if risk := CallRisk(meta, params); r.MaxRisk != "" && !r.MaxRisk.AtLeast(risk) {
+		return fmt.Errorf("role %s may not make %s risk calls of %s", r.Name, risk, name)
+	}
+	return nil
+}
+
+func (r *Role) checkTool(name string, meta tools.Meta) error {
+	switch {
+	case r.Tools != nil && !matchAny(r.Tools, name), matchAny(r.DenyTools, name):
+		return fmt.Errorf("role %s may not use %s", r.Name, name)
+	case r.Categories != nil && !slices.Contains(r.Categories, meta.Category):
+		return fmt.Errorf("role %s may not use %s tools", r.Name, meta.Category)
+	case r.ReadOnly && !meta.ReadOnly && meta.ReadOnlyWhen == nil:
+		return fmt.Errorf("role %s may only use read-only tools", r.Name)
+	}
+	return nil
+}
+
+// commandSeparators split a command line into the commands it runs
+var commandSeparators = regexp.MustCompile(`\|\||&&|[;|&\n]`)
+
+func (r *Role) checkCommand(command string) error {
+	for _, re := range r.deny {
+		if re.MatchString(command) {
+			return fmt.Errorf("role %s may not run commands matching %q", r.Name, re.String())
+		}
+	}
+	if r.allow != nil {
+		if strings.Contains(command, "$(") || strings.Contains(command, "`") {
+			return fmt.Errorf("role %s may not use command substitution", r.Name)
+		}
+		for _, part := range commandSeparators.Split(command, -1) {
+			part = strings.TrimSpace(part)
+			if part != "" && !slices.ContainsFunc(r.allow, func(re *regexp.Regexp) bool { return re.MatchString(part) }) {
+				return fmt.Errorf("role %s may not run %q", r.Name, part)
+			}
+		}
+	}
+	if r.Namespaces != nil {
+		nss, err := namespaces(command)
+		if err != nil {
+			return fmt.Errorf("role %s can't check the namespaces the command uses: %w", r.Name, err)
+		}
+		return r.checkNamespaces(nss)
+	}
+	return nil
+}
+
+func (r *Role) checkNamespaces(nss []string) error {
+	for _, ns := range nss {
+		if !matchAny(r.Namespaces, ns) {
+			if ns == "*" {
+				return fmt.Errorf("role %s may not use all namespaces", r.Name)
+			}
+			return fmt.Errorf("role %s may not use namespace %s", r.Name, ns)
+		}
+	}
+	return nil
+}
+
+// paramNamespaces returns the namespaces a call's "namespace" parameter, or
+// that of an MCP call's arguments, names: "default" when it is empty, "*"
+// with all_namespaces/allNamespaces set
+func paramNamespaces(params map[string]any) []string {
+	var out []string
+	args, _ := params["arguments"].(map[string]any)
+	for _, p := range []map[string]any{params, args} {
+		if ns, ok := p["namespace"].(string); ok {
+			if ns == "" {
+				ns = "default"
+			}
+			out = append(out, ns)
+		}
+		if p["all_namespaces"] == true || p["allNamespaces"] == true {
+			out = append(out, "*")
+		}
+	}
+	return out
+}
+
+// nestedCommands run the command lines they are given, whose namespaces
+// can't be found
+var nestedCommands = []string{"sh", "bash", "dash", "zsh", "ksh", "eval", "xargs", "su", "watch", "source"}
+
+// namespaces returns the Kubernetes namespaces the kubectl and helm commands
+// in a command line use: "default" without -n, "*" for --all-namespaces. It
+// fails on a command line it can't split (see splitCommand) or one that
+// runs others through sh -c, eval or xargs.
+func namespaces(command string) ([]string, error) {
+	if strings.Contains(command, "$(") || strings.Contains(command, "`") {
+		return nil, fmt.Errorf("command substitution isn't allowed")
+	}
+	parts, err := splitCommand(command, true)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, fields := range parts {
+		if i := slices.IndexFunc(fields, func(f string) bool { return slices.Contains(nestedCommands, path.Base(f)) }); i >= 0 {
+			return nil, fmt.Errorf("%s runs commands that can't be checked", fields[i])
+		}
+		if !slices.ContainsFunc(fields, func(f string) bool {
+			base := path.Base(f)
+			return base == "kubectl" || base == "helm"
+		}) {
+			continue
+		}
+		var found []string
+		for i, f := range fields {
+			switch {
+			case f == "-A" || f == "--all-namespaces":
+				found = append(found, "*")
+			case (f == "-n" || f == "--namespace") && i+1 < len(fields):
+				found = append(found, fields[i+1])
+			case strings.HasPrefix(f, "--namespace="):
+				found = append(found, strings.TrimPrefix(f, "--namespace="))
+			case strings.HasPrefix(f, "-n") && len(f) > 2 && !strings.HasPrefix(f, "--"):
+				found = append(found, strings.TrimPrefix(f[2:], "="))
+			}
+		}
+		if found == nil {
+			found = []string{"default"}
+		}
+		out = append(out, found...)
+	}
+	return out, nil
+}
+
+// hostname strips user@ and :port from an ssh host
+func hostname(host string) string {
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		host = host[i+1:]
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return host
+}
+
+// matchAny reports whether name matches one of the wildcard patterns
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
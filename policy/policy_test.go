@@ -0,0 +1,144 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rathore/langchain-agent/tools"
+)
+
+const testPolicy = `
+default: operator
+roles:
+  viewer:
+    read_only: true
+  operator:
+    tools: ["*"]
+    deny_tools: [edge_gpio]
+    categories: [local, remote, knowledge, device]
+    hosts: ["*.lab.example.com", "10.0.0.*"]
+    namespaces: [default, "team-*"]
+    commands:
+      allow: ['^(ls|cat|df|uptime|grep|kubectl get)\b']
+      deny: ['\bsudo\b']
+  dev:
+    namespaces: [dev]
+  locked:
+    tools: []
+`
+
+func loadTestPolicy(t *testing.T, data string) (*File, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path)
+}
+
+func TestLoad(t *testing.T) {
+	f, err := loadTestPolicy(t, testPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, err := f.Role(""); err != nil || r.Name != "operator" {
+		t.Errorf("default role = %v, %v", r, err)
+	}
+	if _, err := f.Role("admin"); err == nil {
+		t.Error("an unknown role should fail")
+	}
+
+	for _, tt := range []struct{ data, wantErr string }{
+		{"roles: {}", "has no roles"},
+		{"default: admin\nroles:\n  viewer: {}", `default role "admin" is not defined`},
+		{"roles:\n  viewer:\n    commands:\n      deny: ['(']", "invalid command rule"},
+		{"roles:\n  viewer:\n    hosts: ['[a-']", "invalid pattern"},
+	} {
+		if _, err := loadTestPolicy(t, tt.data); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Load(%q) error = %v, want %q", tt.data, err, tt.wantErr)
+		}
+	}
+
+	f, err = loadTestPolicy(t, "roles:\n  viewer: {}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Role(""); err == nil {
+		t.Error("Role(\"\") without a default should fail")
+	}
+}
+
+func TestRole_Check(t *testing.T) {
+	f, err := loadTestPolicy(t, testPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := tools.Meta{Category: tools.CategoryLocal}
+	remote := tools.Meta{Category: tools.CategoryRemote}
+	knowledge := tools.Meta{Category: tools.CategoryKnowledge, ReadOnly: true}
+	workspace := tools.Meta{Category: tools.CategoryLocal, ReadOnlyWhen: &tools.ReadOnlyCalls{Param: "action", Values: []string{"list", "read"}}}
+
+	tests := []struct {
+		role    string
+		tool    string
+		meta    tools.Meta
+		params  map[string]any
+		wantErr string
+	}{
+		{"viewer", "wiki", knowledge, nil, ""},
+		{"viewer", "shell", local, map[string]any{"command": "ls"}, "only use read-only tools"},
+		{"viewer", "workspace", workspace, map[string]any{"action": "read"}, ""},
+		{"viewer", "workspace", workspace, map[string]any{"action": "write"}, "only make read-only calls of workspace"},
+		{"locked", "wiki", knowledge, nil, "may not use wiki"},
+		{"operator", "edge_gpio", tools.Meta{Category: tools.CategoryDevice}, nil, "may not use edge_gpio"},
+		{"operator", "mcp_fs", tools.Meta{Category: tools.CategoryMCP}, nil, "may not use mcp tools"},
+
+		{"operator", "ssh", remote, map[string]any{"host": "root@db1.lab.example.com:22", "command": "uptime"}, ""},
+		{"operator", "ssh", remote, map[string]any{"host": "10.0.0.7", "command": "df -h"}, ""},
+		{"operator", "ssh", remote, map[string]any{"host": "prod.example.com", "command": "uptime"}, "may not connect to prod.example.com"},
+
+		{"operator", "shell", local, map[string]any{"command": "ls -la | grep go"}, ""},
+		{"operator", "shell", local, map[string]any{"command": "ls; rm -rf /"}, `may not run "rm -rf /"`},
+		{"operator", "shell", local, map[string]any{"command": "cat $(which sh)"}, "command substitution"},
+		{"operator", "shell", local, map[string]any{"command": "sudo ls"}, "may not run commands matching"},
+
+		{"operator", "shell", local, map[string]any{"command": "kubectl get pods"}, ""},
+		{"operator", "shell", local, map[string]any{"command": "kubectl get pods -n team-a"}, ""},
+		{"operator", "shell", local, map[string]any{"command": "kubectl get pods --namespace=kube-system"}, "may not use namespace kube-system"},
+		{"operator", "shell", local, map[string]any{"command": "kubectl get pods -nkube-system"}, "may not use namespace kube-system"},
+		{"operator", "shell", local, map[string]any{"command": "kubectl get pods -A"}, "may not use all namespaces"},
+		{"operator", "k8s", local, map[string]any{"namespace": "team-b"}, ""},
+		{"operator", "k8s", local, map[string]any{"namespace": "kube-system"}, "may not use namespace kube-system"},
+		{"operator", "k8s", local, map[string]any{"tool_name": "pods_list", "arguments": map[string]any{"namespace": "kube-system"}}, "may not use namespace kube-system"},
+		{"operator", "k8s", local, map[string]any{"arguments": map[string]any{"allNamespaces": true}}, "may not use all namespaces"},
+		{"dev", "shell", local, map[string]any{"command": "kubectl -n dev get pods -o name > pods.txt && kubectl -n 'dev' logs api-1 | grep -c error"}, ""},
+		{"dev", "shell", local, map[string]any{"command": "kubectl -n dev get pods -o jsonpath='{.items[*].metadata.name}'"}, ""},
+		{"dev", "shell", local, map[string]any{"command": "sh -c 'kubectl -n kube-system delete pod x'"}, "sh runs commands that can't be checked"},
+		{"dev", "shell", local, map[string]any{"command": `eval "kubectl -n kube-system delete pod x"`}, "eval runs commands"},
+		{"dev", "shell", local, map[string]any{"command": "echo pod/x | xargs kubectl -n dev delete"}, "xargs runs commands"},
+		{"dev", "shell", local, map[string]any{"command": "/bin/bash -c 'kubectl get pods'"}, "/bin/bash runs commands"},
+		{"dev", "shell", local, map[string]any{"command": "kubectl 'get' pods -n \"kube-system\""}, "may not use namespace kube-system"},
+		{"dev", "shell", local, map[string]any{"command": "kubectl delete pod x --namespace={dev,kube-system}"}, "unquoted {"},
+		{"dev", "shell", local, map[string]any{"command": "kubectl -n $NS delete pod x"}, "unquoted $"},
+	}
+	for _, tt := range tests {
+		role, err := f.Role(tt.role)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = role.Check(tt.tool, tt.meta, tt.params)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: %s %v: unexpected error %v", tt.role, tt.tool, tt.params, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: %s %v: error = %v, want %q", tt.role, tt.tool, tt.params, err, tt.wantErr)
+		}
+	}
+
+	viewer, _ := f.Role("viewer")
+	if viewer.AllowsTool("shell", local) || !viewer.AllowsTool("wiki", knowledge) || !viewer.AllowsTool("workspace", workspace) {
+		t.Error("viewer should allow wiki and workspace and not shell")
+	}
+}
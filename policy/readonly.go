@@ -0,0 +1,615 @@
+package policy
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rathore/langchain-agent/tools"
+)
+
+// Checker checks a tool call before it runs; it is an agent.Policy
+type Checker interface {
+	Check(tool string, meta tools.Meta, params map[string]any) error
+}
+
+// ReadOnly returns the --read-only policy: only calls that look at state
+// may run, and then only if next (the session's role, or nil) allows them
+// too. A call looks at state if its tool is registered as read-only (or
+// the call is, see tools.Meta.ReadOnlyWhen), or its "command" (shell,
+// ssh) runs only commands known to change nothing, such as ls, grep,
+// kubectl get and systemctl status. Everything else is denied: writes,
+// unknown commands, redirections, MCP tools not annotated readOnlyHint.
+func ReadOnly(next Checker) Checker {
+	return readOnly{next}
+}
+
+type readOnly struct{ next Checker }
+
+func (p readOnly) Check(tool string, meta tools.Meta, params map[string]any) error {
+	if err := checkReadOnly(tool, meta, params); err != nil {
+		return err
+	}
+	if p.next != nil {
+		return p.next.Check(tool, meta, params)
+	}
+	return nil
+}
+
+func checkReadOnly(tool string, meta tools.Meta, params map[string]any) error {
+	if meta.ReadOnlyCall(params) {
+		return nil
+	}
+	if command, ok := params["command"].(string); ok {
+		if err := ReadOnlyCommand(command); err != nil {
+			return fmt.Errorf("read-only mode: %w", err)
+		}
+		return nil
+	}
+	if name, ok := params["tool_name"].(string); ok && meta.Category == tools.CategoryMCP {
+		return fmt.Errorf("read-only mode: MCP tool %s may change state (it isn't annotated readOnlyHint)", name)
+	}
+	if action, ok := params["action"].(string); ok {
+		return fmt.Errorf("read-only mode: %s %s may change state", tool, action)
+	}
+	return fmt.Errorf("read-only mode: %s may change state", tool)
+}
+
+// readOnlyCommands are the commands that change nothing, with a check of
+// their arguments for those that only sometimes do (nil: any arguments)
+var readOnlyCommands = map[string]func(args []string) error{
+	// Files and text
+	"ls": nil, "cat": nil, "head": nil, "tail": nil, "less": nil, "more": nil, "wc": nil, "stat": nil,
+	"grep": nil, "egrep": nil, "fgrep": nil, "zgrep": nil, "zcat": nil, "cut": nil,
+	"tr": nil, "column": nil, "jq": nil, "diff": nil, "md5sum": nil, "sha256sum": nil, "readlink": nil,
+	"realpath": nil, "basename": nil, "dirname": nil, "pwd": nil, "echo": nil, "printf": nil, "true": nil,
+	"test": nil,
+	"file": denyArgs("-C", "--compile"),
+	"find": denyArgs("-delete", "-exec", "-execdir", "-ok", "-okdir", "-fprint", "-fprint0", "-fprintf", "-fls"),
+	"rg":   denyArgs("--pre", "--hostname-bin"), // run programs
+	"sort": denyArgs("-o", "--output", "--compress-program"),
+	"tree": denyArgs("-o"),
+	"uniq": maxOperands(1), // uniq in out writes out
+	"sed":  checkSed,
+	"awk":  checkAwk,
+
+	// System
+	"df": nil, "du": nil, "free": nil, "uptime": nil, "uname": nil, "whoami": nil, "id": nil, "ps": nil,
+	"pgrep": nil, "pidof": nil, "lsof": nil, "lsblk": nil, "lscpu": nil, "lsusb": nil, "lspci": nil,
+	"vmstat": nil, "iostat": nil, "mpstat": nil, "nproc": nil, "sensors": nil, "printenv": nil, "which": nil,
+	"type": nil, "getent": nil, "last": nil, "w": nil, "who": nil,
+	"date":       denyArgs("-s", "--set"),
+	"hostname":   all(denyArgs("-F", "--file"), maxOperands(0)), // hostname NAME sets it
+	"env":        checkEnv,
+	"dmesg":      denyArgs("-c", "-C", "--clear", "--read-clear", "-n", "--console-level", "-D", "--console-off", "-E", "--console-on"),
+	"journalctl": denyArgs("--rotate", "--vacuum-size", "--vacuum-time", "--vacuum-files", "--flush", "--sync", "--relinquish-var", "--smart-relinquish-var", "--setup-keys", "--update-catalog"),
+	"systemctl":  subcommands(nil, "status", "show", "cat", "is-active", "is-enabled", "is-failed", "is-system-running", "list-units", "list-unit-files", "list-timers", "list-sockets", "list-dependencies", "list-jobs"),
+	"vcgencmd":   subcommands(nil, "measure_temp", "measure_volts", "measure_clock", "get_throttled", "get_mem", "version", "get_config"),
+	"nvidia-smi": denyArgs("-r", "--gpu-reset", "-pm", "--persistence-mode", "-e", "--ecc-config", "-c", "--compute-mode", "-pl", "--power-limit", "-ac", "--applications-clocks", "-rac", "-lgc", "-rgc"),
+
+	// Network
+	"ping": nil, "dig": nil, "nslookup": nil, "host": nil, "traceroute": nil, "tracepath": nil, "mtr": nil,
+	"netstat": nil,
+	"ss":      denyArgs("-K", "--kill"),
+	"ip":      checkIP,
+	"curl":    checkCurl,
+
+	// Containers, orchestration and version control
+	"kubectl": subcommands(kubectlValueFlags, "get", "describe", "logs", "top", "explain", "api-resources", "api-versions",
+		"version", "cluster-info", "events", "auth can-i", "auth whoami", "config view", "config get-contexts",
+		"config current-context", "config get-clusters", "config get-users", "rollout status", "rollout history"),
+	// --post-renderer runs a program on the rendered manifests
+	"helm": all(denyArgs("--post-renderer", "--post-renderer-args", "--output-dir"),
+		subcommands(helmValueFlags, "list", "ls", "status", "get", "history", "show", "inspect", "search", "version",
+			"env", "template", "lint", "repo list", "plugin list", "dependency list")),
+	"docker": subcommands(dockerValueFlags, "ps", "images", "logs", "inspect", "stats", "top", "version", "info", "port",
+		"diff", "history", "container ls", "container list", "container inspect", "container logs", "image ls",
+		"image list", "image inspect", "network ls", "network inspect", "volume ls", "volume inspect", "system df",
+		"compose ps", "compose logs", "compose config"),
+	// -c and --config-env can set core.fsmonitor or core.pager to a command
+	"git": all(denyArgs("-c", "--config-env", "--output", "--ext-diff"),
+		subcommands(gitValueFlags, "status", "log", "diff", "show", "blame", "ls-files", "rev-parse", "describe",
+			"shortlog", "remote -v", "branch --list", "tag --list")),
+}
+
+// Flags of kubectl, helm, docker and git that take a value, so that the
+// subcommand after e.g. "-n prod" is found
+var (
+	kubectlValueFlags = []string{"-n", "--namespace", "--context", "--kubeconfig", "--cluster", "--user", "-s", "--server", "--token", "--as", "--request-timeout"}
+	helmValueFlags    = []string{"-n", "--namespace", "--kube-context", "--kubeconfig"}
+	dockerValueFlags  = []string{"-H", "--host", "-c", "--context", "--config", "-l", "--log-level"}
+	gitValueFlags     = []string{"-C", "--git-dir", "--work-tree"}
+)
+
+// binDirs are the directories a command named by its path may be in
+var binDirs = []string{"/bin", "/usr/bin", "/sbin", "/usr/sbin", "/usr/local/bin"}
+
+// ReadOnlyCommand returns an error unless every command of a command line
+// is known to only look at state. Command substitution and output
+// redirection (except to /dev/null and between descriptors, as in 2>&1)
+// are denied, as are words the shell would expand (variables, braces and
+// globs, which can turn into flags such as -delete), sudo, commands run by another (xargs, sh -c) and
+// VAR=value prefixes, which can change what a command runs (LD_PRELOAD,
+// GIT_EXTERNAL_DIFF).
+func ReadOnlyCommand(command string) error {
+	if strings.Contains(command, "$(") || strings.Contains(command, "`") || strings.Contains(command, "<(") || strings.Contains(command, ">(") {
+		return fmt.Errorf("command substitution isn't allowed")
+	}
+	parts, err := splitCommand(command, false)
+	if err != nil {
+		return err
+	}
+	for _, fields := range parts {
+		if len(fields) == 0 {
+			continue
+		}
+		if name, _, ok := strings.Cut(fields[0], "="); ok && name != "" {
+			return fmt.Errorf("setting %s for a command isn't allowed", name)
+		}
+		name := fields[0]
+		if strings.Contains(name, "/") {
+			if !slices.Contains(binDirs, path.Dir(name)) {
+				return fmt.Errorf("%s isn't known to be read-only", name)
+			}
+			name = path.Base(name)
+		}
+		check, ok := readOnlyCommands[name]
+		if !ok {
+			return fmt.Errorf("%s isn't known to be read-only", name)
+		}
+		if check != nil {
+			if err := check(fields[1:]); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// splitCommand splits a command line into its commands (at ;, &&, ||, |, &
+// and newlines outside quotes), each as its words with quotes removed. It
+// fails on $ outside single quotes and {, }, *, ? and [ outside any, so
+// that the words are what the command gets, and on output redirection to a
+// file unless writes, when the file's name is left out of the words.
+func splitCommand(command string, writes bool) ([][]string, error) {
+	var parts [][]string
+	var words []string
+	var word strings.Builder
+	inWord := false
+	target := false // the next word is a file redirected to
+	endWord := func() {
+		if inWord {
+			if !target {
+				words = append(words, word.String())
+			}
+			word.Reset()
+			inWord, target = false, false
+		}
+	}
+	endPart := func() {
+		endWord()
+		parts = append(parts, words)
+		words = nil
+	}
+	var quote byte
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '$' && quote == '"' {
+				return nil, fmt.Errorf("$ isn't allowed in double quotes: the shell would expand it")
+			} else if c == '\\' && quote == '"' && i+1 < len(command) {
+				i++
+				word.WriteByte(command[i])
+			} else {
+				word.WriteByte(c)
+			}
+		case c == '\'' || c == '"':
+			quote, inWord = c, true
+		case c == '\\' && i+1 < len(command):
+			i++
+			word.WriteByte(command[i])
+			inWord = true
+		case c == '>' || c == '&' && i+1 < len(command) && command[i+1] == '>':
+			// A descriptor number before > belongs to the redirection
+			if _, err := strconv.Atoi(word.String()); inWord && err == nil {
+				word.Reset()
+				inWord = false
+			}
+			endWord()
+			j := i + 1
+			for j < len(command) && (command[j] == '>' || command[j] == '&' && j == i+1 && c == '&') {
+				j++
+			}
+			rest := strings.TrimLeft(command[j:], " \t")
+			switch {
+			case len(rest) > 1 && rest[0] == '&' && (rest[1] >= '0' && rest[1] <= '9' || rest[1] == '-'):
+				i = len(command) - len(rest) + 1 // 2>&1
+			case strings.HasPrefix(rest, "/dev/null") && (len(rest) == len("/dev/null") || strings.ContainsRune(" \t;|&)\n", rune(rest[len("/dev/null")]))):
+				i = len(command) - len(rest) + len("/dev/null") - 1
+			case writes:
+				i, target = j-1, true
+			default:
+				return nil, fmt.Errorf("output redirection may write files")
+			}
+		case c == ';' || c == '|' || c == '&' || c == '\n':
+			endPart()
+			if i+1 < len(command) && (command[i+1] == c && c != ';' && c != '\n') {
+				i++ // && and ||
+			}
+		case c == ' ' || c == '\t':
+			endWord()
+		case strings.IndexByte("${}*?[", c) >= 0:
+			return nil, fmt.Errorf("unquoted %c isn't allowed: the shell would expand it", c)
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	endPart()
+	return parts, nil
+}
+
+// denyArgs allows a command unless it has one of the arguments. A denied
+// one-letter flag is also found glued to its value (-o/tmp/x) or to other
+// flags (-uo), so some harmless values are turned down too.
+func denyArgs(denied ...string) func([]string) error {
+	return func(args []string) error {
+		for _, a := range args {
+			flag, _, _ := strings.Cut(a, "=")
+			if slices.Contains(denied, flag) {
+				return fmt.Errorf("%s may change state", a)
+			}
+			if len(a) > 2 && a[0] == '-' && a[1] != '-' {
+				for _, c := range a[1:] {
+					if slices.Contains(denied, "-"+string(c)) {
+						return fmt.Errorf("%s may change state", a)
+					}
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// all allows a command only if every check does
+func all(checks ...func([]string) error) func([]string) error {
+	return func(args []string) error {
+		for _, check := range checks {
+			if err := check(args); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// maxOperands allows a command with at most n arguments that aren't flags
+func maxOperands(n int) func([]string) error {
+	return func(args []string) error {
+		operands := 0
+		for _, a := range args {
+			if !strings.HasPrefix(a, "-") {
+				operands++
+			}
+		}
+		if operands > n {
+			return fmt.Errorf("%d operands may change state", operands)
+		}
+		return nil
+	}
+}
+
+// subcommands allows a command whose subcommand (the first one or two
+// words that aren't flags) is in allowed. valueFlags are the flags whose
+// value is the next argument.
+func subcommands(valueFlags []string, allowed ...string) func([]string) error {
+	return func(args []string) error {
+		var words []string
+		for i := 0; i < len(args) && len(words) < 2; i++ {
+			a := args[i]
+			if strings.HasPrefix(a, "-") {
+				if slices.Contains(valueFlags, a) {
+					i++
+				}
+				if len(words) == 1 && slices.Contains(allowed, words[0]+" "+a) {
+					return nil // e.g. git remote -v
+				}
+				continue
+			}
+			words = append(words, a)
+		}
+		if len(words) == 0 {
+			return fmt.Errorf("no subcommand")
+		}
+		if slices.Contains(allowed, words[0]) || len(words) == 2 && slices.Contains(allowed, words[0]+" "+words[1]) {
+			return nil
+		}
+		if !slices.ContainsFunc(allowed, func(s string) bool { return strings.HasPrefix(s, words[0]+" ") }) {
+			words = words[:1] // e.g. kubectl delete, not delete pod
+		}
+		return fmt.Errorf("%s may change state", strings.Join(words, " "))
+	}
+}
+
+// checkSed denies in-place edits, script files (which can't be checked)
+// and scripts that write files or run commands
+func checkSed(args []string) error {
+	var scripts []string
+	expression := false // a script was given with -e
+	var operands []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case strings.HasPrefix(a, "--"):
+			flag, value, hasValue := strings.Cut(a, "=")
+			switch flag {
+			case "--in-place":
+				return fmt.Errorf("%s edits files", a)
+			case "--file":
+				return fmt.Errorf("%s: script files can't be checked", a)
+			case "--expression":
+				if !hasValue && i+1 < len(args) {
+					i++
+					value = args[i]
+				}
+				scripts, expression = append(scripts, value), true
+			}
+		case strings.HasPrefix(a, "-") && len(a) > 1:
+			for j := 1; j < len(a); j++ {
+				switch a[j] {
+				case 'i':
+					return fmt.Errorf("%s edits files", a)
+				case 'f':
+					return fmt.Errorf("%s: script files can't be checked", a)
+				case 'e', 'l':
+					value := a[j+1:]
+					if value == "" && i+1 < len(args) {
+						i++
+						value = args[i]
+					}
+					if a[j] == 'e' {
+						scripts, expression = append(scripts, value), true
+					}
+					j = len(a)
+				}
+			}
+		default:
+			operands = append(operands, a)
+		}
+	}
+	if !expression && len(operands) > 0 {
+		scripts = append(scripts, operands[0])
+	}
+	for _, script := range scripts {
+		if err := checkSedScript(script); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSedScript denies the e, w and W commands and the e and w flags of
+// s, going through the script command by command; a script it can't
+// follow is denied too
+func checkSedScript(script string) error {
+	i := 0
+	// delimited skips past the text up to the next unescaped delim
+	delimited := func(delim byte) bool {
+		for ; i < len(script); i++ {
+			switch script[i] {
+			case '\\':
+				i++
+			case delim:
+				i++
+				return true
+			}
+		}
+		return false
+	}
+	toEOL := func() {
+		for i < len(script) && script[i] != '\n' {
+			i++
+		}
+	}
+	for i < len(script) {
+		c := script[i]
+		switch {
+		case strings.IndexByte(" \t\n;{}!,~+$", c) >= 0 || c >= '0' && c <= '9':
+			i++
+		case c == '/':
+			i++
+			if !delimited('/') {
+				return fmt.Errorf("can't check the sed script")
+			}
+		case c == '\\': // \cREGEXc
+			if i+1 >= len(script) {
+				return fmt.Errorf("can't check the sed script")
+			}
+			delim := script[i+1]
+			i += 2
+			if !delimited(delim) {
+				return fmt.Errorf("can't check the sed script")
+			}
+		case c == '#', c == 'a', c == 'i', c == 'c', c == ':', c == 'b', c == 't', c == 'T', c == 'r', c == 'R':
+			toEOL() // text, a label or a file to read
+		case c == 'e' || c == 'w' || c == 'W':
+			return fmt.Errorf("the %c command may run commands or write files", c)
+		case c == 's' || c == 'y':
+			if i+1 >= len(script) {
+				return fmt.Errorf("can't check the sed script")
+			}
+			delim := script[i+1]
+			i += 2
+			if !delimited(delim) || !delimited(delim) {
+				return fmt.Errorf("can't check the sed script")
+			}
+			for c == 's' && i < len(script) && strings.IndexByte(" \t\n;}", script[i]) < 0 {
+				if script[i] == 'e' || script[i] == 'w' {
+					return fmt.Errorf("the %c flag of s may run commands or write files", script[i])
+				}
+				i++
+			}
+		case c >= 'a' && c <= 'z' || c == '=' || c >= 'A' && c <= 'Z':
+			i++ // p, d, n, q, ...
+		default:
+			return fmt.Errorf("can't check the sed script")
+		}
+	}
+	return nil
+}
+
+// awkFiles are the awk options that read program files, which can't be
+// checked; awkWrites are gawk's that write files or load a shared library
+var (
+	awkFiles  = []string{"-f", "--file", "-i", "--include", "-E", "--exec"}
+	awkWrites = []string{"-o", "--pretty-print", "-p", "--profile", "-d", "--dump-variables", "-l", "--load"}
+)
+
+// checkAwk denies awk programs that run commands or write files: any | or
+// > (so comparisons must be written with <), system(), program files and
+// gawk's options that write files or load code
+func checkAwk(args []string) error {
+	for _, a := range args {
+		switch {
+		case awkFlag(a, awkFiles):
+			return fmt.Errorf("%s: program files can't be checked", a)
+		case awkFlag(a, awkWrites):
+			return fmt.Errorf("%s may write files or load code", a)
+		case strings.ContainsAny(a, "|>") || strings.Contains(a, "system("):
+			return fmt.Errorf("the program may run commands or write files")
+		}
+	}
+	return nil
+}
+
+// awkFlag reports whether a is one of flags: a long one also by a prefix
+// (gawk takes --prof for --profile), a short one also glued to a value or
+// to other flags (-ofile, -so), up to -F, -v or -W, whose value follows
+func awkFlag(a string, flags []string) bool {
+	if strings.HasPrefix(a, "--") {
+		name, _, _ := strings.Cut(a, "=")
+		return len(name) > 2 && slices.ContainsFunc(flags, func(f string) bool { return strings.HasPrefix(f, name) })
+	}
+	if len(a) < 2 || a[0] != '-' {
+		return false
+	}
+	for _, c := range a[1:] {
+		if slices.Contains(flags, "-"+string(c)) {
+			return true
+		}
+		if strings.ContainsRune("FvW", c) {
+			return false
+		}
+	}
+	return false
+}
+
+// ipVerbs are the ip commands that change state. ip takes any prefix of a
+// command (a for add, s for set), so the verb is denied if it starts one.
+var ipVerbs = []string{"add", "append", "change", "chg", "delete", "exec", "flush", "prepend", "replace", "restore", "save", "set", "update"}
+
+// checkIP allows ip OBJECT with a verb that changes nothing, such as show,
+// list or get
+func checkIP(args []string) error {
+	var words []string
+	for i := 0; i < len(args) && len(words) < 2; i++ {
+		a := args[i]
+		if strings.HasPrefix(a, "-") {
+			flag := strings.TrimLeft(a, "-")
+			if flag != "" && (strings.HasPrefix("batch", flag) || strings.HasPrefix("force", flag)) {
+				return fmt.Errorf("%s may change state", a)
+			}
+			if slices.Contains([]string{"n", "netns", "f", "family", "rc", "rcvbuf", "l", "loops"}, flag) {
+				i++
+			}
+			continue
+		}
+		words = append(words, a)
+	}
+	if len(words) < 2 {
+		return nil // ip OBJECT shows it
+	}
+	if verb := words[1]; slices.ContainsFunc(ipVerbs, func(v string) bool { return strings.HasPrefix(v, verb) }) {
+		return fmt.Errorf("%s may change state", verb)
+	}
+	return nil
+}
+
+// checkEnv allows env only to print the environment, not to run a command
+func checkEnv(args []string) error {
+	for _, a := range args {
+		if flag, _, _ := strings.Cut(a, "="); flag == "--split-string" || !strings.HasPrefix(a, "--") && strings.HasPrefix(a, "-") && strings.Contains(a, "S") {
+			return fmt.Errorf("env may not run other commands")
+		}
+		if !strings.HasPrefix(a, "-") && !strings.Contains(a, "=") {
+			return fmt.Errorf("env may not run other commands")
+		}
+	}
+	return nil
+}
+
+// curlValueFlags are the short curl options that take a value; in a group
+// such as -sd x or -o/tmp/x the rest of it, or the next argument, is the
+// value
+const curlValueFlags = "AbcCdDeEFHKmoPQrTuUwxXyYz"
+
+// checkCurl allows GET and HEAD requests that don't write files
+func checkCurl(args []string) error {
+	// split short groups so each option is checked with its value
+	var opts [][2]string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if strings.HasPrefix(a, "--") {
+			flag, value, hasValue := strings.Cut(a, "=")
+			if !hasValue && (flag == "--request" || flag == "--output") && i+1 < len(args) {
+				i++
+				value = args[i]
+			}
+			opts = append(opts, [2]string{flag, value})
+			continue
+		}
+		if !strings.HasPrefix(a, "-") || len(a) < 2 {
+			continue
+		}
+		for j := 1; j < len(a); j++ {
+			flag := "-" + string(a[j])
+			if !strings.ContainsRune(curlValueFlags, rune(a[j])) {
+				opts = append(opts, [2]string{flag, ""})
+				continue
+			}
+			value := a[j+1:]
+			if value == "" && i+1 < len(args) {
+				i++
+				value = args[i]
+			}
+			opts = append(opts, [2]string{flag, value})
+			break
+		}
+	}
+	for _, o := range opts {
+		flag, value := o[0], o[1]
+		switch {
+		case flag == "-X" || flag == "--request":
+			if m := strings.ToUpper(value); m != "GET" && m != "HEAD" {
+				return fmt.Errorf("%s requests may change state", m)
+			}
+		case slices.Contains([]string{"-d", "--data", "--data-raw", "--data-binary", "--data-urlencode", "--data-ascii", "--json", "-F", "--form", "--form-string", "-T", "--upload-file"}, flag):
+			return fmt.Errorf("%s sends data", flag)
+		case (flag == "-o" || flag == "--output") && value == "/dev/null":
+		case slices.Contains([]string{"-o", "--output", "-O", "--remote-name", "--remote-name-all", "-c", "--cookie-jar", "-D", "--dump-header",
+			"--trace", "--trace-ascii", "--libcurl", "--etag-save", "--output-dir", "--stderr"}, flag):
+			return fmt.Errorf("%s writes files", flag)
+		case flag == "-K" || flag == "--config":
+			return fmt.Errorf("%s: config files can't be checked", flag)
+		}
+	}
+	return nil
+}
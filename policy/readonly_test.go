@@ -0,0 +1,173 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rathore/langchain-agent/tools"
+)
+
+func TestReadOnlyCommand(t *testing.T) {
+	for _, command := range []string{
+		"df -h",
+		"ls -la /var/log | grep -E 'error|warn' | sort | uniq -c",
+		"kubectl -n prod get pods -o wide",
+		"kubectl --context=staging describe deploy/api && kubectl logs api-1 --tail=50",
+		"kubectl rollout status deploy/api",
+		"helm -n prod list",
+		"systemctl status nginx; journalctl -u nginx --since today",
+		"find /var/log -name '*.gz' -mtime +7",
+		"curl -s -o /dev/null -w '%{http_code}' http://localhost:8080/health",
+		"cat /etc/os-release 2>/dev/null || uname -a",
+		"ps aux 2>&1 | head",
+		"sort -u /etc/hosts",
+		"/usr/bin/uptime",
+		"awk '90 < $3 {print $1}' usage.txt",
+		"sed -n '1,20p' app.log",
+		"sed -n -e '/error/,+2p' -e 's/secret=[^ ]*/secret=***/g' app.log",
+		"sed -E 's|/var/|/srv/|g; /^#/d' paths.txt | head",
+		"git -C /srv/app log --oneline -5",
+		"docker compose ps",
+		"ip addr show",
+		"ip -n prod -4 a show dev eth0",
+		"ip link",
+		"rg -n TODO src",
+		"awk -F: -v OFS=, '{print $1, $NF}' /etc/passwd",
+		`grep "a b" f`,
+		"file /bin/ls",
+		"helm template api ./chart -n prod",
+	} {
+		if err := ReadOnlyCommand(command); err != nil {
+			t.Errorf("ReadOnlyCommand(%q) = %v, want allowed", command, err)
+		}
+	}
+
+	for _, tt := range []struct{ command, wantErr string }{
+		{"rm -rf /tmp/x", "rm isn't known to be read-only"},
+		{"sudo cat /etc/shadow", "sudo isn't known"},
+		{"echo hi > /etc/motd", "output redirection"},
+		{"ls &>> out.log", "output redirection"},
+		{"ls; reboot", "reboot isn't known"},
+		{"cat $(which ls)", "command substitution"},
+		{"kubectl delete pod api-1", "delete may change state"},
+		{"kubectl -n prod apply -f app.yaml", "apply may change state"},
+		{"kubectl scale deploy/api --replicas=0", "scale may change state"},
+		{"kubectl rollout restart deploy/api", "rollout restart may change state"},
+		{"helm upgrade api ./chart", "upgrade may change state"},
+		{"systemctl restart nginx", "restart may change state"},
+		{"find / -name core -delete", "-delete may change state"},
+		{"sed -i s/a/b/ app.conf", "-i edits files"},
+		{"awk 'BEGIN {system(\"reboot\")}'", "may run commands"},
+		{"curl -X POST http://api/restart", "POST requests may change state"},
+		{"curl -d @data.json http://api", "-d sends data"},
+		{"curl -o page.html http://example.com", "-o writes files"},
+		{"env rm -rf /", "env may not run other commands"},
+		{"ls | xargs rm", "xargs isn't known"},
+		{"./ls", "./ls isn't known"},
+		{"sort -o /etc/hosts /etc/hosts", "-o may change state"},
+		{"hostname evil", "1 operands may change state"},
+		{"ip link set eth0 down", "set may change state"},
+		{"git push origin main", "push may change state"},
+		{"echo 'unterminated", "unterminated quote"},
+		{"LD_PRELOAD=/tmp/evil.so ls", "setting LD_PRELOAD"},
+		{"GIT_EXTERNAL_DIFF=/tmp/x git diff", "setting GIT_EXTERNAL_DIFF"},
+		{`awk 'BEGIN{print "x">"/tmp/f"}'`, "may run commands or write files"},
+		{`awk '{print|"sh"}' f`, "may run commands or write files"},
+		{"awk -f prog.awk f", "program files can't be checked"},
+		{"sed -n '1e touch /tmp/pwn' f", "the e command"},
+		{"sed 's/x/id/e' f", "the e flag of s"},
+		{"sed -n 's/a/b/w /tmp/out' f", "the w flag of s"},
+		{"sed -n '/re/w /tmp/out' f", "the w command"},
+		{"sed -e p -e 'W out' f", "the W command"},
+		{"sed -ni s/a/b/ f", "-ni edits files"},
+		{"sed -f script.sed f", "script files can't be checked"},
+		{"git -c core.fsmonitor='touch /tmp/x' status", "-c may change state"},
+		{"git diff --output=/etc/passwd", "--output=/etc/passwd may change state"},
+		{"git diff --ext-diff", "--ext-diff may change state"},
+		{"rg --pre=/tmp/evil foo .", "--pre=/tmp/evil may change state"},
+		{"ip addr a 10.0.0.1/24 dev eth0", "a may change state"},
+		{"ip link s eth0 down", "s may change state"},
+		{"ip -4 route del default", "del may change state"},
+		{"ip -batch cmds.txt", "-batch may change state"},
+		{"ip netns exec prod ls", "exec may change state"},
+		{"curl -o/tmp/x http://a", "-o writes files"},
+		{"curl -sSo page.html http://a", "-o writes files"},
+		{"curl -sd x http://a", "-d sends data"},
+		{"curl -sXPOST http://a", "POST requests"},
+		{"curl --request DELETE http://a", "DELETE requests"},
+		{"curl -K evil.cfg http://a", "config files can't be checked"},
+		{"curl --trace /tmp/t http://a", "--trace writes files"},
+		{"sort --compress-program=sh f", "--compress-program=sh may change state"},
+		{"sort -uo/etc/hosts /etc/hosts", "-uo/etc/hosts may change state"},
+		{"env -S 'rm -rf /'", "env may not run other commands"},
+		{"hostname -F /tmp/name", "-F may change state"},
+		{"date -s2020-01-01", "-s2020-01-01 may change state"},
+		{"find /tmp -name x $NOPE-delete", "unquoted $"},
+		{"find . {-delete,-print}", "unquoted {"},
+		{"sed -e s/a/b/ $NOPE-i /etc/hosts", "unquoted $"},
+		{"curl $NOPE-XPOST http://x/", "unquoted $"},
+		{"sort ${X}-o /etc/passwd /dev/null", "unquoted $"},
+		{"date $X-s 2020-01-01", "unquoted $"},
+		{`date "$X-s" 2020-01-01`, "$ isn't allowed in double quotes"},
+		{"find . -name x -* /tmp", "unquoted *"},
+		{"awk -o/tmp/out '{print}' f", "-o/tmp/out may write files"},
+		{"awk --pretty-print=/tmp/out 1 f", "--pretty-print=/tmp/out may write files"},
+		{"awk --prof 1 f", "--prof may write files"},
+		{"awk -p 1 f", "-p may write files"},
+		{"awk -d/tmp/vars 1 f", "-d/tmp/vars may write files"},
+		{"awk -l evil 1 f", "-l may write files or load code"},
+		{"awk --load=evil 1 f", "--load=evil may write files or load code"},
+		{"helm template api ./chart --post-renderer /tmp/evil", "--post-renderer may change state"},
+		{"helm template api ./chart --post-renderer=./x --post-renderer-args=-c", "--post-renderer=./x may change state"},
+		{"helm template api ./chart --output-dir /etc", "--output-dir may change state"},
+		{"file -C -m /tmp/magic", "-C may change state"},
+		{"file --compile", "--compile may change state"},
+	} {
+		if err := ReadOnlyCommand(tt.command); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("ReadOnlyCommand(%q) = %v, want %q", tt.command, err, tt.wantErr)
+		}
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	shell := tools.Meta{Category: tools.CategoryLocal}
+	mcp := tools.Meta{Category: tools.CategoryMCP, ReadOnlyWhen: &tools.ReadOnlyCalls{Param: "tool_name", Values: []string{"read_file"}}}
+	workspace := tools.Meta{Category: tools.CategoryLocal, ReadOnlyWhen: &tools.ReadOnlyCalls{Param: "action", Values: []string{"list", "read"}}}
+
+	p := ReadOnly(nil)
+	for _, tt := range []struct {
+		tool    string
+		meta    tools.Meta
+		params  map[string]any
+		wantErr string
+	}{
+		{"wiki", tools.Meta{ReadOnly: true}, map[string]any{"query": "backup"}, ""},
+		{"shell", shell, map[string]any{"command": "df -h"}, ""},
+		{"shell", shell, map[string]any{"command": "rm -rf /"}, "read-only mode: rm isn't known to be read-only"},
+		{"ssh", tools.Meta{Category: tools.CategoryRemote}, map[string]any{"host": "web1", "command": "kubectl get pods"}, ""},
+		{"fs", mcp, map[string]any{"tool_name": "read_file"}, ""},
+		{"fs", mcp, map[string]any{"tool_name": "write_file"}, "MCP tool write_file may change state"},
+		{"workspace", workspace, map[string]any{"action": "read", "name": "a.log"}, ""},
+		{"workspace", workspace, map[string]any{"action": "delete", "name": "a.log"}, "workspace delete may change state"},
+		{"edge_gpio", tools.Meta{Category: tools.CategoryDevice}, map[string]any{"pin": 4}, "edge_gpio may change state"},
+	} {
+		err := p.Check(tt.tool, tt.meta, tt.params)
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("Check(%s, %v) = %v, want %q", tt.tool, tt.params, err, tt.wantErr)
+		}
+	}
+
+	// The session's role still applies
+	f, err := loadTestPolicy(t, testPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	operator, _ := f.Role("operator")
+	p = ReadOnly(operator)
+	if err := p.Check("shell", shell, map[string]any{"command": "find / -name core"}); err == nil || !strings.Contains(err.Error(), "role operator may not run") {
+		t.Errorf("read-only with role operator = %v, want the role's denial", err)
+	}
+	if err := p.Check("shell", shell, map[string]any{"command": "df -h"}); err != nil {
+		t.Errorf("read-only with role operator, df = %v", err)
+	}
+}
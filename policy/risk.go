@@ -0,0 +1,45 @@
+package policy
+
+import (
+	"fmt"
+
+	"github.com/rathore/langchain-agent/tools"
+)
+
+// CallRisk is the risk of a tool call: the tool's (see
+// tools.Meta.CallRisk), except that a "command" (shell, ssh) that
+// ReadOnlyCommand accepts is low risk
+func CallRisk(meta tools.Meta, params map[string]any) tools.Risk {
+	if command, ok := params["command"].(string); ok && ReadOnlyCommand(command) == nil {
+		return tools.RiskLow
+	}
+	return meta.CallRisk(params)
+}
+
+// ApproveFunc asks whether a tool call of the given risk may run
+type ApproveFunc func(tool string, risk tools.Risk, params map[string]any) bool
+
+// Approve returns a policy that asks approve before a call of risk min or
+// higher runs, once next (the session's role, or nil) allows it; calls
+// turned down are denied
+func Approve(next Checker, min tools.Risk, approve ApproveFunc) Checker {
+	return approval{next: next, min: min, approve: approve}
+}
+
+type approval struct {
+	next    Checker
+	min     tools.Risk
+	approve ApproveFunc
+}
+
+func (p approval) Check(tool string, meta tools.Meta, params map[string]any) error {
+	if p.next != nil {
+		if err := p.next.Check(tool, meta, params); err != nil {
+			return err
+		}
+	}
+	if risk := CallRisk(meta, params); risk.AtLeast(p.min) && !p.approve(tool, risk, params) {
+		return fmt.Errorf("%s risk call of %s not approved", risk, tool)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rathore/langchain-agent/tools"
+)
+
+var (
+	shellMeta     = (&tools.ShellTool{}).Meta()
+	workspaceMeta = (&tools.WorkspaceTool{}).Meta()
+)
+
+func TestCallRisk(t *testing.T) {
+	tests := []struct {
+		meta   tools.Meta
+		params map[string]any
+		want   tools.Risk
+	}{
+		{shellMeta, map[string]any{"command": "df -h"}, tools.RiskLow},
+		{shellMeta, map[string]any{"command": "systemctl restart nginx"}, tools.RiskHigh},
+		{workspaceMeta, map[string]any{"action": "read"}, tools.RiskLow},
+		{workspaceMeta, map[string]any{"action": "delete"}, tools.RiskMedium},
+		{tools.Meta{}, nil, tools.RiskMedium},
+		{tools.Meta{ReadOnly: true}, nil, tools.RiskLow},
+	}
+	for _, tt := range tests {
+		if got := CallRisk(tt.meta, tt.params); got != tt.want {
+			t.Errorf("CallRisk(%+v, %v) = %s, want %s", tt.meta, tt.params, got, tt.want)
+		}
+	}
+}
+
+func TestRole_MaxRisk(t *testing.T) {
+	f, err := loadTestPolicy(t, "roles:\n  careful:\n    max_risk: Medium\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	role, _ := f.Role("careful")
+	if err := role.Check("shell", shellMeta, map[string]any{"command": "kubectl get pods"}); err != nil {
+		t.Errorf("read-only command denied: %v", err)
+	}
+	if err := role.Check("workspace", workspaceMeta, map[string]any{"action": "write"}); err != nil {
+		t.Errorf("medium risk call denied: %v", err)
+	}
+	err = role.Check("shell", shellMeta, map[string]any{"command": "kubectl delete pod api-1"})
+	if err == nil || !strings.Contains(err.Error(), "may not make high risk calls of shell") {
+		t.Errorf("high risk call: err = %v", err)
+	}
+
+	if _, err := loadTestPolicy(t, "roles:\n  r:\n    max_risk: extreme\n"); err == nil || !strings.Contains(err.Error(), "invalid risk") {
+		t.Errorf("invalid max_risk: err = %v", err)
+	}
+}
+
+func TestApprove(t *testing.T) {
+	var asked []string
+	answer := true
+	p := Approve(ReadOnly(nil), tools.RiskMedium, func(tool string, risk tools.Risk, params map[string]any) bool {
+		asked = append(asked, tool+":"+string(risk))
+		return answer
+	})
+
+	if err := p.Check("workspace", workspaceMeta, map[string]any{"action": "list"}); err != nil || len(asked) != 0 {
+		t.Errorf("low risk call: err = %v, asked %v", err, asked)
+	}
+	// next denies before anyone is asked
+	if err := p.Check("workspace", workspaceMeta, map[string]any{"action": "write"}); err == nil || len(asked) != 0 {
+		t.Errorf("call next denies: err = %v, asked %v", err, asked)
+	}
+
+	p = Approve(nil, tools.RiskMedium, func(tool string, risk tools.Risk, params map[string]any) bool {
+		asked = append(asked, tool+":"+string(risk))
+		return answer
+	})
+	if err := p.Check("workspace", workspaceMeta, map[string]any{"action": "write"}); err != nil {
+		t.Errorf("approved call: err = %v", err)
+	}
+	answer = false
+	err := p.Check("shell", shellMeta, map[string]any{"command": "reboot"})
+	if err == nil || !strings.Contains(err.Error(), "high risk call of shell not approved") {
+		t.Errorf("turned down call: err = %v", err)
+	}
+	if strings.Join(asked, ",") != "workspace:medium,shell:high" {
+		t.Errorf("asked %v", asked)
+	}
+}
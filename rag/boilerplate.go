@@ -0,0 +1,138 @@
+package rag
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// DefaultSkipSelectors are the HTML elements of Confluence exports that
+// repeat on every page without saying anything about it: the space
+// navigation sidebar, the "Document generated by Confluence" footer and the
+// comment section. Breadcrumbs and the "Created by" line are read as page
+// metadata and never indexed as text.
+var DefaultSkipSelectors = []string{
+	"nav", "#navigation", "#sidebar", ".ia-secondary-container", ".acs-side-bar",
+	"footer", "#footer",
+	"#comments", "#comments-section", ".comment-thread",
+}
+
+// DefaultSkipPatterns match text chunks that are export boilerplate in any
+// format: author/date lines and generator footers
+var DefaultSkipPatterns = []string{
+	`^Created by .+ on \w+ \d{1,2}, \d{4}`,
+	`^(Last )?(Modified|modified|Updated|updated) by .+ (on|at) \w+ \d{1,2}, \d{4}`,
+	`^Posted by .+ at \w+ \d{1,2}, \d{4}`,
+	`^Document generated by Confluence on `,
+}
+
+// htmlSelector is a simple CSS selector: a tag, #id and/or .class
+type htmlSelector struct {
+	tag, id, class string
+}
+
+// selectorRe matches the selectors parseSelectors accepts
+var selectorRe = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9]*)?(#[\w-]+)?(\.[\w-]+)?$`)
+
+// parseSelectors parses SkipSelectors. Only tag, #id, .class and their
+// combinations (div#footer, div.sidebar) are supported.
+func parseSelectors(list []string) ([]htmlSelector, error) {
+	var sels []htmlSelector
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		m := selectorRe.FindStringSubmatch(s)
+		if s == "" || m == nil {
+			return nil, fmt.Errorf("unsupported skip selector %q (use tag, #id, .class or tag.class)", s)
+		}
+		sels = append(sels, htmlSelector{
+			tag:   strings.ToLower(m[1]),
+			id:    strings.TrimPrefix(m[2], "#"),
+			class: strings.TrimPrefix(m[3], "."),
+		})
+	}
+	return sels, nil
+}
+
+// matches reports whether element n matches the selector
+func (s htmlSelector) matches(n *html.Node) bool {
+	if s.tag != "" && n.Data != s.tag {
+		return false
+	}
+	if s.id != "" && !strings.EqualFold(htmlAttr(n, "id"), s.id) {
+		return false
+	}
+	if s.class != "" && !hasClass(n, s.class) {
+		return false
+	}
+	return true
+}
+
+// skipElement reports whether n is boilerplate to leave out of the page. A
+// Confluence export section (.pageSection) is matched by its title heading
+// too, so "#comments" drops the whole Comments section.
+func (l *ConfluenceLoader) skipElement(n *html.Node) bool {
+	for _, s := range l.skip {
+		if s.matches(n) {
+			return true
+		}
+		if hasClass(n, "pageSection") {
+			if title := findElement(n, func(c *html.Node) bool { return hasClass(c, "pageSectionTitle") }); title != nil && s.matches(title) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// findElement returns the first element under n (depth first) satisfying ok
+func findElement(n *html.Node, ok func(*html.Node) bool) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		if ok(c) {
+			return c
+		}
+		if found := findElement(c, ok); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// compileSkipPatterns compiles SkipPatterns
+func compileSkipPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid skip pattern %q: %w", p, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// dropBoilerplate returns the chunks that match none of the skip patterns
+func (idx *Indexer) dropBoilerplate(chunks []TextChunk) []TextChunk {
+	if len(idx.skipText) == 0 {
+		return chunks
+	}
+	kept := make([]TextChunk, 0, len(chunks))
+	for _, c := range chunks {
+		text := strings.TrimSpace(strings.TrimPrefix(c.Content, "- "))
+		skip := false
+		for _, re := range idx.skipText {
+			if re.MatchString(text) {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,115 @@
+package rag
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// exportPage is a Confluence export page with the usual boilerplate around
+// one paragraph of content
+const exportPage = `<html><head><title>OPS : Restart API</title></head><body>
+<div id="page">
+<div class="ia-secondary-container"><ul><li>Pages</li><li>Blog posts and other space tools</li></ul></div>
+<div id="main" class="aui-page-panel">
+<div id="content" class="view">
+<div id="main-content" class="wiki-content group">
+<p>Drain the node, then restart the api pods one at a time.</p>
+<p>Created by Jane Doe on Mar 03, 2020, in the old wiki.</p>
+</div>
+<div class="pageSection group">
+<div class="pageSectionHeader"><h2 id="comments" class="pageSectionTitle">Comments:</h2></div>
+<table><tr><td><p>Does this also apply to the worker pods?</p><div class="smallfont">Posted by jdoe at Mar 04, 2020 10:11</div></td></tr></table>
+</div>
+</div>
+</div>
+<div id="footer" role="contentinfo"><section class="footer-body"><p>Document generated by Confluence on Mar 05, 2020 09:00</p></section></div>
+</div>
+</body></html>`
+
+func TestConfluenceLoader_SkipsBoilerplate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "restart.html")
+	os.WriteFile(path, []byte(exportPage), 0644)
+
+	l := NewConfluenceLoader(dir)
+	page, _ := l.LoadPage(path)
+	if len(page.Chunks) < 5 {
+		t.Fatalf("without skip selectors: %d chunks, want the boilerplate too", len(page.Chunks))
+	}
+
+	var err error
+	if l.skip, err = parseSelectors(DefaultSkipSelectors); err != nil {
+		t.Fatal(err)
+	}
+	page, _ = l.LoadPage(path)
+	var got []string
+	for _, c := range page.Chunks {
+		got = append(got, c.Content)
+	}
+	want := "Drain the node, then restart the api pods one at a time.|Created by Jane Doe on Mar 03, 2020, in the old wiki."
+	if strings.Join(got, "|") != want {
+		t.Errorf("chunks = %q, want only the content", got)
+	}
+}
+
+func TestParseSelectors(t *testing.T) {
+	sels, err := parseSelectors([]string{"nav", "#footer", "DIV.pageSection", "span#x.y"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []htmlSelector{{tag: "nav"}, {id: "footer"}, {tag: "div", class: "pageSection"}, {tag: "span", id: "x", class: "y"}}
+	for i := range want {
+		if sels[i] != want[i] {
+			t.Errorf("selector %d = %+v, want %+v", i, sels[i], want[i])
+		}
+	}
+	for _, bad := range []string{"div > p", "", "[role=nav]"} {
+		if _, err := parseSelectors([]string{bad}); err == nil {
+			t.Errorf("parseSelectors(%q) should fail", bad)
+		}
+	}
+}
+
+func TestIndexer_SkipPatterns(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "restart.html"), []byte(exportPage), 0644)
+
+	for _, tt := range []struct {
+		name     string
+		patterns []string
+		want     int
+	}{
+		{"defaults", DefaultSkipPatterns, 1},
+		{"none", nil, 2},
+		{"custom", []string{`^Drain`}, 1},
+	} {
+		store := NewMemoryStore()
+		config := DefaultConfig()
+		config.WikiPath = dir
+		config.ChunkTokens = 0 // one chunk per element
+		config.SkipPatterns = tt.patterns
+		config.Store = store
+		config.Embedder = &fakeEmbedder{}
+		idx, err := NewIndexer(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := idx.Index(context.Background()); err != nil {
+			t.Fatalf("%s: Index() error = %v", tt.name, err)
+		}
+		if n, _ := store.Count(context.Background()); n != tt.want {
+			t.Errorf("%s: %d documents, want %d", tt.name, n, tt.want)
+		}
+	}
+
+	config := DefaultConfig()
+	config.SkipPatterns = []string{"(unclosed"}
+	config.Store = NewMemoryStore()
+	config.Embedder = &fakeEmbedder{}
+	if _, err := NewIndexer(config); err == nil || !strings.Contains(err.Error(), "invalid skip pattern") {
+		t.Errorf("NewIndexer() error = %v, want invalid skip pattern", err)
+	}
+}
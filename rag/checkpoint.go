@@ -0,0 +1,83 @@
+package rag
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// checkpointPages is how many pages are indexed (described, embedded and
+// upserted) between checkpoints
+const checkpointPages = 25
+
+// indexCheckpoint records the progress of a full Index run so an interrupted
+// run can resume: the collection is kept and finished pages are skipped.
+type indexCheckpoint struct {
+	Collection string         `json:"collection"`
+	Source     string         `json:"source"`
+	EmbedModel string         `json:"embed_model"` // vectors from another model can't be mixed in
+	Started    time.Time      `json:"started"`
+	PagesDone  []string       `json:"pages_done"` // page file paths / URLs
+	Stats      IndexStats     `json:"stats"`      // counts so far
+	Failures   []IndexFailure `json:"failures,omitempty"`
+}
+
+// newCheckpoint starts tracking a fresh full index run
+func (idx *Indexer) newCheckpoint() *indexCheckpoint {
+	return &indexCheckpoint{
+		Collection: idx.config.CollectionName,
+		Source:     idx.source(),
+		EmbedModel: idx.embedModel(),
+		Started:    time.Now(),
+	}
+}
+
+// loadCheckpoint returns the checkpoint of an interrupted run of this
+// collection and source, or nil if there is none (or it doesn't match)
+func (idx *Indexer) loadCheckpoint() *indexCheckpoint {
+	if idx.config.CheckpointFile == "" || idx.config.FreshIndex {
+		return nil
+	}
+	data, err := os.ReadFile(idx.config.CheckpointFile)
+	if err != nil {
+		return nil
+	}
+	var cp indexCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		fmt.Fprintf(idx.out, "Warning: ignoring unreadable index checkpoint: %v\n", err)
+		return nil
+	}
+	want := idx.newCheckpoint()
+	if cp.Collection != want.Collection || cp.Source != want.Source || cp.EmbedModel != want.EmbedModel {
+		return nil
+	}
+	return &cp
+}
+
+// saveCheckpoint persists progress after a group of pages is stored
+func (idx *Indexer) saveCheckpoint(cp *indexCheckpoint) error {
+	if idx.config.CheckpointFile == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(idx.config.CheckpointFile), 0755); err != nil {
+		return err
+	}
+	tmp := idx.config.CheckpointFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, idx.config.CheckpointFile)
+}
+
+// clearCheckpoint removes the checkpoint once a run completes
+func (idx *Indexer) clearCheckpoint() {
+	if idx.config.CheckpointFile != "" {
+		os.Remove(idx.config.CheckpointFile)
+	}
+}
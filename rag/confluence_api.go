@@ -0,0 +1,255 @@
+package rag
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// ConfluenceAPIConfig configures the live Confluence REST API loader
+type ConfluenceAPIConfig struct {
+	BaseURL     string   // e.g. https://acme.atlassian.net/wiki or https://confluence.acme.com
+	User        string   // Cloud: account email (basic auth with Token). Empty: Token is sent as a Bearer PAT (Server/DC)
+	Token       string   // API token (Cloud) or personal access token (Server/DC)
+	Spaces      []string // Optional space keys to restrict to
+	CQL         string   // Optional extra CQL, ANDed with the space/type filter
+	PageSize    int      // Results per request (default: 25)
+	StateFile   string   // Optional: persist last-modified watermark here for delta sync
+	TableFormat string   // "rows" (default) or "markdown"; see ConfluenceLoader.TableFormat
+}
+
+// ConfluenceAPILoader pulls pages directly from the Confluence REST API
+// (/rest/api/content/search), following pagination links. When StateFile is
+// set, only pages modified since the last successful sync are fetched.
+//
+// Images are not downloaded; only the page body text is indexed.
+type ConfluenceAPILoader struct {
+	config ConfluenceAPIConfig
+	client *http.Client
+	parser *ConfluenceLoader
+	since  time.Time // watermark read from StateFile
+	latest time.Time // newest last-modified seen in this run
+}
+
+// Ensure ConfluenceAPILoader implements Loader
+var _ Loader = (*ConfluenceAPILoader)(nil)
+
+// confluenceSyncState is the on-disk delta-sync watermark
+type confluenceSyncState struct {
+	LastModified time.Time `json:"last_modified"`
+}
+
+// NewConfluenceAPILoader creates a loader for a live Confluence instance
+func NewConfluenceAPILoader(config ConfluenceAPIConfig) *ConfluenceAPILoader {
+	if config.PageSize == 0 {
+		config.PageSize = 25
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+
+	l := &ConfluenceAPILoader{
+		config: config,
+		client: &http.Client{Timeout: 60 * time.Second},
+		parser: &ConfluenceLoader{TableFormat: config.TableFormat},
+	}
+	if config.StateFile != "" {
+		if data, err := os.ReadFile(config.StateFile); err == nil {
+			var state confluenceSyncState
+			if json.Unmarshal(data, &state) == nil {
+				l.since = state.LastModified
+			}
+		}
+	}
+	return l
+}
+
+// Incremental reports whether this run is a delta sync (a previous watermark
+// exists), in which case the existing collection must be kept.
+func (l *ConfluenceAPILoader) Incremental() bool {
+	return !l.since.IsZero()
+}
+
+// CommitSync persists the newest last-modified time seen so the next run only
+// fetches pages changed after it. Call after the pages were indexed successfully.
+func (l *ConfluenceAPILoader) CommitSync() error {
+	if l.config.StateFile == "" || l.latest.IsZero() {
+		return nil
+	}
+	data, err := json.MarshalIndent(confluenceSyncState{LastModified: l.latest}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.config.StateFile), 0755); err != nil {
+		return fmt.Errorf("failed to create sync state dir: %w", err)
+	}
+	return os.WriteFile(l.config.StateFile, data, 0644)
+}
+
+// confluenceSearchResponse is the subset of /rest/api/content/search we use
+type confluenceSearchResponse struct {
+	Results []struct {
+		ID    string `json:"id"`
+		Title string `json:"title"`
+		Space struct {
+			Key string `json:"key"`
+		} `json:"space"`
+		Version struct {
+			When time.Time `json:"when"`
+		} `json:"version"`
+		Metadata struct {
+			Labels struct {
+				Results []struct {
+					Name string `json:"name"`
+				} `json:"results"`
+			} `json:"labels"`
+		} `json:"metadata"`
+		Ancestors []struct {
+			Title string `json:"title"`
+		} `json:"ancestors"`
+		Body struct {
+			Storage struct {
+				Value string `json:"value"`
+			} `json:"storage"`
+		} `json:"body"`
+		Links struct {
+			WebUI string `json:"webui"`
+		} `json:"_links"`
+	} `json:"results"`
+	Links struct {
+		Base string `json:"base"`
+		Next string `json:"next"`
+	} `json:"_links"`
+}
+
+// LoadAll fetches every page matching the configured spaces/CQL
+func (l *ConfluenceAPILoader) LoadAll() ([]PageContent, error) {
+	params := url.Values{}
+	params.Set("cql", l.buildCQL())
+	params.Set("expand", "body.storage,version,space,metadata.labels,ancestors")
+	params.Set("limit", fmt.Sprintf("%d", l.config.PageSize))
+	next := l.config.BaseURL + "/rest/api/content/search?" + params.Encode()
+
+	var pages []PageContent
+	for next != "" {
+		result, err := l.fetch(next)
+		if err != nil {
+			return nil, err
+		}
+
+		base := result.Links.Base
+		if base == "" {
+			base = l.config.BaseURL
+		}
+
+		for _, r := range result.Results {
+			page, err := l.parseBody(r.Body.Storage.Value)
+			if err != nil {
+				fmt.Printf("Warning: failed to parse page %s (%s): %v\n", r.ID, r.Title, err)
+				continue
+			}
+			page.Title = r.Title
+			page.FilePath = base + r.Links.WebUI
+			for i, c := range page.Chunks {
+				if c.Type == "heading" && c.Anchor == "" {
+					page.Chunks[i].Anchor = confluenceAnchor(r.Title, c.Content)
+				}
+			}
+			page.Space = r.Space.Key
+			page.LastModified = r.Version.When
+			for _, label := range r.Metadata.Labels.Results {
+				page.Labels = append(page.Labels, strings.ToLower(label.Name))
+			}
+			for _, a := range r.Ancestors {
+				page.Breadcrumbs = append(page.Breadcrumbs, a.Title)
+			}
+			if r.Version.When.After(l.latest) {
+				l.latest = r.Version.When
+			}
+			if len(page.Chunks) > 0 {
+				pages = append(pages, *page)
+			}
+		}
+
+		next = ""
+		if result.Links.Next != "" {
+			next = base + result.Links.Next
+		}
+	}
+
+	return pages, nil
+}
+
+// buildCQL combines the type, space, user CQL and delta-sync filters
+func (l *ConfluenceAPILoader) buildCQL() string {
+	clauses := []string{"type=page"}
+	if len(l.config.Spaces) > 0 {
+		quoted := make([]string, len(l.config.Spaces))
+		for i, s := range l.config.Spaces {
+			quoted[i] = fmt.Sprintf("%q", s)
+		}
+		clauses = append(clauses, fmt.Sprintf("space in (%s)", strings.Join(quoted, ",")))
+	}
+	if l.config.CQL != "" {
+		clauses = append(clauses, "("+l.config.CQL+")")
+	}
+	if !l.since.IsZero() {
+		clauses = append(clauses, fmt.Sprintf("lastmodified >= %q", l.since.UTC().Format("2006-01-02 15:04")))
+	}
+	return strings.Join(clauses, " AND ") + " order by lastmodified asc"
+}
+
+// fetch performs one authenticated search request
+func (l *ConfluenceAPILoader) fetch(reqURL string) (*confluenceSearchResponse, error) {
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if l.config.User != "" {
+		req.SetBasicAuth(l.config.User, l.config.Token)
+	} else if l.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+l.config.Token)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query confluence: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("confluence returned %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var result confluenceSearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &result, nil
+}
+
+// confluenceAnchor returns the fragment Confluence generates for a heading
+// when rendering a page: "<PageTitle>-<Heading>" with spaces removed
+func confluenceAnchor(title, heading string) string {
+	return strings.ReplaceAll(title, " ", "") + "-" + strings.ReplaceAll(heading, " ", "")
+}
+
+// parseBody extracts chunks from a page's storage-format XHTML body
+func (l *ConfluenceAPILoader) parseBody(body string) (*PageContent, error) {
+	doc, err := html.Parse(strings.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	page := &PageContent{}
+	l.parser.extractContent(doc, page, "")
+	page.Images = nil // storage-format attachments are not local files
+	return page, nil
+}
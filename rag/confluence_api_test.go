@@ -0,0 +1,109 @@
+package rag
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfluenceAPILoader_LoadAll(t *testing.T) {
+	var srv *httptest.Server
+	var cqls []string
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q, want Bearer secret", got)
+		}
+		cqls = append(cqls, r.URL.Query().Get("cql"))
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("cursor") == "" {
+			fmt.Fprintf(w, `{"results":[{"id":"1","title":"Runbook","version":{"when":"2024-03-01T10:00:00.000Z"},
+				"metadata":{"labels":{"results":[{"name":"Runbook"},{"name":"api"}]}},
+				"ancestors":[{"title":"Operations"},{"title":"Runbooks"}],
+				"body":{"storage":{"value":"<h1>Restart</h1><p>Run systemctl restart api on the node.</p>"}},
+				"_links":{"webui":"/spaces/OPS/pages/1"}}],
+				"_links":{"base":%q,"next":"/rest/api/content/search?cursor=abc"}}`, srv.URL)
+			return
+		}
+		fmt.Fprintf(w, `{"results":[{"id":"2","title":"Empty","version":{"when":"2024-04-01T10:00:00.000Z"},
+			"body":{"storage":{"value":""}},"_links":{"webui":"/spaces/OPS/pages/2"}}],
+			"_links":{"base":%q}}`, srv.URL)
+	}))
+	defer srv.Close()
+
+	stateFile := filepath.Join(t.TempDir(), "sync.json")
+	loader := NewConfluenceAPILoader(ConfluenceAPIConfig{
+		BaseURL:   srv.URL,
+		Token:     "secret",
+		Spaces:    []string{"OPS"},
+		StateFile: stateFile,
+	})
+	if loader.Incremental() {
+		t.Error("Incremental() = true before first sync")
+	}
+
+	pages, err := loader.LoadAll()
+	if err != nil {
+		t.Fatalf("LoadAll() error = %v", err)
+	}
+	if len(pages) != 1 {
+		t.Fatalf("LoadAll() = %d pages, want 1 (empty page skipped)", len(pages))
+	}
+	if pages[0].Title != "Runbook" {
+		t.Errorf("Title = %q, want Runbook", pages[0].Title)
+	}
+	if pages[0].FilePath != srv.URL+"/spaces/OPS/pages/1" {
+		t.Errorf("FilePath = %q, want page URL", pages[0].FilePath)
+	}
+	if got := pages[0].Chunks[0].Anchor; got != "Runbook-Restart" {
+		t.Errorf("heading Anchor = %q, want Runbook-Restart", got)
+	}
+	if got := strings.Join(pages[0].Labels, ","); got != "runbook,api" {
+		t.Errorf("Labels = %q, want runbook,api", got)
+	}
+	if got := strings.Join(pages[0].Breadcrumbs, " > "); got != "Operations > Runbooks" {
+		t.Errorf("Breadcrumbs = %q, want Operations > Runbooks", got)
+	}
+	if len(pages[0].Chunks) != 2 {
+		t.Errorf("Chunks = %d, want 2", len(pages[0].Chunks))
+	}
+	if !strings.Contains(cqls[0], `space in ("OPS")`) {
+		t.Errorf("cql = %q, want space filter", cqls[0])
+	}
+
+	if err := loader.CommitSync(); err != nil {
+		t.Fatalf("CommitSync() error = %v", err)
+	}
+	if _, err := os.Stat(stateFile); err != nil {
+		t.Fatalf("state file not written: %v", err)
+	}
+
+	// A second loader picks up the watermark and only asks for newer pages
+	delta := NewConfluenceAPILoader(ConfluenceAPIConfig{BaseURL: srv.URL, Token: "secret", StateFile: stateFile})
+	if !delta.Incremental() {
+		t.Error("Incremental() = false after sync")
+	}
+	if cql := delta.buildCQL(); !strings.Contains(cql, `lastmodified >= "2024-04-01 10:00"`) {
+		t.Errorf("delta cql = %q, want lastmodified filter", cql)
+	}
+}
+
+func TestConfluenceAPILoader_BasicAuthAndErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "me@acme.com" || pass != "tok" {
+			t.Errorf("basic auth = %q/%q/%v", user, pass, ok)
+		}
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	loader := NewConfluenceAPILoader(ConfluenceAPIConfig{BaseURL: srv.URL, User: "me@acme.com", Token: "tok"})
+	if _, err := loader.LoadAll(); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("LoadAll() error = %v, want 403", err)
+	}
+}
@@ -0,0 +1,156 @@
+package rag
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// Confluence HTML exports wrap each page's content in navigation and metadata
+// markup: the breadcrumb trail (<ol id="breadcrumbs">), the "Created by ...,
+// last modified by ... on Mar 03, 2020" line (<div class="page-metadata">)
+// and the label list (<div id="labels-section">, <a rel="tag">). The space
+// key is only in the Space Details table of the export's index.html.
+
+// modifiedRe matches the date at the end of the page-metadata line
+var modifiedRe = regexp.MustCompile(`\bon ([A-Z][a-z]{2} \d{1,2}, \d{4})\s*$`)
+
+// hasClass reports whether an element has class in its class attribute
+func hasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(htmlAttr(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// extractPageMeta records the export's breadcrumbs, last-modified date and
+// labels on page. Returns true when n was one of those elements, so it isn't
+// indexed as content.
+func (l *ConfluenceLoader) extractPageMeta(n *html.Node, page *PageContent) bool {
+	switch {
+	case n.Data == "ol" && htmlAttr(n, "id") == "breadcrumbs":
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && c.Data == "li" {
+				if text := l.extractText(c); text != "" {
+					page.Breadcrumbs = append(page.Breadcrumbs, text)
+				}
+			}
+		}
+		return true
+
+	case hasClass(n, "page-metadata"):
+		if m := modifiedRe.FindStringSubmatch(l.extractText(n)); m != nil {
+			if t, err := time.Parse("Jan 2, 2006", m[1]); err == nil {
+				page.LastModified = t
+			}
+		}
+		return true
+
+	case htmlAttr(n, "id") == "labels-section" || hasClass(n, "label-list"):
+		l.collectLabels(n, page)
+		return true
+
+	case n.Data == "a" && htmlAttr(n, "rel") == "tag":
+		l.collectLabels(n, page)
+		return true
+	}
+	return false
+}
+
+// collectLabels adds the text of every label link under n to page.Labels
+func (l *ConfluenceLoader) collectLabels(n *html.Node, page *PageContent) {
+	if n.Type == html.ElementNode && n.Data == "a" {
+		label := strings.ToLower(l.extractText(n))
+		if label != "" && !slices.Contains(page.Labels, label) {
+			page.Labels = append(page.Labels, label)
+		}
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		l.collectLabels(c, page)
+	}
+}
+
+// spaceKey returns the key of the space exported to dir: the Key row of the
+// Space Details table in the nearest index.html at or above dir, within the
+// export. Returns "" when there is none.
+func (l *ConfluenceLoader) spaceKey(dir string) string {
+	if key, ok := l.spaces[dir]; ok {
+		return key
+	}
+	key := l.readSpaceKey(filepath.Join(dir, "index.html"))
+	if base := filepath.Clean(l.basePath); key == "" && dir != base && strings.HasPrefix(dir, base) {
+		if parent := filepath.Dir(dir); parent != dir {
+			key = l.spaceKey(parent)
+		}
+	}
+	if l.spaces == nil {
+		l.spaces = make(map[string]string)
+	}
+	l.spaces[dir] = key
+	return key
+}
+
+// readSpaceKey reads the space key from an export's index.html
+func (l *ConfluenceLoader) readSpaceKey(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	doc, err := html.Parse(f)
+	if err != nil {
+		return ""
+	}
+
+	var key string
+	var find func(n *html.Node)
+	find = func(n *html.Node) {
+		if key != "" {
+			return
+		}
+		if n.Type == html.ElementNode && n.Data == "tr" {
+			var cells []string
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.ElementNode && (c.Data == "th" || c.Data == "td") {
+					cells = append(cells, l.extractText(c))
+				}
+			}
+			if len(cells) == 2 && strings.EqualFold(strings.TrimSuffix(cells[0], ":"), "key") {
+				key = cells[1]
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			find(c)
+		}
+	}
+	find(doc)
+	return key
+}
+
+// labelsValue renders labels as the "labels" payload field. The value is
+// wrapped in commas (",runbook,postgres,") so a label filter can match
+// ",runbook," as a substring in every store without hitting "runbook-old".
+func labelsValue(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	return "," + strings.Join(labels, ",") + ","
+}
+
+// SplitLabels returns the labels of a "labels" payload field
+func SplitLabels(value string) []string {
+	value = strings.Trim(value, ",")
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
@@ -0,0 +1,101 @@
+package rag
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfluenceLoader_ExportMetadata(t *testing.T) {
+	dir := t.TempDir()
+	index := `<html><head><title>Operations</title></head><body>
+<h2 id="space-details">Space Details:</h2>
+<table class="confluenceTable">
+<tr><th class="confluenceTh">Key:</th><td class="confluenceTd">OPS</td></tr>
+<tr><th class="confluenceTh">Name:</th><td class="confluenceTd">Operations</td></tr>
+</table></body></html>`
+	page := `<html><head><title>Operations : Restart API</title></head><body>
+<div id="breadcrumb-section"><ol id="breadcrumbs">
+<li class="first"><span><a href="../index.html">Operations</a></span></li>
+<li><span><a href="Runbooks_65538.html">Runbooks</a></span></li>
+</ol></div>
+<div class="page-metadata">Created by <span class="author">Jane Doe</span>, last modified by <span class="editor">John Smith</span> on Mar 03, 2020</div>
+<div id="main-content" class="wiki-content group"><p>Drain the node, then restart the api pods one at a time.</p></div>
+<div id="labels-section" class="pageSection group"><ul class="label-list">
+<li class="aui-label"><a class="aui-label-split-main" href="#" rel="tag">Runbook</a></li>
+<li class="aui-label"><a class="aui-label-split-main" href="#" rel="tag">api</a></li>
+</ul></div>
+</body></html>`
+	os.WriteFile(filepath.Join(dir, "index.html"), []byte(index), 0644)
+	os.Mkdir(filepath.Join(dir, "pages"), 0755)
+	os.WriteFile(filepath.Join(dir, "pages", "Restart-API_65539.html"), []byte(page), 0644)
+
+	pages, err := NewConfluenceLoader(dir).LoadAll()
+	if err != nil {
+		t.Fatalf("LoadAll() error = %v", err)
+	}
+	var restart *PageContent
+	for i := range pages {
+		if strings.HasSuffix(pages[i].FilePath, "Restart-API_65539.html") {
+			restart = &pages[i]
+		}
+	}
+	if restart == nil {
+		t.Fatalf("LoadAll() = %d pages without the restart page", len(pages))
+	}
+	if restart.Space != "OPS" {
+		t.Errorf("Space = %q, want OPS", restart.Space)
+	}
+	if got := strings.Join(restart.Breadcrumbs, " > "); got != "Operations > Runbooks" {
+		t.Errorf("Breadcrumbs = %q", got)
+	}
+	if got := strings.Join(restart.Labels, ","); got != "runbook,api" {
+		t.Errorf("Labels = %q, want runbook,api", got)
+	}
+	if want := time.Date(2020, 3, 3, 0, 0, 0, 0, time.UTC); !restart.LastModified.Equal(want) {
+		t.Errorf("LastModified = %v, want %v", restart.LastModified, want)
+	}
+	// The metadata is not page content
+	if len(restart.Chunks) != 1 || !strings.HasPrefix(restart.Chunks[0].Content, "Drain the node") {
+		t.Errorf("Chunks = %+v, want only the paragraph", restart.Chunks)
+	}
+}
+
+func TestIndexer_LabelFilter(t *testing.T) {
+	dir := t.TempDir()
+	for name, body := range map[string]string{
+		"restart.html": `<p>Restart the api pods one at a time after draining the node.</p><a rel="tag">runbook</a>`,
+		"old.html":     `<p>The old restart procedure rebooted every node in the cluster at once.</p><a rel="tag">runbook-old</a>`,
+	} {
+		page := `<html><head><title>` + name + `</title></head><body>` + body + `</body></html>`
+		os.WriteFile(filepath.Join(dir, name), []byte(page), 0644)
+	}
+
+	store := NewMemoryStore()
+	config := DefaultConfig()
+	config.WikiPath = dir
+	config.Store = store
+	config.Embedder = &fakeEmbedder{}
+	idx, err := NewIndexer(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Index(context.Background()); err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	result, err := store.Scroll(context.Background(), ScrollQuery{Filter: &Filter{Label: "runbook"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Docs) == 0 {
+		t.Fatal("no documents labeled runbook")
+	}
+	for _, d := range result.Docs {
+		if d.Metadata["page_title"] != "restart.html" || d.Metadata["labels"] != ",runbook," {
+			t.Errorf("label filter returned %+v", d.Metadata)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package rag
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"strings"
+)
+
+// MinHash / LSH parameters. 32 bands of 4 rows make chunks with Jaccard
+// similarity above ~0.6 very likely to share a bucket; candidates are then
+// confirmed against the configured threshold.
+const (
+	dedupShingleWords = 5
+	minhashBands      = 32
+	minhashRows       = 4
+	minhashSize       = minhashBands * minhashRows
+)
+
+// deduper drops text chunks that are near-identical to one already seen in
+// the run: Confluence exports repeat navigation blocks, footers and included
+// pages on many pages, and indexing every copy crowds out real results.
+// Similarity is the Jaccard index of word 5-gram shingles, estimated with
+// MinHash and bucketed with locality-sensitive hashing so each chunk is only
+// compared with likely matches.
+type deduper struct {
+	threshold float64
+	sigs      [][minhashSize]uint64
+	buckets   map[uint64][]int // band hash -> indexes into sigs
+	dropped   int
+}
+
+// newDeduper returns a deduper, or nil if threshold is 0 (disabled)
+func newDeduper(threshold float64) *deduper {
+	if threshold <= 0 {
+		return nil
+	}
+	return &deduper{threshold: threshold, buckets: make(map[uint64][]int)}
+}
+
+// filter returns docs without near-duplicates of earlier chunks. Only text
+// chunks are compared; image descriptions and OCR text are kept.
+func (d *deduper) filter(docs []Document) []Document {
+	if d == nil {
+		return docs
+	}
+	kept := docs[:0:0]
+	for _, doc := range docs {
+		if doc.SourceType == "text" && d.seen(doc.Content) {
+			d.dropped++
+			continue
+		}
+		kept = append(kept, doc)
+	}
+	return kept
+}
+
+// seen reports whether text is a near-duplicate of an earlier text,
+// remembering it if not
+func (d *deduper) seen(text string) bool {
+	sig := minhash(shingles(text))
+	bands := make([]uint64, minhashBands)
+	for b := range bands {
+		h := fnv.New64a()
+		buf := []byte{byte(b)}
+		for _, v := range sig[b*minhashRows : (b+1)*minhashRows] {
+			buf = binary.LittleEndian.AppendUint64(buf, v)
+		}
+		h.Write(buf)
+		bands[b] = h.Sum64()
+
+		for _, i := range d.buckets[bands[b]] {
+			if similarity(sig, d.sigs[i]) >= d.threshold {
+				return true
+			}
+		}
+	}
+
+	d.sigs = append(d.sigs, sig)
+	for _, band := range bands {
+		d.buckets[band] = append(d.buckets[band], len(d.sigs)-1)
+	}
+	return false
+}
+
+// shingles hashes the overlapping word n-grams of text. Texts shorter than
+// one shingle hash as a whole.
+func shingles(text string) []uint64 {
+	words := keywordTerms(text)
+	n := max(len(words)-dedupShingleWords+1, 1)
+	hashes := make([]uint64, 0, n)
+	for i := 0; i < n; i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:min(i+dedupShingleWords, len(words))], " ")))
+		hashes = append(hashes, h.Sum64())
+	}
+	return hashes
+}
+
+// minhash returns the MinHash signature of a shingle set: for each of
+// minhashSize hash functions, the minimum hash over the set
+func minhash(shingles []uint64) [minhashSize]uint64 {
+	var sig [minhashSize]uint64
+	for i := range sig {
+		sig[i] = ^uint64(0)
+	}
+	for _, s := range shingles {
+		for i := range sig {
+			if h := mix64(s ^ minhashSeeds[i]); h < sig[i] {
+				sig[i] = h
+			}
+		}
+	}
+	return sig
+}
+
+// similarity estimates Jaccard similarity as the fraction of equal minima
+func similarity(a, b [minhashSize]uint64) float64 {
+	same := 0
+	for i := range a {
+		if a[i] == b[i] {
+			same++
+		}
+	}
+	return float64(same) / minhashSize
+}
+
+// minhashSeeds derives one hash function per signature slot
+var minhashSeeds = func() [minhashSize]uint64 {
+	var seeds [minhashSize]uint64
+	for i := range seeds {
+		seeds[i] = mix64(uint64(i) + 0x9e3779b97f4a7c15)
+	}
+	return seeds
+}()
+
+// mix64 is the splitmix64 finalizer, a fast well-distributed 64-bit mix
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
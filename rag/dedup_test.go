@@ -0,0 +1,48 @@
+package rag
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDeduper_DropsNearDuplicates(t *testing.T) {
+	footer := "This page is maintained by the platform team. For questions ask in the platform channel or open a ticket in the PLAT project, and see the runbook index for related procedures."
+	docs := []Document{
+		{ID: "a", SourceType: "text", Content: "To deploy the API, run the release pipeline and watch the canary dashboard for errors before promoting."},
+		{ID: "b", SourceType: "text", Content: footer},
+		{ID: "c", SourceType: "text", Content: footer + " Last updated"}, // near-duplicate
+		{ID: "d", SourceType: "text", Content: "The database failover procedure promotes the replica and repoints the service DNS."},
+		{ID: "e", SourceType: "image", Content: footer},                                                                                // images are kept
+		{ID: "f", SourceType: "text", Content: "To deploy the worker, run the release pipeline and watch the queue depth for errors."}, // similar topic, different text
+	}
+
+	d := newDeduper(0.9)
+	kept := d.filter(docs)
+	if got := ids(kept); fmt.Sprint(got) != "[a b d e f]" {
+		t.Errorf("filter() kept %v, want [a b d e f]", got)
+	}
+	if d.dropped != 1 {
+		t.Errorf("dropped = %d, want 1", d.dropped)
+	}
+
+	// Later batches are compared with chunks seen earlier in the run
+	if kept := d.filter([]Document{{ID: "g", SourceType: "text", Content: footer}}); len(kept) != 0 {
+		t.Errorf("filter() kept %v, want the repeated footer dropped", ids(kept))
+	}
+
+	if kept := newDeduper(0).filter(docs); len(kept) != len(docs) {
+		t.Error("a zero threshold should disable deduplication")
+	}
+}
+
+func TestMinhashSimilarity(t *testing.T) {
+	text := "the quick brown fox jumps over the lazy dog while the cat sleeps on the warm mat by the door"
+	same := similarity(minhash(shingles(text)), minhash(shingles(text)))
+	if same != 1 {
+		t.Errorf("similarity(identical) = %v, want 1", same)
+	}
+	other := similarity(minhash(shingles(text)), minhash(shingles("kubernetes pods restart when the liveness probe fails three times in a row")))
+	if other > 0.1 {
+		t.Errorf("similarity(unrelated) = %v, want ~0", other)
+	}
+}
@@ -0,0 +1,5 @@
+// Package rag indexes wiki exports (Confluence, MediaWiki, Notion) into a
+// vector store for semantic search: NewIndexer loads, chunks, describes
+// images and embeds the pages per IndexerConfig, and tools.NewWikiTool
+// searches the result.
+package rag
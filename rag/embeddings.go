@@ -8,6 +8,31 @@ import (
 	"github.com/tmc/langchaingo/llms/ollama"
 )
 
+// Embedder turns text into embedding vectors. EmbeddingClient (Ollama) and
+// OpenAIEmbeddings implement it.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+	EmbedBatch(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// Ensure the embedding backends implement Embedder
+var (
+	_ Embedder = (*EmbeddingClient)(nil)
+	_ Embedder = (*OpenAIEmbeddings)(nil)
+)
+
+// DetectVectorSize embeds a probe string and returns the model's vector size
+func DetectVectorSize(ctx context.Context, e Embedder) (int, error) {
+	vector, err := e.Embed(ctx, "dimension probe")
+	if err != nil {
+		return 0, fmt.Errorf("failed to detect vector size: %w", err)
+	}
+	if len(vector) == 0 {
+		return 0, fmt.Errorf("failed to detect vector size: empty embedding")
+	}
+	return len(vector), nil
+}
+
 // EmbeddingClient generates text embeddings using Ollama
 type EmbeddingClient struct {
 	embedder embeddings.Embedder
@@ -0,0 +1,133 @@
+package rag
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// BM25 and reciprocal-rank-fusion parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+	rrfK   = 60 // standard RRF damping constant
+)
+
+// keywordStopwords are dropped from keyword queries; they match nearly every
+// chunk and would flood the candidate set.
+var keywordStopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "does": true, "do": true, "for": true, "from": true,
+	"how": true, "in": true, "is": true, "it": true, "me": true, "of": true,
+	"on": true, "or": true, "show": true, "the": true, "this": true, "to": true,
+	"what": true, "when": true, "where": true, "which": true, "who": true,
+	"why": true, "with": true, "wiki": true, "search": true, "find": true,
+}
+
+// keywordTerms lowercases text and splits it on anything that isn't a letter
+// or digit, matching Qdrant's "word" full-text tokenizer.
+func keywordTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// queryTerms returns the unique, non-stopword terms of a query. A query in
+// another language also loses that language's stopwords.
+func queryTerms(query string) []string {
+	seen := map[string]bool{}
+	var terms []string
+	var stop map[string]bool
+	if lang := DetectLanguage(query); lang != "en" {
+		stop = languageStopwords[lang]
+	}
+	for _, t := range keywordTerms(query) {
+		if keywordStopwords[t] || stop[t] || seen[t] {
+			continue
+		}
+		seen[t] = true
+		terms = append(terms, t)
+	}
+	return terms
+}
+
+// bm25Rank scores candidate documents against terms. df holds each term's
+// document frequency and total is the collection size, so IDF reflects the
+// whole corpus even though only the candidates are scored.
+func bm25Rank(docs []Document, terms []string, df map[string]int, total int) []Document {
+	if len(docs) == 0 || len(terms) == 0 {
+		return nil
+	}
+
+	lengths := make([]int, len(docs))
+	freqs := make([]map[string]int, len(docs))
+	sumLen := 0
+	for i, d := range docs {
+		tf := map[string]int{}
+		words := keywordTerms(d.Content)
+		for _, w := range words {
+			tf[w]++
+		}
+		freqs[i] = tf
+		lengths[i] = len(words)
+		sumLen += len(words)
+	}
+	avgLen := float64(sumLen) / float64(len(docs))
+	if avgLen == 0 {
+		avgLen = 1
+	}
+
+	var ranked []Document
+	for i, d := range docs {
+		score := 0.0
+		for _, t := range terms {
+			f := float64(freqs[i][t])
+			if f == 0 {
+				continue
+			}
+			n := float64(df[t])
+			idf := math.Log(1 + (float64(total)-n+0.5)/(n+0.5))
+			norm := f * (bm25K1 + 1) / (f + bm25K1*(1-bm25B+bm25B*float64(lengths[i])/avgLen))
+			score += idf * norm
+		}
+		if score > 0 {
+			d.Score = float32(score)
+			ranked = append(ranked, d)
+		}
+	}
+
+	sort.SliceStable(ranked, func(a, b int) bool { return ranked[a].Score > ranked[b].Score })
+	return ranked
+}
+
+// FuseRRF merges ranked result lists with reciprocal rank fusion:
+// score(d) = Σ 1/(rrfK + rank). Each Document's Score becomes its fused score.
+func FuseRRF(limit int, lists ...[]Document) []Document {
+	scores := map[string]float64{}
+	docs := map[string]Document{}
+	var order []string
+
+	for _, list := range lists {
+		for rank, d := range list {
+			if _, ok := docs[d.ID]; !ok {
+				docs[d.ID] = d
+				order = append(order, d.ID)
+			}
+			scores[d.ID] += 1.0 / float64(rrfK+rank+1)
+		}
+	}
+
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+	if len(order) > limit {
+		order = order[:limit]
+	}
+
+	fused := make([]Document, len(order))
+	for i, id := range order {
+		d := docs[id]
+		d.Score = float32(scores[id])
+		fused[i] = d
+	}
+	return fused
+}
@@ -0,0 +1,48 @@
+package rag
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQueryTerms(t *testing.T) {
+	got := queryTerms("What is the error E1234 on api-01.prod? the E1234")
+	want := []string{"error", "e1234", "api", "01", "prod"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("queryTerms() = %v, want %v", got, want)
+	}
+}
+
+func TestBM25Rank(t *testing.T) {
+	docs := []Document{
+		{ID: "generic", Content: "The service restarts pods when the probe fails."},
+		{ID: "exact", Content: "Error E1234 means the cert on api-01 expired."},
+		{ID: "none", Content: "Nothing relevant here."},
+	}
+	df := map[string]int{"e1234": 1, "api": 5}
+
+	ranked := bm25Rank(docs, []string{"e1234", "api"}, df, 100)
+	if len(ranked) != 1 {
+		t.Fatalf("bm25Rank() = %d docs, want 1 (only the matching doc)", len(ranked))
+	}
+	if ranked[0].ID != "exact" || ranked[0].Score <= 0 {
+		t.Errorf("top = %+v, want exact with positive score", ranked[0])
+	}
+}
+
+func TestFuseRRF(t *testing.T) {
+	vector := []Document{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	keyword := []Document{{ID: "c"}, {ID: "d"}}
+
+	fused := FuseRRF(3, vector, keyword)
+	if len(fused) != 3 {
+		t.Fatalf("FuseRRF() = %d docs, want 3", len(fused))
+	}
+	// "c" appears in both lists and must outrank single-list hits
+	if fused[0].ID != "c" {
+		t.Errorf("top = %q, want c (present in both lists)", fused[0].ID)
+	}
+	if fused[1].ID != "a" {
+		t.Errorf("second = %q, want a", fused[1].ID)
+	}
+}
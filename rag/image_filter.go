@@ -0,0 +1,63 @@
+package rag
+
+import (
+	"image"
+	_ "image/gif"  // decode GIF sizes
+	_ "image/jpeg" // decode JPEG sizes
+	_ "image/png"  // decode PNG sizes
+	"os"
+)
+
+// DefaultMinImageSize is the smallest width and height of the images
+// DefaultConfig describes. Confluence emoticons, status icons and avatars
+// are 16-24 px, and a description of them adds nothing to the index.
+const DefaultMinImageSize = 32
+
+// pageImages returns the images of a page worth describing, and how many
+// were left out by SkipImages, MinImageBytes, MinImageWidth/MinImageHeight
+// or MaxPageImages. Images whose size can't be read (missing files,
+// formats such as SVG) are kept: describing them reports the problem or
+// works anyway.
+func (idx *Indexer) pageImages(page PageContent) ([]ImageRef, int) {
+	if idx.config.SkipImages {
+		return nil, len(page.Images)
+	}
+	var kept []ImageRef
+	skipped := 0
+	for _, img := range page.Images {
+		if !idx.imageLargeEnough(img.FullPath) ||
+			idx.config.MaxPageImages > 0 && len(kept) == idx.config.MaxPageImages {
+			skipped++
+			continue
+		}
+		kept = append(kept, img)
+	}
+	return kept, skipped
+}
+
+// imageLargeEnough reports whether an image file meets the minimum file
+// size and dimensions
+func (idx *Indexer) imageLargeEnough(path string) bool {
+	minW, minH, minBytes := idx.config.MinImageWidth, idx.config.MinImageHeight, idx.config.MinImageBytes
+	if minW <= 0 && minH <= 0 && minBytes <= 0 {
+		return true
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return true
+	}
+	defer f.Close()
+	if minBytes > 0 {
+		if info, err := f.Stat(); err == nil && info.Size() < minBytes {
+			return false
+		}
+	}
+	if minW <= 0 && minH <= 0 {
+		return true
+	}
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return true
+	}
+	return cfg.Width >= minW && cfg.Height >= minH
+}
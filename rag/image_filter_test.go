@@ -0,0 +1,75 @@
+package rag
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writePNG writes a w×h PNG and returns its path
+func writePNG(t *testing.T, dir, name string, w, h int) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, image.NewGray(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPageImages(t *testing.T) {
+	dir := t.TempDir()
+	svg := filepath.Join(dir, "flow.svg")
+	os.WriteFile(svg, []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="10" height="10"/>`), 0644)
+	page := PageContent{Images: []ImageRef{
+		{FullPath: writePNG(t, dir, "smile.png", 16, 16)},
+		{FullPath: writePNG(t, dir, "arch.png", 640, 480)},
+		{FullPath: writePNG(t, dir, "banner.png", 800, 20)},
+		{FullPath: svg},
+		{FullPath: filepath.Join(dir, "missing.png")},
+		{FullPath: writePNG(t, dir, "seq.png", 300, 200)},
+	}}
+	names := func(imgs []ImageRef) string {
+		var out []string
+		for _, img := range imgs {
+			out = append(out, filepath.Base(img.FullPath))
+		}
+		return strings.Join(out, ",")
+	}
+
+	tests := []struct {
+		name    string
+		config  IndexerConfig
+		want    string
+		skipped int
+	}{
+		{"no filters", IndexerConfig{}, "smile.png,arch.png,banner.png,flow.svg,missing.png,seq.png", 0},
+		{"defaults", DefaultConfig(), "arch.png,flow.svg,missing.png,seq.png", 2},
+		{"per page limit", IndexerConfig{MinImageWidth: 32, MinImageHeight: 32, MaxPageImages: 1}, "arch.png", 5},
+		{"width only", IndexerConfig{MinImageWidth: 500}, "arch.png,banner.png,flow.svg,missing.png", 2},
+		{"file size", IndexerConfig{MinImageBytes: 1 << 20}, "missing.png", 5},
+		{"skip all", IndexerConfig{SkipImages: true}, "", 6},
+	}
+	for _, tt := range tests {
+		idx := &Indexer{config: tt.config}
+		kept, skipped := idx.pageImages(page)
+		if got := names(kept); got != tt.want || skipped != tt.skipped {
+			t.Errorf("%s: pageImages() = %s, %d skipped; want %s, %d", tt.name, got, skipped, tt.want, tt.skipped)
+		}
+	}
+}
+
+func TestIndexStats_Undescribed(t *testing.T) {
+	s := IndexStats{Images: 3, Undescribed: 12, LastIndexed: time.Now()}
+	if out := s.String(); !strings.Contains(out, "Images:       3 (12 skipped)") {
+		t.Errorf("String() = %q", out)
+	}
+}
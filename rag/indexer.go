@@ -2,195 +2,910 @@ package rag
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 // IndexerConfig holds configuration for the indexer
 type IndexerConfig struct {
-	WikiPath       string // Path to Confluence HTML export
-	QdrantURL      string // Qdrant server URL
-	CollectionName string // Qdrant collection name
-	EmbedModel     string // Embedding model (e.g., nomic-embed-text)
-	VisionModel    string // Vision model (e.g., llava)
-	VectorSize     int    // Vector dimensions
-	ChunkSize      int    // Max chunk size for text
+	WikiPath       string        // Path to wiki export (directory, or XML file for mediawiki)
+	Format         string        // Export format: "confluence" (default), "mediawiki", "notion"
+	TableFormat    string        // HTML table chunks: "rows" (default) or "markdown"
+	SkipSelectors  []string      // HTML elements left out of pages: tag, #id, .class or tag.class (DefaultConfig: DefaultSkipSelectors)
+	SkipPatterns   []string      // Regular expressions; text chunks matching one are dropped (DefaultConfig: DefaultSkipPatterns)
+	MaxFileSize    int64         // Page files larger than this many bytes are skipped and reported (DefaultConfig: DefaultMaxFileSize; 0 = no limit)
+	QdrantURL      string        // Qdrant server URL (http:// or https://)
+	Qdrant         QdrantOptions // Qdrant API key and TLS settings
+	MilvusURL      string        // Milvus server URL (RESTful API, e.g. http://localhost:19530)
+	MilvusToken    string        // Milvus "user:password" or API key
+	WeaviateURL    string        // Weaviate server URL (e.g. http://localhost:8080)
+	WeaviateAPIKey string        // Weaviate API key
+	StorePath      string        // LocalStore directory, used when no server URL is set (default: <user cache dir>/langchain-agent/vectors)
+	CollectionName string        // Qdrant collection name
+	EmbedProvider  string        // Embedding backend: "ollama" (default) or "openai" (any OpenAI-compatible API)
+	EmbedModel     string        // Embedding model (e.g., nomic-embed-text; empty for the provider default)
+	EmbedURL       string        // Embedding API base URL for "openai" (default: https://api.openai.com/v1)
+	EmbedAPIKey    string        // Embedding API key for "openai"
+	VisionProvider string        // Vision backend: "ollama" (default) or "openai" (any OpenAI-compatible chat API)
+	VisionModel    string        // Vision model (e.g., llava; empty for the provider default)
+	VisionURL      string        // Ollama server or OpenAI base URL for the vision model (default: the provider's)
+	VisionAPIKey   string        // Vision API key for "openai"
+	VisionWorkers  int           // Images described concurrently (default 2)
+	VisionProfiles string        // Prompt per kind of image (VisionPrompts): "" (generic prompt only), "heuristic" (by file name and alt text) or "classify" (heuristics, then ask VisionModel)
+	OCR            string        // Also index the text inside images: "" (off), "tesseract" or "vision" (VisionModel transcribes it)
+	SkipImages     bool          // Leave images out of the index: no descriptions and no OCR
+	MaxPageImages  int           // Images described per page, in page order (0 = all)
+	MinImageWidth  int           // Images narrower than this many pixels are skipped (DefaultConfig: DefaultMinImageSize; 0 = any)
+	MinImageHeight int           // Images shorter than this many pixels are skipped (DefaultConfig: DefaultMinImageSize; 0 = any)
+	MinImageBytes  int64         // Image files smaller than this are skipped (0 = any)
+	SummaryModel   string        // Ollama model that writes a summary document per page ("" = no summaries)
+	SummaryURL     string        // Ollama server for SummaryModel (default: the local one)
+	VectorSize     int           // Vector dimensions (0 = detect from the embedding model)
+	ChunkSize      int           // Max chunk size for text in bytes (used only when ChunkTokens is 0)
+	ChunkTokens    int           // Max chunk size in embedding-model tokens
+	MinChunkTokens int           // Consecutive smaller page chunks are merged up to this size
+	Tokenizer      Tokenizer     // Token counter for ChunkTokens (default: ApproxTokenizer)
+	EmbedRetries   int           // Retries per failed embedding batch, with exponential backoff
+	DedupThreshold float64       // Drop text chunks at least this similar (Jaccard, 0-1) to an earlier one; 0 disables
+	ReportFile     string        // Optional: write skipped documents as JSON here
+	LoadReportFile string        // Optional: write the loader's LoadReport (missing images, broken links, ...) as JSON here
+	CheckpointFile string        // Where full-index progress is kept for resuming (default: <user cache dir>/langchain-agent/checkpoints/<collection>.json; none with an injected Store)
+	FreshIndex     bool          // Ignore any checkpoint and re-index from scratch
+	StatsFile      string        // Where index statistics are kept (default: <user cache dir>/langchain-agent/stats/<collection>.json; none with an injected Store)
+	ActiveFile     string        // Where Reembed records the collection that replaced CollectionName (default: <user cache dir>/langchain-agent/collections/<collection>.json; none with an injected Store)
+
+	// Store, when set, is used instead of connecting to a vector database
+	// (e.g. NewMemoryStore() in tests)
+	Store Store
+
+	// Embedder, when set, is used instead of creating one from EmbedProvider
+	Embedder Embedder
+
+	// OCRExtractor, when set, is used instead of creating one from OCR
+	OCRExtractor TextExtractor
+
+	// Summarizer, when set, is used instead of creating one from SummaryModel
+	Summarizer Summarizer
+
+	// Confluence, when set, pulls pages from the REST API instead of WikiPath
+	Confluence *ConfluenceAPIConfig
+
+	// Sources, when set, indexes several roots into one collection instead
+	// of WikiPath or Confluence; each document is tagged with its source's Name
+	Sources []IndexSource
 }
 
 // DefaultConfig returns default indexer configuration
 func DefaultConfig() IndexerConfig {
 	return IndexerConfig{
-		QdrantURL:      "http://localhost:6333",
 		CollectionName: "confluence_wiki",
 		EmbedModel:     "nomic-embed-text",
 		VisionModel:    "llava",
-		VectorSize:     768, // nomic-embed-text dimension
 		ChunkSize:      500,
+		ChunkTokens:    256,
+		MinChunkTokens: 48,
+		EmbedRetries:   3,
+		VisionWorkers:  2,
+		DedupThreshold: 0.9,
+		SkipSelectors:  DefaultSkipSelectors,
+		SkipPatterns:   DefaultSkipPatterns,
+		MaxFileSize:    DefaultMaxFileSize,
+		MinImageWidth:  DefaultMinImageSize,
+		MinImageHeight: DefaultMinImageSize,
 	}
 }
 
 // Indexer handles indexing Confluence content into the vector store
 type Indexer struct {
 	config     IndexerConfig
-	embeddings *EmbeddingClient
-	vision     *VisionClient
-	store      *VectorStore
-	loader     *ConfluenceLoader
+	embeddings Embedder
+	vision     imageDescriber
+	summarizer Summarizer    // nil when page summaries are off
+	ocr        TextExtractor // nil when OCR is off
+	store      Store
+	collection string            // name of store's collection: CollectionName, or the one Reembed switched to
+	active     *ActiveCollection // nil until Reembed switches collections
+	loader     Loader
+	skipText   []*regexp.Regexp // compiled SkipPatterns
+
+	retryDelay time.Duration  // first backoff delay; doubles per retry
+	failures   []IndexFailure // documents skipped by the last Index run
+	stats      *IndexStats    // recorded by the last Index run in this process
+
+	running sync.Mutex // serializes Index runs (e.g. from Watch)
+	out     io.Writer  // where the run holding running reports progress
+	mu      sync.Mutex // guards stats, which Stats reads during a run
+}
+
+// IndexFailure records a document that could not be indexed
+type IndexFailure struct {
+	Stage     string `json:"stage"` // "vision", "ocr", "summary" or "embed"
+	PageTitle string `json:"page_title"`
+	FilePath  string `json:"file_path"`
+	Error     string `json:"error"`
+}
+
+// incrementalLoader is implemented by loaders that support delta sync
+type incrementalLoader interface {
+	Incremental() bool
+	CommitSync() error
 }
 
 // NewIndexer creates a new indexer
 func NewIndexer(config IndexerConfig) (*Indexer, error) {
-	embeddings, err := NewEmbeddingClient(config.EmbedModel)
+	embeddings, err := newEmbedder(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create embedding client: %w", err)
 	}
 
-	cacheFile := filepath.Join(config.WikiPath, ".vision_cache.json")
-	vision, err := NewVisionClient(config.VisionModel, cacheFile)
+	var cacheDir string // vision and summary caches live with the export
+	wikiPath := config.WikiPath
+	if wikiPath == "" && len(config.Sources) > 0 {
+		wikiPath = config.Sources[0].Path // keep the caches with the first export
+	}
+	if wikiPath != "" {
+		cacheDir = wikiPath
+		if info, err := os.Stat(cacheDir); err == nil && !info.IsDir() {
+			cacheDir = filepath.Dir(cacheDir) // e.g. a MediaWiki XML dump
+		}
+	}
+	cacheFile := func(name string) string {
+		if cacheDir == "" {
+			return ""
+		}
+		return filepath.Join(cacheDir, name)
+	}
+	vision, err := NewVisionClientWithOptions(VisionOptions{
+		Provider: config.VisionProvider,
+		Model:    config.VisionModel,
+		URL:      config.VisionURL,
+		APIKey:   config.VisionAPIKey,
+	}, cacheFile(".vision_cache.json"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create vision client: %w", err)
 	}
+	summarizer := config.Summarizer
+	if summarizer == nil && config.SummaryModel != "" {
+		summarizer, err = NewLLMSummarizer(config.SummaryModel, config.SummaryURL, cacheFile(".summary_cache.json"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create summarizer: %w", err)
+		}
+	}
 
-	store := NewVectorStore(config.QdrantURL, config.CollectionName)
-	loader := NewConfluenceLoader(config.WikiPath)
+	switch config.VisionProfiles {
+	case ProfilesOff, ProfilesHeuristic, ProfilesClassify:
+	default:
+		return nil, fmt.Errorf("unknown vision profile mode %q (use heuristic or classify)", config.VisionProfiles)
+	}
+	ocr, err := newOCR(config, vision)
+	if err != nil {
+		return nil, err
+	}
+	skipText, err := compileSkipPatterns(config.SkipPatterns)
+	if err != nil {
+		return nil, err
+	}
+	if config.Store == nil {
+		if cacheDir, err := os.UserCacheDir(); err == nil {
+			if config.StatsFile == "" {
+				config.StatsFile = filepath.Join(cacheDir, "langchain-agent", "stats", config.CollectionName+".json")
+			}
+			if config.CheckpointFile == "" {
+				config.CheckpointFile = filepath.Join(cacheDir, "langchain-agent", "checkpoints", config.CollectionName+".json")
+			}
+			if config.ActiveFile == "" {
+				config.ActiveFile = filepath.Join(cacheDir, "langchain-agent", "collections", config.CollectionName+".json")
+			}
+		}
+	}
+
+	// After a Reembed the store is the collection it switched to
+	active, err := loadActive(config.ActiveFile)
+	if err != nil {
+		return nil, err
+	}
+	storeConfig := config
+	if active != nil {
+		storeConfig.CollectionName = active.Collection
+	}
+	store, err := newStore(storeConfig)
+	if err != nil {
+		return nil, err
+	}
+	loader, err := newLoader(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Tokenizer == nil {
+		config.Tokenizer = ApproxTokenizer{}
+	}
 
 	return &Indexer{
 		config:     config,
 		embeddings: embeddings,
 		vision:     vision,
+		summarizer: summarizer,
+		ocr:        ocr,
 		store:      store,
+		collection: storeConfig.CollectionName,
+		active:     active,
 		loader:     loader,
+		skipText:   skipText,
+		retryDelay: time.Second,
+		out:        os.Stdout,
 	}, nil
 }
 
+// newEmbedder creates the configured embedding backend
+func newEmbedder(config IndexerConfig) (Embedder, error) {
+	if config.Embedder != nil {
+		return config.Embedder, nil
+	}
+	switch config.EmbedProvider {
+	case "", "ollama":
+		return NewEmbeddingClient(config.EmbedModel)
+	case "openai":
+		return NewOpenAIEmbeddings(config.EmbedURL, config.EmbedAPIKey, config.EmbedModel), nil
+	default:
+		return nil, fmt.Errorf("unknown embedding provider %q (use ollama or openai)", config.EmbedProvider)
+	}
+}
+
+// newOCR picks the image text extractor, or returns nil when OCR is off
+func newOCR(config IndexerConfig, vision *VisionClient) (TextExtractor, error) {
+	if config.OCRExtractor != nil {
+		return config.OCRExtractor, nil
+	}
+	switch config.OCR {
+	case "":
+		return nil, nil
+	case "tesseract":
+		return &TesseractOCR{}, nil
+	case "vision":
+		return vision, nil
+	default:
+		return nil, fmt.Errorf("unknown OCR engine %q (use tesseract or vision)", config.OCR)
+	}
+}
+
+// newStore connects to the configured vector database, or opens the embedded
+// on-disk store when no server URL is set
+func newStore(config IndexerConfig) (Store, error) {
+	if config.Store != nil {
+		return config.Store, nil
+	}
+	servers := 0
+	for _, u := range []string{config.QdrantURL, config.MilvusURL, config.WeaviateURL} {
+		if u != "" {
+			servers++
+		}
+	}
+	if servers > 1 {
+		return nil, fmt.Errorf("only one of QdrantURL, MilvusURL and WeaviateURL may be set")
+	}
+
+	switch {
+	case config.QdrantURL != "":
+		return NewVectorStoreWithOptions(config.QdrantURL, config.CollectionName, config.Qdrant)
+	case config.MilvusURL != "":
+		return NewMilvusStore(config.MilvusURL, config.MilvusToken, config.CollectionName), nil
+	case config.WeaviateURL != "":
+		return NewWeaviateStore(config.WeaviateURL, config.WeaviateAPIKey, config.CollectionName), nil
+	}
+	dir := config.StorePath
+	if dir == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate cache dir for local vector store: %w", err)
+		}
+		dir = filepath.Join(cacheDir, "langchain-agent", "vectors")
+	}
+	return NewLocalStore(dir, config.CollectionName)
+}
+
+// newLoader picks the page loader for the configured source and format
+func newLoader(config IndexerConfig) (Loader, error) {
+	if len(config.Sources) > 0 {
+		return newMultiLoader(config)
+	}
+	skip, err := parseSelectors(config.SkipSelectors)
+	if err != nil {
+		return nil, err
+	}
+	if config.Confluence != nil {
+		return NewConfluenceAPILoader(*config.Confluence), nil
+	}
+	switch config.Format {
+	case "", "confluence":
+		l := NewConfluenceLoader(config.WikiPath)
+		l.TableFormat = config.TableFormat
+		l.MaxFileSize = config.MaxFileSize
+		l.skip = skip
+		return l, nil
+	case "mediawiki":
+		return NewMediaWikiLoader(config.WikiPath), nil
+	case "notion":
+		l := NewNotionLoader(config.WikiPath)
+		l.html.TableFormat = config.TableFormat
+		l.html.MaxFileSize = config.MaxFileSize
+		l.html.skip = skip
+		return l, nil
+	default:
+		return nil, fmt.Errorf("unknown wiki format %q (use confluence, mediawiki or notion)", config.Format)
+	}
+}
+
 // Index performs full re-indexing of the wiki content
 func (idx *Indexer) Index(ctx context.Context) error {
-	fmt.Println("Loading Confluence HTML export...")
+	return idx.index(ctx, idx.loader, os.Stdout)
+}
+
+// index runs the indexing pipeline over the pages from loader, reporting
+// progress to out
+func (idx *Indexer) index(ctx context.Context, loader Loader, out io.Writer) error {
+	idx.running.Lock()
+	defer idx.running.Unlock()
+	idx.out = out
+
+	incremental := false
+	if il, ok := loader.(incrementalLoader); ok {
+		incremental = il.Incremental()
+	}
+
+	switch {
+	case len(idx.config.Sources) > 0:
+		fmt.Fprintf(idx.out, "Loading %d sources...\n", len(idx.config.Sources))
+	case idx.config.Confluence != nil:
+		fmt.Fprintf(idx.out, "Loading pages from Confluence API (%s)...\n", idx.config.Confluence.BaseURL)
+	default:
+		format := idx.config.Format
+		if format == "" {
+			format = "confluence"
+		}
+		fmt.Fprintf(idx.out, "Loading %s export...\n", format)
+	}
 
 	// Load all pages
-	pages, err := idx.loader.LoadAll()
+	pages, err := loader.LoadAll()
 	if err != nil {
 		return fmt.Errorf("failed to load pages: %w", err)
 	}
 
-	fmt.Printf("Found %d pages to index\n", len(pages))
+	fmt.Fprintf(idx.out, "Found %d pages to index\n", len(pages))
+	var report LoadReport
+	if rl, ok := loader.(reportingLoader); ok {
+		report = rl.LoadReport()
+	}
 
-	// Delete and recreate collection
-	fmt.Println("Resetting vector store...")
-	if err := idx.store.DeleteCollection(ctx); err != nil {
-		return fmt.Errorf("failed to delete collection: %w", err)
+	// Delete and recreate collection, unless this is a delta sync or a
+	// resumed full index
+	var cp *indexCheckpoint
+	if !incremental {
+		cp = idx.loadCheckpoint()
+	}
+	switch {
+	case incremental && idx.active != nil && idx.active.EmbedModel != idx.embedModel():
+		return fmt.Errorf("collection %s holds %s vectors, not %s: re-embed it or run a fresh index", idx.collection, idx.active.EmbedModel, idx.embedModel())
+	case incremental:
+		fmt.Fprintln(idx.out, "Delta sync: keeping existing vector store")
+		cp = idx.newCheckpoint() // tracks progress in memory only
+	case cp != nil:
+		fmt.Fprintf(idx.out, "Resuming interrupted index started %s: %d pages already stored\n",
+			cp.Started.Local().Format("2006-01-02 15:04"), len(cp.PagesDone))
+	default:
+		fmt.Fprintln(idx.out, "Resetting vector store...")
+		if err := idx.store.DeleteCollection(ctx); err != nil {
+			return fmt.Errorf("failed to delete collection: %w", err)
+		}
+		cp = idx.newCheckpoint()
+	}
+	if idx.config.VectorSize == 0 {
+		size, err := DetectVectorSize(ctx, idx.embeddings)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(idx.out, "Embedding model produces %d-dimensional vectors\n", size)
+		idx.config.VectorSize = size
 	}
 	if err := idx.store.EnsureCollection(ctx, idx.config.VectorSize); err != nil {
 		return fmt.Errorf("failed to create collection: %w", err)
 	}
+	// A full index may have refilled a re-embedded collection with another model
+	if a := idx.active; a != nil && (a.EmbedModel != idx.embedModel() || a.VectorSize != idx.config.VectorSize) {
+		updated := ActiveCollection{Collection: a.Collection, EmbedModel: idx.embedModel(), VectorSize: idx.config.VectorSize, Switched: a.Switched}
+		if err := idx.saveActive(updated); err != nil {
+			return fmt.Errorf("failed to update active collection: %w", err)
+		}
+		idx.active = &updated
+	}
+
+	// A changed page may have lost chunks; purge its old points before re-adding
+	if incremental {
+		for _, page := range pages {
+			if err := idx.store.DeleteByFilter(ctx, &Filter{FilePath: page.FilePath}); err != nil {
+				return fmt.Errorf("failed to purge stale chunks of %s: %w", page.Title, err)
+			}
+		}
+	}
 
-	// Process each page
-	var allDocs []Document
-	docCount := 0
+	done := make(map[string]bool, len(cp.PagesDone))
+	for _, path := range cp.PagesDone {
+		done[path] = true
+	}
+	idx.failures = cp.Failures
+	stats := cp.Stats
+	stats.Incremental = incremental
+	dedup := newDeduper(idx.config.DedupThreshold)
 
-	for i, page := range pages {
-		fmt.Printf("Processing page %d/%d: %s\n", i+1, len(pages), page.Title)
+	// Process pages in groups, storing each group before the next so an
+	// interrupted run loses at most one group of work
+	for start := 0; start < len(pages); start += checkpointPages {
+		end := min(start+checkpointPages, len(pages))
 
-		// Process text chunks
-		for _, chunk := range page.Chunks {
-			// Split into smaller chunks if needed
-			textChunks := ChunkText(chunk.Content, idx.config.ChunkSize)
-			for _, text := range textChunks {
-				if len(text) < 20 {
-					continue // Skip very short chunks
-				}
+		var docs []Document
+		var images []imageJob
+		for i, page := range pages[start:end] {
+			if done[page.FilePath] {
+				continue
+			}
+			fmt.Fprintf(idx.out, "Processing page %d/%d: %s\n", start+i+1, len(pages), page.Title)
+			docs = append(docs, idx.pageDocs(page)...)
+			kept, skipped := idx.pageImages(page)
+			for _, img := range kept {
+				images = append(images, imageJob{page: page, img: img})
+			}
+			stats.Undescribed += skipped
+		}
 
-				docID := generateDocID(page.FilePath, text)
-				allDocs = append(allDocs, Document{
-					ID:         docID,
-					Content:    text,
-					SourceType: "text",
-					Metadata: map[string]string{
-						"page_title": page.Title,
-						"file_path":  page.FilePath,
-						"chunk_type": chunk.Type,
-					},
+		docs = dedup.filter(docs)
+
+		// Page summaries are added after deduplication: pages with shared
+		// boilerplate still get their own overview
+		for _, page := range pages[start:end] {
+			if done[page.FilePath] {
+				continue
+			}
+			summary, err := idx.summaryDoc(ctx, page)
+			if err != nil {
+				fmt.Fprintf(idx.out, "  Warning: failed to summarize %s: %v\n", page.Title, err)
+				idx.failures = append(idx.failures, IndexFailure{
+					Stage:     "summary",
+					PageTitle: page.Title,
+					FilePath:  page.FilePath,
+					Error:     err.Error(),
 				})
-				docCount++
+			} else if summary != nil {
+				docs = append(docs, *summary)
 			}
 		}
 
 		// Process images with vision model
-		for _, img := range page.Images {
-			fmt.Printf("  Describing image: %s\n", filepath.Base(img.FullPath))
+		for _, result := range idx.processImages(ctx, images) {
+			docs = append(docs, result.docs...)
+			idx.failures = append(idx.failures, result.failures...)
+		}
+		if len(docs) > 0 {
+			fmt.Fprintf(idx.out, "Generated %d document chunks, generating embeddings...\n", len(docs))
 
-			description, err := idx.vision.DescribeImage(ctx, img.FullPath)
+			// Generate embeddings in batches
+			var err error
+			docs, err = idx.embedDocs(ctx, docs)
 			if err != nil {
-				fmt.Printf("  Warning: failed to describe image %s: %v\n", img.FullPath, err)
-				continue
+				return err
 			}
 
-			docID := generateDocID(img.FullPath, "image")
-			allDocs = append(allDocs, Document{
-				ID:         docID,
-				Content:    description,
-				SourceType: "image",
-				ImagePath:  img.FullPath,
-				Metadata: map[string]string{
-					"page_title": page.Title,
-					"file_path":  page.FilePath,
-					"image_alt":  img.Alt,
-				},
+			fmt.Fprintln(idx.out, "Storing documents in vector store...")
+			if err := idx.store.Upsert(ctx, docs); err != nil {
+				return fmt.Errorf("failed to upsert documents: %w", err)
+			}
+		}
+
+		stats.addDocs(docs)
+		if dedup != nil {
+			stats.Duplicates += dedup.dropped
+			dedup.dropped = 0
+		}
+		for _, page := range pages[start:end] {
+			if !done[page.FilePath] {
+				done[page.FilePath] = true
+				cp.PagesDone = append(cp.PagesDone, page.FilePath)
+			}
+		}
+		if !incremental {
+			cp.Stats = stats
+			cp.Failures = idx.failures
+			if err := idx.saveCheckpoint(cp); err != nil {
+				fmt.Fprintf(idx.out, "Warning: failed to save index checkpoint: %v\n", err)
+			}
+		}
+	}
+	idx.clearCheckpoint()
+
+	// Keep the old watermark if anything was skipped so the next delta sync retries it
+	if il, ok := loader.(incrementalLoader); ok && len(idx.failures) == 0 {
+		if err := il.CommitSync(); err != nil {
+			fmt.Fprintf(idx.out, "Warning: failed to save sync state: %v\n", err)
+		}
+	}
+
+	if err := idx.recordStats(stats, len(pages)); err != nil {
+		fmt.Fprintf(idx.out, "Warning: failed to save index stats: %v\n", err)
+	}
+
+	fmt.Fprintf(idx.out, "Indexing complete! %d documents indexed.\n", stats.Chunks+stats.Images+stats.Summaries)
+	if stats.Duplicates > 0 {
+		fmt.Fprintf(idx.out, "Dropped %d near-duplicate chunks\n", stats.Duplicates)
+	}
+	if len(idx.failures) > 0 {
+		fmt.Fprintf(idx.out, "Warning: %d documents were skipped:\n", len(idx.failures))
+		for _, f := range idx.failures {
+			fmt.Fprintf(idx.out, "  [%s] %s (%s): %s\n", f.Stage, f.PageTitle, f.FilePath, f.Error)
+		}
+		if err := idx.writeReport(); err != nil {
+			fmt.Fprintf(idx.out, "Warning: failed to write index report: %v\n", err)
+		}
+	}
+	if report.Problems() > 0 {
+		fmt.Fprintf(idx.out, "Warning: %d problems found in the export:\n%s", report.Problems(), report.Summary(loadReportPaths))
+	}
+	if err := idx.writeLoadReport(report); err != nil {
+		fmt.Fprintf(idx.out, "Warning: failed to write load report: %v\n", err)
+	}
+	return nil
+}
+
+// pageDocs splits a page's text into documents (without vectors)
+func (idx *Indexer) pageDocs(page PageContent) []Document {
+	var docs []Document
+	pageChunks := assignSections(idx.dropBoilerplate(page.Chunks))
+	if idx.config.ChunkTokens > 0 {
+		pageChunks = packChunks(pageChunks, idx.config.MinChunkTokens, idx.config.ChunkTokens, idx.config.Tokenizer)
+	}
+	// Chunks record their position in the page and their section's, so a
+	// search hit can be expanded to the enclosing section
+	section, position := -1, 0
+	pageLang := pageLanguage(page)
+	for i, chunk := range pageChunks {
+		if i == 0 || chunk.Section != pageChunks[i-1].Section || chunk.Anchor != pageChunks[i-1].Anchor {
+			section++
+		}
+		// Split into smaller chunks if needed
+		textChunks := idx.splitChunk(chunk.Content)
+		for _, text := range textChunks {
+			if len(text) < 20 {
+				continue // Skip very short chunks
+			}
+
+			meta := pageMetadata(page, "chunk_type", chunk.Type)
+			if chunk.Section != "" {
+				meta["section"] = chunk.Section
+			}
+			if chunk.Anchor != "" {
+				meta["anchor"] = chunk.Anchor
+			}
+			if lang := DetectLanguage(text); lang != "" {
+				meta["language"] = lang
+			} else if pageLang != "" {
+				meta["language"] = pageLang
+			}
+			meta["section_index"] = strconv.Itoa(section)
+			meta["chunk_index"] = strconv.Itoa(position)
+			position++
+			docs = append(docs, Document{
+				ID:         generateDocID(page.FilePath, text),
+				Content:    text,
+				SourceType: "text",
+				Metadata:   meta,
 			})
-			docCount++
 		}
 	}
+	return docs
+}
+
+// imageDescriber describes an image in words; *VisionClient implements it
+type imageDescriber interface {
+	DescribeImage(ctx context.Context, imagePath string) (string, error)
+}
+
+// imageJob is one page image waiting for description
+type imageJob struct {
+	page PageContent
+	img  ImageRef
+}
+
+// imageResult holds the documents and failures produced for one image
+type imageResult struct {
+	docs     []Document
+	failures []IndexFailure
+}
+
+// processImages describes images (and runs OCR) with up to VisionWorkers
+// requests in flight. Results are returned in job order so document order
+// doesn't depend on scheduling.
+func (idx *Indexer) processImages(ctx context.Context, jobs []imageJob) []imageResult {
+	workers := max(idx.config.VisionWorkers, 1)
+	results := make([]imageResult, len(jobs))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, job := range jobs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fmt.Fprintf(idx.out, "  Describing image %d/%d: %s\n", i+1, len(jobs), filepath.Base(job.img.FullPath))
+			results[i] = idx.processImage(ctx, job.page, job.img)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// processImage produces the OCR chunks and the description of one image
+func (idx *Indexer) processImage(ctx context.Context, page PageContent, img ImageRef) imageResult {
+	var result imageResult
+
+	// OCR text is indexed separately so exact terms from screenshots
+	// (config keys, hostnames) stay searchable even if the description misses them
+	docs, err := idx.imageTextDocs(ctx, page, img)
+	if err != nil {
+		fmt.Fprintf(idx.out, "  Warning: failed to extract text from image %s: %v\n", img.FullPath, err)
+		result.failures = append(result.failures, IndexFailure{
+			Stage:     "ocr",
+			PageTitle: page.Title,
+			FilePath:  img.FullPath,
+			Error:     err.Error(),
+		})
+	}
+	result.docs = docs
+
+	profile := idx.imageProfile(ctx, img)
+	description, err := idx.describeImage(ctx, img, profile)
+	if err != nil {
+		fmt.Fprintf(idx.out, "  Warning: failed to describe image %s: %v\n", img.FullPath, err)
+		result.failures = append(result.failures, IndexFailure{
+			Stage:     "vision",
+			PageTitle: page.Title,
+			FilePath:  img.FullPath,
+			Error:     err.Error(),
+		})
+		return result
+	}
+
+	meta := pageMetadata(page, "image_alt", img.Alt)
+	if profile != ProfileGeneric {
+		meta["image_kind"] = profile
+	}
+	result.docs = append(result.docs, Document{
+		ID:         generateDocID(img.FullPath, "image"),
+		Content:    description,
+		SourceType: "image",
+		ImagePath:  img.FullPath,
+		Metadata:   meta,
+	})
+	return result
+}
 
-	fmt.Printf("Generated %d document chunks, generating embeddings...\n", docCount)
+// imageTextDocs runs OCR on an image and returns its text as "ocr" chunks,
+// or nil when OCR is off or finds no text
+func (idx *Indexer) imageTextDocs(ctx context.Context, page PageContent, img ImageRef) ([]Document, error) {
+	if idx.ocr == nil {
+		return nil, nil
+	}
+	text, err := idx.ocr.ExtractText(ctx, img.FullPath)
+	if err != nil {
+		return nil, err
+	}
 
-	// Generate embeddings in batches
-	batchSize := 10
-	for i := 0; i < len(allDocs); i += batchSize {
-		end := i + batchSize
-		if end > len(allDocs) {
-			end = len(allDocs)
+	var docs []Document
+	for _, chunk := range idx.splitChunk(text) {
+		if len(strings.TrimSpace(chunk)) < 20 {
+			continue // Stray characters from icons and borders
+		}
+		meta := pageMetadata(page, "chunk_type", "ocr")
+		if img.Alt != "" {
+			meta["image_alt"] = img.Alt
+		}
+		if lang := DetectLanguage(chunk); lang != "" {
+			meta["language"] = lang
 		}
+		docs = append(docs, Document{
+			ID:         generateDocID(img.FullPath, "ocr:"+chunk),
+			Content:    chunk,
+			SourceType: "image",
+			ImagePath:  img.FullPath,
+			Metadata:   meta,
+		})
+	}
+	return docs, nil
+}
+
+// embedBatchSize is the number of documents embedded per request
+const embedBatchSize = 10
 
-		batch := allDocs[i:end]
+// embedDocs fills in document vectors batch by batch. A failing batch is
+// retried with exponential backoff, then embedded one document at a time so
+// a single poisoned document is skipped (and recorded) instead of aborting
+// the run. Returns the successfully embedded documents.
+func (idx *Indexer) embedDocs(ctx context.Context, docs []Document) ([]Document, error) {
+	embedded := make([]Document, 0, len(docs))
+	for i := 0; i < len(docs); i += embedBatchSize {
+		end := min(i+embedBatchSize, len(docs))
+		batch := docs[i:end]
 		texts := make([]string, len(batch))
 		for j, doc := range batch {
 			texts[j] = doc.Content
 		}
 
+		vectors, err := idx.embedWithRetry(ctx, texts)
+		if err == nil {
+			for j := range batch {
+				batch[j].Vector = vectors[j]
+			}
+			embedded = append(embedded, batch...)
+		} else {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("failed to embed batch: %w", err)
+			}
+			fmt.Fprintf(idx.out, "Warning: batch %d-%d failed (%v), embedding documents individually\n", i+1, end, err)
+			for _, doc := range batch {
+				vector, err := idx.embeddings.Embed(ctx, doc.Content)
+				if err != nil {
+					idx.failures = append(idx.failures, IndexFailure{
+						Stage:     "embed",
+						PageTitle: doc.Metadata["page_title"],
+						FilePath:  doc.Metadata["file_path"],
+						Error:     err.Error(),
+					})
+					continue
+				}
+				doc.Vector = vector
+				embedded = append(embedded, doc)
+			}
+		}
+
+		fmt.Fprintf(idx.out, "Embedded %d/%d documents\n", end, len(docs))
+	}
+	return embedded, nil
+}
+
+// embedWithRetry embeds a batch, retrying EmbedRetries times with
+// exponential backoff
+func (idx *Indexer) embedWithRetry(ctx context.Context, texts []string) ([][]float32, error) {
+	delay := idx.retryDelay
+	for attempt := 0; ; attempt++ {
 		vectors, err := idx.embeddings.EmbedBatch(ctx, texts)
-		if err != nil {
-			return fmt.Errorf("failed to embed batch: %w", err)
+		if err == nil && len(vectors) != len(texts) {
+			err = fmt.Errorf("got %d embeddings for %d texts", len(vectors), len(texts))
+		}
+		if err == nil || attempt >= idx.config.EmbedRetries {
+			return vectors, err
 		}
 
-		for j := range batch {
-			allDocs[i+j].Vector = vectors[j]
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
 		}
+		delay *= 2
+	}
+}
+
+// Failures returns the documents skipped by the last Index run
+func (idx *Indexer) Failures() []IndexFailure {
+	idx.running.Lock()
+	defer idx.running.Unlock()
+	return idx.failures
+}
 
-		fmt.Printf("Embedded %d/%d documents\n", end, len(allDocs))
+// writeReport saves the skipped documents to ReportFile, if configured
+func (idx *Indexer) writeReport() error {
+	if idx.config.ReportFile == "" {
+		return nil
 	}
+	data, err := json.MarshalIndent(idx.failures, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(idx.config.ReportFile, data, 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(idx.out, "Index report written to %s\n", idx.config.ReportFile)
+	return nil
+}
 
-	// Upsert all documents
-	fmt.Println("Storing documents in vector store...")
-	if err := idx.store.Upsert(ctx, allDocs); err != nil {
-		return fmt.Errorf("failed to upsert documents: %w", err)
+// pageMetadata returns the payload fields shared by every document of a page,
+// plus one extra key/value pair. Optional fields are omitted when unknown so
+// filters on them don't match empty strings.
+func pageMetadata(page PageContent, key, value string) map[string]string {
+	meta := map[string]string{
+		"page_title": page.Title,
+		"file_path":  page.FilePath,
+		key:          value,
+	}
+	if page.Space != "" {
+		meta["space"] = page.Space
 	}
+	if page.Source != "" {
+		meta["source"] = page.Source
+	}
+	if !page.LastModified.IsZero() {
+		meta["last_modified"] = page.LastModified.UTC().Format(time.RFC3339)
+	}
+	if len(page.Labels) > 0 {
+		meta["labels"] = labelsValue(page.Labels)
+	}
+	if len(page.Breadcrumbs) > 0 {
+		meta["breadcrumbs"] = strings.Join(page.Breadcrumbs, " > ")
+	}
+	return meta
+}
 
-	fmt.Printf("Indexing complete! %d documents indexed.\n", len(allDocs))
-	return nil
+// assignSections returns a copy of chunks with each chunk's Section and Anchor
+// set from the nearest heading at or above it, so results can cite the part
+// of the page they came from
+func assignSections(chunks []TextChunk) []TextChunk {
+	out := make([]TextChunk, len(chunks))
+	var section, anchor string
+	for i, c := range chunks {
+		if c.Type == "heading" {
+			section, anchor = c.Content, c.Anchor
+		}
+		c.Section, c.Anchor = section, anchor
+		out[i] = c
+	}
+	return out
+}
+
+// splitChunk splits text to the configured token budget, or to ChunkSize
+// bytes when token-aware chunking is disabled
+func (idx *Indexer) splitChunk(text string) []string {
+	if idx.config.ChunkTokens > 0 {
+		return ChunkTextTokens(text, idx.config.ChunkTokens, idx.config.Tokenizer)
+	}
+	return ChunkText(text, idx.config.ChunkSize)
 }
 
 // GetStore returns the vector store for querying
-func (idx *Indexer) GetStore() *VectorStore {
+func (idx *Indexer) GetStore() Store {
 	return idx.store
 }
 
 // GetEmbeddings returns the embedding client for querying
-func (idx *Indexer) GetEmbeddings() *EmbeddingClient {
+func (idx *Indexer) GetEmbeddings() Embedder {
 	return idx.embeddings
 }
 
+// KeywordIndex loads the wiki's pages and returns their text chunks in a
+// memory-only store without vectors, searched by keywords alone (SearchQuery
+// with Text and no Vector). It stands in for the vector store while that is
+// unreachable; diagrams and page summaries, which need the models, are left
+// out.
+func (idx *Indexer) KeywordIndex(ctx context.Context) (*LocalStore, error) {
+	pages, err := idx.loader.LoadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pages: %w", err)
+	}
+	store := NewMemoryStore()
+	for _, page := range pages {
+		if err := store.Upsert(ctx, idx.pageDocs(page)); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
 // generateDocID creates a unique ID for a document (UUID v5)
 func generateDocID(path, content string) string {
 	// Use a fixed namespace UUID for wiki documents
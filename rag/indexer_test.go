@@ -0,0 +1,540 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeEmbedder returns deterministic 3-dim vectors from keyword counts
+type fakeEmbedder struct {
+	calls int
+}
+
+func (f *fakeEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	f.calls++
+	lower := strings.ToLower(text)
+	return []float32{
+		float32(strings.Count(lower, "deploy")) + 0.1,
+		float32(strings.Count(lower, "network")) + 0.1,
+		float32(strings.Count(lower, "database")) + 0.1,
+	}, nil
+}
+
+func (f *fakeEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, t := range texts {
+		vectors[i], _ = f.Embed(ctx, t)
+	}
+	return vectors, nil
+}
+
+func TestIndexer_IndexDetectsVectorSize(t *testing.T) {
+	dir := t.TempDir()
+	page := `<html><head><title>Deploy Guide</title></head><body>
+<h1 id="DeployGuide-Overview">Overview</h1>
+<p>To deploy the service, run the deploy pipeline and watch the rollout.</p>
+<p>The network team owns the load balancer configuration for every region.</p>
+</body></html>`
+	if err := os.WriteFile(filepath.Join(dir, "deploy.html"), []byte(page), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := DefaultConfig()
+	config.WikiPath = dir
+	config.ChunkTokens = 0 // one chunk per paragraph
+	config.Store = NewMemoryStore()
+	config.Embedder = &fakeEmbedder{}
+	idx, err := NewIndexer(config)
+	if err != nil {
+		t.Fatalf("NewIndexer() error = %v", err)
+	}
+	if err := idx.Index(context.Background()); err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	if idx.config.VectorSize != 3 {
+		t.Errorf("VectorSize = %d, want 3 (detected)", idx.config.VectorSize)
+	}
+
+	ctx := context.Background()
+	query, _ := idx.GetEmbeddings().Embed(ctx, "network")
+	docs, err := idx.GetStore().Search(ctx, SearchQuery{Vector: query, Limit: 1})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(docs) != 1 || !strings.Contains(docs[0].Content, "load balancer") || docs[0].Metadata["page_title"] != "Deploy Guide" {
+		t.Errorf("Search() = %+v, want the network paragraph", docs)
+	}
+	if docs[0].Metadata["section"] != "Overview" || docs[0].Metadata["anchor"] != "DeployGuide-Overview" {
+		t.Errorf("Metadata = %v, want the heading's section and anchor", docs[0].Metadata)
+	}
+	if docs[0].Metadata["section_index"] != "0" || docs[0].Metadata["chunk_index"] != "1" {
+		t.Errorf("Metadata = %v, want section 0, chunk 1 (for parent-section lookup)", docs[0].Metadata)
+	}
+}
+
+// flakyEmbedder fails its first failBatches batch calls and always fails on
+// texts containing "POISON"
+type flakyEmbedder struct {
+	fakeEmbedder
+	failBatches int
+	batchCalls  int
+}
+
+func (f *flakyEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	if strings.Contains(text, "POISON") {
+		return nil, fmt.Errorf("input rejected")
+	}
+	return f.fakeEmbedder.Embed(ctx, text)
+}
+
+func (f *flakyEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	f.batchCalls++
+	if f.batchCalls <= f.failBatches {
+		return nil, fmt.Errorf("connection reset")
+	}
+	vectors := make([][]float32, len(texts))
+	for i, t := range texts {
+		v, err := f.Embed(ctx, t)
+		if err != nil {
+			return nil, err
+		}
+		vectors[i] = v
+	}
+	return vectors, nil
+}
+
+func TestIndexer_EmbedDocsRetriesAndSkipsPoisoned(t *testing.T) {
+	embedder := &flakyEmbedder{failBatches: 2}
+	idx := &Indexer{
+		config:     IndexerConfig{EmbedRetries: 3},
+		embeddings: embedder,
+		out:        io.Discard,
+	}
+
+	var docs []Document
+	for i := 0; i < 12; i++ {
+		content := fmt.Sprintf("chunk %d about deploy", i)
+		if i == 11 {
+			content = "POISON chunk"
+		}
+		docs = append(docs, Document{ID: fmt.Sprint(i), Content: content, Metadata: map[string]string{"page_title": "P", "file_path": "p.html"}})
+	}
+
+	embedded, err := idx.embedDocs(context.Background(), docs)
+	if err != nil {
+		t.Fatalf("embedDocs() error = %v", err)
+	}
+	if len(embedded) != 11 {
+		t.Errorf("embedded %d docs, want 11 (poisoned one skipped)", len(embedded))
+	}
+	for _, d := range embedded {
+		if len(d.Vector) != 3 {
+			t.Fatalf("doc %s has no vector", d.ID)
+		}
+	}
+	// Batch 1: 2 transient failures then success. Batch 2: all 4 attempts fail.
+	if embedder.batchCalls != 3+4 {
+		t.Errorf("batch calls = %d, want 7", embedder.batchCalls)
+	}
+
+	failures := idx.Failures()
+	if len(failures) != 1 || failures[0].Stage != "embed" || failures[0].FilePath != "p.html" {
+		t.Errorf("Failures() = %+v, want the poisoned doc", failures)
+	}
+
+	idx.config.ReportFile = filepath.Join(t.TempDir(), "report.json")
+	if err := idx.writeReport(); err != nil {
+		t.Fatalf("writeReport() error = %v", err)
+	}
+	data, _ := os.ReadFile(idx.config.ReportFile)
+	if !strings.Contains(string(data), `"error": "input rejected"`) {
+		t.Errorf("report = %s", data)
+	}
+}
+
+func TestIndexer_Stats(t *testing.T) {
+	dir := t.TempDir()
+	page := `<html><head><title>Deploy Guide</title></head><body>
+<p>To deploy the service, run the deploy pipeline and watch the rollout.</p>
+<p>The network team owns the load balancer configuration for every region.</p>
+</body></html>`
+	if err := os.WriteFile(filepath.Join(dir, "deploy.html"), []byte(page), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := DefaultConfig()
+	config.WikiPath = dir
+	config.ChunkTokens = 0
+	config.Store = NewMemoryStore()
+	config.Embedder = &fakeEmbedder{}
+	config.StatsFile = filepath.Join(t.TempDir(), "stats", "wiki.json")
+	idx, err := NewIndexer(config)
+	if err != nil {
+		t.Fatalf("NewIndexer() error = %v", err)
+	}
+	ctx := context.Background()
+
+	before, err := idx.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats() before indexing error = %v", err)
+	}
+	if !before.LastIndexed.IsZero() || before.Vectors != 0 {
+		t.Errorf("Stats() before indexing = %+v, want empty", before)
+	}
+
+	if err := idx.Index(ctx); err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+
+	// A fresh indexer (e.g. the next run) reads the saved stats
+	reopened, err := NewIndexer(config)
+	if err != nil {
+		t.Fatalf("NewIndexer() error = %v", err)
+	}
+	stats, err := reopened.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if stats.Pages != 1 || stats.Chunks != 2 || stats.Images != 0 || stats.Vectors != 2 || stats.VectorSize != 3 {
+		t.Errorf("Stats() = %+v, want 1 page, 2 chunks, 2 vectors of 3 dims", stats)
+	}
+	if stats.Source != dir || stats.Collection != "confluence_wiki" || stats.LastIndexed.IsZero() {
+		t.Errorf("Stats() = %+v, want source, collection and index time", stats)
+	}
+	if out := stats.String(); !strings.Contains(out, "Chunks:       2") || !strings.Contains(out, "(full)") {
+		t.Errorf("String() = %q", out)
+	}
+}
+
+// fakeOCR returns fixed text for every image
+type fakeOCR struct{ text string }
+
+func (f fakeOCR) ExtractText(context.Context, string) (string, error) { return f.text, nil }
+
+func TestIndexer_IndexesImageText(t *testing.T) {
+	dir := t.TempDir()
+	page := `<html><head><title>Postgres</title></head><body>
+<p>The primary database runs on a dedicated host with streaming replicas.</p>
+<img src="pg.png" alt="pg settings">
+</body></html>`
+	if err := os.WriteFile(filepath.Join(dir, "pg.html"), []byte(page), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pg.png"), []byte("not really a png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewMemoryStore()
+	config := DefaultConfig()
+	config.WikiPath = dir
+	config.ChunkTokens = 0
+	config.Store = store
+	config.Embedder = &fakeEmbedder{}
+	config.OCRExtractor = fakeOCR{text: "max_connections = 500\nshared_buffers = 8GB"}
+	config.VisionModel = "missing-test-model" // descriptions fail; OCR text is indexed regardless
+	idx, err := NewIndexer(config)
+	if err != nil {
+		t.Fatalf("NewIndexer() error = %v", err)
+	}
+	if err := idx.Index(context.Background()); err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+
+	docs, _ := store.Search(context.Background(), SearchQuery{Vector: []float32{1, 1, 1}, Text: "max_connections", Limit: 1})
+	if len(docs) != 1 || !strings.Contains(docs[0].Content, "max_connections = 500") {
+		t.Fatalf("Search() = %+v, want the OCR chunk", docs)
+	}
+	if docs[0].SourceType != "image" || docs[0].Metadata["chunk_type"] != "ocr" || !strings.HasSuffix(docs[0].ImagePath, "pg.png") {
+		t.Errorf("OCR doc = %+v, want an image doc with chunk_type ocr", docs[0])
+	}
+}
+
+// slowDescriber records how many descriptions run at once
+type slowDescriber struct {
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func (d *slowDescriber) DescribeImage(_ context.Context, path string) (string, error) {
+	d.mu.Lock()
+	d.inFlight++
+	d.maxInFlight = max(d.maxInFlight, d.inFlight)
+	d.mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	d.mu.Lock()
+	d.inFlight--
+	d.mu.Unlock()
+	return "diagram " + filepath.Base(path), nil
+}
+
+func TestIndexer_ProcessImagesConcurrently(t *testing.T) {
+	describer := &slowDescriber{}
+	idx := &Indexer{config: IndexerConfig{VisionWorkers: 3}, vision: describer, out: io.Discard}
+
+	var jobs []imageJob
+	for i := 0; i < 8; i++ {
+		jobs = append(jobs, imageJob{page: PageContent{Title: "Arch"}, img: ImageRef{FullPath: fmt.Sprintf("/wiki/%d.png", i)}})
+	}
+	results := idx.processImages(context.Background(), jobs)
+
+	if describer.maxInFlight != 3 {
+		t.Errorf("max concurrent descriptions = %d, want 3", describer.maxInFlight)
+	}
+	for i, r := range results {
+		if len(r.docs) != 1 || r.docs[0].Content != fmt.Sprintf("diagram %d.png", i) {
+			t.Errorf("result %d = %+v, want results in job order", i, r.docs)
+		}
+	}
+}
+
+func TestIndexer_ResumesFromCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	pages := map[string]string{
+		"deploy.html":  `<html><head><title>Deploy</title></head><body><p>To deploy the service, run the deploy pipeline and watch the rollout.</p></body></html>`,
+		"network.html": `<html><head><title>Network</title></head><body><p>The network team owns the load balancer configuration for every region.</p></body></html>`,
+	}
+	for name, html := range pages {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(html), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// An interrupted run already stored the deploy page
+	ctx := context.Background()
+	store := NewMemoryStore()
+	store.EnsureCollection(ctx, 3)
+	store.Upsert(ctx, []Document{{ID: "deploy-chunk", Content: "deploy", Vector: []float32{1, 0, 0}, Metadata: map[string]string{"file_path": filepath.Join(dir, "deploy.html")}}})
+
+	embedder := &fakeEmbedder{}
+	config := DefaultConfig()
+	config.WikiPath = dir
+	config.ChunkTokens = 0
+	config.VectorSize = 3
+	config.Store = store
+	config.Embedder = embedder
+	config.CheckpointFile = filepath.Join(t.TempDir(), "checkpoint.json")
+	idx, err := NewIndexer(config)
+	if err != nil {
+		t.Fatalf("NewIndexer() error = %v", err)
+	}
+	cp := idx.newCheckpoint()
+	cp.PagesDone = []string{filepath.Join(dir, "deploy.html")}
+	cp.Stats.Chunks = 1
+	if err := idx.saveCheckpoint(cp); err != nil {
+		t.Fatalf("saveCheckpoint() error = %v", err)
+	}
+
+	if err := idx.Index(ctx); err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	if embedder.calls != 1 {
+		t.Errorf("embedded %d chunks, want 1 (only the unfinished page)", embedder.calls)
+	}
+	if n, _ := store.Count(ctx); n != 2 {
+		t.Errorf("Count() = %d, want 2 (earlier page kept)", n)
+	}
+	if stats, _ := idx.Stats(ctx); stats.Chunks != 2 || stats.Pages != 2 {
+		t.Errorf("Stats() = %+v, want counts carried over from the checkpoint", stats)
+	}
+	if _, err := os.Stat(config.CheckpointFile); !os.IsNotExist(err) {
+		t.Errorf("checkpoint file still exists after a completed run")
+	}
+
+	// FreshIndex ignores a checkpoint and resets the collection
+	if err := idx.saveCheckpoint(cp); err != nil {
+		t.Fatal(err)
+	}
+	idx.config.FreshIndex = true
+	embedder.calls = 0
+	if err := idx.Index(ctx); err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	if embedder.calls != 2 {
+		t.Errorf("embedded %d chunks, want 2 (fresh index)", embedder.calls)
+	}
+	if docs, _ := store.Scroll(ctx, ScrollQuery{}); len(docs.Docs) != 2 || docs.Docs[0].ID == "deploy-chunk" || docs.Docs[1].ID == "deploy-chunk" {
+		t.Errorf("Scroll() = %+v, want the old collection reset", docs.Docs)
+	}
+}
+
+func TestIndexer_MultiSource(t *testing.T) {
+	wiki, runbooks := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(wiki, "deploy.html"), []byte(`<html><head><title>Deploy</title></head><body><p>To deploy the service, run the deploy pipeline and watch the rollout.</p></body></html>`), 0644)
+	os.WriteFile(filepath.Join(runbooks, "network.md"), []byte("# Network outage\n\nCheck the network load balancer health and fail over to the standby region.\n"), 0644)
+
+	store := NewMemoryStore()
+	config := DefaultConfig()
+	config.Sources = []IndexSource{
+		{Name: "wiki", Path: wiki},
+		{Name: "runbooks", Path: runbooks, Format: "notion"},
+	}
+	config.ChunkTokens = 0
+	config.Store = store
+	config.Embedder = &fakeEmbedder{}
+	idx, err := NewIndexer(config)
+	if err != nil {
+		t.Fatalf("NewIndexer() error = %v", err)
+	}
+	ctx := context.Background()
+	if err := idx.Index(ctx); err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+
+	docs, err := store.Search(ctx, SearchQuery{Vector: []float32{1, 1, 1}, Limit: 10, Filter: &Filter{Source: "runbooks"}})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(docs) == 0 {
+		t.Fatal("Search(source=runbooks) returned nothing")
+	}
+	for _, d := range docs {
+		if d.Metadata["source"] != "runbooks" || d.Metadata["page_title"] != "Network outage" {
+			t.Errorf("doc %+v, want only runbooks documents", d.Metadata)
+		}
+	}
+	stats, _ := idx.Stats(ctx)
+	if stats.Sources["wiki"] != 1 || stats.Sources["runbooks"] == 0 || !strings.Contains(stats.Source, "runbooks="+runbooks) {
+		t.Errorf("Stats() = %+v, want per-source counts", stats)
+	}
+
+	config.Sources = append(config.Sources, IndexSource{Name: "wiki", Path: runbooks})
+	if _, err := NewIndexer(config); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("NewIndexer() with duplicate source names error = %v", err)
+	}
+}
+
+// fakeSummarizer summarizes a page by its title
+type fakeSummarizer struct{ calls int }
+
+func (f *fakeSummarizer) Summarize(_ context.Context, title, text string) (string, error) {
+	f.calls++
+	return "An overview of " + title + " covering networking topics.", nil
+}
+
+func TestIndexer_PageSummaries(t *testing.T) {
+	dir := t.TempDir()
+	page := `<html><head><title>Network Overview</title></head><body>
+<p>The network team owns the load balancer configuration for every region.</p>
+<p>Firewall rules are reviewed weekly by the security team.</p>
+</body></html>`
+	if err := os.WriteFile(filepath.Join(dir, "network.html"), []byte(page), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewMemoryStore()
+	summarizer := &fakeSummarizer{}
+	config := DefaultConfig()
+	config.WikiPath = dir
+	config.ChunkTokens = 0
+	config.Store = store
+	config.Embedder = &fakeEmbedder{}
+	config.Summarizer = summarizer
+	idx, err := NewIndexer(config)
+	if err != nil {
+		t.Fatalf("NewIndexer() error = %v", err)
+	}
+	ctx := context.Background()
+	if err := idx.Index(ctx); err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+
+	if summarizer.calls != 1 {
+		t.Errorf("Summarize called %d times, want once per page", summarizer.calls)
+	}
+	docs, _ := store.Search(ctx, SearchQuery{Vector: []float32{1, 1, 1}, Limit: 5, Filter: &Filter{ChunkType: "summary"}})
+	if len(docs) != 1 || docs[0].Content != `Summary of "Network Overview": An overview of Network Overview covering networking topics.` {
+		t.Fatalf("summary docs = %+v", docs)
+	}
+	if docs[0].Metadata["page_title"] != "Network Overview" || docs[0].SourceType != "text" {
+		t.Errorf("summary metadata = %+v", docs[0])
+	}
+	if stats, _ := idx.Stats(ctx); stats.Summaries != 1 || stats.Chunks != 2 {
+		t.Errorf("Stats() = %+v, want 1 summary and 2 chunks", stats)
+	}
+}
+
+// editLoader returns fixed pages, as a delta sync once incremental is set
+type editLoader struct {
+	pages       []PageContent
+	incremental bool
+}
+
+func (l *editLoader) LoadAll() ([]PageContent, error) { return l.pages, nil }
+func (l *editLoader) Incremental() bool               { return l.incremental }
+func (l *editLoader) CommitSync() error               { return nil }
+
+func TestIndexer_DeltaSyncReplacesEditedPages(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	config := DefaultConfig()
+	config.ChunkTokens = 0
+	config.VectorSize = 3
+	config.Store = store
+	config.Embedder = &fakeEmbedder{}
+	config.CheckpointFile = filepath.Join(t.TempDir(), "checkpoint.json")
+	idx, err := NewIndexer(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := func(path string, chunks ...string) PageContent {
+		p := PageContent{Title: path, FilePath: path}
+		for _, c := range chunks {
+			p.Chunks = append(p.Chunks, TextChunk{Content: c, Type: "paragraph"})
+		}
+		return p
+	}
+	contents := func() map[string][]string {
+		docs, err := store.Scroll(ctx, ScrollQuery{Limit: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := map[string][]string{}
+		for _, d := range docs.Docs {
+			out[d.Metadata["file_path"]] = append(out[d.Metadata["file_path"]], d.Content)
+		}
+		for _, c := range out {
+			sort.Strings(c)
+		}
+		return out
+	}
+
+	loader := &editLoader{pages: []PageContent{
+		page("deploy", "Run the deploy pipeline for the service.", "Rollbacks use the previous image tag."),
+		page("network", "The network team owns the load balancer."),
+	}}
+	if err := idx.index(ctx, loader, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each edit replaces the page's chunks; chunks it lost don't linger, and
+	// pages the sync didn't return are kept
+	loader.incremental = true
+	for _, edit := range [][]string{
+		{"Deploys go through the release pipeline now."},
+		{"Deploys are paused during the freeze.", "Ask the release manager for exceptions."},
+	} {
+		loader.pages = []PageContent{page("deploy", edit...)}
+		if err := idx.index(ctx, loader, io.Discard); err != nil {
+			t.Fatal(err)
+		}
+		got := contents()
+		want := slices.Clone(edit)
+		sort.Strings(want)
+		if !slices.Equal(got["deploy"], want) {
+			t.Errorf("deploy chunks after an edit = %q, want %q", got["deploy"], want)
+		}
+		if len(got["network"]) != 1 {
+			t.Errorf("network chunks = %q, want the unchanged page kept", got["network"])
+		}
+	}
+}
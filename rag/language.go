@@ -0,0 +1,72 @@
+package rag
+
+import "strings"
+
+// MultilingualEmbedModel is the Ollama embedding model for wikis that mix
+// languages: it maps a question and its answer close together even when
+// they are in different languages, which English-only models don't
+const MultilingualEmbedModel = "bge-m3"
+
+// languageStopwords are frequent function words of the languages
+// DetectLanguage recognizes, keyed by ISO 639-1 code
+var languageStopwords = map[string]map[string]bool{
+	"en": wordSet("the and is are of to in that it for with on this be as by you not or from have was will can if an which how what where"),
+	"de": wordSet("der die das und ist nicht ein eine zu den mit von für auf im dem sich des auch werden wird sind oder bei wenn kann nach aus wir sie es ich wie wo welche"),
+	"fr": wordSet("le la les et est des une un du pour dans que qui sur pas avec ce sont au par il ou cette nous vous comment je où quel quelle"),
+	"es": wordSet("el la los las y es de que en un una para por con no se del al como está son lo pero cómo qué dónde cuál"),
+	"nl": wordSet("de het een en is van niet dat op te met voor zijn wordt worden er aan ook bij als kan naar om deze hoe ik waar welke wat"),
+	"it": wordSet("il la di che e è per un una non con sono del della le gli nel si da come anche questo dove quale cosa"),
+	"pt": wordSet("o a os as de que e é do da em um uma para com não se no na por mais dos das são como onde qual eu"),
+}
+
+// wordSet turns a space-separated word list into a set
+func wordSet(words string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
+
+// DetectLanguage guesses the language of text from its stopwords and
+// returns its ISO 639-1 code (en, de, fr, es, nl, it or pt). Returns ""
+// when the text is too short to tell or two languages score the same, so
+// callers can fall back to the page's language.
+func DetectLanguage(text string) string {
+	words := keywordTerms(text)
+	if len(words) < 4 {
+		return ""
+	}
+	scores := map[string]int{}
+	for _, w := range words {
+		for lang, stop := range languageStopwords {
+			if stop[w] {
+				scores[lang]++
+			}
+		}
+	}
+	best, bestScore, tie := "", 0, false
+	for lang, s := range scores {
+		switch {
+		case s > bestScore:
+			best, bestScore, tie = lang, s, false
+		case s == bestScore:
+			tie = true
+		}
+	}
+	if bestScore < 2 || tie {
+		return ""
+	}
+	return best
+}
+
+// pageLanguage is the language of a page's text as a whole, used for chunks
+// too short to detect on their own
+func pageLanguage(page PageContent) string {
+	var sb strings.Builder
+	for _, c := range page.Chunks {
+		sb.WriteString(c.Content)
+		sb.WriteString("\n")
+	}
+	return DetectLanguage(sb.String())
+}
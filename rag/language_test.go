@@ -0,0 +1,81 @@
+package rag
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	for _, tt := range []struct{ text, want string }{
+		{"To deploy the service, run the pipeline and watch the rollout.", "en"},
+		{"Der Dienst wird über die Pipeline ausgerollt, die auch die Tests ausführt.", "de"},
+		{"Le service est déployé par la pipeline avec les tests.", "fr"},
+		{"El servicio se despliega con la pipeline y los tests.", "es"},
+		{"De dienst wordt met de pipeline uitgerold en is daarna beschikbaar.", "nl"},
+		{"kubectl rollout restart deploy/api", ""}, // no stopwords
+		{"Deploy Guide", ""}, // too short
+	} {
+		if got := DetectLanguage(tt.text); got != tt.want {
+			t.Errorf("DetectLanguage(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestQueryTerms_GermanStopwords(t *testing.T) {
+	got := queryTerms("Wie starte ich den Dienst auf dem Server neu?")
+	want := []string{"starte", "dienst", "server", "neu"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("queryTerms() = %v, want %v", got, want)
+	}
+}
+
+func TestIndexer_LanguageMetadata(t *testing.T) {
+	dir := t.TempDir()
+	page := `<html><head><title>Netzwerk</title></head><body>
+<h1>Übersicht der Netzwerk-Topologie</h1>
+<p>Das Netzwerk-Team ist für die Konfiguration der Load Balancer in jeder Region zuständig.</p>
+<p>Die Firewall-Regeln werden bei jedem Deployment automatisch geprüft und sind im Repository abgelegt.</p>
+<p>The English summary of the network page is kept here for the on-call team.</p>
+</body></html>`
+	os.WriteFile(filepath.Join(dir, "netzwerk.html"), []byte(page), 0644)
+
+	store := NewMemoryStore()
+	config := DefaultConfig()
+	config.WikiPath = dir
+	config.ChunkTokens = 0 // one chunk per element
+	config.Store = store
+	config.Embedder = &fakeEmbedder{}
+	idx, err := NewIndexer(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Index(context.Background()); err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	result, _ := store.Scroll(context.Background(), ScrollQuery{})
+	got := map[string]string{}
+	for _, d := range result.Docs {
+		got[d.Content[:12]] = d.Metadata["language"]
+	}
+	want := map[string]string{
+		"Übersicht d":  "de", // too short on its own: the page's language
+		"Das Netzwerk": "de",
+		"Die Firewall": "de",
+		"The English ": "en",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("languages = %v, want %v", got, want)
+	}
+
+	stats, _ := idx.Stats(context.Background())
+	if stats.Languages["de"] != 3 || stats.Languages["en"] != 1 {
+		t.Errorf("Stats().Languages = %v", stats.Languages)
+	}
+	english, _ := store.Scroll(context.Background(), ScrollQuery{Filter: &Filter{Language: "en"}})
+	if len(english.Docs) != 1 {
+		t.Errorf("language filter returned %d docs, want 1", len(english.Docs))
+	}
+}
@@ -0,0 +1,195 @@
+package rag
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// LoadReport lists the corpus problems a loader found while loading an
+// export, so they can be fixed at the source instead of scrolling past as
+// per-file warnings
+type LoadReport struct {
+	MissingImages []PageRef        `json:"missing_images,omitempty"` // image references whose files aren't in the export
+	BrokenLinks   []PageRef        `json:"broken_links,omitempty"`   // links to export pages that don't exist
+	Unreadable    []UnreadableFile `json:"unreadable,omitempty"`     // files that couldn't be read or parsed
+	EmptyPages    []string         `json:"empty_pages,omitempty"`    // pages without text or images
+	Orphans       []string         `json:"orphans,omitempty"`        // pages no other page links to
+}
+
+// PageRef is a reference from a page to a file
+type PageRef struct {
+	Page   string `json:"page"`
+	Target string `json:"target"`
+}
+
+// UnreadableFile is a file a loader skipped
+type UnreadableFile struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// loadReportPaths bounds the paths listed per kind of problem at the end of
+// an Index run; LoadReportFile has them all
+const loadReportPaths = 5
+
+// reportingLoader is implemented by loaders that collect a LoadReport
+type reportingLoader interface {
+	LoadReport() LoadReport
+}
+
+// Problems returns the number of problems in the report
+func (r LoadReport) Problems() int {
+	return len(r.MissingImages) + len(r.BrokenLinks) + len(r.Unreadable) + len(r.EmptyPages) + len(r.Orphans)
+}
+
+// Summary formats the report as one count line per kind of problem,
+// followed by at most maxPaths of its paths
+func (r LoadReport) Summary(maxPaths int) string {
+	var sb strings.Builder
+	section := func(name string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		fmt.Fprintf(&sb, "  %s: %d\n", name, len(items))
+		for i, item := range items {
+			if i == maxPaths {
+				fmt.Fprintf(&sb, "    ... and %d more\n", len(items)-maxPaths)
+				break
+			}
+			fmt.Fprintf(&sb, "    %s\n", item)
+		}
+	}
+	refs := func(list []PageRef) []string {
+		out := make([]string, len(list))
+		for i, ref := range list {
+			out[i] = ref.Page + " -> " + ref.Target
+		}
+		return out
+	}
+	unreadable := make([]string, len(r.Unreadable))
+	for i, u := range r.Unreadable {
+		unreadable[i] = u.Path + ": " + u.Error
+	}
+	section("Missing images", refs(r.MissingImages))
+	section("Broken links", refs(r.BrokenLinks))
+	section("Unreadable or skipped files", unreadable)
+	section("Empty pages", r.EmptyPages)
+	section("Orphan pages", r.Orphans)
+	return sb.String()
+}
+
+// merge appends the problems of another report
+func (r *LoadReport) merge(o LoadReport) {
+	r.MissingImages = append(r.MissingImages, o.MissingImages...)
+	r.BrokenLinks = append(r.BrokenLinks, o.BrokenLinks...)
+	r.Unreadable = append(r.Unreadable, o.Unreadable...)
+	r.EmptyPages = append(r.EmptyPages, o.EmptyPages...)
+	r.Orphans = append(r.Orphans, o.Orphans...)
+}
+
+// addPage records the missing images and emptiness of a loaded page
+func (r *LoadReport) addPage(page *PageContent) {
+	for _, img := range page.missingImages {
+		r.MissingImages = append(r.MissingImages, PageRef{Page: page.FilePath, Target: img})
+	}
+	if len(page.Chunks) == 0 && len(page.Images) == 0 {
+		r.EmptyPages = append(r.EmptyPages, page.FilePath)
+	}
+}
+
+// LoadReport returns the problems found by the last LoadAll
+func (l *ConfluenceLoader) LoadReport() LoadReport { return l.report }
+
+// LoadReport returns the problems found by the last LoadAll
+func (l *NotionLoader) LoadReport() LoadReport { return l.report }
+
+// LoadReport returns the problems found by the last LoadAll
+func (l *MediaWikiLoader) LoadReport() LoadReport { return l.report }
+
+// LoadReport combines the reports of the sources that collect one
+func (m *multiLoader) LoadReport() LoadReport {
+	var r LoadReport
+	for _, l := range m.loaders {
+		if rl, ok := l.(reportingLoader); ok {
+			r.merge(rl.LoadReport())
+		}
+	}
+	return r
+}
+
+// pageLink returns the export file a link points to, or "" for links that
+// leave the export (other schemes, absolute URLs, anchors on the same page)
+// or point at something other than a page
+func pageLink(href, filePath string) string {
+	u, err := url.Parse(href)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" || strings.HasPrefix(u.Path, "/") {
+		return ""
+	}
+	ext := strings.ToLower(filepath.Ext(u.Path))
+	if ext != ".html" && ext != ".htm" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(filePath), filepath.FromSlash(u.Path))
+}
+
+// recordLink notes a link from the page being parsed to another export page
+func (l *ConfluenceLoader) recordLink(n *html.Node, page *PageContent) {
+	if target := pageLink(htmlAttr(n, "href"), page.FilePath); target != "" {
+		page.links = append(page.links, target)
+	}
+}
+
+// checkLinks records broken links and, for exports with an index.html at
+// the root (whose page tree links every page), the pages nothing links to
+func (l *ConfluenceLoader) checkLinks(pages []*PageContent) {
+	exists := map[string]bool{}
+	for _, p := range pages {
+		exists[p.FilePath] = true
+	}
+	linked := map[string]bool{}
+	for _, p := range pages {
+		for _, target := range p.links {
+			if target == p.FilePath {
+				continue
+			}
+			linked[target] = true
+			if !exists[target] {
+				if _, err := os.Stat(target); err != nil {
+					l.report.BrokenLinks = append(l.report.BrokenLinks, PageRef{Page: p.FilePath, Target: target})
+				}
+			}
+		}
+	}
+
+	root := filepath.Join(filepath.Clean(l.basePath), "index.html")
+	if !exists[root] {
+		return
+	}
+	for _, p := range pages {
+		if filepath.Base(p.FilePath) != "index.html" && !linked[p.FilePath] {
+			l.report.Orphans = append(l.report.Orphans, p.FilePath)
+		}
+	}
+}
+
+// writeLoadReport saves the last load report to LoadReportFile, if configured
+func (idx *Indexer) writeLoadReport(report LoadReport) error {
+	if idx.config.LoadReportFile == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(idx.config.LoadReportFile, data, 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(idx.out, "Load report written to %s\n", idx.config.LoadReportFile)
+	return nil
+}
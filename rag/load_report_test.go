@@ -0,0 +1,101 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfluenceLoader_LoadReport(t *testing.T) {
+	dir := t.TempDir()
+	for name, body := range map[string]string{
+		"index.html":        `<ul><li><a href="Deploy_1.html">Deploy</a></li><li><a href="Empty_3.html">Empty</a></li></ul>`,
+		"Deploy_1.html":     `<p>Run the pipeline, then see <a href="Rollback_2.html#Steps">Rollback</a> and <a href="https://example.com/x.html">docs</a>.</p><img src="attachments/1/flow.png"><img src="https://example.com/logo.png">`,
+		"Empty_3.html":      `<div></div>`,
+		"Forgotten_4.html":  `<p>Nothing links to this page any more.</p>`,
+		"Self_5.html":       `<p>Links only to itself: <a href="Self_5.html#top">top</a>.</p>`,
+		"attachments/x.txt": "not a page",
+	} {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("<html><head><title>"+name+"</title></head><body>"+body+"</body></html>"), 0644)
+	}
+
+	l := NewConfluenceLoader(dir)
+	if _, err := l.LoadAll(); err != nil {
+		t.Fatal(err)
+	}
+	report := l.LoadReport()
+	at := func(name string) string { return filepath.Join(dir, name) }
+	want := LoadReport{
+		MissingImages: []PageRef{{Page: at("Deploy_1.html"), Target: "attachments/1/flow.png"}},
+		BrokenLinks:   []PageRef{{Page: at("Deploy_1.html"), Target: at("Rollback_2.html")}},
+		EmptyPages:    []string{at("Empty_3.html")},
+		Orphans:       []string{at("Forgotten_4.html"), at("Self_5.html")},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("LoadReport() = %+v\nwant %+v", report, want)
+	}
+
+	summary := report.Summary(1)
+	for _, line := range []string{"Missing images: 1", "Broken links: 1", "Orphan pages: 2", "... and 1 more", "Deploy_1.html -> attachments/1/flow.png"} {
+		if !strings.Contains(summary, line) {
+			t.Errorf("Summary() missing %q:\n%s", line, summary)
+		}
+	}
+
+	// The next load starts a fresh report
+	os.Remove(at("Empty_3.html"))
+	l.LoadAll()
+	if got := l.LoadReport(); len(got.EmptyPages) != 0 || len(got.MissingImages) != 1 {
+		t.Errorf("second LoadReport() = %+v", got)
+	}
+}
+
+func TestNotionLoader_LoadReport(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "Runbook 0123456789abcdef0123456789abcdef.md"), []byte("# Runbook\n\nRestart the service.\n\n![topology](Runbook/topology.png)\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "Blank.md"), []byte("\n"), 0644)
+
+	l := NewNotionLoader(dir)
+	if _, err := l.LoadAll(); err != nil {
+		t.Fatal(err)
+	}
+	report := l.LoadReport()
+	if len(report.MissingImages) != 1 || report.MissingImages[0].Target != "Runbook/topology.png" {
+		t.Errorf("MissingImages = %+v", report.MissingImages)
+	}
+	if len(report.EmptyPages) != 1 || filepath.Base(report.EmptyPages[0]) != "Blank.md" {
+		t.Errorf("EmptyPages = %v", report.EmptyPages)
+	}
+}
+
+func TestIndexer_WritesLoadReport(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "deploy.html"), []byte(`<html><body><p>To deploy the service, run the deploy pipeline.</p><img src="gone.png"></body></html>`), 0644)
+
+	config := DefaultConfig()
+	config.WikiPath = dir
+	config.Store = NewMemoryStore()
+	config.Embedder = &fakeEmbedder{}
+	config.LoadReportFile = filepath.Join(t.TempDir(), "load.json")
+	idx, err := NewIndexer(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Index(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(config.LoadReportFile)
+	if err != nil {
+		t.Fatalf("load report not written: %v", err)
+	}
+	var report LoadReport
+	if err := json.Unmarshal(data, &report); err != nil || len(report.MissingImages) != 1 || report.MissingImages[0].Target != "gone.png" {
+		t.Errorf("load report = %s (%v)", data, err)
+	}
+}
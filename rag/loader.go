@@ -6,22 +6,33 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"golang.org/x/net/html"
 )
 
 // PageContent represents parsed content from a Confluence HTML page
 type PageContent struct {
-	Title    string
-	FilePath string
-	Chunks   []TextChunk
-	Images   []ImageRef
+	Title        string
+	FilePath     string
+	Space        string    // Space key, when the source knows it
+	Source       string    // Name of the index source, in multi-source indexes
+	LastModified time.Time // Zero when unknown
+	Labels       []string  // Confluence labels, lowercase
+	Breadcrumbs  []string  // Trail of pages above this one, top first
+	Chunks       []TextChunk
+	Images       []ImageRef
+
+	missingImages []string // local image references whose files don't exist (LoadReport)
+	links         []string // export pages this page links to (LoadReport)
 }
 
 // TextChunk represents a chunk of text from a page
 type TextChunk struct {
 	Content string
-	Type    string // "heading", "paragraph", "list", "code"
+	Type    string // "heading", "paragraph", "list", "code", "table"
+	Section string // Text of the nearest heading at or above this chunk
+	Anchor  string // URL fragment of that heading, when the source defines one
 }
 
 // ImageRef represents a reference to an image in the page
@@ -31,19 +42,48 @@ type ImageRef struct {
 	FullPath string // Full path to image file
 }
 
+// Loader produces pages for the indexer. ConfluenceLoader reads a local HTML
+// export; ConfluenceAPILoader pulls pages from a live Confluence instance.
+type Loader interface {
+	LoadAll() ([]PageContent, error)
+}
+
+// Ensure ConfluenceLoader implements Loader
+var _ Loader = (*ConfluenceLoader)(nil)
+
 // ConfluenceLoader parses Confluence HTML exports
 type ConfluenceLoader struct {
 	basePath string
+
+	// TableFormat controls how <table> elements become chunks:
+	// "rows" (default) emits one "Header: value; ..." chunk per row,
+	// "markdown" emits Markdown tables (header repeated every tableRowsPerChunk rows).
+	TableFormat string
+
+	// MaxFileSize skips (and reports) page files larger than this many
+	// bytes; 0 means no limit. Binary files are always skipped.
+	MaxFileSize int64
+
+	spaces map[string]string // export directory -> space key from its index.html
+	skip   []htmlSelector    // boilerplate elements left out (SkipSelectors)
+	report LoadReport        // problems found by the last LoadAll
 }
 
+// tableRowsPerChunk bounds Markdown table chunks so they stay embeddable
+const tableRowsPerChunk = 20
+
 // NewConfluenceLoader creates a new loader for a Confluence export directory
 func NewConfluenceLoader(basePath string) *ConfluenceLoader {
 	return &ConfluenceLoader{basePath: basePath}
 }
 
-// LoadAll loads all HTML pages from the export
+// LoadAll loads all HTML pages from the export. Files that fail to parse,
+// missing images, broken links, empty and orphan pages are collected in
+// LoadReport rather than failing the load.
 func (l *ConfluenceLoader) LoadAll() ([]PageContent, error) {
 	var pages []PageContent
+	var parsed []*PageContent
+	l.report = LoadReport{}
 
 	err := filepath.Walk(l.basePath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -60,10 +100,16 @@ func (l *ConfluenceLoader) LoadAll() ([]PageContent, error) {
 
 		page, err := l.LoadPage(path)
 		if err != nil {
-			// Log error but continue with other pages
-			fmt.Printf("Warning: failed to parse %s: %v\n", path, err)
+			// Report it and continue with other pages
+			l.report.Unreadable = append(l.report.Unreadable, UnreadableFile{Path: path, Error: err.Error()})
 			return nil
 		}
+		l.report.addPage(page)
+		parsed = append(parsed, page)
+
+		if page.Space == "" {
+			page.Space = l.spaceKey(filepath.Dir(path))
+		}
 
 		if len(page.Chunks) > 0 || len(page.Images) > 0 {
 			pages = append(pages, *page)
@@ -75,15 +121,16 @@ func (l *ConfluenceLoader) LoadAll() ([]PageContent, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to walk directory: %w", err)
 	}
+	l.checkLinks(parsed)
 
 	return pages, nil
 }
 
 // LoadPage loads and parses a single HTML page
 func (l *ConfluenceLoader) LoadPage(filePath string) (*PageContent, error) {
-	f, err := os.Open(filePath)
+	f, err := openPageFile(filePath, l.MaxFileSize)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
+		return nil, err
 	}
 	defer f.Close()
 
@@ -105,6 +152,9 @@ func (l *ConfluenceLoader) LoadPage(filePath string) (*PageContent, error) {
 // extractContent recursively extracts content from HTML nodes
 func (l *ConfluenceLoader) extractContent(n *html.Node, page *PageContent, filePath string) {
 	if n.Type == html.ElementNode {
+		if l.extractPageMeta(n, page) || l.skipElement(n) {
+			return
+		}
 		switch n.Data {
 		case "title":
 			if n.FirstChild != nil && n.FirstChild.Type == html.TextNode {
@@ -117,6 +167,7 @@ func (l *ConfluenceLoader) extractContent(n *html.Node, page *PageContent, fileP
 				page.Chunks = append(page.Chunks, TextChunk{
 					Content: text,
 					Type:    "heading",
+					Anchor:  htmlAttr(n, "id"),
 				})
 			}
 
@@ -148,10 +199,18 @@ func (l *ConfluenceLoader) extractContent(n *html.Node, page *PageContent, fileP
 			}
 
 		case "img":
-			img := l.extractImage(n, filePath)
+			img := l.extractImage(n, page, filePath)
 			if img != nil {
 				page.Images = append(page.Images, *img)
 			}
+
+		case "a":
+			l.recordLink(n, page)
+
+		case "table":
+			// Tables are handled as a unit; don't recurse into cells
+			l.extractTable(n, page, filePath)
+			return
 		}
 	}
 
@@ -161,6 +220,137 @@ func (l *ConfluenceLoader) extractContent(n *html.Node, page *PageContent, fileP
 	}
 }
 
+// extractTable converts a <table> into row-oriented or Markdown chunks, and
+// collects any images inside its cells
+func (l *ConfluenceLoader) extractTable(table *html.Node, page *PageContent, filePath string) {
+	var header []string
+	var rows [][]string
+
+	var walk func(n *html.Node, inHead bool)
+	walk = func(n *html.Node, inHead bool) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.Data {
+			case "thead":
+				walk(c, true)
+			case "tbody", "tfoot":
+				walk(c, false)
+			case "tr":
+				var cells []string
+				allTH := true
+				for td := c.FirstChild; td != nil; td = td.NextSibling {
+					if td.Type == html.ElementNode && (td.Data == "td" || td.Data == "th") {
+						cells = append(cells, l.extractText(td))
+						if td.Data != "th" {
+							allTH = false
+						}
+					}
+				}
+				if len(cells) == 0 {
+					continue
+				}
+				if header == nil && len(rows) == 0 && (inHead || allTH) {
+					header = cells
+				} else {
+					rows = append(rows, cells)
+				}
+			}
+		}
+	}
+	walk(table, false)
+
+	l.collectImages(table, page, filePath)
+
+	if len(rows) == 0 && header == nil {
+		return
+	}
+
+	if l.TableFormat == "markdown" {
+		if header == nil && len(rows) > 0 {
+			header, rows = rows[0], rows[1:]
+		}
+		for start := 0; start < len(rows) || start == 0; start += tableRowsPerChunk {
+			end := start + tableRowsPerChunk
+			if end > len(rows) {
+				end = len(rows)
+			}
+			page.Chunks = append(page.Chunks, TextChunk{
+				Content: markdownTable(header, rows[start:end]),
+				Type:    "table",
+			})
+			if end == len(rows) {
+				break
+			}
+		}
+		return
+	}
+
+	for _, row := range rows {
+		var parts []string
+		for i, cell := range row {
+			if cell == "" {
+				continue
+			}
+			if i < len(header) && header[i] != "" {
+				parts = append(parts, header[i]+": "+cell)
+			} else {
+				parts = append(parts, cell)
+			}
+		}
+		if len(parts) > 0 {
+			page.Chunks = append(page.Chunks, TextChunk{
+				Content: strings.Join(parts, "; "),
+				Type:    "table",
+			})
+		}
+	}
+}
+
+// collectImages finds <img> elements anywhere under n
+func (l *ConfluenceLoader) collectImages(n *html.Node, page *PageContent, filePath string) {
+	if n.Type == html.ElementNode && n.Data == "img" {
+		if img := l.extractImage(n, page, filePath); img != nil {
+			page.Images = append(page.Images, *img)
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		l.collectImages(c, page, filePath)
+	}
+}
+
+// markdownTable renders a header and rows as a Markdown table
+func markdownTable(header []string, rows [][]string) string {
+	escape := func(s string) string { return strings.ReplaceAll(s, "|", "\\|") }
+	line := func(cells []string, width int) string {
+		out := make([]string, width)
+		for i := range out {
+			if i < len(cells) {
+				out[i] = escape(cells[i])
+			}
+		}
+		return "| " + strings.Join(out, " | ") + " |"
+	}
+
+	width := len(header)
+	for _, r := range rows {
+		if len(r) > width {
+			width = len(r)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(line(header, width))
+	sb.WriteString("\n|")
+	sb.WriteString(strings.Repeat(" --- |", width))
+	for _, r := range rows {
+		sb.WriteString("\n")
+		sb.WriteString(line(r, width))
+	}
+	return sb.String()
+}
+
 // extractText extracts all text from a node and its children
 func (l *ConfluenceLoader) extractText(n *html.Node) string {
 	var text strings.Builder
@@ -181,8 +371,19 @@ func (l *ConfluenceLoader) extractTextRecursive(n *html.Node, text *strings.Buil
 	}
 }
 
-// extractImage extracts image information from an img tag
-func (l *ConfluenceLoader) extractImage(n *html.Node, filePath string) *ImageRef {
+// htmlAttr returns the value of an element attribute, or ""
+func htmlAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// extractImage extracts image information from an img tag. Local images
+// whose files don't exist are recorded on page for the LoadReport.
+func (l *ConfluenceLoader) extractImage(n *html.Node, page *PageContent, filePath string) *ImageRef {
 	var src, alt string
 	for _, attr := range n.Attr {
 		switch attr.Key {
@@ -217,6 +418,7 @@ func (l *ConfluenceLoader) extractImage(n *html.Node, filePath string) *ImageRef
 		// Try relative to base path
 		fullPath = filepath.Join(l.basePath, src)
 		if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+			page.missingImages = append(page.missingImages, src)
 			return nil
 		}
 	}
@@ -179,3 +179,78 @@ func TestImageExtraction(t *testing.T) {
 		t.Errorf("Image alt = %q, want %q", img.Alt, "Architecture Diagram")
 	}
 }
+
+func TestTableExtraction(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "confluence-table-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	testHTML := `<!DOCTYPE html>
+<html>
+<head><title>Runbook</title></head>
+<body>
+<p>Service ports:</p>
+<table>
+<thead><tr><th>Service</th><th>Port</th><th>Owner</th></tr></thead>
+<tbody>
+<tr><td><p>api-gateway</p></td><td>8443</td><td>platform</td></tr>
+<tr><td>billing | v2</td><td>9090</td><td></td></tr>
+</tbody>
+</table>
+</body>
+</html>`
+
+	htmlPath := filepath.Join(tmpDir, "runbook.html")
+	if err := os.WriteFile(htmlPath, []byte(testHTML), 0644); err != nil {
+		t.Fatalf("Failed to write test HTML: %v", err)
+	}
+
+	loader := NewConfluenceLoader(tmpDir)
+	page, err := loader.LoadPage(htmlPath)
+	if err != nil {
+		t.Fatalf("LoadPage() error = %v", err)
+	}
+
+	var tables []string
+	for _, chunk := range page.Chunks {
+		if chunk.Type == "table" {
+			tables = append(tables, chunk.Content)
+		}
+		if chunk.Type == "paragraph" && chunk.Content == "api-gateway" {
+			t.Error("cell paragraph should not be extracted separately")
+		}
+	}
+	want := []string{
+		"Service: api-gateway; Port: 8443; Owner: platform",
+		"Service: billing | v2; Port: 9090",
+	}
+	if len(tables) != len(want) {
+		t.Fatalf("table chunks = %q, want %q", tables, want)
+	}
+	for i := range want {
+		if tables[i] != want[i] {
+			t.Errorf("row %d = %q, want %q", i, tables[i], want[i])
+		}
+	}
+
+	loader.TableFormat = "markdown"
+	page, err = loader.LoadPage(htmlPath)
+	if err != nil {
+		t.Fatalf("LoadPage() error = %v", err)
+	}
+	wantMD := "| Service | Port | Owner |\n| --- | --- | --- |\n| api-gateway | 8443 | platform |\n| billing \\| v2 | 9090 |  |"
+	found := false
+	for _, chunk := range page.Chunks {
+		if chunk.Type == "table" {
+			found = true
+			if chunk.Content != wantMD {
+				t.Errorf("markdown table =\n%s\nwant\n%s", chunk.Content, wantMD)
+			}
+		}
+	}
+	if !found {
+		t.Error("Expected a markdown table chunk")
+	}
+}
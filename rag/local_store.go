@@ -0,0 +1,550 @@
+package rag
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LocalStore is an embedded, on-disk vector store: documents live in memory
+// and are persisted to a gob file per collection. Writes are appended to a
+// log next to it (<collection>.gob.log), which is folded into the file once
+// it outgrows it, so indexing doesn't rewrite the collection per batch.
+// Search is a brute-force cosine scan, which is fast enough for wiki-sized
+// corpora (tens of thousands of chunks) and needs no external service.
+//
+// Results are deterministic: ties are broken by document ID, so a LocalStore
+// from NewMemoryStore doubles as a test fake.
+type LocalStore struct {
+	path string // empty for a memory-only store
+
+	mu         sync.RWMutex
+	vectorSize int
+	docs       map[string]Document
+	df         map[string]int // documents per keyword term, for BM25
+	saved      int64          // size of the collection file when last written
+	logged     int64          // bytes appended to the log since then
+	torn       bool           // the log ends in a write cut short, past logged
+}
+
+// Ensure both stores implement Store
+var (
+	_ Store = (*LocalStore)(nil)
+	_ Store = (*VectorStore)(nil)
+)
+
+// localStoreFile is the on-disk format of a LocalStore collection
+type localStoreFile struct {
+	VectorSize int
+	Docs       []Document
+}
+
+// localStoreOp is one write in the log of a collection: a length (4 bytes,
+// big endian) and the gob of the op
+type localStoreOp struct {
+	Upsert []Document
+	Delete []string
+}
+
+// minLogCompact is the log size below which it isn't folded into the
+// collection file, however small that is
+const minLogCompact = 1 << 20
+
+// NewLocalStore opens the collection stored in dir/<collectionName>.gob,
+// loading it and replaying its log if they exist
+func NewLocalStore(dir, collectionName string) (*LocalStore, error) {
+	s := &LocalStore{
+		path: filepath.Join(dir, collectionName+".gob"),
+		docs: make(map[string]Document),
+		df:   make(map[string]int),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	if err := s.replay(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewMemoryStore creates a memory-only store with no backing file, for tests
+// and throwaway indexes
+func NewMemoryStore() *LocalStore {
+	return &LocalStore{docs: make(map[string]Document), df: make(map[string]int)}
+}
+
+// load reads the collection file, if there is one
+func (s *LocalStore) load() error {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open local store: %w", err)
+	}
+	defer f.Close()
+
+	var data localStoreFile
+	if err := gob.NewDecoder(bufio.NewReader(f)).Decode(&data); err != nil {
+		return fmt.Errorf("failed to read local store %s: %w", s.path, err)
+	}
+	if info, err := f.Stat(); err == nil {
+		s.saved = info.Size()
+	}
+	s.vectorSize = data.VectorSize
+	for _, d := range data.Docs {
+		s.put(d)
+	}
+	return nil
+}
+
+// replay applies the writes in the log. A write cut short (the process
+// died while appending it) is skipped, and cut off before the next write.
+func (s *LocalStore) replay() error {
+	f, err := os.Open(s.logPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open local store log: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to open local store log: %w", err)
+	}
+
+	r := bufio.NewReader(f)
+	var offset int64
+	for {
+		var size uint32
+		err := binary.Read(r, binary.BigEndian, &size)
+		if err == io.EOF {
+			break
+		}
+		if err == nil && offset+4+int64(size) > info.Size() {
+			err = io.ErrUnexpectedEOF
+		}
+		var record []byte
+		if err == nil {
+			record = make([]byte, size)
+			_, err = io.ReadFull(r, record)
+		}
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			s.torn = true
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read local store log: %w", err)
+		}
+		var op localStoreOp
+		if err := gob.NewDecoder(bytes.NewReader(record)).Decode(&op); err != nil {
+			return fmt.Errorf("failed to read local store log %s: %w", s.logPath(), err)
+		}
+		for _, d := range op.Upsert {
+			s.put(d)
+		}
+		for _, id := range op.Delete {
+			s.remove(id)
+		}
+		offset += 4 + int64(size)
+	}
+	s.logged = offset
+	return nil
+}
+
+// put adds or replaces a document, keeping df up to date. Callers hold mu.
+func (s *LocalStore) put(d Document) {
+	if old, ok := s.docs[d.ID]; ok {
+		s.count(old, -1)
+	}
+	s.docs[d.ID] = d
+	s.count(d, 1)
+}
+
+// remove deletes a document, keeping df up to date. Callers hold mu.
+func (s *LocalStore) remove(id string) bool {
+	d, ok := s.docs[id]
+	if ok {
+		s.count(d, -1)
+		delete(s.docs, id)
+	}
+	return ok
+}
+
+// count adds n to the document frequency of each of d's terms
+func (s *LocalStore) count(d Document, n int) {
+	seen := map[string]bool{}
+	for _, w := range keywordTerms(d.Content) {
+		if seen[w] {
+			continue
+		}
+		seen[w] = true
+		if s.df[w] += n; s.df[w] <= 0 {
+			delete(s.df, w)
+		}
+	}
+}
+
+// logPath returns the file writes are appended to
+func (s *LocalStore) logPath() string {
+	return s.path + ".log"
+}
+
+// Path returns the file backing the collection ("" for a memory-only store)
+func (s *LocalStore) Path() string {
+	return s.path
+}
+
+// EnsureCollection records the vector size and creates the collection file
+// if it doesn't exist
+func (s *LocalStore) EnsureCollection(ctx context.Context, vectorSize int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.vectorSize != 0 && s.vectorSize != vectorSize {
+		return fmt.Errorf("collection has %d-dimensional vectors, want %d (re-index to reset)", s.vectorSize, vectorSize)
+	}
+	s.vectorSize = vectorSize
+	if s.path == "" {
+		return nil
+	}
+	if _, err := os.Stat(s.path); err == nil {
+		return nil
+	}
+	return s.save()
+}
+
+// DeleteCollection drops all documents and removes the collection file
+func (s *LocalStore) DeleteCollection(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.vectorSize = 0
+	s.docs = make(map[string]Document)
+	s.df = make(map[string]int)
+	s.saved, s.logged, s.torn = 0, 0, false
+	if s.path == "" {
+		return nil
+	}
+	for _, path := range []string{s.path, s.logPath()} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete collection: %w", err)
+		}
+	}
+	return nil
+}
+
+// Upsert adds or updates documents and appends them to the log
+func (s *LocalStore) Upsert(ctx context.Context, docs []Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	op := localStoreOp{Upsert: make([]Document, 0, len(docs))}
+	for _, d := range docs {
+		if s.vectorSize != 0 && len(d.Vector) != s.vectorSize {
+			return fmt.Errorf("document %s has %d-dimensional vector, want %d", d.ID, len(d.Vector), s.vectorSize)
+		}
+		d.Score = 0
+		s.put(d)
+		op.Upsert = append(op.Upsert, d)
+	}
+	return s.write(op)
+}
+
+// Search finds similar documents. With q.Text set, cosine similarity and BM25
+// keyword ranking are fused with reciprocal rank fusion, as in VectorStore;
+// with q.Text and no q.Vector, documents are ranked by keywords alone.
+func (s *LocalStore) Search(ctx context.Context, q SearchQuery) ([]Document, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var candidates []Document
+	for _, d := range s.docs {
+		if q.Filter.matches(d) {
+			candidates = append(candidates, d)
+		}
+	}
+	// Fixed input order keeps BM25 and fusion ties deterministic
+	sort.Slice(candidates, func(a, b int) bool { return candidates[a].ID < candidates[b].ID })
+
+	if q.Text == "" {
+		return s.vectorRank(candidates, q.Vector, q.Limit, q.WithVectors), nil
+	}
+
+	keywordDocs := bm25Rank(candidates, queryTerms(q.Text), s.df, len(s.docs))
+	if len(q.Vector) == 0 {
+		if len(keywordDocs) > q.Limit {
+			keywordDocs = keywordDocs[:q.Limit]
+		}
+		if !q.WithVectors {
+			for i := range keywordDocs {
+				keywordDocs[i].Vector = nil
+			}
+		}
+		return keywordDocs, nil
+	}
+	if len(keywordDocs) > q.Limit*3 {
+		keywordDocs = keywordDocs[:q.Limit*3]
+	}
+	// Over-fetch each leg so fusion has room to reorder
+	vectorDocs := s.vectorRank(candidates, q.Vector, q.Limit*3, q.WithVectors)
+	fused := FuseRRF(q.Limit, vectorDocs, keywordDocs)
+	if !q.WithVectors {
+		for i := range fused {
+			fused[i].Vector = nil
+		}
+	}
+	return fused, nil
+}
+
+// vectorRank returns the limit documents most cosine-similar to vector
+func (s *LocalStore) vectorRank(docs []Document, vector []float32, limit int, withVectors bool) []Document {
+	ranked := make([]Document, len(docs))
+	for i, d := range docs {
+		d.Score = cosine(vector, d.Vector)
+		if !withVectors {
+			d.Vector = nil
+		}
+		ranked[i] = d
+	}
+	// docs is sorted by ID, so a stable sort breaks score ties by ID
+	sort.SliceStable(ranked, func(a, b int) bool { return ranked[a].Score > ranked[b].Score })
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
+
+// Scroll pages through stored documents in ID order. The cursor is the ID of
+// the first document of the next page.
+func (s *LocalStore) Scroll(ctx context.Context, q ScrollQuery) (ScrollResult, error) {
+	if q.Limit <= 0 {
+		q.Limit = defaultScrollLimit
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matched []Document
+	for _, d := range s.docs {
+		if d.ID >= q.Offset && q.Filter.matches(d) {
+			d.Vector = nil
+			matched = append(matched, d)
+		}
+	}
+	sort.Slice(matched, func(a, b int) bool { return matched[a].ID < matched[b].ID })
+
+	var result ScrollResult
+	if len(matched) > q.Limit {
+		result.NextOffset = matched[q.Limit].ID
+		matched = matched[:q.Limit]
+	}
+	result.Docs = matched
+	return result, nil
+}
+
+// DeleteByIDs removes the given documents
+func (s *LocalStore) DeleteByIDs(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	op := localStoreOp{}
+	for _, id := range ids {
+		if s.remove(id) {
+			op.Delete = append(op.Delete, id)
+		}
+	}
+	return s.write(op)
+}
+
+// DeleteByFilter removes every document matching the filter
+func (s *LocalStore) DeleteByFilter(ctx context.Context, f *Filter) error {
+	if f.isEmpty() {
+		return errEmptyDeleteFilter
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	op := localStoreOp{}
+	for id, d := range s.docs {
+		if f.matches(d) {
+			s.remove(id)
+			op.Delete = append(op.Delete, id)
+		}
+	}
+	return s.write(op)
+}
+
+// Count returns the number of documents in the collection
+func (s *LocalStore) Count(ctx context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.docs), nil
+}
+
+// write appends op to the log, and folds the log into the collection file
+// once it is larger than that. Callers hold mu.
+func (s *LocalStore) write(op localStoreOp) error {
+	if s.path == "" || len(op.Upsert) == 0 && len(op.Delete) == 0 {
+		return nil
+	}
+	var record bytes.Buffer
+	record.Write(make([]byte, 4))
+	if err := gob.NewEncoder(&record).Encode(op); err != nil {
+		return fmt.Errorf("failed to write local store log: %w", err)
+	}
+	binary.BigEndian.PutUint32(record.Bytes(), uint32(record.Len()-4))
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create store dir: %w", err)
+	}
+	f, err := os.OpenFile(s.logPath(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write local store log: %w", err)
+	}
+	if s.torn {
+		err = f.Truncate(s.logged)
+		s.torn = err != nil
+	}
+	if err == nil {
+		_, err = f.Write(record.Bytes())
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write local store log: %w", err)
+	}
+	s.logged += int64(record.Len())
+	if s.logged > max(s.saved, minLogCompact) {
+		return s.save()
+	}
+	return nil
+}
+
+// save writes the collection atomically (temp file + rename) and removes
+// the log, which it now holds. Callers hold mu.
+func (s *LocalStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create store dir: %w", err)
+	}
+
+	data := localStoreFile{VectorSize: s.vectorSize, Docs: make([]Document, 0, len(s.docs))}
+	for _, d := range s.docs {
+		data.Docs = append(data.Docs, d)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write local store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write local store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write local store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write local store: %w", err)
+	}
+	if info, err := os.Stat(s.path); err == nil {
+		s.saved = info.Size()
+	}
+	// Replaying a log over the writes it holds changes nothing, so a log
+	// left behind by a crash here is harmless
+	if err := os.Remove(s.logPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove local store log: %w", err)
+	}
+	s.logged, s.torn = 0, false
+	return nil
+}
+
+// matches reports whether a document satisfies the filter, mirroring the
+// Qdrant conditions built by conditions(). A nil filter matches everything.
+func (f *Filter) matches(d Document) bool {
+	if f == nil {
+		return true
+	}
+	if f.PageTitle != "" && !strings.Contains(strings.ToLower(d.Metadata["page_title"]), strings.ToLower(f.PageTitle)) {
+		return false
+	}
+	if f.ChunkType != "" && d.Metadata["chunk_type"] != f.ChunkType {
+		return false
+	}
+	if f.SourceType != "" && d.SourceType != f.SourceType {
+		return false
+	}
+	if f.Space != "" && d.Metadata["space"] != f.Space {
+		return false
+	}
+	if f.Source != "" && d.Metadata["source"] != f.Source {
+		return false
+	}
+	if f.FilePath != "" && d.Metadata["file_path"] != f.FilePath {
+		return false
+	}
+	if f.Label != "" && !strings.Contains(d.Metadata["labels"], f.labelMatch()) {
+		return false
+	}
+	if f.Language != "" && d.Metadata["language"] != f.Language {
+		return false
+	}
+	if !f.ModifiedAfter.IsZero() || !f.ModifiedBefore.IsZero() {
+		// RFC 3339 UTC timestamps compare correctly as strings
+		modified := d.Metadata["last_modified"]
+		if modified == "" {
+			return false
+		}
+		if !f.ModifiedAfter.IsZero() && modified < f.ModifiedAfter.UTC().Format(time.RFC3339) {
+			return false
+		}
+		if !f.ModifiedBefore.IsZero() && modified >= f.ModifiedBefore.UTC().Format(time.RFC3339) {
+			return false
+		}
+	}
+	return true
+}
+
+// cosine returns the cosine similarity of two vectors (0 if either is empty
+// or their lengths differ)
+func cosine(a, b []float32) float32 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(na) * math.Sqrt(nb)))
+}
@@ -0,0 +1,308 @@
+package rag
+
+import (
+	"context"
+	"maps"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocalStore_PersistAndSearch(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store, err := NewLocalStore(dir, "wiki")
+	if err != nil {
+		t.Fatalf("NewLocalStore() error = %v", err)
+	}
+	if err := store.EnsureCollection(ctx, 2); err != nil {
+		t.Fatalf("EnsureCollection() error = %v", err)
+	}
+	docs := []Document{
+		{ID: "1", Content: "Deployments roll out gradually.", Vector: []float32{1, 0}, SourceType: "text", Metadata: map[string]string{"page_title": "Deploy"}},
+		{ID: "2", Content: "Pods restart on probe failure.", Vector: []float32{0.7, 0.7}, SourceType: "text"},
+		{ID: "3", Content: "Host db-7.prod returns error E1234 when disk is full.", Vector: []float32{0, 1}, SourceType: "text"},
+		{ID: "4", Content: "Network topology diagram.", Vector: []float32{1, 0.1}, SourceType: "image", ImagePath: "/tmp/net.png"},
+	}
+	if err := store.Upsert(ctx, docs); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	// Reopen from disk
+	store, err = NewLocalStore(dir, "wiki")
+	if err != nil {
+		t.Fatalf("NewLocalStore() reopen error = %v", err)
+	}
+	if n, _ := store.Count(ctx); n != 4 {
+		t.Fatalf("Count() after reopen = %d, want 4", n)
+	}
+
+	got, err := store.Search(ctx, SearchQuery{Vector: []float32{1, 0}, Limit: 2})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(got) != 2 || got[0].ID != "1" || got[1].ID != "4" {
+		t.Errorf("vector Search() = %+v, want [1 4]", got)
+	}
+	if got[0].Metadata["page_title"] != "Deploy" || got[0].Vector != nil {
+		t.Errorf("result = %+v, want metadata kept and vector dropped", got[0])
+	}
+
+	// Hybrid search lifts the exact-identifier match to the top
+	got, _ = store.Search(ctx, SearchQuery{Vector: []float32{1, 0}, Text: "error E1234", Limit: 2})
+	if len(got) == 0 || got[0].ID != "3" {
+		t.Errorf("hybrid Search() = %+v, want 3 first", got)
+	}
+
+	got, _ = store.Search(ctx, SearchQuery{Vector: []float32{1, 0}, Text: "error E1234", Limit: 2, WithVectors: true})
+	if len(got) == 0 || len(got[0].Vector) != 2 {
+		t.Errorf("Search(WithVectors) = %+v, want vectors kept", got)
+	}
+
+	// Filters apply before ranking
+	got, _ = store.Search(ctx, SearchQuery{Vector: []float32{1, 0}, Limit: 5, Filter: &Filter{SourceType: "image"}})
+	if len(got) != 1 || got[0].ImagePath != "/tmp/net.png" {
+		t.Errorf("filtered Search() = %+v, want only the image", got)
+	}
+
+	if err := store.DeleteCollection(ctx); err != nil {
+		t.Fatalf("DeleteCollection() error = %v", err)
+	}
+	store, _ = NewLocalStore(dir, "wiki")
+	if n, _ := store.Count(ctx); n != 0 {
+		t.Errorf("Count() after delete = %d, want 0", n)
+	}
+}
+
+func TestFilterMatches(t *testing.T) {
+	doc := Document{
+		SourceType: "text",
+		Metadata: map[string]string{
+			"page_title":    "Network Topology",
+			"chunk_type":    "table",
+			"space":         "NET",
+			"last_modified": "2024-03-01T10:00:00Z",
+			"labels":        ",runbook,postgres,",
+		},
+	}
+	tests := []struct {
+		name   string
+		filter *Filter
+		want   bool
+	}{
+		{"nil", nil, true},
+		{"title substring", &Filter{PageTitle: "topology"}, true},
+		{"wrong space", &Filter{Space: "OPS"}, false},
+		{"chunk and source", &Filter{ChunkType: "table", SourceType: "text"}, true},
+		{"after", &Filter{ModifiedAfter: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}, true},
+		{"before", &Filter{ModifiedBefore: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}, false},
+		{"label", &Filter{Label: "Runbook"}, true},
+		{"label prefix", &Filter{Label: "run"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.matches(doc); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStore_DeterministicTies(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	var docs []Document
+	for _, id := range []string{"d", "b", "a", "c"} {
+		docs = append(docs, Document{ID: id, Content: "same text", Vector: []float32{1, 1}})
+	}
+	if err := store.Upsert(ctx, docs); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	if store.Path() != "" {
+		t.Errorf("Path() = %q, want empty for a memory store", store.Path())
+	}
+
+	for i := 0; i < 5; i++ {
+		got, _ := store.Search(ctx, SearchQuery{Vector: []float32{1, 1}, Text: "same", Limit: 3})
+		if len(got) != 3 || got[0].ID != "a" || got[1].ID != "b" || got[2].ID != "c" {
+			t.Fatalf("Search() = %+v, want ties ordered a, b, c", got)
+		}
+	}
+}
+
+func TestLocalStore_ScrollAndListIDs(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	var docs []Document
+	for _, id := range []string{"e", "c", "a", "d", "b"} {
+		space := "OPS"
+		if id == "d" {
+			space = "DEV"
+		}
+		docs = append(docs, Document{ID: id, Content: id, Vector: []float32{1}, Metadata: map[string]string{"space": space}})
+	}
+	store.Upsert(ctx, docs)
+
+	page, err := store.Scroll(ctx, ScrollQuery{Limit: 2})
+	if err != nil {
+		t.Fatalf("Scroll() error = %v", err)
+	}
+	if len(page.Docs) != 2 || page.Docs[0].ID != "a" || page.Docs[1].ID != "b" || page.NextOffset != "c" {
+		t.Fatalf("first page = %+v", page)
+	}
+	if page.Docs[0].Vector != nil {
+		t.Error("Scroll() should not return vectors")
+	}
+
+	ids, err := ListIDs(ctx, store, &Filter{Space: "OPS"})
+	if err != nil {
+		t.Fatalf("ListIDs() error = %v", err)
+	}
+	if strings.Join(ids, ",") != "a,b,c,e" {
+		t.Errorf("ListIDs() = %v, want a,b,c,e", ids)
+	}
+}
+
+func TestLocalStore_Delete(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	store.Upsert(ctx, []Document{
+		{ID: "a1", Vector: []float32{1}, Metadata: map[string]string{"file_path": "a.html"}},
+		{ID: "a2", Vector: []float32{1}, Metadata: map[string]string{"file_path": "a.html"}},
+		{ID: "b1", Vector: []float32{1}, Metadata: map[string]string{"file_path": "b.html"}},
+		{ID: "c1", Vector: []float32{1}, Metadata: map[string]string{"file_path": "c.html"}},
+	})
+
+	if err := store.DeleteByFilter(ctx, &Filter{FilePath: "a.html"}); err != nil {
+		t.Fatalf("DeleteByFilter() error = %v", err)
+	}
+	if err := store.DeleteByIDs(ctx, []string{"c1", "missing"}); err != nil {
+		t.Fatalf("DeleteByIDs() error = %v", err)
+	}
+	ids, _ := ListIDs(ctx, store, nil)
+	if strings.Join(ids, ",") != "b1" {
+		t.Errorf("remaining = %v, want [b1]", ids)
+	}
+
+	if err := store.DeleteByFilter(ctx, &Filter{}); err == nil {
+		t.Error("DeleteByFilter() with an empty filter should fail")
+	}
+}
+
+func TestMemoryStore_KeywordOnlySearch(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	store.Upsert(ctx, []Document{
+		{ID: "1", Content: "Deployments roll out gradually.", SourceType: "text"},
+		{ID: "2", Content: "Host db-7.prod returns error E1234 when disk is full.", SourceType: "text"},
+		{ID: "3", Content: "Error budgets are reviewed monthly.", SourceType: "text"},
+	})
+
+	got, err := store.Search(ctx, SearchQuery{Text: "error E1234", Limit: 1})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(got) != 1 || got[0].ID != "2" {
+		t.Errorf("keyword Search() = %+v, want only 2", got)
+	}
+}
+
+func TestLocalStore_Log(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewLocalStore(dir, "wiki")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.EnsureCollection(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	info, _ := os.Stat(store.Path())
+
+	// Batches are appended to the log; the collection file is left alone
+	for _, id := range []string{"a", "b", "c"} {
+		if err := store.Upsert(ctx, []Document{{ID: id, Content: "page " + id, Vector: []float32{1}}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Upsert(ctx, []Document{{ID: "b", Content: "page b edited", Vector: []float32{1}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.DeleteByIDs(ctx, []string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := os.Stat(store.Path()); after.Size() != info.Size() || !after.ModTime().Equal(info.ModTime()) {
+		t.Error("the collection file was rewritten by small writes")
+	}
+	if _, err := os.Stat(store.Path() + ".log"); err != nil {
+		t.Fatalf("log: %v", err)
+	}
+
+	// A write cut short is skipped, then cut off by the next one
+	f, _ := os.OpenFile(store.Path()+".log", os.O_WRONLY|os.O_APPEND, 0)
+	f.Write([]byte{0, 0, 1, 0, 'x'})
+	f.Close()
+	store, err = NewLocalStore(dir, "wiki")
+	if err != nil {
+		t.Fatalf("reopen with a torn log: %v", err)
+	}
+	if err := store.Upsert(ctx, []Document{{ID: "d", Content: "page d", Vector: []float32{1}}}); err != nil {
+		t.Fatal(err)
+	}
+	store, err = NewLocalStore(dir, "wiki")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := store.Scroll(ctx, ScrollQuery{})
+	var ids []string
+	for _, d := range got.Docs {
+		ids = append(ids, d.ID+":"+d.Content)
+	}
+	if strings.Join(ids, ",") != "b:page b edited,c:page c,d:page d" {
+		t.Errorf("after replay = %v", ids)
+	}
+
+	// A log larger than the collection file is folded into it
+	big := strings.Repeat("word ", minLogCompact/5)
+	if err := store.Upsert(ctx, []Document{{ID: "e", Content: big, Vector: []float32{1}}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(store.Path() + ".log"); !os.IsNotExist(err) {
+		t.Errorf("log after compaction: %v, want removed", err)
+	}
+	store, _ = NewLocalStore(dir, "wiki")
+	if n, _ := store.Count(ctx); n != 4 {
+		t.Errorf("Count() after compaction = %d, want 4", n)
+	}
+}
+
+func TestLocalStore_DocumentFrequencies(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	store.Upsert(ctx, []Document{
+		{ID: "1", Content: "disk full on db-7"},
+		{ID: "2", Content: "disk disk latency"},
+		{ID: "3", Content: "restart the api"},
+	})
+	store.Upsert(ctx, []Document{{ID: "3", Content: "disk replaced"}})
+	store.DeleteByIDs(ctx, []string{"1"})
+	store.DeleteByFilter(ctx, &Filter{SourceType: "image"})
+
+	// The cached counts match counting from scratch
+	want := NewMemoryStore()
+	for _, d := range store.docs {
+		want.put(d)
+	}
+	if !maps.Equal(store.df, want.df) {
+		t.Errorf("df = %v, want %v", store.df, want.df)
+	}
+	if store.df["disk"] != 2 || store.df["restart"] != 0 || store.df["full"] != 0 {
+		t.Errorf("df = %v", store.df)
+	}
+	got, _ := store.Search(ctx, SearchQuery{Text: "replaced", Limit: 5})
+	if len(got) != 1 || got[0].ID != "3" {
+		t.Errorf("keyword Search() = %+v, want 3", got)
+	}
+}
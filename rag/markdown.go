@@ -0,0 +1,132 @@
+package rag
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	mdHeadingRe = regexp.MustCompile(`^#{1,6}\s+(.*?)\s*#*$`)
+	mdListRe    = regexp.MustCompile(`^(?:[-*+]|\d+[.)])\s+(.*)$`)
+	mdImageRe   = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	mdLinkRe    = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	mdEmphRe    = regexp.MustCompile("[*_`]{1,3}")
+)
+
+// parseMarkdown converts a Markdown document into typed text chunks and image
+// references. Image paths are resolved relative to filePath and kept only if
+// the file exists, mirroring ConfluenceLoader.extractImage.
+func parseMarkdown(content, filePath string) *PageContent {
+	page := &PageContent{FilePath: filePath}
+
+	var para []string
+	var code []string
+	inFence := false
+
+	flushPara := func() {
+		if t := cleanMarkdownInline(strings.Join(para, " ")); t != "" {
+			page.Chunks = append(page.Chunks, TextChunk{Content: t, Type: "paragraph"})
+		}
+		para = nil
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			if inFence {
+				if t := strings.TrimSpace(strings.Join(code, "\n")); t != "" {
+					page.Chunks = append(page.Chunks, TextChunk{Content: t, Type: "code"})
+				}
+				code = nil
+			} else {
+				flushPara()
+			}
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			code = append(code, line)
+			continue
+		}
+
+		for _, m := range mdImageRe.FindAllStringSubmatch(line, -1) {
+			if img, missing := resolveMarkdownImage(m[2], m[1], filePath); img != nil {
+				page.Images = append(page.Images, *img)
+			} else if missing {
+				page.missingImages = append(page.missingImages, m[2])
+			}
+		}
+
+		switch {
+		case trimmed == "":
+			flushPara()
+		case mdHeadingRe.MatchString(trimmed):
+			flushPara()
+			title := cleanMarkdownInline(mdHeadingRe.FindStringSubmatch(trimmed)[1])
+			if title == "" {
+				continue
+			}
+			if page.Title == "" && strings.HasPrefix(trimmed, "# ") {
+				page.Title = title
+			}
+			page.Chunks = append(page.Chunks, TextChunk{Content: title, Type: "heading", Anchor: markdownAnchor(title)})
+		case mdListRe.MatchString(trimmed):
+			flushPara()
+			if item := cleanMarkdownInline(mdListRe.FindStringSubmatch(trimmed)[1]); item != "" {
+				page.Chunks = append(page.Chunks, TextChunk{Content: "- " + item, Type: "list"})
+			}
+		default:
+			para = append(para, trimmed)
+		}
+	}
+	flushPara()
+
+	return page
+}
+
+// cleanMarkdownInline strips images, link targets and emphasis markers
+func cleanMarkdownInline(s string) string {
+	s = mdImageRe.ReplaceAllString(s, "")
+	s = mdLinkRe.ReplaceAllString(s, "$1")
+	s = mdEmphRe.ReplaceAllString(s, "")
+	s = strings.TrimPrefix(strings.TrimSpace(s), "> ")
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// resolveMarkdownImage returns an ImageRef for a local image link, or
+// missing = true when the link is to a local image file that doesn't exist
+func resolveMarkdownImage(src, alt, filePath string) (img *ImageRef, missing bool) {
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") || strings.HasPrefix(src, "data:") {
+		return nil, false
+	}
+	ext := strings.ToLower(filepath.Ext(src))
+	if ext != ".png" && ext != ".jpg" && ext != ".jpeg" && ext != ".gif" && ext != ".svg" {
+		return nil, false
+	}
+	// Notion URL-encodes spaces in relative links
+	decoded := strings.ReplaceAll(src, "%20", " ")
+	fullPath := filepath.Join(filepath.Dir(filePath), decoded)
+	if _, err := os.Stat(fullPath); err != nil {
+		return nil, true
+	}
+	return &ImageRef{Src: src, Alt: alt, FullPath: fullPath}, false
+}
+
+// markdownAnchor returns the GitHub-style fragment for a heading: lowercase,
+// punctuation dropped, spaces turned into hyphens
+func markdownAnchor(heading string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == ' ':
+			return '-'
+		case r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			return unicode.ToLower(r)
+		default:
+			return -1
+		}
+	}, heading)
+}
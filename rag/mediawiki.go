@@ -0,0 +1,190 @@
+package rag
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// MediaWikiLoader parses MediaWiki XML dumps (Special:Export or dumpBackup.php).
+// Only the latest revision of each page is indexed; files/images are not part
+// of XML dumps and are skipped.
+type MediaWikiLoader struct {
+	dumpPath string
+	report   LoadReport // problems found by the last LoadAll
+}
+
+// Ensure MediaWikiLoader implements Loader
+var _ Loader = (*MediaWikiLoader)(nil)
+
+// NewMediaWikiLoader creates a loader for a MediaWiki XML dump file
+func NewMediaWikiLoader(dumpPath string) *MediaWikiLoader {
+	return &MediaWikiLoader{dumpPath: dumpPath}
+}
+
+// mediaWikiPage mirrors a <page> element in the dump
+type mediaWikiPage struct {
+	Title     string    `xml:"title"`
+	NS        int       `xml:"ns"`
+	Redirect  *struct{} `xml:"redirect"`
+	Revisions []struct {
+		Text string `xml:"text"`
+	} `xml:"revision"`
+}
+
+// LoadAll streams the dump and returns one PageContent per article
+func (l *MediaWikiLoader) LoadAll() ([]PageContent, error) {
+	f, err := os.Open(l.dumpPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dump: %w", err)
+	}
+	defer f.Close()
+
+	var pages []PageContent
+	l.report = LoadReport{}
+	dec := xml.NewDecoder(f)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse dump: %w", err)
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "page" {
+			continue
+		}
+
+		var p mediaWikiPage
+		if err := dec.DecodeElement(&p, &start); err != nil {
+			return nil, fmt.Errorf("failed to decode page: %w", err)
+		}
+		// Only main-namespace articles; skip talk/user/template pages and redirects
+		if p.NS != 0 || p.Redirect != nil || len(p.Revisions) == 0 {
+			continue
+		}
+
+		page := parseWikitext(p.Revisions[len(p.Revisions)-1].Text)
+		page.Title = p.Title
+		page.FilePath = l.dumpPath + "#" + strings.ReplaceAll(p.Title, " ", "_")
+		l.report.addPage(page)
+		if len(page.Chunks) > 0 {
+			pages = append(pages, *page)
+		}
+	}
+
+	return pages, nil
+}
+
+var (
+	wikiHeadingRe  = regexp.MustCompile(`^(={2,6})\s*(.*?)\s*={2,6}\s*$`)
+	wikiTemplateRe = regexp.MustCompile(`\{\{[^{}]*\}\}`)
+	wikiFileRe     = regexp.MustCompile(`\[\[(?i:file|image):[^\]]*\]\]`)
+	wikiLinkRe     = regexp.MustCompile(`\[\[(?:[^|\]]*\|)?([^\]]*)\]\]`)
+	wikiExtLinkRe  = regexp.MustCompile(`\[https?://\S+\s+([^\]]*)\]`)
+	wikiBareLinkRe = regexp.MustCompile(`\[(https?://[^\]\s]+)\]`)
+	wikiEmphasisRe = regexp.MustCompile(`'{2,5}`)
+	wikiRefRe      = regexp.MustCompile(`(?s)<ref[^>]*/>|<ref[^>]*>.*?</ref>`)
+	wikiTagRe      = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+)
+
+// parseWikitext converts MediaWiki markup into typed text chunks
+func parseWikitext(text string) *PageContent {
+	page := &PageContent{}
+
+	// Templates can nest; strip innermost first until none remain
+	for wikiTemplateRe.MatchString(text) {
+		text = wikiTemplateRe.ReplaceAllString(text, "")
+	}
+	text = wikiRefRe.ReplaceAllString(text, "")
+
+	var para []string
+	var code []string
+	inPre := false
+
+	flushPara := func() {
+		if t := cleanWikiInline(strings.Join(para, " ")); t != "" {
+			page.Chunks = append(page.Chunks, TextChunk{Content: t, Type: "paragraph"})
+		}
+		para = nil
+	}
+	flushCode := func() {
+		if t := strings.TrimSpace(strings.Join(code, "\n")); t != "" {
+			page.Chunks = append(page.Chunks, TextChunk{Content: t, Type: "code"})
+		}
+		code = nil
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if inPre {
+			if strings.Contains(trimmed, "</pre>") || strings.Contains(trimmed, "</syntaxhighlight>") {
+				inPre = false
+				flushCode()
+				continue
+			}
+			code = append(code, line)
+			continue
+		}
+		if strings.HasPrefix(trimmed, "<pre") || strings.HasPrefix(trimmed, "<syntaxhighlight") {
+			flushPara()
+			flushCode()
+			inPre = true
+			continue
+		}
+
+		switch {
+		case trimmed == "":
+			flushPara()
+			flushCode()
+		case wikiHeadingRe.MatchString(trimmed):
+			flushPara()
+			flushCode()
+			m := wikiHeadingRe.FindStringSubmatch(trimmed)
+			if t := cleanWikiInline(m[2]); t != "" {
+				page.Chunks = append(page.Chunks, TextChunk{Content: t, Type: "heading"})
+			}
+		case strings.HasPrefix(trimmed, "*") || strings.HasPrefix(trimmed, "#"):
+			flushPara()
+			flushCode()
+			item := cleanWikiInline(strings.TrimLeft(trimmed, "*#:; "))
+			if item != "" {
+				page.Chunks = append(page.Chunks, TextChunk{Content: "- " + item, Type: "list"})
+			}
+		case strings.HasPrefix(line, " "):
+			// Leading-space lines are preformatted in wikitext
+			flushPara()
+			code = append(code, strings.TrimPrefix(line, " "))
+		case strings.HasPrefix(trimmed, "{|") || strings.HasPrefix(trimmed, "|") || strings.HasPrefix(trimmed, "!"):
+			// Table markup: keep cell text as paragraph content
+			cell := strings.TrimLeft(trimmed, "{|!-}+ ")
+			if cell != "" {
+				para = append(para, strings.ReplaceAll(cell, "||", " | "))
+			}
+		default:
+			flushCode()
+			para = append(para, trimmed)
+		}
+	}
+	flushPara()
+	flushCode()
+
+	return page
+}
+
+// cleanWikiInline strips inline link, emphasis and tag markup
+func cleanWikiInline(s string) string {
+	s = wikiFileRe.ReplaceAllString(s, "")
+	s = wikiLinkRe.ReplaceAllString(s, "$1")
+	s = wikiExtLinkRe.ReplaceAllString(s, "$1")
+	s = wikiBareLinkRe.ReplaceAllString(s, "$1")
+	s = wikiEmphasisRe.ReplaceAllString(s, "")
+	s = wikiTagRe.ReplaceAllString(s, "")
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,82 @@
+package rag
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMediaWikiLoader(t *testing.T) {
+	dump := `<mediawiki xmlns="http://www.mediawiki.org/xml/export-0.10/">
+  <page>
+    <title>Deployment Guide</title>
+    <ns>0</ns>
+    <revision><text xml:space="preserve">{{Infobox|owner={{User|ops}}}}
+Intro with a [[Kubernetes|k8s]] link and '''bold''' text.
+
+== Rollout ==
+* Run [https://ci.example.com the pipeline]
+# Check pods
+ kubectl get pods -n prod
+&lt;pre&gt;
+helm upgrade api ./chart
+&lt;/pre&gt;
+[[File:diagram.png|thumb|Diagram]]</text></revision>
+  </page>
+  <page>
+    <title>Talk:Deployment Guide</title>
+    <ns>1</ns>
+    <revision><text>discussion</text></revision>
+  </page>
+  <page>
+    <title>Old Name</title>
+    <ns>0</ns>
+    <redirect title="Deployment Guide"/>
+    <revision><text>#REDIRECT [[Deployment Guide]]</text></revision>
+  </page>
+</mediawiki>`
+
+	path := filepath.Join(t.TempDir(), "dump.xml")
+	if err := os.WriteFile(path, []byte(dump), 0644); err != nil {
+		t.Fatalf("Failed to write dump: %v", err)
+	}
+
+	pages, err := NewMediaWikiLoader(path).LoadAll()
+	if err != nil {
+		t.Fatalf("LoadAll() error = %v", err)
+	}
+	if len(pages) != 1 {
+		t.Fatalf("LoadAll() = %d pages, want 1 (talk page and redirect skipped)", len(pages))
+	}
+
+	page := pages[0]
+	if page.Title != "Deployment Guide" {
+		t.Errorf("Title = %q, want Deployment Guide", page.Title)
+	}
+	if !strings.HasSuffix(page.FilePath, "#Deployment_Guide") {
+		t.Errorf("FilePath = %q, want page anchor", page.FilePath)
+	}
+
+	byType := map[string][]string{}
+	for _, c := range page.Chunks {
+		byType[c.Type] = append(byType[c.Type], c.Content)
+	}
+	if got := byType["paragraph"]; len(got) == 0 || got[0] != "Intro with a k8s link and bold text." {
+		t.Errorf("paragraph = %q, want cleaned intro", got)
+	}
+	if got := byType["heading"]; len(got) != 1 || got[0] != "Rollout" {
+		t.Errorf("headings = %q, want [Rollout]", got)
+	}
+	if got := byType["list"]; len(got) != 2 || got[0] != "- Run the pipeline" {
+		t.Errorf("list = %q, want 2 cleaned items", got)
+	}
+	if got := byType["code"]; len(got) != 2 || got[1] != "helm upgrade api ./chart" {
+		t.Errorf("code = %q, want indented and <pre> blocks", got)
+	}
+	for _, c := range page.Chunks {
+		if strings.Contains(c.Content, "Infobox") || strings.Contains(c.Content, "File:") {
+			t.Errorf("markup leaked into chunk: %q", c.Content)
+		}
+	}
+}
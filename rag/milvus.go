@@ -0,0 +1,319 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MilvusStore stores documents in a Milvus collection via the v2 RESTful API
+// (/v2/vectordb/...). Collections use the quick-setup schema: a VarChar "id"
+// primary key, a "vector" field and dynamic fields for the payload.
+//
+// Milvus has no BM25 over dynamic fields, so SearchQuery.Text is ignored and
+// searches are vector-only.
+type MilvusStore struct {
+	baseURL        string
+	token          string // "user:password" or API key; empty for no auth
+	collectionName string
+	client         *http.Client
+}
+
+// Ensure MilvusStore implements Store
+var _ Store = (*MilvusStore)(nil)
+
+// NewMilvusStore creates a new Milvus store client
+func NewMilvusStore(baseURL, token, collectionName string) *MilvusStore {
+	return &MilvusStore{
+		baseURL:        strings.TrimSuffix(baseURL, "/"),
+		token:          token,
+		collectionName: collectionName,
+		client:         &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// EnsureCollection creates the collection if it doesn't exist
+func (s *MilvusStore) EnsureCollection(ctx context.Context, vectorSize int) error {
+	var has struct {
+		Has bool `json:"has"`
+	}
+	if err := s.post(ctx, "/collections/has", map[string]any{"collectionName": s.collectionName}, &has); err != nil {
+		return fmt.Errorf("failed to check collection: %w", err)
+	}
+	if has.Has {
+		return nil
+	}
+
+	createReq := map[string]any{
+		"collectionName":   s.collectionName,
+		"dimension":        vectorSize,
+		"metricType":       "COSINE",
+		"idType":           "VarChar",
+		"primaryFieldName": "id",
+		"vectorFieldName":  "vector",
+		"params":           map[string]any{"max_length": 64},
+	}
+	if err := s.post(ctx, "/collections/create", createReq, nil); err != nil {
+		return fmt.Errorf("failed to create collection: %w", err)
+	}
+	return nil
+}
+
+// DeleteCollection drops the collection (for re-indexing)
+func (s *MilvusStore) DeleteCollection(ctx context.Context) error {
+	// Dropping a missing collection succeeds in Milvus
+	if err := s.post(ctx, "/collections/drop", map[string]any{"collectionName": s.collectionName}, nil); err != nil {
+		return fmt.Errorf("failed to delete collection: %w", err)
+	}
+	return nil
+}
+
+// Upsert adds or updates documents in the store
+func (s *MilvusStore) Upsert(ctx context.Context, docs []Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	rows := make([]map[string]any, len(docs))
+	for i, doc := range docs {
+		row := map[string]any{
+			"id":          doc.ID,
+			"vector":      doc.Vector,
+			"content":     doc.Content,
+			"source_type": doc.SourceType,
+		}
+		for k, v := range doc.Metadata {
+			row[k] = v
+		}
+		if doc.ImagePath != "" {
+			row["image_path"] = doc.ImagePath
+		}
+		rows[i] = row
+	}
+
+	upsertReq := map[string]any{
+		"collectionName": s.collectionName,
+		"data":           rows,
+	}
+	if err := s.post(ctx, "/entities/upsert", upsertReq, nil); err != nil {
+		return fmt.Errorf("failed to upsert entities: %w", err)
+	}
+	return nil
+}
+
+// Search finds the documents most similar to q.Vector
+func (s *MilvusStore) Search(ctx context.Context, q SearchQuery) ([]Document, error) {
+	searchReq := map[string]any{
+		"collectionName": s.collectionName,
+		"data":           [][]float32{q.Vector},
+		"annsField":      "vector",
+		"limit":          q.Limit,
+		"outputFields":   []string{"*"},
+	}
+	if q.WithVectors {
+		searchReq["outputFields"] = []string{"*", "vector"}
+	}
+	if expr := q.Filter.milvusExpr(); expr != "" {
+		searchReq["filter"] = expr
+	}
+
+	var hits []map[string]any
+	if err := s.post(ctx, "/entities/search", searchReq, &hits); err != nil {
+		return nil, fmt.Errorf("failed to search: %w", err)
+	}
+
+	docs := make([]Document, len(hits))
+	for i, h := range hits {
+		docs[i] = milvusDocument(h)
+	}
+	return docs, nil
+}
+
+// milvusDocument converts a search hit or query row into a Document
+func milvusDocument(row map[string]any) Document {
+	p := qdrantPoint{ID: row["id"], Payload: map[string]any{}}
+	if d, ok := row["distance"].(float64); ok {
+		p.Score = float32(d) // COSINE metric returns similarity
+	}
+	if vec, ok := row["vector"].([]any); ok {
+		p.Vector = jsonFloats(vec)
+	}
+	for k, v := range row {
+		if k != "id" && k != "distance" && k != "vector" {
+			p.Payload[k] = v
+		}
+	}
+	return p.toDocument()
+}
+
+// Scroll pages through stored entities in primary-key order. The cursor is
+// the last ID of the previous page.
+func (s *MilvusStore) Scroll(ctx context.Context, q ScrollQuery) (ScrollResult, error) {
+	if q.Limit <= 0 {
+		q.Limit = defaultScrollLimit
+	}
+	var clauses []string
+	if q.Offset != "" {
+		clauses = append(clauses, fmt.Sprintf("id > %s", strconv.Quote(q.Offset)))
+	}
+	if expr := q.Filter.milvusExpr(); expr != "" {
+		clauses = append(clauses, expr)
+	}
+	queryReq := map[string]any{
+		"collectionName": s.collectionName,
+		"filter":         strings.Join(clauses, " and "),
+		"limit":          q.Limit,
+		"outputFields":   []string{"*"},
+	}
+
+	var rows []map[string]any
+	if err := s.post(ctx, "/entities/query", queryReq, &rows); err != nil {
+		return ScrollResult{}, fmt.Errorf("failed to scroll: %w", err)
+	}
+
+	result := ScrollResult{Docs: make([]Document, len(rows))}
+	for i, row := range rows {
+		result.Docs[i] = milvusDocument(row)
+	}
+	sort.Slice(result.Docs, func(a, b int) bool { return result.Docs[a].ID < result.Docs[b].ID })
+	if len(result.Docs) == q.Limit {
+		result.NextOffset = result.Docs[len(result.Docs)-1].ID
+	}
+	return result, nil
+}
+
+// DeleteByIDs removes the given entities
+func (s *MilvusStore) DeleteByIDs(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	quoted := make([]string, len(ids))
+	for i, id := range ids {
+		quoted[i] = strconv.Quote(id)
+	}
+	return s.deleteWhere(ctx, fmt.Sprintf("id in [%s]", strings.Join(quoted, ", ")))
+}
+
+// DeleteByFilter removes every entity matching the filter
+func (s *MilvusStore) DeleteByFilter(ctx context.Context, f *Filter) error {
+	if f.isEmpty() {
+		return errEmptyDeleteFilter
+	}
+	return s.deleteWhere(ctx, f.milvusExpr())
+}
+
+// deleteWhere deletes entities matching a boolean expression
+func (s *MilvusStore) deleteWhere(ctx context.Context, expr string) error {
+	deleteReq := map[string]any{
+		"collectionName": s.collectionName,
+		"filter":         expr,
+	}
+	if err := s.post(ctx, "/entities/delete", deleteReq, nil); err != nil {
+		return fmt.Errorf("failed to delete entities: %w", err)
+	}
+	return nil
+}
+
+// Count returns the number of documents in the collection
+func (s *MilvusStore) Count(ctx context.Context) (int, error) {
+	queryReq := map[string]any{
+		"collectionName": s.collectionName,
+		"filter":         "",
+		"outputFields":   []string{"count(*)"},
+	}
+	var rows []map[string]any
+	if err := s.post(ctx, "/entities/query", queryReq, &rows); err != nil {
+		return 0, fmt.Errorf("failed to count entities: %w", err)
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	count, _ := rows[0]["count(*)"].(float64)
+	return int(count), nil
+}
+
+// post sends a request to a /v2/vectordb endpoint and decodes the "data"
+// field of the response into out (if non-nil). Milvus reports failures with
+// HTTP 200 and a non-zero "code".
+func (s *MilvusStore) post(ctx context.Context, path string, reqBody, out any) error {
+	body, _ := json.Marshal(reqBody)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.baseURL+"/v2/vectordb"+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("milvus returned %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var result struct {
+		Code    int             `json:"code"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if result.Code != 0 {
+		return fmt.Errorf("milvus error %d: %s", result.Code, result.Message)
+	}
+	if out != nil && len(result.Data) > 0 {
+		if err := json.Unmarshal(result.Data, out); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return nil
+}
+
+// milvusExpr converts the filter to a Milvus boolean expression over the
+// dynamic payload fields. Returns "" for an empty filter.
+func (f *Filter) milvusExpr() string {
+	if f == nil {
+		return ""
+	}
+	var clauses []string
+	if f.PageTitle != "" {
+		clauses = append(clauses, fmt.Sprintf("page_title like %s", strconv.Quote("%"+f.PageTitle+"%")))
+	}
+	if f.Label != "" {
+		clauses = append(clauses, fmt.Sprintf("labels like %s", strconv.Quote("%"+f.labelMatch()+"%")))
+	}
+	for _, kv := range [][2]string{
+		{"chunk_type", f.ChunkType},
+		{"source_type", f.SourceType},
+		{"space", f.Space},
+		{"source", f.Source},
+		{"file_path", f.FilePath},
+		{"language", f.Language},
+	} {
+		if kv[1] != "" {
+			clauses = append(clauses, fmt.Sprintf("%s == %s", kv[0], strconv.Quote(kv[1])))
+		}
+	}
+	if !f.ModifiedAfter.IsZero() {
+		clauses = append(clauses, fmt.Sprintf("last_modified >= %q", f.ModifiedAfter.UTC().Format(time.RFC3339)))
+	}
+	if !f.ModifiedBefore.IsZero() {
+		clauses = append(clauses, fmt.Sprintf("last_modified < %q", f.ModifiedBefore.UTC().Format(time.RFC3339)))
+	}
+	return strings.Join(clauses, " and ")
+}
@@ -0,0 +1,83 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMilvusStore(t *testing.T) {
+	var paths []string
+	var bodies []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		if r.Header.Get("Authorization") != "Bearer root:Milvus" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+
+		switch r.URL.Path {
+		case "/v2/vectordb/collections/has":
+			w.Write([]byte(`{"code":0,"data":{"has":false}}`))
+		case "/v2/vectordb/entities/search":
+			w.Write([]byte(`{"code":0,"data":[{"id":"a1","distance":0.92,"content":"Topology diagram","source_type":"image","image_path":"/w/net.png","page_title":"Network"}]}`))
+		case "/v2/vectordb/entities/query":
+			w.Write([]byte(`{"code":0,"data":[{"count(*)":42}]}`))
+		case "/v2/vectordb/entities/upsert":
+			w.Write([]byte(`{"code":1100,"message":"dimension mismatch"}`))
+		default:
+			w.Write([]byte(`{"code":0,"data":{}}`))
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	store := NewMilvusStore(srv.URL+"/", "root:Milvus", "wiki")
+
+	if err := store.EnsureCollection(ctx, 768); err != nil {
+		t.Fatalf("EnsureCollection() error = %v", err)
+	}
+	if paths[1] != "/v2/vectordb/collections/create" || bodies[1]["dimension"] != float64(768) {
+		t.Errorf("create request = %s %v", paths[1], bodies[1])
+	}
+
+	docs, err := store.Search(ctx, SearchQuery{Vector: []float32{1}, Text: "ignored", Limit: 3, Filter: &Filter{SourceType: "image", PageTitle: "Net"}})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if want := `page_title like "%Net%" and source_type == "image"`; bodies[2]["filter"] != want {
+		t.Errorf("filter = %v, want %s", bodies[2]["filter"], want)
+	}
+	if len(docs) != 1 || docs[0].ID != "a1" || docs[0].ImagePath != "/w/net.png" || docs[0].Metadata["page_title"] != "Network" {
+		t.Errorf("Search() = %+v", docs)
+	}
+
+	if n, err := store.Count(ctx); err != nil || n != 42 {
+		t.Errorf("Count() = %d, %v; want 42", n, err)
+	}
+
+	err = store.Upsert(ctx, []Document{{ID: "x", Vector: []float32{1}}})
+	if err == nil || !strings.Contains(err.Error(), "dimension mismatch") {
+		t.Errorf("Upsert() error = %v, want milvus error message", err)
+	}
+}
+
+func TestFilterMilvusExpr(t *testing.T) {
+	f := &Filter{Space: "OPS", ModifiedBefore: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)}
+	want := `space == "OPS" and last_modified < "2024-06-01T00:00:00Z"`
+	if got := f.milvusExpr(); got != want {
+		t.Errorf("milvusExpr() = %s, want %s", got, want)
+	}
+	if got := (&Filter{Label: "runbook"}).milvusExpr(); got != `labels like "%,runbook,%"` {
+		t.Errorf("label milvusExpr() = %s", got)
+	}
+	if got := (*Filter)(nil).milvusExpr(); got != "" {
+		t.Errorf("nil milvusExpr() = %q, want empty", got)
+	}
+}
@@ -0,0 +1,57 @@
+package rag
+
+// MMR re-ranks candidates with maximal marginal relevance and returns up to k
+// of them. Candidates must be ordered best first with their Vector set; their
+// scores are divided by the top score to form the relevance term, so any
+// backend's score (cosine, RRF, hybrid) works. Each pick maximises
+//
+//	lambda*relevance(d) - (1-lambda)*max cosine(d, already picked)
+//
+// lambda 1 keeps the original ranking; lower values trade relevance for
+// diversity, pushing near-duplicate chunks down.
+func MMR(candidates []Document, lambda float64, k int) []Document {
+	if k > len(candidates) {
+		k = len(candidates)
+	}
+	if k <= 0 {
+		return nil
+	}
+
+	var top float32
+	for _, d := range candidates {
+		top = max(top, d.Score)
+	}
+	relevance := make([]float64, len(candidates))
+	for i, d := range candidates {
+		if top > 0 {
+			relevance[i] = float64(d.Score / top)
+		} else {
+			relevance[i] = 1
+		}
+	}
+
+	// maxSim[i] is candidate i's highest similarity to any picked document
+	maxSim := make([]float64, len(candidates))
+	picked := make([]bool, len(candidates))
+	selected := make([]Document, 0, k)
+	for len(selected) < k {
+		best, bestScore := -1, 0.0
+		for i := range candidates {
+			if picked[i] {
+				continue
+			}
+			score := lambda*relevance[i] - (1-lambda)*maxSim[i]
+			if best < 0 || score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		picked[best] = true
+		selected = append(selected, candidates[best])
+		for i := range candidates {
+			if !picked[i] {
+				maxSim[i] = max(maxSim[i], float64(cosine(candidates[i].Vector, candidates[best].Vector)))
+			}
+		}
+	}
+	return selected
+}
@@ -0,0 +1,38 @@
+package rag
+
+import "testing"
+
+func TestMMR(t *testing.T) {
+	// Three near-duplicates of one paragraph outrank a distinct chunk
+	candidates := []Document{
+		{ID: "dup1", Score: 0.95, Vector: []float32{1, 0, 0}},
+		{ID: "dup2", Score: 0.94, Vector: []float32{0.99, 0.05, 0}},
+		{ID: "dup3", Score: 0.93, Vector: []float32{0.98, 0.1, 0}},
+		{ID: "other", Score: 0.80, Vector: []float32{0, 1, 0}},
+	}
+
+	got := MMR(candidates, 1, 2)
+	if len(got) != 2 || got[0].ID != "dup1" || got[1].ID != "dup2" {
+		t.Errorf("MMR(lambda=1) = %v, want original order", ids(got))
+	}
+
+	got = MMR(candidates, 0.5, 2)
+	if len(got) != 2 || got[0].ID != "dup1" || got[1].ID != "other" {
+		t.Errorf("MMR(lambda=0.5) = %v, want [dup1 other]", ids(got))
+	}
+
+	if got := MMR(candidates, 0.5, 10); len(got) != 4 {
+		t.Errorf("MMR(k > candidates) returned %d docs, want 4", len(got))
+	}
+	if got := MMR(nil, 0.5, 3); got != nil {
+		t.Errorf("MMR(nil) = %v, want nil", got)
+	}
+}
+
+func ids(docs []Document) []string {
+	out := make([]string, len(docs))
+	for i, d := range docs {
+		out[i] = d.ID
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+package rag
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// notionIDSuffixRe matches the 32-hex-digit page ID Notion appends to exported
+// file names, e.g. "Deploy Guide 1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d.md"
+var notionIDSuffixRe = regexp.MustCompile(`\s+[0-9a-f]{32}$`)
+
+// NotionLoader parses a Notion workspace export (Markdown & CSV or HTML).
+// HTML pages are handled by the Confluence parser; Markdown pages by parseMarkdown.
+type NotionLoader struct {
+	basePath string
+	html     *ConfluenceLoader
+	report   LoadReport // problems found by the last LoadAll
+}
+
+// Ensure NotionLoader implements Loader
+var _ Loader = (*NotionLoader)(nil)
+
+// NewNotionLoader creates a loader for an extracted Notion export directory
+func NewNotionLoader(basePath string) *NotionLoader {
+	return &NotionLoader{basePath: basePath, html: NewConfluenceLoader(basePath)}
+}
+
+// LoadAll loads every Markdown and HTML page in the export
+func (l *NotionLoader) LoadAll() ([]PageContent, error) {
+	var pages []PageContent
+	l.report = LoadReport{}
+
+	err := filepath.Walk(l.basePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		var page *PageContent
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".md", ".markdown":
+			data, err := readPageFile(path, l.html.MaxFileSize)
+			if err != nil {
+				l.report.Unreadable = append(l.report.Unreadable, UnreadableFile{Path: path, Error: err.Error()})
+				return nil
+			}
+			page = parseMarkdown(string(data), path)
+		case ".html", ".htm":
+			page, err = l.html.LoadPage(path)
+			if err != nil {
+				l.report.Unreadable = append(l.report.Unreadable, UnreadableFile{Path: path, Error: err.Error()})
+				return nil
+			}
+		default:
+			return nil
+		}
+
+		if page.Title == "" {
+			page.Title = notionTitle(path)
+		}
+		l.report.addPage(page)
+		if len(page.Chunks) > 0 || len(page.Images) > 0 {
+			pages = append(pages, *page)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory: %w", err)
+	}
+
+	return pages, nil
+}
+
+// notionTitle derives a page title from an export file name
+func notionTitle(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return notionIDSuffixRe.ReplaceAllString(name, "")
+}
@@ -0,0 +1,70 @@
+package rag
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNotionLoader(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	pageDir := filepath.Join(tmpDir, "Runbooks 0123456789abcdef0123456789abcdef")
+	if err := os.MkdirAll(pageDir, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(pageDir, "flow.png"), []byte("fake png"), 0644); err != nil {
+		t.Fatalf("Failed to write image: %v", err)
+	}
+
+	md := "# Restart API\n\nUse **systemctl** to restart the [api](https://x) service.\n\n" +
+		"- check health\n1. drain node\n\n```bash\nsystemctl restart api\n```\n\n" +
+		"![Flow Chart](Runbooks%200123456789abcdef0123456789abcdef/flow.png)\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "Restart API fedcba9876543210fedcba9876543210.md"), []byte(md), 0644); err != nil {
+		t.Fatalf("Failed to write md: %v", err)
+	}
+	untitled := "Some notes without a heading.\n"
+	if err := os.WriteFile(filepath.Join(pageDir, "Notes 00112233445566778899aabbccddeeff.md"), []byte(untitled), 0644); err != nil {
+		t.Fatalf("Failed to write md: %v", err)
+	}
+
+	pages, err := NewNotionLoader(tmpDir).LoadAll()
+	if err != nil {
+		t.Fatalf("LoadAll() error = %v", err)
+	}
+	if len(pages) != 2 {
+		t.Fatalf("LoadAll() = %d pages, want 2", len(pages))
+	}
+
+	titles := map[string]PageContent{}
+	for _, p := range pages {
+		titles[p.Title] = p
+	}
+
+	restart, ok := titles["Restart API"]
+	if !ok {
+		t.Fatalf("missing page titled from heading, got %v", titles)
+	}
+	want := []TextChunk{
+		{Content: "Restart API", Type: "heading", Anchor: "restart-api"},
+		{Content: "Use systemctl to restart the api service.", Type: "paragraph"},
+		{Content: "- check health", Type: "list"},
+		{Content: "- drain node", Type: "list"},
+		{Content: "systemctl restart api", Type: "code"},
+	}
+	if len(restart.Chunks) != len(want) {
+		t.Fatalf("Chunks = %+v, want %+v", restart.Chunks, want)
+	}
+	for i := range want {
+		if restart.Chunks[i] != want[i] {
+			t.Errorf("chunk %d = %+v, want %+v", i, restart.Chunks[i], want[i])
+		}
+	}
+	if len(restart.Images) != 1 || restart.Images[0].Alt != "Flow Chart" {
+		t.Errorf("Images = %+v, want resolved flow.png", restart.Images)
+	}
+
+	if _, ok := titles["Notes"]; !ok {
+		t.Errorf("missing page titled from file name (ID suffix stripped), got %v", titles)
+	}
+}
@@ -0,0 +1,49 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// TextExtractor pulls the literal text out of an image (OCR). VisionClient
+// (a text-extraction prompt) and TesseractOCR implement it.
+type TextExtractor interface {
+	ExtractText(ctx context.Context, imagePath string) (string, error)
+}
+
+// Ensure both extractors implement TextExtractor
+var (
+	_ TextExtractor = (*VisionClient)(nil)
+	_ TextExtractor = (*TesseractOCR)(nil)
+)
+
+// TesseractOCR runs the tesseract CLI. It is much faster than a vision model
+// and exact on clean screenshots, but reads nothing from hand-drawn diagrams.
+type TesseractOCR struct {
+	Binary   string // tesseract executable (default: "tesseract" on $PATH)
+	Language string // tesseract -l languages, e.g. "eng+deu" (default: tesseract's own default)
+}
+
+// ExtractText runs tesseract on the image and returns its output
+func (t *TesseractOCR) ExtractText(ctx context.Context, imagePath string) (string, error) {
+	binary := t.Binary
+	if binary == "" {
+		binary = "tesseract"
+	}
+	args := []string{imagePath, "stdout"}
+	if t.Language != "" {
+		args = append(args, "-l", t.Language)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("tesseract failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,36 @@
+package rag
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestTesseractOCR(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as a fake tesseract")
+	}
+	dir := t.TempDir()
+	fake := filepath.Join(dir, "tesseract")
+	script := "#!/bin/sh\necho \"args: $*\"\necho 'max_connections = 500'\n"
+	if err := os.WriteFile(fake, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	ocr := &TesseractOCR{Binary: fake, Language: "eng"}
+	got, err := ocr.ExtractText(context.Background(), "/wiki/pg.png")
+	if err != nil {
+		t.Fatalf("ExtractText() error = %v", err)
+	}
+	if !strings.Contains(got, "args: /wiki/pg.png stdout -l eng") || !strings.HasSuffix(got, "max_connections = 500") {
+		t.Errorf("ExtractText() = %q", got)
+	}
+
+	ocr.Binary = filepath.Join(dir, "missing")
+	if _, err := ocr.ExtractText(context.Background(), "/wiki/pg.png"); err == nil {
+		t.Error("ExtractText() with a missing binary should fail")
+	}
+}
@@ -0,0 +1,100 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OpenAIEmbeddings generates embeddings with the OpenAI /embeddings API. Any
+// OpenAI-compatible server (vLLM, LM Studio, LocalAI, Together, ...) works by
+// pointing baseURL at it.
+type OpenAIEmbeddings struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+// NewOpenAIEmbeddings creates an OpenAI-compatible embedding client. baseURL
+// defaults to https://api.openai.com/v1 and model to text-embedding-3-small.
+func NewOpenAIEmbeddings(baseURL, apiKey, model string) *OpenAIEmbeddings {
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	if model == "" {
+		model = "text-embedding-3-small"
+	}
+	return &OpenAIEmbeddings{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		client:  &http.Client{Timeout: 120 * time.Second},
+	}
+}
+
+// Embed generates an embedding for a single text
+func (c *OpenAIEmbeddings) Embed(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := c.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) == 0 {
+		return nil, fmt.Errorf("no embedding returned")
+	}
+	return vectors[0], nil
+}
+
+// EmbedBatch generates embeddings for multiple texts in one request
+func (c *OpenAIEmbeddings) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	body, _ := json.Marshal(map[string]any{
+		"model": c.model,
+		"input": texts,
+	})
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed texts: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to embed texts: %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("got %d embeddings for %d texts", len(result.Data), len(texts))
+	}
+
+	sort.Slice(result.Data, func(a, b int) bool { return result.Data[a].Index < result.Data[b].Index })
+	vectors := make([][]float32, len(result.Data))
+	for i, d := range result.Data {
+		vectors[i] = d.Embedding
+	}
+	return vectors, nil
+}
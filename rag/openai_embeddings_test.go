@@ -0,0 +1,42 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAIEmbeddings(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer sk-test" {
+			t.Errorf("request = %s auth=%q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "text-embedding-3-small" || len(req.Input) == 0 {
+			t.Errorf("body = %+v", req)
+		}
+		// Out of order on purpose: results must be sorted by index
+		w.Write([]byte(`{"data":[{"index":1,"embedding":[0,1,0]},{"index":0,"embedding":[1,0,0]}]}`))
+	}))
+	defer srv.Close()
+
+	e := NewOpenAIEmbeddings(srv.URL+"/v1/", "sk-test", "")
+	vectors, err := e.EmbedBatch(context.Background(), []string{"first", "second"})
+	if err != nil {
+		t.Fatalf("EmbedBatch() error = %v", err)
+	}
+	if len(vectors) != 2 || vectors[0][0] != 1 || vectors[1][1] != 1 {
+		t.Errorf("EmbedBatch() = %v, want vectors in input order", vectors)
+	}
+
+	// A single-text call against a batch-shaped response is an error
+	if _, err := e.Embed(context.Background(), "only"); err == nil {
+		t.Error("Embed() should fail when the response count doesn't match")
+	}
+}
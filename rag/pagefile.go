@@ -0,0 +1,98 @@
+package rag
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// DefaultMaxFileSize is the largest page file the loaders parse. HTML is
+// parsed into an in-memory tree several times the size of the file, so one
+// runaway export page could otherwise exhaust the indexer's memory.
+const DefaultMaxFileSize = 50 << 20
+
+// sniffLen is how much of a page file is inspected for binary content
+const sniffLen = 8192
+
+// errBinaryFile is returned for page files whose content isn't text
+var errBinaryFile = errors.New("binary file, not a page")
+
+// pageFile is an open page file: reads go through the sniffing buffer and
+// stop at the size limit, Close closes the file
+type pageFile struct {
+	io.Reader
+	io.Closer
+}
+
+// openPageFile opens a page file for parsing. Files larger than maxSize
+// (0 = no limit) and files that look binary (a NUL byte in the first 8 KB,
+// or content sniffed as something other than text, e.g. an image or a zip
+// saved as .html) are refused with an error saying why.
+func openPageFile(path string, maxSize int64) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	if maxSize > 0 && info.Size() > maxSize {
+		f.Close()
+		return nil, fmt.Errorf("file too large (%s, limit %s)", formatSize(info.Size()), formatSize(maxSize))
+	}
+
+	br := bufio.NewReaderSize(f, sniffLen)
+	head, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		f.Close()
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if isBinary(head) {
+		f.Close()
+		return nil, errBinaryFile
+	}
+
+	// The file may have grown since Stat
+	var r io.Reader = br
+	if maxSize > 0 {
+		r = io.LimitReader(br, maxSize)
+	}
+	return pageFile{Reader: r, Closer: f}, nil
+}
+
+// readPageFile reads a whole page file with the checks of openPageFile
+func readPageFile(path string, maxSize int64) ([]byte, error) {
+	f, err := openPageFile(path, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// isBinary reports whether the start of a file looks like binary data
+func isBinary(head []byte) bool {
+	if bytes.IndexByte(head, 0) >= 0 {
+		return true
+	}
+	return !strings.HasPrefix(http.DetectContentType(head), "text/")
+}
+
+// formatSize formats a byte count for people
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d bytes", n)
+	}
+}
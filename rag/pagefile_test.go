@@ -0,0 +1,83 @@
+package rag
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfluenceLoader_SkipsHugeAndBinaryFiles(t *testing.T) {
+	dir := t.TempDir()
+	png := append([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), bytes.Repeat([]byte{0}, 64)...)
+	files := map[string][]byte{
+		"Deploy_1.html":     []byte("<html><head><title>Deploy</title></head><body><p>Run the pipeline.</p></body></html>"),
+		"Huge_2.html":       []byte("<html><body><p>" + strings.Repeat("x", 4096) + "</p></body></html>"),
+		"Screenshot_3.html": png,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l := NewConfluenceLoader(dir)
+	l.MaxFileSize = 1024
+	pages, err := l.LoadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 1 || pages[0].Title != "Deploy" {
+		t.Fatalf("LoadAll() = %d pages, want only Deploy", len(pages))
+	}
+
+	reasons := map[string]string{}
+	for _, u := range l.LoadReport().Unreadable {
+		reasons[filepath.Base(u.Path)] = u.Error
+	}
+	if !strings.Contains(reasons["Huge_2.html"], "file too large") {
+		t.Errorf("Huge_2.html reason = %q, want file too large", reasons["Huge_2.html"])
+	}
+	if !strings.Contains(reasons["Screenshot_3.html"], "binary file") {
+		t.Errorf("Screenshot_3.html reason = %q, want binary file", reasons["Screenshot_3.html"])
+	}
+
+	// No limit: the big page loads, the binary one is still refused
+	l.MaxFileSize = 0
+	if pages, _ := l.LoadAll(); len(pages) != 2 {
+		t.Errorf("LoadAll() without limit = %d pages, want 2", len(pages))
+	}
+}
+
+func TestIsBinary(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want bool
+	}{
+		{"html", "<!DOCTYPE html><html><body>Hi</body></html>", false},
+		{"bom html", "\xef\xbb\xbf<html><p>Grüße</p></html>", false},
+		{"markdown", "# Restart API\n\nRun `systemctl restart api`.\n", false},
+		{"nul byte", "<html>\x00</html>", true},
+		{"zip", "PK\x03\x04\x14\x00\x00\x00", true},
+		{"gif", "GIF89a\x01\x00\x01\x00", true},
+	}
+	for _, tt := range tests {
+		if got := isBinary([]byte(tt.head)); got != tt.want {
+			t.Errorf("isBinary(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReadPageFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "page.md")
+	os.WriteFile(path, []byte("# Title\n\nBody text.\n"), 0644)
+	data, err := readPageFile(path, 1024)
+	if err != nil || string(data) != "# Title\n\nBody text.\n" {
+		t.Errorf("readPageFile() = %q, %v", data, err)
+	}
+	if _, err := readPageFile(path, 8); err == nil || !strings.Contains(err.Error(), "limit 8 bytes") {
+		t.Errorf("readPageFile() over limit err = %v", err)
+	}
+}
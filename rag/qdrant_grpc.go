@@ -0,0 +1,400 @@
+package rag
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"math"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// qdrantGRPC is a minimal client for Qdrant's gRPC Points service, used for
+// the bulk-data calls (upsert, vector search) where JSON encoding of
+// thousands of float vectors dominates indexing time. Messages are encoded
+// by hand with protowire against the field numbers in Qdrant's points.proto,
+// which avoids pulling in the generated client.
+type qdrantGRPC struct {
+	conn   *grpc.ClientConn
+	apiKey string
+}
+
+// Qdrant gRPC method names
+const (
+	qdrantUpsertMethod = "/qdrant.Points/Upsert"
+	qdrantSearchMethod = "/qdrant.Points/Search"
+)
+
+// dialQdrantGRPC connects to a Qdrant gRPC endpoint (e.g. localhost:6334),
+// over TLS when useTLS is set (tlsConfig nil means Go's defaults)
+func dialQdrantGRPC(addr, apiKey string, tlsConfig *tls.Config, useTLS bool) (*qdrantGRPC, error) {
+	creds := insecure.NewCredentials()
+	if useTLS {
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
+	conn, err := grpc.NewClient(addr,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(rawCodec{}), grpc.MaxCallSendMsgSize(64<<20)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Qdrant gRPC: %w", err)
+	}
+	return &qdrantGRPC{conn: conn, apiKey: apiKey}, nil
+}
+
+// Close closes the gRPC connection
+func (g *qdrantGRPC) Close() error {
+	return g.conn.Close()
+}
+
+// invoke calls a unary method with a pre-encoded request
+func (g *qdrantGRPC) invoke(ctx context.Context, method string, req []byte) ([]byte, error) {
+	if g.apiKey != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "api-key", g.apiKey)
+	}
+	var resp []byte
+	if err := g.conn.Invoke(ctx, method, &req, &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// upsert writes one batch of points (UpsertPoints, wait=true)
+func (g *qdrantGRPC) upsert(ctx context.Context, collection string, docs []Document) error {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, collection)
+	b = protowire.AppendTag(b, 2, protowire.VarintType)
+	b = protowire.AppendVarint(b, 1)
+	for _, doc := range docs {
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendBytes(b, encodePoint(doc))
+	}
+	_, err := g.invoke(ctx, qdrantUpsertMethod, b)
+	return err
+}
+
+// search runs a SearchPoints request with payloads (and vectors if requested)
+func (g *qdrantGRPC) search(ctx context.Context, collection string, vector []float32, limit int, filter *Filter, withVectors bool) ([]Document, error) {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, collection)
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	b = protowire.AppendBytes(b, packedFloats(vector))
+	if f := encodeFilter(filter); f != nil {
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendBytes(b, f)
+	}
+	b = protowire.AppendTag(b, 4, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(limit))
+	// with_payload { enable: true }
+	b = protowire.AppendTag(b, 6, protowire.BytesType)
+	b = protowire.AppendBytes(b, protowire.AppendVarint(protowire.AppendTag(nil, 1, protowire.VarintType), 1))
+	if withVectors {
+		// with_vectors { enable: true }
+		b = protowire.AppendTag(b, 11, protowire.BytesType)
+		b = protowire.AppendBytes(b, protowire.AppendVarint(protowire.AppendTag(nil, 1, protowire.VarintType), 1))
+	}
+
+	resp, err := g.invoke(ctx, qdrantSearchMethod, b)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []Document
+	err = walkFields(resp, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		if num != 1 || typ != protowire.BytesType {
+			return nil
+		}
+		p, err := decodeScoredPoint(v)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, p.toDocument())
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode search response: %w", err)
+	}
+	return docs, nil
+}
+
+// encodePoint encodes a PointStruct: id=1, payload=3 (map<string, Value>), vectors=4
+func encodePoint(doc Document) []byte {
+	var b []byte
+
+	// PointId { uuid = 2 }
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendBytes(b, protowire.AppendString(protowire.AppendTag(nil, 2, protowire.BytesType), doc.ID))
+
+	payload := map[string]string{
+		"content":     doc.Content,
+		"source_type": doc.SourceType,
+	}
+	for k, v := range doc.Metadata {
+		payload[k] = v
+	}
+	if doc.ImagePath != "" {
+		payload["image_path"] = doc.ImagePath
+	}
+	for k, v := range payload {
+		// Value { string_value = 4 }
+		value := protowire.AppendString(protowire.AppendTag(nil, 4, protowire.BytesType), v)
+		var entry []byte
+		entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+		entry = protowire.AppendString(entry, k)
+		entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+		entry = protowire.AppendBytes(entry, value)
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendBytes(b, entry)
+	}
+
+	// Vectors { vector = 1 { data = 1 } }
+	vec := protowire.AppendBytes(protowire.AppendTag(nil, 1, protowire.BytesType), packedFloats(doc.Vector))
+	b = protowire.AppendTag(b, 4, protowire.BytesType)
+	b = protowire.AppendBytes(b, protowire.AppendBytes(protowire.AppendTag(nil, 1, protowire.BytesType), vec))
+	return b
+}
+
+// packedFloats encodes a packed repeated float field body
+func packedFloats(v []float32) []byte {
+	b := make([]byte, 0, 4*len(v))
+	for _, f := range v {
+		b = protowire.AppendFixed32(b, math.Float32bits(f))
+	}
+	return b
+}
+
+// encodeFilter encodes a Filter message with "must" conditions (field 2),
+// mirroring Filter.conditions(). Returns nil for an empty filter.
+func encodeFilter(f *Filter) []byte {
+	if f == nil {
+		return nil
+	}
+
+	var conds [][]byte
+	// FieldCondition { key = 1, match = 2 { keyword = 1 | text = 4 } }
+	match := func(key string, matchField protowire.Number, value string) []byte {
+		m := protowire.AppendString(protowire.AppendTag(nil, matchField, protowire.BytesType), value)
+		var fc []byte
+		fc = protowire.AppendTag(fc, 1, protowire.BytesType)
+		fc = protowire.AppendString(fc, key)
+		fc = protowire.AppendTag(fc, 2, protowire.BytesType)
+		fc = protowire.AppendBytes(fc, m)
+		return fc
+	}
+	if f.PageTitle != "" {
+		conds = append(conds, match("page_title", 4, f.PageTitle))
+	}
+	if f.Label != "" {
+		conds = append(conds, match("labels", 4, f.labelMatch()))
+	}
+	for _, kv := range [][2]string{
+		{"chunk_type", f.ChunkType},
+		{"source_type", f.SourceType},
+		{"space", f.Space},
+		{"source", f.Source},
+		{"file_path", f.FilePath},
+		{"language", f.Language},
+	} {
+		if kv[1] != "" {
+			conds = append(conds, match(kv[0], 1, kv[1]))
+		}
+	}
+	if !f.ModifiedAfter.IsZero() || !f.ModifiedBefore.IsZero() {
+		// FieldCondition { key = 1, datetime_range = 8 { lt = 1, gte = 3 } }
+		var r []byte
+		if !f.ModifiedBefore.IsZero() {
+			r = protowire.AppendTag(r, 1, protowire.BytesType)
+			r = protowire.AppendBytes(r, timestamp(f.ModifiedBefore))
+		}
+		if !f.ModifiedAfter.IsZero() {
+			r = protowire.AppendTag(r, 3, protowire.BytesType)
+			r = protowire.AppendBytes(r, timestamp(f.ModifiedAfter))
+		}
+		var fc []byte
+		fc = protowire.AppendTag(fc, 1, protowire.BytesType)
+		fc = protowire.AppendString(fc, "last_modified")
+		fc = protowire.AppendTag(fc, 8, protowire.BytesType)
+		fc = protowire.AppendBytes(fc, r)
+		conds = append(conds, fc)
+	}
+	if len(conds) == 0 {
+		return nil
+	}
+
+	var b []byte
+	for _, fc := range conds {
+		// Condition { field = 1 }
+		cond := protowire.AppendBytes(protowire.AppendTag(nil, 1, protowire.BytesType), fc)
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendBytes(b, cond)
+	}
+	return b
+}
+
+// timestamp encodes a google.protobuf.Timestamp
+func timestamp(t time.Time) []byte {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(t.Unix()))
+	if t.Nanosecond() != 0 {
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(t.Nanosecond()))
+	}
+	return b
+}
+
+// decodeScoredPoint decodes a ScoredPoint: id=1, payload=2, score=3, vectors=6
+func decodeScoredPoint(b []byte) (qdrantPoint, error) {
+	p := qdrantPoint{Payload: map[string]any{}}
+	err := walkFields(b, func(num protowire.Number, typ protowire.Type, v []byte, scalar uint64) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			return walkFields(v, func(n protowire.Number, t protowire.Type, id []byte, numID uint64) error {
+				switch {
+				case n == 1 && t == protowire.VarintType:
+					p.ID = float64(numID)
+				case n == 2 && t == protowire.BytesType:
+					p.ID = string(id)
+				}
+				return nil
+			})
+		case num == 2 && typ == protowire.BytesType:
+			var key string
+			var value any
+			err := walkFields(v, func(n protowire.Number, t protowire.Type, kv []byte, _ uint64) error {
+				switch {
+				case n == 1 && t == protowire.BytesType:
+					key = string(kv)
+				case n == 2 && t == protowire.BytesType:
+					value = decodeValue(kv)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if value != nil {
+				p.Payload[key] = value
+			}
+		case num == 3 && typ == protowire.Fixed32Type:
+			p.Score = math.Float32frombits(uint32(scalar))
+		case num == 6 && typ == protowire.BytesType:
+			// VectorsOutput { vector = 1 { data = 1 } }; named vectors are skipped
+			return walkFields(v, func(n protowire.Number, t protowire.Type, vec []byte, _ uint64) error {
+				if n != 1 || t != protowire.BytesType {
+					return nil
+				}
+				return walkFields(vec, func(n protowire.Number, t protowire.Type, data []byte, _ uint64) error {
+					if n == 1 && t == protowire.BytesType {
+						p.Vector = unpackFloats(data)
+					}
+					return nil
+				})
+			})
+		}
+		return nil
+	})
+	return p, err
+}
+
+// unpackFloats decodes a packed repeated float field body
+func unpackFloats(b []byte) []float32 {
+	v := make([]float32, 0, len(b)/4)
+	for len(b) >= 4 {
+		bits, n := protowire.ConsumeFixed32(b)
+		v = append(v, math.Float32frombits(bits))
+		b = b[n:]
+	}
+	return v
+}
+
+// decodeValue decodes the scalar kinds of a qdrant.Value (string, double,
+// integer, bool); structs and lists are skipped
+func decodeValue(b []byte) any {
+	var out any
+	_ = walkFields(b, func(num protowire.Number, typ protowire.Type, v []byte, scalar uint64) error {
+		switch {
+		case num == 2 && typ == protowire.Fixed64Type:
+			out = math.Float64frombits(scalar)
+		case num == 3 && typ == protowire.VarintType:
+			out = float64(int64(scalar))
+		case num == 4 && typ == protowire.BytesType:
+			out = string(v)
+		case num == 5 && typ == protowire.VarintType:
+			out = scalar != 0
+		}
+		return nil
+	})
+	return out
+}
+
+// walkFields iterates over the fields of an encoded message. Length-delimited
+// values are passed as bytes; varint and fixed values as scalar.
+func walkFields(b []byte, fn func(num protowire.Number, typ protowire.Type, v []byte, scalar uint64) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		var v []byte
+		var scalar uint64
+		switch typ {
+		case protowire.VarintType:
+			scalar, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed32Type:
+			var x uint32
+			x, n = protowire.ConsumeFixed32(b)
+			scalar = uint64(x)
+		case protowire.Fixed64Type:
+			scalar, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			v, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if err := fn(num, typ, v, scalar); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rawCodec passes pre-encoded protobuf bytes through gRPC unchanged
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return nil, fmt.Errorf("rawCodec: unexpected type %T", v)
+	}
+	return *b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("rawCodec: unexpected type %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+func (rawCodec) Name() string { return "proto" }
@@ -0,0 +1,144 @@
+package rag
+
+import (
+	"context"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// startFakeQdrantGRPC serves raw protobuf requests, recording them by method
+// and answering Search with a single scored point
+func startFakeQdrantGRPC(t *testing.T) (addr string, requests map[string][][]byte) {
+	t.Helper()
+	requests = map[string][][]byte{}
+
+	var point []byte
+	point = protowire.AppendTag(point, 1, protowire.BytesType)
+	point = protowire.AppendBytes(point, protowire.AppendString(protowire.AppendTag(nil, 2, protowire.BytesType), "doc-1"))
+	entry := protowire.AppendString(protowire.AppendTag(nil, 1, protowire.BytesType), "content")
+	entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+	entry = protowire.AppendBytes(entry, protowire.AppendString(protowire.AppendTag(nil, 4, protowire.BytesType), "Disk full runbook"))
+	point = protowire.AppendTag(point, 2, protowire.BytesType)
+	point = protowire.AppendBytes(point, entry)
+	point = protowire.AppendTag(point, 3, protowire.Fixed32Type)
+	point = protowire.AppendFixed32(point, math.Float32bits(0.75))
+	searchResp := protowire.AppendBytes(protowire.AppendTag(nil, 1, protowire.BytesType), point)
+
+	srv := grpc.NewServer(
+		grpc.ForceServerCodec(rawCodec{}),
+		grpc.UnknownServiceHandler(func(_ any, stream grpc.ServerStream) error {
+			method, _ := grpc.MethodFromServerStream(stream)
+			if md, _ := metadata.FromIncomingContext(stream.Context()); len(md.Get("api-key")) == 0 || md.Get("api-key")[0] != "secret" {
+				t.Errorf("%s: missing api-key metadata", method)
+			}
+			var req []byte
+			if err := stream.RecvMsg(&req); err != nil {
+				return err
+			}
+			requests[method] = append(requests[method], req)
+			resp := []byte{}
+			if method == qdrantSearchMethod {
+				resp = searchResp
+			}
+			return stream.SendMsg(&resp)
+		}),
+	)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String(), requests
+}
+
+func TestVectorStore_GRPC(t *testing.T) {
+	addr, requests := startFakeQdrantGRPC(t)
+	store, err := NewVectorStoreWithOptions("http://unused", "wiki", QdrantOptions{APIKey: "secret", GRPCAddr: addr})
+	if err != nil {
+		t.Fatalf("NewVectorStoreWithOptions() error = %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	// 300 points are sent as two batches
+	docs := make([]Document, upsertBatchSize+44)
+	for i := range docs {
+		docs[i] = Document{ID: "id", Content: "x", Vector: []float32{1, 2}, SourceType: "text"}
+	}
+	if err := store.Upsert(ctx, docs); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	if n := len(requests[qdrantUpsertMethod]); n != 2 {
+		t.Fatalf("upsert requests = %d, want 2", n)
+	}
+	points := 0
+	walkFields(requests[qdrantUpsertMethod][1], func(num protowire.Number, _ protowire.Type, v []byte, _ uint64) error {
+		if num == 1 && string(v) != "wiki" {
+			t.Errorf("collection_name = %q, want wiki", v)
+		}
+		if num == 3 {
+			points++
+		}
+		return nil
+	})
+	if points != 44 {
+		t.Errorf("second batch points = %d, want 44", points)
+	}
+
+	got, err := store.Search(ctx, SearchQuery{
+		Vector: []float32{1, 2},
+		Limit:  3,
+		Filter: &Filter{Space: "OPS", ModifiedAfter: time.Unix(1700000000, 0)},
+	})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(got) != 1 || got[0].ID != "doc-1" || got[0].Content != "Disk full runbook" || got[0].Score != 0.75 {
+		t.Errorf("Search() = %+v", got)
+	}
+	var hasFilter bool
+	walkFields(requests[qdrantSearchMethod][0], func(num protowire.Number, _ protowire.Type, _ []byte, scalar uint64) error {
+		if num == 3 {
+			hasFilter = true
+		}
+		if num == 4 && scalar != 3 {
+			t.Errorf("limit = %d, want 3", scalar)
+		}
+		return nil
+	})
+	if !hasFilter {
+		t.Error("search request has no filter")
+	}
+}
+
+func TestDecodeScoredPoint_NumericID(t *testing.T) {
+	id := protowire.AppendVarint(protowire.AppendTag(nil, 1, protowire.VarintType), 42)
+	b := protowire.AppendBytes(protowire.AppendTag(nil, 1, protowire.BytesType), id)
+	p, err := decodeScoredPoint(b)
+	if err != nil {
+		t.Fatalf("decodeScoredPoint() error = %v", err)
+	}
+	if doc := p.toDocument(); doc.ID != "42" {
+		t.Errorf("ID = %q, want 42", doc.ID)
+	}
+}
+
+func TestDecodeScoredPoint_Vector(t *testing.T) {
+	data := protowire.AppendBytes(protowire.AppendTag(nil, 1, protowire.BytesType), packedFloats([]float32{0.5, -1}))
+	vectors := protowire.AppendBytes(protowire.AppendTag(nil, 1, protowire.BytesType), data)
+	b := protowire.AppendBytes(protowire.AppendTag(nil, 6, protowire.BytesType), vectors)
+	p, err := decodeScoredPoint(b)
+	if err != nil {
+		t.Fatalf("decodeScoredPoint() error = %v", err)
+	}
+	if len(p.Vector) != 2 || p.Vector[0] != 0.5 || p.Vector[1] != -1 {
+		t.Errorf("Vector = %v, want [0.5 -1]", p.Vector)
+	}
+}
@@ -0,0 +1,187 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// reembedPageSize is the number of documents read from the old collection
+// per Scroll page
+const reembedPageSize = 256
+
+// ActiveCollection records the collection that replaced CollectionName after
+// Reembed, and the embedding model its vectors came from. It's saved to
+// IndexerConfig.ActiveFile, so searches and later Index runs use it.
+type ActiveCollection struct {
+	Collection string    `json:"collection"`
+	EmbedModel string    `json:"embed_model"` // provider/model, as in checkpoints
+	VectorSize int       `json:"vector_size"`
+	Switched   time.Time `json:"switched"`
+}
+
+// ReembedResult describes a finished Reembed
+type ReembedResult struct {
+	From       string // collection that was read (and is kept)
+	To         string // collection the wiki now uses
+	Docs       int
+	VectorSize int
+}
+
+// Reembed copies the documents of the collection in use into a new one,
+// embedding their stored content with the indexer's embedding model instead
+// of re-loading, re-describing and re-chunking the wiki, then switches
+// CollectionName over to it by rewriting ActiveFile. The new collection is
+// named <CollectionName>_<model>. The old collection is kept for processes
+// still searching it; nothing is switched if a document fails to embed.
+func (idx *Indexer) Reembed(ctx context.Context) (ReembedResult, error) {
+	idx.running.Lock()
+	defer idx.running.Unlock()
+	idx.out = os.Stdout
+
+	result := ReembedResult{From: idx.collection, To: idx.config.CollectionName + "_" + collectionSuffix(idx.config.EmbedProvider, idx.config.EmbedModel)}
+	if result.To == result.From {
+		return result, fmt.Errorf("collection %s already holds %s vectors", result.From, idx.embedModel())
+	}
+	if idx.config.ActiveFile == "" {
+		return result, fmt.Errorf("no ActiveFile to switch %s over with", idx.config.CollectionName)
+	}
+	size, err := DetectVectorSize(ctx, idx.embeddings)
+	if err != nil {
+		return result, err
+	}
+	result.VectorSize = size
+
+	config := idx.config
+	config.Store = nil
+	config.CollectionName = result.To
+	dst, err := newStore(config)
+	if err != nil {
+		return result, err
+	}
+	if err := dst.DeleteCollection(ctx); err != nil {
+		return result, fmt.Errorf("failed to reset collection %s: %w", result.To, err)
+	}
+	if err := dst.EnsureCollection(ctx, size); err != nil {
+		return result, fmt.Errorf("failed to create collection %s: %w", result.To, err)
+	}
+
+	idx.failures = nil
+	q := ScrollQuery{Limit: reembedPageSize}
+	for {
+		page, err := idx.store.Scroll(ctx, q)
+		if err != nil {
+			return result, fmt.Errorf("failed to read collection %s: %w", result.From, err)
+		}
+		docs, err := idx.embedDocs(ctx, page.Docs)
+		if err != nil {
+			return result, err
+		}
+		if err := dst.Upsert(ctx, docs); err != nil {
+			return result, fmt.Errorf("failed to upsert documents: %w", err)
+		}
+		result.Docs += len(docs)
+		if page.NextOffset == "" {
+			break
+		}
+		q.Offset = page.NextOffset
+	}
+	if len(idx.failures) > 0 {
+		return result, fmt.Errorf("%d documents failed to embed; still using %s", len(idx.failures), result.From)
+	}
+	if count, err := dst.Count(ctx); err != nil {
+		return result, fmt.Errorf("failed to count vectors: %w", err)
+	} else if count != result.Docs {
+		return result, fmt.Errorf("collection %s holds %d vectors, want %d; still using %s", result.To, count, result.Docs, result.From)
+	}
+
+	active := ActiveCollection{Collection: result.To, EmbedModel: idx.embedModel(), VectorSize: size, Switched: time.Now()}
+	if err := idx.saveActive(active); err != nil {
+		return result, fmt.Errorf("failed to switch to %s: %w", result.To, err)
+	}
+	idx.store, idx.collection, idx.active = dst, result.To, &active
+	idx.config.VectorSize = size
+
+	// The recorded stats now describe the new collection
+	stats, err := idx.loadStats()
+	if err == nil && stats != nil {
+		updated := *stats
+		updated.Collection, updated.VectorSize = result.To, size
+		err = idx.saveStats(updated)
+	}
+	if err != nil {
+		fmt.Fprintf(idx.out, "Warning: failed to save index stats: %v\n", err)
+	}
+	return result, nil
+}
+
+// embedModel names the indexer's embedding model as provider/model
+func (idx *Indexer) embedModel() string {
+	provider := idx.config.EmbedProvider
+	if provider == "" {
+		provider = "ollama"
+	}
+	return provider + "/" + idx.config.EmbedModel
+}
+
+// collectionSuffix turns an embedding model into a collection name part
+// every backend accepts, e.g. "nomic-embed-text" becomes "nomic_embed_text"
+func collectionSuffix(provider, model string) string {
+	if model == "" {
+		model = provider // the provider's default model
+	}
+	if model == "" {
+		model = "ollama"
+	}
+	suffix := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return '_'
+	}, model)
+	return strings.Trim(suffix, "_")
+}
+
+// loadActive reads the collection Reembed switched CollectionName to, or
+// returns nil if it was never switched
+func loadActive(path string) (*ActiveCollection, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read active collection: %w", err)
+	}
+	var active ActiveCollection
+	if err := json.Unmarshal(data, &active); err != nil || active.Collection == "" {
+		return nil, fmt.Errorf("failed to read active collection %s: invalid file", path)
+	}
+	return &active, nil
+}
+
+// saveActive replaces ActiveFile in one rename, so readers see either the
+// old collection or the new one
+func (idx *Indexer) saveActive(active ActiveCollection) error {
+	data, err := json.MarshalIndent(active, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(idx.config.ActiveFile), 0755); err != nil {
+		return err
+	}
+	tmp := idx.config.ActiveFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, idx.config.ActiveFile)
+}
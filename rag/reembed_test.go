@@ -0,0 +1,155 @@
+package rag
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// wideEmbedder stands in for a new embedding model: 4-dim vectors
+type wideEmbedder struct{ fakeEmbedder }
+
+func (w *wideEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	v, _ := w.fakeEmbedder.Embed(ctx, text)
+	return append(v, float32(strings.Count(strings.ToLower(text), "region"))+0.1), nil
+}
+
+func (w *wideEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, t := range texts {
+		vectors[i], _ = w.Embed(ctx, t)
+	}
+	return vectors, nil
+}
+
+func TestIndexer_Reembed(t *testing.T) {
+	dir := t.TempDir()
+	wiki := filepath.Join(dir, "wiki")
+	os.Mkdir(wiki, 0755)
+	page := `<html><head><title>Deploy Guide</title></head><body>
+<p>To deploy the service, run the deploy pipeline and watch the rollout.</p>
+<p>The network team owns the load balancer configuration for every region.</p>
+</body></html>`
+	if err := os.WriteFile(filepath.Join(wiki, "deploy.html"), []byte(page), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := DefaultConfig()
+	config.WikiPath = wiki
+	config.ChunkTokens = 0
+	config.StorePath = filepath.Join(dir, "vectors")
+	config.StatsFile = filepath.Join(dir, "stats.json")
+	config.CheckpointFile = filepath.Join(dir, "checkpoint.json")
+	config.ActiveFile = filepath.Join(dir, "active.json")
+	config.Embedder = &fakeEmbedder{}
+	idx, err := NewIndexer(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := idx.Index(ctx); err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+
+	// The new model embeds the stored chunks without loading the wiki
+	config.EmbedModel = "mxbai-embed-large"
+	config.Embedder = &wideEmbedder{}
+	config.WikiPath = filepath.Join(dir, "gone")
+	idx, err = NewIndexer(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := idx.Reembed(ctx)
+	if err != nil {
+		t.Fatalf("Reembed() error = %v", err)
+	}
+	if result.From != "confluence_wiki" || result.To != "confluence_wiki_mxbai_embed_large" || result.Docs != 2 || result.VectorSize != 4 {
+		t.Errorf("Reembed() = %+v", result)
+	}
+
+	// The next run searches the new collection
+	reopened, err := NewIndexer(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, _ := reopened.GetEmbeddings().Embed(ctx, "which region")
+	docs, err := reopened.GetStore().Search(ctx, SearchQuery{Vector: query, Limit: 1})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(docs) != 1 || !strings.Contains(docs[0].Content, "load balancer") || docs[0].Metadata["page_title"] != "Deploy Guide" {
+		t.Errorf("Search() = %+v, want the network paragraph with its metadata", docs)
+	}
+	stats, err := reopened.Stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Collection != result.To || stats.VectorSize != 4 || stats.Vectors != 2 || stats.Chunks != 2 {
+		t.Errorf("Stats() = %+v, want the new collection", stats)
+	}
+	if _, err := reopened.Reembed(ctx); err == nil || !strings.Contains(err.Error(), "already holds ollama/mxbai-embed-large vectors") {
+		t.Errorf("second Reembed() error = %v", err)
+	}
+
+	// A delta sync can't mix another model's vectors into it
+	config.EmbedModel = ""
+	reopened, _ = NewIndexer(config)
+	reopened.active.EmbedModel = "ollama/other"
+	if err := reopened.index(ctx, deltaLoader{}, io.Discard); err == nil || !strings.Contains(err.Error(), "re-embed it") {
+		t.Errorf("delta sync with another model error = %v", err)
+	}
+}
+
+// deltaLoader is a delta sync that found no changed pages
+type deltaLoader struct{}
+
+func (deltaLoader) LoadAll() ([]PageContent, error) { return nil, nil }
+func (deltaLoader) Incremental() bool               { return true }
+func (deltaLoader) CommitSync() error               { return nil }
+
+func TestIndexer_ReembedKeepsCollectionOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	old := NewMemoryStore()
+	old.EnsureCollection(context.Background(), 3)
+	old.Upsert(context.Background(), []Document{
+		{ID: "a", Content: "deploy steps", Vector: []float32{1, 0, 0}},
+		{ID: "b", Content: "POISON", Vector: []float32{0, 1, 0}},
+	})
+
+	config := DefaultConfig()
+	config.EmbedModel = "mxbai-embed-large"
+	config.EmbedRetries = 0
+	config.Store = old
+	config.StorePath = filepath.Join(dir, "vectors")
+	config.ActiveFile = filepath.Join(dir, "active.json")
+	config.Embedder = &flakyEmbedder{}
+	idx, err := NewIndexer(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := idx.Reembed(context.Background()); err == nil || !strings.Contains(err.Error(), "1 documents failed to embed; still using confluence_wiki") {
+		t.Errorf("Reembed() error = %v", err)
+	}
+	if _, err := os.Stat(config.ActiveFile); !os.IsNotExist(err) {
+		t.Errorf("active collection was switched: %v", err)
+	}
+	if idx.GetStore() != old {
+		t.Error("indexer switched stores")
+	}
+}
+
+func TestCollectionSuffix(t *testing.T) {
+	for _, tt := range []struct{ provider, model, want string }{
+		{"ollama", "nomic-embed-text", "nomic_embed_text"},
+		{"ollama", "mxbai-embed-large:335m", "mxbai_embed_large_335m"},
+		{"openai", "", "openai"},
+		{"openai", "BAAI/bge-M3", "baai_bge_m3"},
+	} {
+		if got := collectionSuffix(tt.provider, tt.model); got != tt.want {
+			t.Errorf("collectionSuffix(%q, %q) = %q, want %q", tt.provider, tt.model, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package rag
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IndexSource is one root of a multi-source index. Every document loaded
+// from it is tagged with Name in its "source" field, which searches can
+// filter on, so a wiki, runbooks and other exports share one collection.
+type IndexSource struct {
+	Name       string               // Tag stored on each document (e.g. "wiki", "runbooks")
+	Path       string               // Export directory, or XML file for mediawiki
+	Format     string               // Loader format for Path (default: IndexerConfig.Format)
+	Confluence *ConfluenceAPIConfig // When set, pull pages from the REST API instead of Path
+}
+
+// location returns the path or URL the source is loaded from
+func (s IndexSource) location() string {
+	if s.Confluence != nil {
+		return s.Confluence.BaseURL
+	}
+	return s.Path
+}
+
+// multiLoader loads pages from several sources and tags each page with the
+// name of its source
+type multiLoader struct {
+	names   []string
+	loaders []Loader
+}
+
+// Ensure multiLoader implements Loader
+var _ Loader = (*multiLoader)(nil)
+
+// newMultiLoader creates a loader per source. Delta sync needs every source
+// to track changes, so it isn't supported here.
+func newMultiLoader(config IndexerConfig) (*multiLoader, error) {
+	m := &multiLoader{}
+	seen := map[string]bool{}
+	for _, src := range config.Sources {
+		if src.Name == "" {
+			return nil, fmt.Errorf("index source %s has no name", src.location())
+		}
+		if seen[src.Name] {
+			return nil, fmt.Errorf("duplicate index source name %q", src.Name)
+		}
+		seen[src.Name] = true
+		if src.Confluence != nil && src.Confluence.StateFile != "" {
+			return nil, fmt.Errorf("index source %q: delta sync is not supported with multiple sources", src.Name)
+		}
+
+		sub := config
+		sub.Sources = nil
+		sub.WikiPath = src.Path
+		sub.Confluence = src.Confluence
+		if src.Format != "" {
+			sub.Format = src.Format
+		}
+		l, err := newLoader(sub)
+		if err != nil {
+			return nil, fmt.Errorf("index source %q: %w", src.Name, err)
+		}
+		m.names = append(m.names, src.Name)
+		m.loaders = append(m.loaders, l)
+	}
+	return m, nil
+}
+
+// LoadAll loads every source in order
+func (m *multiLoader) LoadAll() ([]PageContent, error) {
+	var pages []PageContent
+	for i, l := range m.loaders {
+		loaded, err := l.LoadAll()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load source %q: %w", m.names[i], err)
+		}
+		fmt.Printf("  %s: %d pages\n", m.names[i], len(loaded))
+		for _, p := range loaded {
+			p.Source = m.names[i]
+			pages = append(pages, p)
+		}
+	}
+	return pages, nil
+}
+
+// sourcesSummary describes the configured sources, e.g.
+// "wiki=/exports/wiki, runbooks=/srv/runbooks"
+func sourcesSummary(sources []IndexSource) string {
+	parts := make([]string, len(sources))
+	for i, s := range sources {
+		parts[i] = s.Name + "=" + s.location()
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,209 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// IndexStats describes an indexed collection: what the last Index run put in
+// it and how many vectors the store holds now. Stats are saved per collection
+// to IndexerConfig.StatsFile, since not every backend can attach metadata to
+// a collection.
+type IndexStats struct {
+	Collection  string         `json:"collection"`
+	Source      string         `json:"source"` // wiki path, Confluence URL or name=location list
+	Pages       int            `json:"pages"`
+	Chunks      int            `json:"chunks"`                // text documents, including OCR text from images
+	Images      int            `json:"images"`                // diagram descriptions
+	Undescribed int            `json:"undescribed,omitempty"` // images left out: too small, over MaxPageImages, or SkipImages
+	Summaries   int            `json:"summaries,omitempty"`   // page summaries
+	Skipped     int            `json:"skipped"`
+	Duplicates  int            `json:"duplicates,omitempty"` // near-duplicate chunks dropped
+	Spaces      map[string]int `json:"spaces,omitempty"`     // documents per Confluence space
+	Sources     map[string]int `json:"sources,omitempty"`    // documents per source of a multi-source index
+	Languages   map[string]int `json:"languages,omitempty"`  // text documents per detected language
+	VectorSize  int            `json:"vector_size"`
+	Incremental bool           `json:"incremental"` // last run was a delta sync; counts cover changed pages only
+	LastIndexed time.Time      `json:"last_indexed"`
+	Vectors     int            `json:"vectors"` // live count from the store
+}
+
+// String formats the stats for display, one field per line
+func (s IndexStats) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Collection:   %s\n", s.Collection)
+	if s.Source != "" {
+		fmt.Fprintf(&sb, "Source:       %s\n", s.Source)
+	}
+	if s.LastIndexed.IsZero() {
+		sb.WriteString("Last indexed: unknown (no stats recorded)\n")
+	} else {
+		run := "full"
+		if s.Incremental {
+			run = "delta sync"
+		}
+		fmt.Fprintf(&sb, "Last indexed: %s (%s)\n", s.LastIndexed.Local().Format("2006-01-02 15:04:05"), run)
+		fmt.Fprintf(&sb, "Pages:        %d\n", s.Pages)
+		fmt.Fprintf(&sb, "Chunks:       %d\n", s.Chunks)
+		if s.Undescribed > 0 {
+			fmt.Fprintf(&sb, "Images:       %d (%d skipped)\n", s.Images, s.Undescribed)
+		} else {
+			fmt.Fprintf(&sb, "Images:       %d\n", s.Images)
+		}
+		if s.Summaries > 0 {
+			fmt.Fprintf(&sb, "Summaries:    %d\n", s.Summaries)
+		}
+		if s.Skipped > 0 {
+			fmt.Fprintf(&sb, "Skipped:      %d\n", s.Skipped)
+		}
+		if s.Duplicates > 0 {
+			fmt.Fprintf(&sb, "Duplicates:   %d\n", s.Duplicates)
+		}
+	}
+	fmt.Fprintf(&sb, "Vectors:      %d", s.Vectors)
+	if s.VectorSize > 0 {
+		fmt.Fprintf(&sb, " (%d dims)", s.VectorSize)
+	}
+	sb.WriteString("\n")
+	if len(s.Sources) > 0 {
+		fmt.Fprintf(&sb, "Sources:      %s\n", formatCounts(s.Sources))
+	}
+	if len(s.Spaces) > 0 {
+		fmt.Fprintf(&sb, "Spaces:       %s\n", formatCounts(s.Spaces))
+	}
+	if len(s.Languages) > 0 {
+		fmt.Fprintf(&sb, "Languages:    %s\n", formatCounts(s.Languages))
+	}
+	return sb.String()
+}
+
+// formatCounts formats a count map as "a=1, b=2" in key order
+func formatCounts(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s=%d", k, counts[k])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Stats returns the statistics recorded by the last Index run (this process's
+// or a previous one's) with a live vector count from the store
+func (idx *Indexer) Stats(ctx context.Context) (IndexStats, error) {
+	stats, err := idx.loadStats()
+	if err != nil {
+		return IndexStats{}, err
+	}
+	if stats == nil {
+		stats = &IndexStats{Collection: idx.collection, Source: idx.source()}
+	}
+
+	result := *stats
+	count, err := idx.store.Count(ctx)
+	if err != nil {
+		return IndexStats{}, fmt.Errorf("failed to count vectors: %w", err)
+	}
+	result.Vectors = count
+	return result, nil
+}
+
+// loadStats returns the stats recorded by the last Index run, or nil if
+// there are none
+func (idx *Indexer) loadStats() (*IndexStats, error) {
+	idx.mu.Lock()
+	stats := idx.stats
+	idx.mu.Unlock()
+	if stats == nil && idx.config.StatsFile != "" {
+		if data, err := os.ReadFile(idx.config.StatsFile); err == nil {
+			var saved IndexStats
+			if err := json.Unmarshal(data, &saved); err != nil {
+				return nil, fmt.Errorf("failed to read index stats: %w", err)
+			}
+			stats = &saved
+		}
+	}
+	return stats, nil
+}
+
+// addDocs counts indexed documents into the stats
+func (s *IndexStats) addDocs(docs []Document) {
+	for _, d := range docs {
+		switch {
+		case d.SourceType == "image" && d.Metadata["chunk_type"] != "ocr":
+			s.Images++
+		case d.Metadata["chunk_type"] == "summary":
+			s.Summaries++
+		default:
+			s.Chunks++
+		}
+		if space := d.Metadata["space"]; space != "" {
+			if s.Spaces == nil {
+				s.Spaces = map[string]int{}
+			}
+			s.Spaces[space]++
+		}
+		if source := d.Metadata["source"]; source != "" {
+			if s.Sources == nil {
+				s.Sources = map[string]int{}
+			}
+			s.Sources[source]++
+		}
+		if lang := d.Metadata["language"]; lang != "" {
+			if s.Languages == nil {
+				s.Languages = map[string]int{}
+			}
+			s.Languages[lang]++
+		}
+	}
+}
+
+// recordStats completes the stats of an Index run and saves them to StatsFile
+func (idx *Indexer) recordStats(stats IndexStats, pages int) error {
+	stats.Collection = idx.collection
+	stats.Source = idx.source()
+	stats.Pages = pages
+	stats.Skipped = len(idx.failures)
+	stats.VectorSize = idx.config.VectorSize
+	stats.LastIndexed = time.Now()
+	return idx.saveStats(stats)
+}
+
+// saveStats makes stats the current ones and saves them to StatsFile
+func (idx *Indexer) saveStats(stats IndexStats) error {
+	idx.mu.Lock()
+	idx.stats = &stats
+	idx.mu.Unlock()
+
+	if idx.config.StatsFile == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(idx.config.StatsFile), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(idx.config.StatsFile, data, 0644)
+}
+
+// source describes where the indexer reads pages from
+func (idx *Indexer) source() string {
+	if len(idx.config.Sources) > 0 {
+		return sourcesSummary(idx.config.Sources)
+	}
+	if idx.config.Confluence != nil {
+		return idx.config.Confluence.BaseURL
+	}
+	return idx.config.WikiPath
+}
@@ -3,10 +3,18 @@ package rag
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Document represents a document in the vector store
@@ -20,11 +28,147 @@ type Document struct {
 	ImagePath  string            `json:"image_path,omitempty"`
 }
 
+// Store is a collection of embedded documents that can be searched by
+// similarity. VectorStore (Qdrant) and LocalStore (embedded) implement it.
+type Store interface {
+	EnsureCollection(ctx context.Context, vectorSize int) error
+	DeleteCollection(ctx context.Context) error
+	Upsert(ctx context.Context, docs []Document) error
+	Search(ctx context.Context, q SearchQuery) ([]Document, error)
+	Scroll(ctx context.Context, q ScrollQuery) (ScrollResult, error)
+	DeleteByIDs(ctx context.Context, ids []string) error
+	DeleteByFilter(ctx context.Context, f *Filter) error
+	Count(ctx context.Context) (int, error)
+}
+
+// errEmptyDeleteFilter guards DeleteByFilter against wiping the collection
+var errEmptyDeleteFilter = fmt.Errorf("refusing to delete with an empty filter (use DeleteCollection)")
+
+// isEmpty reports whether the filter has no conditions
+func (f *Filter) isEmpty() bool {
+	return f == nil || *f == (Filter{})
+}
+
+// ScrollQuery pages through stored documents without a query vector
+type ScrollQuery struct {
+	Filter *Filter // Optional payload filter
+	Limit  int     // Page size (default: 100)
+	Offset string  // Opaque cursor from a previous ScrollResult.NextOffset; empty for the first page
+}
+
+// ScrollResult is one page of documents. Vectors are not returned.
+type ScrollResult struct {
+	Docs       []Document
+	NextOffset string // Cursor for the next page; empty when there are no more
+}
+
+// ListIDs returns the IDs of every stored document matching filter, paging
+// through Scroll. Used to diff the index against the current corpus.
+func ListIDs(ctx context.Context, store Store, filter *Filter) ([]string, error) {
+	var ids []string
+	q := ScrollQuery{Filter: filter, Limit: 256}
+	for {
+		page, err := store.Scroll(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range page.Docs {
+			ids = append(ids, d.ID)
+		}
+		if page.NextOffset == "" {
+			return ids, nil
+		}
+		q.Offset = page.NextOffset
+	}
+}
+
+// defaultScrollLimit is the page size used when ScrollQuery.Limit is unset
+const defaultScrollLimit = 100
+
 // VectorStore wraps Qdrant for storing and querying embeddings
 type VectorStore struct {
 	baseURL        string
 	collectionName string
 	client         *http.Client
+	grpc           *qdrantGRPC // nil: everything goes over REST
+	collection     QdrantCollectionConfig
+
+	statsMu sync.Mutex
+	stats   termStats // document frequencies of query terms, for BM25
+}
+
+// termStats caches the corpus statistics BM25 needs, so repeated searches
+// don't count every query term again
+type termStats struct {
+	at    time.Time      // when total was counted; zero: nothing cached
+	total int            // points in the collection
+	df    map[string]int // points matching each term
+}
+
+// keywordStatsTTL is how long term statistics are reused. Writes through
+// this store clear them at once; other writers' show up after it.
+const keywordStatsTTL = 5 * time.Minute
+
+// QdrantOptions configures authentication and TLS for managed or production
+// Qdrant instances. The zero value connects without auth using system roots.
+type QdrantOptions struct {
+	APIKey             string // Sent as the "api-key" header on every request
+	CAFile             string // PEM CA bundle to trust in addition to system roots
+	InsecureSkipVerify bool   // Skip TLS certificate verification (testing only)
+	GRPCAddr           string // Optional host:port of the gRPC API (usually :6334) for upserts and vector search
+
+	// Collection tunes collections created by EnsureCollection
+	Collection QdrantCollectionConfig
+}
+
+// QdrantCollectionConfig holds index and storage settings applied when a
+// collection is created. Zero values keep Qdrant's defaults. Existing
+// collections are not modified; re-index to apply changes.
+type QdrantCollectionConfig struct {
+	HNSWM           int    // HNSW graph degree (Qdrant default 16); lower saves memory, higher improves recall
+	HNSWEfConstruct int    // HNSW build-time beam width (default 100)
+	OnDiskVectors   bool   // Keep original vectors on disk (memory-mapped)
+	OnDiskPayload   bool   // Keep payloads on disk instead of in RAM
+	Quantization    string // "", "scalar" (int8, ~4x smaller) or "product" (x16 compression)
+}
+
+// createBody builds the PUT /collections/{name} request body
+func (c QdrantCollectionConfig) createBody(vectorSize int) map[string]any {
+	vectors := map[string]any{
+		"size":     vectorSize,
+		"distance": "Cosine",
+	}
+	if c.OnDiskVectors {
+		vectors["on_disk"] = true
+	}
+	body := map[string]any{"vectors": vectors}
+
+	hnsw := map[string]any{}
+	if c.HNSWM > 0 {
+		hnsw["m"] = c.HNSWM
+	}
+	if c.HNSWEfConstruct > 0 {
+		hnsw["ef_construct"] = c.HNSWEfConstruct
+	}
+	if len(hnsw) > 0 {
+		body["hnsw_config"] = hnsw
+	}
+	if c.OnDiskPayload {
+		body["on_disk_payload"] = true
+	}
+
+	// Quantized vectors stay in RAM for fast scoring; originals rescore
+	switch c.Quantization {
+	case "scalar":
+		body["quantization_config"] = map[string]any{
+			"scalar": map[string]any{"type": "int8", "quantile": 0.99, "always_ram": true},
+		}
+	case "product":
+		body["quantization_config"] = map[string]any{
+			"product": map[string]any{"compression": "x16", "always_ram": true},
+		}
+	}
+	return body
 }
 
 // NewVectorStore creates a new Qdrant vector store client
@@ -36,7 +180,85 @@ func NewVectorStore(baseURL, collectionName string) *VectorStore {
 	}
 }
 
-// EnsureCollection creates the collection if it doesn't exist
+// NewVectorStoreWithOptions creates a Qdrant client with an API key and/or
+// custom TLS settings
+func NewVectorStoreWithOptions(baseURL, collectionName string, opts QdrantOptions) (*VectorStore, error) {
+	switch opts.Collection.Quantization {
+	case "", "scalar", "product":
+	default:
+		return nil, fmt.Errorf("unknown Qdrant quantization %q (use scalar or product)", opts.Collection.Quantization)
+	}
+	s := NewVectorStore(strings.TrimSuffix(baseURL, "/"), collectionName)
+	s.collection = opts.Collection
+
+	tlsConfig, err := opts.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	var rt http.RoundTripper = transport
+	if opts.APIKey != "" {
+		rt = &apiKeyTransport{base: transport, apiKey: opts.APIKey}
+	}
+	s.client = &http.Client{Transport: rt}
+
+	if opts.GRPCAddr != "" {
+		g, err := dialQdrantGRPC(opts.GRPCAddr, opts.APIKey, tlsConfig, strings.HasPrefix(s.baseURL, "https://"))
+		if err != nil {
+			return nil, err
+		}
+		s.grpc = g
+	}
+	return s, nil
+}
+
+// tlsConfig builds the client TLS settings; nil means Go's defaults
+func (opts QdrantOptions) tlsConfig() (*tls.Config, error) {
+	if opts.CAFile == "" && !opts.InsecureSkipVerify {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: opts.InsecureSkipVerify}
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Qdrant CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
+
+// Close releases the gRPC connection, if any
+func (s *VectorStore) Close() error {
+	if s.grpc != nil {
+		return s.grpc.Close()
+	}
+	return nil
+}
+
+// apiKeyTransport adds Qdrant's "api-key" header to every request
+type apiKeyTransport struct {
+	base   http.RoundTripper
+	apiKey string
+}
+
+func (t *apiKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("api-key", t.apiKey)
+	return t.base.RoundTrip(req)
+}
+
+// EnsureCollection creates the collection if it doesn't exist, and its
+// full-text index either way
 func (s *VectorStore) EnsureCollection(ctx context.Context, vectorSize int) error {
 	// Check if collection exists
 	url := fmt.Sprintf("%s/collections/%s", s.baseURL, s.collectionName)
@@ -51,17 +273,12 @@ func (s *VectorStore) EnsureCollection(ctx context.Context, vectorSize int) erro
 	defer resp.Body.Close()
 
 	if resp.StatusCode == 200 {
-		return nil // Collection exists
+		// Collections created before the keyword leg lack its index
+		return s.ensureTextIndex(ctx)
 	}
 
 	// Create collection
-	createReq := map[string]any{
-		"vectors": map[string]any{
-			"size":     vectorSize,
-			"distance": "Cosine",
-		},
-	}
-	body, _ := json.Marshal(createReq)
+	body, _ := json.Marshal(s.collection.createBody(vectorSize))
 
 	req, err = http.NewRequestWithContext(ctx, "PUT", url, bytes.NewReader(body))
 	if err != nil {
@@ -80,11 +297,46 @@ func (s *VectorStore) EnsureCollection(ctx context.Context, vectorSize int) erro
 		return fmt.Errorf("failed to create collection: %s", string(respBody))
 	}
 
+	return s.ensureTextIndex(ctx)
+}
+
+// ensureTextIndex creates the full-text payload index on "content" used by
+// the keyword leg of hybrid search
+func (s *VectorStore) ensureTextIndex(ctx context.Context) error {
+	indexReq := map[string]any{
+		"field_name": "content",
+		"field_schema": map[string]any{
+			"type":      "text",
+			"tokenizer": "word",
+			"lowercase": true,
+		},
+	}
+	body, _ := json.Marshal(indexReq)
+
+	url := fmt.Sprintf("%s/collections/%s/index?wait=true", s.baseURL, s.collectionName)
+	req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to create text index: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to create text index: %s", string(respBody))
+	}
+
 	return nil
 }
 
 // DeleteCollection deletes the collection (for re-indexing)
 func (s *VectorStore) DeleteCollection(ctx context.Context) error {
+	defer s.resetStats()
 	url := fmt.Sprintf("%s/collections/%s", s.baseURL, s.collectionName)
 	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
 	if err != nil {
@@ -106,12 +358,30 @@ func (s *VectorStore) DeleteCollection(ctx context.Context) error {
 	return nil
 }
 
-// Upsert adds or updates documents in the store
+// upsertBatchSize bounds the points per upsert request, keeping request
+// bodies at a few MB for 768-dim vectors
+const upsertBatchSize = 256
+
+// Upsert adds or updates documents in the store, in batches
 func (s *VectorStore) Upsert(ctx context.Context, docs []Document) error {
-	if len(docs) == 0 {
-		return nil
+	defer s.resetStats()
+	for start := 0; start < len(docs); start += upsertBatchSize {
+		end := min(start+upsertBatchSize, len(docs))
+		var err error
+		if s.grpc != nil {
+			err = s.grpc.upsert(ctx, s.collectionName, docs[start:end])
+		} else {
+			err = s.upsertREST(ctx, docs[start:end])
+		}
+		if err != nil {
+			return fmt.Errorf("failed to upsert points: %w", err)
+		}
 	}
+	return nil
+}
 
+// upsertREST writes one batch of points over the REST API
+func (s *VectorStore) upsertREST(ctx context.Context, docs []Document) error {
 	points := make([]map[string]any, len(docs))
 	for i, doc := range docs {
 		payload := map[string]any{
@@ -146,93 +416,475 @@ func (s *VectorStore) Upsert(ctx context.Context, docs []Document) error {
 
 	resp, err := s.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to upsert points: %w", err)
+		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to upsert points: %s", string(respBody))
+		return statusError(resp.StatusCode, respBody)
 	}
 
 	return nil
 }
 
-// Search finds similar documents
-func (s *VectorStore) Search(ctx context.Context, queryVector []float32, limit int) ([]Document, error) {
-	searchReq := map[string]any{
-		"vector":       queryVector,
-		"limit":        limit,
-		"with_payload": true,
+// SearchQuery describes a similarity search
+type SearchQuery struct {
+	Vector []float32 // Query embedding
+	Text   string    // Raw query text; when set, a BM25 keyword search runs alongside and results are fused
+	Limit  int       // Max results
+	Filter *Filter   // Optional payload filter
+
+	WithVectors bool // Return each result's stored vector (e.g. for MMR re-ranking)
+}
+
+// Filter restricts search results by payload metadata. Empty fields are ignored.
+type Filter struct {
+	PageTitle      string    // Substring of the page title
+	ChunkType      string    // "heading", "paragraph", "list", "code", "table", "section"
+	SourceType     string    // "text" or "image" (diagrams)
+	Space          string    // Confluence space key
+	Source         string    // Name of the index source (multi-source indexes)
+	FilePath       string    // Exact source file path or page URL
+	Label          string    // Confluence label the page carries
+	Language       string    // ISO 639-1 code of the chunk's language (DetectLanguage)
+	ModifiedAfter  time.Time // Only pages last modified at or after this time
+	ModifiedBefore time.Time // Only pages last modified before this time
+}
+
+// labelMatch is the substring of the "labels" payload field (see
+// labelsValue) that pages carrying f.Label contain
+func (f *Filter) labelMatch() string {
+	return "," + strings.ToLower(f.Label) + ","
+}
+
+// conditions converts the filter to Qdrant "must" conditions
+func (f *Filter) conditions() []map[string]any {
+	if f == nil {
+		return nil
+	}
+	var conds []map[string]any
+	if f.PageTitle != "" {
+		// Without a full-text index on page_title Qdrant treats this as a substring match
+		conds = append(conds, map[string]any{"key": "page_title", "match": map[string]any{"text": f.PageTitle}})
+	}
+	if f.Label != "" {
+		conds = append(conds, map[string]any{"key": "labels", "match": map[string]any{"text": f.labelMatch()}})
+	}
+	for _, kv := range [][2]string{
+		{"chunk_type", f.ChunkType},
+		{"source_type", f.SourceType},
+		{"space", f.Space},
+		{"source", f.Source},
+		{"file_path", f.FilePath},
+		{"language", f.Language},
+	} {
+		if kv[1] != "" {
+			conds = append(conds, map[string]any{"key": kv[0], "match": map[string]any{"value": kv[1]}})
+		}
+	}
+	if !f.ModifiedAfter.IsZero() || !f.ModifiedBefore.IsZero() {
+		r := map[string]any{}
+		if !f.ModifiedAfter.IsZero() {
+			r["gte"] = f.ModifiedAfter.UTC().Format(time.RFC3339)
+		}
+		if !f.ModifiedBefore.IsZero() {
+			r["lt"] = f.ModifiedBefore.UTC().Format(time.RFC3339)
+		}
+		conds = append(conds, map[string]any{"key": "last_modified", "range": r})
 	}
-	body, _ := json.Marshal(searchReq)
+	return conds
+}
 
-	url := fmt.Sprintf("%s/collections/%s/points/search", s.baseURL, s.collectionName)
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+// keywordCandidates bounds how many keyword-matching points are fetched per
+// query term for BM25 scoring; terms stop being fetched once that many
+// candidates are in
+const keywordCandidates = 256
+
+// Search finds similar documents. With q.Text set it runs a hybrid search:
+// cosine similarity and BM25 keyword ranking are combined with reciprocal rank
+// fusion, so exact identifiers (hostnames, error codes) that embeddings blur
+// still surface. Document.Score is then the fused RRF score.
+func (s *VectorStore) Search(ctx context.Context, q SearchQuery) ([]Document, error) {
+	if q.Text == "" {
+		return s.vectorSearch(ctx, q.Vector, q.Limit, q.Filter, q.WithVectors)
+	}
+
+	// Over-fetch each leg so fusion has room to reorder
+	vectorDocs, err := s.vectorSearch(ctx, q.Vector, q.Limit*3, q.Filter, q.WithVectors)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := s.client.Do(req)
+	keywordDocs, err := s.keywordSearch(ctx, q.Text, q.Limit*3, q.Filter, q.WithVectors)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search: %w", err)
+		// Keyword leg is best-effort; vector results are still useful
+		if len(vectorDocs) > q.Limit {
+			vectorDocs = vectorDocs[:q.Limit]
+		}
+		return vectorDocs, nil
 	}
-	defer resp.Body.Close()
+	return FuseRRF(q.Limit, vectorDocs, keywordDocs), nil
+}
 
-	if resp.StatusCode != 200 {
-		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to search: %s", string(respBody))
+// vectorSearch runs a plain cosine-similarity search
+func (s *VectorStore) vectorSearch(ctx context.Context, queryVector []float32, limit int, filter *Filter, withVectors bool) ([]Document, error) {
+	if s.grpc != nil {
+		docs, err := s.grpc.search(ctx, s.collectionName, queryVector, limit, filter, withVectors)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search: %w", err)
+		}
+		return docs, nil
+	}
+
+	searchReq := map[string]any{
+		"vector":       queryVector,
+		"limit":        limit,
+		"with_payload": true,
+		"with_vector":  withVectors,
+	}
+	if conds := filter.conditions(); len(conds) > 0 {
+		searchReq["filter"] = map[string]any{"must": conds}
 	}
 
 	var result struct {
-		Result []struct {
-			ID      any            `json:"id"`
-			Score   float32        `json:"score"`
-			Payload map[string]any `json:"payload"`
-		} `json:"result"`
+		Result []qdrantPoint `json:"result"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := s.post(ctx, "/points/search", searchReq, &result); err != nil {
+		return nil, fmt.Errorf("failed to search: %w", err)
 	}
 
 	docs := make([]Document, len(result.Result))
 	for i, r := range result.Result {
-		doc := Document{
-			Score: r.Score,
+		docs[i] = r.toDocument()
+	}
+	return docs, nil
+}
+
+// keywordSearch fetches points whose content matches the query terms via
+// the full-text payload index, rarest term first, so that the matches of a
+// rare identifier aren't crowded out by those of a common word. They are
+// ranked with BM25 using corpus-wide document frequencies.
+func (s *VectorStore) keywordSearch(ctx context.Context, text string, limit int, filter *Filter, withVectors bool) ([]Document, error) {
+	terms := queryTerms(text)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	total, df, err := s.termStats(ctx, terms)
+	if err != nil {
+		return nil, err
+	}
+
+	byRarity := slices.Clone(terms)
+	slices.SortStableFunc(byRarity, func(a, b string) int { return df[a] - df[b] })
+	var candidates []Document
+	seen := map[string]bool{}
+	for _, t := range byRarity {
+		if df[t] == 0 {
+			continue
+		}
+		if len(candidates) >= keywordCandidates {
+			break
+		}
+		scrollReq := map[string]any{
+			"filter":       map[string]any{"must": append([]map[string]any{textMatch(t)}, filter.conditions()...)},
+			"limit":        keywordCandidates,
+			"with_payload": true,
+			"with_vector":  withVectors,
+		}
+		var scroll struct {
+			Result struct {
+				Points []qdrantPoint `json:"points"`
+			} `json:"result"`
+		}
+		if err := s.post(ctx, "/points/scroll", scrollReq, &scroll); err != nil {
+			return nil, fmt.Errorf("keyword scroll failed: %w", err)
+		}
+		for _, p := range scroll.Result.Points {
+			doc := p.toDocument()
+			if !seen[doc.ID] {
+				seen[doc.ID] = true
+				candidates = append(candidates, doc)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	ranked := bm25Rank(candidates, terms, df, total)
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked, nil
+}
+
+// termStats returns the number of points and the document frequency of
+// each term, counting only what isn't cached
+func (s *VectorStore) termStats(ctx context.Context, terms []string) (int, map[string]int, error) {
+	s.statsMu.Lock()
+	if time.Since(s.stats.at) > keywordStatsTTL {
+		s.stats = termStats{}
+	}
+	stats := s.stats
+	df := make(map[string]int, len(terms))
+	var missing []string
+	for _, t := range terms {
+		if n, ok := stats.df[t]; ok {
+			df[t] = n
+		} else {
+			missing = append(missing, t)
 		}
+	}
+	s.statsMu.Unlock()
 
-		// Handle ID which can be string or int
-		switch id := r.ID.(type) {
-		case string:
-			doc.ID = id
-		case float64:
-			doc.ID = fmt.Sprintf("%d", int(id))
+	if stats.at.IsZero() {
+		total, err := s.Count(ctx)
+		if err != nil {
+			return 0, nil, err
+		}
+		stats = termStats{at: time.Now(), total: total, df: map[string]int{}}
+	}
+	for _, t := range missing {
+		countReq := map[string]any{
+			"filter": map[string]any{"must": []map[string]any{textMatch(t)}},
+			"exact":  true,
+		}
+		var count struct {
+			Result struct {
+				Count int `json:"count"`
+			} `json:"result"`
 		}
+		if err := s.post(ctx, "/points/count", countReq, &count); err != nil {
+			return 0, nil, fmt.Errorf("keyword count failed: %w", err)
+		}
+		df[t] = count.Result.Count
+	}
 
-		if content, ok := r.Payload["content"].(string); ok {
-			doc.Content = content
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	if s.stats.at.IsZero() {
+		s.stats = stats
+	}
+	if s.stats.at.Equal(stats.at) { // not reset by a write meanwhile
+		for _, t := range missing {
+			s.stats.df[t] = df[t]
 		}
-		if sourceType, ok := r.Payload["source_type"].(string); ok {
-			doc.SourceType = sourceType
+	}
+	return stats.total, df, nil
+}
+
+// resetStats drops the cached term statistics after a write
+func (s *VectorStore) resetStats() {
+	s.statsMu.Lock()
+	s.stats = termStats{}
+	s.statsMu.Unlock()
+}
+
+// Scroll pages through stored points in ID order
+func (s *VectorStore) Scroll(ctx context.Context, q ScrollQuery) (ScrollResult, error) {
+	if q.Limit <= 0 {
+		q.Limit = defaultScrollLimit
+	}
+	scrollReq := map[string]any{
+		"limit":        q.Limit,
+		"with_payload": true,
+		"with_vector":  false,
+	}
+	if conds := q.Filter.conditions(); len(conds) > 0 {
+		scrollReq["filter"] = map[string]any{"must": conds}
+	}
+	if q.Offset != "" {
+		// Numeric point IDs must be sent as numbers
+		if n, err := strconv.ParseUint(q.Offset, 10, 64); err == nil {
+			scrollReq["offset"] = n
+		} else {
+			scrollReq["offset"] = q.Offset
 		}
-		if imagePath, ok := r.Payload["image_path"].(string); ok {
-			doc.ImagePath = imagePath
+	}
+
+	var scroll struct {
+		Result struct {
+			Points         []qdrantPoint `json:"points"`
+			NextPageOffset any           `json:"next_page_offset"`
+		} `json:"result"`
+	}
+	if err := s.post(ctx, "/points/scroll", scrollReq, &scroll); err != nil {
+		return ScrollResult{}, fmt.Errorf("failed to scroll: %w", err)
+	}
+
+	result := ScrollResult{Docs: make([]Document, len(scroll.Result.Points))}
+	for i, p := range scroll.Result.Points {
+		result.Docs[i] = p.toDocument()
+	}
+	if next := scroll.Result.NextPageOffset; next != nil {
+		result.NextOffset = qdrantPoint{ID: next}.toDocument().ID
+	}
+	return result, nil
+}
+
+// DeleteByIDs removes the given points
+func (s *VectorStore) DeleteByIDs(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	defer s.resetStats()
+	points := make([]any, len(ids))
+	for i, id := range ids {
+		// Numeric point IDs must be sent as numbers
+		if n, err := strconv.ParseUint(id, 10, 64); err == nil {
+			points[i] = n
+		} else {
+			points[i] = id
 		}
+	}
+	var result map[string]any
+	if err := s.post(ctx, "/points/delete?wait=true", map[string]any{"points": points}, &result); err != nil {
+		return fmt.Errorf("failed to delete points: %w", err)
+	}
+	return nil
+}
+
+// DeleteByFilter removes every point matching the filter, e.g. all chunks of
+// one page via Filter{FilePath: ...}
+func (s *VectorStore) DeleteByFilter(ctx context.Context, f *Filter) error {
+	if f.isEmpty() {
+		return errEmptyDeleteFilter
+	}
+	defer s.resetStats()
+	deleteReq := map[string]any{"filter": map[string]any{"must": f.conditions()}}
+	var result map[string]any
+	if err := s.post(ctx, "/points/delete?wait=true", deleteReq, &result); err != nil {
+		return fmt.Errorf("failed to delete points: %w", err)
+	}
+	return nil
+}
+
+// textMatch builds a full-text match condition on the content payload
+func textMatch(term string) map[string]any {
+	return map[string]any{"key": "content", "match": map[string]any{"text": term}}
+}
+
+// qdrantPoint is a point as returned by search and scroll
+type qdrantPoint struct {
+	ID      any            `json:"id"`
+	Score   float32        `json:"score"`
+	Payload map[string]any `json:"payload"`
+	Vector  []float32      `json:"vector,omitempty"`
+}
+
+// jsonFloats converts a decoded JSON number array into a vector
+func jsonFloats(v []any) []float32 {
+	out := make([]float32, len(v))
+	for i, x := range v {
+		f, _ := x.(float64)
+		out[i] = float32(f)
+	}
+	return out
+}
+
+// toDocument converts a Qdrant point into a Document
+func (p qdrantPoint) toDocument() Document {
+	doc := Document{
+		Score:  p.Score,
+		Vector: p.Vector,
+	}
+
+	// Handle ID which can be string or int
+	switch id := p.ID.(type) {
+	case string:
+		doc.ID = id
+	case float64:
+		doc.ID = fmt.Sprintf("%d", int(id))
+	}
+
+	if content, ok := p.Payload["content"].(string); ok {
+		doc.Content = content
+	}
+	if sourceType, ok := p.Payload["source_type"].(string); ok {
+		doc.SourceType = sourceType
+	}
+	if imagePath, ok := p.Payload["image_path"].(string); ok {
+		doc.ImagePath = imagePath
+	}
 
-		doc.Metadata = make(map[string]string)
-		for k, v := range r.Payload {
-			if k != "content" && k != "source_type" && k != "image_path" {
-				if str, ok := v.(string); ok {
-					doc.Metadata[k] = str
-				}
+	doc.Metadata = make(map[string]string)
+	for k, v := range p.Payload {
+		if k != "content" && k != "source_type" && k != "image_path" {
+			if str, ok := v.(string); ok {
+				doc.Metadata[k] = str
 			}
 		}
+	}
+	return doc
+}
+
+// post sends a JSON request to a collection endpoint and decodes the response
+func (s *VectorStore) post(ctx context.Context, path string, reqBody, out any) error {
+	body, _ := json.Marshal(reqBody)
 
-		docs[i] = doc
+	url := fmt.Sprintf("%s/collections/%s%s", s.baseURL, s.collectionName, path)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
 	}
+	req.Header.Set("Content-Type", "application/json")
 
-	return docs, nil
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return statusError(resp.StatusCode, respBody)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// CollectionInfo describes a Qdrant collection
+type CollectionInfo struct {
+	Exists bool
+	Status string // green, yellow (optimizing) or red
+	Points int
+}
+
+// Info reports whether the collection exists, its status and size. An
+// error means Qdrant couldn't be asked (unreachable, auth, TLS).
+func (s *VectorStore) Info(ctx context.Context) (CollectionInfo, error) {
+	url := fmt.Sprintf("%s/collections/%s", s.baseURL, s.collectionName)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return CollectionInfo{}, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return CollectionInfo{}, fmt.Errorf("failed to get collection info: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return CollectionInfo{}, nil
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return CollectionInfo{}, fmt.Errorf("qdrant returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var result struct {
+		Result struct {
+			Status      string `json:"status"`
+			PointsCount int    `json:"points_count"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return CollectionInfo{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return CollectionInfo{Exists: true, Status: result.Result.Status, Points: result.Result.PointsCount}, nil
 }
 
 // Count returns the number of documents in the collection
@@ -248,6 +900,10 @@ func (s *VectorStore) Count(ctx context.Context) (int, error) {
 		return 0, fmt.Errorf("failed to get collection info: %w", err)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return 0, statusError(resp.StatusCode, respBody)
+	}
 
 	var result struct {
 		Result struct {
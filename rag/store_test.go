@@ -0,0 +1,372 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeQdrant serves the subset of the Qdrant REST API used by VectorStore.
+// Keyword matching is a plain substring check on the content payload.
+type fakeQdrant struct {
+	points   []qdrantPoint
+	requests []string
+	bodies   []map[string]any
+}
+
+func (f *fakeQdrant) handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		f.bodies = append(f.bodies, body)
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/points/search"):
+			json.NewEncoder(w).Encode(map[string]any{"result": f.points})
+		case strings.HasSuffix(r.URL.Path, "/points/scroll"):
+			points := f.match(body)
+			if limit, ok := body["limit"].(float64); ok && len(points) > int(limit) {
+				points = points[:int(limit)]
+			}
+			json.NewEncoder(w).Encode(map[string]any{"result": map[string]any{"points": points}})
+		case strings.HasSuffix(r.URL.Path, "/points/count"):
+			json.NewEncoder(w).Encode(map[string]any{"result": map[string]any{"count": len(f.match(body))}})
+		case r.Method == "GET":
+			json.NewEncoder(w).Encode(map[string]any{"result": map[string]any{"points_count": len(f.points)}})
+		default:
+			json.NewEncoder(w).Encode(map[string]any{"result": true})
+		}
+	}
+}
+
+// match applies should/must full-text conditions from a filter
+func (f *fakeQdrant) match(body map[string]any) []qdrantPoint {
+	filter, _ := body["filter"].(map[string]any)
+	terms := func(key string) []string {
+		var out []string
+		conds, _ := filter[key].([]any)
+		for _, c := range conds {
+			m, _ := c.(map[string]any)["match"].(map[string]any)
+			if text, ok := m["text"].(string); ok && c.(map[string]any)["key"] == "content" {
+				out = append(out, text)
+			}
+		}
+		return out
+	}
+	should, must := terms("should"), terms("must")
+
+	var out []qdrantPoint
+	for _, p := range f.points {
+		content := strings.ToLower(p.Payload["content"].(string))
+		ok := len(should) == 0
+		for _, t := range should {
+			if strings.Contains(content, t) {
+				ok = true
+			}
+		}
+		for _, t := range must {
+			if !strings.Contains(content, t) {
+				ok = false
+			}
+		}
+		if ok {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func TestVectorStore_HybridSearch(t *testing.T) {
+	fake := &fakeQdrant{points: []qdrantPoint{
+		{ID: "1", Score: 0.9, Payload: map[string]any{"content": "Deployments roll out gradually.", "source_type": "text", "page_title": "Deploy"}},
+		{ID: "2", Score: 0.8, Payload: map[string]any{"content": "Pods restart on probe failure.", "source_type": "text"}},
+		{ID: "3", Score: 0.1, Payload: map[string]any{"content": "Host db-7.prod returns error E1234 when disk is full.", "source_type": "text"}},
+	}}
+	srv := httptest.NewServer(fake.handler())
+	defer srv.Close()
+
+	store := NewVectorStore(srv.URL, "wiki")
+
+	// Vector-only search keeps cosine order
+	docs, err := store.Search(context.Background(), SearchQuery{Vector: []float32{1}, Limit: 2})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(docs) != 3 || docs[0].ID != "1" || docs[0].Metadata["page_title"] != "Deploy" {
+		t.Errorf("vector Search() = %+v, want points in server order with metadata", docs)
+	}
+
+	// Hybrid search lifts the exact-identifier match to the top
+	docs, err = store.Search(context.Background(), SearchQuery{Vector: []float32{1}, Text: "error E1234", Limit: 2})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("hybrid Search() = %d docs, want 2", len(docs))
+	}
+	if docs[0].ID != "3" {
+		t.Errorf("hybrid top = %q, want 3 (keyword match)", docs[0].ID)
+	}
+}
+
+func TestVectorStore_KeywordSearchRareTermsFirst(t *testing.T) {
+	fake := &fakeQdrant{}
+	for i := range 2 * keywordCandidates {
+		fake.points = append(fake.points, qdrantPoint{ID: float64(i), Payload: map[string]any{"content": "disk usage is fine"}})
+	}
+	fake.points = append(fake.points, qdrantPoint{ID: "rare", Payload: map[string]any{"content": "disk full: error e1234"}})
+	srv := httptest.NewServer(fake.handler())
+	defer srv.Close()
+	store := NewVectorStore(srv.URL, "wiki")
+	ctx := context.Background()
+
+	docs, err := store.keywordSearch(ctx, "disk E1234", 5, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) == 0 || docs[0].ID != "rare" {
+		t.Fatalf("keywordSearch() top = %+v, want the only point with e1234, beyond the first %d disk matches", docs, keywordCandidates)
+	}
+
+	// Term statistics are counted once, until a write
+	counts := func() int {
+		n := 0
+		for _, r := range fake.requests {
+			if strings.HasSuffix(r, "/points/count") {
+				n++
+			}
+		}
+		return n
+	}
+	if n := counts(); n != 2 {
+		t.Errorf("first search made %d count requests, want 2", n)
+	}
+	store.keywordSearch(ctx, "disk e1234 full", 5, nil, false)
+	if n := counts(); n != 3 {
+		t.Errorf("after a second search: %d count requests, want 3 (only full is new)", n)
+	}
+	store.Upsert(ctx, []Document{{ID: "1", Content: "x", Vector: []float32{1}}})
+	store.keywordSearch(ctx, "disk e1234", 5, nil, false)
+	if n := counts(); n != 5 {
+		t.Errorf("after a write: %d count requests, want 5", n)
+	}
+}
+
+func TestVectorStore_EnsureCollectionCreatesTextIndex(t *testing.T) {
+	var paths []string
+	exists := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		if r.Method == "GET" && !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"result":true}`))
+	}))
+	defer srv.Close()
+
+	if err := NewVectorStore(srv.URL, "wiki").EnsureCollection(context.Background(), 768); err != nil {
+		t.Fatalf("EnsureCollection() error = %v", err)
+	}
+	want := []string{"GET /collections/wiki", "PUT /collections/wiki", "PUT /collections/wiki/index"}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("requests = %v, want %v", paths, want)
+	}
+
+	// An existing collection, maybe from before hybrid search, gets the index too
+	paths, exists = nil, true
+	if err := NewVectorStore(srv.URL, "wiki").EnsureCollection(context.Background(), 768); err != nil {
+		t.Fatalf("EnsureCollection() error = %v", err)
+	}
+	want = []string{"GET /collections/wiki", "PUT /collections/wiki/index"}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("requests for an existing collection = %v, want %v", paths, want)
+	}
+}
+
+func TestVectorStore_SearchFilter(t *testing.T) {
+	fake := &fakeQdrant{}
+	srv := httptest.NewServer(fake.handler())
+	defer srv.Close()
+
+	filter := &Filter{
+		SourceType:    "image",
+		Space:         "NET",
+		PageTitle:     "Topology",
+		ModifiedAfter: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	_, err := NewVectorStore(srv.URL, "wiki").Search(context.Background(), SearchQuery{Vector: []float32{1}, Limit: 5, Filter: filter})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	got, _ := json.Marshal(fake.bodies[0]["filter"])
+	want := `{"must":[{"key":"page_title","match":{"text":"Topology"}},` +
+		`{"key":"source_type","match":{"value":"image"}},` +
+		`{"key":"space","match":{"value":"NET"}},` +
+		`{"key":"last_modified","range":{"gte":"2024-01-01T00:00:00Z"}}]}`
+	if string(got) != want {
+		t.Errorf("filter = %s\nwant %s", got, want)
+	}
+}
+
+func TestNewVectorStoreWithOptions_APIKeyAndCA(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("api-key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"result":{"points_count":7}}`))
+	}))
+	defer srv.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, cert, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewVectorStoreWithOptions(srv.URL, "wiki", QdrantOptions{APIKey: "secret", CAFile: caFile})
+	if err != nil {
+		t.Fatalf("NewVectorStoreWithOptions() error = %v", err)
+	}
+	if n, err := store.Count(context.Background()); err != nil || n != 7 {
+		t.Errorf("Count() = %d, %v; want 7", n, err)
+	}
+
+	// Without the CA the self-signed certificate is rejected
+	plain, _ := NewVectorStoreWithOptions(srv.URL, "wiki", QdrantOptions{APIKey: "secret"})
+	if _, err := plain.Count(context.Background()); err == nil {
+		t.Error("Count() without CA should fail certificate verification")
+	}
+
+	if _, err := NewVectorStoreWithOptions(srv.URL, "wiki", QdrantOptions{CAFile: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Error("NewVectorStoreWithOptions() should fail for a missing CA file")
+	}
+}
+
+func TestVectorStore_Scroll(t *testing.T) {
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"result":{"points":[{"id":"a","payload":{"content":"Runbook","file_path":"ops/a.html"}}],"next_page_offset":17}}`))
+	}))
+	defer srv.Close()
+
+	page, err := NewVectorStore(srv.URL, "wiki").Scroll(context.Background(), ScrollQuery{
+		Filter: &Filter{Space: "OPS"},
+		Limit:  1,
+		Offset: "12",
+	})
+	if err != nil {
+		t.Fatalf("Scroll() error = %v", err)
+	}
+	if len(page.Docs) != 1 || page.Docs[0].Metadata["file_path"] != "ops/a.html" || page.NextOffset != "17" {
+		t.Errorf("Scroll() = %+v", page)
+	}
+	if body["offset"] != float64(12) || body["with_vector"] != false || body["filter"] == nil {
+		t.Errorf("scroll request = %v, want numeric offset, no vectors and a filter", body)
+	}
+}
+
+func TestVectorStore_Delete(t *testing.T) {
+	fake := &fakeQdrant{}
+	srv := httptest.NewServer(fake.handler())
+	defer srv.Close()
+	store := NewVectorStore(srv.URL, "wiki")
+	ctx := context.Background()
+
+	if err := store.DeleteByFilter(ctx, &Filter{FilePath: "ops/a.html"}); err != nil {
+		t.Fatalf("DeleteByFilter() error = %v", err)
+	}
+	if err := store.DeleteByIDs(ctx, []string{"6ba7b810-9dad-11d1-80b4-00c04fd430c8", "42"}); err != nil {
+		t.Fatalf("DeleteByIDs() error = %v", err)
+	}
+	if fake.requests[0] != "POST /collections/wiki/points/delete" {
+		t.Errorf("request = %s", fake.requests[0])
+	}
+
+	got, _ := json.Marshal(fake.bodies)
+	want := `[{"filter":{"must":[{"key":"file_path","match":{"value":"ops/a.html"}}]}},` +
+		`{"points":["6ba7b810-9dad-11d1-80b4-00c04fd430c8",42]}]`
+	if string(got) != want {
+		t.Errorf("bodies = %s\nwant %s", got, want)
+	}
+	if err := store.DeleteByFilter(ctx, nil); err == nil {
+		t.Error("DeleteByFilter(nil) should fail")
+	}
+}
+
+func TestVectorStore_EnsureCollectionTuning(t *testing.T) {
+	var created map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Path == "/collections/wiki" {
+			_ = json.NewDecoder(r.Body).Decode(&created)
+		}
+		w.Write([]byte(`{"result":true}`))
+	}))
+	defer srv.Close()
+
+	store, err := NewVectorStoreWithOptions(srv.URL, "wiki", QdrantOptions{Collection: QdrantCollectionConfig{
+		HNSWM:         32,
+		OnDiskPayload: true,
+		Quantization:  "scalar",
+	}})
+	if err != nil {
+		t.Fatalf("NewVectorStoreWithOptions() error = %v", err)
+	}
+	if err := store.EnsureCollection(context.Background(), 768); err != nil {
+		t.Fatalf("EnsureCollection() error = %v", err)
+	}
+
+	got, _ := json.Marshal(created)
+	want := `{"hnsw_config":{"m":32},"on_disk_payload":true,` +
+		`"quantization_config":{"scalar":{"always_ram":true,"quantile":0.99,"type":"int8"}},` +
+		`"vectors":{"distance":"Cosine","size":768}}`
+	if string(got) != want {
+		t.Errorf("create body = %s\nwant %s", got, want)
+	}
+
+	if _, err := NewVectorStoreWithOptions(srv.URL, "wiki", QdrantOptions{Collection: QdrantCollectionConfig{Quantization: "binary8"}}); err == nil {
+		t.Error("NewVectorStoreWithOptions() should reject unknown quantization")
+	}
+}
+
+func TestVectorStore_Info(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/collections/wiki":
+			w.Write([]byte(`{"result": {"status": "green", "points_count": 42}}`))
+		case "/collections/locked":
+			http.Error(w, `{"status": {"error": "Invalid api-key"}}`, http.StatusForbidden)
+		default:
+			http.Error(w, `{"status": {"error": "Not found"}}`, http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	info, err := NewVectorStore(srv.URL, "wiki").Info(context.Background())
+	if err != nil || info != (CollectionInfo{Exists: true, Status: "green", Points: 42}) {
+		t.Errorf("Info(wiki) = %+v, %v", info, err)
+	}
+	info, err = NewVectorStore(srv.URL, "missing").Info(context.Background())
+	if err != nil || info.Exists {
+		t.Errorf("Info(missing) = %+v, %v, want Exists=false without error", info, err)
+	}
+	if _, err := NewVectorStore(srv.URL, "locked").Info(context.Background()); err == nil || !strings.Contains(err.Error(), "Invalid api-key") {
+		t.Errorf("Info(locked) error = %v, want the Qdrant error", err)
+	}
+}
@@ -0,0 +1,131 @@
+package rag
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/ollama"
+)
+
+// maxSummaryInput caps the page text sent for summarization (in bytes)
+const maxSummaryInput = 12000
+
+// Summarizer writes a short summary of a page. Summaries are indexed as
+// their own documents (chunk_type "summary") so broad questions match a
+// page overview instead of a random paragraph.
+type Summarizer interface {
+	Summarize(ctx context.Context, title, text string) (string, error)
+}
+
+// LLMSummarizer summarizes pages with an Ollama model. Summaries are cached
+// by page content, so unchanged pages aren't summarized again on re-index.
+// It is safe for concurrent use.
+type LLMSummarizer struct {
+	llm       llms.Model
+	cacheFile string
+
+	mu    sync.Mutex // guards cache and the cache file
+	cache map[string]string
+}
+
+// Ensure LLMSummarizer implements Summarizer
+var _ Summarizer = (*LLMSummarizer)(nil)
+
+// NewLLMSummarizer creates a summarizer using an Ollama model. serverURL
+// may be empty for the default server; cacheFile may be empty for no cache.
+func NewLLMSummarizer(model, serverURL, cacheFile string) (*LLMSummarizer, error) {
+	opts := []ollama.Option{ollama.WithModel(model)}
+	if serverURL != "" {
+		opts = append(opts, ollama.WithServerURL(serverURL))
+	}
+	l, err := ollama.New(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ollama client: %w", err)
+	}
+	s := &LLMSummarizer{llm: l, cacheFile: cacheFile, cache: make(map[string]string)}
+	if cacheFile != "" {
+		if data, err := os.ReadFile(cacheFile); err == nil {
+			json.Unmarshal(data, &s.cache)
+		}
+	}
+	return s, nil
+}
+
+// Summarize returns a few sentences on what the page covers
+func (s *LLMSummarizer) Summarize(ctx context.Context, title, text string) (string, error) {
+	if len(text) > maxSummaryInput {
+		text = text[:maxSummaryInput]
+	}
+	sum := sha256.Sum256([]byte(title + "\x00" + text))
+	key := hex.EncodeToString(sum[:])
+
+	s.mu.Lock()
+	summary, ok := s.cache[key]
+	s.mu.Unlock()
+	if ok {
+		return summary, nil
+	}
+
+	prompt := fmt.Sprintf(`Summarize this wiki page in 2-4 sentences. Say what the page is for, which systems, services or teams it covers, and the main topics or procedures in it, so someone can tell whether the page answers their question. Reply with the summary only.
+
+Title: %s
+
+%s`, title, text)
+	summary, err := llms.GenerateFromSinglePrompt(ctx, s.llm, prompt)
+	if err != nil {
+		return "", fmt.Errorf("failed to summarize page: %w", err)
+	}
+	summary = strings.TrimSpace(summary)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cache[key] = summary
+	if s.cacheFile != "" {
+		if data, err := json.MarshalIndent(s.cache, "", "  "); err == nil {
+			os.WriteFile(s.cacheFile, data, 0644)
+		}
+	}
+	return summary, nil
+}
+
+// summaryDoc summarizes a page into a "summary" document, or returns nil
+// when summaries are off or the page has no text
+func (idx *Indexer) summaryDoc(ctx context.Context, page PageContent) (*Document, error) {
+	if idx.summarizer == nil {
+		return nil, nil
+	}
+	var sb strings.Builder
+	for _, c := range page.Chunks {
+		sb.WriteString(c.Content)
+		sb.WriteString("\n")
+	}
+	text := strings.TrimSpace(sb.String())
+	if text == "" {
+		return nil, nil
+	}
+
+	summary, err := idx.summarizer.Summarize(ctx, page.Title, text)
+	if err != nil {
+		return nil, err
+	}
+	if summary == "" {
+		return nil, nil
+	}
+	meta := pageMetadata(page, "chunk_type", "summary")
+	if lang := DetectLanguage(summary); lang != "" {
+		meta["language"] = lang
+	}
+	return &Document{
+		ID:         generateDocID(page.FilePath, "summary"),
+		Content:    fmt.Sprintf("Summary of %q: %s", page.Title, summary),
+		SourceType: "text",
+		Metadata:   meta,
+	}, nil
+}
@@ -0,0 +1,131 @@
+package rag
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tokenizer counts tokens the way an embedding model would, so chunks can be
+// sized to the model's context rather than to a byte count.
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+// ApproxTokenizer estimates WordPiece/BPE token counts without a vocabulary:
+// every word costs one token per 4 runes (at least one), and every punctuation
+// or symbol rune costs one more. It over-counts slightly for plain English,
+// which keeps chunks safely under the embedder's limit.
+type ApproxTokenizer struct{}
+
+// CountTokens returns the estimated token count of text
+func (ApproxTokenizer) CountTokens(text string) int {
+	n := 0
+	for _, word := range strings.Fields(text) {
+		letters := 0
+		for _, r := range word {
+			if unicode.IsPunct(r) || unicode.IsSymbol(r) {
+				n++
+			} else {
+				letters++
+			}
+		}
+		n += (letters + 3) / 4
+	}
+	return n
+}
+
+// ChunkTextTokens splits text into chunks of at most maxTokens tokens,
+// breaking on sentence boundaries and falling back to word boundaries for
+// sentences that are longer than the budget on their own.
+func ChunkTextTokens(content string, maxTokens int, tok Tokenizer) []string {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil
+	}
+	if tok.CountTokens(content) <= maxTokens {
+		return []string{content}
+	}
+
+	var chunks []string
+	var current []string
+	currentTokens := 0
+
+	flush := func() {
+		if len(current) > 0 {
+			chunks = append(chunks, strings.Join(current, " "))
+			current = nil
+			currentTokens = 0
+		}
+	}
+
+	for _, sentence := range splitSentences(content) {
+		n := tok.CountTokens(sentence)
+		if n > maxTokens {
+			flush()
+			chunks = append(chunks, splitWords(sentence, maxTokens, tok)...)
+			continue
+		}
+		if currentTokens+n > maxTokens {
+			flush()
+		}
+		current = append(current, sentence)
+		currentTokens += n
+	}
+	flush()
+
+	return chunks
+}
+
+// splitWords hard-wraps an over-long sentence on word boundaries
+func splitWords(sentence string, maxTokens int, tok Tokenizer) []string {
+	var chunks []string
+	var current []string
+	currentTokens := 0
+	for _, word := range strings.Fields(sentence) {
+		n := tok.CountTokens(word)
+		if currentTokens+n > maxTokens && len(current) > 0 {
+			chunks = append(chunks, strings.Join(current, " "))
+			current = nil
+			currentTokens = 0
+		}
+		current = append(current, word)
+		currentTokens += n
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, strings.Join(current, " "))
+	}
+	return chunks
+}
+
+// packChunks merges consecutive small page chunks (a heading and its short
+// paragraphs, runs of list items) until they reach minTokens, so the index
+// isn't filled with vectors for three-word fragments. Chunks are never merged
+// past maxTokens. Merged chunks of different types get the type "section".
+func packChunks(chunks []TextChunk, minTokens, maxTokens int, tok Tokenizer) []TextChunk {
+	var packed []TextChunk
+	var current *TextChunk
+	currentTokens := 0
+
+	for _, c := range chunks {
+		n := tok.CountTokens(c.Content)
+		if current != nil && (currentTokens >= minTokens || currentTokens+n > maxTokens) {
+			packed = append(packed, *current)
+			current = nil
+		}
+		if current == nil {
+			cc := c
+			current = &cc
+			currentTokens = n
+			continue
+		}
+		current.Content += "\n" + c.Content
+		if current.Type != c.Type {
+			current.Type = "section"
+		}
+		currentTokens += n
+	}
+	if current != nil {
+		packed = append(packed, *current)
+	}
+	return packed
+}
@@ -0,0 +1,74 @@
+package rag
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApproxTokenizer(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"pod", 1},
+		{"kubernetes", 3},
+		{"kubectl get pods", 4},
+		{"exit code 137.", 4},
+	}
+	for _, tt := range tests {
+		if got := (ApproxTokenizer{}).CountTokens(tt.text); got != tt.want {
+			t.Errorf("CountTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestChunkTextTokens(t *testing.T) {
+	tok := ApproxTokenizer{}
+
+	if got := ChunkTextTokens("Short text.", 50, tok); len(got) != 1 {
+		t.Errorf("short text = %d chunks, want 1", len(got))
+	}
+	if got := ChunkTextTokens("   ", 50, tok); got != nil {
+		t.Errorf("empty text = %v, want nil", got)
+	}
+
+	long := strings.Repeat("The pod restarted after the probe failed. ", 20)
+	chunks := ChunkTextTokens(long, 30, tok)
+	if len(chunks) < 2 {
+		t.Fatalf("long text = %d chunks, want several", len(chunks))
+	}
+	for _, c := range chunks {
+		if n := tok.CountTokens(c); n > 30 {
+			t.Errorf("chunk has %d tokens, want <= 30: %q", n, c)
+		}
+	}
+
+	// A single sentence over budget is hard-wrapped on words
+	runOn := strings.Repeat("word ", 100)
+	for _, c := range ChunkTextTokens(runOn, 10, tok) {
+		if n := tok.CountTokens(c); n > 10 {
+			t.Errorf("wrapped chunk has %d tokens, want <= 10", n)
+		}
+	}
+}
+
+func TestPackChunks(t *testing.T) {
+	chunks := []TextChunk{
+		{Content: "Rollout", Type: "heading"},
+		{Content: "Drain the node first.", Type: "paragraph"},
+		{Content: "- cordon", Type: "list"},
+		{Content: strings.Repeat("long paragraph text ", 10), Type: "paragraph"},
+	}
+
+	packed := packChunks(chunks, 12, 40, ApproxTokenizer{})
+	if len(packed) != 2 {
+		t.Fatalf("packChunks() = %d chunks, want 2: %+v", len(packed), packed)
+	}
+	if packed[0].Type != "section" || !strings.HasPrefix(packed[0].Content, "Rollout\nDrain") {
+		t.Errorf("first chunk = %+v, want merged heading+paragraph section", packed[0])
+	}
+	if packed[1].Type != "paragraph" {
+		t.Errorf("second chunk type = %q, want paragraph (over budget, not merged)", packed[1].Type)
+	}
+}
@@ -0,0 +1,47 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrUnavailable marks errors from a vector store that answered with a
+// server error (HTTP 5xx), such as a Qdrant node that is restarting
+var ErrUnavailable = errors.New("vector store unavailable")
+
+// Unreachable reports whether err means the store couldn't be reached or is
+// failing, rather than that it rejected the request: a network error, a
+// timeout, a server error or a gRPC Unavailable status. A cancelled context
+// is neither.
+func Unreachable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, ErrUnavailable) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if s, ok := status.FromError(e); ok && s.Code() != codes.OK {
+			return s.Code() == codes.Unavailable || s.Code() == codes.DeadlineExceeded
+		}
+	}
+	return false
+}
+
+// statusError is the error for a Qdrant response other than 200 OK: its body,
+// marked ErrUnavailable for server errors
+func statusError(code int, body []byte) error {
+	if code >= 500 {
+		return fmt.Errorf("%w: HTTP %d: %s", ErrUnavailable, code, body)
+	}
+	return fmt.Errorf("%s", body)
+}
@@ -0,0 +1,48 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnreachable(t *testing.T) {
+	for _, tt := range []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New(`{"status":{"error":"Wrong input: Collection wiki not found"}}`), false},
+		{fmt.Errorf("failed to search: %w", context.Canceled), false},
+		{fmt.Errorf("failed to search: %w", context.DeadlineExceeded), true},
+		{fmt.Errorf("%w: HTTP 503: restarting", ErrUnavailable), true},
+		{fmt.Errorf("failed to search: %w", status.Error(codes.Unavailable, "connection refused")), true},
+		{status.Error(codes.NotFound, "no collection"), false},
+	} {
+		if got := Unreachable(tt.err); got != tt.want {
+			t.Errorf("Unreachable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestVectorStore_Unreachable(t *testing.T) {
+	ctx := context.Background()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "service restarting", http.StatusServiceUnavailable)
+	}))
+	store := NewVectorStore(srv.URL, "wiki")
+	if _, err := store.Search(ctx, SearchQuery{Vector: []float32{1}, Limit: 1}); !Unreachable(err) {
+		t.Errorf("Search() on HTTP 503 error = %v, want unreachable", err)
+	}
+
+	srv.Close()
+	if _, err := store.Count(ctx); !Unreachable(err) {
+		t.Errorf("Count() on a closed server error = %v, want unreachable", err)
+	}
+}
@@ -2,37 +2,100 @@ package rag
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/llms/ollama"
+	"github.com/tmc/langchaingo/llms/openai"
 )
 
-// VisionClient generates descriptions for images using LLaVA
+// VisionClient generates descriptions for images with a multimodal model:
+// LLaVA or another Ollama vision model, or an OpenAI-compatible chat API.
+// It is safe for concurrent use.
 type VisionClient struct {
-	llm       *ollama.LLM
+	llm       llms.Model
 	model     string
 	cacheFile string
-	cache     map[string]string
+	imageURLs bool // send images as data: URLs (OpenAI) rather than binary parts (Ollama)
+
+	mu    sync.Mutex // guards cache and the cache file
+	cache visionCache
+}
+
+// VisionOptions selects the vision backend
+type VisionOptions struct {
+	Provider string // "ollama" (default) or "openai" (any OpenAI-compatible chat API with image input)
+	Model    string // default: llava for ollama, gpt-4o-mini for openai
+	URL      string // Ollama server URL or OpenAI base URL (default: the provider's)
+	APIKey   string // API key for "openai"
+}
+
+// visionCache holds generated image text keyed by "<kind>:<sha256 of the
+// image>", so an edited diagram misses the cache even though its path is
+// unchanged. Paths remembers each file's last-seen hash so entries for
+// replaced content are dropped rather than kept forever.
+type visionCache struct {
+	Entries map[string]string `json:"entries"`
+	Paths   map[string]string `json:"paths"` // absolute path -> sha256
 }
 
 // NewVisionClient creates a new vision client using Ollama LLaVA
 func NewVisionClient(model string, cacheFile string) (*VisionClient, error) {
-	llm, err := ollama.New(ollama.WithModel(model))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create ollama client: %w", err)
+	return NewVisionClientWithOptions(VisionOptions{Model: model}, cacheFile)
+}
+
+// NewVisionClientWithOptions creates a vision client for the given backend
+func NewVisionClientWithOptions(opts VisionOptions, cacheFile string) (*VisionClient, error) {
+	var llm llms.Model
+	switch opts.Provider {
+	case "", "ollama":
+		if opts.Model == "" {
+			opts.Model = "llava"
+		}
+		ollamaOpts := []ollama.Option{ollama.WithModel(opts.Model)}
+		if opts.URL != "" {
+			ollamaOpts = append(ollamaOpts, ollama.WithServerURL(opts.URL))
+		}
+		l, err := ollama.New(ollamaOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create ollama client: %w", err)
+		}
+		llm = l
+	case "openai":
+		if opts.Model == "" {
+			opts.Model = "gpt-4o-mini"
+		}
+		token := opts.APIKey
+		if token == "" {
+			token = "unused" // local OpenAI-compatible servers don't check it
+		}
+		openaiOpts := []openai.Option{openai.WithModel(opts.Model), openai.WithToken(token)}
+		if opts.URL != "" {
+			openaiOpts = append(openaiOpts, openai.WithBaseURL(opts.URL))
+		}
+		l, err := openai.New(openaiOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create openai client: %w", err)
+		}
+		llm = l
+	default:
+		return nil, fmt.Errorf("unknown vision provider %q (use ollama or openai)", opts.Provider)
 	}
 
 	client := &VisionClient{
 		llm:       llm,
-		model:     model,
+		model:     opts.Model,
+		imageURLs: opts.Provider == "openai",
 		cacheFile: cacheFile,
-		cache:     make(map[string]string),
+		cache:     newVisionCache(),
 	}
 
 	// Load cache if exists
@@ -45,21 +108,122 @@ func NewVisionClient(model string, cacheFile string) (*VisionClient, error) {
 
 // DescribeImage generates a text description for an image
 func (c *VisionClient) DescribeImage(ctx context.Context, imagePath string) (string, error) {
-	// Check cache first
-	absPath, _ := filepath.Abs(imagePath)
-	if desc, ok := c.cache[absPath]; ok {
+	return c.DescribeImageAs(ctx, imagePath, ProfileGeneric)
+}
+
+// DescribeImageAs generates a description with the prompt of a vision
+// profile (see VisionPrompts); unknown profiles get the generic prompt
+func (c *VisionClient) DescribeImageAs(ctx context.Context, imagePath, profile string) (string, error) {
+	imageData, err := os.ReadFile(imagePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read image: %w", err)
+	}
+
+	prompt, ok := VisionPrompts[profile]
+	if !ok {
+		profile, prompt = ProfileGeneric, VisionPrompts[ProfileGeneric]
+	}
+
+	// Check cache first; generic descriptions keep their old key
+	kind := "describe"
+	if profile != ProfileGeneric {
+		kind += "-" + profile
+	}
+	key := c.cacheKey(kind, imagePath, imageData)
+	if desc, ok := c.lookup(key); ok {
 		return desc, nil
 	}
 
-	// Read image file
+	description, err := c.generate(ctx, imagePath, imageData, prompt)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate description: %w", err)
+	}
+
+	// Cache the result
+	c.store(key, description)
+
+	return description, nil
+}
+
+// noTextReply is what the text-extraction prompt asks for when an image has no text
+const noTextReply = "NO_TEXT"
+
+// ExtractText transcribes the text visible in an image (config screenshots,
+// dashboards, labels) verbatim, so exact terms can be found by keyword
+// search. Returns "" for images without text. Implements TextExtractor.
+func (c *VisionClient) ExtractText(ctx context.Context, imagePath string) (string, error) {
 	imageData, err := os.ReadFile(imagePath)
 	if err != nil {
 		return "", fmt.Errorf("failed to read image: %w", err)
 	}
+	key := c.cacheKey("ocr", imagePath, imageData)
+	if text, ok := c.lookup(key); ok {
+		return text, nil
+	}
 
-	// Encode as base64
-	b64Image := base64.StdEncoding.EncodeToString(imageData)
+	prompt := `Transcribe all text visible in this image exactly as written, including labels, hostnames, commands, configuration keys and values, and numbers. Keep the original line breaks. Do not describe or explain the image. If there is no text, reply with ` + noTextReply + `.`
 
+	text, err := c.generate(ctx, imagePath, imageData, prompt)
+	if err != nil {
+		return "", fmt.Errorf("failed to extract text: %w", err)
+	}
+	text = strings.TrimSpace(text)
+	if text == noTextReply {
+		text = ""
+	}
+
+	c.store(key, text)
+	return text, nil
+}
+
+// cacheKey returns the cache key for kind ("describe" or "ocr") of an image's
+// content. If the file at imagePath has changed since it was last seen, the
+// entries for its old content are dropped (unless another file shares it).
+func (c *VisionClient) cacheKey(kind, imagePath string, imageData []byte) string {
+	sum := sha256.Sum256(imageData)
+	hash := hex.EncodeToString(sum[:])
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	absPath, _ := filepath.Abs(imagePath)
+	if old, ok := c.cache.Paths[absPath]; ok && old != hash {
+		c.cache.Paths[absPath] = hash
+		shared := false
+		for _, h := range c.cache.Paths {
+			shared = shared || h == old
+		}
+		if !shared {
+			for k := range c.cache.Entries {
+				if strings.HasSuffix(k, ":"+old) {
+					delete(c.cache.Entries, k)
+				}
+			}
+		}
+		c.saveCache()
+	}
+	c.cache.Paths[absPath] = hash
+	return kind + ":" + hash
+}
+
+// lookup returns a cached entry
+func (c *VisionClient) lookup(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	text, ok := c.cache.Entries[key]
+	return text, ok
+}
+
+// store caches an entry and saves the cache file
+func (c *VisionClient) store(key, text string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache.Entries[key] = text
+	c.saveCache()
+}
+
+// generate sends an image and a prompt to the vision model
+func (c *VisionClient) generate(ctx context.Context, imagePath string, imageData []byte, prompt string) (string, error) {
 	// Determine MIME type
 	ext := strings.ToLower(filepath.Ext(imagePath))
 	mimeType := "image/png"
@@ -72,21 +236,12 @@ func (c *VisionClient) DescribeImage(ctx context.Context, imagePath string) (str
 		mimeType = "image/webp"
 	}
 
-	// Create prompt for image description
-	prompt := `Describe this diagram or image in detail. Focus on:
-1. What type of diagram/image it is (architecture diagram, flowchart, screenshot, etc.)
-2. The main components or elements shown
-3. The relationships or connections between components
-4. Any text or labels visible
-5. The overall purpose or what it's trying to communicate
-
-Provide a clear, comprehensive description that would allow someone to understand the image without seeing it.`
-
 	// Create message with image
-	content := []llms.ContentPart{
-		llms.BinaryPart(mimeType, imageData),
-		llms.TextPart(prompt),
+	var image llms.ContentPart = llms.BinaryPart(mimeType, imageData)
+	if c.imageURLs {
+		image = llms.ImageURLPart("data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(imageData))
 	}
+	content := []llms.ContentPart{image, llms.TextPart(prompt)}
 
 	// Send to LLM
 	resp, err := c.llm.GenerateContent(ctx, []llms.MessageContent{
@@ -96,23 +251,13 @@ Provide a clear, comprehensive description that would allow someone to understan
 		},
 	})
 	if err != nil {
-		return "", fmt.Errorf("failed to generate description: %w", err)
+		return "", err
 	}
 
 	if len(resp.Choices) == 0 {
 		return "", fmt.Errorf("no response from vision model")
 	}
-
-	description := resp.Choices[0].Content
-
-	// Cache the result
-	c.cache[absPath] = description
-	c.saveCache()
-
-	// Also return base64 for reference (not used in embedding, just for debugging)
-	_ = b64Image
-
-	return description, nil
+	return resp.Choices[0].Content, nil
 }
 
 // loadCache loads the description cache from file
@@ -126,10 +271,22 @@ func (c *VisionClient) loadCache() {
 		return // File doesn't exist yet
 	}
 
-	json.Unmarshal(data, &c.cache)
+	// Caches from before content hashing (path -> description) have no
+	// "entries" and are discarded: their descriptions may be stale
+	var cache visionCache
+	if json.Unmarshal(data, &cache) == nil && cache.Entries != nil {
+		if cache.Paths == nil {
+			cache.Paths = make(map[string]string)
+		}
+		c.cache = cache
+	}
+}
+
+func newVisionCache() visionCache {
+	return visionCache{Entries: make(map[string]string), Paths: make(map[string]string)}
 }
 
-// saveCache saves the description cache to file
+// saveCache saves the description cache to file. The caller holds c.mu.
 func (c *VisionClient) saveCache() {
 	if c.cacheFile == "" {
 		return
@@ -145,7 +302,9 @@ func (c *VisionClient) saveCache() {
 
 // ClearCache clears the description cache
 func (c *VisionClient) ClearCache() {
-	c.cache = make(map[string]string)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache = newVisionCache()
 	if c.cacheFile != "" {
 		os.Remove(c.cacheFile)
 	}
@@ -0,0 +1,192 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Vision profiles: the kinds of image that get their own description prompt
+const (
+	ProfileGeneric    = ""
+	ProfileDiagram    = "diagram"    // architecture, network and flow diagrams
+	ProfileScreenshot = "screenshot" // application, console and settings screens
+	ProfileChart      = "chart"      // graphs and monitoring dashboards
+)
+
+// genericPrompt is the description prompt for images of unknown kind
+const genericPrompt = `Describe this diagram or image in detail. Focus on:
+1. What type of diagram/image it is (architecture diagram, flowchart, screenshot, etc.)
+2. The main components or elements shown
+3. The relationships or connections between components
+4. Any text or labels visible
+5. The overall purpose or what it's trying to communicate
+
+Provide a clear, comprehensive description that would allow someone to understand the image without seeing it.`
+
+// VisionPrompts are the description prompts of the vision profiles. The
+// generic one is used for images the profiles don't cover.
+var VisionPrompts = map[string]string{
+	ProfileGeneric: genericPrompt,
+	ProfileDiagram: `This is an architecture, network or flow diagram. Describe it so an engineer can answer questions about the system without seeing it:
+1. Every component (services, hosts, databases, queues, load balancers, external systems), with its label exactly as written
+2. Every connection: which component talks to which, in which direction, and any protocol, port or label on the line
+3. Groupings such as networks, subnets, zones, regions, clusters or namespaces, and which components sit in each
+4. For flowcharts and sequences, the steps in order, including decisions and their branches
+5. What the diagram as a whole shows`,
+	ProfileScreenshot: `This is a screenshot of an application, console, terminal or settings screen. Describe it so someone can find and repeat what it shows:
+1. Which application or tool and which page, tab or dialog it is
+2. Every visible field, setting, option or column, with its value exactly as written
+3. Any error, warning or status message, quoted verbatim
+4. Commands, paths, hostnames, URLs and identifiers, quoted verbatim
+5. Anything highlighted, selected or pointed at, and what the screenshot is meant to show`,
+	ProfileChart: `This is a chart, graph or monitoring dashboard. Describe the data so questions about it can be answered without seeing it:
+1. The chart type and title; for a dashboard, each panel in turn
+2. The axes with their units, and the time range shown
+3. Each series or legend entry and what it measures
+4. Notable values: current levels, peaks, dips, thresholds or alert lines, with numbers where readable
+5. Trends and anomalies, and when they happen`,
+}
+
+// Vision profile modes (IndexerConfig.VisionProfiles)
+const (
+	ProfilesOff       = ""          // every image gets the generic prompt
+	ProfilesHeuristic = "heuristic" // pick a profile from the image's file name and alt text
+	ProfilesClassify  = "classify"  // heuristics, then ask the vision model about undecided images
+)
+
+// profileWords are file name and alt text words that suggest a profile
+var profileWords = map[string][]string{
+	ProfileDiagram: {
+		"arch", "architecture", "diagram", "topology", "flow", "flowchart", "sequence",
+		"network", "infra", "infrastructure", "components", "component",
+		"drawio", "gliffy", "lucidchart", "uml", "erd", "c4", "dataflow", "workflow",
+	},
+	ProfileScreenshot: {
+		"screenshot", "screenshots", "screen", "screencap", "ui", "console", "terminal",
+		"settings", "config", "configuration", "dialog",
+	},
+	ProfileChart: {
+		"chart", "graph", "graphs", "dashboard", "dashboards", "grafana", "kibana", "datadog",
+		"metrics", "latency", "throughput", "cpu", "trend", "histogram",
+		"plot", "panel", "utilization", "capacity", "p99", "p95", "qps", "rps",
+	},
+}
+
+// pastedImageRe matches the names Confluence and screenshot tools give
+// pasted images (image-20240102-101112.png, image2019-3-4_5-6-7.png, Screen
+// Shot 2020-..., Screenshot_2021...), which are nearly always screenshots
+var pastedImageRe = regexp.MustCompile(`(?i)^(image-?\d{4}|screen[ _-]?shot|screenshot|scr\d)`)
+
+// ClassifyImage picks the vision profile of an image from its file name and
+// alt text: the profile with the most matching words, or ProfileGeneric when
+// none matches or there is a tie
+func ClassifyImage(path, alt string) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	words := strings.FieldsFunc(strings.ToLower(name+" "+alt), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+	scores := map[string]int{}
+	for _, w := range words {
+		for profile, list := range profileWords {
+			for _, pw := range list {
+				if w == pw {
+					scores[profile]++
+				}
+			}
+		}
+	}
+	best, bestScore, tie := ProfileGeneric, 0, false
+	for _, profile := range []string{ProfileDiagram, ProfileScreenshot, ProfileChart} {
+		switch s := scores[profile]; {
+		case s > bestScore:
+			best, bestScore, tie = profile, s, false
+		case s == bestScore && s > 0:
+			tie = true
+		}
+	}
+	if tie {
+		return ProfileGeneric
+	}
+	if best == ProfileGeneric && pastedImageRe.MatchString(name) {
+		return ProfileScreenshot
+	}
+	return best
+}
+
+// classifyPrompt asks the vision model for an image's profile in one word
+const classifyPrompt = `Which one word best describes this image: diagram (architecture, network or flow diagram), screenshot (of an application, console or settings screen), chart (graph or monitoring dashboard) or other? Reply with the word only.`
+
+// ClassifyImage asks the vision model which profile an image needs, for
+// images the file name heuristics can't place. The short prompt and one-word
+// reply cost far less than a description. Answers are cached like
+// descriptions.
+func (c *VisionClient) ClassifyImage(ctx context.Context, imagePath string) (string, error) {
+	imageData, err := os.ReadFile(imagePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read image: %w", err)
+	}
+	key := c.cacheKey("classify", imagePath, imageData)
+	if profile, ok := c.lookup(key); ok {
+		return profile, nil
+	}
+	reply, err := c.generate(ctx, imagePath, imageData, classifyPrompt)
+	if err != nil {
+		return "", fmt.Errorf("failed to classify image: %w", err)
+	}
+	profile := parseProfile(reply)
+	c.store(key, profile)
+	return profile, nil
+}
+
+// parseProfile reads the profile from a classification reply, which small
+// models don't always keep to one word
+func parseProfile(reply string) string {
+	for _, w := range strings.Fields(strings.ToLower(reply)) {
+		switch w = strings.Trim(w, ".,:;\"'*`"); w {
+		case ProfileDiagram, ProfileScreenshot, ProfileChart:
+			return w
+		case "dashboard", "graph":
+			return ProfileChart
+		}
+	}
+	return ProfileGeneric
+}
+
+// profiledDescriber describes images with a profile's prompt;
+// *VisionClient implements it
+type profiledDescriber interface {
+	DescribeImageAs(ctx context.Context, imagePath, profile string) (string, error)
+	ClassifyImage(ctx context.Context, imagePath string) (string, error)
+}
+
+// imageProfile picks the vision profile for img under VisionProfiles
+func (idx *Indexer) imageProfile(ctx context.Context, img ImageRef) string {
+	if idx.config.VisionProfiles == ProfilesOff {
+		return ProfileGeneric
+	}
+	d, ok := idx.vision.(profiledDescriber)
+	if !ok {
+		return ProfileGeneric
+	}
+	profile := ClassifyImage(img.FullPath, img.Alt)
+	if profile == ProfileGeneric && idx.config.VisionProfiles == ProfilesClassify {
+		var err error
+		if profile, err = d.ClassifyImage(ctx, img.FullPath); err != nil {
+			fmt.Fprintf(idx.out, "  Warning: failed to classify image %s: %v\n", img.FullPath, err)
+			return ProfileGeneric
+		}
+	}
+	return profile
+}
+
+// describeImage describes img with the prompt of its profile
+func (idx *Indexer) describeImage(ctx context.Context, img ImageRef, profile string) (string, error) {
+	if d, ok := idx.vision.(profiledDescriber); ok && profile != ProfileGeneric {
+		return d.DescribeImageAs(ctx, img.FullPath, profile)
+	}
+	return idx.vision.DescribeImage(ctx, img.FullPath)
+}
@@ -0,0 +1,157 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestClassifyImage(t *testing.T) {
+	for _, tt := range []struct{ path, alt, want string }{
+		{"attachments/1/network-topology.png", "", ProfileDiagram},
+		{"payments.drawio.png", "", ProfileDiagram},
+		{"q3.png", "Grafana dashboard: API latency", ProfileChart},
+		{"cpu_usage_graph.png", "", ProfileChart},
+		{"jenkins.png", "Jenkins settings screen", ProfileScreenshot},
+		{"attachments/9/image-20240102-101112.png", "", ProfileScreenshot},
+		{"Screen Shot 2020-03-04 at 10.11.12.png", "", ProfileScreenshot},
+		{"image2019-3-4_5-6-7.png", "deploy flow", ProfileDiagram}, // words beat the pasted-image name
+		{"logo.png", "", ProfileGeneric},
+		{"arch.png", "console graph", ProfileGeneric}, // one each: a tie
+	} {
+		if got := ClassifyImage(tt.path, tt.alt); got != tt.want {
+			t.Errorf("ClassifyImage(%q, %q) = %q, want %q", tt.path, tt.alt, got, tt.want)
+		}
+	}
+}
+
+func TestParseProfile(t *testing.T) {
+	for reply, want := range map[string]string{
+		"chart":                    ProfileChart,
+		"Diagram.":                 ProfileDiagram,
+		"**Screenshot**":           ProfileScreenshot,
+		"It is a dashboard":        ProfileChart,
+		"other":                    ProfileGeneric,
+		"a photo of a server rack": ProfileGeneric,
+	} {
+		if got := parseProfile(reply); got != want {
+			t.Errorf("parseProfile(%q) = %q, want %q", reply, got, want)
+		}
+	}
+}
+
+func TestIndexer_VisionProfiles(t *testing.T) {
+	// The vision model answers classification questions with "Chart." and
+	// descriptions with the first line of the prompt it got
+	var mu sync.Mutex
+	var classified []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Content []struct {
+					Type string `json:"type"`
+					Text string `json:"text"`
+				} `json:"content"`
+			} `json:"messages"`
+		}
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &req)
+		var prompt string
+		for _, part := range req.Messages[0].Content {
+			if part.Type == "text" {
+				prompt = part.Text
+			}
+		}
+		reply := strings.SplitN(prompt, "\n", 2)[0]
+		if prompt == classifyPrompt {
+			mu.Lock()
+			classified = append(classified, prompt)
+			mu.Unlock()
+			reply = "Chart."
+		}
+		out, _ := json.Marshal(reply)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"id":"1","object":"chat.completion","model":"m","choices":[{"index":0,"message":{"role":"assistant","content":`+string(out)+`},"finish_reason":"stop"}]}`)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	page := `<html><head><title>Payments</title></head><body>
+<p>The payments service handles card transactions for every region.</p>
+<img src="payments-architecture.png" alt="">
+<img src="image-20240102-101112.png" alt="">
+<img src="q3.png" alt="">
+</body></html>`
+	os.WriteFile(filepath.Join(dir, "payments.html"), []byte(page), 0644)
+	for _, img := range []string{"payments-architecture.png", "image-20240102-101112.png", "q3.png"} {
+		os.WriteFile(filepath.Join(dir, img), []byte(img), 0644)
+	}
+
+	for _, tt := range []struct {
+		mode        string
+		want        map[string]string // image -> start of description
+		kinds       map[string]string // image -> image_kind
+		classifyReq int
+	}{
+		{
+			mode: ProfilesOff,
+			want: map[string]string{"payments-architecture.png": "Describe this diagram", "image-20240102-101112.png": "Describe this diagram", "q3.png": "Describe this diagram"},
+		},
+		{
+			mode:  ProfilesHeuristic,
+			want:  map[string]string{"payments-architecture.png": "This is an architecture", "image-20240102-101112.png": "This is a screenshot", "q3.png": "Describe this diagram"},
+			kinds: map[string]string{"payments-architecture.png": "diagram", "image-20240102-101112.png": "screenshot"},
+		},
+		{
+			mode:        ProfilesClassify,
+			want:        map[string]string{"payments-architecture.png": "This is an architecture", "image-20240102-101112.png": "This is a screenshot", "q3.png": "This is a chart"},
+			kinds:       map[string]string{"payments-architecture.png": "diagram", "image-20240102-101112.png": "screenshot", "q3.png": "chart"},
+			classifyReq: 1,
+		},
+	} {
+		classified = nil
+		os.Remove(filepath.Join(dir, ".vision_cache.json"))
+		store := NewMemoryStore()
+		config := DefaultConfig()
+		config.WikiPath = dir
+		config.Store = store
+		config.Embedder = &fakeEmbedder{}
+		config.VisionProvider = "openai"
+		config.VisionURL = srv.URL
+		config.VisionProfiles = tt.mode
+		idx, err := NewIndexer(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := idx.Index(context.Background()); err != nil {
+			t.Fatalf("%s: Index() error = %v", tt.mode, err)
+		}
+		result, _ := store.Scroll(context.Background(), ScrollQuery{Filter: &Filter{SourceType: "image"}})
+		if len(result.Docs) != 3 {
+			t.Fatalf("%s: %d image docs, want 3", tt.mode, len(result.Docs))
+		}
+		for _, d := range result.Docs {
+			name := filepath.Base(d.ImagePath)
+			if !strings.HasPrefix(d.Content, tt.want[name]) {
+				t.Errorf("%s: %s described as %q, want %q", tt.mode, name, d.Content, tt.want[name])
+			}
+			if d.Metadata["image_kind"] != tt.kinds[name] {
+				t.Errorf("%s: %s image_kind = %q, want %q", tt.mode, name, d.Metadata["image_kind"], tt.kinds[name])
+			}
+		}
+		if len(classified) != tt.classifyReq {
+			t.Errorf("%s: %d classification requests, want %d", tt.mode, len(classified), tt.classifyReq)
+		}
+	}
+
+	if _, err := NewIndexer(IndexerConfig{VisionProfiles: "ml", Store: NewMemoryStore(), Embedder: &fakeEmbedder{}}); err == nil || !strings.Contains(err.Error(), "unknown vision profile mode") {
+		t.Errorf("NewIndexer(VisionProfiles: ml) error = %v", err)
+	}
+}
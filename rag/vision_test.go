@@ -0,0 +1,96 @@
+package rag
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVisionCache_KeyedByContent(t *testing.T) {
+	dir := t.TempDir()
+	img := filepath.Join(dir, "arch.png")
+	if err := os.WriteFile(img, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cacheFile := filepath.Join(dir, ".vision_cache.json")
+	c := &VisionClient{cacheFile: cacheFile, cache: newVisionCache()}
+
+	key := c.cacheKey("describe", img, []byte("v1"))
+	c.cache.Entries[key] = "three services behind a load balancer"
+	c.saveCache()
+
+	// A reloaded cache answers without calling the model (llm is nil)
+	reloaded := &VisionClient{cacheFile: cacheFile, cache: newVisionCache()}
+	reloaded.loadCache()
+	got, err := reloaded.DescribeImage(context.Background(), img)
+	if err != nil || got != "three services behind a load balancer" {
+		t.Fatalf("DescribeImage() = %q, %v; want the cached description", got, err)
+	}
+
+	// The same content under another path shares the entry
+	copyPath := filepath.Join(dir, "copy.png")
+	os.WriteFile(copyPath, []byte("v1"), 0644)
+	if k := reloaded.cacheKey("describe", copyPath, []byte("v1")); k != key {
+		t.Errorf("cacheKey() for identical content = %q, want %q", k, key)
+	}
+
+	// Editing the original keeps the entry while the copy still uses it...
+	reloaded.cacheKey("describe", img, []byte("v2"))
+	if _, ok := reloaded.cache.Entries[key]; !ok {
+		t.Error("entry dropped while another file still has that content")
+	}
+	// ...and drops it once no file has that content any more
+	reloaded.cacheKey("describe", copyPath, []byte("v2"))
+	if _, ok := reloaded.cache.Entries[key]; ok {
+		t.Error("stale entry kept after every file with that content changed")
+	}
+}
+
+func TestVisionCache_DiscardsLegacyFormat(t *testing.T) {
+	cacheFile := filepath.Join(t.TempDir(), ".vision_cache.json")
+	os.WriteFile(cacheFile, []byte(`{"/wiki/arch.png": "stale description"}`), 0644)
+
+	c := &VisionClient{cacheFile: cacheFile, cache: newVisionCache()}
+	c.loadCache()
+	if len(c.cache.Entries) != 0 || c.cache.Paths == nil {
+		t.Errorf("cache = %+v, want the path-keyed legacy cache discarded", c.cache)
+	}
+}
+
+func TestVisionClient_OpenAIBackend(t *testing.T) {
+	var sawImage bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" || r.Header.Get("Authorization") != "Bearer sk-test" {
+			t.Errorf("request %s with auth %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		sawImage = strings.Contains(string(body), "data:image/png;base64,")
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"id":"1","object":"chat.completion","model":"gpt-4o-mini","choices":[{"index":0,"message":{"role":"assistant","content":"A flowchart of the deploy pipeline."},"finish_reason":"stop"}]}`)
+	}))
+	defer srv.Close()
+
+	img := filepath.Join(t.TempDir(), "flow.png")
+	os.WriteFile(img, []byte("png bytes"), 0644)
+
+	c, err := NewVisionClientWithOptions(VisionOptions{Provider: "openai", URL: srv.URL, APIKey: "sk-test"}, "")
+	if err != nil {
+		t.Fatalf("NewVisionClientWithOptions() error = %v", err)
+	}
+	got, err := c.DescribeImage(context.Background(), img)
+	if err != nil {
+		t.Fatalf("DescribeImage() error = %v", err)
+	}
+	if got != "A flowchart of the deploy pipeline." || !sawImage {
+		t.Errorf("DescribeImage() = %q (image sent: %v)", got, sawImage)
+	}
+
+	if _, err := NewVisionClientWithOptions(VisionOptions{Provider: "bard"}, ""); err == nil {
+		t.Error("NewVisionClientWithOptions() should reject an unknown provider")
+	}
+}
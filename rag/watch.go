@@ -0,0 +1,246 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// WatchOptions controls Indexer.Watch
+type WatchOptions struct {
+	Poll     time.Duration // check export files for changes this often (0 = don't watch files)
+	Interval time.Duration // also re-index on this schedule (0 = off)
+}
+
+// fileState is what a snapshot records per file to notice edits
+type fileState struct {
+	modTime int64 // UnixNano; comparable with ==
+	size    int64
+}
+
+// Watch keeps the index in sync with a changing export until ctx is done.
+// Export files are polled rather than watched with fsnotify: inotify sees
+// nothing of changes made to a network mount from another machine (where
+// shared exports often live) and needs a watch per directory, which large
+// exports run out of, while a poll compares mtimes and sizes for little
+// cost and no new dependency. Once a change has settled for one poll, only
+// the pages whose files (or images) changed are re-indexed and pages whose
+// files disappeared are removed, so searches keep working meanwhile. On the
+// Interval schedule the same sync runs, except that Confluence API sources
+// are re-indexed as by Index (a delta sync when configured). Watch runs
+// behind the REPL or --output json, so its progress and warnings go to
+// stderr.
+func (idx *Indexer) Watch(ctx context.Context, opts WatchOptions) {
+	base := idx.snapshot()
+	known, err := idx.pagePaths()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: wiki watch disabled: %v\n", err)
+		return
+	}
+
+	var poll, interval <-chan time.Time
+	if opts.Poll > 0 && len(idx.roots()) > 0 {
+		t := time.NewTicker(opts.Poll)
+		defer t.Stop()
+		poll = t.C
+	}
+	if opts.Interval > 0 {
+		t := time.NewTicker(opts.Interval)
+		defer t.Stop()
+		interval = t.C
+	}
+	if poll == nil && interval == nil {
+		return
+	}
+
+	last := base // latest snapshot seen while waiting for changes to settle
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll:
+			current := idx.snapshot()
+			if !maps.Equal(current, last) {
+				last = current // still changing; wait for the next poll
+				continue
+			}
+			if maps.Equal(current, base) {
+				continue
+			}
+			if known, err = idx.syncFiles(ctx, base, current, known); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: wiki sync failed: %v\n", err)
+				continue
+			}
+			base = current
+		case <-interval:
+			if idx.hasAPISource() {
+				if err := idx.index(ctx, idx.loader, os.Stderr); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: scheduled wiki re-index failed: %v\n", err)
+				}
+				continue
+			}
+			current := idx.snapshot()
+			if maps.Equal(current, base) {
+				continue
+			}
+			if known, err = idx.syncFiles(ctx, base, current, known); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: scheduled wiki sync failed: %v\n", err)
+				continue
+			}
+			base, last = current, current
+		}
+	}
+}
+
+// syncFiles re-indexes the pages affected by files that differ between two
+// snapshots and drops pages that are gone. known holds the page paths of the
+// previous load; the page paths of this load are returned.
+func (idx *Indexer) syncFiles(ctx context.Context, before, after map[string]fileState, known map[string]bool) (map[string]bool, error) {
+	changed := map[string]bool{}
+	for path, state := range after {
+		if before[path] != state {
+			changed[path] = true
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changed[path] = true
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Wiki export changed (%d files), syncing index...\n", len(changed))
+
+	loader := &changedLoader{Loader: idx.loader, changed: changed}
+	if err := idx.index(ctx, loader, os.Stderr); err != nil {
+		return known, err
+	}
+	for path := range known {
+		if loader.all[path] {
+			continue
+		}
+		if err := idx.store.DeleteByFilter(ctx, &Filter{FilePath: path}); err != nil {
+			return known, fmt.Errorf("failed to remove deleted page %s: %w", path, err)
+		}
+		fmt.Fprintf(os.Stderr, "Removed deleted page: %s\n", path)
+	}
+	return loader.all, nil
+}
+
+// changedLoader loads only the pages affected by changed files, as a delta
+// sync: a page is affected if its file, or one of its images, changed. A
+// MediaWiki page is affected when its dump file changed.
+type changedLoader struct {
+	Loader
+	changed map[string]bool
+	all     map[string]bool // paths of every page loaded, changed or not
+}
+
+func (l *changedLoader) LoadAll() ([]PageContent, error) {
+	pages, err := l.Loader.LoadAll()
+	if err != nil {
+		return nil, err
+	}
+	l.all = make(map[string]bool, len(pages))
+	var affected []PageContent
+	for _, page := range pages {
+		l.all[page.FilePath] = true
+		if l.affects(page) {
+			affected = append(affected, page)
+		}
+	}
+	return affected, nil
+}
+
+func (l *changedLoader) affects(page PageContent) bool {
+	file, _, _ := strings.Cut(page.FilePath, "#")
+	if l.changed[file] {
+		return true
+	}
+	for _, img := range page.Images {
+		if l.changed[img.FullPath] {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *changedLoader) Incremental() bool { return true }
+func (l *changedLoader) CommitSync() error { return nil }
+
+// pagePaths loads the pages once to learn which exist, so pages whose files
+// are later deleted can be removed from the index
+func (idx *Indexer) pagePaths() (map[string]bool, error) {
+	if len(idx.roots()) == 0 {
+		return nil, nil
+	}
+	pages, err := idx.loader.LoadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pages: %w", err)
+	}
+	paths := make(map[string]bool, len(pages))
+	for _, page := range pages {
+		paths[page.FilePath] = true
+	}
+	return paths, nil
+}
+
+// roots returns the export paths on disk: WikiPath, or the Path of each source
+func (idx *Indexer) roots() []string {
+	var roots []string
+	if len(idx.config.Sources) == 0 && idx.config.Confluence == nil && idx.config.WikiPath != "" {
+		roots = append(roots, idx.config.WikiPath)
+	}
+	for _, src := range idx.config.Sources {
+		if src.Confluence == nil && src.Path != "" {
+			roots = append(roots, src.Path)
+		}
+	}
+	return roots
+}
+
+// hasAPISource reports whether any pages come from the Confluence API
+func (idx *Indexer) hasAPISource() bool {
+	if idx.config.Confluence != nil {
+		return true
+	}
+	for _, src := range idx.config.Sources {
+		if src.Confluence != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshot records the modification time and size of every file under the
+// export roots. Dotfiles (such as the vision cache) are skipped, since the
+// indexer writes them itself.
+func (idx *Indexer) snapshot() map[string]fileState {
+	files := map[string]fileState{}
+	for _, root := range idx.roots() {
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil // vanished mid-walk; the next snapshot settles it
+			}
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			files[path] = fileState{modTime: info.ModTime().UnixNano(), size: info.Size()}
+			return nil
+		})
+	}
+	return files
+}
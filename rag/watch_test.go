@@ -0,0 +1,81 @@
+package rag
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIndexer_WatchSyncsChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, title, body string) {
+		html := "<html><head><title>" + title + "</title></head><body><p>" + body + "</p></body></html>"
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(html), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("deploy.html", "Deploy", "To deploy the service, run the deploy pipeline and watch the rollout.")
+	write("network.html", "Network", "The network team owns the load balancer configuration for every region.")
+
+	store := NewMemoryStore()
+	config := DefaultConfig()
+	config.WikiPath = dir
+	config.ChunkTokens = 0
+	config.Store = store
+	config.Embedder = &fakeEmbedder{}
+	idx, err := NewIndexer(config)
+	if err != nil {
+		t.Fatalf("NewIndexer() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := idx.Index(ctx); err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		idx.Watch(ctx, WatchOptions{Poll: 10 * time.Millisecond})
+		close(done)
+	}()
+	time.Sleep(30 * time.Millisecond) // let Watch take its first snapshot
+
+	contents := func() string {
+		page, _ := store.Scroll(context.Background(), ScrollQuery{Limit: 100})
+		var all []string
+		for _, d := range page.Docs {
+			all = append(all, d.Content)
+		}
+		return strings.Join(all, "\n")
+	}
+	waitFor := func(what string, ok func(string) bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !ok(contents()) {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s; index has:\n%s", what, contents())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// An edited page is re-indexed; untouched pages stay
+	write("deploy.html", "Deploy", "Deploys now go through the blue/green pipeline with automatic rollback.")
+	waitFor("the edited page", func(s string) bool {
+		return strings.Contains(s, "blue/green") && !strings.Contains(s, "watch the rollout") && strings.Contains(s, "load balancer")
+	})
+
+	// A deleted page is removed
+	os.Remove(filepath.Join(dir, "network.html"))
+	waitFor("the deleted page to go", func(s string) bool { return !strings.Contains(s, "load balancer") })
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Watch() did not return after cancel")
+	}
+}
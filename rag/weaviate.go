@@ -0,0 +1,465 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// weaviateProperties are the payload fields stored as class properties and
+// returned by searches. Other metadata keys are dropped.
+var weaviateProperties = []string{
+	"content", "source_type", "image_path", "page_title", "file_path",
+	"chunk_type", "image_alt", "space", "source", "last_modified",
+	"section", "anchor", "section_index", "chunk_index", "labels", "breadcrumbs", "language",
+}
+
+// weaviateExactProperties are matched as whole values rather than words
+var weaviateExactProperties = map[string]bool{
+	"source_type": true, "chunk_type": true, "space": true, "source": true, "last_modified": true,
+	"anchor": true, "section_index": true, "chunk_index": true,
+	"file_path": true, "image_path": true, "labels": true, "language": true,
+}
+
+// WeaviateStore stores documents in a Weaviate class via the REST and
+// GraphQL APIs. Vectors are supplied by the indexer (vectorizer "none").
+// SearchQuery.Text runs Weaviate's native hybrid (BM25 + vector) search.
+type WeaviateStore struct {
+	baseURL   string
+	apiKey    string // empty for anonymous access
+	className string
+	client    *http.Client
+}
+
+// Ensure WeaviateStore implements Store
+var _ Store = (*WeaviateStore)(nil)
+
+// NewWeaviateStore creates a new Weaviate store client. Weaviate class names
+// must start with an upper-case letter, so collectionName is converted
+// (e.g. "confluence_wiki" becomes class "Confluence_wiki").
+func NewWeaviateStore(baseURL, apiKey, collectionName string) *WeaviateStore {
+	return &WeaviateStore{
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		apiKey:    apiKey,
+		className: weaviateClassName(collectionName),
+		client:    &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// weaviateClassName converts a collection name into a valid class name
+func weaviateClassName(name string) string {
+	class := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+	if class == "" || !unicode.IsLetter(rune(class[0])) {
+		return "C" + class
+	}
+	return strings.ToUpper(class[:1]) + class[1:]
+}
+
+// EnsureCollection creates the class if it doesn't exist
+func (s *WeaviateStore) EnsureCollection(ctx context.Context, vectorSize int) error {
+	status, body, err := s.do(ctx, "GET", "/v1/schema/"+s.className, nil)
+	if err != nil {
+		return fmt.Errorf("failed to check collection: %w", err)
+	}
+	if status == 200 {
+		return nil // Class exists
+	}
+
+	props := make([]map[string]any, len(weaviateProperties))
+	for i, name := range weaviateProperties {
+		tokenization := "word"
+		if weaviateExactProperties[name] {
+			tokenization = "field"
+		}
+		props[i] = map[string]any{
+			"name":         name,
+			"dataType":     []string{"text"},
+			"tokenization": tokenization,
+		}
+	}
+	classReq := map[string]any{
+		"class":             s.className,
+		"vectorizer":        "none",
+		"vectorIndexConfig": map[string]any{"distance": "cosine"},
+		"properties":        props,
+	}
+	status, body, err = s.do(ctx, "POST", "/v1/schema", classReq)
+	if err != nil {
+		return fmt.Errorf("failed to create collection: %w", err)
+	}
+	if status != 200 {
+		return fmt.Errorf("failed to create collection: %s", body)
+	}
+	return nil
+}
+
+// DeleteCollection deletes the class and its objects (for re-indexing)
+func (s *WeaviateStore) DeleteCollection(ctx context.Context) error {
+	status, body, err := s.do(ctx, "DELETE", "/v1/schema/"+s.className, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete collection: %w", err)
+	}
+	// 404 is fine - class didn't exist
+	if status != 200 && status != 404 {
+		return fmt.Errorf("failed to delete collection: %s", body)
+	}
+	return nil
+}
+
+// Upsert adds or updates documents in the store. Document IDs must be UUIDs,
+// which generateDocID guarantees.
+func (s *WeaviateStore) Upsert(ctx context.Context, docs []Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	objects := make([]map[string]any, len(docs))
+	for i, doc := range docs {
+		props := map[string]any{
+			"content":     doc.Content,
+			"source_type": doc.SourceType,
+		}
+		for k, v := range doc.Metadata {
+			props[k] = v
+		}
+		if doc.ImagePath != "" {
+			props["image_path"] = doc.ImagePath
+		}
+		objects[i] = map[string]any{
+			"class":      s.className,
+			"id":         doc.ID,
+			"vector":     doc.Vector,
+			"properties": props,
+		}
+	}
+
+	status, body, err := s.do(ctx, "POST", "/v1/batch/objects", map[string]any{"objects": objects})
+	if err != nil {
+		return fmt.Errorf("failed to upsert objects: %w", err)
+	}
+	if status != 200 {
+		return fmt.Errorf("failed to upsert objects: %s", body)
+	}
+
+	// The batch endpoint reports per-object failures with HTTP 200
+	var results []struct {
+		ID     string `json:"id"`
+		Result struct {
+			Errors *struct {
+				Error []struct {
+					Message string `json:"message"`
+				} `json:"error"`
+			} `json:"errors"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(body, &results); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	for _, r := range results {
+		if r.Result.Errors != nil && len(r.Result.Errors.Error) > 0 {
+			return fmt.Errorf("failed to upsert object %s: %s", r.ID, r.Result.Errors.Error[0].Message)
+		}
+	}
+	return nil
+}
+
+// Search finds similar documents. With q.Text set it runs Weaviate's hybrid
+// search (alpha 0.5), whose fused score becomes Document.Score.
+func (s *WeaviateStore) Search(ctx context.Context, q SearchQuery) ([]Document, error) {
+	args := map[string]any{"limit": q.Limit}
+	if q.Text != "" {
+		args["hybrid"] = map[string]any{"query": q.Text, "vector": q.Vector, "alpha": 0.5}
+	} else {
+		args["nearVector"] = map[string]any{"vector": q.Vector}
+	}
+	if where := q.Filter.weaviateWhere(); where != nil {
+		args["where"] = where
+	}
+	docs, err := s.get(ctx, args, q.WithVectors)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search: %w", err)
+	}
+	return docs, nil
+}
+
+// get runs a GraphQL Get query on the class with the given arguments
+func (s *WeaviateStore) get(ctx context.Context, args map[string]any, withVectors bool) ([]Document, error) {
+	additional := "id distance score"
+	if withVectors {
+		additional += " vector"
+	}
+	query := fmt.Sprintf("{ Get { %s(%s) { %s _additional { %s } } } }",
+		s.className, graphqlArgs(args), strings.Join(weaviateProperties, " "), additional)
+
+	var result struct {
+		Get map[string][]map[string]any `json:"Get"`
+	}
+	if err := s.graphql(ctx, query, &result); err != nil {
+		return nil, err
+	}
+
+	objects := result.Get[s.className]
+	docs := make([]Document, len(objects))
+	for i, obj := range objects {
+		p := qdrantPoint{Payload: map[string]any{}}
+		if extra, ok := obj["_additional"].(map[string]any); ok {
+			p.ID = extra["id"]
+			if score, ok := extra["score"].(string); ok && score != "" {
+				f, _ := strconv.ParseFloat(score, 32)
+				p.Score = float32(f)
+			} else if d, ok := extra["distance"].(float64); ok {
+				p.Score = float32(1 - d) // cosine distance to similarity
+			}
+			if vec, ok := extra["vector"].([]any); ok {
+				p.Vector = jsonFloats(vec)
+			}
+		}
+		for k, v := range obj {
+			if k != "_additional" && v != nil {
+				p.Payload[k] = v
+			}
+		}
+		docs[i] = p.toDocument()
+	}
+	return docs, nil
+}
+
+// Scroll pages through stored objects. Weaviate's ID cursor ("after") can't
+// be combined with a where filter, so the cursor is a numeric offset.
+func (s *WeaviateStore) Scroll(ctx context.Context, q ScrollQuery) (ScrollResult, error) {
+	if q.Limit <= 0 {
+		q.Limit = defaultScrollLimit
+	}
+	offset := 0
+	if q.Offset != "" {
+		n, err := strconv.Atoi(q.Offset)
+		if err != nil {
+			return ScrollResult{}, fmt.Errorf("invalid scroll offset %q", q.Offset)
+		}
+		offset = n
+	}
+
+	args := map[string]any{"limit": q.Limit, "offset": offset}
+	if where := q.Filter.weaviateWhere(); where != nil {
+		args["where"] = where
+	}
+	docs, err := s.get(ctx, args, false)
+	if err != nil {
+		return ScrollResult{}, fmt.Errorf("failed to scroll: %w", err)
+	}
+
+	result := ScrollResult{Docs: docs}
+	if len(docs) == q.Limit {
+		result.NextOffset = strconv.Itoa(offset + len(docs))
+	}
+	return result, nil
+}
+
+// DeleteByIDs removes the given objects
+func (s *WeaviateStore) DeleteByIDs(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return s.deleteWhere(ctx, map[string]any{
+		"path":           []string{"id"},
+		"operator":       "ContainsAny",
+		"valueTextArray": ids,
+	})
+}
+
+// DeleteByFilter removes every object matching the filter
+func (s *WeaviateStore) DeleteByFilter(ctx context.Context, f *Filter) error {
+	if f.isEmpty() {
+		return errEmptyDeleteFilter
+	}
+	return s.deleteWhere(ctx, f.weaviateWhere())
+}
+
+// deleteWhere runs a batch delete. The REST API takes the same "where"
+// structure as GraphQL, in JSON (enums become strings).
+func (s *WeaviateStore) deleteWhere(ctx context.Context, where map[string]any) error {
+	deleteReq := map[string]any{
+		"match":  map[string]any{"class": s.className, "where": where},
+		"output": "minimal",
+	}
+	status, body, err := s.do(ctx, "DELETE", "/v1/batch/objects", deleteReq)
+	if err != nil {
+		return fmt.Errorf("failed to delete objects: %w", err)
+	}
+	if status != 200 {
+		return fmt.Errorf("failed to delete objects: %s", body)
+	}
+	return nil
+}
+
+// Count returns the number of documents in the collection
+func (s *WeaviateStore) Count(ctx context.Context) (int, error) {
+	query := fmt.Sprintf("{ Aggregate { %s { meta { count } } } }", s.className)
+	var result struct {
+		Aggregate map[string][]struct {
+			Meta struct {
+				Count int `json:"count"`
+			} `json:"meta"`
+		} `json:"Aggregate"`
+	}
+	if err := s.graphql(ctx, query, &result); err != nil {
+		return 0, fmt.Errorf("failed to count objects: %w", err)
+	}
+	if agg := result.Aggregate[s.className]; len(agg) > 0 {
+		return agg[0].Meta.Count, nil
+	}
+	return 0, nil
+}
+
+// graphql runs a GraphQL query and decodes its "data" field into out
+func (s *WeaviateStore) graphql(ctx context.Context, query string, out any) error {
+	status, body, err := s.do(ctx, "POST", "/v1/graphql", map[string]any{"query": query})
+	if err != nil {
+		return err
+	}
+	if status != 200 {
+		return fmt.Errorf("weaviate returned %d: %s", status, body)
+	}
+
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("%s", result.Errors[0].Message)
+	}
+	if err := json.Unmarshal(result.Data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// do sends a JSON request and returns the status code and response body
+func (s *WeaviateStore) do(ctx context.Context, method, path string, reqBody any) (int, []byte, error) {
+	var reader io.Reader
+	if reqBody != nil {
+		body, _ := json.Marshal(reqBody)
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.baseURL+path, reader)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, bytes.TrimSpace(body), err
+}
+
+// graphqlEnum is a GraphQL enum value, written without quotes
+type graphqlEnum string
+
+// weaviateWhere converts the filter to a Weaviate "where" argument.
+// Returns nil for an empty filter.
+func (f *Filter) weaviateWhere() map[string]any {
+	if f == nil {
+		return nil
+	}
+	cond := func(path string, op graphqlEnum, value string) map[string]any {
+		return map[string]any{"path": []string{path}, "operator": op, "valueText": value}
+	}
+
+	var operands []map[string]any
+	if f.PageTitle != "" {
+		operands = append(operands, cond("page_title", "Like", "*"+f.PageTitle+"*"))
+	}
+	if f.Label != "" {
+		operands = append(operands, cond("labels", "Like", "*"+f.labelMatch()+"*"))
+	}
+	for _, kv := range [][2]string{
+		{"chunk_type", f.ChunkType},
+		{"source_type", f.SourceType},
+		{"space", f.Space},
+		{"source", f.Source},
+		{"file_path", f.FilePath},
+		{"language", f.Language},
+	} {
+		if kv[1] != "" {
+			operands = append(operands, cond(kv[0], "Equal", kv[1]))
+		}
+	}
+	if !f.ModifiedAfter.IsZero() {
+		operands = append(operands, cond("last_modified", "GreaterThanEqual", f.ModifiedAfter.UTC().Format(time.RFC3339)))
+	}
+	if !f.ModifiedBefore.IsZero() {
+		operands = append(operands, cond("last_modified", "LessThan", f.ModifiedBefore.UTC().Format(time.RFC3339)))
+	}
+
+	switch len(operands) {
+	case 0:
+		return nil
+	case 1:
+		return operands[0]
+	default:
+		return map[string]any{"operator": graphqlEnum("And"), "operands": operands}
+	}
+}
+
+// graphqlArgs renders a map as GraphQL arguments (without the surrounding
+// parentheses), with keys sorted for deterministic output
+func graphqlArgs(args map[string]any) string {
+	keys := make([]string, 0, len(args))
+	for k := range args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + ": " + graphqlValue(args[k])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// graphqlValue renders a Go value as a GraphQL input literal
+func graphqlValue(v any) string {
+	switch val := v.(type) {
+	case graphqlEnum:
+		return string(val)
+	case map[string]any:
+		return "{" + graphqlArgs(val) + "}"
+	case []map[string]any:
+		parts := make([]string, len(val))
+		for i, m := range val {
+			parts[i] = graphqlValue(m)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	default:
+		// Strings, numbers and flat slices share JSON's literal syntax
+		data, _ := json.Marshal(val)
+		return string(data)
+	}
+}
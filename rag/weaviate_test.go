@@ -0,0 +1,72 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWeaviateClassName(t *testing.T) {
+	tests := map[string]string{
+		"confluence_wiki":     "Confluence_wiki",
+		"confluence_wiki-ops": "Confluence_wiki_ops",
+		"2024docs":            "C2024docs",
+	}
+	for in, want := range tests {
+		if got := weaviateClassName(in); got != want {
+			t.Errorf("weaviateClassName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestWeaviateStore_Search(t *testing.T) {
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query string `json:"query"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		query = body.Query
+		w.Write([]byte(`{"data":{"Get":{"Wiki":[{"content":"Error E1234 means disk full","source_type":"text","page_title":"Runbook","image_path":null,"_additional":{"id":"6ba7b810-9dad-11d1-80b4-00c04fd430c8","score":"0.8","distance":null}}]}}}`))
+	}))
+	defer srv.Close()
+
+	store := NewWeaviateStore(srv.URL, "", "wiki")
+	docs, err := store.Search(context.Background(), SearchQuery{
+		Vector: []float32{0.5, 1},
+		Text:   `error "E1234"`,
+		Limit:  2,
+		Filter: &Filter{Space: "OPS", ChunkType: "code"},
+	})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	for _, want := range []string{
+		`Wiki(hybrid: {alpha: 0.5, query: "error \"E1234\"", vector: [0.5,1]}, limit: 2, where: {operands: [`,
+		`{operator: Equal, path: ["chunk_type"], valueText: "code"}`,
+		`operator: And`,
+	} {
+		if !strings.Contains(query, want) {
+			t.Errorf("query missing %s\ngot: %s", want, query)
+		}
+	}
+	if len(docs) != 1 || docs[0].Score != 0.8 || docs[0].Metadata["page_title"] != "Runbook" || docs[0].ImagePath != "" {
+		t.Errorf("Search() = %+v", docs)
+	}
+}
+
+func TestWeaviateStore_UpsertReportsObjectErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id":"x","result":{"errors":{"error":[{"message":"vector lengths don't match"}]}}}]`))
+	}))
+	defer srv.Close()
+
+	err := NewWeaviateStore(srv.URL, "key", "wiki").Upsert(context.Background(), []Document{{ID: "x"}})
+	if err == nil || !strings.Contains(err.Error(), "vector lengths") {
+		t.Errorf("Upsert() error = %v, want per-object error", err)
+	}
+}
@@ -0,0 +1,3 @@
+// Package redact masks secrets (API keys, passwords, private keys, bearer
+// tokens) in text before it reaches the LLM, logs or the terminal.
+package redact
@@ -0,0 +1,126 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Mask replaces every redacted secret
+const Mask = "[REDACTED]"
+
+// DefaultPatterns match common secrets. A pattern with a group named
+// "secret" masks only that group, keeping e.g. the "password=" before it.
+var DefaultPatterns = []string{
+	// PEM private keys, to the end of the text when the END line is cut off
+	`-----BEGIN[A-Z ]*PRIVATE KEY-----(?:[\s\S]*?-----END[A-Z ]*PRIVATE KEY-----|[\s\S]*)`,
+	// Authorization headers
+	`(?i)\bbearer[ \t]+(?P<secret>[A-Za-z0-9\-._~+/]{8,}=*)`,
+	`(?i)\bauthorization:[ \t]*basic[ \t]+(?P<secret>[A-Za-z0-9+/]{8,}=*)`,
+	// password=..., "api_key": "...", DB_PASSWORD: ..., aws_secret_access_key = ...
+	`(?i)[A-Za-z0-9_.-]*(?:password|passwd|secret|token|api[_-]?key|access[_-]?key|private[_-]?key|credentials?)["']?[ \t]*[:=][ \t]*["']?(?P<secret>[^\s"',;&=:][^\s"',;&]*)`,
+	// user:password@ in URLs
+	`[A-Za-z][A-Za-z0-9+.-]*://[^/\s:@]+:(?P<secret>[^/\s@]+)@`,
+	// JWTs
+	`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]+`,
+	// Provider keys: AWS, GitHub, Slack, Google, OpenAI/Anthropic
+	`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+	`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`,
+	`\bxox[abprs]-[A-Za-z0-9-]{10,}`,
+	`\bAIza[0-9A-Za-z_-]{35}`,
+	`\bsk-(?:ant-|proj-)?[A-Za-z0-9_-]{20,}`,
+}
+
+// DefaultAllow are values the patterns match that aren't secrets:
+// placeholders, environment references and booleans
+var DefaultAllow = []string{
+	`(?i)null|none|nil|true|false|changeme`,
+	`\*+|x+|<[^>]*>`,
+	`\$\{?[A-Za-z_][A-Za-z0-9_]*\}?`,
+}
+
+// Config selects the patterns a Redactor masks
+type Config struct {
+	// Patterns are regular expressions to mask besides DefaultPatterns
+	Patterns []string
+	// Allow are regular expressions for values to leave alone, besides
+	// DefaultAllow. One must match a whole secret, e.g. "test-token-[0-9]+".
+	Allow []string
+	// NoDefaults drops DefaultPatterns and DefaultAllow
+	NoDefaults bool
+}
+
+// Redactor masks secrets in text. A nil Redactor masks nothing.
+type Redactor struct {
+	patterns []*regexp.Regexp
+	allow    []*regexp.Regexp
+}
+
+// New compiles the patterns of cfg
+func New(cfg Config) (*Redactor, error) {
+	patterns, allow := cfg.Patterns, cfg.Allow
+	if !cfg.NoDefaults {
+		patterns = append(append([]string{}, DefaultPatterns...), patterns...)
+		allow = append(append([]string{}, DefaultAllow...), allow...)
+	}
+	allow = append(allow, regexp.QuoteMeta(Mask)) // redacting twice changes nothing
+
+	r := &Redactor{}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	for _, p := range allow {
+		re, err := regexp.Compile(`^(?:` + p + `)$`)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact allow pattern %q: %w", p, err)
+		}
+		r.allow = append(r.allow, re)
+	}
+	return r, nil
+}
+
+// Redact returns s with every secret replaced by Mask
+func (r *Redactor) Redact(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, re := range r.patterns {
+		s = r.replace(re, s)
+	}
+	return s
+}
+
+// replace masks the matches of re in s, or their "secret" group
+func (r *Redactor) replace(re *regexp.Regexp, s string) string {
+	group := max(re.SubexpIndex("secret"), 0)
+	var b strings.Builder
+	last := 0
+	for _, m := range re.FindAllStringSubmatchIndex(s, -1) {
+		start, end := m[2*group], m[2*group+1]
+		if start < 0 || start == end || r.allowed(s[start:end]) {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(Mask)
+		last = end
+	}
+	if b.Len() == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// allowed reports whether secret is on the allowlist
+func (r *Redactor) allowed(secret string) bool {
+	for _, re := range r.allow {
+		if re.MatchString(secret) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rathore/langchain-agent/llm"
+	"github.com/rathore/langchain-agent/tools"
+)
+
+// Cassette is a recorded conversation: the agent's tools and its runs
+type Cassette struct {
+	Tools []ToolSpec `json:"tools"`
+	Runs  []Run      `json:"runs"`
+}
+
+// ToolSpec is a tool as the LLM saw it, with its registry metadata
+type ToolSpec struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters"`
+	Category    string         `json:"category,omitempty"`
+	ReadOnly    bool           `json:"read_only,omitempty"`
+	Risk        tools.Risk     `json:"risk,omitempty"`
+	Latency     time.Duration  `json:"latency_ns,omitempty"`
+	Aliases     []string       `json:"aliases,omitempty"`
+
+	ReadOnlyWhen *tools.ReadOnlyCalls `json:"read_only_when,omitempty"`
+}
+
+// Run is one query and every LLM exchange it took
+type Run struct {
+	Query   string `json:"query"`
+	Fresh   bool   `json:"fresh,omitempty"`   // started without history
+	Context string `json:"context,omitempty"` // auto-RAG results
+	Steps   []Step `json:"steps"`
+	Answer  string `json:"answer,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Step is one LLM exchange and the tool it called. Error is set instead of
+// Response if the LLM call failed.
+type Step struct {
+	Request  []llm.Message `json:"request"`
+	Response string        `json:"response"`
+	Error    string        `json:"error,omitempty"`
+	ToolCall *ToolCall     `json:"tool_call,omitempty"`
+}
+
+// ToolCall is a tool's input and output; Error is set if the call failed
+type ToolCall struct {
+	Name   string         `json:"name"`
+	Params map[string]any `json:"params"`
+	Result string         `json:"result"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// Load reads a cassette file
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette as indented JSON, replacing path atomically
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// Diff describes how got differs from want, or returns "" if they match
+func Diff(want, got *Cassette) string {
+	var b strings.Builder
+	diffText(&b, "tools", toJSON(want.Tools), toJSON(got.Tools))
+	if len(want.Runs) != len(got.Runs) {
+		fmt.Fprintf(&b, "runs: want %d, got %d\n", len(want.Runs), len(got.Runs))
+	}
+	for i := range min(len(want.Runs), len(got.Runs)) {
+		w, g := want.Runs[i], got.Runs[i]
+		run := fmt.Sprintf("run %d (%q)", i+1, w.Query)
+		if len(w.Steps) != len(g.Steps) {
+			fmt.Fprintf(&b, "%s: want %d steps, got %d\n", run, len(w.Steps), len(g.Steps))
+		}
+		for j := range min(len(w.Steps), len(g.Steps)) {
+			ws, gs := w.Steps[j], g.Steps[j]
+			step := fmt.Sprintf("%s step %d", run, j+1)
+			if len(ws.Request) != len(gs.Request) {
+				fmt.Fprintf(&b, "%s: want %d request messages, got %d\n", step, len(ws.Request), len(gs.Request))
+			}
+			for k := range min(len(ws.Request), len(gs.Request)) {
+				wm, gm := ws.Request[k], gs.Request[k]
+				diffText(&b, fmt.Sprintf("%s request message %d (%s)", step, k+1, wm.Role),
+					wm.Role+": "+wm.Content, gm.Role+": "+gm.Content)
+			}
+			diffText(&b, step+" response", ws.Response, gs.Response)
+			diffText(&b, step+" error", ws.Error, gs.Error)
+			diffText(&b, step+" tool call", toJSON(ws.ToolCall), toJSON(gs.ToolCall))
+		}
+		diffText(&b, run+" answer", w.Answer, g.Answer)
+		diffText(&b, run+" error", w.Error, g.Error)
+	}
+	return b.String()
+}
+
+// diffText writes the lines where want and got differ, leaving out the
+// lines they share before and after
+func diffText(b *strings.Builder, where, want, got string) {
+	if want == got {
+		return
+	}
+	wl, gl := strings.Split(want, "\n"), strings.Split(got, "\n")
+	p := 0
+	for p < len(wl) && p < len(gl) && wl[p] == gl[p] {
+		p++
+	}
+	s := 0
+	for s < len(wl)-p && s < len(gl)-p && wl[len(wl)-1-s] == gl[len(gl)-1-s] {
+		s++
+	}
+	fmt.Fprintf(b, "%s, line %d:\n", where, p+1)
+	for _, l := range wl[p : len(wl)-s] {
+		fmt.Fprintf(b, "  - %s\n", l)
+	}
+	for _, l := range gl[p : len(gl)-s] {
+		fmt.Fprintf(b, "  + %s\n", l)
+	}
+}
+
+// toJSON renders v for comparison, one field per line
+func toJSON(v any) string {
+	data, _ := json.MarshalIndent(v, "", "  ")
+	return string(data)
+}
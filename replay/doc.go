@@ -0,0 +1,21 @@
+// Package replay records agent runs to golden files ("cassettes") and
+// replays them with the LLM's recorded responses and mocked tools, so a
+// change to the system prompt, the tool descriptions or the response parser
+// shows up as a diff of what the agent sends the LLM.
+//
+// Recording wraps the agent's client:
+//
+//	rec := replay.NewRecorder(client)
+//	ag, err := agent.New(agent.Config{Client: rec, Registry: registry})
+//	result, err := ag.RunDetailed(ctx, query)
+//	rec.AddRun(result, err)
+//	err = rec.Cassette(ag.Registry()).Save("testdata/disk.json")
+//
+// and a test replays it against the current code:
+//
+//	want, err := replay.Load("testdata/disk.json")
+//	got, err := replay.Replay(ctx, want, agent.Config{Registry: registry})
+//	if diff := replay.Diff(want, got); diff != "" {
+//		t.Errorf("replay differs:\n%s", diff)
+//	}
+package replay
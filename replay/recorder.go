@@ -0,0 +1,143 @@
+package replay
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rathore/langchain-agent/agent"
+	"github.com/rathore/langchain-agent/llm"
+	"github.com/rathore/langchain-agent/redact"
+	"github.com/rathore/langchain-agent/tools"
+)
+
+// Recorder is an llm.ChatClient that records every exchange with the
+// client it wraps. AddRun files the exchanges since the last run under the
+// run that made them.
+type Recorder struct {
+	client llm.ChatClient
+
+	// Redactor masks recorded auto-RAG context as the agent's does; tool
+	// results are recorded after the agent's redaction
+	Redactor *redact.Redactor
+
+	mu      sync.Mutex
+	runs    []Run
+	pending []Step // exchanges of the run in progress
+	context string // auto-RAG results of the run in progress
+}
+
+// Ensure Recorder keeps the agent streaming
+var _ llm.StreamingChatClient = (*Recorder)(nil)
+
+// NewRecorder records the exchanges with client
+func NewRecorder(client llm.ChatClient) *Recorder {
+	return &Recorder{client: client}
+}
+
+func (r *Recorder) Chat(ctx context.Context, messages []llm.Message) (*llm.Response, error) {
+	resp, err := r.client.Chat(ctx, messages)
+	r.record(messages, resp, err)
+	return resp, err
+}
+
+// ChatStream streams if the wrapped client does; otherwise the answer is
+// passed to streamFunc in one piece
+func (r *Recorder) ChatStream(ctx context.Context, messages []llm.Message, streamFunc func(chunk string)) (*llm.Response, error) {
+	var resp *llm.Response
+	var err error
+	if sc, ok := r.client.(llm.StreamingChatClient); ok {
+		resp, err = sc.ChatStream(ctx, messages, streamFunc)
+	} else {
+		resp, err = r.client.Chat(ctx, messages)
+		if err == nil && len(resp.ToolCalls) == 0 {
+			streamFunc(resp.Content)
+		}
+	}
+	r.record(messages, resp, err)
+	return resp, err
+}
+
+func (r *Recorder) record(messages []llm.Message, resp *llm.Response, err error) {
+	step := Step{Request: append([]llm.Message(nil), messages...)}
+	if err != nil {
+		step.Error = err.Error()
+	} else {
+		step.Response = resp.Content
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending = append(r.pending, step)
+}
+
+// Retriever wraps the agent's auto-RAG retriever so its results are
+// recorded too
+func (r *Recorder) Retriever(inner agent.ContextRetriever) agent.ContextRetriever {
+	return recordingRetriever{r, inner}
+}
+
+type recordingRetriever struct {
+	r     *Recorder
+	inner agent.ContextRetriever
+}
+
+func (rr recordingRetriever) Retrieve(ctx context.Context, query string) (string, error) {
+	found, err := rr.inner.Retrieve(ctx, query)
+	if err == nil {
+		rr.r.mu.Lock()
+		rr.r.context = rr.r.Redactor.Redact(found)
+		rr.r.mu.Unlock()
+	}
+	return found, err
+}
+
+// AddRun records a finished run with the exchanges it made. Tool results
+// are taken from result, after the agent's redaction.
+func (r *Recorder) AddRun(result *agent.RunResult, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run := Run{Steps: r.pending, Context: r.context}
+	r.pending, r.context = nil, ""
+	if result != nil {
+		run.Query = result.Query
+		run.Answer = result.Answer
+		for i, step := range result.Steps {
+			if i < len(run.Steps) && step.ToolCall != nil {
+				run.Steps[i].ToolCall = &ToolCall{
+					Name:   step.ToolCall.Name,
+					Params: step.ToolCall.Params,
+					Result: step.ToolCall.Result,
+					Error:  step.ToolCall.Error,
+				}
+			}
+		}
+	}
+	if err != nil {
+		run.Error = err.Error()
+	}
+	// The first request of a run without history is system prompt + query
+	run.Fresh = len(run.Steps) > 0 && len(run.Steps[0].Request) == 2
+	r.runs = append(r.runs, run)
+}
+
+// Cassette returns the runs recorded so far, with the tools of registry
+func (r *Recorder) Cassette(registry *tools.Registry) *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := &Cassette{Runs: append([]Run(nil), r.runs...)}
+	for _, t := range registry.Tools() {
+		meta, _ := registry.Meta(t.Name())
+		c.Tools = append(c.Tools, ToolSpec{
+			Name:        t.Name(),
+			Description: t.Description(),
+			Parameters:  t.Parameters(),
+			Category:    meta.Category,
+			ReadOnly:    meta.ReadOnly,
+			Risk:        meta.Risk,
+			Latency:     meta.Latency,
+			Aliases:     registry.Aliases(t.Name()),
+
+			ReadOnlyWhen: meta.ReadOnlyWhen,
+		})
+	}
+	return c
+}
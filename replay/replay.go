@@ -0,0 +1,161 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/rathore/langchain-agent/agent"
+	"github.com/rathore/langchain-agent/llm"
+	"github.com/rathore/langchain-agent/tools"
+)
+
+// Replay runs the queries of c through a new agent that gets the recorded
+// LLM responses, re-parsed with llm.ParseResponse, and tools that return
+// the recorded results. It returns what the agent did as a cassette for
+// Diff.
+//
+// cfg configures the agent as in the recording (Policy, Redactor, MaxIter);
+// its Client and Retriever are replaced. The tools are cfg.Registry's with
+// their calls mocked, so changes to their descriptions show in the prompt,
+// or without a Registry the recorded ones.
+func Replay(ctx context.Context, c *Cassette, cfg agent.Config) (*Cassette, error) {
+	p := &player{}
+	registry := tools.NewRegistry()
+	if cfg.Registry != nil {
+		for _, t := range cfg.Registry.Tools() {
+			meta, _ := cfg.Registry.Meta(t.Name())
+			spec := ToolSpec{Name: t.Name(), Description: t.Description(), Parameters: t.Parameters()}
+			if err := register(registry, mockTool{spec, p}, meta, cfg.Registry.Aliases(t.Name())); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		for _, spec := range c.Tools {
+			meta := tools.Meta{Category: spec.Category, ReadOnly: spec.ReadOnly, ReadOnlyWhen: spec.ReadOnlyWhen, Risk: spec.Risk, Latency: spec.Latency}
+			if err := register(registry, mockTool{spec, p}, meta, spec.Aliases); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	rec := NewRecorder(p)
+	cfg.Client = rec
+	cfg.Tools = nil
+	cfg.Registry = registry
+	cfg.Retriever = rec.Retriever(p)
+	ag, err := agent.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, run := range c.Runs {
+		p.start(run)
+		if run.Fresh {
+			ag.ClearHistory()
+		}
+		result, err := ag.RunDetailed(ctx, run.Query)
+		rec.AddRun(result, err)
+	}
+	return rec.Cassette(registry), nil
+}
+
+func register(r *tools.Registry, t tools.Tool, meta tools.Meta, aliases []string) error {
+	name, err := r.Register(t, meta)
+	if err != nil {
+		return err
+	}
+	for _, alias := range aliases {
+		if err := r.Alias(alias, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// player serves one recorded run at a time: its LLM responses, auto-RAG
+// context and tool results, in order
+type player struct {
+	mu        sync.Mutex
+	run       Run
+	responses int // LLM responses served
+	calls     int // tool calls served
+}
+
+func (p *player) start(run Run) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.run, p.responses, p.calls = run, 0, 0
+}
+
+func (p *player) Chat(ctx context.Context, messages []llm.Message) (*llm.Response, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.responses >= len(p.run.Steps) {
+		return nil, fmt.Errorf("replay: no recorded response %d", p.responses+1)
+	}
+	step := p.run.Steps[p.responses]
+	p.responses++
+	if step.Error != "" {
+		return nil, errors.New(step.Error)
+	}
+	return llm.ParseResponse(step.Response), nil
+}
+
+func (p *player) Retrieve(ctx context.Context, query string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.run.Context, nil
+}
+
+// call returns the result of the next recorded tool call of the run, if it
+// was made with the same tool and parameters
+func (p *player) call(name string, params map[string]any) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var recorded []*ToolCall
+	for _, step := range p.run.Steps {
+		if step.ToolCall != nil {
+			recorded = append(recorded, step.ToolCall)
+		}
+	}
+	if p.calls >= len(recorded) {
+		return "", fmt.Errorf("replay: unexpected call to %s", name)
+	}
+	want := recorded[p.calls]
+	p.calls++
+	if want.Name != name || !sameParams(want.Params, params) {
+		return "", fmt.Errorf("replay: recorded call was %s %v", want.Name, want.Params)
+	}
+	if want.Error != "" {
+		return "", errors.New(want.Error)
+	}
+	return want.Result, nil
+}
+
+// sameParams compares parameters as JSON, as they were recorded
+func sameParams(want, got map[string]any) bool {
+	var normalized map[string]any
+	data, _ := json.Marshal(got)
+	json.Unmarshal(data, &normalized)
+	if len(want) == 0 && len(normalized) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(want, normalized)
+}
+
+// mockTool looks like a recorded or real tool and returns recorded results
+type mockTool struct {
+	spec ToolSpec
+	p    *player
+}
+
+func (t mockTool) Name() string               { return t.spec.Name }
+func (t mockTool) Description() string        { return t.spec.Description }
+func (t mockTool) Parameters() map[string]any { return t.spec.Parameters }
+
+func (t mockTool) Call(ctx context.Context, params map[string]any) (string, error) {
+	return t.p.call(t.spec.Name, params)
+}
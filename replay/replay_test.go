@@ -0,0 +1,152 @@
+package replay
+
+import (
+	"context"
+	"flag"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rathore/langchain-agent/agent"
+	"github.com/rathore/langchain-agent/llm"
+	"github.com/rathore/langchain-agent/tools"
+)
+
+var update = flag.Bool("update", false, "rewrite the cassettes in testdata with what the agent sends now")
+
+// testRegistry holds the CLI's built-in tools, registered as main does
+func testRegistry() *tools.Registry {
+	r := tools.NewRegistry()
+	r.Register(&tools.SSHTool{}, tools.Meta{})
+	r.Register(&tools.ShellTool{}, tools.Meta{})
+	for _, alias := range []string{"bash", "sh", "run_command"} {
+		r.Alias(alias, "shell")
+	}
+	r.Register(tools.NewEdgeTempTool("pi@edge.local"), tools.Meta{})
+	r.Register(tools.NewWikiTool(nil, nil), tools.Meta{})
+	return r
+}
+
+// TestGolden replays the cassettes in testdata. If it fails after a change
+// to the prompt, the tools or the parser, check the diff and rerun with
+// -update to accept it.
+func TestGolden(t *testing.T) {
+	paths, err := filepath.Glob("testdata/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no cassettes in testdata")
+	}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			want, err := Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Replay(context.Background(), want, agent.Config{Registry: testRegistry()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *update {
+				if err := got.Save(path); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if diff := Diff(want, got); diff != "" {
+				t.Errorf("replay differs from %s (rerun with -update to accept):\n%s", path, diff)
+			}
+		})
+	}
+}
+
+// scriptedClient answers with canned responses
+type scriptedClient struct{ responses []string }
+
+func (c *scriptedClient) Chat(ctx context.Context, messages []llm.Message) (*llm.Response, error) {
+	resp := llm.ParseResponse(c.responses[0])
+	c.responses = c.responses[1:]
+	return resp, nil
+}
+
+// echoTool echoes its input
+type echoTool struct{ description string }
+
+func (t *echoTool) Name() string        { return "echo" }
+func (t *echoTool) Description() string { return t.description }
+func (t *echoTool) Parameters() map[string]any {
+	return map[string]any{"type": "object", "properties": map[string]any{"text": map[string]any{"type": "string"}}}
+}
+func (t *echoTool) Call(ctx context.Context, params map[string]any) (string, error) {
+	text, _ := params["text"].(string)
+	return "echo: " + text, nil
+}
+
+func TestRecordReplay(t *testing.T) {
+	rec := NewRecorder(&scriptedClient{responses: []string{
+		`{"name": "echo", "parameters": {"text": "hi"}}`,
+		"It said hi.",
+		"Nothing to do.",
+	}})
+	registry := tools.NewRegistry()
+	registry.Register(&echoTool{description: "Echo a text"}, tools.Meta{Category: tools.CategoryLocal, ReadOnly: true})
+	ag, err := agent.New(agent.Config{Client: rec, Registry: registry})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []string{"say hi", "thanks"} {
+		result, err := ag.RunDetailed(context.Background(), q)
+		rec.AddRun(result, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "echo.json")
+	if err := rec.Cassette(registry).Save(path); err != nil {
+		t.Fatal(err)
+	}
+	want, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want.Runs) != 2 || !want.Runs[0].Fresh || want.Runs[1].Fresh || len(want.Runs[0].Steps) != 2 {
+		t.Fatalf("recorded runs = %+v", want.Runs)
+	}
+	if call := want.Runs[0].Steps[0].ToolCall; call == nil || call.Result != "echo: hi" {
+		t.Errorf("recorded tool call = %+v", call)
+	}
+
+	// Unchanged code replays identically, with the recorded tools or the real ones
+	for _, cfg := range []agent.Config{{}, {Registry: registry}} {
+		got, err := Replay(context.Background(), want, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := Diff(want, got); diff != "" {
+			t.Errorf("replay differs:\n%s", diff)
+		}
+	}
+
+	// A changed tool description changes the system prompt
+	changed := tools.NewRegistry()
+	changed.Register(&echoTool{description: "Repeat a text back"}, tools.Meta{Category: tools.CategoryLocal, ReadOnly: true})
+	got, err := Replay(context.Background(), want, agent.Config{Registry: changed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := Diff(want, got)
+	for _, s := range []string{"request message 1 (system)", "-", "Echo a text", "+", "Repeat a text back"} {
+		if !strings.Contains(diff, s) {
+			t.Errorf("diff missing %q:\n%s", s, diff)
+		}
+	}
+
+	// A recorded response the parser now reads differently changes the run
+	want.Runs[0].Steps[0].Response = `{"tool": "echo", "params": {"text": "bye"}}`
+	got, err = Replay(context.Background(), want, agent.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := Diff(want, got); !strings.Contains(diff, "replay: recorded call was echo") {
+		t.Errorf("diff should show the unexpected tool call:\n%s", diff)
+	}
+}
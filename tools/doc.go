@@ -0,0 +1,4 @@
+// Package tools holds the tools the agent can call (shell, ssh, MCP servers,
+// wiki search, edge devices) and the Tool interface for writing new ones.
+// Tools holding connections implement Closeable.
+package tools
@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/rathore/langchain-agent/tools/schema"
 )
 
 // EdgeGPIOTool reads or writes a GPIO line on the configured edge target
@@ -21,50 +24,40 @@ func NewEdgeGPIOTool(host string) *EdgeGPIOTool {
 
 func (t *EdgeGPIOTool) Name() string { return "edge_gpio" }
 
+// Meta declares reads as read-only; writes drive whatever is wired to the pin
+func (t *EdgeGPIOTool) Meta() Meta {
+	return Meta{
+		Category:     CategoryDevice,
+		ReadOnlyWhen: &ReadOnlyCalls{Param: "action", Values: []string{"read"}},
+		Risk:         RiskHigh,
+		Latency:      2 * time.Second,
+	}
+}
+
 func (t *EdgeGPIOTool) Description() string {
 	return fmt.Sprintf("Read or write a GPIO line on the configured edge target (%s) via libgpiod (gpioget/gpioset). Defaults to gpiochip0; on Pi 5 use chip='gpiochip4'.", t.host)
 }
 
+// gpioParams are the parameters of an edge_gpio call
+type gpioParams struct {
+	Pin    int    `json:"pin" desc:"GPIO line offset within the chip (e.g. 17 for BCM17 on Pi 4 gpiochip0)" required:"true" min:"0"`
+	Action string `json:"action" desc:"'read' to read the line, 'write' to set it" required:"true" enum:"read,write"`
+	Value  string `json:"value" desc:"Required when action='write': 'high' or 'low'"`
+	Chip   string `json:"chip" desc:"Optional gpiochip name. Default: gpiochip0. Use gpiochip4 for Pi 5." default:"gpiochip0"`
+}
+
 func (t *EdgeGPIOTool) Parameters() map[string]any {
-	return map[string]any{
-		"type": "object",
-		"properties": map[string]any{
-			"pin": map[string]any{
-				"type":        "integer",
-				"description": "GPIO line offset within the chip (e.g. 17 for BCM17 on Pi 4 gpiochip0)",
-			},
-			"action": map[string]any{
-				"type":        "string",
-				"description": "'read' to read the line, 'write' to set it",
-			},
-			"value": map[string]any{
-				"type":        "string",
-				"description": "Required when action='write': 'high' or 'low'",
-			},
-			"chip": map[string]any{
-				"type":        "string",
-				"description": "Optional gpiochip name. Default: gpiochip0. Use gpiochip4 for Pi 5.",
-			},
-		},
-		"required": []string{"pin", "action"},
-	}
+	return schema.For[gpioParams]()
 }
 
 func (t *EdgeGPIOTool) Call(ctx context.Context, params map[string]any) (string, error) {
-	pin, ok := pinAsInt(params["pin"])
-	if !ok {
-		return "", fmt.Errorf("pin parameter required (integer)")
-	}
-	action, ok := params["action"].(string)
-	if !ok {
-		return "", fmt.Errorf("action parameter required ('read' or 'write')")
-	}
-	chip, _ := params["chip"].(string)
-	if chip == "" {
-		chip = "gpiochip0"
+	p, err := schema.Decode[gpioParams](params)
+	if err != nil {
+		return "", err
 	}
+	pin, chip := p.Pin, p.Chip
 
-	switch action {
+	switch p.Action {
 	case "read":
 		out, err := t.exec(ctx, t.host, fmt.Sprintf("gpioget %s %d", chip, pin))
 		if err != nil {
@@ -72,8 +65,8 @@ func (t *EdgeGPIOTool) Call(ctx context.Context, params map[string]any) (string,
 		}
 		return "level=" + strings.TrimSpace(out), nil
 	case "write":
-		v, ok := params["value"].(string)
-		if !ok {
+		v := p.Value
+		if v == "" {
 			return "", fmt.Errorf("value parameter required when action='write'")
 		}
 		var bit string
@@ -91,22 +84,6 @@ func (t *EdgeGPIOTool) Call(ctx context.Context, params map[string]any) (string,
 		}
 		return fmt.Sprintf("ok: %s line %d set %s", chip, pin, v), nil
 	default:
-		return "", fmt.Errorf("action must be 'read' or 'write' (got %q)", action)
-	}
-}
-
-// pinAsInt accepts either a JSON number (float64) or a string.
-func pinAsInt(v any) (int, bool) {
-	switch x := v.(type) {
-	case float64:
-		return int(x), true
-	case int:
-		return x, true
-	case string:
-		var n int
-		if _, err := fmt.Sscanf(x, "%d", &n); err == nil {
-			return n, true
-		}
+		return "", fmt.Errorf("action must be 'read' or 'write' (got %q)", p.Action)
 	}
-	return 0, false
 }
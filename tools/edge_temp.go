@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // EdgeTempTool reads CPU temperature on the configured edge target.
@@ -22,6 +23,10 @@ func NewEdgeTempTool(host string) *EdgeTempTool {
 
 func (t *EdgeTempTool) Name() string { return "edge_temp" }
 
+func (t *EdgeTempTool) Meta() Meta {
+	return Meta{Category: CategoryDevice, ReadOnly: true, Latency: 2 * time.Second}
+}
+
 func (t *EdgeTempTool) Description() string {
 	return fmt.Sprintf("Read CPU temperature on the configured edge target (%s) via /sys/class/thermal. Works on Pi and amd64 Linux. No parameters.", t.host)
 }
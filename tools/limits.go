@@ -0,0 +1,187 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// unavailableError marks an error as the tool's backend failing (see
+// Unavailable)
+type unavailableError struct{ err error }
+
+func (e unavailableError) Error() string { return e.err.Error() }
+func (e unavailableError) Unwrap() error { return e.err }
+
+// Unavailable marks err as a failure of what the tool talks to (an
+// unreachable host, a dead MCP server, a crashed plugin) rather than of the
+// call itself, such as a bad parameter. Circuit breakers count only these.
+func Unavailable(err error) error {
+	if err == nil || IsUnavailable(err) {
+		return err
+	}
+	return unavailableError{err}
+}
+
+// IsUnavailable reports whether err, or an error it wraps, was marked by
+// Unavailable
+func IsUnavailable(err error) bool {
+	var u unavailableError
+	return errors.As(err, &u)
+}
+
+// Limits make a tool resilient to a slow or failing backend
+type Limits struct {
+	// MaxConcurrent bounds the calls running at once; more wait their turn
+	// (0: no limit)
+	MaxConcurrent int
+	// Failures in a row that open the circuit: calls then fail at once,
+	// without reaching the backend, until Cooldown has passed (0: never)
+	Failures int
+	// Cooldown is how long an open circuit fails calls before letting one
+	// through to try the backend again (default 30s)
+	Cooldown time.Duration
+	// KeyParam, when set, names the parameter whose value gets a circuit
+	// of its own, e.g. "host" so one unreachable host doesn't block others
+	KeyParam string
+}
+
+// LimitedTool is a Tool with Limits. Calls that return an Unavailable error
+// or time out count as failures.
+type LimitedTool struct {
+	Tool
+	limits Limits
+	slots  chan struct{} // nil without a concurrency limit
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+// circuit is the breaker state of a tool or of one KeyParam value
+type circuit struct {
+	failures  int       // in a row
+	openUntil time.Time // zero while closed
+	trial     bool      // a call is testing the backend after Cooldown
+	lastErr   error
+}
+
+// WithLimits wraps t with limits. The wrapper is shared by every agent the
+// tool is registered with, so limits hold across webhook users.
+func WithLimits(t Tool, limits Limits) *LimitedTool {
+	if limits.Cooldown <= 0 {
+		limits.Cooldown = 30 * time.Second
+	}
+	lt := &LimitedTool{Tool: t, limits: limits, circuits: map[string]*circuit{}}
+	if limits.MaxConcurrent > 0 {
+		lt.slots = make(chan struct{}, limits.MaxConcurrent)
+	}
+	return lt
+}
+
+// Unwrap returns the tool without limits
+func (t *LimitedTool) Unwrap() Tool { return t.Tool }
+
+// Close closes the wrapped tool if it holds resources
+func (t *LimitedTool) Close() error {
+	if c, ok := t.Tool.(Closeable); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func (t *LimitedTool) Call(ctx context.Context, params map[string]any) (string, error) {
+	key := ""
+	if t.limits.KeyParam != "" {
+		key, _ = params[t.limits.KeyParam].(string)
+	}
+	if err := t.admit(key); err != nil {
+		return "", err
+	}
+
+	if t.slots != nil {
+		select {
+		case t.slots <- struct{}{}:
+			defer func() { <-t.slots }()
+		case <-ctx.Done():
+			t.endTrial(key)
+			return "", fmt.Errorf("%s: waiting for a free slot (%d calls at once): %w", t.Name(), t.limits.MaxConcurrent, ctx.Err())
+		}
+	}
+
+	output, err := t.Tool.Call(ctx, params)
+	switch {
+	case IsUnavailable(err), errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil:
+		t.record(key, err)
+	case ctx.Err() != nil:
+		t.endTrial(key) // cancelled: says nothing about the backend
+	default:
+		t.record(key, nil)
+	}
+	return output, err
+}
+
+// admit fails the call at once if key's circuit is open. After Cooldown
+// one call is let through as a trial.
+func (t *LimitedTool) admit(key string) error {
+	if t.limits.Failures <= 0 {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c := t.circuits[key]
+	if c == nil || c.openUntil.IsZero() {
+		return nil
+	}
+	wait := time.Until(c.openUntil)
+	if wait > 0 || c.trial {
+		target := t.Name()
+		if key != "" {
+			target += " " + key
+		}
+		retry := "a retry is in progress"
+		if wait > 0 {
+			retry = fmt.Sprintf("not retrying for %s", wait.Round(time.Second))
+		}
+		return Unavailable(fmt.Errorf("%s is unavailable: %d calls in a row failed (last: %v); %s. Don't call it again for now: tell the user, or use another way",
+			target, c.failures, c.lastErr, retry))
+	}
+	c.trial = true
+	return nil
+}
+
+// record updates key's circuit with a call's outcome: failure is the
+// error if the backend failed, nil if it answered
+func (t *LimitedTool) record(key string, failure error) {
+	if t.limits.Failures <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c := t.circuits[key]
+	if failure == nil {
+		delete(t.circuits, key) // closed
+		return
+	}
+	if c == nil {
+		c = &circuit{}
+		t.circuits[key] = c
+	}
+	c.failures++
+	c.lastErr = failure
+	if c.trial || c.failures >= t.limits.Failures {
+		c.openUntil = time.Now().Add(t.limits.Cooldown)
+	}
+	c.trial = false
+}
+
+// endTrial lets another call try the backend when a trial call gave up
+// before reaching it
+func (t *LimitedTool) endTrial(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if c := t.circuits[key]; c != nil {
+		c.trial = false
+	}
+}
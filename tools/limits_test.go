@@ -0,0 +1,143 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyTool fails with Unavailable errors for the hosts in down
+type flakyTool struct {
+	mu    sync.Mutex
+	down  map[string]bool
+	calls int
+}
+
+func (f *flakyTool) Name() string               { return "ssh" }
+func (f *flakyTool) Description() string        { return "flaky" }
+func (f *flakyTool) Parameters() map[string]any { return nil }
+func (f *flakyTool) Call(ctx context.Context, params map[string]any) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	host, _ := params["host"].(string)
+	if host == "" {
+		return "", errors.New("host parameter required")
+	}
+	if f.down[host] {
+		return "", Unavailable(fmt.Errorf("failed to connect to %s:22: i/o timeout", host))
+	}
+	return "up", nil
+}
+
+func TestLimitedTool_CircuitBreaker(t *testing.T) {
+	flaky := &flakyTool{down: map[string]bool{"db-01": true}}
+	tool := WithLimits(flaky, Limits{Failures: 2, Cooldown: 50 * time.Millisecond, KeyParam: "host"})
+	ctx := context.Background()
+	call := func(host string) error {
+		_, err := tool.Call(ctx, map[string]any{"host": host})
+		return err
+	}
+
+	// Call errors don't count; two backend failures open db-01's circuit
+	for range 3 {
+		call("")
+	}
+	call("db-01")
+	call("db-01")
+	before := flaky.calls
+	err := call("db-01")
+	if err == nil || !strings.Contains(err.Error(), "ssh db-01 is unavailable: 2 calls in a row failed (last: failed to connect to db-01:22: i/o timeout); not retrying for") {
+		t.Errorf("open circuit error = %v", err)
+	}
+	if !IsUnavailable(err) || flaky.calls != before {
+		t.Errorf("open circuit reached the tool, or its error isn't Unavailable")
+	}
+	if err := call("web-01"); err != nil {
+		t.Errorf("other host: %v", err)
+	}
+
+	// After the cooldown one trial call goes through; it fails, so the
+	// circuit opens again at once
+	time.Sleep(60 * time.Millisecond)
+	call("db-01")
+	if flaky.calls != before+2 {
+		t.Errorf("trial call didn't reach the tool")
+	}
+	if err := call("db-01"); err == nil || !strings.Contains(err.Error(), "3 calls in a row failed") {
+		t.Errorf("after failed trial: %v", err)
+	}
+
+	// A trial that succeeds closes it
+	time.Sleep(60 * time.Millisecond)
+	flaky.down["db-01"] = false
+	if err := call("db-01"); err != nil {
+		t.Errorf("trial: %v", err)
+	}
+	if err := call("db-01"); err != nil {
+		t.Errorf("closed circuit: %v", err)
+	}
+}
+
+// slowTool counts the calls running at once
+type slowTool struct {
+	running, peak atomic.Int32
+}
+
+func (s *slowTool) Name() string               { return "mcp" }
+func (s *slowTool) Description() string        { return "slow" }
+func (s *slowTool) Parameters() map[string]any { return nil }
+func (s *slowTool) Call(ctx context.Context, params map[string]any) (string, error) {
+	n := s.running.Add(1)
+	defer s.running.Add(-1)
+	for {
+		peak := s.peak.Load()
+		if n <= peak || s.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	return "ok", nil
+}
+
+func TestLimitedTool_MaxConcurrent(t *testing.T) {
+	slow := &slowTool{}
+	tool := WithLimits(slow, Limits{MaxConcurrent: 2})
+	var wg sync.WaitGroup
+	for range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tool.Call(context.Background(), nil)
+		}()
+	}
+	wg.Wait()
+	if peak := slow.peak.Load(); peak != 2 {
+		t.Errorf("peak concurrent calls = %d, want 2", peak)
+	}
+
+	// A call waiting for a slot gives up with its context
+	tool = WithLimits(&slowTool{}, Limits{MaxConcurrent: 1})
+	tool.slots <- struct{}{}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := tool.Call(ctx, nil); !errors.Is(err, context.DeadlineExceeded) || IsUnavailable(err) {
+		t.Errorf("waiting call: %v", err)
+	}
+}
+
+func TestLimitedTool_Unwrap(t *testing.T) {
+	mcpTool := newMCPToolFromClient(&mockMCPClient{}, "fs", testTools())
+	tool := WithLimits(mcpTool, Limits{Failures: 3})
+	if tool.Name() != "fs" || tool.Unwrap() != Tool(mcpTool) {
+		t.Errorf("wrapped tool = %s, %v", tool.Name(), tool.Unwrap())
+	}
+	if err := tool.Close(); err != nil || !mcpTool.client.(*mockMCPClient).closed {
+		t.Errorf("Close didn't close the MCP client: %v", err)
+	}
+}
@@ -2,8 +2,12 @@ package tools
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
+	"mime"
+	"path"
 	"strings"
+	"time"
 
 	"github.com/mark3labs/mcp-go/client"
 	"github.com/mark3labs/mcp-go/mcp"
@@ -23,6 +27,15 @@ type MCPTool struct {
 	serverCmd string
 	tools     []mcp.Tool
 	toolMap   map[string]mcp.Tool
+	short     map[string]string // prompt descriptions by tool (see ShortenDescriptions)
+
+	// Timeout bounds each call (default 60s)
+	Timeout time.Duration
+
+	// Workspace, when set, receives the images, audio and binary resources
+	// tools return; the LLM gets their file names. Without one they are
+	// left out.
+	Workspace *Workspace
 }
 
 // Ensure MCPTool implements Closeable
@@ -110,6 +123,15 @@ func (m *MCPTool) Name() string {
 	return m.name
 }
 
+// Meta declares the server's tools annotated readOnlyHint as read-only
+func (m *MCPTool) Meta() Meta {
+	return Meta{
+		Category:     CategoryMCP,
+		ReadOnlyWhen: &ReadOnlyCalls{Param: "tool_name", Values: m.ReadOnlyTools()},
+		Latency:      2 * time.Second,
+	}
+}
+
 func (m *MCPTool) Description() string {
 	if len(m.tools) == 0 {
 		return "MCP server tool (no tools discovered)"
@@ -121,6 +143,18 @@ func (m *MCPTool) Description() string {
 	return fmt.Sprintf("MCP server tool. Available tools: %s", strings.Join(names, ", "))
 }
 
+// ReadOnlyTools lists the server's tools annotated readOnlyHint, which
+// don't change their environment
+func (m *MCPTool) ReadOnlyTools() []string {
+	var names []string
+	for _, t := range m.tools {
+		if hint := t.Annotations.ReadOnlyHint; hint != nil && *hint {
+			names = append(names, t.Name)
+		}
+	}
+	return names
+}
+
 func (m *MCPTool) Parameters() map[string]any {
 	// Build enum list with descriptions for tool_name
 	var enumValues []string
@@ -128,6 +162,9 @@ func (m *MCPTool) Parameters() map[string]any {
 	for _, t := range m.tools {
 		enumValues = append(enumValues, t.Name)
 		desc := t.Description
+		if short, ok := m.short[t.Name]; ok {
+			desc = short
+		}
 		if desc == "" {
 			desc = t.Name
 		}
@@ -177,12 +214,24 @@ func (m *MCPTool) Call(ctx context.Context, params map[string]any) (string, erro
 	req.Params.Name = toolName
 	req.Params.Arguments = arguments
 
-	result, err := m.client.CallTool(ctx, req)
+	timeout := m.Timeout
+	if timeout == 0 {
+		timeout = 60 * time.Second
+	}
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	result, err := m.client.CallTool(callCtx, req)
 	if err != nil {
-		return "", fmt.Errorf("MCP call %q failed: %w", toolName, err)
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("MCP call %q cancelled: %w", toolName, ctx.Err())
+		}
+		if callCtx.Err() != nil {
+			return "", Unavailable(fmt.Errorf("MCP call %q timed out after %s", toolName, timeout))
+		}
+		return "", Unavailable(fmt.Errorf("MCP call %q failed: %w", toolName, err))
 	}
 
-	// Extract text content from result
+	// Extract text content from result, saving binary content
 	var parts []string
 	for _, content := range result.Content {
 		switch c := content.(type) {
@@ -190,6 +239,17 @@ func (m *MCPTool) Call(ctx context.Context, params map[string]any) (string, erro
 			parts = append(parts, c.Text)
 		case *mcp.TextContent:
 			parts = append(parts, c.Text)
+		case mcp.ImageContent:
+			parts = append(parts, m.saveBinary(toolName, c.MIMEType, c.Data))
+		case mcp.AudioContent:
+			parts = append(parts, m.saveBinary(toolName, c.MIMEType, c.Data))
+		case mcp.EmbeddedResource:
+			switch r := c.Resource.(type) {
+			case mcp.TextResourceContents:
+				parts = append(parts, r.Text)
+			case mcp.BlobResourceContents:
+				parts = append(parts, m.saveBinary(toolName, r.MIMEType, r.Blob))
+			}
 		}
 	}
 
@@ -206,6 +266,36 @@ func (m *MCPTool) Call(ctx context.Context, params map[string]any) (string, erro
 	return output, nil
 }
 
+// saveBinary saves base64 content a tool returned to the workspace and
+// describes it for the LLM
+func (m *MCPTool) saveBinary(toolName, mimeType, data string) string {
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return fmt.Sprintf("[%s content: invalid base64: %v]", mimeType, err)
+	}
+	if m.Workspace == nil {
+		return fmt.Sprintf("[%s content (%s) omitted: no workspace to save it in]", mimeType, formatSize(int64(len(raw))))
+	}
+	ext := ".bin"
+	if exts, _ := mime.ExtensionsByType(mimeType); len(exts) > 0 {
+		ext = exts[len(exts)-1]
+	}
+	base := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return '_'
+	}, m.name+"_"+toolName)
+	name, err := m.Workspace.Save(path.Join("mcp", base+ext), raw)
+	if err != nil {
+		return fmt.Sprintf("[%s content (%s) not saved: %v]", mimeType, formatSize(int64(len(raw))), err)
+	}
+	return fmt.Sprintf("[%s content (%s) saved to the workspace as %s]", mimeType, formatSize(int64(len(raw))), name)
+}
+
 func (m *MCPTool) Close() error {
 	if m.client != nil {
 		return m.client.Close()
@@ -217,3 +307,46 @@ func (m *MCPTool) Close() error {
 func (m *MCPTool) ToolCount() int {
 	return len(m.tools)
 }
+
+// MCPServerTool describes a tool discovered on an MCP server
+type MCPServerTool struct {
+	Name        string
+	Description string
+	Parameters  map[string]any // JSON schema of the tool's arguments
+}
+
+// CallArguments implements Dispatcher with the schema of the server tool
+// named by tool_name
+func (m *MCPTool) CallArguments(params map[string]any) (map[string]any, map[string]any) {
+	name, _ := params["tool_name"].(string)
+	t, ok := m.toolMap[name]
+	if !ok {
+		return nil, nil
+	}
+	args, _ := params["arguments"].(map[string]any)
+	if args == nil {
+		args = map[string]any{}
+		params["arguments"] = args
+	}
+	return t.InputSchema.Properties, args
+}
+
+// ServerTools lists the tools discovered on the MCP server
+func (m *MCPTool) ServerTools() []MCPServerTool {
+	out := make([]MCPServerTool, 0, len(m.tools))
+	for _, t := range m.tools {
+		schema := map[string]any{"type": t.InputSchema.Type}
+		if len(t.InputSchema.Properties) > 0 {
+			schema["properties"] = t.InputSchema.Properties
+		}
+		if len(t.InputSchema.Required) > 0 {
+			required := make([]any, len(t.InputSchema.Required))
+			for i, name := range t.InputSchema.Required {
+				required[i] = name
+			}
+			schema["required"] = required
+		}
+		out = append(out, MCPServerTool{Name: t.Name, Description: t.Description, Parameters: schema})
+	}
+	return out
+}
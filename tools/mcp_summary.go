@@ -0,0 +1,142 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rathore/langchain-agent/llm"
+)
+
+const describePrompt = `You shorten the descriptions of tools for the tool list of an assistant with a small context window.
+Keep what the tool does, when to use it and any limits on its arguments; drop examples, markdown, notes on output format and repetition.
+Reply with only the new description: plain text, at most %d characters.`
+
+// DescriptionCache keeps shortened tool descriptions in a JSON file, by a
+// hash of the tool's name, description and the length limit, so each
+// description is only summarized once, and again when it changes
+type DescriptionCache struct {
+	Path    string
+	entries map[string]string
+}
+
+// LoadDescriptionCache reads the cache at path; a missing file is an empty
+// cache
+func LoadDescriptionCache(path string) (*DescriptionCache, error) {
+	c := &DescriptionCache{Path: path, entries: map[string]string{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read description cache: %w", err)
+	}
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse description cache %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Save writes the cache to its file
+func (c *DescriptionCache) Save() error {
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0755); err != nil {
+		return fmt.Errorf("failed to save description cache: %w", err)
+	}
+	if err := os.WriteFile(c.Path, data, 0644); err != nil {
+		return fmt.Errorf("failed to save description cache: %w", err)
+	}
+	return nil
+}
+
+func descriptionKey(name, description string, maxLen int) string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%d\x00%s\x00%s", maxLen, name, description))
+	return hex.EncodeToString(sum[:16])
+}
+
+// ShortenDescriptions has client summarize the descriptions of the
+// server's tools longer than maxLen characters, for the system prompt of
+// a small model; /tools still shows them whole. Summaries come from cache
+// when it has them (nil: no cache) and new ones are added to it. A
+// description that can't be summarized is cut to its first sentences
+// instead, and the first such error is returned. It returns how many
+// descriptions were summarized by client.
+func (m *MCPTool) ShortenDescriptions(ctx context.Context, client llm.ChatClient, maxLen int, cache *DescriptionCache) (int, error) {
+	if maxLen < 20 {
+		return 0, fmt.Errorf("description limit %d is too short", maxLen)
+	}
+	if m.short == nil {
+		m.short = map[string]string{}
+	}
+	summarized := 0
+	var firstErr error
+	for _, t := range m.tools {
+		if len(t.Description) <= maxLen {
+			continue
+		}
+		key := descriptionKey(t.Name, t.Description, maxLen)
+		if cache != nil {
+			if short, ok := cache.entries[key]; ok {
+				m.short[t.Name] = short
+				continue
+			}
+		}
+		short, err := summarizeDescription(ctx, client, t.Name, t.Description, maxLen)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to summarize the description of %s: %w", t.Name, err)
+			}
+			m.short[t.Name] = cutDescription(t.Description, maxLen)
+			continue
+		}
+		m.short[t.Name] = short
+		summarized++
+		if cache != nil {
+			cache.entries[key] = short
+		}
+	}
+	return summarized, firstErr
+}
+
+// summarizeDescription has client write a description of tool name of at
+// most maxLen characters
+func summarizeDescription(ctx context.Context, client llm.ChatClient, name, description string, maxLen int) (string, error) {
+	resp, err := client.Chat(ctx, []llm.Message{
+		{Role: "system", Content: fmt.Sprintf(describePrompt, maxLen)},
+		{Role: "user", Content: fmt.Sprintf("Tool: %s\nDescription:\n%s", name, description)},
+	})
+	if err != nil {
+		return "", err
+	}
+	short := strings.Join(strings.Fields(resp.Content), " ")
+	if short == "" {
+		return "", errors.New("empty summary")
+	}
+	return cutDescription(short, maxLen), nil
+}
+
+// cutDescription shortens description to at most maxLen characters: as
+// many whole sentences as fit, else the words that fit and "..."
+func cutDescription(description string, maxLen int) string {
+	description = strings.Join(strings.Fields(description), " ")
+	if len(description) <= maxLen {
+		return description
+	}
+	head := description[:maxLen+1]
+	if i := strings.LastIndex(head, ". "); i > 0 {
+		return head[:i+1]
+	}
+	if i := strings.LastIndex(head[:maxLen-2], " "); i > 0 {
+		return head[:i] + "..."
+	}
+	return description[:maxLen-3] + "..."
+}
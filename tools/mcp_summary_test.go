@@ -0,0 +1,84 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rathore/langchain-agent/llm"
+)
+
+// failingClient fails every Chat call
+type failingClient struct{}
+
+func (failingClient) Chat(context.Context, []llm.Message) (*llm.Response, error) {
+	return nil, errors.New("offline")
+}
+
+func TestMCPTool_ShortenDescriptions(t *testing.T) {
+	long := "Search the issue tracker. " + strings.Repeat("Supports filters by label, milestone and assignee. ", 10)
+	serverTools := []mcp.Tool{
+		{Name: "search_issues", Description: long},
+		{Name: "get_issue", Description: "Get an issue by number"},
+	}
+	path := filepath.Join(t.TempDir(), "descriptions.json")
+	cache, err := LoadDescriptionCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &scriptedClient{reply: "  Search issues,\nfiltered by label or assignee.  "}
+	tool := newMCPToolFromClient(&mockMCPClient{}, "mcp_gh", serverTools)
+	n, err := tool.ShortenDescriptions(context.Background(), client, 60, cache)
+	if err != nil || n != 1 || len(client.prompts) != 1 {
+		t.Fatalf("ShortenDescriptions() = %d, %v after %d prompts; want one summary", n, err, len(client.prompts))
+	}
+	desc := tool.Parameters()["properties"].(map[string]any)["tool_name"].(map[string]any)["description"].(string)
+	if want := "search_issues: Search issues, filtered by label or assignee.; get_issue: Get an issue by number"; !strings.HasSuffix(desc, want) {
+		t.Errorf("tool_name description = %q", desc)
+	}
+	if tool.ServerTools()[0].Description != long {
+		t.Error("ServerTools() lost the whole description")
+	}
+	if err := cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The next run takes the summary from the cache
+	cache, _ = LoadDescriptionCache(path)
+	tool = newMCPToolFromClient(&mockMCPClient{}, "mcp_gh", serverTools)
+	if n, err := tool.ShortenDescriptions(context.Background(), failingClient{}, 60, cache); n != 0 || err != nil {
+		t.Errorf("cached ShortenDescriptions() = %d, %v", n, err)
+	}
+	if tool.short["search_issues"] != "Search issues, filtered by label or assignee." {
+		t.Errorf("cached description = %q", tool.short["search_issues"])
+	}
+
+	// Without a summary, the description is cut to what fits
+	tool = newMCPToolFromClient(&mockMCPClient{}, "mcp_gh", serverTools)
+	if _, err := tool.ShortenDescriptions(context.Background(), failingClient{}, 60, nil); err == nil {
+		t.Error("ShortenDescriptions() hid the client's error")
+	}
+	if tool.short["search_issues"] != "Search the issue tracker." {
+		t.Errorf("cut description = %q", tool.short["search_issues"])
+	}
+}
+
+func TestCutDescription(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Short.", "Short."},
+		{"First sentence. Second sentence is long.", "First sentence."},
+		{"one two three four five six seven", "one two three..."},
+		{"abcdefghijklmnopqrstuvwxyz", "abcdefghijklmnopq..."},
+	}
+	for _, tt := range tests {
+		if got := cutDescription(tt.in, 20); got != tt.want {
+			t.Errorf("cutDescription(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 )
@@ -63,6 +64,16 @@ func TestMCPTool_Description_NoTools(t *testing.T) {
 	}
 }
 
+func TestMCPTool_ReadOnlyTools(t *testing.T) {
+	tools := testTools()
+	tools[0].Annotations.ReadOnlyHint = mcp.ToBoolPtr(true)
+	tools = append(tools, mcp.Tool{Name: "write_file", Annotations: mcp.ToolAnnotation{ReadOnlyHint: mcp.ToBoolPtr(false)}})
+	tool := newMCPToolFromClient(&mockMCPClient{}, "", tools)
+	if got := tool.ReadOnlyTools(); len(got) != 1 || got[0] != "read_file" {
+		t.Errorf("ReadOnlyTools() = %v, want [read_file]", got)
+	}
+}
+
 func TestMCPTool_Parameters(t *testing.T) {
 	tool := newMCPToolFromClient(&mockMCPClient{}, "", testTools())
 	params := tool.Parameters()
@@ -116,6 +127,55 @@ func TestMCPTool_Call_Success(t *testing.T) {
 	}
 }
 
+func TestMCPTool_Call_BinaryContent(t *testing.T) {
+	mock := &mockMCPClient{
+		callToolFn: func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					mcp.TextContent{Type: "text", Text: "Screenshot taken"},
+					mcp.ImageContent{Type: "image", MIMEType: "image/png", Data: "iVBORw0KGgo="},
+				},
+			}, nil
+		},
+	}
+	tool := newMCPToolFromClient(mock, "", testTools())
+	params := map[string]any{"tool_name": "read_file"}
+
+	result, err := tool.Call(context.Background(), params)
+	if err != nil || result != "Screenshot taken\n[image/png content (8 bytes) omitted: no workspace to save it in]" {
+		t.Errorf("without workspace: Call() = %q, %v", result, err)
+	}
+
+	ws, err := NewWorkspace(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool.Workspace = ws
+	tool.Call(context.Background(), params)
+	result, _ = tool.Call(context.Background(), params)
+	if !strings.HasSuffix(result, "[image/png content (8 bytes) saved to the workspace as mcp/mcp_read_file-2.png]") {
+		t.Errorf("with workspace: Call() = %q", result)
+	}
+	if data, err := ws.Read("mcp/mcp_read_file-2.png"); err != nil || string(data) != "\x89PNG\r\n\x1a\n" {
+		t.Errorf("saved image = %q, %v", data, err)
+	}
+}
+
+func TestMCPTool_Call_Timeout(t *testing.T) {
+	mock := &mockMCPClient{
+		callToolFn: func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+	tool := newMCPToolFromClient(mock, "", testTools())
+	tool.Timeout = 10 * time.Millisecond
+	_, err := tool.Call(context.Background(), map[string]any{"tool_name": "read_file"})
+	if err == nil || !strings.Contains(err.Error(), `MCP call "read_file" timed out after 10ms`) || !IsUnavailable(err) {
+		t.Errorf("Call() error = %v", err)
+	}
+}
+
 func TestMCPTool_Call_MissingToolName(t *testing.T) {
 	tool := newMCPToolFromClient(&mockMCPClient{}, "", testTools())
 	_, err := tool.Call(context.Background(), map[string]any{})
@@ -225,3 +285,41 @@ func TestMCPTool_ToolCount(t *testing.T) {
 		t.Errorf("ToolCount() = %d, want 2", got)
 	}
 }
+
+func TestMCPTool_ServerTools(t *testing.T) {
+	tools := testTools()
+	tools[0].InputSchema = mcp.ToolInputSchema{
+		Type:       "object",
+		Properties: map[string]any{"path": map[string]any{"type": "string"}},
+		Required:   []string{"path"},
+	}
+	got := newMCPToolFromClient(&mockMCPClient{}, "", tools).ServerTools()
+	if len(got) != 2 || got[0].Name != "read_file" || got[0].Description != "Read a file from disk" {
+		t.Fatalf("ServerTools() = %+v", got)
+	}
+	if _, ok := got[0].Parameters["properties"].(map[string]any)["path"]; !ok {
+		t.Errorf("Parameters = %v, want the path property", got[0].Parameters)
+	}
+	if req, _ := got[0].Parameters["required"].([]any); len(req) != 1 || req[0] != "path" {
+		t.Errorf("required = %v, want [path]", got[0].Parameters["required"])
+	}
+}
+
+func TestMCPTool_CallArguments(t *testing.T) {
+	tools := []mcp.Tool{{Name: "pods_list", InputSchema: mcp.ToolInputSchema{Type: "object", Properties: map[string]any{"namespace": map[string]any{"type": "string"}}}}}
+	var tool Dispatcher = newMCPToolFromClient(&mockMCPClient{}, "k8s", tools)
+
+	params := map[string]any{"tool_name": "pods_list"}
+	props, args := tool.CallArguments(params)
+	if _, ok := props["namespace"]; !ok {
+		t.Errorf("properties = %v, want the server tool's", props)
+	}
+	args["namespace"] = "prod"
+	if got := params["arguments"].(map[string]any)["namespace"]; got != "prod" {
+		t.Errorf("arguments = %v, want the added map in params", params["arguments"])
+	}
+
+	if props, _ := tool.CallArguments(map[string]any{"tool_name": "nope"}); props != nil {
+		t.Errorf("properties for an unknown tool = %v, want nil", props)
+	}
+}
@@ -0,0 +1,181 @@
+package tools
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// minProgressRun is how many consecutive updates of one progress line are
+// collapsed into its last
+const minProgressRun = 3
+
+var (
+	// ansiEscapeRe matches terminal escape sequences: CSI (colors, cursor
+	// movement, erase), OSC (window titles, hyperlinks) and the two-byte forms
+	ansiEscapeRe = regexp.MustCompile(`\x1b(?:\[[0-?]*[ -/]*[@-~]|\][^\x07\x1b]*(?:\x07|\x1b\\)|[()][0-9A-Za-z]|[@-Z\\-_])`)
+	// progressRe matches what marks a progress line: a percentage or a bar
+	progressRe = regexp.MustCompile(`\d(?:\.\d+)?\s?%|[#=█■━]{4,}|\.{10}`)
+	// progressKeyRe matches what changes between updates of a progress line
+	progressKeyRe = regexp.MustCompile(`[\d\s#=█■━░▏▎▍▌▋▊▉>.:-]+`)
+)
+
+// NormalizeOutput cleans command output for the LLM: it decodes UTF-16 and
+// stray Latin-1 bytes, strips ANSI escape codes, keeps only the final state
+// of lines redrawn with carriage returns, and collapses runs of progress
+// updates (download bars, percentages) into their last line. Binary output
+// is replaced by a note of its size.
+func NormalizeOutput(s string) string {
+	if s == "" {
+		return s
+	}
+	s = decodeText(s)
+	if isBinary(s) {
+		return fmt.Sprintf("(binary output, %d bytes not shown)\n", len(s))
+	}
+	s = ansiEscapeRe.ReplaceAllString(s, "")
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = cleanLine(line)
+	}
+	return strings.Join(collapseProgress(lines), "\n")
+}
+
+// decodeText returns s as valid UTF-8. UTF-16 (from Windows hosts) is
+// detected by its byte order mark or by NUL high bytes; any other invalid
+// byte is read as Latin-1, or U+FFFD for C1 control codes.
+func decodeText(s string) string {
+	if u, ok := decodeUTF16(s); ok {
+		return u
+	}
+	if utf8.ValidString(s) {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		if r == utf8.RuneError && size == 1 {
+			if r = rune(s[0]); r < 0xa0 {
+				r = utf8.RuneError
+			}
+		}
+		b.WriteRune(r)
+		s = s[size:]
+	}
+	return b.String()
+}
+
+// decodeUTF16 decodes s if it looks like UTF-16: a byte order mark, or NUL
+// bytes in every other position of its start
+func decodeUTF16(s string) (string, bool) {
+	bigEndian := false
+	switch {
+	case strings.HasPrefix(s, "\xff\xfe"):
+		s = s[2:]
+	case strings.HasPrefix(s, "\xfe\xff"):
+		s, bigEndian = s[2:], true
+	default:
+		n := min(len(s), 64) &^ 1
+		if n < 4 {
+			return "", false
+		}
+		lowNUL, highNUL := 0, 0
+		for i := 0; i < n; i += 2 {
+			if s[i] == 0 {
+				lowNUL++
+			}
+			if s[i+1] == 0 {
+				highNUL++
+			}
+		}
+		switch {
+		case highNUL == n/2 && lowNUL == 0:
+		case lowNUL == n/2 && highNUL == 0:
+			bigEndian = true
+		default:
+			return "", false
+		}
+	}
+	units := make([]uint16, len(s)/2)
+	for i := range units {
+		lo, hi := uint16(s[2*i]), uint16(s[2*i+1])
+		if bigEndian {
+			lo, hi = hi, lo
+		}
+		units[i] = hi<<8 | lo
+	}
+	return string(utf16.Decode(units)), true
+}
+
+// isBinary reports whether more than a tenth of s's start is control
+// characters other than whitespace and escape sequences
+func isBinary(s string) bool {
+	sample := s[:min(len(s), 4096)]
+	control, total := 0, 0
+	for _, r := range sample {
+		total++
+		if r == 0 || (unicode.IsControl(r) && !strings.ContainsRune("\t\n\r\x1b\b\f\v", r)) {
+			control++
+		}
+	}
+	return control*10 > total
+}
+
+// cleanLine applies carriage returns and backspaces as a terminal would
+// display them, keeping what was written last, and drops other control
+// characters and trailing whitespace
+func cleanLine(line string) string {
+	line = strings.TrimRight(line, "\r")
+	if i := strings.LastIndexByte(line, '\r'); i >= 0 {
+		line = line[i+1:]
+	}
+	if strings.ContainsFunc(line, unicode.IsControl) {
+		var out []rune
+		for _, r := range line {
+			switch {
+			case r == '\b':
+				if len(out) > 0 {
+					out = out[:len(out)-1]
+				}
+			case r == '\t' || !unicode.IsControl(r):
+				out = append(out, r)
+			}
+		}
+		line = string(out)
+	}
+	return strings.TrimRight(line, " \t")
+}
+
+// collapseProgress replaces each run of updates of the same progress line
+// with a note and the last update
+func collapseProgress(lines []string) []string {
+	var out []string
+	for i := 0; i < len(lines); {
+		j := i + 1
+		if progressRe.MatchString(lines[i]) {
+			key := progressKey(lines[i])
+			for j < len(lines) && progressRe.MatchString(lines[j]) && progressKey(lines[j]) == key {
+				j++
+			}
+		}
+		if j-i >= minProgressRun {
+			out = append(out, fmt.Sprintf("[%d progress updates omitted]", j-i-1))
+			out = append(out, lines[j-1])
+		} else {
+			out = append(out, lines[i:j]...)
+		}
+		i = j
+	}
+	return out
+}
+
+// progressKey is line without the digits, bars and spacing that change as
+// progress is made
+func progressKey(line string) string {
+	return progressKeyRe.ReplaceAllString(line, "")
+}
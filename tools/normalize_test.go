@@ -0,0 +1,52 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeOutput(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"plain", "total 8\ndrwxr-xr-x 2 root root 4096 .\n", "total 8\ndrwxr-xr-x 2 root root 4096 .\n"},
+		{"colors", "\x1b[1;31mERROR\x1b[0m disk full\x1b[K\n", "ERROR disk full\n"},
+		{"title and charset", "\x1b]0;user@host: ~\x07\x1b(Bprompt", "prompt"},
+		{"crlf", "line one\r\nline two\r\n", "line one\nline two\n"},
+		{"carriage return redraw", "Downloading  10%\rDownloading  55%\rDownloading 100%\ndone", "Downloading 100%\ndone"},
+		{"backspace overstrike", "spin|\b/\b-\bok", "spinok"},
+		{"latin-1", "caf\xe9 cr\xe8me", "café crème"},
+		{"utf-16le", "\xff\xfeo\x00k\x00\n\x00", "ok\n"},
+		{"utf-16 without bom", "C\x00:\x00\\\x00U\x00s\x00e\x00r\x00s\x00", `C:\Users`},
+		{"binary", "\x7fELF\x02\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x03\x00>\x00", "(binary output, 20 bytes not shown)\n"},
+		{
+			"progress lines",
+			"Pulling layer\n  5% [=>        ] 1MB/20MB\n 40% [====>     ] 8MB/20MB\n100% [==========] 20MB/20MB\nPull complete",
+			"Pulling layer\n[2 progress updates omitted]\n100% [==========] 20MB/20MB\nPull complete",
+		},
+		{
+			"table with percentages",
+			"/dev/sda1  50G  20G  30G  40% /\ntmpfs  1.0G  0  1.0G  0% /dev/shm\n/dev/sdb1  1T  900G  100G  90% /data",
+			"/dev/sda1  50G  20G  30G  40% /\ntmpfs  1.0G  0  1.0G  0% /dev/shm\n/dev/sdb1  1T  900G  100G  90% /data",
+		},
+		{"two updates kept", "10% done\n20% done", "10% done\n20% done"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeOutput(tt.in); got != tt.want {
+			t.Errorf("%s: NormalizeOutput(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestShellTool_NormalizesOutput(t *testing.T) {
+	command := map[string]any{"command": `printf '\033[32mok\033[0m\n'`}
+	got, err := (&ShellTool{}).Call(context.Background(), command)
+	if err != nil || got != "ok\n" {
+		t.Errorf("Call() = %q, %v; want colors stripped", got, err)
+	}
+	got, _ = (&ShellTool{RawOutput: true}).Call(context.Background(), command)
+	if !strings.Contains(got, "\x1b[32m") {
+		t.Errorf("Call() with RawOutput = %q, want output untouched", got)
+	}
+}
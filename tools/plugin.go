@@ -0,0 +1,226 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// pluginDescribeTimeout bounds a plugin's describe call, run at startup
+const pluginDescribeTimeout = 10 * time.Second
+
+// pluginNameRe matches the tool names plugins may declare
+var pluginNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// PluginTool is a tool implemented by an external executable, in any
+// language, that follows a JSON-over-stdio contract:
+//
+//	plugin describe   prints {"name": "...", "description": "...", "parameters": {JSON schema}}
+//	                  and optionally "read_only": true for tools that change nothing,
+//	                  "risk": "low", "medium" or "high" and "latency_ms": typical call time
+//	plugin call       reads the parameters as a JSON object on stdin and
+//	                  prints {"result": "..."} or {"error": "..."}
+//
+// The executable is run afresh for every call. A non-zero exit status is an
+// error, reported with the plugin's stderr.
+type PluginTool struct {
+	Path     string
+	Timeout  time.Duration // per call (default 60s)
+	ReadOnly bool          // declared by the plugin
+	Risk     Risk          // declared by the plugin ("" = medium)
+	Latency  time.Duration // declared by the plugin (0 = unknown)
+
+	name        string
+	description string
+	parameters  map[string]any
+}
+
+// pluginDescription is the output of `plugin describe`
+type pluginDescription struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters"`
+	ReadOnly    bool           `json:"read_only"`
+	Risk        string         `json:"risk"`
+	LatencyMS   int            `json:"latency_ms"`
+}
+
+// pluginResult is the output of `plugin call`
+type pluginResult struct {
+	Result *string `json:"result"`
+	Error  string  `json:"error"`
+}
+
+// NewPluginTool runs the executable at path with "describe" to learn the
+// tool's name, description and parameters
+func NewPluginTool(ctx context.Context, path string) (*PluginTool, error) {
+	ctx, cancel := context.WithTimeout(ctx, pluginDescribeTimeout)
+	defer cancel()
+	out, err := runPlugin(ctx, path, "describe", nil)
+	if err != nil {
+		return nil, err
+	}
+	var desc pluginDescription
+	if err := json.Unmarshal(out, &desc); err != nil {
+		return nil, fmt.Errorf("invalid describe output: %w", err)
+	}
+	if !pluginNameRe.MatchString(desc.Name) {
+		return nil, fmt.Errorf("invalid tool name %q (use letters, digits, _ and -)", desc.Name)
+	}
+	if desc.Description == "" {
+		return nil, fmt.Errorf("tool %s has no description", desc.Name)
+	}
+	if desc.Parameters == nil {
+		desc.Parameters = map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+	var risk Risk
+	if desc.Risk != "" {
+		r, err := ParseRisk(desc.Risk)
+		if err != nil {
+			return nil, fmt.Errorf("tool %s: %w", desc.Name, err)
+		}
+		risk = r
+	}
+	return &PluginTool{
+		Path:        path,
+		ReadOnly:    desc.ReadOnly,
+		Risk:        risk,
+		Latency:     time.Duration(desc.LatencyMS) * time.Millisecond,
+		name:        desc.Name,
+		description: desc.Description,
+		parameters:  desc.Parameters,
+	}, nil
+}
+
+// FindPlugins lists the executables in dir, skipping hidden files. A
+// missing dir has no plugins.
+func FindPlugins(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugins dir: %w", err)
+	}
+	var paths []string
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		info, err := os.Stat(path) // follows symlinks
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// LoadPlugins describes every plugin in dir. Plugins that fail to describe
+// themselves, or repeat another plugin's name, are left out and reported in
+// the error, which doesn't stop the others from loading.
+func LoadPlugins(ctx context.Context, dir string) ([]*PluginTool, error) {
+	paths, err := FindPlugins(dir)
+	if err != nil {
+		return nil, err
+	}
+	var plugins []*PluginTool
+	var errs []error
+	seen := map[string]string{}
+	for _, path := range paths {
+		p, err := NewPluginTool(ctx, path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("plugin %s: %w", filepath.Base(path), err))
+			continue
+		}
+		if other, ok := seen[p.name]; ok {
+			errs = append(errs, fmt.Errorf("plugin %s: tool %s is already provided by %s", filepath.Base(path), p.name, filepath.Base(other)))
+			continue
+		}
+		seen[p.name] = path
+		plugins = append(plugins, p)
+	}
+	return plugins, errors.Join(errs...)
+}
+
+func (p *PluginTool) Name() string {
+	return p.name
+}
+
+func (p *PluginTool) Meta() Meta {
+	return Meta{Category: CategoryPlugin, ReadOnly: p.ReadOnly, Risk: p.Risk, Latency: p.Latency}
+}
+
+func (p *PluginTool) Description() string {
+	return p.description
+}
+
+func (p *PluginTool) Parameters() map[string]any {
+	return p.parameters
+}
+
+func (p *PluginTool) Call(ctx context.Context, params map[string]any) (string, error) {
+	input, err := json.Marshal(params)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode parameters: %w", err)
+	}
+	timeout := p.Timeout
+	if timeout == 0 {
+		timeout = 60 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	out, err := runPlugin(ctx, p.Path, "call", input)
+	if err != nil {
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			return "", Unavailable(fmt.Errorf("plugin %s timed out after %s", p.name, timeout))
+		case nil:
+			return "", Unavailable(err)
+		}
+		return "", err
+	}
+	var res pluginResult
+	if err := json.Unmarshal(out, &res); err != nil {
+		return "", Unavailable(fmt.Errorf("plugin %s returned invalid output: %w", p.name, err))
+	}
+	if res.Error != "" {
+		return "", errors.New(res.Error)
+	}
+	if res.Result == nil {
+		return "", Unavailable(fmt.Errorf("plugin %s returned neither result nor error", p.name))
+	}
+	return *res.Result, nil
+}
+
+// runPlugin runs the plugin with one argument and returns its stdout
+func runPlugin(ctx context.Context, path, command string, stdin []byte) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, path, command)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second // don't wait on children still holding stdout after a timeout
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			if len(msg) > 500 {
+				msg = msg[:500] + "..."
+			}
+			return nil, fmt.Errorf("%s %s: %w: %s", filepath.Base(path), command, err, msg)
+		}
+		return nil, fmt.Errorf("%s %s: %w", filepath.Base(path), command, err)
+	}
+	return stdout.Bytes(), nil
+}
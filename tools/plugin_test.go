@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writePlugin writes an executable shell script plugin into dir
+func writePlugin(t *testing.T, dir, name, script string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const echoPlugin = `case "$1" in
+describe) echo '{"name": "echo", "description": "Echo the text back", "parameters": {"type": "object", "properties": {"text": {"type": "string"}}}, "read_only": true, "risk": "low", "latency_ms": 150}' ;;
+call) read -r input; case "$input" in
+	*fail*) echo '{"error": "asked to fail"}' ;;
+	*crash*) echo "boom" >&2; exit 3 ;;
+	*sleep*) sleep 5 ;;
+	*) printf '{"result": "%s"}\n' "$(echo "$input" | sed 's/"/\\"/g')" ;;
+	esac ;;
+esac
+`
+
+func TestPluginTool(t *testing.T) {
+	path := writePlugin(t, t.TempDir(), "echo.sh", echoPlugin)
+	p, err := NewPluginTool(context.Background(), path)
+	if err != nil {
+		t.Fatalf("NewPluginTool() error = %v", err)
+	}
+	if p.Name() != "echo" || p.Description() != "Echo the text back" || p.Parameters()["type"] != "object" {
+		t.Errorf("plugin = %s %q %v", p.Name(), p.Description(), p.Parameters())
+	}
+	if m := p.Meta(); m.Category != CategoryPlugin || !m.ReadOnly || m.Risk != RiskLow || m.Latency != 150*time.Millisecond {
+		t.Errorf("Meta() = %+v", m)
+	}
+
+	// The plugin prints its stdin back as the result
+	got, err := p.Call(context.Background(), map[string]any{"text": "hi"})
+	if err != nil || got != `{"text":"hi"}` {
+		t.Errorf("Call() = %q, %v", got, err)
+	}
+
+	tests := []struct {
+		text    string
+		wantErr string
+	}{
+		{"fail", "asked to fail"},
+		{"crash", "exit status 3: boom"},
+		{"sleep", "timed out"},
+	}
+	p.Timeout = 200 * time.Millisecond
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			_, err := p.Call(context.Background(), map[string]any{"text": tt.text})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Call() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadPlugins(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "echo.sh", echoPlugin)
+	writePlugin(t, dir, "echo-copy", echoPlugin)
+	writePlugin(t, dir, "broken", `echo 'not json'`)
+	writePlugin(t, dir, "badname", `echo '{"name": "has space", "description": "x"}'`)
+	writePlugin(t, dir, ".hidden", echoPlugin)
+	os.WriteFile(filepath.Join(dir, "README"), []byte("not executable"), 0644)
+	os.Mkdir(filepath.Join(dir, "subdir"), 0755)
+
+	plugins, err := LoadPlugins(context.Background(), dir)
+	if len(plugins) != 1 || plugins[0].Name() != "echo" {
+		t.Fatalf("LoadPlugins() = %v, want the echo plugin", plugins)
+	}
+	// echo-copy sorts first, so echo.sh is the duplicate
+	for _, want := range []string{"plugin broken: invalid describe output", `invalid tool name "has space"`, "plugin echo.sh: tool echo is already provided by echo-copy"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadPlugins() error = %v, want %q", err, want)
+		}
+	}
+
+	if plugins, err := LoadPlugins(context.Background(), filepath.Join(dir, "missing")); plugins != nil || err != nil {
+		t.Errorf("LoadPlugins(missing dir) = %v, %v, want nothing", plugins, err)
+	}
+}
@@ -0,0 +1,434 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rathore/langchain-agent/tools/schema"
+)
+
+// Bounds of what a Prometheus call shows the LLM
+const (
+	promMaxSeries = 50  // series of a query result
+	promMaxPoints = 20  // values shown per series of a range query
+	promMaxValues = 200 // label names or values
+)
+
+// PrometheusTool queries a Prometheus server (or one with its HTTP API, such
+// as Thanos or VictoriaMetrics): instant and range PromQL queries, label
+// names and values, and the alerts firing, so a pod crash can be matched
+// with the CPU and memory use before it.
+type PrometheusTool struct {
+	URL     string        // e.g. http://prometheus:9090
+	Token   string        // sent as a bearer token when set
+	Timeout time.Duration // of each request (default 30s)
+	Client  *http.Client  // nil: http.DefaultClient
+
+	now func() time.Time // for tests
+}
+
+func (t *PrometheusTool) Name() string { return "prometheus" }
+
+func (t *PrometheusTool) Meta() Meta {
+	return Meta{Category: CategoryRemote, ReadOnly: true}
+}
+
+func (t *PrometheusTool) Description() string {
+	return "Query Prometheus metrics with PromQL: current values ('query'), values over time ('query_range', e.g. memory use before a pod restarted), label names and values to find the right series, and the alerts firing. Times are durations back from now (30m, 2h) or RFC 3339."
+}
+
+// prometheusParams are the parameters of a Prometheus call
+type prometheusParams struct {
+	Action string `json:"action" desc:"'query' evaluates PromQL now (or at time), 'query_range' over start..end, 'labels' lists label names, 'label_values' the values of label, 'alerts' the active alerts" required:"true" enum:"query,query_range,labels,label_values,alerts"`
+	Query  string `json:"query" desc:"For query and query_range: the PromQL expression, e.g. sum by (pod) (rate(container_cpu_usage_seconds_total{namespace=\"prod\"}[5m]))"`
+	Label  string `json:"label" desc:"For label_values: the label name, e.g. pod"`
+	Match  string `json:"match" desc:"For labels and label_values: only series matching this selector, e.g. {namespace=\"prod\"}"`
+	Time   string `json:"time" desc:"For query: when to evaluate it (default: now)"`
+	Start  string `json:"start" desc:"For query_range: the start" default:"1h"`
+	End    string `json:"end" desc:"For query_range: the end (default: now)"`
+	Step   string `json:"step" desc:"For query_range: the resolution, e.g. 1m (default: the range / 60)"`
+}
+
+func (t *PrometheusTool) Parameters() map[string]any {
+	return schema.For[prometheusParams]()
+}
+
+func (t *PrometheusTool) Call(ctx context.Context, params map[string]any) (string, error) {
+	p, err := schema.Decode[prometheusParams](params)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	if t.now != nil {
+		now = t.now()
+	}
+	path, q := "", url.Values{}
+	format := formatPromResult
+	switch p.Action {
+	case "query":
+		if p.Query == "" {
+			return "", fmt.Errorf("query is required for query")
+		}
+		path = "/api/v1/query"
+		q.Set("query", p.Query)
+		if p.Time != "" {
+			at, err := parsePromTime(p.Time, now)
+			if err != nil {
+				return "", fmt.Errorf("invalid time: %w", err)
+			}
+			q.Set("time", promTimestamp(at))
+		}
+	case "query_range":
+		if p.Query == "" {
+			return "", fmt.Errorf("query is required for query_range")
+		}
+		start, err := parsePromTime(p.Start, now)
+		if err != nil {
+			return "", fmt.Errorf("invalid start: %w", err)
+		}
+		end := now
+		if p.End != "" {
+			if end, err = parsePromTime(p.End, now); err != nil {
+				return "", fmt.Errorf("invalid end: %w", err)
+			}
+		}
+		if !end.After(start) {
+			return "", fmt.Errorf("start %s is not before end %s", start.Format(time.RFC3339), end.Format(time.RFC3339))
+		}
+		step := end.Sub(start) / 60
+		if p.Step != "" {
+			if step, err = time.ParseDuration(p.Step); err != nil || step <= 0 {
+				return "", fmt.Errorf("invalid step %q: use a duration such as 30s or 5m", p.Step)
+			}
+		}
+		step = max(step.Round(time.Second), time.Second)
+		path = "/api/v1/query_range"
+		q.Set("query", p.Query)
+		q.Set("start", promTimestamp(start))
+		q.Set("end", promTimestamp(end))
+		q.Set("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+	case "labels", "label_values":
+		path = "/api/v1/labels"
+		if p.Action == "label_values" {
+			if p.Label == "" {
+				return "", fmt.Errorf("label is required for label_values")
+			}
+			path = "/api/v1/label/" + url.PathEscape(p.Label) + "/values"
+		}
+		if p.Match != "" {
+			q.Set("match[]", p.Match)
+		}
+		format = formatPromValues
+	default: // alerts
+		path = "/api/v1/alerts"
+		format = formatPromAlerts
+	}
+
+	r, err := t.get(ctx, path, q)
+	if err != nil {
+		return "", err
+	}
+	out, err := format(r.Data)
+	if err != nil {
+		return "", err
+	}
+	for _, w := range r.Warnings {
+		out += "\nWarning: " + w
+	}
+	return out, nil
+}
+
+// Version returns the version of the Prometheus server, checking that it
+// answers
+func (t *PrometheusTool) Version(ctx context.Context) (string, error) {
+	r, err := t.get(ctx, "/api/v1/status/buildinfo", nil)
+	if err != nil {
+		return "", err
+	}
+	var info struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(r.Data, &info); err != nil {
+		return "", fmt.Errorf("failed to parse Prometheus response: %w", err)
+	}
+	return info.Version, nil
+}
+
+// promResponse is the envelope of every Prometheus API response
+type promResponse struct {
+	Status    string          `json:"status"`
+	Data      json.RawMessage `json:"data"`
+	ErrorType string          `json:"errorType"`
+	Error     string          `json:"error"`
+	Warnings  []string        `json:"warnings"`
+}
+
+// get calls the API at path. Failing to reach the server, and its own
+// failures, are Unavailable; a query it turns down is not.
+func (t *PrometheusTool) get(ctx context.Context, path string, q url.Values) (*promResponse, error) {
+	timeout := t.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	u := strings.TrimRight(t.URL, "/") + path
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Prometheus URL: %w", err)
+	}
+	if t.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.Token)
+	}
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, Unavailable(fmt.Errorf("failed to reach Prometheus: %w", err))
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return nil, Unavailable(fmt.Errorf("failed to read Prometheus response: %w", err))
+	}
+
+	var r promResponse
+	if err := json.Unmarshal(body, &r); err != nil || r.Status == "" {
+		err = fmt.Errorf("Prometheus returned %s: %s", resp.Status, truncateBody(body))
+		if resp.StatusCode >= 500 {
+			return nil, Unavailable(err)
+		}
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			return nil, fmt.Errorf("%w; check the Prometheus token", err)
+		}
+		return nil, err
+	}
+	if r.Status != "success" {
+		err := fmt.Errorf("Prometheus %s error: %s", r.ErrorType, r.Error)
+		if resp.StatusCode >= 500 && r.ErrorType != "execution" {
+			return nil, Unavailable(err)
+		}
+		return nil, err
+	}
+	return &r, nil
+}
+
+// truncateBody shortens a non-API response (e.g. a proxy's error page) for
+// an error message
+func truncateBody(body []byte) string {
+	s := strings.Join(strings.Fields(string(body)), " ")
+	if len(s) > 200 {
+		s = s[:200] + "..."
+	}
+	if s == "" {
+		s = "an empty body"
+	}
+	return s
+}
+
+// parsePromTime parses a time given as a duration back from now (90m),
+// an RFC 3339 time or a Unix timestamp
+func parsePromTime(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(strings.TrimPrefix(s, "-")); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9)), nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a duration (1h), RFC 3339 time or Unix timestamp", s)
+}
+
+func promTimestamp(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixMilli())/1000, 'f', -1, 64)
+}
+
+// promSample is a [time, "value"] pair
+type promSample [2]any
+
+func (s promSample) time() time.Time {
+	f, _ := s[0].(float64)
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(frac*1e9)).UTC()
+}
+
+func (s promSample) value() string {
+	v, _ := s[1].(string)
+	return v
+}
+
+// formatPromResult formats the data of a query or query_range response:
+// a line per series of a vector, a summary and evenly spaced values per
+// series of a matrix
+func formatPromResult(data json.RawMessage) (string, error) {
+	var r struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return "", fmt.Errorf("failed to parse Prometheus response: %w", err)
+	}
+	switch r.ResultType {
+	case "scalar", "string":
+		var s promSample
+		if err := json.Unmarshal(r.Result, &s); err != nil {
+			return "", fmt.Errorf("failed to parse Prometheus response: %w", err)
+		}
+		return s.value(), nil
+	}
+
+	var series []struct {
+		Metric map[string]string `json:"metric"`
+		Value  promSample        `json:"value"`
+		Values []promSample      `json:"values"`
+	}
+	if err := json.Unmarshal(r.Result, &series); err != nil {
+		return "", fmt.Errorf("failed to parse Prometheus response: %w", err)
+	}
+	if len(series) == 0 {
+		return "No series matched: check the metric and label names with labels and label_values.", nil
+	}
+	var sb strings.Builder
+	for i, s := range series {
+		if i == promMaxSeries {
+			fmt.Fprintf(&sb, "... and %d more series; narrow the query, e.g. with topk or a label filter\n", len(series)-i)
+			break
+		}
+		labels := formatPromLabels(s.Metric)
+		if r.ResultType == "vector" {
+			fmt.Fprintf(&sb, "%s %s\n", labels, s.Value.value())
+			continue
+		}
+		fmt.Fprintf(&sb, "%s\n  %s\n", labels, summarizePromValues(s.Values))
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+// summarizePromValues describes the values of a series over time: their
+// count, minimum, maximum and last, and at most promMaxPoints of them
+func summarizePromValues(values []promSample) string {
+	if len(values) == 0 {
+		return "no values"
+	}
+	minI, maxI := 0, 0
+	nums := make([]float64, len(values))
+	for i, v := range values {
+		nums[i], _ = strconv.ParseFloat(v.value(), 64)
+		if nums[i] < nums[minI] {
+			minI = i
+		}
+		if nums[i] > nums[maxI] {
+			maxI = i
+		}
+	}
+	last := values[len(values)-1]
+	at := func(s promSample) string { return s.time().Format("15:04:05") }
+	summary := fmt.Sprintf("%d values %s to %s UTC; min %s at %s, max %s at %s, last %s",
+		len(values), values[0].time().Format("2006-01-02 15:04:05"), at(last),
+		values[minI].value(), at(values[minI]), values[maxI].value(), at(values[maxI]), last.value())
+
+	points := make([]string, 0, promMaxPoints)
+	for i := range min(len(values), promMaxPoints) {
+		v := values[i]
+		if len(values) > promMaxPoints {
+			v = values[i*(len(values)-1)/(promMaxPoints-1)]
+		}
+		points = append(points, at(v)+" "+v.value())
+	}
+	return summary + "\n  " + strings.Join(points, ", ")
+}
+
+// formatPromLabels writes labels as PromQL does: name{a="1", b="2"}
+func formatPromLabels(metric map[string]string) string {
+	var names []string
+	for name := range metric {
+		if name != "__name__" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf("%s=%q", name, metric[name])
+	}
+	return metric["__name__"] + "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// formatPromValues lists the label names or values of a labels or
+// label_values response, at most promMaxValues
+func formatPromValues(data json.RawMessage) (string, error) {
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return "", fmt.Errorf("failed to parse Prometheus response: %w", err)
+	}
+	if len(values) == 0 {
+		return "None found.", nil
+	}
+	sort.Strings(values)
+	more := ""
+	if len(values) > promMaxValues {
+		more = fmt.Sprintf("\n... and %d more; narrow them with match", len(values)-promMaxValues)
+		values = values[:promMaxValues]
+	}
+	return fmt.Sprintf("%d: %s%s", len(values), strings.Join(values, ", "), more), nil
+}
+
+// formatPromAlerts lists the active alerts, firing ones first
+func formatPromAlerts(data json.RawMessage) (string, error) {
+	var r struct {
+		Alerts []struct {
+			Labels      map[string]string `json:"labels"`
+			Annotations map[string]string `json:"annotations"`
+			State       string            `json:"state"`
+			ActiveAt    time.Time         `json:"activeAt"`
+			Value       string            `json:"value"`
+		} `json:"alerts"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return "", fmt.Errorf("failed to parse Prometheus response: %w", err)
+	}
+	if len(r.Alerts) == 0 {
+		return "No alerts are active.", nil
+	}
+	sort.SliceStable(r.Alerts, func(i, j int) bool {
+		return r.Alerts[i].State == "firing" && r.Alerts[j].State != "firing"
+	})
+	var sb strings.Builder
+	for _, a := range r.Alerts {
+		labels := make(map[string]string, len(a.Labels))
+		for k, v := range a.Labels {
+			labels[k] = v
+		}
+		name := labels["alertname"]
+		delete(labels, "alertname")
+		fmt.Fprintf(&sb, "%s %s%s since %s", a.State, name, formatPromLabels(labels), a.ActiveAt.UTC().Format("2006-01-02 15:04:05 UTC"))
+		if a.Value != "" {
+			fmt.Fprintf(&sb, ", value %s", a.Value)
+		}
+		if text := a.Annotations["summary"]; text != "" {
+			fmt.Fprintf(&sb, ": %s", text)
+		} else if text := a.Annotations["description"]; text != "" {
+			fmt.Fprintf(&sb, ": %s", text)
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
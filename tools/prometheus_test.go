@@ -0,0 +1,168 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakePrometheus answers the Prometheus API paths with canned bodies and
+// records the queries it got
+func fakePrometheus(t *testing.T, bodies map[string]string) (*PrometheusTool, *[]url.Values) {
+	t.Helper()
+	var queries []url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		queries = append(queries, r.URL.Query())
+		body, ok := bodies[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if strings.Contains(body, `"status":"error"`) {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	return &PrometheusTool{URL: srv.URL + "/", Token: "secret", now: func() time.Time { return now }}, &queries
+}
+
+func TestPrometheusTool_Query(t *testing.T) {
+	tool, queries := fakePrometheus(t, map[string]string{
+		"/api/v1/query": `{"status":"success","data":{"resultType":"vector","result":[
+			{"metric":{"__name__":"up","job":"api","instance":"10.0.0.1:8080"},"value":[1792065600,"1"]},
+			{"metric":{"__name__":"up","job":"db","instance":"10.0.0.2:9187"},"value":[1792065600,"0"]}]}}`,
+	})
+	out, err := tool.Call(context.Background(), map[string]any{"action": "query", "query": "up", "time": "10m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "up{instance=\"10.0.0.1:8080\", job=\"api\"} 1\nup{instance=\"10.0.0.2:9187\", job=\"db\"} 0"
+	if out != want {
+		t.Errorf("query =\n%s\nwant:\n%s", out, want)
+	}
+	if q := (*queries)[0]; q.Get("query") != "up" || q.Get("time") != "1792065000" {
+		t.Errorf("query params = %v", q)
+	}
+}
+
+func TestPrometheusTool_QueryRange(t *testing.T) {
+	var values []string
+	for i := range 61 {
+		values = append(values, fmt.Sprintf(`[%d,"%d"]`, 1792062000+60*i, 100+i%30))
+	}
+	tool, queries := fakePrometheus(t, map[string]string{
+		"/api/v1/query_range": `{"status":"success","warnings":["partial response"],"data":{"resultType":"matrix","result":[
+			{"metric":{"pod":"api-1"},"values":[` + strings.Join(values, ",") + `]}]}}`,
+	})
+	out, err := tool.Call(context.Background(), map[string]any{"action": "query_range", "query": "container_memory_working_set_bytes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := (*queries)[0]
+	if q.Get("start") != "1792062000" || q.Get("end") != "1792065600" || q.Get("step") != "60" {
+		t.Errorf("range params = %v, want the last hour at 1m", q)
+	}
+	for _, want := range []string{
+		`{pod="api-1"}`,
+		"61 values 2026-10-15 11:00:00 to 12:00:00 UTC; min 100 at 11:00:00, max 129 at 11:29:00, last 100",
+		"11:00:00 100, 11:03:00 103,",
+		"\nWarning: partial response",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("query_range output lacks %q:\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, ", "); n > promMaxPoints+5 {
+		t.Errorf("query_range shows %d values, want at most %d:\n%s", n, promMaxPoints, out)
+	}
+
+	if _, err := tool.Call(context.Background(), map[string]any{"action": "query_range", "query": "up", "start": "1h", "end": "2h"}); err == nil || !strings.Contains(err.Error(), "is not before end") {
+		t.Errorf("reversed range error = %v", err)
+	}
+}
+
+func TestPrometheusTool_Labels(t *testing.T) {
+	tool, queries := fakePrometheus(t, map[string]string{
+		"/api/v1/label/pod/values": `{"status":"success","data":["web-2","api-1"]}`,
+	})
+	out, err := tool.Call(context.Background(), map[string]any{"action": "label_values", "label": "pod", "match": `{namespace="prod"}`})
+	if err != nil || out != "2: api-1, web-2" {
+		t.Errorf("label_values = %q, %v", out, err)
+	}
+	if got := (*queries)[0]["match[]"]; len(got) != 1 || got[0] != `{namespace="prod"}` {
+		t.Errorf("match[] = %v", got)
+	}
+	if _, err := tool.Call(context.Background(), map[string]any{"action": "label_values"}); err == nil {
+		t.Error("label_values without a label succeeded")
+	}
+}
+
+func TestPrometheusTool_Alerts(t *testing.T) {
+	tool, _ := fakePrometheus(t, map[string]string{
+		"/api/v1/alerts": `{"status":"success","data":{"alerts":[
+			{"labels":{"alertname":"HighLatency","service":"api"},"state":"pending","activeAt":"2026-10-15T11:58:00Z"},
+			{"labels":{"alertname":"KubePodCrashLooping","pod":"api-1"},"annotations":{"summary":"Pod is crash looping."},"state":"firing","activeAt":"2026-10-15T11:40:00Z","value":"3e+00"}]}}`,
+	})
+	out, err := tool.Call(context.Background(), map[string]any{"action": "alerts"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "firing KubePodCrashLooping{pod=\"api-1\"} since 2026-10-15 11:40:00 UTC, value 3e+00: Pod is crash looping.\n" +
+		"pending HighLatency{service=\"api\"} since 2026-10-15 11:58:00 UTC"
+	if out != want {
+		t.Errorf("alerts =\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestPrometheusTool_Errors(t *testing.T) {
+	tool, _ := fakePrometheus(t, map[string]string{
+		"/api/v1/query": `{"status":"error","errorType":"bad_data","error":"parse error at char 5"}`,
+	})
+	_, err := tool.Call(context.Background(), map[string]any{"action": "query", "query": "up{"})
+	if err == nil || !strings.Contains(err.Error(), "bad_data error: parse error at char 5") || IsUnavailable(err) {
+		t.Errorf("bad query error = %v", err)
+	}
+
+	tool.Token = "wrong"
+	if _, err := tool.Call(context.Background(), map[string]any{"action": "query", "query": "up"}); err == nil || !strings.Contains(err.Error(), "check the Prometheus token") {
+		t.Errorf("unauthorized error = %v", err)
+	}
+
+	// A server that can't be reached opens the circuit breaker
+	down := &PrometheusTool{URL: "http://127.0.0.1:1"}
+	if _, err := down.Call(context.Background(), map[string]any{"action": "alerts"}); !IsUnavailable(err) {
+		t.Errorf("unreachable error = %v, want Unavailable", err)
+	}
+}
+
+func TestParsePromTime(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"90m", now.Add(-90 * time.Minute)},
+		{"-2h", now.Add(-2 * time.Hour)},
+		{"2026-10-15T10:30:00Z", time.Date(2026, 10, 15, 10, 30, 0, 0, time.UTC)},
+		{"1792065600", now},
+	}
+	for _, tt := range tests {
+		if got, err := parsePromTime(tt.in, now); err != nil || !got.Equal(tt.want) {
+			t.Errorf("parsePromTime(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+	if _, err := parsePromTime("yesterday", now); err == nil {
+		t.Error("parsePromTime(yesterday) succeeded")
+	}
+}
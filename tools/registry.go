@@ -0,0 +1,273 @@
+package tools
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Tool categories, for listings and policies
+const (
+	CategoryLocal     = "local"     // runs on this machine (shell)
+	CategoryRemote    = "remote"    // runs on other hosts (ssh)
+	CategoryDevice    = "device"    // operates edge devices
+	CategoryMCP       = "mcp"       // an MCP server
+	CategoryKnowledge = "knowledge" // searches indexed documents
+	CategoryPlugin    = "plugin"    // an external plugin executable
+)
+
+// Risk is how much harm a tool call can do if it goes wrong, for approvals
+// and policies
+type Risk string
+
+// Risk levels, in increasing order
+const (
+	RiskLow    Risk = "low"    // only looks at state
+	RiskMedium Risk = "medium" // changes state in a limited way
+	RiskHigh   Risk = "high"   // runs arbitrary commands or drives hardware
+)
+
+// riskLevels orders the risks
+var riskLevels = map[Risk]int{RiskLow: 1, RiskMedium: 2, RiskHigh: 3}
+
+// ParseRisk parses "low", "medium" or "high"
+func ParseRisk(s string) (Risk, error) {
+	r := Risk(strings.ToLower(strings.TrimSpace(s)))
+	if riskLevels[r] == 0 {
+		return "", fmt.Errorf("invalid risk %q (use low, medium or high)", s)
+	}
+	return r, nil
+}
+
+// AtLeast reports whether r is min or higher
+func (r Risk) AtLeast(min Risk) bool {
+	return riskLevels[r] >= riskLevels[min]
+}
+
+// Meta is what the registry records about a tool besides the Tool itself
+type Meta struct {
+	Category string
+	// Namespace qualifies the tool's name when another tool already has it:
+	// a second read_file from namespace "fs2" is registered as fs2_read_file
+	Namespace string
+	// ReadOnly tools only look at state, never change it, so policies may
+	// allow them where mutating tools need approval
+	ReadOnly bool
+	// ReadOnlyWhen marks the calls that only look at state of a tool that
+	// isn't ReadOnly as a whole, e.g. the workspace tool's "read" action
+	ReadOnlyWhen *ReadOnlyCalls
+	// Risk is how much harm the tool's calls that change state can do (""
+	// = medium, see CallRisk)
+	Risk Risk
+	// Latency is how long a call typically takes (0 = unknown), so slow
+	// tools can be left for when a faster one won't do
+	Latency time.Duration
+}
+
+// MetaDeclarer is implemented by tools that declare their own metadata.
+// Register fills the fields its Meta leaves unset from the tool's.
+type MetaDeclarer interface {
+	Meta() Meta
+}
+
+// ReadOnlyCalls are the calls whose parameter Param is one of Values
+type ReadOnlyCalls struct {
+	Param  string
+	Values []string
+}
+
+// ReadOnlyCall reports whether a call with params only looks at state
+func (m Meta) ReadOnlyCall(params map[string]any) bool {
+	if m.ReadOnly {
+		return true
+	}
+	if m.ReadOnlyWhen == nil {
+		return false
+	}
+	v, ok := params[m.ReadOnlyWhen.Param].(string)
+	return ok && slices.Contains(m.ReadOnlyWhen.Values, v)
+}
+
+// CallRisk is the risk of a call with params: low for a read-only call,
+// otherwise the tool's Risk
+func (m Meta) CallRisk(params map[string]any) Risk {
+	switch {
+	case m.ReadOnlyCall(params):
+		return RiskLow
+	case m.Risk == "":
+		return RiskMedium
+	}
+	return m.Risk
+}
+
+// withDeclared fills the fields m leaves unset from the metadata the tool
+// declares
+func (m Meta) withDeclared(d Meta) Meta {
+	if m.Category == "" {
+		m.Category = d.Category
+	}
+	m.ReadOnly = m.ReadOnly || d.ReadOnly
+	if m.ReadOnlyWhen == nil {
+		m.ReadOnlyWhen = d.ReadOnlyWhen
+	}
+	if m.Risk == "" {
+		m.Risk = d.Risk
+	}
+	if m.Latency == 0 {
+		m.Latency = d.Latency
+	}
+	return m
+}
+
+// declaredMeta returns the metadata t, or the tool it wraps, declares
+func declaredMeta(t Tool) (Meta, bool) {
+	for {
+		if d, ok := t.(MetaDeclarer); ok {
+			return d.Meta(), true
+		}
+		u, ok := t.(interface{ Unwrap() Tool })
+		if !ok {
+			return Meta{}, false
+		}
+		t = u.Unwrap()
+	}
+}
+
+// Registry holds the agent's tools by name, with their metadata and aliases
+type Registry struct {
+	order   []string // registered names, in registration order
+	tools   map[string]Tool
+	meta    map[string]Meta
+	aliases map[string]string // alias → registered name
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{tools: map[string]Tool{}, meta: map[string]Meta{}, aliases: map[string]string{}}
+}
+
+// namespacedTool is a tool registered under a qualified name
+type namespacedTool struct {
+	Tool
+	name string
+}
+
+func (t namespacedTool) Name() string { return t.name }
+
+// Unwrap returns the tool as it was registered
+func (t namespacedTool) Unwrap() Tool { return t.Tool }
+
+// Register adds t and returns the name it is registered under: its own, or
+// namespace_name when that is taken and meta has a namespace. Fields meta
+// leaves unset are taken from the tool's own Meta, if it is a MetaDeclarer.
+func (r *Registry) Register(t Tool, meta Meta) (string, error) {
+	if d, ok := declaredMeta(t); ok {
+		meta = meta.withDeclared(d)
+	}
+	name := t.Name()
+	if r.taken(name) {
+		if meta.Namespace == "" {
+			return "", fmt.Errorf("tool %s is already registered", name)
+		}
+		name = meta.Namespace + "_" + name
+		if r.taken(name) {
+			return "", fmt.Errorf("tool %s is already registered", name)
+		}
+		t = namespacedTool{Tool: t, name: name}
+	}
+	r.order = append(r.order, name)
+	r.tools[name] = t
+	r.meta[name] = meta
+	return name, nil
+}
+
+// Alias makes alias another name for a registered tool, e.g. "bash" for
+// shell, for models that guess tool names
+func (r *Registry) Alias(alias, name string) error {
+	if _, ok := r.tools[name]; !ok {
+		return fmt.Errorf("unknown tool: %s", name)
+	}
+	if r.taken(alias) {
+		return fmt.Errorf("tool %s is already registered", alias)
+	}
+	r.aliases[alias] = name
+	return nil
+}
+
+func (r *Registry) taken(name string) bool {
+	_, tool := r.tools[name]
+	_, alias := r.aliases[name]
+	return tool || alias
+}
+
+// Lookup finds a tool by registered name or alias
+func (r *Registry) Lookup(name string) (Tool, bool) {
+	if target, ok := r.aliases[name]; ok {
+		name = target
+	}
+	t, ok := r.tools[name]
+	return t, ok
+}
+
+// Meta returns the metadata of the tool with the given name or alias
+func (r *Registry) Meta(name string) (Meta, bool) {
+	if target, ok := r.aliases[name]; ok {
+		name = target
+	}
+	m, ok := r.meta[name]
+	return m, ok
+}
+
+// Tools returns the registered tools in registration order. Their Name is
+// the registered name.
+func (r *Registry) Tools() []Tool {
+	out := make([]Tool, len(r.order))
+	for i, name := range r.order {
+		out[i] = r.tools[name]
+	}
+	return out
+}
+
+// Category returns the registered tools in a category
+func (r *Registry) Category(category string) []Tool {
+	var out []Tool
+	for _, name := range r.order {
+		if r.meta[name].Category == category {
+			out = append(out, r.tools[name])
+		}
+	}
+	return out
+}
+
+// Aliases returns the aliases of the named tool, sorted
+func (r *Registry) Aliases(name string) []string {
+	var out []string
+	for alias, target := range r.aliases {
+		if target == name {
+			out = append(out, alias)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Filter returns a registry of the tools keep accepts, with their metadata
+// and aliases
+func (r *Registry) Filter(keep func(name string) bool) *Registry {
+	out := NewRegistry()
+	for _, name := range r.order {
+		if keep(name) {
+			out.order = append(out.order, name)
+			out.tools[name] = r.tools[name]
+			out.meta[name] = r.meta[name]
+		}
+	}
+	for alias, target := range r.aliases {
+		if _, ok := out.tools[target]; ok {
+			out.aliases[alias] = target
+		}
+	}
+	return out
+}
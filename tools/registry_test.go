@@ -0,0 +1,154 @@
+package tools
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// namedTool is a minimal tool for registry tests
+type namedTool struct{ name, result string }
+
+func (t namedTool) Name() string               { return t.name }
+func (t namedTool) Description() string        { return "test tool " + t.name }
+func (t namedTool) Parameters() map[string]any { return map[string]any{"type": "object"} }
+func (t namedTool) Call(ctx context.Context, params map[string]any) (string, error) {
+	return t.result, nil
+}
+
+func TestRegistry_Namespacing(t *testing.T) {
+	r := NewRegistry()
+	if name, err := r.Register(namedTool{"read_file", "fs1"}, Meta{Category: CategoryMCP, Namespace: "fs1", ReadOnly: true}); err != nil || name != "read_file" {
+		t.Fatalf("first read_file registered as %q, %v", name, err)
+	}
+	name, err := r.Register(namedTool{"read_file", "fs2"}, Meta{Category: CategoryMCP, Namespace: "fs2"})
+	if err != nil || name != "fs2_read_file" {
+		t.Fatalf("second read_file registered as %q, %v; want fs2_read_file", name, err)
+	}
+	if _, err := r.Register(namedTool{"read_file", "fs3"}, Meta{}); err == nil {
+		t.Error("a collision without a namespace should fail")
+	}
+
+	got, ok := r.Lookup("fs2_read_file")
+	if !ok || got.Name() != "fs2_read_file" {
+		t.Fatalf("Lookup(fs2_read_file) = %v, %v", got, ok)
+	}
+	if out, _ := got.Call(context.Background(), nil); out != "fs2" {
+		t.Errorf("fs2_read_file called the wrong tool: %q", out)
+	}
+	if u, ok := got.(interface{ Unwrap() Tool }); !ok || u.Unwrap().Name() != "read_file" {
+		t.Error("namespaced tool should unwrap to the original")
+	}
+	if meta, _ := r.Meta("read_file"); !meta.ReadOnly {
+		t.Error("read_file should keep its metadata")
+	}
+	if names := toolNames(r.Tools()); names != "read_file,fs2_read_file" {
+		t.Errorf("Tools() = %s", names)
+	}
+}
+
+func TestRegistry_Aliases(t *testing.T) {
+	r := NewRegistry()
+	r.Register(namedTool{"shell", "ran"}, Meta{Category: CategoryLocal})
+	r.Register(namedTool{"wiki", "found"}, Meta{Category: CategoryKnowledge, ReadOnly: true})
+	if err := r.Alias("bash", "shell"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Alias("sh", "shell"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Alias("x", "nope"); err == nil {
+		t.Error("aliasing an unknown tool should fail")
+	}
+	if err := r.Alias("wiki", "shell"); err == nil {
+		t.Error("an alias shadowing a tool should fail")
+	}
+	if _, err := r.Register(namedTool{"bash", ""}, Meta{}); err == nil {
+		t.Error("registering a tool under an alias should fail")
+	}
+
+	if got, ok := r.Lookup("bash"); !ok || got.Name() != "shell" {
+		t.Errorf("Lookup(bash) = %v, %v", got, ok)
+	}
+	if meta, ok := r.Meta("sh"); !ok || meta.Category != CategoryLocal {
+		t.Errorf("Meta(sh) = %+v, %v", meta, ok)
+	}
+	if got := r.Aliases("shell"); len(got) != 2 || got[0] != "bash" || got[1] != "sh" {
+		t.Errorf("Aliases(shell) = %v", got)
+	}
+	if names := toolNames(r.Category(CategoryKnowledge)); names != "wiki" {
+		t.Errorf("Category(knowledge) = %s", names)
+	}
+
+	only := r.Filter(func(name string) bool { return name == "wiki" })
+	if names := toolNames(only.Tools()); names != "wiki" {
+		t.Errorf("filtered Tools() = %s", names)
+	}
+	if _, ok := only.Lookup("bash"); ok {
+		t.Error("aliases of filtered-out tools should be dropped")
+	}
+	kept := r.Filter(func(name string) bool { return name == "shell" })
+	if _, ok := kept.Lookup("bash"); !ok {
+		t.Error("aliases of kept tools should be kept")
+	}
+}
+
+func toolNames(ts []Tool) string {
+	var out string
+	for i, t := range ts {
+		if i > 0 {
+			out += ","
+		}
+		out += t.Name()
+	}
+	return out
+}
+
+func TestMeta_ReadOnlyCall(t *testing.T) {
+	workspace := Meta{ReadOnlyWhen: &ReadOnlyCalls{Param: "action", Values: []string{"list", "read"}}}
+	for _, tt := range []struct {
+		meta   Meta
+		params map[string]any
+		want   bool
+	}{
+		{Meta{ReadOnly: true}, nil, true},
+		{Meta{}, map[string]any{"action": "read"}, false},
+		{workspace, map[string]any{"action": "read"}, true},
+		{workspace, map[string]any{"action": "write"}, false},
+		{workspace, map[string]any{}, false},
+	} {
+		if got := tt.meta.ReadOnlyCall(tt.params); got != tt.want {
+			t.Errorf("ReadOnlyCall(%v) = %v, want %v", tt.params, got, tt.want)
+		}
+	}
+}
+
+func TestRegistry_DeclaredMeta(t *testing.T) {
+	r := NewRegistry()
+	r.Register(WithLimits(&SSHTool{}, Limits{}), Meta{})
+	r.Register(&ShellTool{}, Meta{Category: CategoryRemote, Latency: time.Minute})
+	r.Register(namedTool{name: "plain"}, Meta{})
+
+	if m, _ := r.Meta("ssh"); m.Category != CategoryRemote || m.Risk != RiskHigh || m.Latency != 2*time.Second {
+		t.Errorf("ssh meta through limits = %+v", m)
+	}
+	// The caller's fields win over the tool's
+	if m, _ := r.Meta("shell"); m.Category != CategoryRemote || m.Risk != RiskHigh || m.Latency != time.Minute {
+		t.Errorf("shell meta = %+v", m)
+	}
+	if m, _ := r.Meta("plain"); m.CallRisk(nil) != RiskMedium {
+		t.Errorf("undeclared risk = %s, want medium", m.CallRisk(nil))
+	}
+}
+
+func TestParseRisk(t *testing.T) {
+	if r, err := ParseRisk(" High "); err != nil || r != RiskHigh {
+		t.Errorf("ParseRisk(High) = %q, %v", r, err)
+	}
+	if _, err := ParseRisk("severe"); err == nil {
+		t.Error("ParseRisk accepted severe")
+	}
+	if !RiskHigh.AtLeast(RiskMedium) || RiskLow.AtLeast(RiskMedium) || !RiskMedium.AtLeast(RiskMedium) {
+		t.Error("AtLeast orders risks wrongly")
+	}
+}
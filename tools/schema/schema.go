@@ -0,0 +1,364 @@
+// Package schema lets tools declare their parameters as a Go struct: For
+// builds the JSON schema a tool's Parameters returns, and Decode turns the
+// params of a call into the struct, checked against the same tags, instead
+// of casting each map entry by hand.
+//
+//	type readParams struct {
+//		Name  string `json:"name" desc:"File to read" required:"true"`
+//		Mode  string `json:"mode" desc:"How to read it" enum:"text,hex" default:"text"`
+//		Limit int    `json:"limit" desc:"Lines to return" min:"1" default:"200"`
+//	}
+//
+// Tags:
+//   - json: the parameter name (fields without one are skipped)
+//   - desc: its description for the LLM
+//   - required:"true": calls must give it
+//   - enum: the comma-separated values it may take
+//   - default: the value a call that leaves it out gets
+//   - min, max: bounds of a number
+//
+// Fields may be strings, bools, integers, floats, []string, map[string]any,
+// any, or pointers to these, which stay nil when a call leaves them out.
+// A null counts as left out, and so does an empty string where the
+// parameter is required or has a default.
+// Models are often loose with types, so numbers and bools given as strings
+// ("17", "true") are accepted, as are whole numbers for strings.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// field is a parameter declared by a struct field
+type field struct {
+	index    int
+	name     string
+	typ      reflect.Type // the field's type, without a pointer
+	pointer  bool
+	desc     string
+	required bool
+	enum     []string
+	def      string
+	hasDef   bool
+	min, max *float64
+}
+
+// object is a parsed parameter struct
+type object struct {
+	fields []field
+	schema map[string]any
+}
+
+var objects sync.Map // reflect.Type → *object
+
+// For returns the JSON schema of T's parameters. T must be a struct; a
+// field of an unsupported type or a malformed tag panics, as a mistake in
+// the tool rather than in a call.
+func For[T any]() map[string]any {
+	return clone(parse(reflect.TypeFor[T]()).schema)
+}
+
+// Decode returns params as a T: each field gets its parameter, converted to
+// the field's type, or its default. It fails on a missing required
+// parameter, a value that doesn't convert or one outside its enum or bounds.
+// Parameters T doesn't declare are ignored.
+func Decode[T any](params map[string]any) (T, error) {
+	var v T
+	obj := parse(reflect.TypeFor[T]())
+	rv := reflect.ValueOf(&v).Elem()
+	for _, f := range obj.fields {
+		raw, ok := params[f.name]
+		if s, isString := raw.(string); raw == nil || (isString && s == "" && (f.required || f.hasDef)) {
+			ok = false
+		}
+		if !ok {
+			if f.required {
+				return v, fmt.Errorf("%s parameter required", f.name)
+			}
+			if !f.hasDef {
+				continue
+			}
+			raw = f.def
+		}
+		val, err := convert(f, raw)
+		if err != nil {
+			return v, err
+		}
+		if err := f.check(val); err != nil {
+			return v, err
+		}
+		dst := rv.Field(f.index)
+		if f.pointer {
+			p := reflect.New(f.typ)
+			p.Elem().Set(val)
+			dst.Set(p)
+		} else {
+			dst.Set(val)
+		}
+	}
+	return v, nil
+}
+
+// parse reads (once) the fields of a parameter struct type
+func parse(t reflect.Type) *object {
+	if obj, ok := objects.Load(t); ok {
+		return obj.(*object)
+	}
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("schema: %s is not a struct", t))
+	}
+	obj := &object{}
+	props := map[string]any{}
+	required := []string{}
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "" || name == "-" || !sf.IsExported() {
+			continue
+		}
+		f := field{index: i, name: name, typ: sf.Type, desc: sf.Tag.Get("desc"), required: sf.Tag.Get("required") == "true"}
+		if f.typ.Kind() == reflect.Pointer {
+			f.typ, f.pointer = f.typ.Elem(), true
+		}
+		typ := jsonType(f.typ)
+		if typ == "" {
+			panic(fmt.Sprintf("schema: %s.%s: unsupported type %s", t, sf.Name, sf.Type))
+		}
+		prop := map[string]any{}
+		if typ != "any" {
+			prop["type"] = typ
+		}
+		if typ == "array" {
+			prop["items"] = map[string]any{"type": "string"}
+		}
+		if f.desc != "" {
+			prop["description"] = f.desc
+		}
+		if enum := sf.Tag.Get("enum"); enum != "" {
+			f.enum = strings.Split(enum, ",")
+			prop["enum"] = f.enum
+		}
+		for _, bound := range []struct {
+			tag, key string
+			dst      **float64
+		}{{"min", "minimum", &f.min}, {"max", "maximum", &f.max}} {
+			s := sf.Tag.Get(bound.tag)
+			if s == "" {
+				continue
+			}
+			n, err := strconv.ParseFloat(s, 64)
+			if err != nil || (typ != "integer" && typ != "number") {
+				panic(fmt.Sprintf("schema: %s.%s: invalid %s %q", t, sf.Name, bound.tag, s))
+			}
+			*bound.dst = &n
+			prop[bound.key] = n
+		}
+		if def, ok := sf.Tag.Lookup("default"); ok {
+			f.def, f.hasDef = def, true
+			val, err := convert(f, def)
+			if err == nil {
+				err = f.check(val)
+			}
+			if err != nil {
+				panic(fmt.Sprintf("schema: %s.%s: invalid default: %v", t, sf.Name, err))
+			}
+			prop["default"] = val.Interface()
+		}
+		props[name] = prop
+		if f.required {
+			required = append(required, name)
+		}
+		obj.fields = append(obj.fields, f)
+	}
+	obj.schema = map[string]any{"type": "object", "properties": props, "required": required}
+	actual, _ := objects.LoadOrStore(t, obj)
+	return actual.(*object)
+}
+
+// jsonType is the JSON schema type of a field type; "" if unsupported
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.String {
+			return "array"
+		}
+	case reflect.Map:
+		if t.Key().Kind() == reflect.String && t.Elem().Kind() == reflect.Interface {
+			return "object"
+		}
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return "any"
+		}
+	}
+	return ""
+}
+
+// convert turns a parameter value into a value of the field's type
+func convert(f field, raw any) (reflect.Value, error) {
+	t := f.typ
+	switch t.Kind() {
+	case reflect.String:
+		switch x := raw.(type) {
+		case string:
+			return reflect.ValueOf(x).Convert(t), nil
+		case float64, int, int64, json.Number:
+			if n, ok := number(x); ok && n == math.Trunc(n) {
+				return reflect.ValueOf(strconv.FormatFloat(n, 'f', -1, 64)).Convert(t), nil
+			}
+		}
+		return reflect.Value{}, fmt.Errorf("%s must be a string (got %v)", f.name, raw)
+	case reflect.Bool:
+		switch x := raw.(type) {
+		case bool:
+			return reflect.ValueOf(x).Convert(t), nil
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(x)); err == nil {
+				return reflect.ValueOf(b).Convert(t), nil
+			}
+		}
+		return reflect.Value{}, fmt.Errorf("%s must be true or false (got %v)", f.name, raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := number(raw)
+		if !ok || n != math.Trunc(n) {
+			return reflect.Value{}, fmt.Errorf("%s must be an integer (got %v)", f.name, raw)
+		}
+		v := reflect.New(t).Elem()
+		if t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint64 {
+			if n < 0 || v.OverflowUint(uint64(n)) {
+				return reflect.Value{}, fmt.Errorf("%s is out of range (got %v)", f.name, raw)
+			}
+			v.SetUint(uint64(n))
+		} else {
+			if v.OverflowInt(int64(n)) {
+				return reflect.Value{}, fmt.Errorf("%s is out of range (got %v)", f.name, raw)
+			}
+			v.SetInt(int64(n))
+		}
+		return v, nil
+	case reflect.Float32, reflect.Float64:
+		n, ok := number(raw)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("%s must be a number (got %v)", f.name, raw)
+		}
+		return reflect.ValueOf(n).Convert(t), nil
+	case reflect.Slice:
+		var items []string
+		switch x := raw.(type) {
+		case []string:
+			items = x
+		case string:
+			items = []string{x}
+		case []any:
+			for _, item := range x {
+				s, ok := item.(string)
+				if !ok {
+					return reflect.Value{}, fmt.Errorf("%s must be a list of strings (got %v)", f.name, raw)
+				}
+				items = append(items, s)
+			}
+		default:
+			return reflect.Value{}, fmt.Errorf("%s must be a list of strings (got %v)", f.name, raw)
+		}
+		return reflect.ValueOf(items).Convert(t), nil
+	case reflect.Map:
+		m, ok := raw.(map[string]any)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("%s must be an object (got %v)", f.name, raw)
+		}
+		return reflect.ValueOf(m).Convert(t), nil
+	}
+	v := reflect.New(t).Elem()
+	v.Set(reflect.ValueOf(raw))
+	return v, nil
+}
+
+// number reads a JSON number, an int or a numeric string
+func number(raw any) (float64, bool) {
+	switch x := raw.(type) {
+	case float64:
+		return x, true
+	case int:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case json.Number:
+		n, err := x.Float64()
+		return n, err == nil
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+		return n, err == nil && !math.IsNaN(n) && !math.IsInf(n, 0)
+	}
+	return 0, false
+}
+
+// check tests a converted value against the field's enum and bounds
+func (f field) check(v reflect.Value) error {
+	if len(f.enum) > 0 {
+		s := fmt.Sprint(v.Interface())
+		found := false
+		for _, e := range f.enum {
+			if s == e {
+				found = true
+				break
+			}
+		}
+		if !found && s != "" {
+			return fmt.Errorf("%s must be one of %s (got %q)", f.name, strings.Join(f.enum, ", "), s)
+		}
+	}
+	if f.min == nil && f.max == nil {
+		return nil
+	}
+	var n float64
+	switch {
+	case v.CanInt():
+		n = float64(v.Int())
+	case v.CanUint():
+		n = float64(v.Uint())
+	default:
+		n = v.Float()
+	}
+	switch {
+	case f.min != nil && f.max != nil && (n < *f.min || n > *f.max):
+		return fmt.Errorf("%s must be between %v and %v (got %v)", f.name, *f.min, *f.max, n)
+	case f.min != nil && n < *f.min:
+		return fmt.Errorf("%s must be at least %v (got %v)", f.name, *f.min, n)
+	case f.max != nil && n > *f.max:
+		return fmt.Errorf("%s must be at most %v (got %v)", f.name, *f.max, n)
+	}
+	return nil
+}
+
+// clone copies a schema, so callers may change the map they get
+func clone(schema map[string]any) map[string]any {
+	props := map[string]any{}
+	for name, p := range schema["properties"].(map[string]any) {
+		prop := map[string]any{}
+		for k, v := range p.(map[string]any) {
+			prop[k] = v
+		}
+		props[name] = prop
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": props,
+		"required":   append([]string(nil), schema["required"].([]string)...),
+	}
+}
@@ -0,0 +1,125 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testParams struct {
+	Name    string         `json:"name" desc:"File name" required:"true"`
+	Mode    string         `json:"mode" enum:"text,hex" default:"text"`
+	Limit   int            `json:"limit" min:"1" max:"500" default:"200"`
+	Ratio   float64        `json:"ratio" min:"0" max:"1"`
+	Force   bool           `json:"force"`
+	Tags    []string       `json:"tags"`
+	Args    map[string]any `json:"args"`
+	Content *string        `json:"content"`
+	Ignored string
+}
+
+func TestFor(t *testing.T) {
+	got, _ := json.Marshal(For[testParams]())
+	want := `{"properties":{` +
+		`"args":{"type":"object"},` +
+		`"content":{"type":"string"},` +
+		`"force":{"type":"boolean"},` +
+		`"limit":{"default":200,"maximum":500,"minimum":1,"type":"integer"},` +
+		`"mode":{"default":"text","enum":["text","hex"],"type":"string"},` +
+		`"name":{"description":"File name","type":"string"},` +
+		`"ratio":{"maximum":1,"minimum":0,"type":"number"},` +
+		`"tags":{"items":{"type":"string"},"type":"array"}},` +
+		`"required":["name"],"type":"object"}`
+	if string(got) != want {
+		t.Errorf("For() =\n%s\nwant\n%s", got, want)
+	}
+
+	// Callers may change the schema they get
+	For[testParams]()["properties"].(map[string]any)["name"].(map[string]any)["description"] = "changed"
+	if _, ok := For[testParams]()["properties"].(map[string]any)["limit"]; !ok {
+		t.Error("schema lost a property")
+	}
+	if d := For[testParams]()["properties"].(map[string]any)["name"].(map[string]any)["description"]; d != "File name" {
+		t.Errorf("description = %v after changing a copy", d)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	p, err := Decode[testParams](map[string]any{
+		"name":    "app.log",
+		"limit":   "50",
+		"ratio":   float64(0.5),
+		"force":   "true",
+		"tags":    []any{"a", "b"},
+		"args":    map[string]any{"x": 1},
+		"content": "",
+		"extra":   "ignored",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := testParams{Name: "app.log", Mode: "text", Limit: 50, Ratio: 0.5, Force: true, Tags: []string{"a", "b"}, Args: map[string]any{"x": 1}}
+	if p.Content == nil || *p.Content != "" {
+		t.Errorf("Content = %v, want empty string", p.Content)
+	}
+	p.Content = nil
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("Decode() = %+v, want %+v", p, want)
+	}
+
+	p, err = Decode[testParams](map[string]any{"name": "x", "mode": "", "limit": nil, "ratio": float64(17)})
+	if err == nil || err.Error() != "ratio must be between 0 and 1 (got 17)" {
+		t.Errorf("out of bounds: err = %v", err)
+	}
+	p, err = Decode[testParams](map[string]any{"name": "x", "mode": "", "limit": nil})
+	if err != nil || p.Mode != "text" || p.Limit != 200 || p.Content != nil {
+		t.Errorf("defaults: %+v, %v", p, err)
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := []struct {
+		params map[string]any
+		want   string
+	}{
+		{map[string]any{}, "name parameter required"},
+		{map[string]any{"name": ""}, "name parameter required"},
+		{map[string]any{"name": true}, "name must be a string"},
+		{map[string]any{"name": "x", "mode": "binary"}, "mode must be one of text, hex (got \"binary\")"},
+		{map[string]any{"name": "x", "limit": 2.5}, "limit must be an integer (got 2.5)"},
+		{map[string]any{"name": "x", "limit": "lots"}, "limit must be an integer"},
+		{map[string]any{"name": "x", "limit": 0}, "limit must be between 1 and 500 (got 0)"},
+		{map[string]any{"name": "x", "force": "maybe"}, "force must be true or false"},
+		{map[string]any{"name": "x", "tags": []any{1}}, "tags must be a list of strings"},
+		{map[string]any{"name": "x", "args": "a=1"}, "args must be an object"},
+	}
+	for _, tt := range tests {
+		_, err := Decode[testParams](tt.params)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Decode(%v) err = %v, want %q", tt.params, err, tt.want)
+		}
+	}
+}
+
+func TestFor_InvalidTags(t *testing.T) {
+	type badDefault struct {
+		Mode string `json:"mode" enum:"a,b" default:"c"`
+	}
+	type badType struct {
+		When chan int `json:"when"`
+	}
+	for name, f := range map[string]func(){
+		"default": func() { For[badDefault]() },
+		"type":    func() { For[badType]() },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: no panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
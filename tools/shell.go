@@ -4,41 +4,54 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"os"
 	"os/exec"
 	"time"
+
+	"github.com/rathore/langchain-agent/tools/schema"
 )
 
 // ShellTool executes local shell commands
 type ShellTool struct {
 	Timeout time.Duration
+	// Workspace, when set, is the $WORKSPACE directory of commands
+	Workspace *Workspace
+	// RawOutput returns output as the command wrote it, without
+	// NormalizeOutput
+	RawOutput bool
 }
 
 func (s *ShellTool) Name() string {
 	return "shell"
 }
 
+func (s *ShellTool) Meta() Meta {
+	return Meta{Category: CategoryLocal, Risk: RiskHigh}
+}
+
 func (s *ShellTool) Description() string {
-	return "Execute a command on the LOCAL machine only. Do NOT use for remote hosts - use ssh tool instead."
+	desc := "Execute a command on the LOCAL machine only. Do NOT use for remote hosts - use ssh tool instead."
+	if s.Workspace != nil {
+		desc += " Save files you need later (downloaded logs, long output, scripts) under $WORKSPACE."
+	}
+	return desc
+}
+
+// shellParams are the parameters of a shell call
+type shellParams struct {
+	Command string `json:"command" desc:"The shell command to execute locally" required:"true"`
 }
 
 func (s *ShellTool) Parameters() map[string]any {
-	return map[string]any{
-		"type": "object",
-		"properties": map[string]any{
-			"command": map[string]any{
-				"type":        "string",
-				"description": "The shell command to execute locally",
-			},
-		},
-		"required": []string{"command"},
-	}
+	return schema.For[shellParams]()
 }
 
 func (s *ShellTool) Call(ctx context.Context, params map[string]any) (string, error) {
-	command, ok := params["command"].(string)
-	if !ok || command == "" {
-		return "", fmt.Errorf("command parameter required")
+	p, err := schema.Decode[shellParams](params)
+	if err != nil {
+		return "", err
 	}
+	command := p.Command
 
 	timeout := s.Timeout
 	if timeout == 0 {
@@ -49,18 +62,24 @@ func (s *ShellTool) Call(ctx context.Context, params map[string]any) (string, er
 	defer cancel()
 
 	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	if s.Workspace != nil {
+		cmd.Env = append(os.Environ(), "WORKSPACE="+s.Workspace.Dir)
+	}
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	err := cmd.Run()
-	output := stdout.String()
-	if stderr.Len() > 0 {
+	err = cmd.Run()
+	output, errOutput := stdout.String(), stderr.String()
+	if !s.RawOutput {
+		output, errOutput = NormalizeOutput(output), NormalizeOutput(errOutput)
+	}
+	if errOutput != "" {
 		if output != "" {
 			output += "\n"
 		}
-		output += "STDERR:\n" + stderr.String()
+		output += "STDERR:\n" + errOutput
 	}
 
 	if err != nil {
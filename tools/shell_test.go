@@ -170,3 +170,20 @@ func TestShellTool_Call_EnvironmentVariables(t *testing.T) {
 		t.Errorf("Call() = %q, expected HOME to be expanded", result)
 	}
 }
+
+func TestShellTool_Call_Workspace(t *testing.T) {
+	w, err := NewWorkspace(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool := &ShellTool{Workspace: w}
+	if !strings.Contains(tool.Description(), "$WORKSPACE") {
+		t.Error("Description() should mention $WORKSPACE")
+	}
+	if _, err := tool.Call(context.Background(), map[string]any{"command": "echo saved > $WORKSPACE/out.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := w.Read("out.txt"); err != nil || string(data) != "saved\n" {
+		t.Errorf("out.txt = %q, %v", data, err)
+	}
+}
@@ -3,86 +3,119 @@ package tools
 import (
 	"bytes"
 	"context"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"net"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/rathore/langchain-agent/tools/schema"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/agent"
 	"golang.org/x/term"
 )
 
 // SSHTool executes commands on remote hosts via SSH
-type SSHTool struct{}
+type SSHTool struct {
+	// KeepConnections reuses one connection per user@host across calls
+	// instead of dialing (and maybe prompting for a password) every time,
+	// for long-running processes such as the daemon
+	KeepConnections bool
+	// RawOutput returns output as the command wrote it, without
+	// NormalizeOutput
+	RawOutput bool
+	// DialTimeout bounds connecting to a host (default 15s)
+	DialTimeout time.Duration
+
+	// Passphrase returns the passphrase of an encrypted key file, e.g. from
+	// a credential store; nil or an empty passphrase asks on the terminal
+	Passphrase func(keyFile string) ([]byte, error)
+	// Password returns the password of user@host (host without the default
+	// port) the same way
+	Password func(user, host string) (string, error)
+
+	mu    sync.Mutex
+	conns map[string]*ssh.Client // kept connections by user@host:port
+
+	keyMu sync.Mutex
+	keys  map[string]ssh.Signer // decrypted key files; nil if skipped
+}
 
 func (s *SSHTool) Name() string {
 	return "ssh"
 }
 
+func (s *SSHTool) Meta() Meta {
+	return Meta{Category: CategoryRemote, Risk: RiskHigh, Latency: 2 * time.Second}
+}
+
 func (s *SSHTool) Description() string {
 	return "Execute a command on a REMOTE host via SSH. ALWAYS use this when user says 'ssh to', provides user@host, or mentions a remote server/IP address."
 }
 
+// sshParams are the parameters of an ssh call
+type sshParams struct {
+	Host    string `json:"host" desc:"The remote host in format user@hostname or just hostname (uses current user)" required:"true"`
+	Command string `json:"command" desc:"The command to execute on the remote host" required:"true"`
+}
+
 func (s *SSHTool) Parameters() map[string]any {
-	return map[string]any{
-		"type": "object",
-		"properties": map[string]any{
-			"host": map[string]any{
-				"type":        "string",
-				"description": "The remote host in format user@hostname or just hostname (uses current user)",
-			},
-			"command": map[string]any{
-				"type":        "string",
-				"description": "The command to execute on the remote host",
-			},
-		},
-		"required": []string{"host", "command"},
-	}
+	return schema.For[sshParams]()
 }
 
 func (s *SSHTool) Call(ctx context.Context, params map[string]any) (string, error) {
-	hostParam, ok := params["host"].(string)
-	if !ok {
-		return "", fmt.Errorf("host parameter required")
-	}
-	command, ok := params["command"].(string)
-	if !ok {
-		return "", fmt.Errorf("command parameter required")
+	p, err := schema.Decode[sshParams](params)
+	if err != nil {
+		return "", err
 	}
+	command := p.Command
 
 	// Parse user@host format
-	user, host := parseHost(hostParam)
+	user, host := parseHost(p.Host)
 
 	// Add default port if not specified
 	if !strings.Contains(host, ":") {
 		host = host + ":22"
 	}
 
-	// Try key-based auth first, fall back to interactive password prompt
-	client, err := s.dialWithAuth(user, host)
-	if err != nil {
-		return "", fmt.Errorf("failed to connect to %s: %w", host, err)
+	client, session, err := s.open(user, host)
+	var sshErr *SSHError
+	if errors.As(err, &sshErr) && (sshErr.Kind == SSHErrAuth || sshErr.Kind == SSHErrHostKey) {
+		return "", err // the host is up: not for the circuit breaker
 	}
-	defer client.Close()
-
-	// Create session
-	session, err := client.NewSession()
 	if err != nil {
-		return "", fmt.Errorf("failed to create session: %w", err)
+		return "", Unavailable(err)
 	}
 	defer session.Close()
+	if !s.KeepConnections {
+		defer client.Close()
+	}
 
 	// Run command
 	var stdout, stderr bytes.Buffer
 	session.Stdout = &stdout
 	session.Stderr = &stderr
 
-	err = session.Run(command)
-	output := stdout.String()
-	if stderr.Len() > 0 {
-		output += "\nSTDERR:\n" + stderr.String()
+	// Run in the background so a cancelled ctx (Ctrl+C) stops waiting
+	done := make(chan error, 1)
+	go func() { done <- session.Run(command) }()
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		session.Signal(ssh.SIGINT)
+		s.forget(user+"@"+host, client)
+		return "", fmt.Errorf("command cancelled: %w", ctx.Err())
+	}
+	output, errOutput := stdout.String(), stderr.String()
+	if !s.RawOutput {
+		output, errOutput = NormalizeOutput(output), NormalizeOutput(errOutput)
+	}
+	if errOutput != "" {
+		output += "\nSTDERR:\n" + errOutput
 	}
 
 	// Provide clear context about what happened
@@ -98,30 +131,90 @@ func (s *SSHTool) Call(ctx context.Context, params map[string]any) (string, erro
 	return output, nil
 }
 
-// dialWithAuth tries key-based auth first, then falls back to interactive password prompt
+// open starts a session on user@host, over a kept connection when
+// KeepConnections is set and it still works
+func (s *SSHTool) open(user, host string) (*ssh.Client, *ssh.Session, error) {
+	key := user + "@" + host
+	if s.KeepConnections {
+		// Held while dialing, so concurrent calls share one connection
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if client := s.conns[key]; client != nil {
+			if session, err := client.NewSession(); err == nil {
+				return client, session, nil
+			}
+			// Stale (server restarted, network dropped): dial afresh
+			client.Close()
+			delete(s.conns, key)
+		}
+	}
+
+	// Try key-based auth first, fall back to interactive password prompt
+	client, err := s.dialWithAuth(user, host)
+	if err != nil {
+		return nil, nil, classifySSHError(host, err)
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		client.Close()
+		return nil, nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	if s.KeepConnections {
+		if s.conns == nil {
+			s.conns = map[string]*ssh.Client{}
+		}
+		s.conns[key] = client
+	}
+	return client, session, nil
+}
+
+// forget closes a connection and stops keeping it
+func (s *SSHTool) forget(key string, client *ssh.Client) {
+	s.mu.Lock()
+	if s.conns[key] == client {
+		delete(s.conns, key)
+	}
+	s.mu.Unlock()
+	client.Close()
+}
+
+// Close closes the kept connections
+func (s *SSHTool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, client := range s.conns {
+		client.Close()
+		delete(s.conns, key)
+	}
+	return nil
+}
+
+// dialWithAuth tries the ssh-agent and unencrypted key files first, then
+// passphrase-protected key files, then an interactive password prompt.
+// Only a rejected key moves on to the next: a host that can't be reached
+// fails at once, without asking for a passphrase or password.
 func (s *SSHTool) dialWithAuth(user, host string) (*ssh.Client, error) {
-	// Try key-based auth methods first (ssh-agent + key files)
-	keyMethods := getKeyAuthMethods()
-	if len(keyMethods) > 0 {
-		config := &ssh.ClientConfig{
-			User:            user,
-			Auth:            keyMethods,
-			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		}
-		client, err := ssh.Dial("tcp", host, config)
-		if err == nil {
-			return client, nil
+	signers, encrypted := keySigners()
+	if len(signers) > 0 {
+		client, err := s.dialKeys(user, host, signers)
+		if err == nil || !isAuthFailure(err) {
+			return client, err
+		}
+	} else if err := s.probe(host); err != nil {
+		return nil, err
+	}
+	if signers = s.decryptKeys(encrypted); len(signers) > 0 {
+		client, err := s.dialKeys(user, host, signers)
+		if err == nil || !isAuthFailure(err) {
+			return client, err
 		}
 	}
 
-	// Key auth failed or unavailable — prompt for password
-	fmt.Printf("Password for %s@%s: ", user, strings.TrimSuffix(host, ":22"))
-	passwordBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
-	fmt.Println() // newline after password input
+	// Key auth failed or unavailable — use the stored password or prompt
+	password, err := s.password(user, strings.TrimSuffix(host, ":22"))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read password: %w", err)
+		return nil, &SSHError{Kind: SSHErrAuth, Host: host, Err: err}
 	}
-	password := string(passwordBytes)
 
 	config := &ssh.ClientConfig{
 		User: user,
@@ -136,11 +229,61 @@ func (s *SSHTool) dialWithAuth(user, host string) (*ssh.Client, error) {
 					return answers, nil
 				}),
 		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: hostKeyCallback(),
+		Timeout:         s.dialTimeout(),
 	}
 	return ssh.Dial("tcp", host, config)
 }
 
+func (s *SSHTool) dialTimeout() time.Duration {
+	if s.DialTimeout > 0 {
+		return s.DialTimeout
+	}
+	return 15 * time.Second
+}
+
+// probe checks that host accepts connections before asking the user for
+// a passphrase or password
+func (s *SSHTool) probe(host string) error {
+	conn, err := net.DialTimeout("tcp", host, s.dialTimeout())
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// password returns the password of user@host from Password, else asks on
+// the terminal
+func (s *SSHTool) password(user, host string) (string, error) {
+	if s.Password != nil {
+		password, err := s.Password(user, host)
+		if err != nil {
+			return "", fmt.Errorf("failed to get password: %w", err)
+		}
+		if password != "" {
+			return password, nil
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Password for %s@%s: ", user, host)
+	passwordBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr) // newline after password input
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	return string(passwordBytes), nil
+}
+
+// dialKeys connects with public key auth. The keys go in one auth method:
+// the client tries only the first method of each kind.
+func (s *SSHTool) dialKeys(user, host string, signers []ssh.Signer) (*ssh.Client, error) {
+	return ssh.Dial("tcp", host, &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signers...)},
+		HostKeyCallback: hostKeyCallback(),
+		Timeout:         s.dialTimeout(),
+	})
+}
+
 // parseHost extracts user and host from user@host format
 func parseHost(hostStr string) (user, host string) {
 	if idx := strings.Index(hostStr, "@"); idx != -1 {
@@ -154,38 +297,174 @@ func parseHost(hostStr string) (user, host string) {
 	return currentUser, hostStr
 }
 
-// getKeyAuthMethods returns key-based SSH auth methods (ssh-agent + key files)
-func getKeyAuthMethods() []ssh.AuthMethod {
-	var methods []ssh.AuthMethod
+// defaultKeyFiles are the key files tried, as ssh does
+func defaultKeyFiles() []string {
+	home, _ := os.UserHomeDir()
+	var files []string
+	for _, name := range []string{"id_rsa", "id_ed25519", "id_ecdsa"} {
+		files = append(files, filepath.Join(home, ".ssh", name))
+	}
+	return files
+}
 
-	// Try ssh-agent first
+// keySigners returns the ssh-agent's keys and the unencrypted default key
+// files, and the passphrase-protected key files the agent doesn't hold
+func keySigners() (signers []ssh.Signer, encrypted []string) {
+	agentKeys := map[string]bool{}
 	if agentConn := os.Getenv("SSH_AUTH_SOCK"); agentConn != "" {
 		conn, err := net.Dial("unix", agentConn)
 		if err == nil {
-			agentClient := agent.NewClient(conn)
-			methods = append(methods, ssh.PublicKeysCallback(agentClient.Signers))
+			if agentSigners, err := agent.NewClient(conn).Signers(); err == nil {
+				for _, signer := range agentSigners {
+					agentKeys[string(signer.PublicKey().Marshal())] = true
+				}
+				signers = append(signers, agentSigners...)
+			}
 		}
 	}
 
-	// Try default key files
-	home, _ := os.UserHomeDir()
-	keyFiles := []string{
-		filepath.Join(home, ".ssh", "id_rsa"),
-		filepath.Join(home, ".ssh", "id_ed25519"),
-		filepath.Join(home, ".ssh", "id_ecdsa"),
-	}
-
-	for _, keyFile := range keyFiles {
+	for _, keyFile := range defaultKeyFiles() {
 		key, err := os.ReadFile(keyFile)
 		if err != nil {
 			continue
 		}
 		signer, err := ssh.ParsePrivateKey(key)
-		if err != nil {
+		var missing *ssh.PassphraseMissingError
+		switch {
+		case err == nil:
+			signers = append(signers, signer)
+		case errors.As(err, &missing):
+			if missing.PublicKey == nil || !agentKeys[string(missing.PublicKey.Marshal())] {
+				encrypted = append(encrypted, keyFile)
+			}
+		}
+	}
+	return signers, encrypted
+}
+
+// maxPassphraseTries is how often a key's passphrase is asked for before
+// the key is given up
+const maxPassphraseTries = 3
+
+// decryptKeys returns signers for passphrase-protected key files, asking
+// for each passphrase once per process: from Passphrase if set, else on the
+// terminal. Keys whose passphrase isn't given are skipped from then on.
+func (s *SSHTool) decryptKeys(keyFiles []string) []ssh.Signer {
+	s.keyMu.Lock()
+	defer s.keyMu.Unlock()
+	var signers []ssh.Signer
+	for _, keyFile := range keyFiles {
+		if signer, ok := s.keys[keyFile]; ok {
+			if signer != nil {
+				signers = append(signers, signer)
+			}
 			continue
 		}
-		methods = append(methods, ssh.PublicKeys(signer))
+		signer, err := s.decryptKey(keyFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping SSH key %s: %v\n", keyFile, err)
+		}
+		if s.keys == nil {
+			s.keys = map[string]ssh.Signer{}
+		}
+		s.keys[keyFile] = signer // nil: skipped
+		if signer != nil {
+			signers = append(signers, signer)
+		}
 	}
+	return signers
+}
 
-	return methods
+// decryptKey decrypts keyFile with the passphrase from Passphrase, or else
+// asks on the terminal until it decrypts the key; nil without an error if no
+// passphrase was given
+func (s *SSHTool) decryptKey(keyFile string) (ssh.Signer, error) {
+	key, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	if s.Passphrase != nil {
+		passphrase, err := s.Passphrase(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get passphrase: %w", err)
+		}
+		if len(passphrase) > 0 {
+			return ssh.ParsePrivateKeyWithPassphrase(key, passphrase)
+		}
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil, nil
+	}
+	for range maxPassphraseTries {
+		passphrase, err := promptPassphrase(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get passphrase: %w", err)
+		}
+		if len(passphrase) == 0 {
+			return nil, nil
+		}
+		signer, err := ssh.ParsePrivateKeyWithPassphrase(key, passphrase)
+		if err == nil {
+			return signer, nil
+		}
+		if !errors.Is(err, x509.IncorrectPasswordError) {
+			return nil, err
+		}
+		fmt.Fprintln(os.Stderr, "Wrong passphrase.")
+	}
+	return nil, errors.New("wrong passphrase")
+}
+
+// promptPassphrase asks for a key's passphrase on the terminal, on stderr
+// so that stdout (--output json, ask) stays clean
+func promptPassphrase(keyFile string) ([]byte, error) {
+	fmt.Fprintf(os.Stderr, "Passphrase for %s (empty to skip): ", keyFile)
+	passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	return passphrase, err
+}
+
+// SSHAuthInfo describes the credentials the ssh tool can use without
+// prompting for a password
+type SSHAuthInfo struct {
+	AgentSocket   string   // $SSH_AUTH_SOCK; empty if unset
+	AgentKeys     int      // keys loaded in the agent
+	AgentErr      error    // why the agent couldn't be used, if it is set
+	KeyFiles      []string // default key files usable as-is
+	EncryptedKeys []string // default key files that need a passphrase, asked for on first use
+}
+
+// CheckSSHAuth inspects the ssh-agent and default key files that ssh tool
+// connections try before falling back to a password prompt
+func CheckSSHAuth() SSHAuthInfo {
+	var info SSHAuthInfo
+	if info.AgentSocket = os.Getenv("SSH_AUTH_SOCK"); info.AgentSocket != "" {
+		conn, err := net.Dial("unix", info.AgentSocket)
+		if err != nil {
+			info.AgentErr = fmt.Errorf("failed to connect to ssh-agent: %w", err)
+		} else {
+			keys, err := agent.NewClient(conn).List()
+			conn.Close()
+			if err != nil {
+				info.AgentErr = fmt.Errorf("failed to list ssh-agent keys: %w", err)
+			}
+			info.AgentKeys = len(keys)
+		}
+	}
+
+	for _, keyFile := range defaultKeyFiles() {
+		key, err := os.ReadFile(keyFile)
+		if err != nil {
+			continue
+		}
+		_, err = ssh.ParsePrivateKey(key)
+		var missing *ssh.PassphraseMissingError
+		switch {
+		case err == nil:
+			info.KeyFiles = append(info.KeyFiles, keyFile)
+		case errors.As(err, &missing):
+			info.EncryptedKeys = append(info.EncryptedKeys, keyFile)
+		}
+	}
+	return info
 }
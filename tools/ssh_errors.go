@@ -0,0 +1,118 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Kinds of SSHError
+const (
+	SSHErrDNS         = "dns"                // the host name doesn't resolve
+	SSHErrRefused     = "connection_refused" // nothing listens on the port
+	SSHErrUnreachable = "unreachable"        // no route to the host
+	SSHErrTimeout     = "timeout"            // the host didn't answer in time
+	SSHErrAuth        = "auth_failed"        // keys and password were rejected, or there was no password
+	SSHErrHostKey     = "host_key_mismatch"  // the host's key isn't the one in known_hosts
+	SSHErrOther       = "connection_failed"  // anything else, e.g. a connection reset
+)
+
+// sshAdvice tells the model what to do after each kind of failure
+var sshAdvice = map[string]string{
+	SSHErrDNS:         "The host name doesn't resolve: check it for typos or ask the user for the right name or IP address. Retrying won't help",
+	SSHErrRefused:     "The host is up but nothing accepts SSH on that port: check the port (host:port) or tell the user sshd may not be running. Retrying won't help",
+	SSHErrUnreachable: "There is no route to the host: it may be down or on a network this machine can't reach. Tell the user rather than retrying",
+	SSHErrTimeout:     "The host didn't answer in time: it may be down, firewalled or overloaded. Retry once, then tell the user",
+	SSHErrAuth:        "No key or password of this user was accepted. Don't retry: use another user only if the user named one, else ask the user for access",
+	SSHErrHostKey:     "The host's key differs from the one in known_hosts, so it may not be the host it claims to be. Don't retry: tell the user to check the host key",
+	SSHErrOther:       "Retry once, then tell the user",
+}
+
+// SSHError is a failed SSH connection, classified so the model can adapt
+// its next step instead of guessing from "failed to connect"
+type SSHError struct {
+	Kind string // one of the SSHErr kinds
+	Host string // host:port
+	Err  error
+}
+
+func (e *SSHError) Error() string {
+	return fmt.Sprintf("ssh to %s failed (%s): %v. %s", e.Host, e.Kind, e.Err, sshAdvice[e.Kind])
+}
+
+func (e *SSHError) Unwrap() error { return e.Err }
+
+// Retryable reports whether calling again may succeed
+func (e *SSHError) Retryable() bool {
+	return e.Kind == SSHErrTimeout || e.Kind == SSHErrOther
+}
+
+// classifySSHError turns an error connecting to host into an SSHError
+func classifySSHError(host string, err error) *SSHError {
+	var sshErr *SSHError
+	if errors.As(err, &sshErr) {
+		return sshErr
+	}
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var keyErr *knownhosts.KeyError
+	kind := SSHErrOther
+	switch {
+	case errors.As(err, &dnsErr) && dnsErr.IsTimeout:
+		kind = SSHErrTimeout
+	case errors.As(err, &dnsErr):
+		kind = SSHErrDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		kind = SSHErrRefused
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		kind = SSHErrUnreachable
+	case errors.Is(err, os.ErrDeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		kind = SSHErrTimeout
+	case errors.As(err, &keyErr):
+		kind = SSHErrHostKey
+	case isAuthFailure(err):
+		kind = SSHErrAuth
+	}
+	return &SSHError{Kind: kind, Host: host, Err: err}
+}
+
+// isAuthFailure reports whether the server turned down every credential
+// offered; the ssh package has no error type for it
+func isAuthFailure(err error) bool {
+	return strings.Contains(err.Error(), "unable to authenticate")
+}
+
+// hostKeyCallback checks host keys against ~/.ssh/known_hosts. A host
+// listed there with a key of the same type that doesn't match is refused;
+// hosts not listed, or only with keys of other types, are accepted, as
+// they were before known_hosts was read.
+func hostKeyCallback() ssh.HostKeyCallback {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ssh.InsecureIgnoreHostKey()
+	}
+	check, err := knownhosts.New(filepath.Join(home, ".ssh", "known_hosts"))
+	if err != nil {
+		return ssh.InsecureIgnoreHostKey()
+	}
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := check(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if errors.As(err, &keyErr) {
+			for _, want := range keyErr.Want {
+				if want.Key.Type() == key.Type() {
+					return err
+				}
+			}
+			return nil
+		}
+		return err
+	}
+}
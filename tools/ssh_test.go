@@ -0,0 +1,208 @@
+package tools
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// writeKey writes a new ed25519 key to dir/name, encrypted with passphrase
+// unless it is empty, and returns its public key
+func writeKey(t *testing.T, dir, name, passphrase string) ssh.PublicKey {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var block *pem.Block
+	if passphrase == "" {
+		block, err = ssh.MarshalPrivateKey(priv, "")
+	} else {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte(passphrase))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	sshPub, _ := ssh.NewPublicKey(pub)
+	return sshPub
+}
+
+func TestKeySigners(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SSH_AUTH_SOCK", "")
+	dir := filepath.Join(home, ".ssh")
+	os.Mkdir(dir, 0700)
+	plain := writeKey(t, dir, "id_rsa", "")
+	writeKey(t, dir, "id_ed25519", "secret")
+
+	signers, encrypted := keySigners()
+	if len(signers) != 1 || string(signers[0].PublicKey().Marshal()) != string(plain.Marshal()) {
+		t.Errorf("signers = %v, want the unencrypted key", signers)
+	}
+	if want := filepath.Join(dir, "id_ed25519"); len(encrypted) != 1 || encrypted[0] != want {
+		t.Errorf("encrypted = %v, want %s", encrypted, want)
+	}
+	if info := CheckSSHAuth(); len(info.KeyFiles) != 1 || len(info.EncryptedKeys) != 1 {
+		t.Errorf("CheckSSHAuth() = %+v", info)
+	}
+}
+
+func TestSSHTool_DecryptKeys(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good")
+	pub := writeKey(t, dir, "good", "secret")
+	wrong := filepath.Join(dir, "wrong")
+	writeKey(t, dir, "wrong", "other")
+	skipped := filepath.Join(dir, "skipped")
+	writeKey(t, dir, "skipped", "third")
+
+	asked := map[string]int{}
+	s := &SSHTool{Passphrase: func(keyFile string) ([]byte, error) {
+		asked[keyFile]++
+		switch keyFile {
+		case good, wrong:
+			return []byte("secret"), nil
+		}
+		return nil, nil
+	}}
+	for range 2 {
+		signers := s.decryptKeys([]string{good, wrong, skipped})
+		if len(signers) != 1 || string(signers[0].PublicKey().Marshal()) != string(pub.Marshal()) {
+			t.Fatalf("signers = %v, want the good key", signers)
+		}
+	}
+	if asked[good] != 1 || asked[wrong] != 1 || asked[skipped] != 1 {
+		t.Errorf("asked %v, want each key once", asked)
+	}
+
+	s = &SSHTool{Passphrase: func(string) ([]byte, error) { return nil, errors.New("locked") }}
+	if signers := s.decryptKeys([]string{good}); len(signers) != 0 {
+		t.Errorf("signers = %v without a passphrase", signers)
+	}
+}
+
+func TestSSHTool_Password(t *testing.T) {
+	s := &SSHTool{Password: func(user, host string) (string, error) {
+		if user+"@"+host == "deploy@web-1" {
+			return "hunter2", nil
+		}
+		return "", errors.New("locked")
+	}}
+	if password, err := s.password("deploy", "web-1"); err != nil || password != "hunter2" {
+		t.Errorf("password() = %q, %v", password, err)
+	}
+	if _, err := s.password("root", "web-1"); err == nil {
+		t.Error("password() hid the store's error")
+	}
+}
+
+// rejectingServer serves SSH on a local port, turning down every key and
+// password, and returns its address and host key
+func rejectingServer(t *testing.T) (string, ssh.PublicKey) {
+	t.Helper()
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	hostKey, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) { return nil, errors.New("no") },
+		PublicKeyCallback: func(ssh.ConnMetadata, ssh.PublicKey) (*ssh.Permissions, error) {
+			return nil, errors.New("no")
+		},
+	}
+	config.AddHostKey(hostKey)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				ssh.NewServerConn(conn, config)
+				conn.Close()
+			}()
+		}
+	}()
+	return ln.Addr().String(), hostKey.PublicKey()
+}
+
+func TestSSHTool_ConnectErrors(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SSH_AUTH_SOCK", "")
+	dir := filepath.Join(home, ".ssh")
+	os.Mkdir(dir, 0700)
+	writeKey(t, dir, "id_ed25519", "")
+	addr, _ := rejectingServer(t)
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	closed := ln.Addr().String()
+	ln.Close()
+
+	s := &SSHTool{Password: func(user, host string) (string, error) { return "wrong", nil }}
+	call := func(host string) error {
+		_, err := s.Call(context.Background(), map[string]any{"host": "deploy@" + host, "command": "uptime"})
+		return err
+	}
+
+	err := call(closed)
+	var sshErr *SSHError
+	if !errors.As(err, &sshErr) || sshErr.Kind != SSHErrRefused || !IsUnavailable(err) {
+		t.Errorf("closed port: %v", err)
+	}
+	err = call(addr)
+	if !errors.As(err, &sshErr) || sshErr.Kind != SSHErrAuth || IsUnavailable(err) || !strings.Contains(err.Error(), "Don't retry") {
+		t.Errorf("rejected password: %v", err)
+	}
+
+	// A host known by another key of the same type may be an impostor
+	other := writeKey(t, t.TempDir(), "other", "")
+	os.WriteFile(filepath.Join(dir, "known_hosts"), []byte(knownhosts.Line([]string{addr}, other)+"\n"), 0600)
+	err = call(addr)
+	if !errors.As(err, &sshErr) || sshErr.Kind != SSHErrHostKey {
+		t.Errorf("other host key: %v", err)
+	}
+}
+
+func TestClassifySSHError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{&net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "web-1", IsNotFound: true}}, SSHErrDNS},
+		{&net.OpError{Op: "dial", Err: &net.DNSError{Err: "i/o timeout", Name: "web-1", IsTimeout: true}}, SSHErrTimeout},
+		{&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.EHOSTUNREACH)}, SSHErrUnreachable},
+		{&net.OpError{Op: "dial", Err: os.ErrDeadlineExceeded}, SSHErrTimeout},
+		{errors.New("ssh: handshake failed: ssh: unable to authenticate, attempted methods [none publickey], no supported methods remain"), SSHErrAuth},
+		{errors.New("ssh: handshake failed: EOF"), SSHErrOther},
+	}
+	for _, tt := range tests {
+		e := classifySSHError("web-1:22", tt.err)
+		if e.Kind != tt.want {
+			t.Errorf("classifySSHError(%v) = %s, want %s", tt.err, e.Kind, tt.want)
+		}
+		if e.Retryable() != (tt.want == SSHErrTimeout || tt.want == SSHErrOther) {
+			t.Errorf("%s: Retryable() = %v", e.Kind, e.Retryable())
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the tool latency
+// histogram
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// ToolStats are one tool's calls
+type ToolStats struct {
+	Calls   int64         `json:"calls"`
+	Errors  int64         `json:"errors"`
+	Total   time.Duration `json:"total_ns"`
+	Max     time.Duration `json:"max_ns"`
+	Buckets []int64       `json:"buckets"` // calls per latencyBuckets bound, the last for slower ones
+	Last    time.Time     `json:"last"`
+}
+
+// ErrorRate is the share of calls that failed
+func (t ToolStats) ErrorRate() float64 {
+	if t.Calls == 0 {
+		return 0
+	}
+	return float64(t.Errors) / float64(t.Calls)
+}
+
+// Mean is the average call latency
+func (t ToolStats) Mean() time.Duration {
+	if t.Calls == 0 {
+		return 0
+	}
+	return t.Total / time.Duration(t.Calls)
+}
+
+// Percentile returns the histogram bucket bound below which fraction p of
+// calls finished, or Max for calls beyond the last bound
+func (t ToolStats) Percentile(p float64) time.Duration {
+	want := int64(p*float64(t.Calls) + 0.5)
+	var n int64
+	for i, c := range t.Buckets {
+		if n += c; n >= want && i < len(latencyBuckets) {
+			return min(time.Duration(latencyBuckets[i]*float64(time.Second)), t.Max)
+		}
+	}
+	return t.Max
+}
+
+func (t *ToolStats) add(o ToolStats) {
+	t.Calls += o.Calls
+	t.Errors += o.Errors
+	t.Total += o.Total
+	t.Max = max(t.Max, o.Max)
+	if o.Last.After(t.Last) {
+		t.Last = o.Last
+	}
+	if len(t.Buckets) < len(latencyBuckets)+1 {
+		t.Buckets = append(t.Buckets, make([]int64, len(latencyBuckets)+1-len(t.Buckets))...)
+	}
+	for i, c := range o.Buckets {
+		if i < len(t.Buckets) {
+			t.Buckets[i] += c
+		}
+	}
+}
+
+// Stats counts calls, errors and latency per tool. With a file they
+// accumulate across sessions: Flush adds the calls recorded since the last
+// flush to the file, so several processes can share it.
+type Stats struct {
+	path string // "" keeps them in memory only
+
+	mu      sync.Mutex
+	since   time.Time
+	totals  map[string]*ToolStats // the file's plus unflushed calls
+	pending map[string]*ToolStats // not yet in the file
+}
+
+// statsFile is the JSON file Stats are kept in
+type statsFile struct {
+	Since time.Time             `json:"since"`
+	Tools map[string]*ToolStats `json:"tools"`
+}
+
+// NewStats returns empty in-memory Stats
+func NewStats() *Stats {
+	return &Stats{since: time.Now(), totals: map[string]*ToolStats{}, pending: map[string]*ToolStats{}}
+}
+
+// LoadStats returns the Stats kept in path, empty if it doesn't exist yet
+func LoadStats(path string) (*Stats, error) {
+	s := NewStats()
+	s.path = path
+	f, err := readStats(path)
+	if err != nil {
+		return nil, err
+	}
+	if !f.Since.IsZero() {
+		s.since = f.Since
+	}
+	s.totals = f.Tools
+	return s, nil
+}
+
+func readStats(path string) (statsFile, error) {
+	f := statsFile{Tools: map[string]*ToolStats{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return f, fmt.Errorf("failed to read tool stats: %w", err)
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return f, fmt.Errorf("invalid tool stats file %s: %w", path, err)
+	}
+	if f.Tools == nil {
+		f.Tools = map[string]*ToolStats{}
+	}
+	return f, nil
+}
+
+// Record counts a call of the named tool that took elapsed and failed if
+// failed is set. A nil Stats records nothing.
+func (s *Stats) Record(name string, elapsed time.Duration, failed bool) {
+	if s == nil {
+		return
+	}
+	call := ToolStats{Calls: 1, Total: elapsed, Max: elapsed, Last: time.Now(), Buckets: make([]int64, len(latencyBuckets)+1)}
+	if failed {
+		call.Errors = 1
+	}
+	bucket := sort.SearchFloat64s(latencyBuckets, elapsed.Seconds())
+	call.Buckets[bucket] = 1
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range []map[string]*ToolStats{s.totals, s.pending} {
+		if m[name] == nil {
+			m[name] = &ToolStats{}
+		}
+		m[name].add(call)
+	}
+}
+
+// Flush adds the calls recorded since the last flush to the file and
+// reloads it, picking up other processes' calls
+func (s *Stats) Flush() error {
+	if s == nil || s.path == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.pending) == 0 {
+		return nil
+	}
+	f, err := readStats(s.path)
+	if err != nil {
+		return err
+	}
+	if f.Since.IsZero() {
+		f.Since = s.since
+	}
+	for name, t := range s.pending {
+		if f.Tools[name] == nil {
+			f.Tools[name] = &ToolStats{}
+		}
+		f.Tools[name].add(*t)
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode tool stats: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to save tool stats: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to save tool stats: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to save tool stats: %w", err)
+	}
+	s.since, s.totals, s.pending = f.Since, f.Tools, map[string]*ToolStats{}
+	return nil
+}
+
+// Snapshot returns each tool's stats and when counting started
+func (s *Stats) Snapshot() (map[string]ToolStats, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]ToolStats, len(s.totals))
+	for name, t := range s.totals {
+		c := *t
+		c.Buckets = append([]int64(nil), t.Buckets...)
+		out[name] = c
+	}
+	return out, s.since
+}
+
+// WritePrometheus writes the stats in the Prometheus text format: call and
+// error counters and a latency histogram per tool
+func (s *Stats) WritePrometheus(w io.Writer) {
+	snapshot, _ := s.Snapshot()
+	names := make([]string, 0, len(snapshot))
+	for name := range snapshot {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "# HELP langchain_agent_tool_calls_total Tool calls made by the agent.")
+	fmt.Fprintln(w, "# TYPE langchain_agent_tool_calls_total counter")
+	for _, name := range names {
+		fmt.Fprintf(w, "langchain_agent_tool_calls_total{tool=%q} %d\n", name, snapshot[name].Calls)
+	}
+	fmt.Fprintln(w, "# HELP langchain_agent_tool_errors_total Tool calls that returned an error.")
+	fmt.Fprintln(w, "# TYPE langchain_agent_tool_errors_total counter")
+	for _, name := range names {
+		fmt.Fprintf(w, "langchain_agent_tool_errors_total{tool=%q} %d\n", name, snapshot[name].Errors)
+	}
+	fmt.Fprintln(w, "# HELP langchain_agent_tool_duration_seconds Tool call latency.")
+	fmt.Fprintln(w, "# TYPE langchain_agent_tool_duration_seconds histogram")
+	for _, name := range names {
+		t := snapshot[name]
+		var n int64
+		for i, bound := range latencyBuckets {
+			if i < len(t.Buckets) {
+				n += t.Buckets[i]
+			}
+			fmt.Fprintf(w, "langchain_agent_tool_duration_seconds_bucket{tool=%q,le=%q} %d\n", name, strconv.FormatFloat(bound, 'g', -1, 64), n)
+		}
+		fmt.Fprintf(w, "langchain_agent_tool_duration_seconds_bucket{tool=%q,le=\"+Inf\"} %d\n", name, t.Calls)
+		fmt.Fprintf(w, "langchain_agent_tool_duration_seconds_sum{tool=%q} %s\n", name, strconv.FormatFloat(t.Total.Seconds(), 'g', -1, 64))
+		fmt.Fprintf(w, "langchain_agent_tool_duration_seconds_count{tool=%q} %d\n", name, t.Calls)
+	}
+}
@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStats_Record(t *testing.T) {
+	s := NewStats()
+	s.Record("shell", 200*time.Millisecond, false)
+	s.Record("shell", 3*time.Second, true)
+	s.Record("shell", 40*time.Millisecond, false)
+	var none *Stats
+	none.Record("shell", time.Second, false) // no-op
+
+	snapshot, _ := s.Snapshot()
+	got := snapshot["shell"]
+	if got.Calls != 3 || got.Errors != 1 || got.Max != 3*time.Second {
+		t.Fatalf("stats = %+v, want 3 calls, 1 error, max 3s", got)
+	}
+	if rate := got.ErrorRate(); rate < 0.33 || rate > 0.34 {
+		t.Errorf("ErrorRate() = %v", rate)
+	}
+	if mean := got.Mean(); mean != 1080*time.Millisecond {
+		t.Errorf("Mean() = %v, want 1.08s", mean)
+	}
+	if p := got.Percentile(0.5); p != 250*time.Millisecond {
+		t.Errorf("Percentile(0.5) = %v, want the 250ms bucket", p)
+	}
+	if p := got.Percentile(0.95); p != 3*time.Second {
+		t.Errorf("Percentile(0.95) = %v, want Max below the 5s bound", p)
+	}
+}
+
+func TestStats_FlushAcrossProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats", "tool-stats.json")
+	a, err := LoadStats(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := LoadStats(path)
+	a.Record("shell", time.Second, false)
+	b.Record("shell", time.Second, true)
+	b.Record("wiki", time.Second, false)
+	if err := a.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Flush(); err != nil { // nothing new: no double counting
+		t.Fatal(err)
+	}
+
+	c, err := LoadStats(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, _ := c.Snapshot()
+	if snapshot["shell"].Calls != 2 || snapshot["shell"].Errors != 1 || snapshot["wiki"].Calls != 1 {
+		t.Errorf("reloaded stats = %+v, want both processes' calls", snapshot)
+	}
+	if snapshot, _ := b.Snapshot(); snapshot["shell"].Calls != 2 {
+		t.Errorf("Flush() didn't pick up the other process's calls: %+v", snapshot["shell"])
+	}
+}
+
+func TestStats_WritePrometheus(t *testing.T) {
+	s := NewStats()
+	s.Record("ssh", 300*time.Millisecond, true)
+	s.Record("ssh", 2*time.Minute+time.Second, false)
+
+	var sb strings.Builder
+	s.WritePrometheus(&sb)
+	out := sb.String()
+	for _, want := range []string{
+		"# TYPE langchain_agent_tool_calls_total counter",
+		`langchain_agent_tool_calls_total{tool="ssh"} 2`,
+		`langchain_agent_tool_errors_total{tool="ssh"} 1`,
+		`langchain_agent_tool_duration_seconds_bucket{tool="ssh",le="0.25"} 0`,
+		`langchain_agent_tool_duration_seconds_bucket{tool="ssh",le="0.5"} 1`,
+		`langchain_agent_tool_duration_seconds_bucket{tool="ssh",le="120"} 1`,
+		`langchain_agent_tool_duration_seconds_bucket{tool="ssh",le="+Inf"} 2`,
+		`langchain_agent_tool_duration_seconds_sum{tool="ssh"} 121.3`,
+		`langchain_agent_tool_duration_seconds_count{tool="ssh"} 2`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -2,7 +2,9 @@ package tools
 
 import "context"
 
-// Tool defines the interface for agent tools
+// Tool defines the interface for agent tools. Params are the JSON object the
+// LLM sent, checked against Parameters only by the tool itself; errors are
+// shown to the LLM, which may retry.
 type Tool interface {
 	Name() string
 	Description() string
@@ -10,20 +12,15 @@ type Tool interface {
 	Call(ctx context.Context, params map[string]any) (string, error)
 }
 
-// ToolCall represents a parsed tool call from the LLM
-type ToolCall struct {
-	Name   string         `json:"name"`
-	Params map[string]any `json:"parameters"`
+// Dispatcher is implemented by tools that pass a call on to one of several
+// others, such as an MCP server's tools. CallArguments returns the properties
+// of the JSON schema of the arguments params passes on, and those arguments,
+// added to params when missing; nil properties if params names no tool.
+type Dispatcher interface {
+	CallArguments(params map[string]any) (properties map[string]any, args map[string]any)
 }
 
 // Closeable is implemented by tools that hold resources needing cleanup
 type Closeable interface {
 	Close() error
 }
-
-// ToolResult holds the result of a tool execution
-type ToolResult struct {
-	Tool   string
-	Result string
-	Error  error
-}
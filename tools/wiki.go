@@ -3,99 +3,213 @@ package tools
 import (
 	"context"
 	"fmt"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/rathore/langchain-agent/llm"
 	"github.com/rathore/langchain-agent/rag"
+	"github.com/rathore/langchain-agent/tools/schema"
 )
 
+// Query expansion modes for WikiTool.SetQueryExpansion
+const (
+	ExpandNone       = "none"
+	ExpandMultiQuery = "multi-query" // search LLM-generated rephrasings too and fuse the rankings
+	ExpandHyDE       = "hyde"        // embed an LLM-written hypothetical answer instead of the query
+)
+
+// multiQueryCount is how many rephrasings multi-query expansion asks for
+const multiQueryCount = 3
+
+// mmrCandidateFactor is how many candidates per requested result are fetched
+// for diversity re-ranking
+const mmrCandidateFactor = 4
+
+// maxSectionChars bounds the enclosing-section context returned per result
+const maxSectionChars = 2000
+
 // WikiTool searches the indexed Confluence wiki content
 type WikiTool struct {
-	embeddings *rag.EmbeddingClient
-	store      *rag.VectorStore
+	name       string
+	label      string // knowledge base label; empty for the default wiki
+	embeddings rag.Embedder
+	store      rag.Store
+	expansion  string         // query expansion mode; "" or ExpandNone disables it
+	client     llm.ChatClient // generates rewrites when expansion is enabled
+
+	health      *wikiHealth // shared by the copies withStore makes
+	keywordOnly bool        // store is the keyword fallback: search without embedding
 }
 
 // NewWikiTool creates a new wiki search tool
-func NewWikiTool(embeddings *rag.EmbeddingClient, store *rag.VectorStore) *WikiTool {
+func NewWikiTool(embeddings rag.Embedder, store rag.Store) *WikiTool {
+	return NewNamedWikiTool("", embeddings, store)
+}
+
+// NewNamedWikiTool creates a wiki search tool for a labeled knowledge base.
+// The tool is named "wiki_<label>"; an empty label gives the default "wiki" tool.
+func NewNamedWikiTool(label string, embeddings rag.Embedder, store rag.Store) *WikiTool {
+	name := "wiki"
+	if label != "" {
+		name = "wiki_" + label
+	}
 	return &WikiTool{
+		name:       name,
+		label:      label,
 		embeddings: embeddings,
 		store:      store,
+		health:     &wikiHealth{},
+	}
+}
+
+// SetQueryExpansion enables LLM query rewriting before search, which helps
+// recall for terse queries like "oom runbook". If the LLM call fails the
+// original query is searched alone.
+func (w *WikiTool) SetQueryExpansion(mode string, client llm.ChatClient) error {
+	switch mode {
+	case "", ExpandNone:
+		w.expansion, w.client = "", nil
+		return nil
+	case ExpandMultiQuery, ExpandHyDE:
+		if client == nil {
+			return fmt.Errorf("query expansion %q requires an LLM client", mode)
+		}
+		w.expansion, w.client = mode, client
+		return nil
+	default:
+		return fmt.Errorf("unknown query expansion mode %q (use none, multi-query or hyde)", mode)
 	}
 }
 
 func (w *WikiTool) Name() string {
-	return "wiki"
+	return w.name
+}
+
+func (w *WikiTool) Meta() Meta {
+	return Meta{Category: CategoryKnowledge, ReadOnly: true, Latency: time.Second}
 }
 
 func (w *WikiTool) Description() string {
+	if w.label != "" {
+		return fmt.Sprintf("Search the %q knowledge base for relevant documentation, diagrams, and architecture information. Use when user asks about %s documentation or mentions the %s wiki.", w.label, w.label, w.label)
+	}
 	return "Search the Confluence wiki for relevant documentation, diagrams, and architecture information. Use when user asks about internal documentation, architecture diagrams, deployment, or project-specific knowledge."
 }
 
+// wikiParams are the parameters of a wiki call
+type wikiParams struct {
+	Action         string  `json:"action" desc:"Action to perform: 'search' to find relevant content, 'get_page' to read the full text of one page (e.g. after a search hit), 'list' to page through indexed documents (accepts the same filters), 'count' to get total indexed documents" required:"true" enum:"search,get_page,list,count"`
+	Query          string  `json:"query" desc:"Search query (required for 'search' action)"`
+	Page           string  `json:"page" desc:"For 'get_page': the page title, or its file path/URL from a result's Source line"`
+	Limit          int     `json:"limit" desc:"Maximum number of results to return (default: 5 for search, 20 for list)" min:"1"`
+	Diversity      float64 `json:"diversity" desc:"Optional for 'search': 0 to 1 (default 0). Higher values re-rank with maximal marginal relevance so results cover different pages/sections instead of near-duplicate chunks; try 0.3-0.5" min:"0" max:"1"`
+	Context        string  `json:"context" desc:"Optional for 'search': 'section' (default) returns the section around each matching chunk, up to 2000 characters; 'chunk' returns only the matching chunk" enum:"section,chunk"`
+	Offset         string  `json:"offset" desc:"For 'list': the next-page cursor returned by a previous list call"`
+	SourceType     string  `json:"source_type" desc:"Optional: 'image' for diagrams only, 'text' for text only" enum:"text,image"`
+	ChunkType      string  `json:"chunk_type" desc:"Optional: only this kind of chunk: heading, paragraph, list, code, table, section, ocr (text read from images), summary (one overview per page; best for broad questions like what a space or page covers)"`
+	PageTitle      string  `json:"page_title" desc:"Optional: only pages whose title contains this text"`
+	Space          string  `json:"space" desc:"Optional: only pages in this Confluence space key"`
+	Label          string  `json:"label" desc:"Optional: only pages carrying this Confluence label (e.g. runbook)"`
+	Language       string  `json:"language" desc:"Optional: only text in this language, as an ISO 639-1 code (en, de, fr, es, nl, it, pt)"`
+	Source         string  `json:"source" desc:"Optional: only documents from this index source (e.g. wiki, runbooks), shown in brackets in results"`
+	ModifiedAfter  string  `json:"modified_after" desc:"Optional: only pages modified on or after this date (YYYY-MM-DD)"`
+	ModifiedBefore string  `json:"modified_before" desc:"Optional: only pages modified before this date (YYYY-MM-DD)"`
+}
+
 func (w *WikiTool) Parameters() map[string]any {
-	return map[string]any{
-		"type": "object",
-		"properties": map[string]any{
-			"action": map[string]any{
-				"type":        "string",
-				"description": "Action to perform: 'search' to find relevant content, 'count' to get total indexed documents",
-				"enum":        []string{"search", "count"},
-			},
-			"query": map[string]any{
-				"type":        "string",
-				"description": "Search query (required for 'search' action)",
-			},
-			"limit": map[string]any{
-				"type":        "integer",
-				"description": "Maximum number of results to return (default: 5)",
-			},
-		},
-		"required": []string{"action"},
-	}
+	return schema.For[wikiParams]()
 }
 
 func (w *WikiTool) Call(ctx context.Context, params map[string]any) (string, error) {
-	action, ok := params["action"].(string)
-	if !ok {
-		return "", fmt.Errorf("action parameter required")
+	p, err := schema.Decode[wikiParams](params)
+	if err != nil {
+		return "", err
 	}
 
-	switch action {
+	var run func(*WikiTool) (string, error)
+	switch p.Action {
 	case "search":
-		return w.search(ctx, params)
+		run = func(w *WikiTool) (string, error) { return w.search(ctx, p) }
+	case "get_page":
+		run = func(w *WikiTool) (string, error) { return w.getPage(ctx, p) }
+	case "list":
+		run = func(w *WikiTool) (string, error) { return w.list(ctx, p) }
 	case "count":
-		return w.count(ctx)
+		run = func(w *WikiTool) (string, error) { return w.count(ctx) }
 	default:
-		return "", fmt.Errorf("unknown action: %s", action)
+		return "", fmt.Errorf("unknown action: %s", p.Action)
 	}
+	return w.withStore(ctx, run)
 }
 
-func (w *WikiTool) search(ctx context.Context, params map[string]any) (string, error) {
-	query, ok := params["query"].(string)
-	if !ok || query == "" {
+func (w *WikiTool) search(ctx context.Context, p wikiParams) (string, error) {
+	query := p.Query
+	if query == "" {
 		return "", fmt.Errorf("query parameter required for search action")
 	}
 
 	limit := 5
-	if l, ok := params["limit"].(float64); ok {
-		limit = int(l)
+	if p.Limit > 0 {
+		limit = p.Limit
 	}
 
-	// Generate embedding for query
-	queryVector, err := w.embeddings.Embed(ctx, query)
+	filter, err := searchFilter(p)
 	if err != nil {
-		return "", fmt.Errorf("failed to embed query: %w", err)
+		return "", err
 	}
 
-	// Search vector store
-	results, err := w.store.Search(ctx, queryVector, limit)
+	diversity, scope := p.Diversity, p.Context
+
+	fetch := limit
+	if diversity > 0 {
+		fetch = limit * mmrCandidateFactor
+	}
+	results, err := w.expandedSearch(ctx, query, fetch, filter, diversity > 0)
 	if err != nil {
-		return "", fmt.Errorf("failed to search: %w", err)
+		return "", err
+	}
+	if diversity > 0 {
+		results = rag.MMR(results, 1-diversity, limit)
+	}
+	if scope != "chunk" {
+		results = w.expandSections(ctx, results)
 	}
 
 	if len(results) == 0 {
 		return "No relevant results found in the wiki.", nil
 	}
+	return formatResults(results, scope), nil
+}
+
+// Retrieve returns the top limit chunks for query, formatted as search
+// results, or "" when nothing matches. It lets the agent add wiki context
+// to a question before the model decides on tools (auto-RAG).
+// While the vector store is unreachable it returns keyword matches, or ""
+// without a fallback, so each query isn't held up by the same error.
+func (w *WikiTool) Retrieve(ctx context.Context, query string, limit int) (string, error) {
+	found, err := w.withStore(ctx, func(w *WikiTool) (string, error) {
+		results, err := w.expandedSearch(ctx, query, limit, nil, false)
+		if err != nil || len(results) == 0 {
+			return "", err
+		}
+		// Chunks rather than sections keep the prompt small for local models
+		return formatResults(results, "chunk"), nil
+	})
+	if IsUnavailable(err) {
+		return "", nil
+	}
+	if found == degradedNote {
+		return "", nil // no keyword matches
+	}
+	return found, err
+}
 
-	// Format results
+// formatResults renders search results with their citations. Content is
+// truncated to 500 characters unless it is an expanded section.
+func formatResults(results []rag.Document, scope string) string {
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("Found %d relevant results:\n\n", len(results)))
 
@@ -110,20 +224,454 @@ func (w *WikiTool) search(ctx context.Context, params map[string]any) (string, e
 			pageTitle = "Unknown Page"
 		}
 
-		sb.WriteString(fmt.Sprintf("%d. [%s] %s (score: %.2f)\n", i+1, sourceType, pageTitle, doc.Score))
+		sb.WriteString(fmt.Sprintf("%d. [%s] %s%s (score: %.3f)\n", i+1, sourceType, sourceTag(doc), pageTitle, doc.Score))
 
+		if source := citation(doc); source != "" {
+			sb.WriteString(fmt.Sprintf("   Source: %s\n", source))
+		}
 		if doc.SourceType == "image" && doc.ImagePath != "" {
 			sb.WriteString(fmt.Sprintf("   Image: %s\n", doc.ImagePath))
 		}
 
-		// Truncate content for display
+		// Truncate content for display; expanded sections are already bounded
 		content := doc.Content
-		if len(content) > 500 {
+		if len(content) > 500 && (scope == "chunk" || doc.Metadata["section_index"] == "") {
 			content = content[:500] + "..."
 		}
 		sb.WriteString(fmt.Sprintf("   %s\n\n", content))
 	}
+	return sb.String()
+}
+
+// WikiRetriever searches one or more wiki tools for context to add to each
+// user message (auto-RAG)
+type WikiRetriever struct {
+	Tools []*WikiTool
+	Limit int // results per knowledge base
+}
+
+// Retrieve implements agent.ContextRetriever
+func (r WikiRetriever) Retrieve(ctx context.Context, query string) (string, error) {
+	var sb strings.Builder
+	for _, w := range r.Tools {
+		found, err := w.Retrieve(ctx, query, r.Limit)
+		if err != nil {
+			return "", fmt.Errorf("failed to search %s: %w", w.Name(), err)
+		}
+		if found == "" {
+			continue
+		}
+		if len(r.Tools) > 1 {
+			sb.WriteString(fmt.Sprintf("From %s:\n", w.Name()))
+		}
+		sb.WriteString(found)
+	}
+	return sb.String(), nil
+}
+
+// expandSections replaces each text result with the section of its page
+// that encloses it, limited to maxSectionChars around the matching chunk.
+// Later results from a section already returned are dropped. Results from
+// indexes without section metadata are returned unchanged.
+func (w *WikiTool) expandSections(ctx context.Context, results []rag.Document) []rag.Document {
+	seen := map[string]bool{}
+	var expanded []rag.Document
+	for _, doc := range results {
+		section, ok := doc.Metadata["section_index"]
+		if !ok || doc.SourceType != "text" {
+			expanded = append(expanded, doc)
+			continue
+		}
+		key := doc.Metadata["file_path"] + "#" + section
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if text, err := w.sectionText(ctx, doc); err == nil {
+			doc.Content = text
+		}
+		expanded = append(expanded, doc)
+	}
+	return expanded
+}
+
+// sectionText joins the chunks of doc's section in page order, growing a
+// window around doc until it would exceed maxSectionChars
+func (w *WikiTool) sectionText(ctx context.Context, doc rag.Document) (string, error) {
+	pageDocs, err := w.scrollAll(ctx, &rag.Filter{FilePath: doc.Metadata["file_path"]})
+	if err != nil {
+		return "", err
+	}
+	var chunks []rag.Document
+	for _, d := range pageDocs {
+		if d.SourceType == "text" && d.Metadata["section_index"] == doc.Metadata["section_index"] {
+			chunks = append(chunks, d)
+		}
+	}
+	sort.Slice(chunks, func(a, b int) bool { return chunkIndex(chunks[a]) < chunkIndex(chunks[b]) })
+
+	hit := slices.IndexFunc(chunks, func(d rag.Document) bool { return d.ID == doc.ID })
+	if hit < 0 {
+		return doc.Content, nil
+	}
+	lo, hi := hit, hit+1 // window chunks[lo:hi]
+	size := len(doc.Content)
+	for grew := true; grew; {
+		grew = false
+		if hi < len(chunks) && size+len(chunks[hi].Content)+1 <= maxSectionChars {
+			size += len(chunks[hi].Content) + 1
+			hi++
+			grew = true
+		}
+		if lo > 0 && size+len(chunks[lo-1].Content)+1 <= maxSectionChars {
+			lo--
+			size += len(chunks[lo].Content) + 1
+			grew = true
+		}
+	}
+	parts := make([]string, 0, hi-lo)
+	for _, d := range chunks[lo:hi] {
+		parts = append(parts, d.Content)
+	}
+	return strings.Join(parts, "\n"), nil
+}
+
+// scrollAll returns every stored document matching filter
+func (w *WikiTool) scrollAll(ctx context.Context, filter *rag.Filter) ([]rag.Document, error) {
+	var docs []rag.Document
+	q := rag.ScrollQuery{Filter: filter, Limit: 256}
+	for {
+		page, err := w.store.Scroll(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, page.Docs...)
+		if page.NextOffset == "" {
+			return docs, nil
+		}
+		q.Offset = page.NextOffset
+	}
+}
+
+// chunkIndex returns a chunk's position in its page
+func chunkIndex(doc rag.Document) int {
+	n, _ := strconv.Atoi(doc.Metadata["chunk_index"])
+	return n
+}
+
+// citation returns where a result came from: the page path or URL, with the
+// section heading's anchor when known, then the section name
+func citation(doc rag.Document) string {
+	source := doc.Metadata["file_path"]
+	if source == "" {
+		return ""
+	}
+	if anchor := doc.Metadata["anchor"]; anchor != "" {
+		source += "#" + anchor
+	}
+	if section := doc.Metadata["section"]; section != "" && section != doc.Metadata["page_title"] {
+		source += fmt.Sprintf(" (section %q)", section)
+	}
+	return source
+}
+
+// sourceTag returns "[name] " for documents from a multi-source index
+func sourceTag(doc rag.Document) string {
+	if source := doc.Metadata["source"]; source != "" {
+		return "[" + source + "] "
+	}
+	return ""
+}
+
+// expandedSearch runs the search for query, rewriting it first according to
+// the query expansion mode
+func (w *WikiTool) expandedSearch(ctx context.Context, query string, limit int, filter *rag.Filter, withVectors bool) ([]rag.Document, error) {
+	switch w.expansion {
+	case ExpandHyDE:
+		if w.keywordOnly {
+			break // nothing is embedded
+		}
+		// Embed a hypothetical answer, which sits closer to real answer chunks
+		// in vector space; keyword ranking still uses the user's own words.
+		if passage, err := w.rewrite(ctx, hydePrompt(query)); err == nil && passage != "" {
+			return w.searchOne(ctx, passage, query, limit, filter, withVectors)
+		}
+	case ExpandMultiQuery:
+		rewrites, err := w.rewrite(ctx, multiQueryPrompt(query))
+		if err != nil {
+			break
+		}
+		lists := [][]rag.Document{}
+		for _, q := range append([]string{query}, parseRewrites(rewrites, query)...) {
+			docs, err := w.searchOne(ctx, q, q, limit, filter, withVectors)
+			if err != nil {
+				return nil, err
+			}
+			lists = append(lists, docs)
+		}
+		return rag.FuseRRF(limit, lists...), nil
+	}
+	return w.searchOne(ctx, query, query, limit, filter, withVectors)
+}
+
+// searchOne embeds embedText and searches the store, using text for keyword
+// ranking
+func (w *WikiTool) searchOne(ctx context.Context, embedText, text string, limit int, filter *rag.Filter, withVectors bool) ([]rag.Document, error) {
+	var queryVector []float32
+	if !w.keywordOnly {
+		var err error
+		queryVector, err = w.embeddings.Embed(ctx, embedText)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed query: %w", err)
+		}
+	}
+
+	results, err := w.store.Search(ctx, rag.SearchQuery{
+		Vector: queryVector,
+		Text:   text,
+		Limit:  limit,
+		Filter: filter,
 
+		WithVectors: withVectors,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search: %w", err)
+	}
+	return results, nil
+}
+
+// rewrite asks the LLM for a query rewrite and returns its trimmed text
+func (w *WikiTool) rewrite(ctx context.Context, prompt string) (string, error) {
+	resp, err := w.client.Chat(ctx, []llm.Message{{Role: "user", Content: prompt}})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(resp.Content), nil
+}
+
+func hydePrompt(query string) string {
+	return "Write a short passage (3-5 sentences) from internal technical documentation that answers the question below. " +
+		"Write only the passage, in the style of a wiki page; invent plausible details if needed.\n\nQuestion: " + query
+}
+
+func multiQueryPrompt(query string) string {
+	return fmt.Sprintf("Rewrite the search query below as %d alternative search queries for an internal technical wiki. "+
+		"Expand abbreviations and use synonyms. Output one query per line with no numbering or commentary.\n\nQuery: %s", multiQueryCount, query)
+}
+
+// parseRewrites extracts up to multiQueryCount distinct queries from an LLM
+// reply, dropping list markers and repeats of the original query
+func parseRewrites(reply, original string) []string {
+	seen := map[string]bool{strings.ToLower(original): true}
+	var queries []string
+	for _, line := range strings.Split(reply, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "-*0123456789.) "))
+		line = strings.Trim(line, `"`)
+		if line == "" || seen[strings.ToLower(line)] {
+			continue
+		}
+		seen[strings.ToLower(line)] = true
+		queries = append(queries, line)
+		if len(queries) == multiQueryCount {
+			break
+		}
+	}
+	return queries
+}
+
+// searchFilter builds a metadata filter from the optional search parameters.
+// Returns nil when no filter parameter is set.
+func searchFilter(p wikiParams) (*rag.Filter, error) {
+	f := rag.Filter{
+		SourceType: p.SourceType,
+		ChunkType:  p.ChunkType,
+		PageTitle:  p.PageTitle,
+		Space:      p.Space,
+		Label:      p.Label,
+		Language:   p.Language,
+		Source:     p.Source,
+	}
+
+	for _, date := range []struct {
+		key, v string
+		dst    *time.Time
+	}{
+		{"modified_after", p.ModifiedAfter, &f.ModifiedAfter},
+		{"modified_before", p.ModifiedBefore, &f.ModifiedBefore},
+	} {
+		key, v, dst := date.key, date.v, date.dst
+		if v == "" {
+			continue
+		}
+		t, err := parseDate(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a date like 2024-01-31 (got %q)", key, v)
+		}
+		*dst = t
+	}
+
+	if f == (rag.Filter{}) {
+		return nil, nil
+	}
+	return &f, nil
+}
+
+// parseDate accepts YYYY-MM-DD or RFC 3339 timestamps
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// maxPageChars bounds the text returned by get_page
+const maxPageChars = 20000
+
+// getPage returns a page's text rebuilt from its stored chunks in page order,
+// followed by its diagram descriptions. The page is found by file path/URL,
+// or else by title; several matching titles are listed to choose from.
+func (w *WikiTool) getPage(ctx context.Context, p wikiParams) (string, error) {
+	ref := p.Page
+	if ref == "" {
+		ref = p.PageTitle
+	}
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return "", fmt.Errorf("page parameter required for get_page action")
+	}
+
+	docs, err := w.pageByPath(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to get page: %w", err)
+	}
+	if len(docs) == 0 {
+		matches, err := w.scrollAll(ctx, &rag.Filter{PageTitle: ref})
+		if err != nil {
+			return "", fmt.Errorf("failed to get page: %w", err)
+		}
+		titles := map[string]string{} // file path -> title
+		for _, d := range matches {
+			titles[d.Metadata["file_path"]] = d.Metadata["page_title"]
+		}
+		var paths []string
+		for path, title := range titles {
+			if strings.EqualFold(title, ref) {
+				paths = append(paths, path)
+			}
+		}
+		if len(paths) == 0 {
+			for path := range titles {
+				paths = append(paths, path)
+			}
+		}
+		sort.Strings(paths)
+		switch len(paths) {
+		case 0:
+			return fmt.Sprintf("No indexed page matches %q.", ref), nil
+		case 1:
+			for _, d := range matches {
+				if d.Metadata["file_path"] == paths[0] {
+					docs = append(docs, d)
+				}
+			}
+		default:
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("%d pages match %q; call get_page with one of these paths:\n", len(paths), ref))
+			for _, path := range paths[:min(len(paths), 10)] {
+				sb.WriteString(fmt.Sprintf("- %s (%s)\n", titles[path], path))
+			}
+			return sb.String(), nil
+		}
+	}
+
+	var text, diagrams []rag.Document
+	for _, d := range docs {
+		switch {
+		case d.SourceType == "image" && d.Metadata["chunk_type"] != "ocr":
+			diagrams = append(diagrams, d)
+		case d.SourceType == "text" && d.Metadata["chunk_type"] != "summary":
+			text = append(text, d)
+		}
+	}
+	sort.SliceStable(text, func(a, b int) bool { return chunkIndex(text[a]) < chunkIndex(text[b]) })
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Page: %s\nSource: %s\n", docs[0].Metadata["page_title"], docs[0].Metadata["file_path"]))
+	if path := docs[0].Metadata["breadcrumbs"]; path != "" {
+		sb.WriteString("Path: " + path + "\n")
+	}
+	if labels := rag.SplitLabels(docs[0].Metadata["labels"]); len(labels) > 0 {
+		sb.WriteString("Labels: " + strings.Join(labels, ", ") + "\n")
+	}
+	sb.WriteString("\n")
+	for _, d := range text {
+		sb.WriteString(d.Content)
+		sb.WriteString("\n\n")
+	}
+	for _, d := range diagrams {
+		sb.WriteString(fmt.Sprintf("[Diagram %s] %s\n\n", d.ImagePath, d.Content))
+	}
+	out := sb.String()
+	if len(out) > maxPageChars {
+		out = out[:maxPageChars] + "\n... (page truncated)"
+	}
+	return out, nil
+}
+
+// pageByPath returns the documents of the page stored under a file path or
+// URL. A citation's " (section ...)" suffix and "#anchor" are ignored.
+func (w *WikiTool) pageByPath(ctx context.Context, ref string) ([]rag.Document, error) {
+	ref, _, _ = strings.Cut(ref, " (section ")
+	candidates := []string{ref}
+	if i := strings.LastIndex(ref, "#"); i > 0 {
+		candidates = append(candidates, ref[:i]) // MediaWiki page paths contain '#' themselves
+	}
+	for _, path := range candidates {
+		docs, err := w.scrollAll(ctx, &rag.Filter{FilePath: path})
+		if err != nil || len(docs) > 0 {
+			return docs, err
+		}
+	}
+	return nil, nil
+}
+
+// list pages through indexed documents, one line per document
+func (w *WikiTool) list(ctx context.Context, p wikiParams) (string, error) {
+	limit := 20
+	if p.Limit > 0 {
+		limit = p.Limit
+	}
+	offset := p.Offset
+
+	filter, err := searchFilter(p)
+	if err != nil {
+		return "", err
+	}
+
+	page, err := w.store.Scroll(ctx, rag.ScrollQuery{Filter: filter, Limit: limit, Offset: offset})
+	if err != nil {
+		return "", fmt.Errorf("failed to list documents: %w", err)
+	}
+	if len(page.Docs) == 0 {
+		return "No indexed documents match.", nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Listing %d documents:\n\n", len(page.Docs)))
+	for _, doc := range page.Docs {
+		sourceType := "TEXT"
+		if doc.SourceType == "image" {
+			sourceType = "DIAGRAM"
+		}
+		content := strings.Join(strings.Fields(doc.Content), " ")
+		if len(content) > 80 {
+			content = content[:80] + "..."
+		}
+		sb.WriteString(fmt.Sprintf("- %s [%s] %s%s: %s\n", doc.ID, sourceType, sourceTag(doc), doc.Metadata["page_title"], content))
+	}
+	if page.NextOffset != "" {
+		sb.WriteString(fmt.Sprintf("\nMore documents available; call list again with offset=%q\n", page.NextOffset))
+	}
 	return sb.String(), nil
 }
 
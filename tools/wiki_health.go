@@ -0,0 +1,143 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rathore/langchain-agent/rag"
+)
+
+// wikiRetryInterval is how long a wiki tool whose vector store is
+// unreachable waits before trying it again
+const wikiRetryInterval = 30 * time.Second
+
+// degradedNote heads results from the keyword fallback
+const degradedNote = "[Degraded] The wiki's vector index is unreachable; these are keyword matches from the wiki export, which can miss pages that use other words.\n\n"
+
+// wikiHealth tracks whether a wiki tool's vector store answers, and holds
+// the keyword index searched while it doesn't
+type wikiHealth struct {
+	mu      sync.Mutex
+	downErr error     // nil while the store answers
+	retryAt time.Time // when to try the store again
+
+	build    func(ctx context.Context) (rag.Store, error) // nil: no fallback
+	fallback rag.Store
+	buildErr error
+	notify   func(msg string)
+}
+
+// SetFallback has the tool search build's store, built on first use, while
+// its vector store is unreachable (see rag.Indexer.KeywordIndex). Searches
+// on it are by keywords alone.
+func (w *WikiTool) SetFallback(build func(ctx context.Context) (rag.Store, error)) {
+	w.health.mu.Lock()
+	defer w.health.mu.Unlock()
+	w.health.build = build
+}
+
+// OnStatusChange sets a function told when the vector store becomes
+// unreachable and when it answers again
+func (w *WikiTool) OnStatusChange(notify func(msg string)) {
+	w.health.mu.Lock()
+	defer w.health.mu.Unlock()
+	w.health.notify = notify
+}
+
+// Degraded returns why the vector store is unreachable, or nil when it
+// answers
+func (w *WikiTool) Degraded() error {
+	w.health.mu.Lock()
+	defer w.health.mu.Unlock()
+	return w.health.downErr
+}
+
+// withStore runs fn with w, or, while the vector store is unreachable, with
+// a copy of w that searches the keyword fallback. A call that finds the
+// store unreachable runs again on the fallback. Without a fallback the
+// model is told plainly that the wiki can't be searched.
+func (w *WikiTool) withStore(ctx context.Context, fn func(*WikiTool) (string, error)) (string, error) {
+	if !w.health.skip() {
+		out, err := fn(w)
+		if !rag.Unreachable(err) || ctx.Err() != nil {
+			if err == nil {
+				w.health.up(w.name)
+			}
+			return out, err
+		}
+		w.health.down(w.name, err)
+	}
+
+	fallback, err := w.health.fallbackStore(ctx)
+	if fallback == nil {
+		w.health.mu.Lock()
+		downErr, wait := w.health.downErr, time.Until(w.health.retryAt).Round(time.Second)
+		w.health.mu.Unlock()
+		msg := fmt.Sprintf("%s is unavailable: its vector index can't be reached (%v); retrying in %s", w.name, downErr, max(wait, 0))
+		if err != nil {
+			msg += fmt.Sprintf(", and the keyword fallback failed: %v", err)
+		}
+		return "", Unavailable(fmt.Errorf("%s. Answer without the wiki and tell the user its documentation couldn't be searched", msg))
+	}
+	view := *w
+	view.store, view.keywordOnly = fallback, true
+	out, err := fn(&view)
+	if err != nil {
+		return "", err
+	}
+	return degradedNote + out, nil
+}
+
+// skip reports whether the store is known to be unreachable and not due
+// for another try
+func (h *wikiHealth) skip() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.downErr != nil && time.Now().Before(h.retryAt)
+}
+
+// down records that the store failed with err
+func (h *wikiHealth) down(name string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.downErr == nil && h.notify != nil {
+		fallback := "without wiki search"
+		if h.build != nil {
+			fallback = "using keyword search over the wiki export"
+		}
+		h.notify(fmt.Sprintf("%s: vector index unreachable (%v); %s until it answers", name, err, fallback))
+	}
+	h.downErr = err
+	h.retryAt = time.Now().Add(wikiRetryInterval)
+}
+
+// up records that the store answered
+func (h *wikiHealth) up(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.downErr != nil && h.notify != nil {
+		h.notify(fmt.Sprintf("%s: vector index reachable again", name))
+	}
+	h.downErr = nil
+}
+
+// fallbackStore returns the keyword index, building it on first use; nil
+// without a fallback or when building it failed. A build cut short by ctx
+// is tried again by the next call.
+func (h *wikiHealth) fallbackStore(ctx context.Context) (rag.Store, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.build == nil || h.fallback != nil || h.buildErr != nil {
+		return h.fallback, h.buildErr
+	}
+	store, err := h.build(ctx)
+	switch {
+	case err == nil:
+		h.fallback = store
+	case ctx.Err() == nil:
+		h.buildErr = err
+	}
+	return h.fallback, err
+}
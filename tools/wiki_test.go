@@ -0,0 +1,404 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rathore/langchain-agent/llm"
+	"github.com/rathore/langchain-agent/rag"
+)
+
+func TestSearchFilter(t *testing.T) {
+	f, err := searchFilter(wikiParams{Query: "network"})
+	if err != nil || f != nil {
+		t.Fatalf("searchFilter() = %+v, %v; want nil filter without filter params", f, err)
+	}
+
+	f, err = searchFilter(wikiParams{
+		SourceType:    "image",
+		Space:         "NET",
+		Label:         "runbook",
+		ModifiedAfter: "2024-02-01",
+	})
+	if err != nil {
+		t.Fatalf("searchFilter() error = %v", err)
+	}
+	if f.SourceType != "image" || f.Space != "NET" || f.Label != "runbook" {
+		t.Errorf("filter = %+v, want image/NET/runbook", f)
+	}
+	if want := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC); !f.ModifiedAfter.Equal(want) {
+		t.Errorf("ModifiedAfter = %v, want %v", f.ModifiedAfter, want)
+	}
+
+	if _, err := searchFilter(wikiParams{ModifiedBefore: "last week"}); err == nil {
+		t.Error("searchFilter() should reject an unparseable date")
+	}
+}
+
+func TestWikiTool_Count(t *testing.T) {
+	store := rag.NewMemoryStore()
+	ctx := context.Background()
+	if err := store.Upsert(ctx, []rag.Document{
+		{ID: "a", Content: "Deployment runbook", Vector: []float32{1, 0}},
+		{ID: "b", Content: "Network diagram", Vector: []float32{0, 1}},
+	}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	tool := NewNamedWikiTool("ops", nil, store)
+	if tool.Name() != "wiki_ops" {
+		t.Errorf("Name() = %q, want wiki_ops", tool.Name())
+	}
+	got, err := tool.Call(ctx, map[string]any{"action": "count"})
+	if err != nil {
+		t.Fatalf("Call(count) error = %v", err)
+	}
+	if got != "Wiki index contains 2 documents." {
+		t.Errorf("Call(count) = %q", got)
+	}
+}
+
+func TestWikiTool_List(t *testing.T) {
+	store := rag.NewMemoryStore()
+	ctx := context.Background()
+	store.Upsert(ctx, []rag.Document{
+		{ID: "a", Content: "Deployment   runbook", Vector: []float32{1}, SourceType: "text", Metadata: map[string]string{"page_title": "Deploy"}},
+		{ID: "b", Content: "Network diagram", Vector: []float32{1}, SourceType: "image", Metadata: map[string]string{"page_title": "Network"}},
+	})
+	tool := NewWikiTool(nil, store)
+
+	got, err := tool.Call(ctx, map[string]any{"action": "list", "limit": float64(1)})
+	if err != nil {
+		t.Fatalf("Call(list) error = %v", err)
+	}
+	if !strings.Contains(got, "- a [TEXT] Deploy: Deployment runbook") || !strings.Contains(got, `offset="b"`) {
+		t.Errorf("Call(list) = %q, want first doc and a next-page cursor", got)
+	}
+
+	got, _ = tool.Call(ctx, map[string]any{"action": "list", "source_type": "image"})
+	if !strings.Contains(got, "- b [DIAGRAM] Network") || strings.Contains(got, "offset=") {
+		t.Errorf("Call(list, image) = %q, want only the diagram", got)
+	}
+}
+
+// constEmbedder embeds every text as the same vector
+type constEmbedder []float32
+
+func (c constEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	return c, nil
+}
+
+func (c constEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i := range texts {
+		out[i] = c
+	}
+	return out, nil
+}
+
+func TestWikiTool_Search(t *testing.T) {
+	store := rag.NewMemoryStore()
+	ctx := context.Background()
+	store.Upsert(ctx, []rag.Document{
+		{ID: "a", Content: "Rollouts are gradual.", Vector: []float32{1, 0}, SourceType: "text", Metadata: map[string]string{"page_title": "Deploy"}},
+		{ID: "b", Content: "Topology of the core network.", Vector: []float32{0, 1}, SourceType: "image", ImagePath: "/w/net.png", Metadata: map[string]string{"page_title": "Network", "file_path": "/w/network.html", "section": "Core", "anchor": "Network-Core"}},
+	})
+	tool := NewWikiTool(constEmbedder{0, 1}, store)
+
+	got, err := tool.Call(ctx, map[string]any{"action": "search", "query": "network topology", "limit": float64(1)})
+	if err != nil {
+		t.Fatalf("Call(search) error = %v", err)
+	}
+	for _, want := range []string{"Found 1 relevant results", "1. [DIAGRAM] Network", `Source: /w/network.html#Network-Core (section "Core")`, "Image: /w/net.png"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Call(search) = %q, missing %q", got, want)
+		}
+	}
+
+	// Documents from a multi-source index are tagged and filterable by source
+	store.Upsert(ctx, []rag.Document{
+		{ID: "c", Content: "Fail over the core network to the standby region.", Vector: []float32{0, 1}, SourceType: "text", Metadata: map[string]string{"page_title": "Network outage", "source": "runbooks"}},
+	})
+	got, err = tool.Call(ctx, map[string]any{"action": "search", "query": "network", "source": "runbooks"})
+	if err != nil {
+		t.Fatalf("Call(search) error = %v", err)
+	}
+	if !strings.Contains(got, "Found 1 relevant results") || !strings.Contains(got, "1. [TEXT] [runbooks] Network outage") {
+		t.Errorf("Call(search, source=runbooks) = %q, want only the tagged runbook", got)
+	}
+}
+
+// scriptedClient replies to every Chat call with a fixed response
+type scriptedClient struct {
+	reply   string
+	prompts []string
+}
+
+func (c *scriptedClient) Chat(_ context.Context, messages []llm.Message) (*llm.Response, error) {
+	c.prompts = append(c.prompts, messages[len(messages)-1].Content)
+	return &llm.Response{Content: c.reply}, nil
+}
+
+// keywordEmbedder maps texts mentioning "memory" to one axis and everything
+// else to the other
+type keywordEmbedder struct{ embedded []string }
+
+func (e *keywordEmbedder) Embed(_ context.Context, text string) ([]float32, error) {
+	e.embedded = append(e.embedded, text)
+	if strings.Contains(strings.ToLower(text), "memory") {
+		return []float32{1, 0}, nil
+	}
+	return []float32{0, 1}, nil
+}
+
+func (e *keywordEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	var out [][]float32
+	for _, t := range texts {
+		v, _ := e.Embed(ctx, t)
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func TestWikiTool_QueryExpansion(t *testing.T) {
+	ctx := context.Background()
+	store := rag.NewMemoryStore()
+	store.Upsert(ctx, []rag.Document{
+		{ID: "runbook", Content: "Pods killed for exceeding memory limits: raise the limit", Vector: []float32{1, 0}, Metadata: map[string]string{"page_title": "Memory Runbook"}},
+		{ID: "other", Content: "Release calendar", Vector: []float32{0, 1}, Metadata: map[string]string{"page_title": "Releases"}},
+	})
+
+	tool := NewWikiTool(&keywordEmbedder{}, store)
+	if err := tool.SetQueryExpansion("rewrite", nil); err == nil {
+		t.Error("SetQueryExpansion() should reject an unknown mode")
+	}
+	if err := tool.SetQueryExpansion(ExpandHyDE, nil); err == nil {
+		t.Error("SetQueryExpansion() should require a client")
+	}
+
+	t.Run("hyde", func(t *testing.T) {
+		emb := &keywordEmbedder{}
+		tool := NewWikiTool(emb, store)
+		client := &scriptedClient{reply: "When a pod exceeds its memory limit the kernel kills it."}
+		tool.SetQueryExpansion(ExpandHyDE, client)
+
+		got, err := tool.Call(ctx, map[string]any{"action": "search", "query": "oom", "limit": float64(1)})
+		if err != nil {
+			t.Fatalf("Call(search) error = %v", err)
+		}
+		if !strings.Contains(got, "Memory Runbook") {
+			t.Errorf("search = %q, want the runbook via the hypothetical answer", got)
+		}
+		if len(emb.embedded) != 1 || emb.embedded[0] != client.reply {
+			t.Errorf("embedded %q, want only the hypothetical passage", emb.embedded)
+		}
+	})
+
+	t.Run("multi-query", func(t *testing.T) {
+		emb := &keywordEmbedder{}
+		tool := NewWikiTool(emb, store)
+		tool.SetQueryExpansion(ExpandMultiQuery, &scriptedClient{reply: "1. out of memory pod\n2. OOM\n- container memory limit exceeded\n- memory pressure eviction"})
+
+		got, err := tool.Call(ctx, map[string]any{"action": "search", "query": "oom", "limit": float64(1)})
+		if err != nil {
+			t.Fatalf("Call(search) error = %v", err)
+		}
+		if !strings.Contains(got, "Memory Runbook") {
+			t.Errorf("search = %q, want the runbook", got)
+		}
+		// Original plus three rephrasings; "OOM" repeats the query and is dropped
+		want := []string{"oom", "out of memory pod", "container memory limit exceeded", "memory pressure eviction"}
+		if strings.Join(emb.embedded, "|") != strings.Join(want, "|") {
+			t.Errorf("searched %q, want %q", emb.embedded, want)
+		}
+	})
+}
+
+func TestWikiTool_SearchDiversity(t *testing.T) {
+	store := rag.NewMemoryStore()
+	ctx := context.Background()
+	store.Upsert(ctx, []rag.Document{
+		{ID: "a1", Content: "Drain the node before patching.", Vector: []float32{1, 0}, Metadata: map[string]string{"page_title": "Patching"}},
+		{ID: "a2", Content: "Drain the node before patching it.", Vector: []float32{0.99, 0.05}, Metadata: map[string]string{"page_title": "Patching"}},
+		{ID: "b", Content: "Maintenance windows are on Sunday.", Vector: []float32{0.6, 0.8}, Metadata: map[string]string{"page_title": "Maintenance"}},
+	})
+	tool := NewWikiTool(constEmbedder{1, 0}, store)
+
+	got, err := tool.Call(ctx, map[string]any{"action": "search", "query": "patch", "limit": float64(2)})
+	if err != nil {
+		t.Fatalf("Call(search) error = %v", err)
+	}
+	if strings.Contains(got, "Maintenance") {
+		t.Errorf("search without diversity = %q, want both near-duplicates", got)
+	}
+
+	got, err = tool.Call(ctx, map[string]any{"action": "search", "query": "patch", "limit": float64(2), "diversity": 0.5})
+	if err != nil {
+		t.Fatalf("Call(search) error = %v", err)
+	}
+	if !strings.Contains(got, "1. [TEXT] Patching") || !strings.Contains(got, "2. [TEXT] Maintenance") {
+		t.Errorf("search with diversity = %q, want Patching then Maintenance", got)
+	}
+
+	if _, err := tool.Call(ctx, map[string]any{"action": "search", "query": "patch", "diversity": 2.0}); err == nil {
+		t.Error("Call(search) should reject diversity > 1")
+	}
+}
+
+func TestWikiTool_SearchExpandsSections(t *testing.T) {
+	store := rag.NewMemoryStore()
+	ctx := context.Background()
+	meta := func(section, chunk string) map[string]string {
+		return map[string]string{"page_title": "Runbook", "file_path": "/w/runbook.html", "section": "Restart", "section_index": section, "chunk_index": chunk}
+	}
+	store.Upsert(ctx, []rag.Document{
+		{ID: "intro", Content: "This runbook covers the billing service.", Vector: []float32{1, 0}, SourceType: "text", Metadata: meta("0", "0")},
+		{ID: "step1", Content: "First drain the node with kubectl drain.", Vector: []float32{0.5, 0.5}, SourceType: "text", Metadata: meta("1", "1")},
+		{ID: "step2", Content: "Then restart the api pods.", Vector: []float32{0, 1}, SourceType: "text", Metadata: meta("1", "2")},
+		{ID: "step3", Content: "Finally uncordon the node.", Vector: []float32{0.5, 0.5}, SourceType: "text", Metadata: meta("1", "3")},
+	})
+	tool := NewWikiTool(constEmbedder{0, 1}, store)
+
+	got, err := tool.Call(ctx, map[string]any{"action": "search", "query": "restart api", "limit": float64(2)})
+	if err != nil {
+		t.Fatalf("Call(search) error = %v", err)
+	}
+	want := "First drain the node with kubectl drain.\nThen restart the api pods.\nFinally uncordon the node."
+	if !strings.Contains(got, want) {
+		t.Errorf("Call(search) = %q, want the whole Restart section in page order", got)
+	}
+	if strings.Contains(got, "billing") || !strings.Contains(got, "Found 1 relevant results") {
+		t.Errorf("Call(search) = %q, want one result (same-section hits merged, other sections excluded)", got)
+	}
+
+	got, _ = tool.Call(ctx, map[string]any{"action": "search", "query": "restart api", "limit": float64(1), "context": "chunk"})
+	if strings.Contains(got, "kubectl drain") {
+		t.Errorf("Call(search, context=chunk) = %q, want only the matching chunk", got)
+	}
+}
+
+func TestWikiTool_GetPage(t *testing.T) {
+	store := rag.NewMemoryStore()
+	ctx := context.Background()
+	meta := func(path, title, chunkType, chunk string) map[string]string {
+		return map[string]string{"page_title": title, "file_path": path, "chunk_type": chunkType, "chunk_index": chunk}
+	}
+	store.Upsert(ctx, []rag.Document{
+		{ID: "c2", Content: "Then restart the api pods.", Vector: []float32{1, 0}, SourceType: "text", Metadata: meta("/w/restart.html", "Restart API", "paragraph", "1")},
+		{ID: "c1", Content: "First drain the node.", Vector: []float32{1, 0}, SourceType: "text", Metadata: meta("/w/restart.html", "Restart API", "paragraph", "0")},
+		{ID: "s", Content: "Summary of the page.", Vector: []float32{1, 0}, SourceType: "text", Metadata: meta("/w/restart.html", "Restart API", "summary", "")},
+		{ID: "d", Content: "Flowchart of the restart.", Vector: []float32{1, 0}, SourceType: "image", ImagePath: "/w/flow.png", Metadata: meta("/w/restart.html", "Restart API", "", "")},
+		{ID: "o", Content: "Restart the worker queue.", Vector: []float32{1, 0}, SourceType: "text", Metadata: meta("/w/worker.html", "Restart Worker", "paragraph", "0")},
+	})
+	tool := NewWikiTool(constEmbedder{1, 0}, store)
+
+	want := "Page: Restart API\nSource: /w/restart.html\n\nFirst drain the node.\n\nThen restart the api pods.\n\n[Diagram /w/flow.png] Flowchart of the restart.\n\n"
+	for _, ref := range []string{"Restart API", "restart api", "/w/restart.html", `/w/restart.html#Steps (section "Steps")`} {
+		got, err := tool.Call(ctx, map[string]any{"action": "get_page", "page": ref})
+		if err != nil {
+			t.Fatalf("Call(get_page %q) error = %v", ref, err)
+		}
+		if got != want {
+			t.Errorf("Call(get_page %q) = %q, want %q", ref, got, want)
+		}
+	}
+
+	got, _ := tool.Call(ctx, map[string]any{"action": "get_page", "page": "Restart"})
+	if !strings.Contains(got, "2 pages match") || !strings.Contains(got, "Restart Worker (/w/worker.html)") {
+		t.Errorf("Call(get_page ambiguous) = %q, want the candidates listed", got)
+	}
+	got, _ = tool.Call(ctx, map[string]any{"action": "get_page", "page": "Billing"})
+	if !strings.Contains(got, "No indexed page") {
+		t.Errorf("Call(get_page missing) = %q", got)
+	}
+}
+
+func TestWikiRetriever(t *testing.T) {
+	ctx := context.Background()
+	ops, dev := rag.NewMemoryStore(), rag.NewMemoryStore()
+	ops.Upsert(ctx, []rag.Document{{ID: "a", Content: "Restart the api pods with kubectl rollout restart.", Vector: []float32{1, 0}, SourceType: "text", Metadata: map[string]string{"page_title": "Restart API", "file_path": "/ops/restart.html"}}})
+
+	retriever := WikiRetriever{Tools: []*WikiTool{
+		NewNamedWikiTool("ops", constEmbedder{1, 0}, ops),
+		NewNamedWikiTool("dev", constEmbedder{1, 0}, dev),
+	}, Limit: 3}
+	got, err := retriever.Retrieve(ctx, "restart api")
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if !strings.HasPrefix(got, "From wiki_ops:\nFound 1 relevant results") || !strings.Contains(got, "Source: /ops/restart.html") || strings.Contains(got, "wiki_dev") {
+		t.Errorf("Retrieve() = %q, want results from the ops knowledge base only", got)
+	}
+
+	empty := WikiRetriever{Tools: []*WikiTool{NewWikiTool(constEmbedder{1, 0}, dev)}, Limit: 3}
+	if got, _ := empty.Retrieve(ctx, "restart api"); got != "" {
+		t.Errorf("Retrieve() on an empty index = %q, want \"\"", got)
+	}
+}
+
+// downStore fails every call as a Qdrant server that is restarting would,
+// until it is brought up
+type downStore struct {
+	rag.Store
+	up bool
+}
+
+func (s *downStore) Search(ctx context.Context, q rag.SearchQuery) ([]rag.Document, error) {
+	if !s.up {
+		return nil, fmt.Errorf("%w: HTTP 503: restarting", rag.ErrUnavailable)
+	}
+	return s.Store.Search(ctx, q)
+}
+
+func TestWikiTool_Degraded(t *testing.T) {
+	ctx := context.Background()
+	docs := []rag.Document{{ID: "a", Content: "Restart the api pods with kubectl rollout restart.", Vector: []float32{1, 0}, SourceType: "text", Metadata: map[string]string{"page_title": "Restart API"}}}
+	vectors := rag.NewMemoryStore()
+	vectors.Upsert(ctx, docs)
+	store := &downStore{Store: vectors}
+	tool := NewWikiTool(constEmbedder{1, 0}, store)
+	var notes []string
+	tool.OnStatusChange(func(msg string) { notes = append(notes, msg) })
+	search := map[string]any{"action": "search", "query": "restart api"}
+
+	// Without a fallback the model is told the wiki can't be searched
+	_, err := tool.Call(ctx, search)
+	if !IsUnavailable(err) || !strings.Contains(err.Error(), "wiki is unavailable") || !strings.Contains(err.Error(), "Answer without the wiki") {
+		t.Errorf("Call() error = %v, want a clear unavailable message", err)
+	}
+	tool.Call(ctx, search)
+	if len(notes) != 1 || !strings.Contains(notes[0], "without wiki search") || tool.Degraded() == nil {
+		t.Errorf("notes = %q, Degraded() = %v; want one notice and a degraded tool", notes, tool.Degraded())
+	}
+	if got, err := tool.Retrieve(ctx, "restart api", 3); got != "" || err != nil {
+		t.Errorf("Retrieve() = %q, %v; want nothing added to the prompt", got, err)
+	}
+
+	// With one it searches the export by keywords
+	tool.SetFallback(func(ctx context.Context) (rag.Store, error) {
+		keywords := rag.NewMemoryStore()
+		keywords.Upsert(ctx, []rag.Document{{ID: "a", Content: docs[0].Content, SourceType: "text", Metadata: docs[0].Metadata}})
+		return keywords, nil
+	})
+	got, err := tool.Call(ctx, search)
+	if err != nil {
+		t.Fatalf("Call() with a fallback error = %v", err)
+	}
+	if !strings.HasPrefix(got, "[Degraded]") || !strings.Contains(got, "Restart API") {
+		t.Errorf("Call() with a fallback = %q, want marked keyword matches", got)
+	}
+
+	// Once the retry interval is up the store is tried again
+	store.up = true
+	tool.health.retryAt = time.Now()
+	got, err = tool.Call(ctx, search)
+	if err != nil || strings.Contains(got, "[Degraded]") || !strings.Contains(got, "Restart API") {
+		t.Errorf("Call() after recovery = %q, %v", got, err)
+	}
+	if len(notes) != 2 || !strings.Contains(notes[1], "reachable again") || tool.Degraded() != nil {
+		t.Errorf("notes = %q, want a recovery notice", notes)
+	}
+}
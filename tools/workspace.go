@@ -0,0 +1,337 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rathore/langchain-agent/tools/schema"
+)
+
+// Workspace is a directory where tools keep files for the session:
+// downloaded logs, generated scripts, images returned by MCP tools. After
+// every write, files older than MaxAge are removed, then the oldest files
+// until the workspace fits in MaxBytes.
+type Workspace struct {
+	Dir      string
+	MaxAge   time.Duration // 0 keeps files regardless of age
+	MaxBytes int64         // 0 means no size limit
+
+	temporary bool // Dir was created by NewWorkspace and goes on Close
+	mu        sync.Mutex
+}
+
+var _ Closeable = (*Workspace)(nil)
+
+// WorkspaceFile is a file in a Workspace; Name is relative to its Dir
+type WorkspaceFile struct {
+	Name     string
+	Size     int64
+	Modified time.Time
+}
+
+// NewWorkspace opens dir as a workspace, creating it if needed. An empty dir
+// creates a temporary directory that Close removes.
+func NewWorkspace(dir string) (*Workspace, error) {
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "langchain-agent-workspace-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create workspace: %w", err)
+		}
+		return &Workspace{Dir: tmp, temporary: true}, nil
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+	return &Workspace{Dir: abs}, nil
+}
+
+// Path returns the file name in the workspace, which must be a relative
+// path that stays inside it
+func (w *Workspace) Path(name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("file name required")
+	}
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("%q is outside the workspace: use a relative path such as logs/app.log", name)
+	}
+	return filepath.Join(w.Dir, name), nil
+}
+
+// Write writes data to the named file, replacing it, and prunes the
+// workspace
+func (w *Workspace) Write(name string, data []byte) error {
+	path, err := w.Path(name)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	_, err = w.prune(path)
+	return err
+}
+
+// Save writes data to a new file named after name (name-2.ext, name-3.ext,
+// ... when it is taken) and returns the name used
+func (w *Workspace) Save(name string, data []byte) (string, error) {
+	if _, err := w.Path(name); err != nil {
+		return "", err
+	}
+	w.mu.Lock()
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	free := name
+	for i := 2; ; i++ {
+		if _, err := os.Lstat(filepath.Join(w.Dir, free)); os.IsNotExist(err) {
+			break
+		}
+		free = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+	w.mu.Unlock()
+	return free, w.Write(free, data)
+}
+
+// Read returns the content of the named file
+func (w *Workspace) Read(name string) ([]byte, error) {
+	path, err := w.Path(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return data, nil
+}
+
+// Remove deletes the named file
+func (w *Workspace) Remove(name string) error {
+	path, err := w.Path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", name, err)
+	}
+	return nil
+}
+
+// Files lists the workspace's files by name
+func (w *Workspace) Files() ([]WorkspaceFile, error) {
+	var files []WorkspaceFile
+	err := filepath.WalkDir(w.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		name, _ := filepath.Rel(w.Dir, path)
+		files = append(files, WorkspaceFile{Name: filepath.ToSlash(name), Size: info.Size(), Modified: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspace: %w", err)
+	}
+	return files, nil
+}
+
+// Prune applies MaxAge and MaxBytes and returns the files it removed.
+// Files written by tools other than the workspace's own methods (e.g. the
+// shell) are pruned too.
+func (w *Workspace) Prune() ([]string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.prune("")
+}
+
+// prune is Prune keeping the file at path, the one just written
+func (w *Workspace) prune(keep string) ([]string, error) {
+	if w.MaxAge <= 0 && w.MaxBytes <= 0 {
+		return nil, nil
+	}
+	files, err := w.Files()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Modified.Before(files[j].Modified) })
+	var total int64
+	for _, f := range files {
+		total += f.Size
+	}
+	var removed []string
+	for _, f := range files {
+		path := filepath.Join(w.Dir, filepath.FromSlash(f.Name))
+		expired := w.MaxAge > 0 && time.Since(f.Modified) > w.MaxAge
+		over := w.MaxBytes > 0 && total > w.MaxBytes
+		if path == keep || !expired && !over {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return removed, fmt.Errorf("failed to prune workspace: %w", err)
+		}
+		total -= f.Size
+		removed = append(removed, f.Name)
+	}
+	return removed, nil
+}
+
+// Close removes a temporary workspace
+func (w *Workspace) Close() error {
+	if !w.temporary {
+		return nil
+	}
+	return os.RemoveAll(w.Dir)
+}
+
+// WorkspaceTool lets the LLM list, read, write and delete the files of a
+// Workspace
+type WorkspaceTool struct {
+	Workspace *Workspace
+}
+
+func (t *WorkspaceTool) Name() string {
+	return "workspace"
+}
+
+// Meta declares listing and reading as read-only; writes stay in the
+// workspace directory
+func (t *WorkspaceTool) Meta() Meta {
+	return Meta{
+		Category:     CategoryLocal,
+		ReadOnlyWhen: &ReadOnlyCalls{Param: "action", Values: []string{"list", "read"}},
+		Risk:         RiskMedium,
+	}
+}
+
+func (t *WorkspaceTool) Description() string {
+	return "List, read, write and delete files in this session's workspace directory: logs saved by commands, generated scripts, images and files returned by MCP tools. Shell commands can use the same directory as $WORKSPACE, e.g. to save long output and read it in parts."
+}
+
+// workspaceParams are the parameters of a workspace call
+type workspaceParams struct {
+	Action  string  `json:"action" desc:"Action to perform: 'list' the files, 'read' a text file, 'write' a file (replacing it), 'delete' a file" required:"true" enum:"list,read,write,delete"`
+	Name    string  `json:"name" desc:"File path relative to the workspace, e.g. logs/app.log (for read, write and delete)"`
+	Content *string `json:"content" desc:"For 'write': the file content"`
+	Offset  int     `json:"offset" desc:"For 'read': the first line to return, from 1 (default 1)" min:"1" default:"1"`
+	Limit   int     `json:"limit" desc:"For 'read': the number of lines to return (default 200)" min:"1" default:"200"`
+}
+
+func (t *WorkspaceTool) Parameters() map[string]any {
+	return schema.For[workspaceParams]()
+}
+
+func (t *WorkspaceTool) Call(ctx context.Context, params map[string]any) (string, error) {
+	p, err := schema.Decode[workspaceParams](params)
+	if err != nil {
+		return "", err
+	}
+	name := p.Name
+
+	switch p.Action {
+	case "list":
+		return t.list()
+	case "read":
+		return t.read(name, p.Offset, p.Limit)
+	case "write":
+		if p.Content == nil {
+			return "", fmt.Errorf("content parameter required for write action")
+		}
+		content := *p.Content
+		if err := t.Workspace.Write(name, []byte(content)); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Wrote %s (%s) in the workspace, %s", name, formatSize(int64(len(content))), filepath.Join(t.Workspace.Dir, name)), nil
+	case "delete":
+		if err := t.Workspace.Remove(name); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Deleted %s", name), nil
+	default:
+		return "", fmt.Errorf("unknown action: %s", p.Action)
+	}
+}
+
+func (t *WorkspaceTool) list() (string, error) {
+	if _, err := t.Workspace.Prune(); err != nil {
+		return "", err
+	}
+	files, err := t.Workspace.Files()
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return fmt.Sprintf("The workspace (%s) is empty.", t.Workspace.Dir), nil
+	}
+	var b strings.Builder
+	if len(files) == 1 {
+		fmt.Fprintf(&b, "1 file in %s:\n", t.Workspace.Dir)
+	} else {
+		fmt.Fprintf(&b, "%d files in %s:\n", len(files), t.Workspace.Dir)
+	}
+	for _, f := range files {
+		fmt.Fprintf(&b, "- %s (%s, modified %s)\n", f.Name, formatSize(f.Size), f.Modified.Format(time.DateTime))
+	}
+	return b.String(), nil
+}
+
+func (t *WorkspaceTool) read(name string, offset, limit int) (string, error) {
+	data, err := t.Workspace.Read(name)
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("%s is a binary file (%s, %s), not text: pass its path, %s, to a tool that can use it", name,
+			http.DetectContentType(data), formatSize(int64(len(data))), filepath.Join(t.Workspace.Dir, name))
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return fmt.Sprintf("%s is empty", name), nil
+	}
+	if offset > len(lines) {
+		return "", fmt.Errorf("%s has only %d lines", name, len(lines))
+	}
+	end := min(offset-1+limit, len(lines))
+	text := strings.Join(lines[offset-1:end], "")
+	if offset == 1 && end == len(lines) {
+		return text, nil
+	}
+	return fmt.Sprintf("%s, lines %d-%d of %d:\n%s", name, offset, end, len(lines), text), nil
+}
+
+// formatSize formats a byte count for people
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d bytes", n)
+	}
+}
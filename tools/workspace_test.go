@@ -0,0 +1,129 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWorkspace(t *testing.T) {
+	w, err := NewWorkspace(filepath.Join(t.TempDir(), "ws"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write("logs/app.log", []byte("line 1\n")); err != nil {
+		t.Fatal(err)
+	}
+	name, err := w.Save("logs/app.log", []byte("line 2\n"))
+	if err != nil || name != "logs/app-2.log" {
+		t.Errorf("Save = %q, %v; want logs/app-2.log", name, err)
+	}
+	for _, bad := range []string{"", "../escape", "/etc/passwd", "logs/../../x"} {
+		if err := w.Write(bad, nil); err == nil {
+			t.Errorf("Write(%q) should fail", bad)
+		}
+	}
+	files, err := w.Files()
+	if err != nil || len(files) != 2 || files[0].Name != "logs/app-2.log" || files[1].Size != 7 {
+		t.Errorf("Files = %+v, %v", files, err)
+	}
+
+	// A named workspace outlives Close
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(w.Dir); err != nil {
+		t.Errorf("Close removed a named workspace: %v", err)
+	}
+
+	tmp, err := NewWorkspace("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp.Close()
+	if _, err := os.Stat(tmp.Dir); !os.IsNotExist(err) {
+		t.Errorf("Close kept the temporary workspace: %v", err)
+	}
+}
+
+func TestWorkspace_Prune(t *testing.T) {
+	w, err := NewWorkspace(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	for i, name := range []string{"old.txt", "a.txt", "b.txt"} {
+		os.WriteFile(filepath.Join(w.Dir, name), []byte("0123456789"), 0600)
+		modified := old.Add(time.Duration(i) * time.Hour)
+		os.Chtimes(filepath.Join(w.Dir, name), modified, modified)
+	}
+
+	w.MaxAge = 90 * time.Minute
+	removed, err := w.Prune()
+	if err != nil || strings.Join(removed, ",") != "old.txt" {
+		t.Errorf("Prune by age removed %v, %v", removed, err)
+	}
+
+	// The file just written is kept, the oldest others go
+	w.MaxAge, w.MaxBytes = 0, 25
+	if err := w.Write("c.txt", []byte("0123456789")); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := w.Files()
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	if strings.Join(names, ",") != "b.txt,c.txt" {
+		t.Errorf("files after Prune by size = %v", names)
+	}
+}
+
+func TestWorkspaceTool(t *testing.T) {
+	w, err := NewWorkspace(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool := &WorkspaceTool{Workspace: w}
+	ctx := context.Background()
+
+	out, err := tool.Call(ctx, map[string]any{"action": "list"})
+	if err != nil || !strings.Contains(out, "is empty") {
+		t.Errorf("list empty = %q, %v", out, err)
+	}
+	script := "#!/bin/sh\necho one\necho two\necho three\n"
+	if _, err := tool.Call(ctx, map[string]any{"action": "write", "name": "check.sh", "content": script}); err != nil {
+		t.Fatal(err)
+	}
+	out, _ = tool.Call(ctx, map[string]any{"action": "list"})
+	if !strings.Contains(out, "- check.sh (39 bytes") {
+		t.Errorf("list = %q", out)
+	}
+
+	out, _ = tool.Call(ctx, map[string]any{"action": "read", "name": "check.sh"})
+	if out != script {
+		t.Errorf("read = %q", out)
+	}
+	out, _ = tool.Call(ctx, map[string]any{"action": "read", "name": "check.sh", "offset": float64(2), "limit": float64(2)})
+	if out != "check.sh, lines 2-3 of 4:\necho one\necho two\n" {
+		t.Errorf("read lines 2-3 = %q", out)
+	}
+
+	w.Write("shot.png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\xff\xfe"))
+	if _, err := tool.Call(ctx, map[string]any{"action": "read", "name": "shot.png"}); err == nil || !strings.Contains(err.Error(), "binary file (image/png") {
+		t.Errorf("read binary: %v", err)
+	}
+
+	if _, err := tool.Call(ctx, map[string]any{"action": "delete", "name": "check.sh"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tool.Call(ctx, map[string]any{"action": "read", "name": "check.sh"}); err == nil {
+		t.Error("read after delete should fail")
+	}
+	if _, err := tool.Call(ctx, map[string]any{"action": "read", "name": "../x"}); err == nil || !strings.Contains(err.Error(), "outside the workspace") {
+		t.Errorf("read outside: %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package trigger
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxAlertBody limits the size of an Alertmanager notification
+const maxAlertBody = 1 << 20
+
+// alertNotification is Alertmanager's webhook payload
+type alertNotification struct {
+	Status string  `json:"status"`
+	Alerts []alert `json:"alerts"`
+}
+
+type alert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     string            `json:"startsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// event turns a firing alert into the trigger event. Its fields are the
+// alert's labels and annotations (labels win on a clash), status,
+// starts_at, generator_url and fingerprint.
+func (a alert) event() Event {
+	fields := map[string]string{}
+	for k, v := range a.Annotations {
+		fields[k] = v
+	}
+	for k, v := range a.Labels {
+		fields[k] = v
+	}
+	fields["status"] = a.Status
+	fields["starts_at"] = a.StartsAt
+	fields["generator_url"] = a.GeneratorURL
+	fields["fingerprint"] = a.Fingerprint
+
+	key := a.Fingerprint
+	if key == "" {
+		pairs := make([]string, 0, len(a.Labels))
+		for k, v := range a.Labels {
+			pairs = append(pairs, k+"="+v)
+		}
+		sort.Strings(pairs)
+		key = strings.Join(pairs, ",")
+	}
+	summary := a.Labels["alertname"]
+	if summary == "" {
+		summary = "alert"
+	}
+	if where := a.Labels["instance"]; where != "" {
+		summary += " on " + where
+	} else if where := a.Labels["namespace"]; where != "" {
+		summary += " in " + where
+	}
+	return Event{Key: key, Summary: summary, Fields: fields}
+}
+
+// alertServer receives the notifications of the alertmanager triggers
+// listening on one address, each on /alerts/<trigger name>
+type alertServer struct {
+	ln  net.Listener
+	srv *http.Server
+}
+
+// listenAlerts opens the listeners of the alertmanager triggers
+func (r *Runner) listenAlerts(triggers []*Trigger) ([]*alertServer, error) {
+	muxes := map[string]*http.ServeMux{}
+	var addrs []string
+	for _, t := range triggers {
+		mux := muxes[t.Listen]
+		if mux == nil {
+			mux = http.NewServeMux()
+			muxes[t.Listen] = mux
+			addrs = append(addrs, t.Listen)
+		}
+		mux.Handle("/alerts/"+t.Name, r.alertHandler(t))
+	}
+	var servers []*alertServer
+	for _, addr := range addrs {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			for _, s := range servers {
+				s.ln.Close()
+			}
+			return nil, fmt.Errorf("failed to listen for alerts: %w", err)
+		}
+		servers = append(servers, &alertServer{ln: ln, srv: &http.Server{Handler: muxes[addr], ReadHeaderTimeout: 5 * time.Second}})
+	}
+	return servers, nil
+}
+
+// serve serves notifications until ctx is cancelled
+func (s *alertServer) serve(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.srv.Shutdown(shutdownCtx)
+	}()
+	s.srv.Serve(s.ln)
+}
+
+// alertHandler fires t for each firing alert of a notification. Runs are
+// queued, so Alertmanager gets its answer at once.
+func (r *Runner) alertHandler(t *Trigger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			http.Error(w, "POST required", http.StatusMethodNotAllowed)
+			return
+		}
+		if t.TokenSHA256 != "" && !validToken(req, t.TokenSHA256) {
+			http.Error(w, "invalid or missing bearer token", http.StatusUnauthorized)
+			return
+		}
+		var n alertNotification
+		if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxAlertBody)).Decode(&n); err != nil {
+			http.Error(w, "invalid notification: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, a := range n.Alerts {
+			if a.Status == "" {
+				a.Status = n.Status
+			}
+			if a.Status == "firing" {
+				r.fire(t, a.event())
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// validToken reports whether req's bearer token hashes to sha (hex)
+func validToken(req *http.Request, sha string) bool {
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	sum := sha256.Sum256([]byte(token))
+	return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(strings.ToLower(sha))) == 1
+}
@@ -0,0 +1,74 @@
+package trigger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const notification = `{
+  "version": "4",
+  "status": "firing",
+  "alerts": [
+    {"status": "firing", "labels": {"alertname": "DiskFull", "instance": "db-1:9100", "severity": "critical"},
+     "annotations": {"summary": "Disk / is 97% full", "severity": "ignored"}, "startsAt": "2026-10-15T09:00:00Z", "fingerprint": "a1b2"},
+    {"status": "firing", "labels": {"alertname": "HighLatency", "severity": "warning"}, "fingerprint": "c3d4"},
+    {"status": "resolved", "labels": {"alertname": "NodeDown", "severity": "critical"}, "fingerprint": "e5f6"}
+  ]
+}`
+
+func TestAlertHandler(t *testing.T) {
+	trig := &Trigger{Name: "critical", Source: "alertmanager", Listen: ":0", Match: map[string]string{"severity": "critical"}, Prompt: "{{.alertname}} on {{.instance}}: {{.summary}}",
+		TokenSHA256: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"} // "test"
+	if err := trig.compile(); err != nil {
+		t.Fatal(err)
+	}
+	r := NewRunner(&Config{Triggers: []*Trigger{trig}}, nil)
+	h := r.alertHandler(trig)
+
+	post := func(token, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/alerts/critical", strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := post("wrong", notification); code != http.StatusUnauthorized {
+		t.Errorf("wrong token: %d, want 401", code)
+	}
+	if code := post("test", "{"); code != http.StatusBadRequest {
+		t.Errorf("bad JSON: %d, want 400", code)
+	}
+	if code := post("test", notification); code != http.StatusOK {
+		t.Fatalf("notification: %d, want 200", code)
+	}
+	if len(r.queue) != 1 {
+		t.Fatalf("queued %d runs, want 1 (only the firing critical alert)", len(r.queue))
+	}
+	j := <-r.queue
+	prompt, _ := trig.Render(j.event)
+	if prompt != "DiskFull on db-1:9100: Disk / is 97% full" || j.event.Key != "a1b2" || j.event.Summary != "DiskFull on db-1:9100" {
+		t.Errorf("event = %+v, prompt %q", j.event, prompt)
+	}
+}
+
+func TestListenAlerts(t *testing.T) {
+	a := &Trigger{Name: "a", Source: "alertmanager", Listen: "127.0.0.1:0", Prompt: "p"}
+	b := &Trigger{Name: "b", Source: "alertmanager", Listen: "127.0.0.1:0", Prompt: "p"}
+	r := NewRunner(&Config{Triggers: []*Trigger{a, b}}, nil)
+	servers, err := r.listenAlerts([]*Trigger{a, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer servers[0].ln.Close()
+	if len(servers) != 1 {
+		t.Errorf("%d listeners for one address, want 1", len(servers))
+	}
+
+	if _, err := r.listenAlerts([]*Trigger{{Name: "c", Listen: "256.0.0.1:1"}}); err == nil {
+		t.Error("listenAlerts() opened an invalid address")
+	}
+}
@@ -0,0 +1,8 @@
+// Package trigger starts agent runs from events instead of user queries:
+// Kubernetes warning events (watched with kubectl), Prometheus alerts
+// (posted by Alertmanager) and JSON payloads posted to /webhook/<name>
+// (Grafana, CI systems) are matched against the triggers of a --triggers
+// file, whose prompt template becomes the query of a run. Each run's answer
+// is written as a Markdown triage report and can be posted on, e.g. to
+// Slack.
+package trigger
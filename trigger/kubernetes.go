@@ -0,0 +1,173 @@
+package trigger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// kubeRestartMax caps the wait before restarting a kubectl watch that failed
+const kubeRestartMax = 5 * time.Minute
+
+// kubeEvent is the part of a core/v1 Event a trigger uses
+type kubeEvent struct {
+	Metadata struct {
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	InvolvedObject struct {
+		Kind      string `json:"kind"`
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"involvedObject"`
+	Type          string `json:"type"`
+	Reason        string `json:"reason"`
+	Message       string `json:"message"`
+	Count         int    `json:"count"`
+	LastTimestamp string `json:"lastTimestamp"`
+	EventTime     string `json:"eventTime"`
+	Series        *struct {
+		Count            int    `json:"count"`
+		LastObservedTime string `json:"lastObservedTime"`
+	} `json:"series"`
+	Source struct {
+		Component string `json:"component"`
+		Host      string `json:"host"`
+	} `json:"source"`
+	ReportingComponent string `json:"reportingComponent"`
+}
+
+// event turns e into the trigger event. Its fields are namespace, kind,
+// name, object (e.g. pod/api-1), type, reason, message, count, last_seen,
+// component, host and cluster (the kubeconfig context, if set).
+func (e kubeEvent) event(cluster string) Event {
+	ns := e.InvolvedObject.Namespace
+	if ns == "" {
+		ns = e.Metadata.Namespace
+	}
+	count, lastSeen := e.Count, e.LastTimestamp
+	if e.Series != nil {
+		count, lastSeen = e.Series.Count, e.Series.LastObservedTime
+	}
+	if lastSeen == "" {
+		lastSeen = e.EventTime
+	}
+	component := e.Source.Component
+	if component == "" {
+		component = e.ReportingComponent
+	}
+	object := strings.ToLower(e.InvolvedObject.Kind) + "/" + e.InvolvedObject.Name
+	fields := map[string]string{
+		"namespace": ns,
+		"kind":      e.InvolvedObject.Kind,
+		"name":      e.InvolvedObject.Name,
+		"object":    object,
+		"type":      e.Type,
+		"reason":    e.Reason,
+		"message":   strings.TrimSpace(e.Message),
+		"count":     strconv.Itoa(max(count, 1)),
+		"last_seen": lastSeen,
+		"component": component,
+		"host":      e.Source.Host,
+		"cluster":   cluster,
+	}
+	summary := fmt.Sprintf("%s %s", e.Reason, object)
+	if ns != "" {
+		summary += " in " + ns
+	}
+	return Event{
+		Key:     strings.Join([]string{cluster, ns, e.InvolvedObject.Kind, e.InvolvedObject.Name, e.Reason}, "/"),
+		Summary: summary,
+		Fields:  fields,
+	}
+}
+
+// kubectlArgs are the arguments of t's kubectl watch of warning events
+func (t *Trigger) kubectlArgs() []string {
+	args := []string{"get", "events", "--watch-only", "--output", "json", "--field-selector", "type=Warning"}
+	if t.Namespace != "" {
+		args = append(args, "--namespace", t.Namespace)
+	} else {
+		args = append(args, "--all-namespaces")
+	}
+	if t.Context != "" {
+		args = append(args, "--context", t.Context)
+	}
+	return args
+}
+
+// watchKubernetes fires t for each warning event kubectl reports until ctx
+// is cancelled, restarting kubectl with a growing wait when it exits
+func (r *Runner) watchKubernetes(ctx context.Context, t *Trigger) {
+	kubectl := t.Kubectl
+	if kubectl == "" {
+		kubectl = "kubectl"
+	}
+	wait := 10 * time.Second
+	for {
+		started := r.now()
+		err := watchEvents(ctx, kubectl, t.kubectlArgs(), func(e kubeEvent) {
+			r.fire(t, e.event(t.Context))
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if r.now().Sub(started) > kubeRestartMax {
+			wait = 10 * time.Second // it ran fine for a while
+		}
+		if err == nil {
+			err = errors.New("watch ended")
+		}
+		r.logf(t, "kubectl: %v; restarting in %s", err, wait)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		wait = min(2*wait, kubeRestartMax)
+	}
+}
+
+// watchEvents runs kubectl and calls fn for each event it prints
+func watchEvents(ctx context.Context, kubectl string, args []string, fn func(kubeEvent)) error {
+	cmd := exec.CommandContext(ctx, kubectl, args...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start: %w", err)
+	}
+	decodeErr := decodeEvents(stdout, fn)
+	if err := cmd.Wait(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return decodeErr
+}
+
+// decodeEvents reads the stream of JSON objects kubectl get --watch
+// prints, calling fn for each event
+func decodeEvents(r io.Reader, fn func(kubeEvent)) error {
+	dec := json.NewDecoder(r)
+	for {
+		var e kubeEvent
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid event from kubectl: %w", err)
+		}
+		fn(e)
+	}
+}
@@ -0,0 +1,119 @@
+package trigger
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rathore/langchain-agent/agent"
+)
+
+// kubectlOutput is what kubectl get events --watch-only -o json prints
+const kubectlOutput = `{
+    "apiVersion": "v1",
+    "kind": "Event",
+    "metadata": {"name": "api-1.17f", "namespace": "prod"},
+    "involvedObject": {"kind": "Pod", "name": "api-1", "namespace": "prod"},
+    "type": "Warning",
+    "reason": "BackOff",
+    "message": "Back-off restarting failed container api",
+    "count": 7,
+    "lastTimestamp": "2026-10-15T09:29:55Z",
+    "source": {"component": "kubelet", "host": "node-3"}
+}
+{
+    "apiVersion": "v1",
+    "kind": "Event",
+    "metadata": {"name": "n1.18a", "namespace": "default"},
+    "involvedObject": {"kind": "Node", "name": "n1"},
+    "type": "Warning",
+    "reason": "OOMKilling",
+    "message": "Memory cgroup out of memory",
+    "eventTime": "2026-10-15T09:30:01.000000Z",
+    "series": {"count": 2, "lastObservedTime": "2026-10-15T09:30:12.000000Z"},
+    "reportingComponent": "kernel-monitor"
+}
+`
+
+func TestDecodeEvents(t *testing.T) {
+	var events []Event
+	err := decodeEvents(strings.NewReader(kubectlOutput), func(e kubeEvent) { events = append(events, e.event("prod-cluster")) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("decoded %d events, want 2", len(events))
+	}
+	pod := events[0]
+	if pod.Summary != "BackOff pod/api-1 in prod" || pod.Key != "prod-cluster/prod/Pod/api-1/BackOff" {
+		t.Errorf("event = %+v", pod)
+	}
+	for k, want := range map[string]string{"object": "pod/api-1", "count": "7", "component": "kubelet", "host": "node-3", "cluster": "prod-cluster", "last_seen": "2026-10-15T09:29:55Z"} {
+		if pod.Fields[k] != want {
+			t.Errorf("%s = %q, want %q", k, pod.Fields[k], want)
+		}
+	}
+	node := events[1]
+	if node.Fields["namespace"] != "default" || node.Fields["count"] != "2" || node.Fields["component"] != "kernel-monitor" || node.Fields["last_seen"] != "2026-10-15T09:30:12.000000Z" {
+		t.Errorf("series event fields = %v", node.Fields)
+	}
+
+	if err := decodeEvents(strings.NewReader("error: the server doesn't have a resource type"), func(kubeEvent) {}); err == nil {
+		t.Error("decodeEvents() accepted garbage")
+	}
+}
+
+func TestTrigger_KubectlArgs(t *testing.T) {
+	all := (&Trigger{}).kubectlArgs()
+	if got := strings.Join(all, " "); got != "get events --watch-only --output json --field-selector type=Warning --all-namespaces" {
+		t.Errorf("args = %s", got)
+	}
+	one := (&Trigger{Namespace: "prod", Context: "staging"}).kubectlArgs()
+	if got := strings.Join(one, " "); !strings.HasSuffix(got, "--namespace prod --context staging") {
+		t.Errorf("args = %s", got)
+	}
+}
+
+func TestRunner_WatchKubernetes(t *testing.T) {
+	dir := t.TempDir()
+	events := filepath.Join(dir, "events.json")
+	if err := os.WriteFile(events, []byte(kubectlOutput), 0600); err != nil {
+		t.Fatal(err)
+	}
+	kubectl := filepath.Join(dir, "kubectl")
+	if err := os.WriteFile(kubectl, []byte("#!/bin/sh\ncat "+events+"\nexec sleep 60\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	trig := &Trigger{Name: "oom", Source: "kubernetes", Kubectl: kubectl, Match: map[string]string{"reason": "OOMKilling"}, Prompt: "Why was {{.object}} out of memory?"}
+	if err := trig.compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	prompts := make(chan string, 2)
+	r := NewRunner(&Config{Triggers: []*Trigger{trig}}, func(ctx context.Context, prompt string) (*agent.RunResult, error) {
+		prompts <- prompt
+		return &agent.RunResult{Answer: "ok"}, nil
+	})
+	r.Output = io.Discard
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx)
+
+	select {
+	case p := <-prompts:
+		if p != "Why was node/n1 out of memory?" {
+			t.Errorf("prompt = %q", p)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no run triggered")
+	}
+	select {
+	case p := <-prompts:
+		t.Errorf("unmatched event triggered %q", p)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
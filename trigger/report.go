@@ -0,0 +1,85 @@
+package trigger
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var slugRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// writeReport writes report as Markdown into dir, named by its time, trigger
+// and event, and returns the file's path
+func writeReport(dir string, report Report) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create reports dir: %w", err)
+	}
+	slug := strings.Trim(slugRe.ReplaceAllString(strings.ToLower(report.Event.Summary), "-"), "-")
+	if len(slug) > 60 {
+		slug = strings.TrimRight(slug[:60], "-")
+	}
+	name := fmt.Sprintf("%s-%s-%s.md", report.Time.Format("20060102-150405"), report.Trigger, slug)
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(reportMarkdown(report)), 0600); err != nil {
+		return "", fmt.Errorf("failed to write report: %w", err)
+	}
+	return path, nil
+}
+
+// reportMarkdown renders a triage report: the event, the agent's answer and
+// each tool call it made, with its output collapsed
+func reportMarkdown(report Report) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Triage: %s\n\n", report.Event.Summary)
+	fmt.Fprintf(&sb, "- Trigger: %s (%s)\n", report.Trigger, report.Source)
+	fmt.Fprintf(&sb, "- Time: %s\n", report.Time.Format("2006-01-02 15:04:05 MST"))
+	if r := report.Result; r != nil {
+		fmt.Fprintf(&sb, "- Run: %d steps in %s\n", len(r.Steps), r.Elapsed.Round(100*time.Millisecond))
+	}
+
+	sb.WriteString("\n## Event\n\n")
+	for _, name := range sortedFields(report.Event.Fields) {
+		if v := report.Event.Fields[name]; v != "" {
+			fmt.Fprintf(&sb, "- **%s**: %s\n", name, strings.ReplaceAll(v, "\n", " "))
+		}
+	}
+
+	sb.WriteString("\n## Report\n\n")
+	switch {
+	case report.Err != nil:
+		fmt.Fprintf(&sb, "The run failed: %v\n", report.Err)
+	case report.Result != nil:
+		sb.WriteString(strings.TrimSpace(report.Result.Answer) + "\n")
+	}
+
+	if report.Result != nil {
+		var calls []string
+		for _, step := range report.Result.Steps {
+			tc := step.ToolCall
+			if tc == nil {
+				continue
+			}
+			params, _ := json.Marshal(tc.Params)
+			output := tc.Result
+			if tc.Error != "" {
+				output = "Error: " + tc.Error
+			}
+			calls = append(calls, fmt.Sprintf("<details><summary>%s %s</summary>\n\n<pre>%s</pre>\n\n</details>\n",
+				html.EscapeString(tc.Name), html.EscapeString(string(params)), html.EscapeString(strings.TrimRight(output, "\n"))))
+		}
+		if len(calls) > 0 {
+			sb.WriteString("\n## Tool calls\n\n")
+			sb.WriteString(strings.Join(calls, "\n"))
+		}
+	}
+	sb.WriteString("\n## Prompt\n\n")
+	for _, line := range strings.Split(report.Prompt, "\n") {
+		sb.WriteString("> " + line + "\n")
+	}
+	return sb.String()
+}
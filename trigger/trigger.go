@@ -0,0 +1,415 @@
+package trigger
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/rathore/langchain-agent/agent"
+	"github.com/rathore/langchain-agent/format"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultCooldown is how long repeats of an event are ignored when a
+// trigger sets no cooldown
+const defaultCooldown = 10 * time.Minute
+
+// defaultTimeout bounds each triggered run when the config sets no timeout
+const defaultTimeout = 10 * time.Minute
+
+// queueSize is how many events wait for a run before more are dropped
+const queueSize = 32
+
+// keylessRuns is how many events without a key a trigger runs per
+// cooldown, since their repeats can't be told apart
+const keylessRuns = 5
+
+// Config is the --triggers file:
+//
+//	reports: /var/lib/agent/triage   # where triage reports are written
+//	timeout: 5m                      # per run (default 10m)
+//	triggers:
+//	  - name: pod-warnings
+//	    source: kubernetes           # warning events, via kubectl get events --watch
+//	    namespace: prod              # default: all namespaces
+//	    context: prod-cluster        # kubeconfig context (default: the current one)
+//	    match:                       # regexps the event's fields must match in full
+//	      reason: BackOff|OOMKilling|FailedScheduling
+//	    cooldown: 30m                # ignore repeats of an event this long (default 10m)
+//	    prompt: |
+//	      Kubernetes reported {{.reason}} for {{.object}} in namespace {{.namespace}}: {{.message}}
+//	      Find the cause and write a short triage report.
+//	  - name: critical-alerts
+//	    source: alertmanager         # Alertmanager webhook receiver
+//	    listen: ":9095"              # it posts to http://<host>:9095/alerts/critical-alerts
+//	    token_sha256: 9f86d081...    # optional: echo -n "$TOKEN" | sha256sum
+//	    match:
+//	      severity: critical
+//	    prompt: |
+//	      Alert {{.alertname}} is firing on {{.instance}}: {{.summary}}
+//	  - name: ci-failure
+//	    source: webhook              # POST /webhook/ci-failure on the --webhook-port server
+//	    token_sha256: 9f86d081...    # required for webhook triggers
+//	    match:                       # nested JSON fields by dotted path
+//	      build.status: failed
+//	    key: "{{.build.id}}"         # ignore repeats with the same key (default: none)
+//	    prompt: |
+//	      Build {{.build.id}} of {{.repository.name}} failed: {{.build.log_url}}
+//	    post_to:                     # where the answer is posted (any trigger)
+//	      url: $SLACK_WEBHOOK_URL    # $VARS are expanded
+//	      format: slack              # json (default), text, or a format: slack, markdown, plain
+type Config struct {
+	Reports  string        `yaml:"reports"` // "": reports are not written
+	Timeout  time.Duration `yaml:"timeout"`
+	Triggers []*Trigger    `yaml:"triggers"`
+}
+
+// Trigger starts a run for each event of its source that matches
+type Trigger struct {
+	Name     string            `yaml:"name"`
+	Source   string            `yaml:"source"` // kubernetes, alertmanager or webhook
+	Match    map[string]string `yaml:"match"`
+	Cooldown time.Duration     `yaml:"cooldown"`
+	Prompt   string            `yaml:"prompt"`
+	PostTo   *Destination      `yaml:"post_to"`
+
+	// kubernetes
+	Namespace string `yaml:"namespace"`
+	Context   string `yaml:"context"`
+	Kubectl   string `yaml:"kubectl"` // default: kubectl on $PATH
+
+	// alertmanager and webhook
+	Listen      string `yaml:"listen"`       // alertmanager only
+	TokenSHA256 string `yaml:"token_sha256"` // bearer token, hex SHA-256 (webhook: required)
+	Key         string `yaml:"key"`          // webhook only: template of the event key
+
+	match map[string]*regexp.Regexp
+	tmpl  *template.Template
+	key   *template.Template
+}
+
+// Destination is where a trigger posts the outcome of its runs
+type Destination struct {
+	URL     string            `yaml:"url"`
+	Format  string            `yaml:"format"` // json, text or a format.Get name ("": json)
+	Headers map[string]string `yaml:"headers"`
+}
+
+// Event is something a source saw
+type Event struct {
+	Key     string            // identifies repeats of the event, for the cooldown ("": none)
+	Summary string            // one line describing it
+	Fields  map[string]string // matched, listed in reports and, without Data, the prompt template's data
+	Data    any               // the prompt template's data, e.g. a decoded JSON payload
+}
+
+var triggerNameRe = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
+// Load reads and validates a triggers file
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read triggers: %w", err)
+	}
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse triggers: %w", err)
+	}
+	if len(config.Triggers) == 0 {
+		return nil, fmt.Errorf("triggers file %s has no triggers", path)
+	}
+	seen := map[string]bool{}
+	for _, t := range config.Triggers {
+		if err := t.compile(); err != nil {
+			return nil, fmt.Errorf("triggers file %s: %w", path, err)
+		}
+		if seen[t.Name] {
+			return nil, fmt.Errorf("triggers file %s: duplicate trigger %q", path, t.Name)
+		}
+		seen[t.Name] = true
+	}
+	return &config, nil
+}
+
+// compile checks t and parses its match patterns and prompt template
+func (t *Trigger) compile() error {
+	if !triggerNameRe.MatchString(t.Name) {
+		return fmt.Errorf("invalid trigger name %q (use letters, digits, - and _)", t.Name)
+	}
+	switch t.Source {
+	case "kubernetes":
+	case "alertmanager":
+		if t.Listen == "" {
+			return fmt.Errorf("trigger %s: alertmanager needs listen, e.g. \":9095\"", t.Name)
+		}
+	case "webhook":
+		// /webhook/<name> is served outside the webhook's --auth-config
+		if t.TokenSHA256 == "" {
+			return fmt.Errorf("trigger %s: webhook triggers need token_sha256", t.Name)
+		}
+		if t.Key != "" {
+			var err error
+			if t.key, err = template.New(t.Name + " key").Parse(t.Key); err != nil {
+				return fmt.Errorf("trigger %s: key: %w", t.Name, err)
+			}
+		}
+	default:
+		return fmt.Errorf("trigger %s: unknown source %q (use kubernetes, alertmanager or webhook)", t.Name, t.Source)
+	}
+	if b, err := hex.DecodeString(t.TokenSHA256); t.TokenSHA256 != "" && (err != nil || len(b) != sha256.Size) {
+		return fmt.Errorf("trigger %s: token_sha256 must be 64 hex digits", t.Name)
+	}
+	if d := t.PostTo; d != nil {
+		if d.URL == "" {
+			return fmt.Errorf("trigger %s: post_to needs a url", t.Name)
+		}
+		if d.Format != "" && d.Format != "text" {
+			if _, err := format.Get(d.Format); err != nil {
+				return fmt.Errorf("trigger %s: post_to: %w", t.Name, err)
+			}
+		}
+	}
+	if strings.TrimSpace(t.Prompt) == "" {
+		return fmt.Errorf("trigger %s: prompt is required", t.Name)
+	}
+	t.match = map[string]*regexp.Regexp{}
+	for field, pattern := range t.Match {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return fmt.Errorf("trigger %s: match %s: %w", t.Name, field, err)
+		}
+		t.match[field] = re
+	}
+	var err error
+	t.tmpl, err = template.New(t.Name).Option("missingkey=zero").Parse(t.Prompt)
+	if err != nil {
+		return fmt.Errorf("trigger %s: %w", t.Name, err)
+	}
+	return nil
+}
+
+// Matches reports whether every match pattern of t matches e's field
+func (t *Trigger) Matches(e Event) bool {
+	for field, re := range t.match {
+		if !re.MatchString(e.Fields[field]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Render fills t's prompt template with e's data, or its fields
+func (t *Trigger) Render(e Event) (string, error) {
+	return execute(t.tmpl, e)
+}
+
+func execute(tmpl *template.Template, e Event) (string, error) {
+	var data any = e.Fields
+	if e.Data != nil {
+		data = e.Data
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("trigger %s: %w", tmpl.Name(), err)
+	}
+	// missing keys of nested maps print as "<no value>" despite missingkey=zero
+	return strings.TrimSpace(strings.ReplaceAll(sb.String(), "<no value>", "")), nil
+}
+
+// Report is the outcome of a triggered run
+type Report struct {
+	Trigger string
+	Source  string
+	Event   Event
+	Time    time.Time
+	Prompt  string
+	Result  *agent.RunResult // nil if the run failed before its first step
+	Err     error
+	Path    string // the report file, "" when none was written
+}
+
+// RunFunc runs a prompt as a new conversation
+type RunFunc func(ctx context.Context, prompt string) (*agent.RunResult, error)
+
+// job is a matched event waiting for its run
+type job struct {
+	trigger *Trigger
+	event   Event
+}
+
+// Runner watches the triggers' sources and runs the agent for matching
+// events, one run at a time
+type Runner struct {
+	config *Config
+	run    RunFunc
+
+	// Output gets a line per triggered run and per source error (default:
+	// os.Stdout)
+	Output io.Writer
+
+	// OnReport is called after each run
+	OnReport func(Report)
+
+	mu     sync.Mutex
+	last   map[string]time.Time   // last run per trigger and event key
+	bursts map[string][]time.Time // runs of keyless events per trigger, within the cooldown
+	queue  chan job
+	now    func() time.Time
+	client *http.Client // for post_to
+}
+
+// NewRunner returns a Runner of config's triggers that runs prompts with run
+func NewRunner(config *Config, run RunFunc) *Runner {
+	return &Runner{
+		config: config,
+		run:    run,
+		Output: os.Stdout,
+		last:   map[string]time.Time{},
+		bursts: map[string][]time.Time{},
+		queue:  make(chan job, queueSize),
+		now:    time.Now,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Run watches the sources and runs triggered prompts until ctx is
+// cancelled. It fails at once if an Alertmanager listener can't be opened;
+// a kubectl watch that fails is restarted.
+func (r *Runner) Run(ctx context.Context) error {
+	var kube []*Trigger
+	var alerts []*Trigger
+	for _, t := range r.config.Triggers {
+		switch t.Source {
+		case "kubernetes":
+			kube = append(kube, t)
+		case "alertmanager":
+			alerts = append(alerts, t)
+		}
+	}
+	servers, err := r.listenAlerts(alerts)
+	if err != nil {
+		return err
+	}
+	for _, srv := range servers {
+		go srv.serve(ctx)
+	}
+	for _, t := range kube {
+		go r.watchKubernetes(ctx, t)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case j := <-r.queue:
+			r.handle(ctx, j)
+		}
+	}
+}
+
+// fire queues a run of t for e unless e doesn't match, repeats an event
+// run within the cooldown or, without a key, would exceed keylessRuns
+func (r *Runner) fire(t *Trigger, e Event) {
+	if !t.Matches(e) {
+		return
+	}
+	cooldown := t.Cooldown
+	if cooldown == 0 {
+		cooldown = defaultCooldown
+	}
+	r.mu.Lock()
+	now := r.now()
+	if e.Key != "" {
+		key := t.Name + "\x00" + e.Key
+		if last, ok := r.last[key]; ok && now.Sub(last) < cooldown {
+			r.mu.Unlock()
+			return
+		}
+		r.last[key] = now
+	} else {
+		runs := r.bursts[t.Name]
+		for len(runs) > 0 && now.Sub(runs[0]) >= cooldown {
+			runs = runs[1:]
+		}
+		if len(runs) >= keylessRuns {
+			r.bursts[t.Name] = runs
+			r.mu.Unlock()
+			r.logf(t, "%s: dropped, %d events without a key already ran within %s", e.Summary, keylessRuns, cooldown)
+			return
+		}
+		r.bursts[t.Name] = append(runs, now)
+	}
+	r.mu.Unlock()
+
+	select {
+	case r.queue <- job{t, e}:
+	default:
+		r.logf(t, "%s: dropped, %d events already waiting", e.Summary, queueSize)
+	}
+}
+
+// handle runs the agent for one job and writes its report
+func (r *Runner) handle(ctx context.Context, j job) {
+	report := Report{Trigger: j.trigger.Name, Source: j.trigger.Source, Event: j.event, Time: r.now()}
+	report.Prompt, report.Err = j.trigger.Render(j.event)
+	if report.Err == nil {
+		r.logf(j.trigger, "%s: running triage", j.event.Summary)
+		timeout := r.config.Timeout
+		if timeout == 0 {
+			timeout = defaultTimeout
+		}
+		runCtx, cancel := context.WithTimeout(ctx, timeout)
+		report.Result, report.Err = r.run(runCtx, report.Prompt)
+		cancel()
+	}
+	if ctx.Err() != nil {
+		return // shutting down: the run was cut short
+	}
+	if r.config.Reports != "" {
+		path, err := writeReport(r.config.Reports, report)
+		if err != nil {
+			r.logf(j.trigger, "%v", err)
+		}
+		report.Path = path
+	}
+	switch {
+	case report.Err != nil:
+		r.logf(j.trigger, "%s: run failed: %v", j.event.Summary, report.Err)
+	case report.Path != "":
+		r.logf(j.trigger, "%s: report saved to %s", j.event.Summary, report.Path)
+	default:
+		r.logf(j.trigger, "%s: done", j.event.Summary)
+	}
+	if d := j.trigger.PostTo; d != nil {
+		if err := r.post(ctx, d, report); err != nil {
+			r.logf(j.trigger, "%s: %v", j.event.Summary, err)
+		}
+	}
+	if r.OnReport != nil {
+		r.OnReport(report)
+	}
+}
+
+func (r *Runner) logf(t *Trigger, format string, args ...any) {
+	fmt.Fprintf(r.Output, "[Trigger %s] %s\n", t.Name, fmt.Sprintf(format, args...))
+}
+
+// sortedFields returns fields' names in order
+func sortedFields(fields map[string]string) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,179 @@
+package trigger
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rathore/langchain-agent/agent"
+)
+
+const testConfig = `
+reports: REPORTS
+timeout: 2m
+triggers:
+  - name: pod-warnings
+    source: kubernetes
+    namespace: prod
+    match:
+      reason: BackOff|OOMKilling
+    cooldown: 30m
+    prompt: |
+      Kubernetes reported {{.reason}} for {{.object}} in namespace {{.namespace}}: {{.message}}{{.missing}}
+  - name: critical-alerts
+    source: alertmanager
+    listen: "127.0.0.1:0"
+    match:
+      severity: critical
+    prompt: Alert {{.alertname}} is firing on {{.instance}}.
+`
+
+// loadTest writes config to a file and loads it
+func loadTest(t *testing.T, config string) (*Config, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "triggers.yaml")
+	if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path)
+}
+
+func TestLoad(t *testing.T) {
+	config, err := loadTest(t, testConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Triggers) != 2 || config.Timeout != 2*time.Minute || config.Triggers[0].Cooldown != 30*time.Minute {
+		t.Errorf("config = %+v", config)
+	}
+
+	for _, tc := range []struct{ name, config, want string }{
+		{"no triggers", "reports: /tmp\n", "has no triggers"},
+		{"bad source", "triggers:\n  - {name: x, source: syslog, prompt: p}\n", `unknown source "syslog"`},
+		{"no listen", "triggers:\n  - {name: x, source: alertmanager, prompt: p}\n", "needs listen"},
+		{"no prompt", "triggers:\n  - {name: x, source: kubernetes}\n", "prompt is required"},
+		{"bad name", "triggers:\n  - {name: 'a b', source: kubernetes, prompt: p}\n", "invalid trigger name"},
+		{"duplicate", "triggers:\n  - {name: x, source: kubernetes, prompt: p}\n  - {name: x, source: kubernetes, prompt: q}\n", "duplicate trigger"},
+		{"bad pattern", "triggers:\n  - {name: x, source: kubernetes, prompt: p, match: {reason: '('}}\n", "match reason"},
+		{"webhook without token", "triggers:\n  - {name: x, source: webhook, prompt: p}\n", "need token_sha256"},
+		{"bad token", "triggers:\n  - {name: x, source: alertmanager, listen: ':1', token_sha256: abc, prompt: p}\n", "64 hex digits"},
+	} {
+		if _, err := loadTest(t, tc.config); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: Load() error = %v, want %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestTrigger_MatchAndRender(t *testing.T) {
+	config, err := loadTest(t, testConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pods := config.Triggers[0]
+	e := Event{Fields: map[string]string{"reason": "BackOff", "object": "pod/api-1", "namespace": "prod", "message": "Back-off restarting failed container"}}
+	if !pods.Matches(e) {
+		t.Error("BackOff doesn't match")
+	}
+	if pods.Matches(Event{Fields: map[string]string{"reason": "BackOffLimitExceeded"}}) {
+		t.Error("match patterns aren't anchored")
+	}
+	prompt, err := pods.Render(e)
+	if want := "Kubernetes reported BackOff for pod/api-1 in namespace prod: Back-off restarting failed container"; err != nil || prompt != want {
+		t.Errorf("Render() = %q, %v, want %q", prompt, err, want)
+	}
+}
+
+func TestRunner_FireAndReport(t *testing.T) {
+	reports := t.TempDir()
+	config, err := loadTest(t, strings.Replace(testConfig, "REPORTS", reports, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var prompts []string
+	run := func(ctx context.Context, prompt string) (*agent.RunResult, error) {
+		prompts = append(prompts, prompt)
+		if strings.Contains(prompt, "OOMKilling") {
+			return nil, errors.New("model unreachable")
+		}
+		return &agent.RunResult{Answer: "The image tag doesn't exist.", Steps: []agent.Step{
+			{ToolCall: &agent.ToolCall{Name: "shell", Params: map[string]any{"command": "kubectl describe pod api-1"}, Result: "ErrImagePull <none>"}},
+			{Output: "The image tag doesn't exist."},
+		}}, nil
+	}
+	r := NewRunner(config, run)
+	r.Output = io.Discard
+	var got []Report
+	r.OnReport = func(rep Report) { got = append(got, rep) }
+	now := time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	pods := config.Triggers[0]
+	backoff := Event{Key: "prod/Pod/api-1/BackOff", Summary: "BackOff pod/api-1 in prod", Fields: map[string]string{"reason": "BackOff", "object": "pod/api-1", "namespace": "prod"}}
+	r.fire(pods, backoff)
+	r.fire(pods, backoff) // within the cooldown
+	r.fire(pods, Event{Key: "other", Fields: map[string]string{"reason": "Unhealthy"}})
+	r.fire(pods, Event{Key: "oom", Summary: "OOMKilling node/n1", Fields: map[string]string{"reason": "OOMKilling"}})
+	now = now.Add(31 * time.Minute)
+	r.fire(pods, backoff) // cooldown over
+
+	for len(r.queue) > 0 {
+		r.handle(context.Background(), <-r.queue)
+	}
+	if len(prompts) != 3 {
+		t.Fatalf("ran %d prompts, want 3: %q", len(prompts), prompts)
+	}
+	if len(got) != 3 || got[0].Err != nil || got[1].Err == nil {
+		t.Fatalf("reports = %+v", got)
+	}
+
+	data, err := os.ReadFile(got[0].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(got[0].Path) != "20261015-100100-pod-warnings-backoff-pod-api-1-in-prod.md" {
+		t.Errorf("report file = %s", got[0].Path)
+	}
+	report := string(data)
+	for _, want := range []string{"# Triage: BackOff pod/api-1 in prod", "- Trigger: pod-warnings (kubernetes)", "- **reason**: BackOff", "The image tag doesn't exist.", "<summary>shell {&#34;command&#34;:&#34;kubectl describe pod api-1&#34;}</summary>", "ErrImagePull &lt;none&gt;", "> Kubernetes reported BackOff"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+	if data, _ := os.ReadFile(got[1].Path); !strings.Contains(string(data), "The run failed: model unreachable") {
+		t.Errorf("failed run's report:\n%s", data)
+	}
+}
+
+func TestRunner_FireKeyless(t *testing.T) {
+	hook := &Trigger{Name: "grafana", Source: "webhook", Prompt: "{{.title}}", TokenSHA256: testTokenSHA256, Cooldown: time.Hour}
+	if err := hook.compile(); err != nil {
+		t.Fatal(err)
+	}
+	r := NewRunner(&Config{Triggers: []*Trigger{hook}}, nil)
+	var log strings.Builder
+	r.Output = &log
+	now := time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	e := Event{Summary: "webhook grafana"}
+	for range keylessRuns + 3 {
+		r.fire(hook, e)
+		now = now.Add(time.Minute)
+	}
+	if len(r.queue) != keylessRuns {
+		t.Errorf("queued %d keyless runs, want %d", len(r.queue), keylessRuns)
+	}
+	if !strings.Contains(log.String(), "dropped, 5 events without a key already ran within 1h0m0s") {
+		t.Errorf("log = %q", log.String())
+	}
+	now = now.Add(time.Hour - 7*time.Minute) // the first run leaves the window
+	r.fire(hook, e)
+	if len(r.queue) != keylessRuns+1 {
+		t.Errorf("queued %d keyless runs after the cooldown, want %d", len(r.queue), keylessRuns+1)
+	}
+}
@@ -0,0 +1,179 @@
+package trigger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rathore/langchain-agent/format"
+)
+
+// maxWebhookBody limits the size of a webhook payload
+const maxWebhookBody = 1 << 20
+
+// maxFields limits how many fields of a payload are kept for matching and
+// the report
+const maxFields = 200
+
+// WebhookHandler receives the payloads of the webhook triggers on
+// /webhook/<trigger name>, for the --webhook-port server (see
+// webhook.WithRoute). A payload is a JSON object; its run is queued, so the
+// sender gets 202 Accepted at once.
+func (r *Runner) WebhookHandler() http.Handler {
+	triggers := map[string]*Trigger{}
+	for _, t := range r.config.Triggers {
+		if t.Source == "webhook" {
+			triggers[t.Name] = t
+		}
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		t, ok := triggers[strings.TrimPrefix(req.URL.Path, "/webhook/")]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		if req.Method != http.MethodPost {
+			http.Error(w, "POST required", http.StatusMethodNotAllowed)
+			return
+		}
+		if t.TokenSHA256 != "" && !validToken(req, t.TokenSHA256) {
+			http.Error(w, "invalid or missing bearer token", http.StatusUnauthorized)
+			return
+		}
+		var payload map[string]any
+		if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxWebhookBody)).Decode(&payload); err != nil {
+			http.Error(w, "invalid JSON object: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		e, err := t.webhookEvent(payload)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.fire(t, e)
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// webhookEvent turns a payload into the trigger event: its data is the
+// payload, its fields the payload's values by dotted path
+// (e.g. alerts.0.labels.alertname)
+func (t *Trigger) webhookEvent(payload map[string]any) (Event, error) {
+	fields := map[string]string{}
+	flatten("", payload, fields)
+	e := Event{Summary: "webhook " + t.Name, Fields: fields, Data: payload}
+	if t.key != nil {
+		key, err := execute(t.key, e)
+		if err != nil {
+			return e, err
+		}
+		e.Key = key
+		if key != "" {
+			e.Summary += " " + key
+		}
+	}
+	return e, nil
+}
+
+// flatten adds v's scalar values to fields by dotted path
+func flatten(path string, v any, fields map[string]string) {
+	if len(fields) >= maxFields {
+		return
+	}
+	join := func(k string) string {
+		if path == "" {
+			return k
+		}
+		return path + "." + k
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			flatten(join(k), v[k], fields)
+		}
+	case []any:
+		for i, item := range v {
+			flatten(join(strconv.Itoa(i)), item, fields)
+		}
+	case nil:
+	default:
+		fields[path] = fmt.Sprint(v)
+	}
+}
+
+// post sends report to d
+func (r *Runner) post(ctx context.Context, d *Destination, report Report) error {
+	var answer, errText string
+	if report.Result != nil {
+		answer = report.Result.Answer
+	}
+	if report.Err != nil {
+		errText = report.Err.Error()
+	}
+
+	var body bytes.Buffer
+	contentType := "application/json"
+	switch d.Format {
+	case "", "json":
+		json.NewEncoder(&body).Encode(struct {
+			Trigger string            `json:"trigger"`
+			Event   string            `json:"event"`
+			Fields  map[string]string `json:"fields"`
+			Answer  string            `json:"answer,omitempty"`
+			Error   string            `json:"error,omitempty"`
+			Report  string            `json:"report,omitempty"`
+		}{report.Trigger, report.Event.Summary, report.Event.Fields, answer, errText, report.Path})
+	case "text":
+		contentType = "text/plain; charset=utf-8"
+		body.WriteString(answer)
+		if errText != "" {
+			body.Reset()
+			body.WriteString("The run failed: " + errText)
+		}
+	default:
+		f, err := format.Get(d.Format)
+		if err != nil {
+			return fmt.Errorf("failed to post the result: %w", err)
+		}
+		contentType = f.ContentType()
+		result := format.Result{RunResult: report.Result, Error: errText, Title: "Triage: " + report.Event.Summary}
+		if err := f.Format(&body, result); err != nil {
+			return fmt.Errorf("failed to post the result: %w", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, os.ExpandEnv(d.URL), &body)
+	if err != nil {
+		return fmt.Errorf("failed to post the result: invalid post_to url")
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range d.Headers {
+		req.Header.Set(k, os.ExpandEnv(v))
+	}
+	resp, err := r.client.Do(req)
+	if uerr, ok := err.(*url.Error); ok {
+		err = uerr.Err // without the URL, which may hold a secret
+	}
+	if err != nil {
+		return fmt.Errorf("failed to post the result: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("failed to post the result: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package trigger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rathore/langchain-agent/agent"
+)
+
+// testTokenSHA256 is the SHA-256 of the token "test"
+const testTokenSHA256 = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+const ciPayload = `{"build": {"id": 812, "status": "failed", "log_url": "https://ci.example.com/812/log"}, "repository": {"name": "api"}, "stages": [{"name": "test", "ok": false}]}`
+
+func TestWebhookHandler(t *testing.T) {
+	ci := &Trigger{Name: "ci", Source: "webhook", Match: map[string]string{"build.status": "failed"}, Key: "{{.build.id}}", TokenSHA256: testTokenSHA256,
+		Prompt: "Build {{.build.id}} of {{.repository.name}} failed{{.build.branch}}: {{.build.log_url}}"}
+	if err := ci.compile(); err != nil {
+		t.Fatal(err)
+	}
+	r := NewRunner(&Config{Triggers: []*Trigger{ci}}, nil)
+	srv := httptest.NewServer(r.WebhookHandler())
+	defer srv.Close()
+
+	post := func(path, body string) int {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := post("/webhook/other", ciPayload); code != http.StatusNotFound {
+		t.Errorf("unknown trigger: %d, want 404", code)
+	}
+	if code := post("/webhook/ci", "[1, 2]"); code != http.StatusBadRequest {
+		t.Errorf("JSON array: %d, want 400", code)
+	}
+	if code := post("/webhook/ci", ciPayload); code != http.StatusAccepted {
+		t.Fatalf("payload: %d, want 202", code)
+	}
+	post("/webhook/ci", ciPayload)                                             // same key: within the cooldown
+	post("/webhook/ci", strings.Replace(ciPayload, `"failed"`, `"passed"`, 1)) // doesn't match
+	if len(r.queue) != 1 {
+		t.Fatalf("queued %d runs, want 1", len(r.queue))
+	}
+
+	j := <-r.queue
+	if j.event.Key != "812" || j.event.Summary != "webhook ci 812" {
+		t.Errorf("event = %+v", j.event)
+	}
+	if j.event.Fields["stages.0.name"] != "test" || j.event.Fields["stages.0.ok"] != "false" {
+		t.Errorf("fields = %v", j.event.Fields)
+	}
+	prompt, err := ci.Render(j.event)
+	if want := "Build 812 of api failed: https://ci.example.com/812/log"; err != nil || prompt != want {
+		t.Errorf("Render() = %q, %v, want %q", prompt, err, want)
+	}
+}
+
+func TestWebhookHandler_Token(t *testing.T) {
+	hook := &Trigger{Name: "grafana", Source: "webhook", Prompt: "{{.title}}", TokenSHA256: testTokenSHA256}
+	if err := hook.compile(); err != nil {
+		t.Fatal(err)
+	}
+	h := NewRunner(&Config{Triggers: []*Trigger{hook}}, nil).WebhookHandler()
+	for token, want := range map[string]int{"": http.StatusUnauthorized, "nope": http.StatusUnauthorized, "test": http.StatusAccepted} {
+		req := httptest.NewRequest(http.MethodPost, "/webhook/grafana", strings.NewReader(`{"title": "CPU high"}`))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("token %q: %d, want %d", token, rec.Code, want)
+		}
+	}
+}
+
+func TestRunner_PostTo(t *testing.T) {
+	var got []string
+	dest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		got = append(got, req.Header.Get("Content-Type")+" "+req.Header.Get("X-Token")+" "+string(body))
+		if strings.Contains(string(body), "boom") {
+			http.Error(w, "no such channel", http.StatusNotFound)
+		}
+	}))
+	defer dest.Close()
+	t.Setenv("TEST_TOKEN", "s3cret")
+
+	r := NewRunner(&Config{}, func(ctx context.Context, prompt string) (*agent.RunResult, error) {
+		if prompt == "boom" {
+			return nil, errors.New("boom")
+		}
+		return &agent.RunResult{Answer: "Disk full on db-1."}, nil
+	})
+	var log strings.Builder
+	r.Output = &log
+	run := func(format, prompt string) {
+		trig := &Trigger{Name: "t", Source: "webhook", Prompt: prompt, TokenSHA256: testTokenSHA256, PostTo: &Destination{URL: dest.URL, Format: format, Headers: map[string]string{"X-Token": "$TEST_TOKEN"}}}
+		if err := trig.compile(); err != nil {
+			t.Fatal(err)
+		}
+		r.handle(context.Background(), job{trig, Event{Summary: "DiskFull on db-1", Fields: map[string]string{"alertname": "DiskFull"}}})
+	}
+	run("slack", "why?")
+	run("text", "why?")
+	run("", "why?")
+	run("slack", "boom")
+
+	if len(got) != 4 {
+		t.Fatalf("posted %d times, want 4", len(got))
+	}
+	if want := `application/json s3cret {"text":"Disk full on db-1.","blocks":[{"type":"header","text":{"type":"plain_text","text":"Triage: DiskFull on db-1"}},{"type":"section","text":{"type":"mrkdwn","text":"Disk full on db-1."}}]}` + "\n"; got[0] != want {
+		t.Errorf("slack post = %s, want %s", got[0], want)
+	}
+	if want := "text/plain; charset=utf-8 s3cret Disk full on db-1."; got[1] != want {
+		t.Errorf("text post = %s", got[1])
+	}
+	var body map[string]any
+	if err := json.Unmarshal([]byte(strings.SplitN(got[2], " ", 3)[2]), &body); err != nil || body["answer"] != "Disk full on db-1." || body["trigger"] != "t" {
+		t.Errorf("json post = %s (%v)", got[2], err)
+	}
+	if !strings.Contains(got[3], "*Error:* boom") || !strings.Contains(log.String(), "failed to post the result: 404 Not Found: no such channel") {
+		t.Errorf("failed run post = %s, log:\n%s", got[3], log.String())
+	}
+}
@@ -0,0 +1,186 @@
+package webhook
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// User is an authenticated caller and what they may do
+type User struct {
+	Name      string
+	Tools     []string // tools the user may use; "*" allows all
+	RateLimit int      // runs per minute; 0 = unlimited
+	Role      string   // --policy role for the user's tool calls ("" = the default role)
+	Language  string   // language to answer the user in ("" = the server's --language)
+}
+
+// AllowsTool reports whether the user may use the named tool
+func (u *User) AllowsTool(name string) bool {
+	return slices.Contains(u.Tools, "*") || slices.Contains(u.Tools, name)
+}
+
+// Authenticator identifies the caller of a request
+type Authenticator interface {
+	Authenticate(r *http.Request) (*User, error)
+}
+
+// errNoCredentials is returned for requests without an API key or token
+var errNoCredentials = errors.New("authentication required (Authorization: Bearer <API key or OIDC token>)")
+
+// AuthConfig is the --auth-config file for multi-user servers:
+//
+//	oidc:                      # optional: accept ID tokens from this issuer
+//	  issuer: https://accounts.google.com
+//	  audience: my-client-id
+//	  user_claim: email        # default: sub
+//	default:                   # optional: OIDC users not listed below
+//	  tools: [wiki]
+//	  rate_limit: 10
+//	users:
+//	  - name: alice@example.com
+//	    api_key_sha256: 9f86d081...   # echo -n "$KEY" | sha256sum
+//	    tools: ["*"]
+//	    rate_limit: 30
+//	    role: operator            # --policy role (default: the policy's default)
+//	    language: German          # answer language (default: --language)
+type AuthConfig struct {
+	OIDC    *OIDCConfig  `yaml:"oidc"`
+	Default *UserConfig  `yaml:"default"`
+	Users   []UserConfig `yaml:"users"`
+}
+
+// OIDCConfig identifies the OpenID Connect provider whose tokens are accepted
+type OIDCConfig struct {
+	Issuer    string `yaml:"issuer"`
+	Audience  string `yaml:"audience"`   // client ID the tokens must be issued for
+	UserClaim string `yaml:"user_claim"` // claim naming the user (default: sub)
+}
+
+// UserConfig is one user's credentials and permissions. Users without
+// tools may only chat.
+type UserConfig struct {
+	Name         string   `yaml:"name"`
+	APIKeySHA256 string   `yaml:"api_key_sha256"` // hex SHA-256 of the user's API key
+	Tools        []string `yaml:"tools"`
+	RateLimit    int      `yaml:"rate_limit"`
+	Role         string   `yaml:"role"`
+	Language     string   `yaml:"language"`
+}
+
+// LoadAuthConfig reads and validates an auth config file
+func LoadAuthConfig(path string) (*AuthConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read auth config: %w", err)
+	}
+	var config AuthConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse auth config: %w", err)
+	}
+	if config.OIDC == nil && len(config.Users) == 0 {
+		return nil, fmt.Errorf("auth config %s has no users and no oidc provider", path)
+	}
+	if config.OIDC != nil && (config.OIDC.Issuer == "" || config.OIDC.Audience == "") {
+		return nil, fmt.Errorf("auth config %s: oidc needs issuer and audience", path)
+	}
+	seen := map[string]bool{}
+	for _, u := range config.Users {
+		if u.Name == "" {
+			return nil, fmt.Errorf("auth config %s: user without a name", path)
+		}
+		if seen[u.Name] {
+			return nil, fmt.Errorf("auth config %s: duplicate user %q", path, u.Name)
+		}
+		seen[u.Name] = true
+		if u.APIKeySHA256 != "" {
+			if b, err := hex.DecodeString(u.APIKeySHA256); err != nil || len(b) != sha256.Size {
+				return nil, fmt.Errorf("auth config %s: user %q: api_key_sha256 must be 64 hex digits", path, u.Name)
+			}
+		}
+	}
+	return &config, nil
+}
+
+// authenticator accepts API keys (Authorization: Bearer or X-API-Key) and,
+// when configured, OIDC ID tokens
+type authenticator struct {
+	keys  map[string]*User // by hex SHA-256 of the key
+	users map[string]*User // by name
+	def   *User            // OIDC users not in users; nil rejects them
+	oidc  *oidcVerifier
+}
+
+// NewAuthenticator builds the authenticator for config. With OIDC it fetches
+// the provider's signing keys, so it fails early if the issuer is unreachable.
+func NewAuthenticator(ctx context.Context, config *AuthConfig) (Authenticator, error) {
+	a := &authenticator{keys: map[string]*User{}, users: map[string]*User{}}
+	for _, uc := range config.Users {
+		u := &User{Name: uc.Name, Tools: uc.Tools, RateLimit: uc.RateLimit, Role: uc.Role, Language: uc.Language}
+		a.users[u.Name] = u
+		if uc.APIKeySHA256 != "" {
+			a.keys[strings.ToLower(uc.APIKeySHA256)] = u
+		}
+	}
+	if d := config.Default; d != nil {
+		a.def = &User{Tools: d.Tools, RateLimit: d.RateLimit, Role: d.Role, Language: d.Language}
+	}
+	if config.OIDC != nil {
+		v, err := newOIDCVerifier(ctx, *config.OIDC, http.DefaultClient)
+		if err != nil {
+			return nil, err
+		}
+		a.oidc = v
+	}
+	return a, nil
+}
+
+func (a *authenticator) Authenticate(r *http.Request) (*User, error) {
+	token := credentials(r)
+	if token == "" {
+		return nil, errNoCredentials
+	}
+	if a.oidc != nil && strings.Count(token, ".") == 2 {
+		name, err := a.oidc.verify(token)
+		if err != nil {
+			return nil, fmt.Errorf("invalid token: %w", err)
+		}
+		if u := a.users[name]; u != nil {
+			return u, nil
+		}
+		if a.def == nil {
+			return nil, fmt.Errorf("user %q is not allowed", name)
+		}
+		u := *a.def
+		u.Name = name
+		return &u, nil
+	}
+	sum := sha256.Sum256([]byte(token))
+	if u := a.keys[hex.EncodeToString(sum[:])]; u != nil {
+		return u, nil
+	}
+	return nil, errors.New("invalid API key")
+}
+
+// credentials returns the API key or token of a request. Browsers can't set
+// headers on WebSocket connections, so those may use ?access_token= instead.
+func credentials(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return r.URL.Query().Get("access_token")
+	}
+	return ""
+}
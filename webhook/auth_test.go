@@ -0,0 +1,321 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rathore/langchain-agent/agent"
+	"github.com/rathore/langchain-agent/llm"
+	"github.com/rathore/langchain-agent/tools"
+)
+
+func keyHash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestLoadAuthConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"valid", "users:\n  - name: alice\n    api_key_sha256: " + keyHash("k") + "\n    tools: ['*']\n", ""},
+		{"empty", "users: []\n", "no users"},
+		{"oidc without audience", "oidc:\n  issuer: https://idp\n", "issuer and audience"},
+		{"unnamed user", "users:\n  - tools: [shell]\n", "without a name"},
+		{"duplicate user", "users:\n  - name: a\n  - name: a\n", "duplicate"},
+		{"bad hash", "users:\n  - name: a\n    api_key_sha256: secret\n", "64 hex digits"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "auth.yaml")
+			os.WriteFile(path, []byte(tt.yaml), 0600)
+			_, err := LoadAuthConfig(path)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("LoadAuthConfig() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("LoadAuthConfig() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthenticate_APIKey(t *testing.T) {
+	auth, err := NewAuthenticator(context.Background(), &AuthConfig{Users: []UserConfig{
+		{Name: "alice", APIKeySHA256: keyHash("alice-key"), Tools: []string{"*"}},
+		{Name: "bob", APIKeySHA256: strings.ToUpper(keyHash("bob-key"))},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		header map[string]string
+		query  string
+		want   string
+	}{
+		{"bearer", map[string]string{"Authorization": "Bearer alice-key"}, "", "alice"},
+		{"x-api-key", map[string]string{"X-API-Key": "bob-key"}, "", "bob"},
+		{"websocket query", map[string]string{"Upgrade": "websocket"}, "?access_token=bob-key", "bob"},
+		{"query without upgrade", nil, "?access_token=bob-key", ""},
+		{"wrong key", map[string]string{"Authorization": "Bearer nope"}, "", ""},
+		{"no credentials", nil, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/webhook"+tt.query, nil)
+			for k, v := range tt.header {
+				r.Header.Set(k, v)
+			}
+			u, err := auth.Authenticate(r)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("Authenticate() = %+v, want an error", u)
+				}
+				return
+			}
+			if err != nil || u.Name != tt.want {
+				t.Errorf("Authenticate() = %+v, %v, want %s", u, err, tt.want)
+			}
+		})
+	}
+}
+
+// testIssuer is an OIDC provider serving discovery and a key set
+type testIssuer struct {
+	*httptest.Server
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+	hidden bool // leave the EC key out of the key set
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	iss := &testIssuer{rsaKey: rsaKey, ecKey: ecKey}
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": iss.URL, "jwks_uri": iss.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		keys := []map[string]string{{
+			"kty": "RSA", "kid": "rsa1",
+			"n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+		}}
+		if !iss.hidden {
+			keys = append(keys, map[string]string{
+				"kty": "EC", "kid": "ec1", "crv": "P-256",
+				"x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32))),
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	})
+	iss.Server = httptest.NewServer(mux)
+	t.Cleanup(iss.Close)
+	return iss
+}
+
+// token signs claims with the issuer's key for alg (RS256 or ES256)
+func (iss *testIssuer) token(t *testing.T, alg string, claims map[string]any) string {
+	kid := map[string]string{"RS256": "rsa1", "ES256": "ec1"}[alg]
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	var sig []byte
+	if alg == "ES256" {
+		r, s, err := ecdsa.Sign(rand.Reader, iss.ecKey, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	} else {
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, iss.rsaKey, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestAuthenticate_OIDC(t *testing.T) {
+	iss := newTestIssuer(t)
+	iss.hidden = true
+	auth, err := NewAuthenticator(context.Background(), &AuthConfig{
+		OIDC:    &OIDCConfig{Issuer: iss.URL, Audience: "agent", UserClaim: "email"},
+		Default: &UserConfig{Tools: []string{"wiki"}, RateLimit: 5},
+		Users:   []UserConfig{{Name: "admin@example.com", Tools: []string{"*"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The EC key is rotated in later and fetched on first use
+	iss.hidden = false
+	auth.(*authenticator).oidc.fetched = time.Now().Add(-2 * time.Minute)
+
+	now := time.Now().Unix()
+	claims := func(changes map[string]any) map[string]any {
+		c := map[string]any{"iss": iss.URL, "aud": "agent", "email": "dev@example.com", "email_verified": true, "exp": now + 300, "iat": now}
+		for k, v := range changes {
+			c[k] = v
+		}
+		return c
+	}
+	valid := iss.token(t, "RS256", claims(nil))
+	tampered := valid[:strings.LastIndex(valid, ".")] + "." + base64.RawURLEncoding.EncodeToString(bytes.Repeat([]byte{1}, 256))
+	tests := []struct {
+		name    string
+		token   string
+		want    string
+		wantErr string
+	}{
+		{"default user", valid, "dev@example.com", ""},
+		{"listed user, ES256, audience list", iss.token(t, "ES256", claims(map[string]any{"email": "admin@example.com", "aud": []string{"other", "agent"}})), "admin@example.com", ""},
+		{"expired", iss.token(t, "RS256", claims(map[string]any{"exp": now - 120})), "", "expired"},
+		{"within clock skew", iss.token(t, "RS256", claims(map[string]any{"exp": now - 10})), "dev@example.com", ""},
+		{"not yet valid", iss.token(t, "RS256", claims(map[string]any{"nbf": now + 600})), "", "not valid yet"},
+		{"other audience", iss.token(t, "RS256", claims(map[string]any{"aud": "other"})), "", "not issued for this server"},
+		{"other issuer", iss.token(t, "RS256", claims(map[string]any{"iss": "https://evil"})), "", "issued by"},
+		{"no user claim", iss.token(t, "RS256", claims(map[string]any{"email": ""})), "", "no email claim"},
+		{"unverified email", iss.token(t, "RS256", claims(map[string]any{"email": "admin@example.com", "email_verified": false})), "", "email isn't verified"},
+		{"email without email_verified", iss.token(t, "RS256", claims(map[string]any{"email_verified": nil})), "", "email isn't verified"},
+		{"bad signature", tampered, "", "bad signature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/webhook", nil)
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+			u, err := auth.Authenticate(r)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Authenticate() = %+v, %v, want error %q", u, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || u.Name != tt.want {
+				t.Fatalf("Authenticate() = %+v, %v, want %s", u, err, tt.want)
+			}
+		})
+	}
+
+	r := httptest.NewRequest("POST", "/webhook", nil)
+	r.Header.Set("Authorization", "Bearer "+valid)
+	u, _ := auth.Authenticate(r)
+	if u.AllowsTool("shell") || !u.AllowsTool("wiki") || u.RateLimit != 5 {
+		t.Errorf("default user = %+v, want the default's tools and rate limit", u)
+	}
+}
+
+func TestMultiUser(t *testing.T) {
+	auth, _ := NewAuthenticator(context.Background(), &AuthConfig{Users: []UserConfig{
+		{Name: "alice", APIKeySHA256: keyHash("alice-key"), Tools: []string{"shell"}, RateLimit: 2},
+		{Name: "bob", APIKeySHA256: keyHash("bob-key")},
+	}})
+	agents := map[string]*agent.Agent{}
+	m := NewMultiUser(auth, func(u *User) (*agent.Agent, error) {
+		var allowed []tools.Tool
+		if u.AllowsTool("shell") {
+			allowed = append(allowed, &tools.ShellTool{})
+		}
+		client := &scriptedClient{responses: []*llm.Response{
+			{Content: "one", IsFinish: true}, {Content: "two", IsFinish: true}, {Content: "three", IsFinish: true},
+		}}
+		ag, err := agent.New(agent.Config{Client: client, Tools: allowed})
+		agents[u.Name] = ag
+		return ag, err
+	})
+	now := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	srv := httptest.NewServer(newMux(m))
+	defer srv.Close()
+
+	post := func(key, prompt string) (int, response) {
+		req, _ := http.NewRequest("POST", srv.URL+"/webhook", strings.NewReader(`{"prompt": "`+prompt+`"}`))
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var body response
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body
+	}
+
+	if code, body := post("", "hi"); code != http.StatusUnauthorized || body.Error == "" {
+		t.Errorf("no key: %d %+v, want 401", code, body)
+	}
+	if code, body := post("alice-key", "a1"); code != http.StatusOK || body.Answer != "one" {
+		t.Errorf("alice: %d %+v", code, body)
+	}
+	if code, body := post("bob-key", "b1"); code != http.StatusOK || body.Answer != "one" {
+		t.Errorf("bob: %d %+v, want a separate session", code, body)
+	}
+	if code, body := post("alice-key", "a2"); code != http.StatusOK || body.Answer != "two" {
+		t.Errorf("alice again: %d %+v", code, body)
+	}
+	if code, body := post("alice-key", "a3"); code != http.StatusTooManyRequests || !strings.Contains(body.Error, "2 runs per minute") {
+		t.Errorf("alice over limit: %d %+v, want 429", code, body)
+	}
+
+	if h := agents["alice"].History(); len(h) != 4 || h[0].Content != "a1" {
+		t.Errorf("alice history = %+v", h)
+	}
+	if h := agents["bob"].History(); len(h) != 2 || h[0].Content != "b1" {
+		t.Errorf("bob history = %+v", h)
+	}
+	if len(agents["alice"].Tools()) != 1 || len(agents["bob"].Tools()) != 0 {
+		t.Errorf("tools: alice %v, bob %v", agents["alice"].Tools(), agents["bob"].Tools())
+	}
+
+	// Sessions idle for sessionIdle are dropped; a request keeps one alive
+	now = now.Add(sessionIdle / 2)
+	post("bob-key", "b2")
+	now = now.Add(sessionIdle / 2)
+	if code, body := post("alice-key", "a4"); code != http.StatusOK || body.Answer != "one" {
+		t.Errorf("alice after idling: %d %+v, want a new conversation", code, body)
+	}
+	if h := agents["alice"].History(); len(h) != 2 || h[0].Content != "a4" {
+		t.Errorf("alice history after idling = %+v", h)
+	}
+	m.mu.Lock()
+	if len(m.sessions) != 2 || m.sessions["bob"] == nil {
+		t.Errorf("sessions = %v, want bob's kept", m.sessions)
+	}
+	m.mu.Unlock()
+
+	// WebSocket upgrades are authenticated before the handshake
+	resp, err := http.Get(srv.URL + "/ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized || resp.Header.Get("WWW-Authenticate") != "Bearer" {
+		t.Errorf("/ws without a key = %d, want 401 with WWW-Authenticate", resp.StatusCode)
+	}
+	if resp, err := http.Get(srv.URL + "/health"); err != nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("/health = %v, %v, want open", resp, err)
+	}
+}
@@ -0,0 +1,4 @@
+// Package webhook serves an agent over HTTP and WebSocket: Start and Serve
+// for a single local user, StartMultiUser with an Authenticator for shared
+// deployments.
+package webhook
@@ -0,0 +1,250 @@
+package webhook
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clockSkew is the leeway allowed on token expiry and not-before times
+const clockSkew = time.Minute
+
+// oidcVerifier checks OIDC ID tokens (JWTs signed with RS256 or ES256)
+// against the provider's published keys
+type oidcVerifier struct {
+	issuer    string
+	audience  string
+	userClaim string
+	jwksURL   string
+	client    *http.Client
+	now       func() time.Time
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey // by key ID
+	fetched time.Time
+}
+
+// newOIDCVerifier discovers the provider's key set and fetches the keys
+func newOIDCVerifier(ctx context.Context, config OIDCConfig, client *http.Client) (*oidcVerifier, error) {
+	v := &oidcVerifier{
+		issuer:    config.Issuer,
+		audience:  config.Audience,
+		userClaim: config.UserClaim,
+		client:    client,
+		now:       time.Now,
+	}
+	if v.userClaim == "" {
+		v.userClaim = "sub"
+	}
+
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	url := strings.TrimSuffix(config.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := v.getJSON(ctx, url, &discovery); err != nil {
+		return nil, fmt.Errorf("failed to discover OIDC provider: %w", err)
+	}
+	if discovery.Issuer != config.Issuer {
+		return nil, fmt.Errorf("OIDC issuer mismatch: discovery says %q, configured %q", discovery.Issuer, config.Issuer)
+	}
+	if discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC provider %s publishes no jwks_uri", config.Issuer)
+	}
+	v.jwksURL = discovery.JWKSURI
+	if err := v.refreshKeys(ctx); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (v *oidcVerifier) getJSON(ctx context.Context, url string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// refreshKeys fetches the provider's current signing keys
+func (v *oidcVerifier) refreshKeys(ctx context.Context) error {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := v.getJSON(ctx, v.jwksURL, &set); err != nil {
+		return fmt.Errorf("failed to fetch OIDC keys: %w", err)
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, k := range set.Keys {
+		switch {
+		case k.Kty == "RSA":
+			n, err1 := decodeBigInt(k.N)
+			e, err2 := decodeBigInt(k.E)
+			if err1 != nil || err2 != nil || !e.IsInt64() {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		case k.Kty == "EC" && k.Crv == "P-256":
+			x, err1 := decodeBigInt(k.X)
+			y, err2 := decodeBigInt(k.Y)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		}
+	}
+	v.mu.Lock()
+	v.keys, v.fetched = keys, v.now()
+	v.mu.Unlock()
+	return nil
+}
+
+// key returns the signing key with the given ID, refetching the key set
+// (at most once a minute) when the provider has rotated to a new key
+func (v *oidcVerifier) key(kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	key, stale := v.keys[kid], v.now().Sub(v.fetched) > time.Minute
+	v.mu.Unlock()
+	if key != nil {
+		return key, nil
+	}
+	if stale {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := v.refreshKeys(ctx); err != nil {
+			return nil, err
+		}
+		v.mu.Lock()
+		key = v.keys[kid]
+		v.mu.Unlock()
+	}
+	if key == nil {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// verify checks a token's signature, issuer, audience and validity period
+// and returns the user named by the user claim
+func (v *oidcVerifier) verify(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errors.New("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return "", fmt.Errorf("malformed token header: %w", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", errors.New("malformed token signature")
+	}
+	key, err := v.key(header.Kid)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if header.Alg != "RS256" || rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) != nil {
+			return "", errors.New("bad signature")
+		}
+	case *ecdsa.PublicKey:
+		if header.Alg != "ES256" || len(sig) != 64 ||
+			!ecdsa.Verify(k, digest[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])) {
+			return "", errors.New("bad signature")
+		}
+	default:
+		return "", fmt.Errorf("unsupported algorithm %q", header.Alg)
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return "", fmt.Errorf("malformed token claims: %w", err)
+	}
+	if claims["iss"] != v.issuer {
+		return "", fmt.Errorf("token issued by %v, not %s", claims["iss"], v.issuer)
+	}
+	if !hasAudience(claims["aud"], v.audience) {
+		return "", errors.New("token not issued for this server")
+	}
+	now := v.now()
+	exp, ok := claims["exp"].(float64)
+	if !ok || now.After(time.Unix(int64(exp), 0).Add(clockSkew)) {
+		return "", errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(clockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return "", errors.New("token not valid yet")
+	}
+	name, _ := claims[v.userClaim].(string)
+	if name == "" {
+		return "", fmt.Errorf("token has no %s claim", v.userClaim)
+	}
+	// Some providers let users set an email they don't own
+	if v.userClaim == "email" && claims["email_verified"] != true && claims["email_verified"] != "true" {
+		return "", errors.New("token's email isn't verified")
+	}
+	return name, nil
+}
+
+// hasAudience reports whether an aud claim (a string or a list) includes want
+func hasAudience(aud any, want string) bool {
+	switch a := aud.(type) {
+	case string:
+		return a == want
+	case []any:
+		for _, s := range a {
+			if s == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func decodeSegment(seg string, out any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -4,39 +4,141 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
 	"time"
 
 	"github.com/rathore/langchain-agent/agent"
+	"github.com/rathore/langchain-agent/format"
+	"golang.org/x/net/websocket"
 )
 
 type request struct {
 	Prompt string `json:"prompt"`
+	Fresh  bool   `json:"fresh,omitempty"`  // start a new conversation first
+	Format string `json:"format,omitempty"` // answer format (see package format); "": the JSON response
 }
 
 type response struct {
-	Answer string `json:"answer,omitempty"`
-	Error  string `json:"error,omitempty"`
+	Answer   string          `json:"answer,omitempty"`
+	Evidence *agent.Evidence `json:"evidence,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// Option configures a server
+type Option func(*options)
+
+// options are what Options set
+type options struct {
+	routes *http.ServeMux
+	log    io.Writer // request log
+}
+
+// WithRoute serves h on pattern besides the built-in routes, e.g. the
+// trigger receiver on /webhook/ (see trigger.Runner.WebhookHandler). Its
+// requests aren't authenticated by the server: h checks them itself.
+func WithRoute(pattern string, h http.Handler) Option {
+	return func(o *options) { o.routes.Handle(pattern, h) }
+}
+
+// WithLog writes the log of requests to w instead of stdout, e.g. a
+// stream of the agent.Mux the agents' progress goes through
+func WithLog(w io.Writer) Option {
+	return func(o *options) { o.log = w }
 }
 
 // Start runs an HTTP server on the given port that exposes:
 //   - POST /webhook  — body {"prompt": "..."}; runs the agent and returns its answer
+//   - GET  /ws       — WebSocket; send {"prompt": "..."}, receive agent events
 //   - GET  /health   — liveness probe
+//   - GET  /metrics  — tool call stats in the Prometheus text format, when
+//     the agent has Config.ToolStats (not authenticated, like /health)
 //
-// It blocks until ctx is cancelled or the server fails. Run it in its own goroutine.
-func Start(ctx context.Context, port int, ag *agent.Agent) error {
+// Requests may set "fresh": true to clear the conversation history first,
+// and a /webhook request "format" (json, markdown, plain, slack) to get the
+// result in that format instead of {"answer": ...}.
+// opts add routes, such as the trigger receiver (WithRoute). It blocks
+// until ctx is cancelled or the server fails. Run it in its own goroutine.
+func Start(ctx context.Context, port int, ag *agent.Agent, opts ...Option) error {
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return err
+	}
+	return Serve(ctx, ln, ag, opts...)
+}
+
+// Serve is Start on an existing listener, such as a unix socket
+func Serve(ctx context.Context, ln net.Listener, ag *agent.Agent, opts ...Option) error {
+	return serve(ctx, ln, sharedAgent{ag}, opts)
+}
+
+// StartMultiUser is Start for a shared deployment: every /webhook and /ws
+// request must authenticate, and each user gets their own agent
+func StartMultiUser(ctx context.Context, port int, m *MultiUser, opts ...Option) error {
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return err
+	}
+	return serve(ctx, ln, m, opts)
+}
+
+func serve(ctx context.Context, ln net.Listener, src sessionSource, opts []Option) error {
+	srv := &http.Server{
+		Handler:           newMux(src, opts...),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ln) }()
+
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	case err := <-errCh:
+		if err == http.ErrServerClosed {
+			return nil
+		}
+		return err
+	}
+}
+
+// newMux builds the server's routes
+func newMux(src sessionSource, opts ...Option) *http.ServeMux {
 	mux := http.NewServeMux()
+	o := &options{routes: mux, log: os.Stdout}
+	for _, opt := range opts {
+		opt(o)
+	}
 
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("OK"))
 	})
 
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		stats := src.toolStats()
+		if stats == nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		stats.WritePrometheus(w)
+	})
+
 	mux.HandleFunc("/webhook", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			writeJSON(w, http.StatusMethodNotAllowed, response{Error: "POST required"})
 			return
 		}
+		s, ok := openSession(w, r, src)
+		if !ok {
+			return
+		}
 		var req request
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			writeJSON(w, http.StatusBadRequest, response{Error: "invalid JSON: " + err.Error()})
@@ -46,36 +148,62 @@ func Start(ctx context.Context, port int, ag *agent.Agent) error {
 			writeJSON(w, http.StatusBadRequest, response{Error: "prompt is required"})
 			return
 		}
+		var f format.Formatter
+		if req.Format != "" {
+			var err error
+			if f, err = format.Get(req.Format); err != nil {
+				writeJSON(w, http.StatusBadRequest, response{Error: err.Error()})
+				return
+			}
+		}
+		if err := s.allow(); err != nil {
+			writeJSON(w, http.StatusTooManyRequests, response{Error: err.Error()})
+			return
+		}
 
-		fmt.Printf("\n[Webhook] %s\n", req.Prompt)
-		answer, err := ag.Run(r.Context(), req.Prompt)
+		fmt.Fprintf(o.log, "\n[%s] %s\n", s.label("Webhook"), req.Prompt)
+		if req.Fresh {
+			s.agent.ClearHistory()
+		}
+		result, err := s.agent.RunDetailed(r.Context(), req.Prompt)
+		if f != nil {
+			writeFormatted(w, f, result, err)
+			return
+		}
 		if err != nil {
 			writeJSON(w, http.StatusInternalServerError, response{Error: err.Error()})
 			return
 		}
-		writeJSON(w, http.StatusOK, response{Answer: answer})
+		writeJSON(w, http.StatusOK, response{Answer: result.Answer, Evidence: result.Evidence})
 	})
 
-	srv := &http.Server{
-		Addr:              fmt.Sprintf(":%d", port),
-		Handler:           mux,
-		ReadHeaderTimeout: 5 * time.Second,
-	}
+	// Authenticate before upgrading, so rejected clients get a plain HTTP error
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		s, ok := openSession(w, r, src)
+		if !ok {
+			return
+		}
+		websocket.Server{
+			Handshake: checkOrigin,
+			Handler:   func(ws *websocket.Conn) { serveWebSocket(ws, s, o.log) },
+		}.ServeHTTP(w, r)
+	})
 
-	errCh := make(chan error, 1)
-	go func() { errCh <- srv.ListenAndServe() }()
+	return mux
+}
 
-	select {
-	case <-ctx.Done():
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		return srv.Shutdown(shutdownCtx)
-	case err := <-errCh:
-		if err == http.ErrServerClosed {
-			return nil
+// openSession finds the session for r, writing the error response if there
+// is none
+func openSession(w http.ResponseWriter, r *http.Request, src sessionSource) (*session, bool) {
+	s, code, err := src.open(r)
+	if err != nil {
+		if code == http.StatusUnauthorized {
+			w.Header().Set("WWW-Authenticate", "Bearer")
 		}
-		return err
+		writeJSON(w, code, response{Error: err.Error()})
+		return nil, false
 	}
+	return s, true
 }
 
 func writeJSON(w http.ResponseWriter, code int, body response) {
@@ -83,3 +211,77 @@ func writeJSON(w http.ResponseWriter, code int, body response) {
 	w.WriteHeader(code)
 	_ = json.NewEncoder(w).Encode(body)
 }
+
+// writeFormatted writes a run's result, or its error, with f
+func writeFormatted(w http.ResponseWriter, f format.Formatter, result *agent.RunResult, err error) {
+	out := format.Result{RunResult: result}
+	code := http.StatusOK
+	if err != nil {
+		out.Error = err.Error()
+		code = http.StatusInternalServerError
+	}
+	w.Header().Set("Content-Type", f.ContentType())
+	w.WriteHeader(code)
+	_ = f.Format(w, out)
+}
+
+// serveWebSocket runs each {"prompt": "..."} request received on the connection and
+// streams the run's events back as JSON messages: "token", "tool_call" and
+// "tool_result" as they happen, then "answer" or "error". Closing the
+// connection cancels the run in flight. Requests are logged to log.
+func serveWebSocket(ws *websocket.Conn, s *session, log io.Writer) {
+	defer ws.Close()
+	ctx, cancel := context.WithCancel(ws.Request().Context())
+	defer cancel()
+
+	requests := make(chan request)
+	go func() {
+		defer cancel() // the client went away
+		defer close(requests)
+		for {
+			var req request
+			if err := websocket.JSON.Receive(ws, &req); err != nil {
+				return
+			}
+			select {
+			case requests <- req:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for req := range requests {
+		if req.Prompt == "" {
+			websocket.JSON.Send(ws, agent.Event{Type: agent.EventError, Error: "prompt is required"})
+			continue
+		}
+		if err := s.allow(); err != nil {
+			websocket.JSON.Send(ws, agent.Event{Type: agent.EventError, Error: err.Error()})
+			continue
+		}
+		fmt.Fprintf(log, "\n[%s] %s\n", s.label("WebSocket"), req.Prompt)
+		if req.Fresh {
+			s.agent.ClearHistory()
+		}
+		for e := range s.agent.RunStream(ctx, req.Prompt) {
+			websocket.JSON.Send(ws, e)
+		}
+	}
+}
+
+// checkOrigin accepts WebSocket clients that send no Origin (non-browser
+// clients) or one matching the server's host, so other web pages can't
+// drive the agent through a visitor's browser
+func checkOrigin(config *websocket.Config, r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host != r.Host {
+		return fmt.Errorf("websocket origin %q not allowed", origin)
+	}
+	config.Origin = u
+	return nil
+}
@@ -0,0 +1,230 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rathore/langchain-agent/agent"
+	"github.com/rathore/langchain-agent/llm"
+	"github.com/rathore/langchain-agent/tools"
+	"golang.org/x/net/websocket"
+)
+
+// scriptedClient is an llm.ChatClient returning its responses in turn
+type scriptedClient struct {
+	responses []*llm.Response
+}
+
+func (c *scriptedClient) Chat(ctx context.Context, messages []llm.Message) (*llm.Response, error) {
+	resp := c.responses[0]
+	c.responses = c.responses[1:]
+	return resp, nil
+}
+
+func TestWebSocket_StreamsEvents(t *testing.T) {
+	client := &scriptedClient{responses: []*llm.Response{
+		{
+			Content:   `{"name": "shell", "parameters": {"command": "echo hi"}}`,
+			ToolCalls: []llm.ToolCallParse{{Name: "shell", Params: map[string]any{"command": "echo hi"}}},
+		},
+		{Content: "It said hi.", IsFinish: true},
+	}}
+	ag, err := agent.New(agent.Config{Client: client, Tools: []tools.Tool{&tools.ShellTool{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(newMux(sharedAgent{ag}))
+	defer srv.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+	ws, err := websocket.Dial(wsURL, "", srv.URL)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer ws.Close()
+
+	if err := websocket.JSON.Send(ws, request{Prompt: "say hi"}); err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	var events []agent.Event
+	for {
+		var e agent.Event
+		if err := websocket.JSON.Receive(ws, &e); err != nil {
+			t.Fatalf("Receive() error = %v", err)
+		}
+		types = append(types, e.Type)
+		events = append(events, e)
+		if e.Type == agent.EventAnswer || e.Type == agent.EventError {
+			break
+		}
+	}
+	if got, want := strings.Join(types, ","), "tool_call,tool_result,token,answer"; got != want {
+		t.Fatalf("events = %s, want %s", got, want)
+	}
+	if events[1].Result != "hi\n" || events[3].Text != "It said hi." {
+		t.Errorf("events = %+v", events)
+	}
+
+	// A second prompt on the same connection; the empty one is rejected
+	websocket.JSON.Send(ws, request{})
+	var e agent.Event
+	if err := websocket.JSON.Receive(ws, &e); err != nil || e.Type != agent.EventError {
+		t.Errorf("empty prompt: event %+v, err %v, want an error event", e, err)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	stats := tools.NewStats()
+	ag, _ := agent.New(agent.Config{Client: &scriptedClient{}, ToolStats: stats})
+	stats.Record("shell", time.Second, false)
+	srv := httptest.NewServer(newMux(sharedAgent{ag}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `langchain_agent_tool_calls_total{tool="shell"} 1`) {
+		t.Errorf("GET /metrics = %d %s", resp.StatusCode, body)
+	}
+
+	noStats, _ := agent.New(agent.Config{Client: &scriptedClient{}})
+	srv2 := httptest.NewServer(newMux(sharedAgent{noStats}))
+	defer srv2.Close()
+	resp2, err := http.Get(srv2.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp2.Body.Close()
+	if resp2.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /metrics without stats = %d, want 404", resp2.StatusCode)
+	}
+}
+
+func TestWithRoute(t *testing.T) {
+	ag, _ := agent.New(agent.Config{Client: &scriptedClient{}})
+	hook := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusAccepted) })
+	srv := httptest.NewServer(newMux(sharedAgent{ag}, WithRoute("/webhook/", hook)))
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/webhook/ci", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("POST /webhook/ci = %d, want the route's 202", resp.StatusCode)
+	}
+	resp, err = http.Post(srv.URL+"/webhook", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("POST /webhook without a prompt = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestWithLog(t *testing.T) {
+	var log strings.Builder
+	client := &scriptedClient{responses: []*llm.Response{{Content: "Up 3 days.", IsFinish: true}}}
+	ag, _ := agent.New(agent.Config{Client: client})
+	srv := httptest.NewServer(newMux(sharedAgent{ag}, WithLog(&log)))
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/webhook", "application/json", strings.NewReader(`{"prompt": "uptime?"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if want := "\n[Webhook] uptime?\n"; log.String() != want {
+		t.Errorf("log = %q, want %q", log.String(), want)
+	}
+}
+
+func TestWebhook_Format(t *testing.T) {
+	client := &scriptedClient{responses: []*llm.Response{{Content: "**3** pods", IsFinish: true}}}
+	ag, _ := agent.New(agent.Config{Client: client})
+	srv := httptest.NewServer(newMux(sharedAgent{ag}))
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/webhook", "application/json", strings.NewReader(`{"prompt": "pods?", "format": "plain"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/plain; charset=utf-8" || string(body) != "3 pods\n" {
+		t.Errorf("POST /webhook format plain = %d %s %q", resp.StatusCode, resp.Header.Get("Content-Type"), body)
+	}
+
+	resp, err = http.Post(srv.URL+"/webhook", "application/json", strings.NewReader(`{"prompt": "pods?", "format": "html"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("POST /webhook format html = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestWebSocket_RejectsForeignOrigin(t *testing.T) {
+	ag, _ := agent.New(agent.Config{Client: &scriptedClient{}})
+	srv := httptest.NewServer(newMux(sharedAgent{ag}))
+	defer srv.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+	if _, err := websocket.Dial(wsURL, "", "https://evil.example.com"); err == nil {
+		t.Error("Dial() from a foreign origin succeeded, want it rejected")
+	}
+}
+
+func TestServe_UnixSocketFresh(t *testing.T) {
+	client := &scriptedClient{responses: []*llm.Response{
+		{Content: "first", IsFinish: true},
+		{Content: "second", IsFinish: true},
+	}}
+	ag, _ := agent.New(agent.Config{Client: client})
+	sock := filepath.Join(t.TempDir(), "agent.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Serve(ctx, ln, ag) }()
+
+	httpClient := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", sock)
+		},
+	}}
+	for _, body := range []string{`{"prompt": "one"}`, `{"prompt": "two", "fresh": true}`} {
+		resp, err := httpClient.Post("http://agent/webhook", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST %s: %v", body, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("POST %s: status %d", body, resp.StatusCode)
+		}
+	}
+	if history := ag.History(); len(history) != 2 || history[0].Content != "two" {
+		t.Errorf("history = %+v, want only the fresh exchange", history)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Serve() = %v after cancel, want nil", err)
+	}
+}
@@ -0,0 +1,119 @@
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rathore/langchain-agent/agent"
+	"github.com/rathore/langchain-agent/tools"
+	"golang.org/x/time/rate"
+)
+
+// session is the agent a request runs on
+type session struct {
+	user    string // empty when the server has a single agent
+	agent   *agent.Agent
+	limiter *rate.Limiter // nil = unlimited
+	limit   int           // runs per minute, for the error message
+	opened  time.Time     // last request (or WebSocket) on it, for idle expiry
+}
+
+// label names the request source in the server's log
+func (s *session) label(source string) string {
+	if s.user == "" {
+		return source
+	}
+	return source + " " + s.user
+}
+
+// allow takes one run from the user's rate limit
+func (s *session) allow() error {
+	if s.limiter != nil && !s.limiter.Allow() {
+		return fmt.Errorf("rate limit exceeded (%d runs per minute)", s.limit)
+	}
+	return nil
+}
+
+// sessionSource finds the session for a request. On failure it returns the
+// HTTP status to answer with.
+type sessionSource interface {
+	open(r *http.Request) (*session, int, error)
+	toolStats() *tools.Stats // nil: no /metrics
+}
+
+// sharedAgent serves every request with the same agent, for the local
+// single-user server
+type sharedAgent struct{ ag *agent.Agent }
+
+func (s sharedAgent) open(*http.Request) (*session, int, error) {
+	return &session{agent: s.ag}, 0, nil
+}
+
+func (s sharedAgent) toolStats() *tools.Stats {
+	return s.ag.ToolStats()
+}
+
+// sessionIdle is how long a user's session is kept without requests;
+// their next request starts a new conversation
+const sessionIdle = time.Hour
+
+// MultiUser serves each authenticated user with their own agent, so users
+// have separate conversations, tool permissions and rate limits
+type MultiUser struct {
+	auth     Authenticator
+	newAgent func(u *User) (*agent.Agent, error)
+	stats    *tools.Stats
+	now      func() time.Time
+
+	mu       sync.Mutex
+	sessions map[string]*session // by user name
+}
+
+// NewMultiUser authenticates requests with auth and creates a user's agent
+// with newAgent on their first request. newAgent must only give the agent
+// the tools the user is allowed (u.AllowsTool).
+func NewMultiUser(auth Authenticator, newAgent func(u *User) (*agent.Agent, error)) *MultiUser {
+	return &MultiUser{auth: auth, newAgent: newAgent, now: time.Now, sessions: map[string]*session{}}
+}
+
+// SetToolStats serves stats, which the users' agents record into, on
+// /metrics
+func (m *MultiUser) SetToolStats(stats *tools.Stats) {
+	m.stats = stats
+}
+
+func (m *MultiUser) toolStats() *tools.Stats {
+	return m.stats
+}
+
+func (m *MultiUser) open(r *http.Request) (*session, int, error) {
+	u, err := m.auth.Authenticate(r)
+	if err != nil {
+		return nil, http.StatusUnauthorized, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	for name, s := range m.sessions {
+		if now.Sub(s.opened) >= sessionIdle {
+			delete(m.sessions, name)
+		}
+	}
+	if s := m.sessions[u.Name]; s != nil {
+		s.opened = now
+		return s, 0, nil
+	}
+	ag, err := m.newAgent(u)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to create agent for %s: %w", u.Name, err)
+	}
+	s := &session{user: u.Name, agent: ag, limit: u.RateLimit, opened: now}
+	if u.RateLimit > 0 {
+		s.limiter = rate.NewLimiter(rate.Limit(float64(u.RateLimit)/60), u.RateLimit)
+	}
+	m.sessions[u.Name] = s
+	return s, 0, nil
+}